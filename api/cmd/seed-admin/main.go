@@ -25,7 +25,7 @@ func main() {
 	}
 	defer pool.Close()
 
-	sessionMgr := auth.NewSessionManager(pool, 0)
+	sessionMgr := auth.NewSessionManager(pool, 0, 0)
 	authService := auth.NewService(pool, sessionMgr, logger, cfg.TOTPIssuer)
 
 	email := "admin@forgecommerce.local"
@@ -46,7 +46,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	user, err := authService.CreateUser(context.Background(), email, name, password, "super_admin", []string{"*"})
+	user, err := authService.CreateUser(context.Background(), email, name, password, auth.RoleOwner, []string{})
 	if err != nil {
 		slog.Error("failed to create admin user", "error", err)
 		os.Exit(1)
@@ -10,27 +10,35 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/google/uuid"
+
 	"github.com/forgecommerce/api/internal/ai"
 	"github.com/forgecommerce/api/internal/auth"
 	"github.com/forgecommerce/api/internal/config"
 	"github.com/forgecommerce/api/internal/database"
 	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/email"
 	adminhandlers "github.com/forgecommerce/api/internal/handlers/admin"
 	apihandlers "github.com/forgecommerce/api/internal/handlers/api"
 	"github.com/forgecommerce/api/internal/middleware"
 	"github.com/forgecommerce/api/internal/services/attribute"
+	"github.com/forgecommerce/api/internal/services/audit"
 	"github.com/forgecommerce/api/internal/services/bom"
 	"github.com/forgecommerce/api/internal/services/cart"
 	"github.com/forgecommerce/api/internal/services/category"
 	"github.com/forgecommerce/api/internal/services/customer"
 	"github.com/forgecommerce/api/internal/services/discount"
 	"github.com/forgecommerce/api/internal/services/globalattr"
+	"github.com/forgecommerce/api/internal/services/inventory"
+	"github.com/forgecommerce/api/internal/services/invoice"
 	"github.com/forgecommerce/api/internal/services/media"
 	"github.com/forgecommerce/api/internal/storage"
 	"github.com/forgecommerce/api/internal/services/order"
 	"github.com/forgecommerce/api/internal/services/production"
 	"github.com/forgecommerce/api/internal/services/product"
 	"github.com/forgecommerce/api/internal/services/rawmaterial"
+	"github.com/forgecommerce/api/internal/services/recovery"
+	"github.com/forgecommerce/api/internal/services/refund"
 	"github.com/forgecommerce/api/internal/services/report"
 	"github.com/forgecommerce/api/internal/services/shipping"
 	"github.com/forgecommerce/api/internal/services/variant"
@@ -66,20 +74,22 @@ func main() {
 	slog.Info("migrations complete")
 
 	// Initialize auth services
-	sessionMgr := auth.NewSessionManager(pool, 8*time.Hour)
+	sessionMgr := auth.NewSessionManager(pool, cfg.SessionTTL, cfg.SessionIdleTimeout)
 	authService := auth.NewService(pool, sessionMgr, logger, cfg.TOTPIssuer)
 	jwtMgr := auth.NewJWTManager(cfg.JWTSecret)
 
+	// Security keys are bound to the admin panel's host name.
+	relyingParty, err := auth.RelyingPartyFromURL(cfg.AdminURL, cfg.TOTPIssuer)
+	if err != nil {
+		slog.Error("invalid ADMIN_URL for security keys", "error", err)
+		os.Exit(1)
+	}
+	webauthnSvc := auth.NewWebAuthnService(authService, relyingParty)
+	apiKeySvc := auth.NewAPIKeyService(authService)
+
 	// Initialize Stripe service
 	stripeSvc := forgestripe.NewService(cfg.StripeSecretKey, logger)
 
-	// Initialize VAT services
-	vatCache := vat.NewRateCache()
-	vatSyncer := vat.NewRateSyncer(pool, cfg.VAT, logger, vatCache)
-	vatScheduler := vat.NewScheduler(vatSyncer, logger)
-	vatSvc := vat.NewVATService(pool, vatCache, logger)
-	viesClient := vat.NewVIESClient(pool, cfg.VAT.VIESTimeout, cfg.VAT.VIESCacheTTL, logger)
-
 	// Initialize storage backends
 	var publicStore storage.Storage
 	var privateStore storage.Storage
@@ -121,23 +131,64 @@ func main() {
 		publicStore = storage.NewLocal(cfg.MediaPath, "/media")
 	}
 
+	// Outbound webhooks and transactional email; domain services publish
+	// events to both.
+	webhookSvc := webhook.NewService(pool, logger)
+	emailSvc := email.NewService(pool, email.NewSMTPSender(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPFrom), email.Config{
+		From:     cfg.SMTPFrom,
+		ShopURL:  cfg.BaseURL,
+		AdminURL: cfg.AdminURL,
+	}, logger)
+
+	// Invoices are issued when an order is paid and kept in the private bucket.
+	invoiceSvc := invoice.NewService(pool, privateStore, logger)
+	if privateStore == nil {
+		slog.Warn("no private storage configured; invoices will not be issued")
+	}
+	events := webhook.Publishers(webhookSvc, emailSvc, invoiceSvc)
+
+	// Initialize VAT services
+	vatCache := vat.NewRateCache()
+	vatSyncer := vat.NewRateSyncer(pool, cfg.VAT, logger, vatCache, events)
+	vatScheduler := vat.NewScheduler(vatSyncer, logger)
+	vatSvc := vat.NewVATService(pool, vatCache, logger)
+	viesClient := vat.NewVIESClient(pool, cfg.VAT.VIESTimeout, cfg.VAT.VIESCacheTTL, logger)
+
 	// Initialize services
-	productSvc := product.NewService(pool, logger)
+	productSvc := product.NewService(pool, events, logger)
 	categorySvc := category.NewService(pool, logger)
 	rawMaterialSvc := rawmaterial.NewService(pool, logger)
 	attributeSvc := attribute.NewService(pool, logger)
-	variantSvc := variant.NewService(pool, logger)
+	variantSvc := variant.NewService(pool, events, logger)
 	bomSvc := bom.NewService(pool, logger)
-	orderSvc := order.NewService(pool, logger)
+	orderSvc := order.NewService(pool, events, logger)
+	refundSvc := refund.NewService(pool, stripeSvc, orderSvc, events, logger)
 	customerSvc := customer.NewService(pool, logger)
 	discountSvc := discount.NewService(pool, logger)
 	shippingSvc := shipping.NewService(pool, logger)
 	cartSvc := cart.NewService(pool, logger)
 	reportSvc := report.NewService(pool, logger)
-	productionSvc := production.NewService(pool, logger)
+	productionSvc := production.NewService(pool, bomSvc, events, logger)
+	inventorySvc := inventory.NewService(pool, events, logger)
 	mediaSvc := media.NewService(pool, publicStore, privateStore, logger)
-	webhookSvc := webhook.NewService(pool, logger)
 	globalAttrSvc := globalattr.NewService(pool, logger)
+	auditSvc := audit.NewService(pool, logger)
+
+	// Abandoned cart reminders; the coupon discount is optional.
+	var recoveryDiscountID uuid.UUID
+	if cfg.CartRecovery.DiscountID != "" {
+		id, err := uuid.Parse(cfg.CartRecovery.DiscountID)
+		if err != nil {
+			slog.Error("invalid CART_RECOVERY_DISCOUNT_ID", "error", err)
+			os.Exit(1)
+		}
+		recoveryDiscountID = id
+	}
+	recoverySvc := recovery.NewService(pool, discountSvc, emailSvc, jwtMgr, recovery.Config{
+		IdleAfter:  cfg.CartRecovery.IdleAfter,
+		DiscountID: recoveryDiscountID,
+		CouponTTL:  cfg.CartRecovery.CouponTTL,
+	}, logger)
 
 	// Initialize AI services
 	aiRegistry := ai.NewRegistry(cfg.AI, logger)
@@ -146,38 +197,46 @@ func main() {
 	// Initialize public API handlers
 	queries := db.New(pool)
 	publicHandler := apihandlers.NewPublicHandler(productSvc, categorySvc, variantSvc, pool, logger)
-	cartHandler := apihandlers.NewCartHandler(cartSvc, logger)
-	customerHandler := apihandlers.NewCustomerHandler(customerSvc, jwtMgr, logger)
+	cartHandler := apihandlers.NewCartHandler(cartSvc, discountSvc, logger)
+	customerHandler := apihandlers.NewCustomerHandler(customerSvc, cartSvc, orderSvc, emailSvc, jwtMgr, logger)
+	orderHandler := apihandlers.NewOrderHandler(orderSvc, invoiceSvc, jwtMgr, logger)
+	cartRecoveryHandler := apihandlers.NewCartRecoveryHandler(recoverySvc, logger)
 	vatNumberHandler := apihandlers.NewVATNumberHandler(cartSvc, viesClient, logger)
 	checkoutHandler := apihandlers.NewCheckoutHandler(
-		cartSvc, orderSvc, vatSvc, shippingSvc, queries, logger,
+		cartSvc, orderSvc, vatSvc, shippingSvc, discountSvc, inventorySvc, customerSvc, queries, logger,
 		cfg.BaseURL+"/checkout/success?session_id={CHECKOUT_SESSION_ID}",
 		cfg.BaseURL+"/checkout/cancel",
 	)
-	webhookHandler := apihandlers.NewWebhookHandler(stripeSvc, orderSvc, logger, cfg.StripeWebhookKey)
+	webhookHandler := apihandlers.NewWebhookHandler(stripeSvc, orderSvc, cartSvc, inventorySvc, refundSvc, discountSvc, logger, cfg.StripeWebhookKey)
 
 	// Initialize admin handlers
-	adminHandler := adminhandlers.NewHandler(authService, logger)
-	productHandler := adminhandlers.NewProductHandler(productSvc, categorySvc, logger)
+	adminHandler := adminhandlers.NewHandler(authService, webauthnSvc, logger)
+	productHandler := adminhandlers.NewProductHandler(productSvc, categorySvc, auditSvc, logger)
 	categoryHandler := adminhandlers.NewCategoryHandler(categorySvc, logger)
 	rawMaterialHandler := adminhandlers.NewRawMaterialHandler(rawMaterialSvc, logger)
-	settingsHandler := adminhandlers.NewSettingsHandler(pool, vatSyncer, logger)
+	settingsHandler := adminhandlers.NewSettingsHandler(pool, vatSyncer, auditSvc, logger)
 	productVATHandler := adminhandlers.NewProductVATHandler(productSvc, pool, logger)
 	attributeHandler := adminhandlers.NewAttributeHandler(attributeSvc, productSvc, logger)
-	variantHandler := adminhandlers.NewVariantHandler(variantSvc, attributeSvc, productSvc, logger)
+	variantHandler := adminhandlers.NewVariantHandler(variantSvc, attributeSvc, productSvc, auditSvc, logger)
 	bomHandler := adminhandlers.NewBOMHandler(bomSvc, productSvc, rawMaterialSvc, variantSvc, logger)
-	orderHandler := adminhandlers.NewOrderHandler(orderSvc, logger)
-	discountHandler := adminhandlers.NewDiscountHandler(discountSvc, logger)
-	shippingHandler := adminhandlers.NewShippingHandler(shippingSvc, logger)
+	adminOrderHandler := adminhandlers.NewOrderHandler(orderSvc, refundSvc, invoiceSvc, logger)
+	discountHandler := adminhandlers.NewDiscountHandler(discountSvc, auditSvc, logger)
+	shippingHandler := adminhandlers.NewShippingHandler(shippingSvc, auditSvc, logger)
 	dashboardHandler := adminhandlers.NewDashboardHandler(pool, queries, logger)
-	userHandler := adminhandlers.NewUserHandler(authService, logger)
+	userHandler := adminhandlers.NewUserHandler(authService, auditSvc, logger)
+	auditHandler := adminhandlers.NewAuditHandler(auditSvc, authService, logger)
+	sessionHandler := adminhandlers.NewSessionHandler(authService, auditSvc, logger)
+	securityKeyHandler := adminhandlers.NewSecurityKeyHandler(authService, webauthnSvc, logger)
+	apiKeyHandler := adminhandlers.NewAPIKeyHandler(authService, apiKeySvc, auditSvc, logger)
 	reportHandler := adminhandlers.NewReportHandler(reportSvc, logger)
 	productionHandler := adminhandlers.NewProductionHandler(productionSvc, productSvc, logger)
+	stockHandler := adminhandlers.NewStockHandler(inventorySvc, variantSvc, rawMaterialSvc, productSvc, logger)
 	imageHandler := adminhandlers.NewImageHandler(mediaSvc, variantSvc, logger)
 	adminWebhookHandler := adminhandlers.NewWebhookHandler(webhookSvc, logger)
 	csvioHandler := adminhandlers.NewCSVIOHandler(productSvc, rawMaterialSvc, orderSvc, logger)
 	globalAttrHandler := adminhandlers.NewGlobalAttributeHandler(globalAttrSvc, productSvc, logger)
 	aiHandler := adminhandlers.NewAIHandler(aiSvc, logger)
+	adminAPIHandler := adminhandlers.NewAPIHandler(productSvc, variantSvc, orderSvc, rawMaterialSvc, inventorySvc, auditSvc, logger)
 
 	// Admin server (HTMX + templ)
 	adminMux := http.NewServeMux()
@@ -210,13 +269,18 @@ func main() {
 	attributeHandler.RegisterRoutes(protectedMux)
 	variantHandler.RegisterRoutes(protectedMux)
 	bomHandler.RegisterRoutes(protectedMux)
-	orderHandler.RegisterRoutes(protectedMux)
+	adminOrderHandler.RegisterRoutes(protectedMux)
 	discountHandler.RegisterRoutes(protectedMux)
 	shippingHandler.RegisterRoutes(protectedMux)
 	dashboardHandler.RegisterRoutes(protectedMux)
 	userHandler.RegisterRoutes(protectedMux)
+	auditHandler.RegisterRoutes(protectedMux)
+	sessionHandler.RegisterRoutes(protectedMux)
+	securityKeyHandler.RegisterRoutes(protectedMux)
+	apiKeyHandler.RegisterRoutes(protectedMux)
 	reportHandler.RegisterRoutes(protectedMux)
 	productionHandler.RegisterRoutes(protectedMux)
+	stockHandler.RegisterRoutes(protectedMux)
 	imageHandler.RegisterRoutes(protectedMux)
 	adminWebhookHandler.RegisterRoutes(protectedMux)
 	csvioHandler.RegisterRoutes(protectedMux)
@@ -234,9 +298,19 @@ func main() {
 		http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
 	})
 
+	// JSON admin API (API key auth, no session or CSRF)
+	adminAPIMux := http.NewServeMux()
+	adminAPIHandler.RegisterRoutes(adminAPIMux)
+	var adminAPIChain http.Handler = adminAPIMux
+	adminAPIChain = middleware.APIKeyRateLimiter(10, 20)(adminAPIChain) // Per-key rate limiting (10 req/s, burst 20)
+	adminAPIChain = middleware.RequireAPIKey(apiKeySvc)(adminAPIChain)
+
+	adminRoot := http.NewServeMux()
+	adminRoot.Handle("/admin/api/v1/", adminAPIChain)
+	adminRoot.Handle("/", middleware.CSRF(adminMux))
+
 	// Apply global middleware stack
-	var adminChain http.Handler = adminMux
-	adminChain = middleware.CSRF(adminChain)
+	var adminChain http.Handler = adminRoot
 	adminChain = middleware.SecurityHeaders(adminChain)
 	adminChain = middleware.RateLimiter(30, 60)(adminChain) // General admin rate limiting (30 req/s, burst 60)
 	adminChain = middleware.Recover(logger)(adminChain)
@@ -266,14 +340,17 @@ func main() {
 	// Register public API routes (no auth required)
 	publicHandler.RegisterRoutes(apiMux)
 	cartHandler.RegisterRoutes(apiMux)
+	cartRecoveryHandler.RegisterRoutes(apiMux)
 	customerHandler.RegisterPublicRoutes(apiMux)
 	vatNumberHandler.RegisterRoutes(apiMux)
 	checkoutHandler.RegisterRoutes(apiMux)
+	orderHandler.RegisterRoutes(apiMux)
 	webhookHandler.RegisterRoutes(apiMux)
 
 	// Register protected customer API routes (JWT auth required)
 	customerProtectedMux := http.NewServeMux()
 	customerHandler.RegisterProtectedRoutes(customerProtectedMux)
+	orderHandler.RegisterProtectedRoutes(customerProtectedMux)
 	apiMux.Handle("/api/v1/customers/me", middleware.RequireCustomerAuth(jwtMgr)(customerProtectedMux))
 	apiMux.Handle("/api/v1/customers/me/", middleware.RequireCustomerAuth(jwtMgr)(customerProtectedMux))
 
@@ -297,6 +374,20 @@ func main() {
 		vatScheduler.Start(context.Background())
 	}
 
+	// Start outbound webhook delivery worker
+	webhookWorker := webhook.NewWorker(webhookSvc, logger)
+	webhookWorker.Start()
+
+	// Start transactional email outbox worker
+	emailWorker := email.NewWorker(emailSvc, logger)
+	emailWorker.Start()
+
+	// Start abandoned cart reminder worker
+	recoveryWorker := recovery.NewWorker(recoverySvc, cfg.CartRecovery.Interval, logger)
+	if cfg.CartRecovery.Enabled {
+		recoveryWorker.Start()
+	}
+
 	// Start servers
 	errCh := make(chan error, 2)
 
@@ -331,6 +422,15 @@ func main() {
 	// Stop VAT scheduler
 	vatScheduler.Stop()
 
+	// Stop webhook worker; deliveries not yet sent stay queued for the next start
+	webhookWorker.Stop()
+
+	// Stop the cart reminder worker before the email worker it queues for
+	recoveryWorker.Stop()
+
+	// Stop email worker; unsent messages stay in the outbox for the next start
+	emailWorker.Stop()
+
 	if err := adminServer.Shutdown(ctx); err != nil {
 		slog.Error("admin server shutdown error", "error", err)
 	}
@@ -0,0 +1,336 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrAPIKeyNotFound is returned when an API key does not exist.
+	ErrAPIKeyNotFound = errors.New("API key not found")
+
+	// ErrInvalidAPIKey is returned when a presented API key is unknown,
+	// revoked or expired, or its creator has been deactivated.
+	ErrInvalidAPIKey = errors.New("invalid, expired or revoked API key")
+
+	// ErrInvalidAPIKeyName is returned for empty or overlong API key names.
+	ErrInvalidAPIKeyName = errors.New("API key name is required and must be at most 64 characters")
+
+	// ErrInvalidAPIKeyScopes is returned when a key is created without
+	// scopes, or with scopes that are not API scopes or that the creator
+	// does not hold.
+	ErrInvalidAPIKeyScopes = errors.New("choose at least one scope you have permission for")
+
+	// ErrInvalidAPIKeyExpiry is returned when a key's expiry is not in the
+	// future.
+	ErrInvalidAPIKeyExpiry = errors.New("API key expiry must be in the future")
+)
+
+const (
+	// apiKeyPrefix starts every API key so leaked keys are easy to spot.
+	apiKeyPrefix = "fck_"
+
+	// apiKeyBytes is the amount of randomness in a key.
+	apiKeyBytes = 32
+
+	// apiKeyDisplayLen is how much of a key is kept in clear to identify
+	// it in the UI.
+	apiKeyDisplayLen = len(apiKeyPrefix) + 8
+
+	// maxAPIKeyNameLen is the longest API key name accepted.
+	maxAPIKeyNameLen = 64
+
+	// apiKeyTouchInterval limits how often last_used_at is written for a
+	// busy key.
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIScopes are the permissions an API key may be granted, in display
+// order.
+var APIScopes = []string{
+	PermProductsRead, PermProductsWrite,
+	PermInventoryRead, PermInventoryWrite,
+	PermOrdersRead, PermOrdersWrite,
+}
+
+// APIKey is a key for the JSON admin API. The key itself is never stored.
+type APIKey struct {
+	ID         uuid.UUID
+	Name       string
+	Prefix     string // first characters of the key, for display
+	Scopes     []string
+	CreatedBy  uuid.UUID
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	LastUsedIP string
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+// HasScope reports whether the key grants scope.
+func (k *APIKey) HasScope(scope string) bool {
+	return k != nil && slices.Contains(k.Scopes, scope)
+}
+
+// Active reports whether the key can be used at now: it is neither revoked
+// nor expired.
+func (k *APIKey) Active(now time.Time) bool {
+	if k.RevokedAt != nil {
+		return false
+	}
+	return k.ExpiresAt == nil || now.Before(*k.ExpiresAt)
+}
+
+// APIKeyService issues, lists, revokes and authenticates API keys.
+type APIKeyService struct {
+	auth   *Service
+	logger *slog.Logger
+}
+
+// NewAPIKeyService creates a new API key service.
+func NewAPIKeyService(authService *Service) *APIKeyService {
+	return &APIKeyService{
+		auth:   authService,
+		logger: authService.logger,
+	}
+}
+
+// Create issues a new API key on behalf of creator and returns it together
+// with the plaintext key, which cannot be recovered later. Scopes must be
+// API scopes the creator holds. A nil expiresAt means the key does not
+// expire.
+func (s *APIKeyService) Create(ctx context.Context, creator *AdminUser, name string, scopes []string, expiresAt *time.Time) (*APIKey, string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" || utf8.RuneCountInString(name) > maxAPIKeyNameLen {
+		return nil, "", ErrInvalidAPIKeyName
+	}
+	scopes, err := normalizeScopes(creator, scopes)
+	if err != nil {
+		return nil, "", err
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		return nil, "", ErrInvalidAPIKeyExpiry
+	}
+
+	token, err := generateAPIKey()
+	if err != nil {
+		return nil, "", fmt.Errorf("generating API key: %w", err)
+	}
+
+	key := &APIKey{
+		ID:        uuid.New(),
+		Name:      name,
+		Prefix:    token[:apiKeyDisplayLen],
+		Scopes:    scopes,
+		CreatedBy: creator.ID,
+		ExpiresAt: expiresAt,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	_, err = s.auth.pool.Exec(ctx, `
+		INSERT INTO admin_api_keys (id, name, prefix, key_hash, scopes, created_by, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`, key.ID, key.Name, key.Prefix, hashAPIKey(token), key.Scopes, key.CreatedBy, key.ExpiresAt, key.CreatedAt)
+	if err != nil {
+		return nil, "", fmt.Errorf("inserting API key: %w", err)
+	}
+
+	s.logger.Info("API key created",
+		slog.String("api_key_id", key.ID.String()),
+		slog.String("created_by", creator.ID.String()),
+	)
+
+	return key, token, nil
+}
+
+// List returns every API key, newest first, including revoked and expired
+// ones.
+func (s *APIKeyService) List(ctx context.Context) ([]*APIKey, error) {
+	rows, err := s.auth.pool.Query(ctx, `
+		SELECT id, name, prefix, scopes, created_by, expires_at,
+		       last_used_at, last_used_ip, revoked_at, created_at
+		FROM admin_api_keys
+		ORDER BY created_at DESC
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("listing API keys: %w", err)
+	}
+	defer rows.Close()
+
+	var keys []*APIKey
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+// Get fetches an API key by ID.
+func (s *APIKeyService) Get(ctx context.Context, id uuid.UUID) (*APIKey, error) {
+	key, err := scanAPIKey(s.auth.pool.QueryRow(ctx, `
+		SELECT id, name, prefix, scopes, created_by, expires_at,
+		       last_used_at, last_used_ip, revoked_at, created_at
+		FROM admin_api_keys
+		WHERE id = $1
+	`, id))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrAPIKeyNotFound
+	}
+	return key, err
+}
+
+// Revoke stops an API key from being used. Revoking a key twice keeps the
+// original revocation time.
+func (s *APIKeyService) Revoke(ctx context.Context, id uuid.UUID) (*APIKey, error) {
+	tag, err := s.auth.pool.Exec(ctx, `
+		UPDATE admin_api_keys SET revoked_at = $2
+		WHERE id = $1 AND revoked_at IS NULL
+	`, id, time.Now().UTC())
+	if err != nil {
+		return nil, fmt.Errorf("revoking API key: %w", err)
+	}
+
+	key, err := s.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if tag.RowsAffected() > 0 {
+		s.logger.Info("API key revoked", slog.String("api_key_id", id.String()))
+	}
+	return key, nil
+}
+
+// Authenticate returns the API key for token if it is active and its
+// creator is still an active admin user. The key's scopes are narrowed to
+// the permissions the creator holds now, so a key never outlives a
+// demotion. Use is recorded against the key, at most once a minute.
+func (s *APIKeyService) Authenticate(ctx context.Context, token, ipAddress string) (*APIKey, error) {
+	if !strings.HasPrefix(token, apiKeyPrefix) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	key, err := scanAPIKey(s.auth.pool.QueryRow(ctx, `
+		SELECT id, name, prefix, scopes, created_by, expires_at,
+		       last_used_at, last_used_ip, revoked_at, created_at
+		FROM admin_api_keys
+		WHERE key_hash = $1
+	`, hashAPIKey(token)))
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrInvalidAPIKey
+		}
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	if !key.Active(now) {
+		return nil, ErrInvalidAPIKey
+	}
+
+	creator, err := s.auth.GetUserByID(ctx, key.CreatedBy)
+	if err != nil {
+		return nil, fmt.Errorf("fetching API key creator: %w", err)
+	}
+	if !creator.IsActive {
+		return nil, ErrInvalidAPIKey
+	}
+	key.Scopes = slices.DeleteFunc(key.Scopes, func(scope string) bool {
+		return !creator.Can(scope)
+	})
+
+	_, err = s.auth.pool.Exec(ctx, `
+		UPDATE admin_api_keys SET last_used_at = $2, last_used_ip = $3
+		WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $4)
+	`, key.ID, now, ipAddress, now.Add(-apiKeyTouchInterval))
+	if err != nil {
+		s.logger.Error("failed to record API key use",
+			slog.String("api_key_id", key.ID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	return key, nil
+}
+
+type apiKeyContextKey struct{}
+
+// ContextWithAPIKey returns a copy of ctx carrying the API key that
+// authenticated the request.
+func ContextWithAPIKey(ctx context.Context, k *APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, k)
+}
+
+// APIKeyFromContext returns the API key stored by ContextWithAPIKey.
+func APIKeyFromContext(ctx context.Context) (*APIKey, bool) {
+	k, ok := ctx.Value(apiKeyContextKey{}).(*APIKey)
+	return k, ok && k != nil
+}
+
+// normalizeScopes checks that scopes are API scopes the creator holds and
+// returns them deduplicated, in APIScopes order.
+func normalizeScopes(creator *AdminUser, scopes []string) ([]string, error) {
+	for _, scope := range scopes {
+		if !slices.Contains(APIScopes, scope) || !creator.Can(scope) {
+			return nil, ErrInvalidAPIKeyScopes
+		}
+	}
+	var normalized []string
+	for _, scope := range APIScopes {
+		if slices.Contains(scopes, scope) {
+			normalized = append(normalized, scope)
+		}
+	}
+	if len(normalized) == 0 {
+		return nil, ErrInvalidAPIKeyScopes
+	}
+	return normalized, nil
+}
+
+// generateAPIKey returns a new random API key.
+func generateAPIKey() (string, error) {
+	b := make([]byte, apiKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("reading random bytes: %w", err)
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashAPIKey returns the hex SHA-256 of an API key. Keys carry 256 bits of
+// randomness, so a fast unsalted hash is enough.
+func hashAPIKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func scanAPIKey(row pgx.Row) (*APIKey, error) {
+	key := &APIKey{}
+	var lastUsedIP *string
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.Scopes, &key.CreatedBy, &key.ExpiresAt,
+		&key.LastUsedAt, &lastUsedIP, &key.RevokedAt, &key.CreatedAt)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("scanning API key: %w", err)
+	}
+	if lastUsedIP != nil {
+		key.LastUsedIP = *lastUsedIP
+	}
+	return key, nil
+}
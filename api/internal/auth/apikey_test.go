@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateAPIKey(t *testing.T) {
+	a, err := generateAPIKey()
+	if err != nil {
+		t.Fatalf("generateAPIKey: %v", err)
+	}
+	b, err := generateAPIKey()
+	if err != nil {
+		t.Fatalf("generateAPIKey: %v", err)
+	}
+	if !strings.HasPrefix(a, apiKeyPrefix) {
+		t.Errorf("key %q does not start with %q", a, apiKeyPrefix)
+	}
+	if a == b {
+		t.Error("expected distinct keys")
+	}
+	if hashAPIKey(a) == hashAPIKey(b) || len(hashAPIKey(a)) != 64 {
+		t.Errorf("unexpected hashes %q, %q", hashAPIKey(a), hashAPIKey(b))
+	}
+}
+
+func TestAPIKey_Active(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Minute)
+	future := now.Add(time.Minute)
+
+	tests := []struct {
+		name string
+		key  APIKey
+		want bool
+	}{
+		{"no expiry", APIKey{}, true},
+		{"expires later", APIKey{ExpiresAt: &future}, true},
+		{"expired", APIKey{ExpiresAt: &past}, false},
+		{"revoked", APIKey{RevokedAt: &past}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.key.Active(now); got != tt.want {
+			t.Errorf("%s: Active = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAPIKey_HasScope(t *testing.T) {
+	key := &APIKey{Scopes: []string{PermOrdersRead}}
+	if !key.HasScope(PermOrdersRead) {
+		t.Error("expected orders.read")
+	}
+	if key.HasScope(PermOrdersWrite) {
+		t.Error("did not expect orders.write")
+	}
+	var none *APIKey
+	if none.HasScope(PermOrdersRead) {
+		t.Error("nil key must grant nothing")
+	}
+}
+
+func TestNormalizeScopes(t *testing.T) {
+	owner := &AdminUser{Role: RoleOwner}
+	warehouse := &AdminUser{Role: RoleWarehouse}
+
+	got, err := normalizeScopes(owner, []string{PermOrdersWrite, PermProductsRead, PermOrdersWrite})
+	if err != nil {
+		t.Fatalf("normalizeScopes: %v", err)
+	}
+	if want := []string{PermProductsRead, PermOrdersWrite}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+
+	for _, tt := range []struct {
+		name    string
+		creator *AdminUser
+		scopes  []string
+	}{
+		{"empty", owner, nil},
+		{"not an API scope", owner, []string{PermUsersManage}},
+		{"unknown", owner, []string{"everything"}},
+		{"not held by creator", warehouse, []string{PermProductsWrite}},
+	} {
+		if _, err := normalizeScopes(tt.creator, tt.scopes); err != ErrInvalidAPIKeyScopes {
+			t.Errorf("%s: expected ErrInvalidAPIKeyScopes, got %v", tt.name, err)
+		}
+	}
+}
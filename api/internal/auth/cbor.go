@@ -0,0 +1,199 @@
+package auth
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// errCBOR is returned for malformed or unsupported CBOR input.
+var errCBOR = errors.New("invalid CBOR")
+
+// maxCBORDepth bounds nesting so hostile input cannot exhaust the stack.
+const maxCBORDepth = 16
+
+// decodeCBOR decodes the first CBOR data item in data and returns it with
+// the bytes that follow it. It supports the subset WebAuthn authenticators
+// produce (RFC 8949 with definite lengths):
+//
+//	unsigned and negative integers -> int64
+//	byte strings                   -> []byte
+//	text strings                   -> string
+//	arrays                         -> []any
+//	maps                           -> map[any]any (int64 or string keys)
+//	false, true, null              -> bool, nil
+//	floats                         -> float64
+//
+// Tags are skipped and their content returned.
+func decodeCBOR(data []byte) (any, []byte, error) {
+	return decodeCBORItem(data, 0)
+}
+
+func decodeCBORItem(data []byte, depth int) (any, []byte, error) {
+	if depth > maxCBORDepth {
+		return nil, nil, fmt.Errorf("%w: nested too deeply", errCBOR)
+	}
+	if len(data) == 0 {
+		return nil, nil, fmt.Errorf("%w: unexpected end of input", errCBOR)
+	}
+
+	major := data[0] >> 5
+	info := data[0] & 0x1f
+
+	if major == 7 {
+		return decodeCBORSimple(data, info)
+	}
+
+	arg, rest, err := decodeCBORArgument(data, info)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch major {
+	case 0: // unsigned integer
+		if arg > math.MaxInt64 {
+			return nil, nil, fmt.Errorf("%w: integer overflow", errCBOR)
+		}
+		return int64(arg), rest, nil
+
+	case 1: // negative integer: -1 - arg
+		if arg > math.MaxInt64 {
+			return nil, nil, fmt.Errorf("%w: integer overflow", errCBOR)
+		}
+		return -1 - int64(arg), rest, nil
+
+	case 2, 3: // byte string, text string
+		if arg > uint64(len(rest)) {
+			return nil, nil, fmt.Errorf("%w: string longer than input", errCBOR)
+		}
+		b := rest[:arg]
+		if major == 3 {
+			return string(b), rest[arg:], nil
+		}
+		return append([]byte(nil), b...), rest[arg:], nil
+
+	case 4: // array
+		if arg > uint64(len(rest)) {
+			return nil, nil, fmt.Errorf("%w: array longer than input", errCBOR)
+		}
+		items := make([]any, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			var item any
+			item, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, rest, nil
+
+	case 5: // map
+		if arg > uint64(len(rest)) {
+			return nil, nil, fmt.Errorf("%w: map longer than input", errCBOR)
+		}
+		m := make(map[any]any, arg)
+		for i := uint64(0); i < arg; i++ {
+			var key, value any
+			key, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch key.(type) {
+			case int64, string:
+			default:
+				return nil, nil, fmt.Errorf("%w: unsupported map key type %T", errCBOR, key)
+			}
+			value, rest, err = decodeCBORItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			if _, dup := m[key]; dup {
+				return nil, nil, fmt.Errorf("%w: duplicate map key %v", errCBOR, key)
+			}
+			m[key] = value
+		}
+		return m, rest, nil
+
+	default: // 6: tag
+		return decodeCBORItem(rest, depth+1)
+	}
+}
+
+// decodeCBORArgument reads the argument that follows the initial byte.
+func decodeCBORArgument(data []byte, info byte) (uint64, []byte, error) {
+	rest := data[1:]
+	switch {
+	case info < 24:
+		return uint64(info), rest, nil
+	case info <= 27:
+		n := 1 << (info - 24) // 1, 2, 4 or 8 bytes
+		if len(rest) < n {
+			return 0, nil, fmt.Errorf("%w: unexpected end of input", errCBOR)
+		}
+		var v uint64
+		switch n {
+		case 1:
+			v = uint64(rest[0])
+		case 2:
+			v = uint64(binary.BigEndian.Uint16(rest))
+		case 4:
+			v = uint64(binary.BigEndian.Uint32(rest))
+		case 8:
+			v = binary.BigEndian.Uint64(rest)
+		}
+		return v, rest[n:], nil
+	default:
+		return 0, nil, fmt.Errorf("%w: indefinite lengths are not supported", errCBOR)
+	}
+}
+
+// decodeCBORSimple decodes major type 7: simple values and floats.
+func decodeCBORSimple(data []byte, info byte) (any, []byte, error) {
+	rest := data[1:]
+	switch info {
+	case 20:
+		return false, rest, nil
+	case 21:
+		return true, rest, nil
+	case 22, 23: // null, undefined
+		return nil, rest, nil
+	case 25:
+		if len(rest) < 2 {
+			return nil, nil, fmt.Errorf("%w: unexpected end of input", errCBOR)
+		}
+		return float16ToFloat64(binary.BigEndian.Uint16(rest)), rest[2:], nil
+	case 26:
+		if len(rest) < 4 {
+			return nil, nil, fmt.Errorf("%w: unexpected end of input", errCBOR)
+		}
+		return float64(math.Float32frombits(binary.BigEndian.Uint32(rest))), rest[4:], nil
+	case 27:
+		if len(rest) < 8 {
+			return nil, nil, fmt.Errorf("%w: unexpected end of input", errCBOR)
+		}
+		return math.Float64frombits(binary.BigEndian.Uint64(rest)), rest[8:], nil
+	default:
+		return nil, nil, fmt.Errorf("%w: unsupported simple value %d", errCBOR, info)
+	}
+}
+
+func float16ToFloat64(h uint16) float64 {
+	sign := 1.0
+	if h&0x8000 != 0 {
+		sign = -1
+	}
+	exp := int(h>>10) & 0x1f
+	frac := float64(h & 0x3ff)
+	switch exp {
+	case 0:
+		return sign * math.Ldexp(frac, -24)
+	case 0x1f:
+		if frac == 0 {
+			return math.Inf(int(sign))
+		}
+		return math.NaN()
+	default:
+		return sign * math.Ldexp(frac+1024, exp-25)
+	}
+}
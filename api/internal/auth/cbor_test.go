@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestDecodeCBOR(t *testing.T) {
+	tests := []struct {
+		name string
+		in   []byte
+		want any
+	}{
+		{"small uint", []byte{0x0a}, int64(10)},
+		{"uint8", []byte{0x18, 0x64}, int64(100)},
+		{"uint16", []byte{0x19, 0x03, 0xe8}, int64(1000)},
+		{"uint32", []byte{0x1a, 0x00, 0x0f, 0x42, 0x40}, int64(1000000)},
+		{"negative", []byte{0x26}, int64(-7)},
+		{"negative uint16", []byte{0x39, 0x01, 0x00}, int64(-257)},
+		{"byte string", []byte{0x44, 0x01, 0x02, 0x03, 0x04}, []byte{1, 2, 3, 4}},
+		{"text string", []byte{0x64, 'I', 'E', 'T', 'F'}, "IETF"},
+		{"array", []byte{0x83, 0x01, 0x02, 0x03}, []any{int64(1), int64(2), int64(3)}},
+		{"map", []byte{0xa2, 0x01, 0x02, 0x61, 'a', 0xf5}, map[any]any{int64(1): int64(2), "a": true}},
+		{"false", []byte{0xf4}, false},
+		{"null", []byte{0xf6}, nil},
+		{"float16", []byte{0xf9, 0x3c, 0x00}, 1.0},
+		{"float32", []byte{0xfa, 0x47, 0xc3, 0x50, 0x00}, 100000.0},
+		{"tag skipped", []byte{0xc1, 0x1a, 0x51, 0x4b, 0x67, 0xb0}, int64(1363896240)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := decodeCBOR(tt.in)
+			if err != nil {
+				t.Fatalf("decodeCBOR: %v", err)
+			}
+			if len(rest) != 0 {
+				t.Errorf("expected no trailing bytes, got %x", rest)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeCBOR_ReturnsRest(t *testing.T) {
+	_, rest, err := decodeCBOR([]byte{0x01, 0x02, 0x03})
+	if err != nil {
+		t.Fatalf("decodeCBOR: %v", err)
+	}
+	if !bytes.Equal(rest, []byte{0x02, 0x03}) {
+		t.Errorf("rest = %x, want 0203", rest)
+	}
+}
+
+func TestDecodeCBOR_Invalid(t *testing.T) {
+	deep := bytes.Repeat([]byte{0x81}, maxCBORDepth+2)
+	deep = append(deep, 0x00)
+
+	tests := []struct {
+		name string
+		in   []byte
+	}{
+		{"empty", nil},
+		{"truncated argument", []byte{0x19, 0x01}},
+		{"truncated string", []byte{0x45, 0x01, 0x02}},
+		{"huge array", []byte{0x9a, 0xff, 0xff, 0xff, 0xff}},
+		{"indefinite length", []byte{0x5f, 0x41, 0x00, 0xff}},
+		{"duplicate map key", []byte{0xa2, 0x01, 0x00, 0x01, 0x00}},
+		{"array map key", []byte{0xa1, 0x80, 0x00}},
+		{"integer overflow", []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}},
+		{"nested too deeply", deep},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := decodeCBOR(tt.in); !errors.Is(err, errCBOR) {
+				t.Errorf("expected errCBOR, got %v", err)
+			}
+		})
+	}
+}
+
+func TestFloat16ToFloat64(t *testing.T) {
+	tests := []struct {
+		in   uint16
+		want float64
+	}{
+		{0x0000, 0},
+		{0x3c00, 1},
+		{0xc000, -2},
+		{0x7bff, 65504},
+		{0x0001, 5.960464477539063e-08},
+		{0x7c00, math.Inf(1)},
+		{0xfc00, math.Inf(-1)},
+	}
+	for _, tt := range tests {
+		if got := float16ToFloat64(tt.in); got != tt.want {
+			t.Errorf("float16ToFloat64(%#04x) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+	if got := float16ToFloat64(0x7e00); !math.IsNaN(got) {
+		t.Errorf("float16ToFloat64(0x7e00) = %v, want NaN", got)
+	}
+}
@@ -0,0 +1,120 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// COSE algorithm identifiers accepted for WebAuthn credentials, in order of
+// preference.
+const (
+	coseAlgES256 = -7
+	coseAlgEdDSA = -8
+	coseAlgRS256 = -257
+)
+
+// COSE key parameters (RFC 9052, RFC 9053).
+const (
+	coseKeyKty = 1
+	coseKeyAlg = 3
+	coseKeyCrv = -1 // EC2, OKP
+	coseKeyX   = -2 // EC2, OKP
+	coseKeyY   = -3 // EC2
+	coseKeyN   = -1 // RSA
+	coseKeyE   = -2 // RSA
+
+	coseKtyOKP = 1
+	coseKtyEC2 = 2
+	coseKtyRSA = 3
+
+	coseCrvP256    = 1
+	coseCrvEd25519 = 6
+)
+
+// errUnsupportedKey is returned for COSE keys with an algorithm or curve we
+// do not accept.
+var errUnsupportedKey = errors.New("unsupported credential public key")
+
+// coseKey is a parsed COSE_Key public key.
+type coseKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+// parseCOSEKey parses a CBOR-encoded COSE_Key holding an ES256, EdDSA
+// (Ed25519) or RS256 public key.
+func parseCOSEKey(data []byte) (*coseKey, error) {
+	v, rest, err := decodeCBOR(data)
+	if err != nil {
+		return nil, fmt.Errorf("decoding public key: %w", err)
+	}
+	if len(rest) != 0 {
+		return nil, fmt.Errorf("%w: trailing data", errUnsupportedKey)
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("%w: not a map", errUnsupportedKey)
+	}
+
+	kty, _ := m[int64(coseKeyKty)].(int64)
+	alg, _ := m[int64(coseKeyAlg)].(int64)
+
+	switch {
+	case kty == coseKtyEC2 && alg == coseAlgES256:
+		crv, _ := m[int64(coseKeyCrv)].(int64)
+		x, _ := m[int64(coseKeyX)].([]byte)
+		y, _ := m[int64(coseKeyY)].([]byte)
+		if crv != coseCrvP256 || len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("%w: bad P-256 key", errUnsupportedKey)
+		}
+		point := append([]byte{4}, append(x, y...)...)
+		pub, err := ecdsa.ParseUncompressedPublicKey(elliptic.P256(), point)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errUnsupportedKey, err)
+		}
+		return &coseKey{alg: alg, key: pub}, nil
+
+	case kty == coseKtyOKP && alg == coseAlgEdDSA:
+		crv, _ := m[int64(coseKeyCrv)].(int64)
+		x, _ := m[int64(coseKeyX)].([]byte)
+		if crv != coseCrvEd25519 || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("%w: bad Ed25519 key", errUnsupportedKey)
+		}
+		return &coseKey{alg: alg, key: ed25519.PublicKey(x)}, nil
+
+	case kty == coseKtyRSA && alg == coseAlgRS256:
+		n, _ := m[int64(coseKeyN)].([]byte)
+		e, _ := m[int64(coseKeyE)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("%w: bad RSA key", errUnsupportedKey)
+		}
+		exp := int(new(big.Int).SetBytes(e).Int64())
+		return &coseKey{alg: alg, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exp}}, nil
+
+	default:
+		return nil, fmt.Errorf("%w: key type %d, algorithm %d", errUnsupportedKey, kty, alg)
+	}
+}
+
+// verify checks sig over data with the key's algorithm.
+func (k *coseKey) verify(data, sig []byte) bool {
+	switch pub := k.key.(type) {
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return ecdsa.VerifyASN1(pub, digest[:], sig)
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, data, sig)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(data)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	default:
+		return false
+	}
+}
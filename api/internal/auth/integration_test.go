@@ -2,6 +2,7 @@ package auth_test
 
 import (
 	"context"
+	"errors"
 	"log"
 	"os"
 	"testing"
@@ -31,7 +32,7 @@ func TestMain(m *testing.M) {
 }
 
 func newSessionManager() *auth.SessionManager {
-	return auth.NewSessionManager(testDB.Pool, 0) // default 8h TTL
+	return auth.NewSessionManager(testDB.Pool, 0, 0) // default 8h TTL
 }
 
 func newService() *auth.Service {
@@ -78,7 +79,7 @@ func TestJWT_RefreshToken_RoundTrip(t *testing.T) {
 	mgr := auth.NewJWTManager("test-secret-minimum-length-32-chars")
 	id := uuid.New()
 
-	token, err := mgr.GenerateRefreshToken(id, "bob@example.com")
+	token, err := mgr.GenerateRefreshToken(id, "bob@example.com", 0)
 	if err != nil {
 		t.Fatalf("GenerateRefreshToken: %v", err)
 	}
@@ -212,12 +213,104 @@ func TestSessionManager_DeleteUserSessions(t *testing.T) {
 	}
 }
 
+func TestSessionManager_ListAndRevoke(t *testing.T) {
+	testDB.Truncate(t)
+	sm := newSessionManager()
+	ctx := context.Background()
+
+	svc := newService()
+	user := createTestUser(t, svc, "list@example.com", "password123!")
+	other := createTestUser(t, svc, "other@example.com", "password123!")
+
+	current, _ := sm.CreateSession(ctx, user.ID, "10.0.0.1", "Browser1")
+	sm.CreateSession(ctx, user.ID, "10.0.0.2", "Browser2")
+	sm.CreateSession(ctx, user.ID, "10.0.0.3", "Browser3")
+	otherToken, _ := sm.CreateSession(ctx, other.ID, "10.0.0.4", "Browser4")
+
+	sessions, err := sm.ListUserSessions(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("ListUserSessions: %v", err)
+	}
+	if len(sessions) != 3 {
+		t.Fatalf("expected 3 sessions, got %d", len(sessions))
+	}
+
+	// Another user's session cannot be revoked by public ID.
+	otherSess, _ := sm.GetSession(ctx, otherToken)
+	if err := sm.DeleteUserSession(ctx, user.ID, otherSess.PublicID); err != auth.ErrSessionNotFound {
+		t.Errorf("expected ErrSessionNotFound for another user's session, got %v", err)
+	}
+
+	var revoke *auth.Session
+	for _, s := range sessions {
+		if s.ID != current {
+			revoke = s
+			break
+		}
+	}
+	if err := sm.DeleteUserSession(ctx, user.ID, revoke.PublicID); err != nil {
+		t.Fatalf("DeleteUserSession: %v", err)
+	}
+	if _, err := sm.GetSession(ctx, revoke.ID); err != auth.ErrSessionNotFound {
+		t.Errorf("expected revoked session to be gone, got %v", err)
+	}
+
+	n, err := sm.DeleteOtherUserSessions(ctx, user.ID, current)
+	if err != nil {
+		t.Fatalf("DeleteOtherUserSessions: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("expected 1 other session revoked, got %d", n)
+	}
+	if _, err := sm.GetSession(ctx, current); err != nil {
+		t.Errorf("current session should survive: %v", err)
+	}
+	if _, err := sm.GetSession(ctx, otherToken); err != nil {
+		t.Errorf("other user's session should survive: %v", err)
+	}
+}
+
+func TestSessionManager_IdleTimeout(t *testing.T) {
+	testDB.Truncate(t)
+	ctx := context.Background()
+
+	sm := auth.NewSessionManager(testDB.Pool, time.Hour, 50*time.Millisecond)
+	svc := auth.NewService(testDB.Pool, sm, nil, "ForgeCommerce")
+	user := createTestUser(t, svc, "idle@example.com", "password123!")
+
+	token, err := sm.CreateSession(ctx, user.ID, "127.0.0.1", "IdleAgent")
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+
+	// Activity keeps the session alive past the idle timeout.
+	for i := 0; i < 3; i++ {
+		time.Sleep(30 * time.Millisecond)
+		if _, err := sm.GetSession(ctx, token); err != nil {
+			t.Fatalf("GetSession while active: %v", err)
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, err := sm.GetSession(ctx, token); err != auth.ErrSessionNotFound {
+		t.Errorf("expected ErrSessionNotFound after idle timeout, got %v", err)
+	}
+
+	cleaned, err := sm.CleanupExpired(ctx)
+	if err != nil {
+		t.Fatalf("CleanupExpired: %v", err)
+	}
+	if cleaned != 1 {
+		t.Errorf("expected idle session to be cleaned up, got %d", cleaned)
+	}
+}
+
 func TestSessionManager_CleanupExpired(t *testing.T) {
 	testDB.Truncate(t)
 	ctx := context.Background()
 
 	// Use a very short TTL so sessions expire immediately.
-	sm := auth.NewSessionManager(testDB.Pool, 1*time.Millisecond)
+	sm := auth.NewSessionManager(testDB.Pool, 1*time.Millisecond, 0)
 
 	svc := auth.NewService(testDB.Pool, sm, nil, "ForgeCommerce")
 	user := createTestUser(t, svc, "expire@example.com", "password123!")
@@ -771,3 +864,261 @@ func TestUseRecoveryCode_InvalidCode(t *testing.T) {
 		t.Errorf("expected ErrInvalidRecoveryCode, got %v", err)
 	}
 }
+
+// --------------------------------------------------------------------------
+// WebAuthn service tests
+// --------------------------------------------------------------------------
+
+func newWebAuthnService(svc *auth.Service) *auth.WebAuthnService {
+	return auth.NewWebAuthnService(svc, auth.RelyingParty{
+		ID:     "admin.example.com",
+		Name:   "ForgeCommerce",
+		Origin: "https://admin.example.com",
+	})
+}
+
+func TestWebAuthn_BeginRegistration(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	wa := newWebAuthnService(svc)
+	ctx := context.Background()
+
+	user := createTestUser(t, svc, "keys@forge.com", "pass123!")
+
+	opts, err := wa.BeginRegistration(ctx, user)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	if opts.RP.ID != "admin.example.com" {
+		t.Errorf("rp.id: got %q, want admin.example.com", opts.RP.ID)
+	}
+	if opts.User.Name != "keys@forge.com" {
+		t.Errorf("user.name: got %q, want keys@forge.com", opts.User.Name)
+	}
+	if opts.Challenge == "" {
+		t.Error("expected a challenge")
+	}
+	if len(opts.ExcludeCredentials) != 0 {
+		t.Errorf("expected no credentials to exclude, got %d", len(opts.ExcludeCredentials))
+	}
+}
+
+func TestWebAuthn_FinishRegistration_NoChallenge(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	wa := newWebAuthnService(svc)
+	ctx := context.Background()
+
+	user := createTestUser(t, svc, "nochallenge@forge.com", "pass123!")
+
+	_, err := wa.FinishRegistration(ctx, user.ID, "YubiKey", &auth.RegistrationResponse{})
+	if err != auth.ErrWebAuthnNoChallenge {
+		t.Errorf("expected ErrWebAuthnNoChallenge, got %v", err)
+	}
+}
+
+func TestWebAuthn_FinishRegistration_ChallengeUsedOnce(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	wa := newWebAuthnService(svc)
+	ctx := context.Background()
+
+	user := createTestUser(t, svc, "once@forge.com", "pass123!")
+	if _, err := wa.BeginRegistration(ctx, user); err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+
+	// A bad response still consumes the challenge.
+	_, err := wa.FinishRegistration(ctx, user.ID, "YubiKey", &auth.RegistrationResponse{})
+	if !errors.Is(err, auth.ErrWebAuthnVerification) {
+		t.Fatalf("expected ErrWebAuthnVerification, got %v", err)
+	}
+	_, err = wa.FinishRegistration(ctx, user.ID, "YubiKey", &auth.RegistrationResponse{})
+	if err != auth.ErrWebAuthnNoChallenge {
+		t.Errorf("expected ErrWebAuthnNoChallenge on reuse, got %v", err)
+	}
+}
+
+func TestWebAuthn_BeginLogin_NoKeys(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	wa := newWebAuthnService(svc)
+	ctx := context.Background()
+
+	user := createTestUser(t, svc, "nokeys@forge.com", "pass123!")
+
+	has, err := wa.HasCredentials(ctx, user.ID)
+	if err != nil {
+		t.Fatalf("HasCredentials: %v", err)
+	}
+	if has {
+		t.Error("expected no security keys")
+	}
+	if _, err := wa.BeginLogin(ctx, user.ID); err != auth.ErrWebAuthnNotSetup {
+		t.Errorf("expected ErrWebAuthnNotSetup, got %v", err)
+	}
+}
+
+func TestWebAuthn_DeleteCredential_NotFound(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	wa := newWebAuthnService(svc)
+	ctx := context.Background()
+
+	user := createTestUser(t, svc, "delkey@forge.com", "pass123!")
+
+	if err := wa.DeleteCredential(ctx, user.ID, uuid.New()); err != auth.ErrWebAuthnCredentialNotFound {
+		t.Errorf("expected ErrWebAuthnCredentialNotFound, got %v", err)
+	}
+}
+
+// --------------------------------------------------------------------------
+// API key service tests
+// --------------------------------------------------------------------------
+
+// createOwner creates an active owner, who holds every permission.
+func createOwner(t *testing.T, svc *auth.Service, email string) *auth.AdminUser {
+	t.Helper()
+	user, err := svc.CreateUser(context.Background(), email, "Owner", "pass123!", auth.RoleOwner, nil)
+	if err != nil {
+		t.Fatalf("creating owner: %v", err)
+	}
+	return user
+}
+
+func TestAPIKey_CreateAndAuthenticate(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	keys := auth.NewAPIKeyService(svc)
+	ctx := context.Background()
+
+	owner := createOwner(t, svc, "apikeys@forge.com")
+
+	key, token, err := keys.Create(ctx, owner, "ERP", []string{auth.PermOrdersRead, auth.PermInventoryWrite}, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if len(token) <= len(key.Prefix) || token[:len(key.Prefix)] != key.Prefix {
+		t.Errorf("prefix %q is not the start of the key", key.Prefix)
+	}
+
+	got, err := keys.Authenticate(ctx, token, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if got.ID != key.ID || got.CreatedBy != owner.ID {
+		t.Errorf("authenticated key: got %s by %s, want %s by %s", got.ID, got.CreatedBy, key.ID, owner.ID)
+	}
+	if !got.HasScope(auth.PermOrdersRead) || !got.HasScope(auth.PermInventoryWrite) || got.HasScope(auth.PermOrdersWrite) {
+		t.Errorf("unexpected scopes %v", got.Scopes)
+	}
+
+	stored, err := keys.Get(ctx, key.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if stored.LastUsedAt == nil || stored.LastUsedIP != "10.0.0.1" {
+		t.Errorf("expected last use to be recorded, got %v from %q", stored.LastUsedAt, stored.LastUsedIP)
+	}
+
+	if _, err := keys.Authenticate(ctx, token+"x", "10.0.0.1"); err != auth.ErrInvalidAPIKey {
+		t.Errorf("wrong key: expected ErrInvalidAPIKey, got %v", err)
+	}
+}
+
+func TestAPIKey_Create_Validation(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	keys := auth.NewAPIKeyService(svc)
+	ctx := context.Background()
+
+	owner := createOwner(t, svc, "validate@forge.com")
+	warehouse, err := svc.CreateUser(ctx, "warehouse@forge.com", "Warehouse", "pass123!", auth.RoleWarehouse, nil)
+	if err != nil {
+		t.Fatalf("creating warehouse user: %v", err)
+	}
+	past := time.Now().Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		creator *auth.AdminUser
+		keyName string
+		scopes  []string
+		expires *time.Time
+		wantErr error
+	}{
+		{"empty name", owner, " ", []string{auth.PermOrdersRead}, nil, auth.ErrInvalidAPIKeyName},
+		{"no scopes", owner, "ERP", nil, nil, auth.ErrInvalidAPIKeyScopes},
+		{"not an API scope", owner, "ERP", []string{auth.PermUsersManage}, nil, auth.ErrInvalidAPIKeyScopes},
+		{"scope creator lacks", warehouse, "ERP", []string{auth.PermProductsWrite}, nil, auth.ErrInvalidAPIKeyScopes},
+		{"expiry in the past", owner, "ERP", []string{auth.PermOrdersRead}, &past, auth.ErrInvalidAPIKeyExpiry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := keys.Create(ctx, tt.creator, tt.keyName, tt.scopes, tt.expires); err != tt.wantErr {
+				t.Errorf("expected %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestAPIKey_Revoke(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	keys := auth.NewAPIKeyService(svc)
+	ctx := context.Background()
+
+	owner := createOwner(t, svc, "revoke@forge.com")
+	key, token, err := keys.Create(ctx, owner, "Warehouse script", []string{auth.PermInventoryRead}, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	revoked, err := keys.Revoke(ctx, key.ID)
+	if err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if revoked.RevokedAt == nil {
+		t.Error("expected revoked_at to be set")
+	}
+	if _, err := keys.Authenticate(ctx, token, "10.0.0.1"); err != auth.ErrInvalidAPIKey {
+		t.Errorf("revoked key: expected ErrInvalidAPIKey, got %v", err)
+	}
+	if _, err := keys.Revoke(ctx, uuid.New()); err != auth.ErrAPIKeyNotFound {
+		t.Errorf("unknown key: expected ErrAPIKeyNotFound, got %v", err)
+	}
+}
+
+func TestAPIKey_Authenticate_FollowsCreator(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	keys := auth.NewAPIKeyService(svc)
+	ctx := context.Background()
+
+	owner := createOwner(t, svc, "creator@forge.com")
+	_, token, err := keys.Create(ctx, owner, "ERP", []string{auth.PermProductsWrite, auth.PermOrdersRead}, nil)
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// Demoted to accountant: products.write is no longer granted.
+	if _, err := svc.UpdateUser(ctx, owner.ID, owner.Name, auth.RoleAccountant, nil, true); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	key, err := keys.Authenticate(ctx, token, "10.0.0.1")
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if key.HasScope(auth.PermProductsWrite) || !key.HasScope(auth.PermOrdersRead) {
+		t.Errorf("expected scopes narrowed to [orders.read], got %v", key.Scopes)
+	}
+
+	// Deactivated: the key stops working.
+	if err := svc.SetUserActive(ctx, owner.ID, false); err != nil {
+		t.Fatalf("SetUserActive: %v", err)
+	}
+	if _, err := keys.Authenticate(ctx, token, "10.0.0.1"); err != auth.ErrInvalidAPIKey {
+		t.Errorf("inactive creator: expected ErrInvalidAPIKey, got %v", err)
+	}
+}
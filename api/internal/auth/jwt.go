@@ -1,6 +1,8 @@
 package auth
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"time"
@@ -16,30 +18,61 @@ var (
 	ErrInvalidPassword = errors.New("invalid email or password")
 )
 
-// CustomerClaims holds the JWT claims for a customer token.
+// CustomerClaims holds the JWT claims for a customer token. TokenVersion is
+// set on refresh tokens to the customer's token version when it was issued;
+// changing the password bumps the version and so invalidates the token.
 type CustomerClaims struct {
-	CustomerID uuid.UUID `json:"customer_id"`
-	Email      string    `json:"email"`
+	CustomerID   uuid.UUID `json:"customer_id"`
+	Email        string    `json:"email"`
+	TokenVersion int32     `json:"token_version,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// OrderAccessClaims holds the JWT claims for a guest order access token.
+type OrderAccessClaims struct {
+	OrderID     uuid.UUID `json:"order_id"`
+	OrderNumber int64     `json:"order_number"`
+	jwt.RegisteredClaims
+}
+
+// CartRestoreClaims holds the JWT claims for an abandoned cart restore link.
+type CartRestoreClaims struct {
+	CartID uuid.UUID `json:"cart_id"`
 	jwt.RegisteredClaims
 }
 
 // JWTManager handles creation and validation of customer JWTs.
 type JWTManager struct {
-	secret        []byte
-	accessExpiry  time.Duration
-	refreshExpiry time.Duration
+	secret            []byte
+	orderSecret       []byte
+	cartSecret        []byte
+	accessExpiry      time.Duration
+	refreshExpiry     time.Duration
+	orderAccessExpiry time.Duration
 }
 
 // NewJWTManager creates a new JWT manager with the given secret.
-// Access tokens expire in 15 minutes; refresh tokens in 7 days.
+// Access tokens expire in 15 minutes; refresh tokens in 7 days; order access
+// tokens in 24 hours. Order access and cart restore tokens are signed with
+// keys derived from the secret, so no kind of token can be used as another.
 func NewJWTManager(secret string) *JWTManager {
 	return &JWTManager{
-		secret:        []byte(secret),
-		accessExpiry:  15 * time.Minute,
-		refreshExpiry: 7 * 24 * time.Hour,
+		secret:            []byte(secret),
+		orderSecret:       deriveKey(secret, "order-access"),
+		cartSecret:        deriveKey(secret, "cart-restore"),
+		accessExpiry:      15 * time.Minute,
+		refreshExpiry:     7 * 24 * time.Hour,
+		orderAccessExpiry: 24 * time.Hour,
 	}
 }
 
+// deriveKey returns the HMAC-SHA256 of purpose keyed with secret.
+func deriveKey(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
 // GenerateAccessToken creates a short-lived access token for the customer.
 func (m *JWTManager) GenerateAccessToken(customerID uuid.UUID, email string) (string, error) {
 	now := time.Now().UTC()
@@ -62,12 +95,14 @@ func (m *JWTManager) GenerateAccessToken(customerID uuid.UUID, email string) (st
 	return signed, nil
 }
 
-// GenerateRefreshToken creates a long-lived refresh token for the customer.
-func (m *JWTManager) GenerateRefreshToken(customerID uuid.UUID, email string) (string, error) {
+// GenerateRefreshToken creates a long-lived refresh token for the customer,
+// bound to their current token version.
+func (m *JWTManager) GenerateRefreshToken(customerID uuid.UUID, email string, tokenVersion int32) (string, error) {
 	now := time.Now().UTC()
 	claims := CustomerClaims{
-		CustomerID: customerID,
-		Email:      email,
+		CustomerID:   customerID,
+		Email:        email,
+		TokenVersion: tokenVersion,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   customerID.String(),
 			IssuedAt:  jwt.NewNumericDate(now),
@@ -106,3 +141,92 @@ func (m *JWTManager) ValidateToken(tokenStr string) (*CustomerClaims, error) {
 
 	return claims, nil
 }
+
+// GenerateOrderAccessToken creates a token that grants read access to a
+// single order, for guests who looked it up by order number and email. It
+// returns the token and its expiry time.
+func (m *JWTManager) GenerateOrderAccessToken(orderID uuid.UUID, orderNumber int64) (string, time.Time, error) {
+	now := time.Now().UTC()
+	expiresAt := now.Add(m.orderAccessExpiry)
+	claims := OrderAccessClaims{
+		OrderID:     orderID,
+		OrderNumber: orderNumber,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   orderID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			Issuer:    "forgecommerce",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(m.orderSecret)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("signing order access token: %w", err)
+	}
+	return signed, expiresAt, nil
+}
+
+// ValidateOrderAccessToken parses and validates an order access token.
+// Returns the claims if valid, or ErrInvalidToken if not.
+func (m *JWTManager) ValidateOrderAccessToken(tokenStr string) (*OrderAccessClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &OrderAccessClaims{}, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return m.orderSecret, nil
+	})
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(*OrderAccessClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
+
+// GenerateCartRestoreToken creates a token for the restore link in an
+// abandoned cart email. It is valid until expiresAt, normally the cart's own
+// expiry.
+func (m *JWTManager) GenerateCartRestoreToken(cartID uuid.UUID, expiresAt time.Time) (string, error) {
+	claims := CartRestoreClaims{
+		CartID: cartID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   cartID.String(),
+			IssuedAt:  jwt.NewNumericDate(time.Now().UTC()),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			Issuer:    "forgecommerce",
+		},
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	signed, err := token.SignedString(m.cartSecret)
+	if err != nil {
+		return "", fmt.Errorf("signing cart restore token: %w", err)
+	}
+	return signed, nil
+}
+
+// ValidateCartRestoreToken parses and validates a cart restore token.
+// Returns the claims if valid, or ErrInvalidToken if not.
+func (m *JWTManager) ValidateCartRestoreToken(tokenStr string) (*CartRestoreClaims, error) {
+	token, err := jwt.ParseWithClaims(tokenStr, &CartRestoreClaims{}, func(token *jwt.Token) (any, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return m.cartSecret, nil
+	})
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(*CartRestoreClaims)
+	if !ok || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+
+	return claims, nil
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const testSecret = "test-secret-key-for-jwt-tests-minimum-length"
+
+func TestOrderAccessToken_RoundTrip(t *testing.T) {
+	m := NewJWTManager(testSecret)
+	orderID := uuid.New()
+
+	token, expiresAt, err := m.GenerateOrderAccessToken(orderID, 1042)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if d := time.Until(expiresAt); d < 23*time.Hour || d > 25*time.Hour {
+		t.Errorf("expected expiry in about 24 hours, got %v", d)
+	}
+
+	claims, err := m.ValidateOrderAccessToken(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims.OrderID != orderID {
+		t.Errorf("order ID: got %s, want %s", claims.OrderID, orderID)
+	}
+	if claims.OrderNumber != 1042 {
+		t.Errorf("order number: got %d, want %d", claims.OrderNumber, 1042)
+	}
+}
+
+func TestOrderAccessToken_Expired(t *testing.T) {
+	m := NewJWTManager(testSecret)
+	m.orderAccessExpiry = -time.Minute
+
+	token, _, err := m.GenerateOrderAccessToken(uuid.New(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.ValidateOrderAccessToken(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestOrderAccessToken_WrongSecret(t *testing.T) {
+	token, _, err := NewJWTManager(testSecret).GenerateOrderAccessToken(uuid.New(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := NewJWTManager("another-secret").ValidateOrderAccessToken(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+}
+
+func TestOrderAccessToken_NotInterchangeableWithCustomerTokens(t *testing.T) {
+	m := NewJWTManager(testSecret)
+
+	orderToken, _, err := m.GenerateOrderAccessToken(uuid.New(), 1)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.ValidateToken(orderToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("order token accepted as customer token: %v", err)
+	}
+
+	customerToken, err := m.GenerateAccessToken(uuid.New(), "customer@example.com")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.ValidateOrderAccessToken(customerToken); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("customer token accepted as order token: %v", err)
+	}
+}
+
+func TestCartRestoreToken_RoundTrip(t *testing.T) {
+	m := NewJWTManager(testSecret)
+	cartID := uuid.New()
+
+	token, err := m.GenerateCartRestoreToken(cartID, time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	claims, err := m.ValidateCartRestoreToken(token)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if claims.CartID != cartID {
+		t.Errorf("cart ID: got %s, want %s", claims.CartID, cartID)
+	}
+
+	if _, err := m.ValidateOrderAccessToken(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("cart restore token accepted as order token: %v", err)
+	}
+}
+
+func TestCartRestoreToken_Expired(t *testing.T) {
+	m := NewJWTManager(testSecret)
+
+	token, err := m.GenerateCartRestoreToken(uuid.New(), time.Now().Add(-time.Minute))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := m.ValidateCartRestoreToken(token); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("expected ErrInvalidToken, got %v", err)
+	}
+}
@@ -0,0 +1,163 @@
+package auth
+
+import (
+	"context"
+	"slices"
+)
+
+// Permissions guard what an admin user may see and do. A user has the
+// permissions of their role plus any granted to them individually.
+const (
+	PermProductsRead     = "products.read"
+	PermProductsWrite    = "products.write"
+	PermInventoryRead    = "inventory.read"
+	PermInventoryWrite   = "inventory.write"
+	PermOrdersRead       = "orders.read"
+	PermOrdersWrite      = "orders.write"
+	PermOrdersRefund     = "orders.refund"
+	PermDiscountsManage  = "discounts.manage"
+	PermReportsRead      = "reports.read"
+	PermSettingsVAT      = "settings.vat"
+	PermSettingsShipping = "settings.shipping"
+	PermWebhooksManage   = "webhooks.manage"
+	PermUsersManage      = "users.manage"
+	PermAuditRead        = "audit.read"
+	PermAPIKeysManage    = "api_keys.manage"
+)
+
+// PermissionInfo describes a permission for the user management screens.
+type PermissionInfo struct {
+	Name        string
+	Description string
+}
+
+// AllPermissions is the permission catalogue, in display order.
+var AllPermissions = []PermissionInfo{
+	{PermProductsRead, "View products, variants and bills of materials"},
+	{PermProductsWrite, "Create and edit products, categories, attributes and images"},
+	{PermInventoryRead, "View raw materials, stock and production"},
+	{PermInventoryWrite, "Adjust stock, edit raw materials and run production"},
+	{PermOrdersRead, "View orders and download invoices"},
+	{PermOrdersWrite, "Update order status and tracking, and issue invoices"},
+	{PermOrdersRefund, "Refund orders"},
+	{PermDiscountsManage, "Manage discounts, coupons and campaigns"},
+	{PermReportsRead, "View and export sales and VAT reports"},
+	{PermSettingsVAT, "Change VAT settings, invoice details and selling countries"},
+	{PermSettingsShipping, "Change shipping settings"},
+	{PermWebhooksManage, "Manage webhooks"},
+	{PermUsersManage, "Manage admin users and their roles"},
+	{PermAuditRead, "View and export the audit log"},
+	{PermAPIKeysManage, "Issue and revoke API keys"},
+}
+
+// Roles.
+const (
+	RoleOwner      = "owner"
+	RoleManager    = "manager"
+	RoleWarehouse  = "warehouse"
+	RoleAccountant = "accountant"
+)
+
+// RoleInfo describes a predefined role and the permissions it grants.
+type RoleInfo struct {
+	Name        string
+	Label       string
+	Permissions []string
+}
+
+// Roles are the predefined roles, in display order. The owner has every
+// permission.
+var Roles = []RoleInfo{
+	{RoleOwner, "Owner", permissionNames()},
+	{RoleManager, "Manager", []string{
+		PermProductsRead, PermProductsWrite,
+		PermInventoryRead, PermInventoryWrite,
+		PermOrdersRead, PermOrdersWrite, PermOrdersRefund,
+		PermDiscountsManage,
+		PermReportsRead,
+		PermSettingsShipping,
+	}},
+	{RoleWarehouse, "Warehouse", []string{
+		PermProductsRead,
+		PermInventoryRead, PermInventoryWrite,
+		PermOrdersRead, PermOrdersWrite,
+	}},
+	{RoleAccountant, "Accountant", []string{
+		PermOrdersRead, PermOrdersRefund,
+		PermReportsRead,
+		PermSettingsVAT,
+	}},
+}
+
+func permissionNames() []string {
+	names := make([]string, len(AllPermissions))
+	for i, p := range AllPermissions {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// IsValidRole reports whether role is one of the predefined roles.
+func IsValidRole(role string) bool {
+	_, ok := findRole(role)
+	return ok
+}
+
+// IsValidPermission reports whether perm is in the catalogue.
+func IsValidPermission(perm string) bool {
+	return slices.Contains(permissionNames(), perm)
+}
+
+// RolePermissions returns the permissions granted by role.
+func RolePermissions(role string) []string {
+	r, _ := findRole(role)
+	return r.Permissions
+}
+
+// RoleLabel returns the display name of role, or role itself if it is not
+// one of the predefined roles.
+func RoleLabel(role string) string {
+	if r, ok := findRole(role); ok {
+		return r.Label
+	}
+	return role
+}
+
+func findRole(role string) (RoleInfo, bool) {
+	for _, r := range Roles {
+		if r.Name == role {
+			return r, true
+		}
+	}
+	return RoleInfo{}, false
+}
+
+// Can reports whether the user has perm, through their role or an
+// individual grant.
+func (u *AdminUser) Can(perm string) bool {
+	if u == nil {
+		return false
+	}
+	return slices.Contains(RolePermissions(u.Role), perm) || slices.Contains(u.Permissions, perm)
+}
+
+type userContextKey struct{}
+
+// ContextWithUser returns a copy of ctx carrying the signed-in admin user.
+func ContextWithUser(ctx context.Context, u *AdminUser) context.Context {
+	return context.WithValue(ctx, userContextKey{}, u)
+}
+
+// UserFromContext returns the signed-in admin user stored by
+// ContextWithUser.
+func UserFromContext(ctx context.Context) (*AdminUser, bool) {
+	u, ok := ctx.Value(userContextKey{}).(*AdminUser)
+	return u, ok && u != nil
+}
+
+// Can reports whether the admin user signed in on ctx has perm. Templates
+// use it to hide actions the user cannot perform.
+func Can(ctx context.Context, perm string) bool {
+	u, _ := UserFromContext(ctx)
+	return u.Can(perm)
+}
@@ -402,6 +402,33 @@ func (s *Service) ValidateSession(ctx context.Context, token string) (*AdminUser
 	return user, sess, nil
 }
 
+// SessionTTL returns the absolute lifetime of an admin session.
+func (s *Service) SessionTTL() time.Duration {
+	return s.session.SessionTTL()
+}
+
+// ListSessions returns the active sessions of an admin user, most recently
+// used first.
+func (s *Service) ListSessions(ctx context.Context, userID uuid.UUID) ([]*Session, error) {
+	return s.session.ListUserSessions(ctx, userID)
+}
+
+// RevokeSession ends one of the user's sessions, identified by its public ID.
+func (s *Service) RevokeSession(ctx context.Context, userID, publicID uuid.UUID) error {
+	return s.session.DeleteUserSession(ctx, userID, publicID)
+}
+
+// RevokeOtherSessions ends every session of the user except the one
+// identified by currentToken, and returns how many were ended.
+func (s *Service) RevokeOtherSessions(ctx context.Context, userID uuid.UUID, currentToken string) (int64, error) {
+	return s.session.DeleteOtherUserSessions(ctx, userID, currentToken)
+}
+
+// ForceLogout ends every session of the user.
+func (s *Service) ForceLogout(ctx context.Context, userID uuid.UUID) error {
+	return s.session.DeleteUserSessions(ctx, userID)
+}
+
 // ListUsers returns all admin users ordered by creation date.
 func (s *Service) ListUsers(ctx context.Context) ([]*AdminUser, error) {
 	rows, err := s.pool.Query(ctx, `
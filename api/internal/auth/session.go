@@ -25,33 +25,46 @@ var (
 
 // Session represents an active admin session.
 type Session struct {
-	ID          string // session token (text PK)
+	ID          string    // session token (text PK)
+	PublicID    uuid.UUID // identifies the session in the UI without revealing the token
 	AdminUserID uuid.UUID
 	IPAddress   string
 	UserAgent   string
 	CreatedAt   time.Time
+	LastSeenAt  time.Time
 	ExpiresAt   time.Time
 }
 
 // SessionManager manages admin sessions in PostgreSQL.
+//
+// A session ends at its absolute expiry, sessionTTL after it was created,
+// or earlier if it is not used for idleTimeout.
 type SessionManager struct {
-	pool       *pgxpool.Pool
-	sessionTTL time.Duration
+	pool        *pgxpool.Pool
+	sessionTTL  time.Duration
+	idleTimeout time.Duration
 }
 
 // NewSessionManager creates a new session manager with the given connection pool.
-// If sessionTTL is 0, it defaults to 8 hours.
-func NewSessionManager(pool *pgxpool.Pool, sessionTTL time.Duration) *SessionManager {
+// If sessionTTL is 0, it defaults to 8 hours. If idleTimeout is 0, sessions
+// only end at their absolute expiry.
+func NewSessionManager(pool *pgxpool.Pool, sessionTTL, idleTimeout time.Duration) *SessionManager {
 	if sessionTTL == 0 {
 		sessionTTL = defaultSessionTTL
 	}
 
 	return &SessionManager{
-		pool:       pool,
-		sessionTTL: sessionTTL,
+		pool:        pool,
+		sessionTTL:  sessionTTL,
+		idleTimeout: idleTimeout,
 	}
 }
 
+// SessionTTL returns the absolute lifetime of a session.
+func (sm *SessionManager) SessionTTL() time.Duration {
+	return sm.sessionTTL
+}
+
 // CreateSession creates a new session for an admin user.
 // Generates a cryptographically random 32-byte token (hex encoded = 64 chars).
 // The token is stored as the primary key in the sessions table.
@@ -62,12 +75,13 @@ func (sm *SessionManager) CreateSession(ctx context.Context, adminUserID uuid.UU
 		return "", fmt.Errorf("generating session token: %w", err)
 	}
 
-	expiresAt := time.Now().UTC().Add(sm.sessionTTL)
+	now := time.Now().UTC()
+	expiresAt := now.Add(sm.sessionTTL)
 
 	_, err = sm.pool.Exec(ctx, `
-		INSERT INTO sessions (id, admin_user_id, ip_address, user_agent, created_at, expires_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`, token, adminUserID, ipAddress, userAgent, time.Now().UTC(), expiresAt)
+		INSERT INTO sessions (id, admin_user_id, ip_address, user_agent, created_at, last_seen_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $5, $6)
+	`, token, adminUserID, ipAddress, userAgent, now, expiresAt)
 	if err != nil {
 		return "", fmt.Errorf("inserting session: %w", err)
 	}
@@ -76,22 +90,16 @@ func (sm *SessionManager) CreateSession(ctx context.Context, adminUserID uuid.UU
 }
 
 // GetSession retrieves a session by token.
-// Returns the session data if the token is valid and not expired.
-// Extends expiration (sliding window) on each valid access.
+// Returns the session data if the token is valid, not expired and not idle.
+// Records the access so the idle timeout restarts (sliding window); the
+// absolute expiry does not move.
 func (sm *SessionManager) GetSession(ctx context.Context, token string) (*Session, error) {
 	session := &Session{}
 	err := sm.pool.QueryRow(ctx, `
-		SELECT id, admin_user_id, ip_address, user_agent, created_at, expires_at
+		SELECT `+sessionColumns+`
 		FROM sessions
-		WHERE id = $1 AND expires_at > NOW()
-	`, token).Scan(
-		&session.ID,
-		&session.AdminUserID,
-		&session.IPAddress,
-		&session.UserAgent,
-		&session.CreatedAt,
-		&session.ExpiresAt,
-	)
+		WHERE id = $1 AND expires_at > NOW() AND last_seen_at > $2
+	`, token, sm.idleCutoff()).Scan(sessionFields(session)...)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, ErrSessionNotFound
@@ -99,20 +107,48 @@ func (sm *SessionManager) GetSession(ctx context.Context, token string) (*Sessio
 		return nil, fmt.Errorf("querying session: %w", err)
 	}
 
-	// Sliding window: extend expiration on valid access.
-	newExpiry := time.Now().UTC().Add(sm.sessionTTL)
+	now := time.Now().UTC()
 	_, err = sm.pool.Exec(ctx, `
-		UPDATE sessions SET expires_at = $1 WHERE id = $2
-	`, newExpiry, session.ID)
+		UPDATE sessions SET last_seen_at = $1 WHERE id = $2
+	`, now, session.ID)
 	if err != nil {
-		// Log but don't fail the request if we can't extend the session.
+		// Don't fail the request if we can't record the access.
 		return session, nil
 	}
 
-	session.ExpiresAt = newExpiry
+	session.LastSeenAt = now
 	return session, nil
 }
 
+// ListUserSessions returns the active sessions of a user, most recently
+// used first.
+func (sm *SessionManager) ListUserSessions(ctx context.Context, adminUserID uuid.UUID) ([]*Session, error) {
+	rows, err := sm.pool.Query(ctx, `
+		SELECT `+sessionColumns+`
+		FROM sessions
+		WHERE admin_user_id = $1 AND expires_at > NOW() AND last_seen_at > $2
+		ORDER BY last_seen_at DESC
+	`, adminUserID, sm.idleCutoff())
+	if err != nil {
+		return nil, fmt.Errorf("listing user sessions: %w", err)
+	}
+	defer rows.Close()
+
+	var sessions []*Session
+	for rows.Next() {
+		session := &Session{}
+		if err := rows.Scan(sessionFields(session)...); err != nil {
+			return nil, fmt.Errorf("scanning session: %w", err)
+		}
+		sessions = append(sessions, session)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating sessions: %w", err)
+	}
+
+	return sessions, nil
+}
+
 // DeleteSession removes a session by token (logout).
 func (sm *SessionManager) DeleteSession(ctx context.Context, token string) error {
 	result, err := sm.pool.Exec(ctx, `
@@ -129,6 +165,36 @@ func (sm *SessionManager) DeleteSession(ctx context.Context, token string) error
 	return nil
 }
 
+// DeleteUserSession removes one of a user's sessions by its public ID.
+// Returns ErrSessionNotFound if the user has no such session.
+func (sm *SessionManager) DeleteUserSession(ctx context.Context, adminUserID, publicID uuid.UUID) error {
+	result, err := sm.pool.Exec(ctx, `
+		DELETE FROM sessions WHERE admin_user_id = $1 AND public_id = $2
+	`, adminUserID, publicID)
+	if err != nil {
+		return fmt.Errorf("deleting session: %w", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+
+	return nil
+}
+
+// DeleteOtherUserSessions removes all of a user's sessions except the one
+// identified by token, and returns how many were removed.
+func (sm *SessionManager) DeleteOtherUserSessions(ctx context.Context, adminUserID uuid.UUID, token string) (int64, error) {
+	result, err := sm.pool.Exec(ctx, `
+		DELETE FROM sessions WHERE admin_user_id = $1 AND id <> $2
+	`, adminUserID, token)
+	if err != nil {
+		return 0, fmt.Errorf("deleting other user sessions: %w", err)
+	}
+
+	return result.RowsAffected(), nil
+}
+
 // DeleteUserSessions removes all sessions for a user (force logout everywhere).
 func (sm *SessionManager) DeleteUserSessions(ctx context.Context, adminUserID uuid.UUID) error {
 	_, err := sm.pool.Exec(ctx, `
@@ -141,11 +207,11 @@ func (sm *SessionManager) DeleteUserSessions(ctx context.Context, adminUserID uu
 	return nil
 }
 
-// CleanupExpired removes all expired sessions. Should be called periodically.
+// CleanupExpired removes all expired and idle sessions. Should be called periodically.
 func (sm *SessionManager) CleanupExpired(ctx context.Context) (int64, error) {
 	result, err := sm.pool.Exec(ctx, `
-		DELETE FROM sessions WHERE expires_at <= NOW()
-	`)
+		DELETE FROM sessions WHERE expires_at <= NOW() OR last_seen_at <= $1
+	`, sm.idleCutoff())
 	if err != nil {
 		return 0, fmt.Errorf("cleaning up expired sessions: %w", err)
 	}
@@ -153,6 +219,31 @@ func (sm *SessionManager) CleanupExpired(ctx context.Context) (int64, error) {
 	return result.RowsAffected(), nil
 }
 
+const sessionColumns = `id, public_id, admin_user_id, ip_address, user_agent, created_at, last_seen_at, expires_at`
+
+// sessionFields returns scan destinations matching sessionColumns.
+func sessionFields(s *Session) []any {
+	return []any{
+		&s.ID,
+		&s.PublicID,
+		&s.AdminUserID,
+		&s.IPAddress,
+		&s.UserAgent,
+		&s.CreatedAt,
+		&s.LastSeenAt,
+		&s.ExpiresAt,
+	}
+}
+
+// idleCutoff returns the time before which an unused session counts as
+// idle. With no idle timeout it is the zero time, so no session is idle.
+func (sm *SessionManager) idleCutoff() time.Time {
+	if sm.idleTimeout <= 0 {
+		return time.Time{}
+	}
+	return time.Now().UTC().Add(-sm.idleTimeout)
+}
+
 // generateToken generates a cryptographically secure random hex token.
 func generateToken() (string, error) {
 	bytes := make([]byte, tokenBytes)
@@ -0,0 +1,479 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+var (
+	// ErrWebAuthnCredentialNotFound is returned when a security key is not
+	// registered to the user.
+	ErrWebAuthnCredentialNotFound = errors.New("security key not found")
+
+	// ErrWebAuthnNotSetup is returned when a user has no security keys.
+	ErrWebAuthnNotSetup = errors.New("no security keys registered")
+
+	// ErrWebAuthnNoChallenge is returned when a response arrives without an
+	// outstanding, unexpired challenge for the user.
+	ErrWebAuthnNoChallenge = errors.New("security key request expired; please try again")
+
+	// ErrWebAuthnCredentialExists is returned when registering a security key
+	// that is already registered.
+	ErrWebAuthnCredentialExists = errors.New("security key is already registered")
+
+	// ErrInvalidCredentialName is returned for security key names that are
+	// too long.
+	ErrInvalidCredentialName = errors.New("security key name must be at most 64 characters")
+)
+
+const (
+	// webauthnTimeout is how long the browser and server wait for the
+	// user to touch their key.
+	webauthnTimeout = 5 * time.Minute
+
+	// maxCredentialNameLen is the longest security key name accepted.
+	maxCredentialNameLen = 64
+
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+)
+
+// WebAuthnCredential is a security key or passkey registered to an admin
+// user.
+type WebAuthnCredential struct {
+	ID           uuid.UUID
+	AdminUserID  uuid.UUID
+	Name         string
+	CredentialID []byte
+	PublicKey    []byte
+	SignCount    uint32
+	Transports   []string
+	CreatedAt    time.Time
+	LastUsedAt   *time.Time
+}
+
+// CredentialDescriptor identifies a credential to the browser.
+type CredentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"` // base64url
+	Transports []string `json:"transports,omitempty"`
+}
+
+// CredentialParameter names a key type and COSE algorithm the server
+// accepts.
+type CredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// CredentialCreationOptions are passed to navigator.credentials.create.
+// Binary fields are base64url-encoded; the browser script decodes them.
+type CredentialCreationOptions struct {
+	Challenge string `json:"challenge"`
+	RP        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	PubKeyCredParams       []CredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []CredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection struct {
+		ResidentKey      string `json:"residentKey"`
+		UserVerification string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+	Attestation string `json:"attestation"`
+}
+
+// CredentialRequestOptions are passed to navigator.credentials.get.
+type CredentialRequestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []CredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// RegistrationResponse is the browser's PublicKeyCredential from
+// navigator.credentials.create, with binary fields base64url-encoded.
+type RegistrationResponse struct {
+	ID       string `json:"id"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+	} `json:"response"`
+}
+
+// AssertionResponse is the browser's PublicKeyCredential from
+// navigator.credentials.get, with binary fields base64url-encoded.
+type AssertionResponse struct {
+	ID       string `json:"id"`
+	Response struct {
+		ClientDataJSON    string `json:"clientDataJSON"`
+		AuthenticatorData string `json:"authenticatorData"`
+		Signature         string `json:"signature"`
+	} `json:"response"`
+}
+
+// WebAuthnService registers security keys and uses them as a second factor
+// at login, alongside TOTP.
+type WebAuthnService struct {
+	auth   *Service
+	rp     RelyingParty
+	logger *slog.Logger
+}
+
+// NewWebAuthnService creates a new WebAuthn service.
+func NewWebAuthnService(authService *Service, rp RelyingParty) *WebAuthnService {
+	return &WebAuthnService{
+		auth:   authService,
+		rp:     rp,
+		logger: authService.logger,
+	}
+}
+
+// ListCredentials returns the user's security keys, oldest first.
+func (s *WebAuthnService) ListCredentials(ctx context.Context, userID uuid.UUID) ([]*WebAuthnCredential, error) {
+	rows, err := s.auth.pool.Query(ctx, `
+		SELECT id, admin_user_id, name, credential_id, public_key, sign_count,
+		       transports, created_at, last_used_at
+		FROM admin_webauthn_credentials
+		WHERE admin_user_id = $1
+		ORDER BY created_at
+	`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("listing security keys: %w", err)
+	}
+	defer rows.Close()
+
+	var creds []*WebAuthnCredential
+	for rows.Next() {
+		c := &WebAuthnCredential{}
+		var signCount int64
+		if err := rows.Scan(&c.ID, &c.AdminUserID, &c.Name, &c.CredentialID, &c.PublicKey,
+			&signCount, &c.Transports, &c.CreatedAt, &c.LastUsedAt); err != nil {
+			return nil, fmt.Errorf("scanning security key: %w", err)
+		}
+		c.SignCount = uint32(signCount)
+		creds = append(creds, c)
+	}
+	return creds, rows.Err()
+}
+
+// HasCredentials reports whether the user has at least one security key.
+func (s *WebAuthnService) HasCredentials(ctx context.Context, userID uuid.UUID) (bool, error) {
+	var exists bool
+	err := s.auth.pool.QueryRow(ctx, `
+		SELECT EXISTS (SELECT 1 FROM admin_webauthn_credentials WHERE admin_user_id = $1)
+	`, userID).Scan(&exists)
+	if err != nil {
+		return false, fmt.Errorf("checking for security keys: %w", err)
+	}
+	return exists, nil
+}
+
+// BeginRegistration starts registering a new security key for user and
+// returns the options for navigator.credentials.create.
+func (s *WebAuthnService) BeginRegistration(ctx context.Context, user *AdminUser) (*CredentialCreationOptions, error) {
+	creds, err := s.ListCredentials(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	challenge, err := s.newChallenge(ctx, user.ID, ceremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := &CredentialCreationOptions{
+		Challenge:          base64.RawURLEncoding.EncodeToString(challenge),
+		Timeout:            webauthnTimeout.Milliseconds(),
+		ExcludeCredentials: credentialDescriptors(creds),
+		Attestation:        "none",
+	}
+	opts.RP.ID = s.rp.ID
+	opts.RP.Name = s.rp.Name
+	opts.User.ID = base64.RawURLEncoding.EncodeToString(user.ID[:])
+	opts.User.Name = user.Email
+	opts.User.DisplayName = user.Name
+	for _, alg := range []int64{coseAlgES256, coseAlgEdDSA, coseAlgRS256} {
+		opts.PubKeyCredParams = append(opts.PubKeyCredParams, CredentialParameter{Type: "public-key", Alg: alg})
+	}
+	opts.AuthenticatorSelection.ResidentKey = "discouraged"
+	opts.AuthenticatorSelection.UserVerification = "preferred"
+
+	return opts, nil
+}
+
+// FinishRegistration verifies the browser's response to BeginRegistration
+// and stores the new security key under name.
+func (s *WebAuthnService) FinishRegistration(ctx context.Context, userID uuid.UUID, name string, resp *RegistrationResponse) (*WebAuthnCredential, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		name = "Security key"
+	}
+	if utf8.RuneCountInString(name) > maxCredentialNameLen {
+		return nil, ErrInvalidCredentialName
+	}
+
+	challenge, err := s.consumeChallenge(ctx, userID, ceremonyRegistration)
+	if err != nil {
+		return nil, err
+	}
+
+	clientDataJSON, err := decodeBase64URL(resp.Response.ClientDataJSON)
+	if err != nil {
+		return nil, fmt.Errorf("%w: clientDataJSON: %v", ErrWebAuthnVerification, err)
+	}
+	attestationObject, err := decodeBase64URL(resp.Response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: attestationObject: %v", ErrWebAuthnVerification, err)
+	}
+
+	reg, err := verifyRegistration(s.rp, challenge, clientDataJSON, attestationObject)
+	if err != nil {
+		return nil, err
+	}
+
+	transports := resp.Response.Transports
+	if transports == nil {
+		transports = []string{}
+	}
+
+	cred := &WebAuthnCredential{
+		ID:           uuid.New(),
+		AdminUserID:  userID,
+		Name:         name,
+		CredentialID: reg.CredentialID,
+		PublicKey:    reg.PublicKey,
+		SignCount:    reg.SignCount,
+		Transports:   transports,
+		CreatedAt:    time.Now().UTC(),
+	}
+	tag, err := s.auth.pool.Exec(ctx, `
+		INSERT INTO admin_webauthn_credentials (
+			id, admin_user_id, name, credential_id, public_key, sign_count, transports, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (credential_id) DO NOTHING
+	`, cred.ID, userID, cred.Name, cred.CredentialID, cred.PublicKey, int64(cred.SignCount), cred.Transports, cred.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("storing security key: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil, ErrWebAuthnCredentialExists
+	}
+
+	s.auth.AuditLog(ctx, userID, "webauthn_registered", "admin_user", userID, map[string]any{
+		"credential": cred.ID.String(),
+		"name":       cred.Name,
+	})
+
+	return cred, nil
+}
+
+// DeleteCredential removes one of the user's security keys.
+func (s *WebAuthnService) DeleteCredential(ctx context.Context, userID, id uuid.UUID) error {
+	var name string
+	err := s.auth.pool.QueryRow(ctx, `
+		DELETE FROM admin_webauthn_credentials
+		WHERE id = $1 AND admin_user_id = $2
+		RETURNING name
+	`, id, userID).Scan(&name)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrWebAuthnCredentialNotFound
+		}
+		return fmt.Errorf("deleting security key: %w", err)
+	}
+
+	s.auth.AuditLog(ctx, userID, "webauthn_removed", "admin_user", userID, map[string]any{
+		"credential": id.String(),
+		"name":       name,
+	})
+
+	return nil
+}
+
+// BeginLogin starts the security key step of a login and returns the
+// options for navigator.credentials.get.
+func (s *WebAuthnService) BeginLogin(ctx context.Context, userID uuid.UUID) (*CredentialRequestOptions, error) {
+	creds, err := s.ListCredentials(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if len(creds) == 0 {
+		return nil, ErrWebAuthnNotSetup
+	}
+	challenge, err := s.newChallenge(ctx, userID, ceremonyLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	return &CredentialRequestOptions{
+		Challenge:        base64.RawURLEncoding.EncodeToString(challenge),
+		Timeout:          webauthnTimeout.Milliseconds(),
+		RPID:             s.rp.ID,
+		AllowCredentials: credentialDescriptors(creds),
+		UserVerification: "preferred",
+	}, nil
+}
+
+// FinishLogin verifies the browser's response to BeginLogin and creates a
+// session. Returns the session token on success.
+func (s *WebAuthnService) FinishLogin(ctx context.Context, userID uuid.UUID, resp *AssertionResponse, ipAddress, userAgent string) (string, error) {
+	challenge, err := s.consumeChallenge(ctx, userID, ceremonyLogin)
+	if err != nil {
+		return "", err
+	}
+
+	credentialID, err := decodeBase64URL(resp.ID)
+	if err != nil {
+		return "", fmt.Errorf("%w: credential ID: %v", ErrWebAuthnVerification, err)
+	}
+	clientDataJSON, err := decodeBase64URL(resp.Response.ClientDataJSON)
+	if err != nil {
+		return "", fmt.Errorf("%w: clientDataJSON: %v", ErrWebAuthnVerification, err)
+	}
+	authData, err := decodeBase64URL(resp.Response.AuthenticatorData)
+	if err != nil {
+		return "", fmt.Errorf("%w: authenticatorData: %v", ErrWebAuthnVerification, err)
+	}
+	signature, err := decodeBase64URL(resp.Response.Signature)
+	if err != nil {
+		return "", fmt.Errorf("%w: signature: %v", ErrWebAuthnVerification, err)
+	}
+
+	var (
+		credID    uuid.UUID
+		name      string
+		publicKey []byte
+		signCount int64
+	)
+	err = s.auth.pool.QueryRow(ctx, `
+		SELECT id, name, public_key, sign_count
+		FROM admin_webauthn_credentials
+		WHERE credential_id = $1 AND admin_user_id = $2
+	`, credentialID, userID).Scan(&credID, &name, &publicKey, &signCount)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ErrWebAuthnCredentialNotFound
+		}
+		return "", fmt.Errorf("fetching security key: %w", err)
+	}
+
+	newCount, err := verifyAssertion(s.rp, challenge, publicKey, uint32(signCount), clientDataJSON, authData, signature)
+	if err != nil {
+		s.logger.Warn("failed security key login attempt",
+			slog.String("user_id", userID.String()),
+			slog.String("credential", credID.String()),
+			slog.String("error", err.Error()),
+		)
+		return "", err
+	}
+
+	_, err = s.auth.pool.Exec(ctx, `
+		UPDATE admin_webauthn_credentials
+		SET sign_count = $1, last_used_at = $2
+		WHERE id = $3
+	`, int64(newCount), time.Now().UTC(), credID)
+	if err != nil {
+		return "", fmt.Errorf("updating security key: %w", err)
+	}
+
+	token, err := s.auth.session.CreateSession(ctx, userID, ipAddress, userAgent)
+	if err != nil {
+		return "", fmt.Errorf("creating session after security key login: %w", err)
+	}
+
+	if err := s.auth.UpdateLastLogin(ctx, userID); err != nil {
+		s.logger.Error("failed to update last login",
+			slog.String("user_id", userID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+
+	s.auth.AuditLog(ctx, userID, "webauthn_login", "admin_user", userID, map[string]any{
+		"credential": credID.String(),
+		"name":       name,
+	})
+
+	s.logger.Info("admin login via security key",
+		slog.String("user_id", userID.String()),
+		slog.String("ip", ipAddress),
+	)
+
+	return token, nil
+}
+
+// newChallenge generates a random challenge and stores it as the user's
+// outstanding challenge for the ceremony, replacing any earlier one.
+func (s *WebAuthnService) newChallenge(ctx context.Context, userID uuid.UUID, ceremony string) ([]byte, error) {
+	challenge := make([]byte, 32)
+	if _, err := rand.Read(challenge); err != nil {
+		return nil, fmt.Errorf("generating challenge: %w", err)
+	}
+
+	_, err := s.auth.pool.Exec(ctx, `
+		INSERT INTO admin_webauthn_challenges (admin_user_id, ceremony, challenge, expires_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (admin_user_id, ceremony)
+		DO UPDATE SET challenge = EXCLUDED.challenge, expires_at = EXCLUDED.expires_at
+	`, userID, ceremony, challenge, time.Now().UTC().Add(webauthnTimeout))
+	if err != nil {
+		return nil, fmt.Errorf("storing challenge: %w", err)
+	}
+
+	return challenge, nil
+}
+
+// consumeChallenge removes and returns the user's outstanding challenge for
+// the ceremony, so each challenge can be answered only once.
+func (s *WebAuthnService) consumeChallenge(ctx context.Context, userID uuid.UUID, ceremony string) ([]byte, error) {
+	var challenge []byte
+	err := s.auth.pool.QueryRow(ctx, `
+		DELETE FROM admin_webauthn_challenges
+		WHERE admin_user_id = $1 AND ceremony = $2 AND expires_at > $3
+		RETURNING challenge
+	`, userID, ceremony, time.Now().UTC()).Scan(&challenge)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, ErrWebAuthnNoChallenge
+		}
+		return nil, fmt.Errorf("fetching challenge: %w", err)
+	}
+	return challenge, nil
+}
+
+// credentialDescriptors describes creds for allowCredentials and
+// excludeCredentials.
+func credentialDescriptors(creds []*WebAuthnCredential) []CredentialDescriptor {
+	descs := make([]CredentialDescriptor, 0, len(creds))
+	for _, c := range creds {
+		descs = append(descs, CredentialDescriptor{
+			Type:       "public-key",
+			ID:         base64.RawURLEncoding.EncodeToString(c.CredentialID),
+			Transports: c.Transports,
+		})
+	}
+	return descs
+}
+
+// decodeBase64URL decodes base64url with or without padding, as browsers
+// and libraries differ.
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
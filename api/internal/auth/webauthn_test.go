@@ -0,0 +1,387 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+// --------------------------------------------------------------------------
+// A software authenticator for tests
+// --------------------------------------------------------------------------
+
+var testRP = RelyingParty{ID: "admin.example.com", Name: "ForgeCommerce", Origin: "https://admin.example.com"}
+
+// cborPair is a map entry for cborMap, which keeps key order.
+type cborPair struct {
+	key, value []byte
+}
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n < 1<<8:
+		return []byte{major<<5 | 24, byte(n)}
+	case n < 1<<16:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	default:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+	}
+}
+
+func cborInt(v int64) []byte {
+	if v < 0 {
+		return cborHead(1, uint64(-1-v))
+	}
+	return cborHead(0, uint64(v))
+}
+
+func cborBytes(b []byte) []byte { return append(cborHead(2, uint64(len(b))), b...) }
+func cborText(s string) []byte  { return append(cborHead(3, uint64(len(s))), s...) }
+
+func cborMap(pairs ...cborPair) []byte {
+	out := cborHead(5, uint64(len(pairs)))
+	for _, p := range pairs {
+		out = append(out, p.key...)
+		out = append(out, p.value...)
+	}
+	return out
+}
+
+// testAuthenticator holds one credential and signs like a security key.
+type testAuthenticator struct {
+	credentialID []byte
+	signer       crypto.Signer
+	coseKey      []byte
+	signCount    uint32
+}
+
+func newES256Authenticator(t *testing.T) *testAuthenticator {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	point, err := priv.PublicKey.Bytes()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testAuthenticator{
+		credentialID: randomBytes(t, 16),
+		signer:       priv,
+		coseKey: cborMap(
+			cborPair{cborInt(coseKeyKty), cborInt(coseKtyEC2)},
+			cborPair{cborInt(coseKeyAlg), cborInt(coseAlgES256)},
+			cborPair{cborInt(coseKeyCrv), cborInt(coseCrvP256)},
+			cborPair{cborInt(coseKeyX), cborBytes(point[1:33])},
+			cborPair{cborInt(coseKeyY), cborBytes(point[33:])},
+		),
+	}
+}
+
+func newEd25519Authenticator(t *testing.T) *testAuthenticator {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testAuthenticator{
+		credentialID: randomBytes(t, 32),
+		signer:       priv,
+		coseKey: cborMap(
+			cborPair{cborInt(coseKeyKty), cborInt(coseKtyOKP)},
+			cborPair{cborInt(coseKeyAlg), cborInt(coseAlgEdDSA)},
+			cborPair{cborInt(coseKeyCrv), cborInt(coseCrvEd25519)},
+			cborPair{cborInt(coseKeyX), cborBytes(pub)},
+		),
+	}
+}
+
+func randomBytes(t *testing.T, n int) []byte {
+	t.Helper()
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return b
+}
+
+func clientDataJSON(typ string, challenge []byte, origin string) []byte {
+	b, _ := json.Marshal(map[string]any{
+		"type":        typ,
+		"challenge":   base64.RawURLEncoding.EncodeToString(challenge),
+		"origin":      origin,
+		"crossOrigin": false,
+	})
+	return b
+}
+
+func (a *testAuthenticator) authData(rpID string, flags byte, attested bool) []byte {
+	rpHash := sha256.Sum256([]byte(rpID))
+	out := append([]byte(nil), rpHash[:]...)
+	if attested {
+		flags |= authFlagAttestedData
+	}
+	out = append(out, flags)
+	out = binary.BigEndian.AppendUint32(out, a.signCount)
+	if attested {
+		out = append(out, make([]byte, 16)...) // AAGUID
+		out = binary.BigEndian.AppendUint16(out, uint16(len(a.credentialID)))
+		out = append(out, a.credentialID...)
+		out = append(out, a.coseKey...)
+	}
+	return out
+}
+
+// attest returns clientDataJSON and an attestation object for registration.
+func (a *testAuthenticator) attest(rp RelyingParty, challenge []byte) ([]byte, []byte) {
+	cd := clientDataJSON("webauthn.create", challenge, rp.Origin)
+	obj := cborMap(
+		cborPair{cborText("fmt"), cborText("none")},
+		cborPair{cborText("attStmt"), cborMap()},
+		cborPair{cborText("authData"), cborBytes(a.authData(rp.ID, authFlagUserPresent, true))},
+	)
+	return cd, obj
+}
+
+// assert returns clientDataJSON, authenticator data and a signature for
+// login with the authenticator's current signature counter.
+func (a *testAuthenticator) assert(t *testing.T, rp RelyingParty, challenge []byte) ([]byte, []byte, []byte) {
+	t.Helper()
+	cd := clientDataJSON("webauthn.get", challenge, rp.Origin)
+	ad := a.authData(rp.ID, authFlagUserPresent, false)
+	hash := sha256.Sum256(cd)
+	signed := append(append([]byte(nil), ad...), hash[:]...)
+
+	var sig []byte
+	var err error
+	if _, ok := a.signer.(ed25519.PrivateKey); ok {
+		sig, err = a.signer.Sign(rand.Reader, signed, crypto.Hash(0))
+	} else {
+		digest := sha256.Sum256(signed)
+		sig, err = a.signer.Sign(rand.Reader, digest[:], crypto.SHA256)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cd, ad, sig
+}
+
+// --------------------------------------------------------------------------
+// Tests
+// --------------------------------------------------------------------------
+
+func TestRelyingPartyFromURL(t *testing.T) {
+	rp, err := RelyingPartyFromURL("https://admin.example.com:8443/", "ForgeCommerce")
+	if err != nil {
+		t.Fatalf("RelyingPartyFromURL: %v", err)
+	}
+	if rp.ID != "admin.example.com" {
+		t.Errorf("ID = %q, want admin.example.com", rp.ID)
+	}
+	if rp.Origin != "https://admin.example.com:8443" {
+		t.Errorf("Origin = %q, want https://admin.example.com:8443", rp.Origin)
+	}
+
+	if _, err := RelyingPartyFromURL("not a url", "x"); err == nil {
+		t.Error("expected error for URL without scheme and host")
+	}
+}
+
+func TestWebAuthn_RegisterAndLogin(t *testing.T) {
+	authenticators := map[string]func(*testing.T) *testAuthenticator{
+		"ES256":   newES256Authenticator,
+		"Ed25519": newEd25519Authenticator,
+	}
+
+	for name, newAuthenticator := range authenticators {
+		t.Run(name, func(t *testing.T) {
+			a := newAuthenticator(t)
+
+			challenge := randomBytes(t, 32)
+			cd, att := a.attest(testRP, challenge)
+			cred, err := verifyRegistration(testRP, challenge, cd, att)
+			if err != nil {
+				t.Fatalf("verifyRegistration: %v", err)
+			}
+			if string(cred.CredentialID) != string(a.credentialID) {
+				t.Errorf("credential ID mismatch")
+			}
+			if string(cred.PublicKey) != string(a.coseKey) {
+				t.Errorf("public key mismatch")
+			}
+
+			stored := cred.SignCount
+			for i := 0; i < 2; i++ {
+				a.signCount++
+				challenge := randomBytes(t, 32)
+				cd, ad, sig := a.assert(t, testRP, challenge)
+				count, err := verifyAssertion(testRP, challenge, cred.PublicKey, stored, cd, ad, sig)
+				if err != nil {
+					t.Fatalf("verifyAssertion #%d: %v", i+1, err)
+				}
+				if count != a.signCount {
+					t.Errorf("sign count = %d, want %d", count, a.signCount)
+				}
+				stored = count
+			}
+		})
+	}
+}
+
+func TestVerifyRegistration_Rejects(t *testing.T) {
+	a := newES256Authenticator(t)
+	challenge := randomBytes(t, 32)
+
+	tests := []struct {
+		name string
+		cd   []byte
+		att  func() []byte
+	}{
+		{
+			name: "wrong challenge",
+			cd:   clientDataJSON("webauthn.create", randomBytes(t, 32), testRP.Origin),
+		},
+		{
+			name: "wrong origin",
+			cd:   clientDataJSON("webauthn.create", challenge, "https://evil.example.com"),
+		},
+		{
+			name: "wrong type",
+			cd:   clientDataJSON("webauthn.get", challenge, testRP.Origin),
+		},
+		{
+			name: "wrong RP ID",
+			att: func() []byte {
+				_, att := a.attest(RelyingParty{ID: "evil.example.com", Origin: testRP.Origin}, challenge)
+				return att
+			},
+		},
+		{
+			name: "user not present",
+			att: func() []byte {
+				return cborMap(cborPair{cborText("authData"), cborBytes(a.authData(testRP.ID, 0, true))})
+			},
+		},
+		{
+			name: "no attested credential",
+			att: func() []byte {
+				return cborMap(cborPair{cborText("authData"), cborBytes(a.authData(testRP.ID, authFlagUserPresent, false))})
+			},
+		},
+		{
+			name: "not CBOR",
+			att:  func() []byte { return []byte("{}") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cd, att := a.attest(testRP, challenge)
+			if tt.cd != nil {
+				cd = tt.cd
+			}
+			if tt.att != nil {
+				att = tt.att()
+			}
+			if _, err := verifyRegistration(testRP, challenge, cd, att); !errors.Is(err, ErrWebAuthnVerification) {
+				t.Errorf("expected ErrWebAuthnVerification, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifyAssertion_Rejects(t *testing.T) {
+	a := newES256Authenticator(t)
+	other := newES256Authenticator(t)
+	challenge := randomBytes(t, 32)
+
+	t.Run("bad signature", func(t *testing.T) {
+		cd, ad, sig := a.assert(t, testRP, challenge)
+		sig[len(sig)-1] ^= 0xff
+		if _, err := verifyAssertion(testRP, challenge, a.coseKey, 0, cd, ad, sig); !errors.Is(err, ErrWebAuthnVerification) {
+			t.Errorf("expected ErrWebAuthnVerification, got %v", err)
+		}
+	})
+
+	t.Run("other key", func(t *testing.T) {
+		cd, ad, sig := other.assert(t, testRP, challenge)
+		if _, err := verifyAssertion(testRP, challenge, a.coseKey, 0, cd, ad, sig); !errors.Is(err, ErrWebAuthnVerification) {
+			t.Errorf("expected ErrWebAuthnVerification, got %v", err)
+		}
+	})
+
+	t.Run("wrong challenge", func(t *testing.T) {
+		cd, ad, sig := a.assert(t, testRP, randomBytes(t, 32))
+		if _, err := verifyAssertion(testRP, challenge, a.coseKey, 0, cd, ad, sig); !errors.Is(err, ErrWebAuthnVerification) {
+			t.Errorf("expected ErrWebAuthnVerification, got %v", err)
+		}
+	})
+
+	t.Run("counter did not increase", func(t *testing.T) {
+		a.signCount = 5
+		cd, ad, sig := a.assert(t, testRP, challenge)
+		if _, err := verifyAssertion(testRP, challenge, a.coseKey, 5, cd, ad, sig); !errors.Is(err, ErrWebAuthnVerification) {
+			t.Errorf("expected ErrWebAuthnVerification, got %v", err)
+		}
+	})
+
+	t.Run("zero counters allowed", func(t *testing.T) {
+		// Authenticators without a counter always report zero.
+		a.signCount = 0
+		cd, ad, sig := a.assert(t, testRP, challenge)
+		if _, err := verifyAssertion(testRP, challenge, a.coseKey, 0, cd, ad, sig); err != nil {
+			t.Errorf("expected zero counters to be accepted, got %v", err)
+		}
+	})
+}
+
+func TestParseCOSEKey_Rejects(t *testing.T) {
+	tests := []struct {
+		name string
+		key  []byte
+	}{
+		{"not a map", cborInt(1)},
+		{"unknown algorithm", cborMap(
+			cborPair{cborInt(coseKeyKty), cborInt(coseKtyEC2)},
+			cborPair{cborInt(coseKeyAlg), cborInt(-35)},
+		)},
+		{"short P-256 coordinate", cborMap(
+			cborPair{cborInt(coseKeyKty), cborInt(coseKtyEC2)},
+			cborPair{cborInt(coseKeyAlg), cborInt(coseAlgES256)},
+			cborPair{cborInt(coseKeyCrv), cborInt(coseCrvP256)},
+			cborPair{cborInt(coseKeyX), cborBytes(make([]byte, 31))},
+			cborPair{cborInt(coseKeyY), cborBytes(make([]byte, 32))},
+		)},
+		{"point not on curve", cborMap(
+			cborPair{cborInt(coseKeyKty), cborInt(coseKtyEC2)},
+			cborPair{cborInt(coseKeyAlg), cborInt(coseAlgES256)},
+			cborPair{cborInt(coseKeyCrv), cborInt(coseCrvP256)},
+			cborPair{cborInt(coseKeyX), cborBytes(make([]byte, 32))},
+			cborPair{cborInt(coseKeyY), cborBytes(make([]byte, 32))},
+		)},
+		{"short RSA modulus", cborMap(
+			cborPair{cborInt(coseKeyKty), cborInt(coseKtyRSA)},
+			cborPair{cborInt(coseKeyAlg), cborInt(coseAlgRS256)},
+			cborPair{cborInt(coseKeyN), cborBytes(make([]byte, 128))},
+			cborPair{cborInt(coseKeyE), cborBytes([]byte{1, 0, 1})},
+		)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parseCOSEKey(tt.key); !errors.Is(err, errUnsupportedKey) {
+				t.Errorf("expected errUnsupportedKey, got %v", err)
+			}
+		})
+	}
+}
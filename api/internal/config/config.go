@@ -19,6 +19,11 @@ type Config struct {
 	SessionSecret string
 	TOTPIssuer    string
 
+	// Admin sessions end SessionTTL after sign-in, or earlier once unused
+	// for SessionIdleTimeout (0 disables the idle timeout).
+	SessionTTL         time.Duration
+	SessionIdleTimeout time.Duration
+
 	StripeSecretKey    string
 	StripeWebhookKey   string
 	StripePublicKey    string
@@ -28,12 +33,15 @@ type Config struct {
 
 	S3 S3Config
 
-	SMTPHost string
-	SMTPPort int
-	SMTPFrom string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string // optional; enables SMTP AUTH PLAIN over TLS
+	SMTPPassword string
+	SMTPFrom     string
 
-	VAT VATConfig
-	AI  AIConfig
+	VAT          VATConfig
+	AI           AIConfig
+	CartRecovery CartRecoveryConfig
 }
 
 // S3Config holds settings for S3-compatible object storage (CEPH, MinIO, AWS).
@@ -89,6 +97,17 @@ func (c AIConfig) AvailableProviders() []string {
 	return providers
 }
 
+// CartRecoveryConfig holds settings for abandoned cart reminder emails.
+type CartRecoveryConfig struct {
+	Enabled   bool
+	IdleAfter time.Duration // how long a cart must be untouched before a reminder
+	Interval  time.Duration // how often to look for abandoned carts
+	// DiscountID is the discount behind the one-time coupon in each
+	// reminder; empty sends reminders without a coupon.
+	DiscountID string
+	CouponTTL  time.Duration
+}
+
 type VATConfig struct {
 	SyncEnabled       bool
 	SyncCron          string
@@ -111,6 +130,9 @@ func Load() (*Config, error) {
 		SessionSecret: getEnv("SESSION_SECRET", ""),
 		TOTPIssuer:    getEnv("TOTP_ISSUER", "ForgeCommerce"),
 
+		SessionTTL:         getEnvDuration("ADMIN_SESSION_TTL", 8*time.Hour),
+		SessionIdleTimeout: getEnvDuration("ADMIN_SESSION_IDLE_TIMEOUT", time.Hour),
+
 		StripeSecretKey:  getEnv("STRIPE_SECRET_KEY", ""),
 		StripeWebhookKey: getEnv("STRIPE_WEBHOOK_SECRET", ""),
 		StripePublicKey:  getEnv("STRIPE_PUBLIC_KEY", ""),
@@ -129,9 +151,11 @@ func Load() (*Config, error) {
 			PrivateBucket:   getEnv("S3_PRIVATE_BUCKET", ""),
 		},
 
-		SMTPHost: getEnv("SMTP_HOST", "localhost"),
-		SMTPPort: getEnvInt("SMTP_PORT", 1025),
-		SMTPFrom: getEnv("SMTP_FROM", "store@forgecommerce.local"),
+		SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+		SMTPPort:     getEnvInt("SMTP_PORT", 1025),
+		SMTPUsername: getEnv("SMTP_USERNAME", ""),
+		SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+		SMTPFrom:     getEnv("SMTP_FROM", "store@forgecommerce.local"),
 
 		VAT: VATConfig{
 			SyncEnabled:  getEnvBool("VAT_SYNC_ENABLED", true),
@@ -143,6 +167,8 @@ func Load() (*Config, error) {
 		},
 
 		AI: loadAIConfig(),
+
+		CartRecovery: loadCartRecoveryConfig(),
 	}
 
 	if cfg.SessionSecret == "" {
@@ -169,6 +195,9 @@ func LoadDev() *Config {
 			SessionSecret: getEnv("SESSION_SECRET", "dev-session-secret-do-not-use-in-production"),
 			TOTPIssuer:    getEnv("TOTP_ISSUER", "ForgeCommerce"),
 
+			SessionTTL:         getEnvDuration("ADMIN_SESSION_TTL", 8*time.Hour),
+			SessionIdleTimeout: getEnvDuration("ADMIN_SESSION_IDLE_TIMEOUT", time.Hour),
+
 			StripeSecretKey:  getEnv("STRIPE_SECRET_KEY", "sk_test_fake"),
 			StripeWebhookKey: getEnv("STRIPE_WEBHOOK_SECRET", "whsec_fake"),
 			StripePublicKey:  getEnv("STRIPE_PUBLIC_KEY", "pk_test_fake"),
@@ -187,9 +216,11 @@ func LoadDev() *Config {
 				PrivateBucket:   getEnv("S3_PRIVATE_BUCKET", ""),
 			},
 
-			SMTPHost: getEnv("SMTP_HOST", "localhost"),
-			SMTPPort: getEnvInt("SMTP_PORT", 1025),
-			SMTPFrom: getEnv("SMTP_FROM", "store@forgecommerce.local"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnvInt("SMTP_PORT", 1025),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			SMTPFrom:     getEnv("SMTP_FROM", "store@forgecommerce.local"),
 
 			VAT: VATConfig{
 				SyncEnabled:  getEnvBool("VAT_SYNC_ENABLED", true),
@@ -201,6 +232,8 @@ func LoadDev() *Config {
 			},
 
 			AI: loadAIConfig(),
+
+			CartRecovery: loadCartRecoveryConfig(),
 		}
 	}
 	return cfg
@@ -236,6 +269,16 @@ func loadAIConfig() AIConfig {
 	}
 }
 
+func loadCartRecoveryConfig() CartRecoveryConfig {
+	return CartRecoveryConfig{
+		Enabled:    getEnvBool("CART_RECOVERY_ENABLED", false),
+		IdleAfter:  getEnvDuration("CART_RECOVERY_IDLE", 4*time.Hour),
+		Interval:   getEnvDuration("CART_RECOVERY_INTERVAL", 15*time.Minute),
+		DiscountID: getEnv("CART_RECOVERY_DISCOUNT_ID", ""),
+		CouponTTL:  getEnvDuration("CART_RECOVERY_COUPON_TTL", 72*time.Hour),
+	}
+}
+
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -84,6 +84,38 @@ func TestLoadDev_VATDefaults(t *testing.T) {
 	}
 }
 
+func TestLoadDev_CartRecoveryDefaults(t *testing.T) {
+	cfg := LoadDev()
+	cr := cfg.CartRecovery
+
+	if cr.Enabled {
+		t.Error("CartRecovery Enabled should default to false")
+	}
+	if cr.IdleAfter != 4*time.Hour {
+		t.Errorf("CartRecovery IdleAfter: want 4h, got %v", cr.IdleAfter)
+	}
+	if cr.Interval != 15*time.Minute {
+		t.Errorf("CartRecovery Interval: want 15m, got %v", cr.Interval)
+	}
+	if cr.DiscountID != "" {
+		t.Errorf("CartRecovery DiscountID: want empty, got %q", cr.DiscountID)
+	}
+	if cr.CouponTTL != 72*time.Hour {
+		t.Errorf("CartRecovery CouponTTL: want 72h, got %v", cr.CouponTTL)
+	}
+}
+
+func TestLoadDev_SessionDefaults(t *testing.T) {
+	cfg := LoadDev()
+
+	if cfg.SessionTTL != 8*time.Hour {
+		t.Errorf("SessionTTL: want 8h, got %v", cfg.SessionTTL)
+	}
+	if cfg.SessionIdleTimeout != time.Hour {
+		t.Errorf("SessionIdleTimeout: want 1h, got %v", cfg.SessionIdleTimeout)
+	}
+}
+
 func TestLoad_MissingSessionSecret(t *testing.T) {
 	origVal := os.Getenv("SESSION_SECRET")
 	os.Unsetenv("SESSION_SECRET")
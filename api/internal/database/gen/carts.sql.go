@@ -63,7 +63,7 @@ const createCart = `-- name: CreateCart :one
 INSERT INTO carts (
   id, customer_id, email, country_code, vat_number, coupon_code, expires_at, created_at, updated_at
 ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
-RETURNING id, customer_id, email, country_code, vat_number, coupon_code, expires_at, created_at, updated_at
+RETURNING id, customer_id, email, country_code, vat_number, coupon_code, expires_at, created_at, updated_at, order_id
 `
 
 type CreateCartParams struct {
@@ -99,6 +99,7 @@ func (q *Queries) CreateCart(ctx context.Context, arg CreateCartParams) (Cart, e
 		&i.ExpiresAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.OrderID,
 	)
 	return i, err
 }
@@ -113,7 +114,7 @@ func (q *Queries) DeleteExpiredCarts(ctx context.Context) error {
 }
 
 const getCart = `-- name: GetCart :one
-SELECT id, customer_id, email, country_code, vat_number, coupon_code, expires_at, created_at, updated_at FROM carts WHERE id = $1
+SELECT id, customer_id, email, country_code, vat_number, coupon_code, expires_at, created_at, updated_at, order_id FROM carts WHERE id = $1
 `
 
 func (q *Queries) GetCart(ctx context.Context, id uuid.UUID) (Cart, error) {
@@ -129,6 +130,7 @@ func (q *Queries) GetCart(ctx context.Context, id uuid.UUID) (Cart, error) {
 		&i.ExpiresAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.OrderID,
 	)
 	return i, err
 }
@@ -147,7 +149,8 @@ SELECT
   p.slug AS product_slug,
   p.base_price AS product_base_price,
   p.status AS product_status,
-  p.vat_category_id AS product_vat_category_id
+  p.vat_category_id AS product_vat_category_id,
+  p.allow_backorder AS product_allow_backorder
 FROM cart_items ci
 JOIN product_variants pv ON pv.id = ci.variant_id
 JOIN products p ON p.id = pv.product_id
@@ -156,23 +159,24 @@ ORDER BY ci.created_at
 `
 
 type GetCartItemsRow struct {
-	ID                   uuid.UUID      `json:"id"`
-	CartID               uuid.UUID      `json:"cart_id"`
-	VariantID            uuid.UUID      `json:"variant_id"`
-	Quantity             int32          `json:"quantity"`
-	CreatedAt            time.Time      `json:"created_at"`
-	UpdatedAt            time.Time      `json:"updated_at"`
-	VariantSku           string         `json:"variant_sku"`
-	VariantPrice         pgtype.Numeric `json:"variant_price"`
-	VariantStock         int32          `json:"variant_stock"`
-	VariantWeightGrams   *int32         `json:"variant_weight_grams"`
-	VariantIsActive      bool           `json:"variant_is_active"`
-	ProductID            uuid.UUID      `json:"product_id"`
-	ProductName          string         `json:"product_name"`
-	ProductSlug          string         `json:"product_slug"`
-	ProductBasePrice     pgtype.Numeric `json:"product_base_price"`
-	ProductStatus        string         `json:"product_status"`
-	ProductVatCategoryID pgtype.UUID    `json:"product_vat_category_id"`
+	ID                    uuid.UUID      `json:"id"`
+	CartID                uuid.UUID      `json:"cart_id"`
+	VariantID             uuid.UUID      `json:"variant_id"`
+	Quantity              int32          `json:"quantity"`
+	CreatedAt             time.Time      `json:"created_at"`
+	UpdatedAt             time.Time      `json:"updated_at"`
+	VariantSku            string         `json:"variant_sku"`
+	VariantPrice          pgtype.Numeric `json:"variant_price"`
+	VariantStock          int32          `json:"variant_stock"`
+	VariantWeightGrams    *int32         `json:"variant_weight_grams"`
+	VariantIsActive       bool           `json:"variant_is_active"`
+	ProductID             uuid.UUID      `json:"product_id"`
+	ProductName           string         `json:"product_name"`
+	ProductSlug           string         `json:"product_slug"`
+	ProductBasePrice      pgtype.Numeric `json:"product_base_price"`
+	ProductStatus         string         `json:"product_status"`
+	ProductVatCategoryID  pgtype.UUID    `json:"product_vat_category_id"`
+	ProductAllowBackorder bool           `json:"product_allow_backorder"`
 }
 
 func (q *Queries) GetCartItems(ctx context.Context, cartID uuid.UUID) ([]GetCartItemsRow, error) {
@@ -202,6 +206,7 @@ func (q *Queries) GetCartItems(ctx context.Context, cartID uuid.UUID) ([]GetCart
 			&i.ProductBasePrice,
 			&i.ProductStatus,
 			&i.ProductVatCategoryID,
+			&i.ProductAllowBackorder,
 		); err != nil {
 			return nil, err
 		}
@@ -213,6 +218,40 @@ func (q *Queries) GetCartItems(ctx context.Context, cartID uuid.UUID) ([]GetCart
 	return items, nil
 }
 
+const getLatestCustomerCart = `-- name: GetLatestCustomerCart :one
+SELECT id, customer_id, email, country_code, vat_number, coupon_code, expires_at, created_at, updated_at, order_id FROM carts
+WHERE customer_id = $1 AND id <> $2
+  AND order_id IS NULL AND expires_at > $3
+ORDER BY updated_at DESC
+LIMIT 1
+`
+
+type GetLatestCustomerCartParams struct {
+	CustomerID pgtype.UUID `json:"customer_id"`
+	ExcludeID  uuid.UUID   `json:"exclude_id"`
+	Now        time.Time   `json:"now"`
+}
+
+// Returns the customer's most recently updated cart that has not expired or
+// been checked out, other than the given cart.
+func (q *Queries) GetLatestCustomerCart(ctx context.Context, arg GetLatestCustomerCartParams) (Cart, error) {
+	row := q.db.QueryRow(ctx, getLatestCustomerCart, arg.CustomerID, arg.ExcludeID, arg.Now)
+	var i Cart
+	err := row.Scan(
+		&i.ID,
+		&i.CustomerID,
+		&i.Email,
+		&i.CountryCode,
+		&i.VatNumber,
+		&i.CouponCode,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OrderID,
+	)
+	return i, err
+}
+
 const removeCartItem = `-- name: RemoveCartItem :exec
 DELETE FROM cart_items WHERE id = $1
 `
@@ -222,11 +261,105 @@ func (q *Queries) RemoveCartItem(ctx context.Context, id uuid.UUID) error {
 	return err
 }
 
+const setCartCustomer = `-- name: SetCartCustomer :one
+UPDATE carts SET
+  customer_id = $1,
+  expires_at = GREATEST(expires_at, $2),
+  updated_at = $3
+WHERE id = $4
+RETURNING id, customer_id, email, country_code, vat_number, coupon_code, expires_at, created_at, updated_at, order_id
+`
+
+type SetCartCustomerParams struct {
+	CustomerID pgtype.UUID `json:"customer_id"`
+	ExpiresAt  time.Time   `json:"expires_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+	ID         uuid.UUID   `json:"id"`
+}
+
+// Assigns a cart to a customer and pushes its expiry out to at least
+// expires_at.
+func (q *Queries) SetCartCustomer(ctx context.Context, arg SetCartCustomerParams) (Cart, error) {
+	row := q.db.QueryRow(ctx, setCartCustomer,
+		arg.CustomerID,
+		arg.ExpiresAt,
+		arg.UpdatedAt,
+		arg.ID,
+	)
+	var i Cart
+	err := row.Scan(
+		&i.ID,
+		&i.CustomerID,
+		&i.Email,
+		&i.CountryCode,
+		&i.VatNumber,
+		&i.CouponCode,
+		&i.ExpiresAt,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OrderID,
+	)
+	return i, err
+}
+
+const setCartItemQuantity = `-- name: SetCartItemQuantity :one
+INSERT INTO cart_items (id, cart_id, variant_id, quantity, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $5)
+ON CONFLICT (cart_id, variant_id) DO UPDATE SET
+  quantity = EXCLUDED.quantity,
+  updated_at = EXCLUDED.updated_at
+RETURNING id, cart_id, variant_id, quantity, created_at, updated_at
+`
+
+type SetCartItemQuantityParams struct {
+	ID        uuid.UUID `json:"id"`
+	CartID    uuid.UUID `json:"cart_id"`
+	VariantID uuid.UUID `json:"variant_id"`
+	Quantity  int32     `json:"quantity"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Sets the quantity of a variant in a cart, adding the line if needed.
+func (q *Queries) SetCartItemQuantity(ctx context.Context, arg SetCartItemQuantityParams) (CartItem, error) {
+	row := q.db.QueryRow(ctx, setCartItemQuantity,
+		arg.ID,
+		arg.CartID,
+		arg.VariantID,
+		arg.Quantity,
+		arg.CreatedAt,
+	)
+	var i CartItem
+	err := row.Scan(
+		&i.ID,
+		&i.CartID,
+		&i.VariantID,
+		&i.Quantity,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const setCartOrder = `-- name: SetCartOrder :exec
+UPDATE carts SET order_id = $2, updated_at = $3 WHERE id = $1
+`
+
+type SetCartOrderParams struct {
+	ID        uuid.UUID   `json:"id"`
+	OrderID   pgtype.UUID `json:"order_id"`
+	UpdatedAt time.Time   `json:"updated_at"`
+}
+
+func (q *Queries) SetCartOrder(ctx context.Context, arg SetCartOrderParams) error {
+	_, err := q.db.Exec(ctx, setCartOrder, arg.ID, arg.OrderID, arg.UpdatedAt)
+	return err
+}
+
 const updateCart = `-- name: UpdateCart :one
 UPDATE carts SET
   email = $2, country_code = $3, vat_number = $4, coupon_code = $5, updated_at = $6
 WHERE id = $1
-RETURNING id, customer_id, email, country_code, vat_number, coupon_code, expires_at, created_at, updated_at
+RETURNING id, customer_id, email, country_code, vat_number, coupon_code, expires_at, created_at, updated_at, order_id
 `
 
 type UpdateCartParams struct {
@@ -258,6 +391,7 @@ func (q *Queries) UpdateCart(ctx context.Context, arg UpdateCartParams) (Cart, e
 		&i.ExpiresAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.OrderID,
 	)
 	return i, err
 }
@@ -29,9 +29,9 @@ INSERT INTO customers (
   id, email, first_name, last_name, phone, password_hash,
   default_billing_address, default_shipping_address,
   accepts_marketing, stripe_customer_id, vat_number,
-  notes, metadata, created_at, updated_at
+  notes, metadata, created_at, updated_at, email_verified_at
 ) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $14)
-RETURNING id, email, first_name, last_name, phone, password_hash, default_billing_address, default_shipping_address, accepts_marketing, stripe_customer_id, vat_number, notes, metadata, created_at, updated_at
+RETURNING id, email, first_name, last_name, phone, password_hash, default_billing_address, default_shipping_address, accepts_marketing, stripe_customer_id, vat_number, notes, metadata, created_at, updated_at, email_verified_at, token_version
 `
 
 type CreateCustomerParams struct {
@@ -85,12 +85,14 @@ func (q *Queries) CreateCustomer(ctx context.Context, arg CreateCustomerParams)
 		&i.Metadata,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TokenVersion,
 	)
 	return i, err
 }
 
 const getCustomer = `-- name: GetCustomer :one
-SELECT id, email, first_name, last_name, phone, password_hash, default_billing_address, default_shipping_address, accepts_marketing, stripe_customer_id, vat_number, notes, metadata, created_at, updated_at FROM customers WHERE id = $1
+SELECT id, email, first_name, last_name, phone, password_hash, default_billing_address, default_shipping_address, accepts_marketing, stripe_customer_id, vat_number, notes, metadata, created_at, updated_at, email_verified_at, token_version FROM customers WHERE id = $1
 `
 
 func (q *Queries) GetCustomer(ctx context.Context, id uuid.UUID) (Customer, error) {
@@ -112,12 +114,14 @@ func (q *Queries) GetCustomer(ctx context.Context, id uuid.UUID) (Customer, erro
 		&i.Metadata,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TokenVersion,
 	)
 	return i, err
 }
 
 const getCustomerByEmail = `-- name: GetCustomerByEmail :one
-SELECT id, email, first_name, last_name, phone, password_hash, default_billing_address, default_shipping_address, accepts_marketing, stripe_customer_id, vat_number, notes, metadata, created_at, updated_at FROM customers WHERE email = $1
+SELECT id, email, first_name, last_name, phone, password_hash, default_billing_address, default_shipping_address, accepts_marketing, stripe_customer_id, vat_number, notes, metadata, created_at, updated_at, email_verified_at, token_version FROM customers WHERE email = $1
 `
 
 func (q *Queries) GetCustomerByEmail(ctx context.Context, email string) (Customer, error) {
@@ -139,12 +143,14 @@ func (q *Queries) GetCustomerByEmail(ctx context.Context, email string) (Custome
 		&i.Metadata,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TokenVersion,
 	)
 	return i, err
 }
 
 const listCustomers = `-- name: ListCustomers :many
-SELECT id, email, first_name, last_name, phone, password_hash, default_billing_address, default_shipping_address, accepts_marketing, stripe_customer_id, vat_number, notes, metadata, created_at, updated_at FROM customers ORDER BY created_at DESC LIMIT $1 OFFSET $2
+SELECT id, email, first_name, last_name, phone, password_hash, default_billing_address, default_shipping_address, accepts_marketing, stripe_customer_id, vat_number, notes, metadata, created_at, updated_at, email_verified_at, token_version FROM customers ORDER BY created_at DESC LIMIT $1 OFFSET $2
 `
 
 type ListCustomersParams struct {
@@ -177,6 +183,8 @@ func (q *Queries) ListCustomers(ctx context.Context, arg ListCustomersParams) ([
 			&i.Metadata,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.EmailVerifiedAt,
+			&i.TokenVersion,
 		); err != nil {
 			return nil, err
 		}
@@ -188,13 +196,51 @@ func (q *Queries) ListCustomers(ctx context.Context, arg ListCustomersParams) ([
 	return items, nil
 }
 
+const markCustomerEmailVerified = `-- name: MarkCustomerEmailVerified :one
+UPDATE customers SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1 AND email = $2
+RETURNING id, email, first_name, last_name, phone, password_hash, default_billing_address, default_shipping_address, accepts_marketing, stripe_customer_id, vat_number, notes, metadata, created_at, updated_at, email_verified_at, token_version
+`
+
+type MarkCustomerEmailVerifiedParams struct {
+	ID    uuid.UUID `json:"id"`
+	Email string    `json:"email"`
+}
+
+// Only verifies the address the token was issued for, so a token sent
+// before an email change cannot verify the new address.
+func (q *Queries) MarkCustomerEmailVerified(ctx context.Context, arg MarkCustomerEmailVerifiedParams) (Customer, error) {
+	row := q.db.QueryRow(ctx, markCustomerEmailVerified, arg.ID, arg.Email)
+	var i Customer
+	err := row.Scan(
+		&i.ID,
+		&i.Email,
+		&i.FirstName,
+		&i.LastName,
+		&i.Phone,
+		&i.PasswordHash,
+		&i.DefaultBillingAddress,
+		&i.DefaultShippingAddress,
+		&i.AcceptsMarketing,
+		&i.StripeCustomerID,
+		&i.VatNumber,
+		&i.Notes,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TokenVersion,
+	)
+	return i, err
+}
+
 const updateCustomer = `-- name: UpdateCustomer :one
 UPDATE customers SET
   first_name = $2, last_name = $3, phone = $4,
   default_billing_address = $5, default_shipping_address = $6,
   accepts_marketing = $7, vat_number = $8, notes = $9, updated_at = $10
 WHERE id = $1
-RETURNING id, email, first_name, last_name, phone, password_hash, default_billing_address, default_shipping_address, accepts_marketing, stripe_customer_id, vat_number, notes, metadata, created_at, updated_at
+RETURNING id, email, first_name, last_name, phone, password_hash, default_billing_address, default_shipping_address, accepts_marketing, stripe_customer_id, vat_number, notes, metadata, created_at, updated_at, email_verified_at, token_version
 `
 
 type UpdateCustomerParams struct {
@@ -240,6 +286,23 @@ func (q *Queries) UpdateCustomer(ctx context.Context, arg UpdateCustomerParams)
 		&i.Metadata,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.EmailVerifiedAt,
+		&i.TokenVersion,
 	)
 	return i, err
 }
+
+const updateCustomerPassword = `-- name: UpdateCustomerPassword :exec
+UPDATE customers SET password_hash = $2, token_version = token_version + 1, updated_at = NOW() WHERE id = $1
+`
+
+type UpdateCustomerPasswordParams struct {
+	ID           uuid.UUID `json:"id"`
+	PasswordHash *string   `json:"password_hash"`
+}
+
+// Bumping token_version invalidates refresh tokens issued before the change.
+func (q *Queries) UpdateCustomerPassword(ctx context.Context, arg UpdateCustomerPasswordParams) error {
+	_, err := q.db.Exec(ctx, updateCustomerPassword, arg.ID, arg.PasswordHash)
+	return err
+}
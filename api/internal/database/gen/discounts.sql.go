@@ -14,12 +14,64 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const countCouponUsageByCustomer = `-- name: CountCouponUsageByCustomer :one
+SELECT COUNT(*) FROM coupon_usage WHERE coupon_id = $1 AND customer_id = $2
+`
+
+type CountCouponUsageByCustomerParams struct {
+	CouponID   uuid.UUID   `json:"coupon_id"`
+	CustomerID pgtype.UUID `json:"customer_id"`
+}
+
+func (q *Queries) CountCouponUsageByCustomer(ctx context.Context, arg CountCouponUsageByCustomerParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countCouponUsageByCustomer, arg.CouponID, arg.CustomerID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countCouponUsageByEmail = `-- name: CountCouponUsageByEmail :one
+SELECT COUNT(*) FROM coupon_usage WHERE coupon_id = $1 AND email = $2
+`
+
+type CountCouponUsageByEmailParams struct {
+	CouponID uuid.UUID `json:"coupon_id"`
+	Email    *string   `json:"email"`
+}
+
+func (q *Queries) CountCouponUsageByEmail(ctx context.Context, arg CountCouponUsageByEmailParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countCouponUsageByEmail, arg.CouponID, arg.Email)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const countCustomerOrders = `-- name: CountCustomerOrders :one
+SELECT COUNT(*) FROM orders
+WHERE status <> 'cancelled'
+AND (customer_id = $1 OR lower(email) = lower($2))
+`
+
+type CountCustomerOrdersParams struct {
+	CustomerID pgtype.UUID `json:"customer_id"`
+	Email      string      `json:"email"`
+}
+
+// Counts the orders placed by a customer account or email address, for
+// first-order conditions. Cancelled orders do not count.
+func (q *Queries) CountCustomerOrders(ctx context.Context, arg CountCustomerOrdersParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countCustomerOrders, arg.CustomerID, arg.Email)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
 const createCoupon = `-- name: CreateCoupon :one
 INSERT INTO coupons (
   id, code, discount_id, usage_limit, usage_limit_per_customer,
   usage_count, starts_at, ends_at, is_active, created_at, updated_at
 ) VALUES ($1, $2, $3, $4, $5, 0, $6, $7, $8, $9, $9)
-RETURNING id, code, discount_id, usage_limit, usage_limit_per_customer, usage_count, starts_at, ends_at, is_active, created_at, updated_at
+RETURNING id, code, discount_id, usage_limit, usage_limit_per_customer, usage_count, starts_at, ends_at, is_active, created_at, updated_at, campaign_id
 `
 
 type CreateCouponParams struct {
@@ -59,6 +111,43 @@ func (q *Queries) CreateCoupon(ctx context.Context, arg CreateCouponParams) (Cou
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CampaignID,
+	)
+	return i, err
+}
+
+const createCouponUsage = `-- name: CreateCouponUsage :one
+INSERT INTO coupon_usage (id, coupon_id, customer_id, order_id, used_at, email)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, coupon_id, customer_id, order_id, used_at, email
+`
+
+type CreateCouponUsageParams struct {
+	ID         uuid.UUID   `json:"id"`
+	CouponID   uuid.UUID   `json:"coupon_id"`
+	CustomerID pgtype.UUID `json:"customer_id"`
+	OrderID    pgtype.UUID `json:"order_id"`
+	UsedAt     time.Time   `json:"used_at"`
+	Email      *string     `json:"email"`
+}
+
+func (q *Queries) CreateCouponUsage(ctx context.Context, arg CreateCouponUsageParams) (CouponUsage, error) {
+	row := q.db.QueryRow(ctx, createCouponUsage,
+		arg.ID,
+		arg.CouponID,
+		arg.CustomerID,
+		arg.OrderID,
+		arg.UsedAt,
+		arg.Email,
+	)
+	var i CouponUsage
+	err := row.Scan(
+		&i.ID,
+		&i.CouponID,
+		&i.CustomerID,
+		&i.OrderID,
+		&i.UsedAt,
+		&i.Email,
 	)
 	return i, err
 }
@@ -146,7 +235,7 @@ func (q *Queries) DeleteDiscount(ctx context.Context, id uuid.UUID) error {
 }
 
 const getCoupon = `-- name: GetCoupon :one
-SELECT id, code, discount_id, usage_limit, usage_limit_per_customer, usage_count, starts_at, ends_at, is_active, created_at, updated_at FROM coupons WHERE id = $1
+SELECT id, code, discount_id, usage_limit, usage_limit_per_customer, usage_count, starts_at, ends_at, is_active, created_at, updated_at, campaign_id FROM coupons WHERE id = $1
 `
 
 func (q *Queries) GetCoupon(ctx context.Context, id uuid.UUID) (Coupon, error) {
@@ -164,12 +253,13 @@ func (q *Queries) GetCoupon(ctx context.Context, id uuid.UUID) (Coupon, error) {
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CampaignID,
 	)
 	return i, err
 }
 
 const getCouponByCode = `-- name: GetCouponByCode :one
-SELECT id, code, discount_id, usage_limit, usage_limit_per_customer, usage_count, starts_at, ends_at, is_active, created_at, updated_at FROM coupons WHERE code = $1
+SELECT id, code, discount_id, usage_limit, usage_limit_per_customer, usage_count, starts_at, ends_at, is_active, created_at, updated_at, campaign_id FROM coupons WHERE code = $1
 `
 
 func (q *Queries) GetCouponByCode(ctx context.Context, code string) (Coupon, error) {
@@ -187,6 +277,7 @@ func (q *Queries) GetCouponByCode(ctx context.Context, code string) (Coupon, err
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.CampaignID,
 	)
 	return i, err
 }
@@ -218,8 +309,9 @@ func (q *Queries) GetDiscount(ctx context.Context, id uuid.UUID) (Discount, erro
 	return i, err
 }
 
-const incrementCouponUsage = `-- name: IncrementCouponUsage :exec
-UPDATE coupons SET usage_count = usage_count + 1, updated_at = $2 WHERE id = $1
+const incrementCouponUsage = `-- name: IncrementCouponUsage :execrows
+UPDATE coupons SET usage_count = usage_count + 1, updated_at = $2
+WHERE id = $1 AND (usage_limit IS NULL OR usage_count < usage_limit)
 `
 
 type IncrementCouponUsageParams struct {
@@ -227,9 +319,15 @@ type IncrementCouponUsageParams struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
-func (q *Queries) IncrementCouponUsage(ctx context.Context, arg IncrementCouponUsageParams) error {
-	_, err := q.db.Exec(ctx, incrementCouponUsage, arg.ID, arg.UpdatedAt)
-	return err
+// Counts one use of a coupon unless its usage limit is already reached. The
+// limit check and increment are one statement, so concurrent redemptions
+// cannot both take the last use.
+func (q *Queries) IncrementCouponUsage(ctx context.Context, arg IncrementCouponUsageParams) (int64, error) {
+	result, err := q.db.Exec(ctx, incrementCouponUsage, arg.ID, arg.UpdatedAt)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected(), nil
 }
 
 const listActiveDiscounts = `-- name: ListActiveDiscounts :many
@@ -237,9 +335,12 @@ SELECT id, name, type, value, scope, minimum_amount, maximum_discount, starts_at
 WHERE is_active = true
 AND (starts_at IS NULL OR starts_at <= NOW())
 AND (ends_at IS NULL OR ends_at > NOW())
+AND NOT EXISTS (SELECT 1 FROM coupons WHERE coupons.discount_id = discounts.id)
 ORDER BY priority DESC
 `
 
+// Returns the discounts applied automatically. Discounts that have coupons
+// only apply when one of their codes is entered.
 func (q *Queries) ListActiveDiscounts(ctx context.Context) ([]Discount, error) {
 	rows, err := q.db.Query(ctx, listActiveDiscounts)
 	if err != nil {
@@ -277,8 +378,9 @@ func (q *Queries) ListActiveDiscounts(ctx context.Context) ([]Discount, error) {
 }
 
 const listCoupons = `-- name: ListCoupons :many
-SELECT c.id, c.code, c.discount_id, c.usage_limit, c.usage_limit_per_customer, c.usage_count, c.starts_at, c.ends_at, c.is_active, c.created_at, c.updated_at, d.name as discount_name FROM coupons c
+SELECT c.id, c.code, c.discount_id, c.usage_limit, c.usage_limit_per_customer, c.usage_count, c.starts_at, c.ends_at, c.is_active, c.created_at, c.updated_at, c.campaign_id, d.name as discount_name FROM coupons c
 JOIN discounts d ON d.id = c.discount_id
+WHERE c.campaign_id IS NULL
 ORDER BY c.created_at DESC LIMIT $1 OFFSET $2
 `
 
@@ -299,9 +401,12 @@ type ListCouponsRow struct {
 	IsActive              bool               `json:"is_active"`
 	CreatedAt             time.Time          `json:"created_at"`
 	UpdatedAt             time.Time          `json:"updated_at"`
+	CampaignID            pgtype.UUID        `json:"campaign_id"`
 	DiscountName          string             `json:"discount_name"`
 }
 
+// Lists the coupons created one at a time. Codes generated by a campaign are
+// listed with the campaign.
 func (q *Queries) ListCoupons(ctx context.Context, arg ListCouponsParams) ([]ListCouponsRow, error) {
 	rows, err := q.db.Query(ctx, listCoupons, arg.Limit, arg.Offset)
 	if err != nil {
@@ -323,6 +428,7 @@ func (q *Queries) ListCoupons(ctx context.Context, arg ListCouponsParams) ([]Lis
 			&i.IsActive,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.CampaignID,
 			&i.DiscountName,
 		); err != nil {
 			return nil, err
@@ -380,6 +486,38 @@ func (q *Queries) ListDiscounts(ctx context.Context, arg ListDiscountsParams) ([
 	return items, nil
 }
 
+const listProductCategoryIDs = `-- name: ListProductCategoryIDs :many
+SELECT product_id, category_id FROM product_categories
+WHERE product_id = ANY($1::uuid[])
+`
+
+type ListProductCategoryIDsRow struct {
+	ProductID  uuid.UUID `json:"product_id"`
+	CategoryID uuid.UUID `json:"category_id"`
+}
+
+// Returns the category memberships of the given products, for category
+// conditions.
+func (q *Queries) ListProductCategoryIDs(ctx context.Context, productIds []uuid.UUID) ([]ListProductCategoryIDsRow, error) {
+	rows, err := q.db.Query(ctx, listProductCategoryIDs, productIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []ListProductCategoryIDsRow{}
+	for rows.Next() {
+		var i ListProductCategoryIDsRow
+		if err := rows.Scan(&i.ProductID, &i.CategoryID); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const updateDiscount = `-- name: UpdateDiscount :one
 UPDATE discounts SET
   name = $2, type = $3, value = $4, scope = $5,
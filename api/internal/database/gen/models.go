@@ -59,6 +59,20 @@ func (ns NullProductionBatchStatus) Value() (driver.Value, error) {
 	return string(ns.ProductionBatchStatus), nil
 }
 
+type AdminApiKey struct {
+	ID         uuid.UUID          `json:"id"`
+	Name       string             `json:"name"`
+	Prefix     string             `json:"prefix"`
+	KeyHash    string             `json:"key_hash"`
+	Scopes     []string           `json:"scopes"`
+	CreatedBy  uuid.UUID          `json:"created_by"`
+	ExpiresAt  pgtype.Timestamptz `json:"expires_at"`
+	LastUsedAt pgtype.Timestamptz `json:"last_used_at"`
+	LastUsedIp *string            `json:"last_used_ip"`
+	RevokedAt  pgtype.Timestamptz `json:"revoked_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
 type AdminAuditLog struct {
 	ID          uuid.UUID   `json:"id"`
 	AdminUserID pgtype.UUID `json:"admin_user_id"`
@@ -87,6 +101,25 @@ type AdminUser struct {
 	UpdatedAt     time.Time          `json:"updated_at"`
 }
 
+type AdminWebauthnChallenge struct {
+	AdminUserID uuid.UUID `json:"admin_user_id"`
+	Ceremony    string    `json:"ceremony"`
+	Challenge   []byte    `json:"challenge"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type AdminWebauthnCredential struct {
+	ID           uuid.UUID          `json:"id"`
+	AdminUserID  uuid.UUID          `json:"admin_user_id"`
+	Name         string             `json:"name"`
+	CredentialID []byte             `json:"credential_id"`
+	PublicKey    []byte             `json:"public_key"`
+	SignCount    int64              `json:"sign_count"`
+	Transports   []string           `json:"transports"`
+	CreatedAt    time.Time          `json:"created_at"`
+	LastUsedAt   pgtype.Timestamptz `json:"last_used_at"`
+}
+
 type AttributeOptionBomEntry struct {
 	ID            uuid.UUID      `json:"id"`
 	OptionID      uuid.UUID      `json:"option_id"`
@@ -117,6 +150,7 @@ type Cart struct {
 	ExpiresAt   time.Time   `json:"expires_at"`
 	CreatedAt   time.Time   `json:"created_at"`
 	UpdatedAt   time.Time   `json:"updated_at"`
+	OrderID     pgtype.UUID `json:"order_id"`
 }
 
 type CartItem struct {
@@ -128,6 +162,16 @@ type CartItem struct {
 	UpdatedAt time.Time `json:"updated_at"`
 }
 
+type CartRecovery struct {
+	ID         uuid.UUID          `json:"id"`
+	CartID     pgtype.UUID        `json:"cart_id"`
+	Email      string             `json:"email"`
+	CouponID   pgtype.UUID        `json:"coupon_id"`
+	OrderID    pgtype.UUID        `json:"order_id"`
+	SentAt     time.Time          `json:"sent_at"`
+	RestoredAt pgtype.Timestamptz `json:"restored_at"`
+}
+
 type Category struct {
 	ID             uuid.UUID   `json:"id"`
 	Name           string      `json:"name"`
@@ -143,6 +187,16 @@ type Category struct {
 	UpdatedAt      time.Time   `json:"updated_at"`
 }
 
+type CheckoutSnapshot struct {
+	ID               uuid.UUID       `json:"id"`
+	CartID           uuid.UUID       `json:"cart_id"`
+	Items            json.RawMessage `json:"items"`
+	Subtotal         pgtype.Numeric  `json:"subtotal"`
+	VatReverseCharge bool            `json:"vat_reverse_charge"`
+	VatCompanyName   *string         `json:"vat_company_name"`
+	CreatedAt        time.Time       `json:"created_at"`
+}
+
 type Coupon struct {
 	ID                    uuid.UUID          `json:"id"`
 	Code                  string             `json:"code"`
@@ -155,6 +209,20 @@ type Coupon struct {
 	IsActive              bool               `json:"is_active"`
 	CreatedAt             time.Time          `json:"created_at"`
 	UpdatedAt             time.Time          `json:"updated_at"`
+	CampaignID            pgtype.UUID        `json:"campaign_id"`
+}
+
+type CouponCampaign struct {
+	ID                uuid.UUID `json:"id"`
+	Name              string    `json:"name"`
+	DiscountID        uuid.UUID `json:"discount_id"`
+	Prefix            string    `json:"prefix"`
+	CodeLength        int32     `json:"code_length"`
+	Alphabet          string    `json:"alphabet"`
+	CodeCount         int32     `json:"code_count"`
+	UsageLimitPerCode int32     `json:"usage_limit_per_code"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
 }
 
 type CouponUsage struct {
@@ -163,24 +231,27 @@ type CouponUsage struct {
 	CustomerID pgtype.UUID `json:"customer_id"`
 	OrderID    pgtype.UUID `json:"order_id"`
 	UsedAt     time.Time   `json:"used_at"`
+	Email      *string     `json:"email"`
 }
 
 type Customer struct {
-	ID                     uuid.UUID       `json:"id"`
-	Email                  string          `json:"email"`
-	FirstName              *string         `json:"first_name"`
-	LastName               *string         `json:"last_name"`
-	Phone                  *string         `json:"phone"`
-	PasswordHash           *string         `json:"password_hash"`
-	DefaultBillingAddress  []byte          `json:"default_billing_address"`
-	DefaultShippingAddress []byte          `json:"default_shipping_address"`
-	AcceptsMarketing       bool            `json:"accepts_marketing"`
-	StripeCustomerID       *string         `json:"stripe_customer_id"`
-	VatNumber              *string         `json:"vat_number"`
-	Notes                  *string         `json:"notes"`
-	Metadata               json.RawMessage `json:"metadata"`
-	CreatedAt              time.Time       `json:"created_at"`
-	UpdatedAt              time.Time       `json:"updated_at"`
+	ID                     uuid.UUID          `json:"id"`
+	Email                  string             `json:"email"`
+	FirstName              *string            `json:"first_name"`
+	LastName               *string            `json:"last_name"`
+	Phone                  *string            `json:"phone"`
+	PasswordHash           *string            `json:"password_hash"`
+	DefaultBillingAddress  []byte             `json:"default_billing_address"`
+	DefaultShippingAddress []byte             `json:"default_shipping_address"`
+	AcceptsMarketing       bool               `json:"accepts_marketing"`
+	StripeCustomerID       *string            `json:"stripe_customer_id"`
+	VatNumber              *string            `json:"vat_number"`
+	Notes                  *string            `json:"notes"`
+	Metadata               json.RawMessage    `json:"metadata"`
+	CreatedAt              time.Time          `json:"created_at"`
+	UpdatedAt              time.Time          `json:"updated_at"`
+	EmailVerifiedAt        pgtype.Timestamptz `json:"email_verified_at"`
+	TokenVersion           int32              `json:"token_version"`
 }
 
 type CustomerAddress struct {
@@ -203,6 +274,17 @@ type CustomerAddress struct {
 	UpdatedAt         time.Time `json:"updated_at"`
 }
 
+type CustomerToken struct {
+	ID         uuid.UUID          `json:"id"`
+	CustomerID uuid.UUID          `json:"customer_id"`
+	Purpose    string             `json:"purpose"`
+	TokenHash  string             `json:"token_hash"`
+	Email      string             `json:"email"`
+	ExpiresAt  time.Time          `json:"expires_at"`
+	UsedAt     pgtype.Timestamptz `json:"used_at"`
+	CreatedAt  time.Time          `json:"created_at"`
+}
+
 type Discount struct {
 	ID              uuid.UUID          `json:"id"`
 	Name            string             `json:"name"`
@@ -221,6 +303,24 @@ type Discount struct {
 	UpdatedAt       time.Time          `json:"updated_at"`
 }
 
+type EmailOutbox struct {
+	ID            uuid.UUID          `json:"id"`
+	Template      string             `json:"template"`
+	Language      string             `json:"language"`
+	Recipient     string             `json:"recipient"`
+	Subject       string             `json:"subject"`
+	HtmlBody      string             `json:"html_body"`
+	TextBody      string             `json:"text_body"`
+	DedupeKey     *string            `json:"dedupe_key"`
+	Status        string             `json:"status"`
+	Attempts      int32              `json:"attempts"`
+	LastError     *string            `json:"last_error"`
+	NextAttemptAt pgtype.Timestamptz `json:"next_attempt_at"`
+	LockedUntil   pgtype.Timestamptz `json:"locked_until"`
+	SentAt        pgtype.Timestamptz `json:"sent_at"`
+	CreatedAt     time.Time          `json:"created_at"`
+}
+
 type EuCountry struct {
 	CountryCode          string `json:"country_code"`
 	Name                 string `json:"name"`
@@ -271,6 +371,23 @@ type GlobalAttributeOption struct {
 	UpdatedAt         time.Time       `json:"updated_at"`
 }
 
+type Invoice struct {
+	ID            uuid.UUID          `json:"id"`
+	OrderID       uuid.UUID          `json:"order_id"`
+	Year          int32              `json:"year"`
+	Sequence      int32              `json:"sequence"`
+	InvoiceNumber string             `json:"invoice_number"`
+	StorageKey    string             `json:"storage_key"`
+	IssuedAt      time.Time          `json:"issued_at"`
+	StoredAt      pgtype.Timestamptz `json:"stored_at"`
+	CreatedAt     time.Time          `json:"created_at"`
+}
+
+type InvoiceSequence struct {
+	Year       int32 `json:"year"`
+	LastNumber int32 `json:"last_number"`
+}
+
 type MediaAsset struct {
 	ID               uuid.UUID       `json:"id"`
 	Filename         string          `json:"filename"`
@@ -373,6 +490,7 @@ type Product struct {
 	Metadata                json.RawMessage `json:"metadata"`
 	CreatedAt               time.Time       `json:"created_at"`
 	UpdatedAt               time.Time       `json:"updated_at"`
+	AllowBackorder          bool            `json:"allow_backorder"`
 }
 
 type ProductAttribute struct {
@@ -521,6 +639,36 @@ type ProductionBatchMaterial struct {
 	UnitCost         pgtype.Numeric `json:"unit_cost"`
 }
 
+type Refund struct {
+	ID               uuid.UUID      `json:"id"`
+	OrderID          uuid.UUID      `json:"order_id"`
+	CreditNoteNumber *int64         `json:"credit_note_number"`
+	Status           string         `json:"status"`
+	Source           string         `json:"source"`
+	Amount           pgtype.Numeric `json:"amount"`
+	NetAmount        pgtype.Numeric `json:"net_amount"`
+	VatAmount        pgtype.Numeric `json:"vat_amount"`
+	Reason           *string        `json:"reason"`
+	Restocked        bool           `json:"restocked"`
+	StripeRefundID   *string        `json:"stripe_refund_id"`
+	FailureMessage   *string        `json:"failure_message"`
+	CreatedBy        pgtype.UUID    `json:"created_by"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+}
+
+type RefundItem struct {
+	ID          uuid.UUID      `json:"id"`
+	RefundID    uuid.UUID      `json:"refund_id"`
+	OrderItemID pgtype.UUID    `json:"order_item_id"`
+	Description string         `json:"description"`
+	Quantity    int32          `json:"quantity"`
+	Amount      pgtype.Numeric `json:"amount"`
+	NetAmount   pgtype.Numeric `json:"net_amount"`
+	VatRate     pgtype.Numeric `json:"vat_rate"`
+	VatAmount   pgtype.Numeric `json:"vat_amount"`
+}
+
 type RawMaterial struct {
 	ID                uuid.UUID       `json:"id"`
 	Name              string          `json:"name"`
@@ -564,6 +712,8 @@ type Session struct {
 	UserAgent   *string         `json:"user_agent"`
 	ExpiresAt   time.Time       `json:"expires_at"`
 	CreatedAt   time.Time       `json:"created_at"`
+	PublicID    uuid.UUID       `json:"public_id"`
+	LastSeenAt  time.Time       `json:"last_seen_at"`
 }
 
 type ShippingConfig struct {
@@ -606,6 +756,19 @@ type StockMovement struct {
 	CreatedAt      time.Time      `json:"created_at"`
 }
 
+type StockReservation struct {
+	ID                      uuid.UUID   `json:"id"`
+	CartID                  pgtype.UUID `json:"cart_id"`
+	VariantID               uuid.UUID   `json:"variant_id"`
+	Quantity                int32       `json:"quantity"`
+	Status                  string      `json:"status"`
+	StripeCheckoutSessionID *string     `json:"stripe_checkout_session_id"`
+	OrderID                 pgtype.UUID `json:"order_id"`
+	ExpiresAt               time.Time   `json:"expires_at"`
+	CreatedAt               time.Time   `json:"created_at"`
+	UpdatedAt               time.Time   `json:"updated_at"`
+}
+
 type StoreSetting struct {
 	ID                         uuid.UUID `json:"id"`
 	StoreName                  string    `json:"store_name"`
@@ -687,15 +850,21 @@ type WebhookDelivery struct {
 	DeliveredAt    pgtype.Timestamptz `json:"delivered_at"`
 	NextRetryAt    pgtype.Timestamptz `json:"next_retry_at"`
 	CreatedAt      time.Time          `json:"created_at"`
+	Status         string             `json:"status"`
+	LastError      *string            `json:"last_error"`
+	LockedUntil    pgtype.Timestamptz `json:"locked_until"`
 }
 
 type WebhookEndpoint struct {
-	ID          uuid.UUID `json:"id"`
-	Url         string    `json:"url"`
-	Secret      string    `json:"secret"`
-	Events      []string  `json:"events"`
-	IsActive    bool      `json:"is_active"`
-	Description *string   `json:"description"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID                     uuid.UUID          `json:"id"`
+	Url                    string             `json:"url"`
+	Secret                 string             `json:"secret"`
+	Events                 []string           `json:"events"`
+	IsActive               bool               `json:"is_active"`
+	Description            *string            `json:"description"`
+	CreatedAt              time.Time          `json:"created_at"`
+	UpdatedAt              time.Time          `json:"updated_at"`
+	ConsecutiveDeadLetters int32              `json:"consecutive_dead_letters"`
+	DisabledAt             pgtype.Timestamptz `json:"disabled_at"`
+	DisabledReason         *string            `json:"disabled_reason"`
 }
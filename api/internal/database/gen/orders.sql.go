@@ -327,6 +327,50 @@ func (q *Queries) GetOrder(ctx context.Context, id uuid.UUID) (Order, error) {
 	return i, err
 }
 
+const getOrderByCheckoutSession = `-- name: GetOrderByCheckoutSession :one
+SELECT id, order_number, customer_id, status, email, billing_address, shipping_address, subtotal, shipping_fee, shipping_extra_fees, discount_amount, vat_total, total, vat_number, vat_company_name, vat_reverse_charge, vat_country_code, stripe_payment_intent_id, stripe_checkout_session_id, payment_status, discount_id, coupon_id, discount_breakdown, shipping_method, tracking_number, shipped_at, delivered_at, notes, customer_notes, metadata, created_at, updated_at FROM orders WHERE stripe_checkout_session_id = $1
+`
+
+func (q *Queries) GetOrderByCheckoutSession(ctx context.Context, stripeCheckoutSessionID *string) (Order, error) {
+	row := q.db.QueryRow(ctx, getOrderByCheckoutSession, stripeCheckoutSessionID)
+	var i Order
+	err := row.Scan(
+		&i.ID,
+		&i.OrderNumber,
+		&i.CustomerID,
+		&i.Status,
+		&i.Email,
+		&i.BillingAddress,
+		&i.ShippingAddress,
+		&i.Subtotal,
+		&i.ShippingFee,
+		&i.ShippingExtraFees,
+		&i.DiscountAmount,
+		&i.VatTotal,
+		&i.Total,
+		&i.VatNumber,
+		&i.VatCompanyName,
+		&i.VatReverseCharge,
+		&i.VatCountryCode,
+		&i.StripePaymentIntentID,
+		&i.StripeCheckoutSessionID,
+		&i.PaymentStatus,
+		&i.DiscountID,
+		&i.CouponID,
+		&i.DiscountBreakdown,
+		&i.ShippingMethod,
+		&i.TrackingNumber,
+		&i.ShippedAt,
+		&i.DeliveredAt,
+		&i.Notes,
+		&i.CustomerNotes,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const getOrderByNumber = `-- name: GetOrderByNumber :one
 SELECT id, order_number, customer_id, status, email, billing_address, shipping_address, subtotal, shipping_fee, shipping_extra_fees, discount_amount, vat_total, total, vat_number, vat_company_name, vat_reverse_charge, vat_country_code, stripe_payment_intent_id, stripe_checkout_session_id, payment_status, discount_id, coupon_id, discount_breakdown, shipping_method, tracking_number, shipped_at, delivered_at, notes, customer_notes, metadata, created_at, updated_at FROM orders WHERE order_number = $1
 `
@@ -371,6 +415,94 @@ func (q *Queries) GetOrderByNumber(ctx context.Context, orderNumber int64) (Orde
 	return i, err
 }
 
+const getOrderByPaymentIntent = `-- name: GetOrderByPaymentIntent :one
+SELECT id, order_number, customer_id, status, email, billing_address, shipping_address, subtotal, shipping_fee, shipping_extra_fees, discount_amount, vat_total, total, vat_number, vat_company_name, vat_reverse_charge, vat_country_code, stripe_payment_intent_id, stripe_checkout_session_id, payment_status, discount_id, coupon_id, discount_breakdown, shipping_method, tracking_number, shipped_at, delivered_at, notes, customer_notes, metadata, created_at, updated_at FROM orders WHERE stripe_payment_intent_id = $1
+`
+
+func (q *Queries) GetOrderByPaymentIntent(ctx context.Context, stripePaymentIntentID *string) (Order, error) {
+	row := q.db.QueryRow(ctx, getOrderByPaymentIntent, stripePaymentIntentID)
+	var i Order
+	err := row.Scan(
+		&i.ID,
+		&i.OrderNumber,
+		&i.CustomerID,
+		&i.Status,
+		&i.Email,
+		&i.BillingAddress,
+		&i.ShippingAddress,
+		&i.Subtotal,
+		&i.ShippingFee,
+		&i.ShippingExtraFees,
+		&i.DiscountAmount,
+		&i.VatTotal,
+		&i.Total,
+		&i.VatNumber,
+		&i.VatCompanyName,
+		&i.VatReverseCharge,
+		&i.VatCountryCode,
+		&i.StripePaymentIntentID,
+		&i.StripeCheckoutSessionID,
+		&i.PaymentStatus,
+		&i.DiscountID,
+		&i.CouponID,
+		&i.DiscountBreakdown,
+		&i.ShippingMethod,
+		&i.TrackingNumber,
+		&i.ShippedAt,
+		&i.DeliveredAt,
+		&i.Notes,
+		&i.CustomerNotes,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
+const getOrderForUpdate = `-- name: GetOrderForUpdate :one
+SELECT id, order_number, customer_id, status, email, billing_address, shipping_address, subtotal, shipping_fee, shipping_extra_fees, discount_amount, vat_total, total, vat_number, vat_company_name, vat_reverse_charge, vat_country_code, stripe_payment_intent_id, stripe_checkout_session_id, payment_status, discount_id, coupon_id, discount_breakdown, shipping_method, tracking_number, shipped_at, delivered_at, notes, customer_notes, metadata, created_at, updated_at FROM orders WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) GetOrderForUpdate(ctx context.Context, id uuid.UUID) (Order, error) {
+	row := q.db.QueryRow(ctx, getOrderForUpdate, id)
+	var i Order
+	err := row.Scan(
+		&i.ID,
+		&i.OrderNumber,
+		&i.CustomerID,
+		&i.Status,
+		&i.Email,
+		&i.BillingAddress,
+		&i.ShippingAddress,
+		&i.Subtotal,
+		&i.ShippingFee,
+		&i.ShippingExtraFees,
+		&i.DiscountAmount,
+		&i.VatTotal,
+		&i.Total,
+		&i.VatNumber,
+		&i.VatCompanyName,
+		&i.VatReverseCharge,
+		&i.VatCountryCode,
+		&i.StripePaymentIntentID,
+		&i.StripeCheckoutSessionID,
+		&i.PaymentStatus,
+		&i.DiscountID,
+		&i.CouponID,
+		&i.DiscountBreakdown,
+		&i.ShippingMethod,
+		&i.TrackingNumber,
+		&i.ShippedAt,
+		&i.DeliveredAt,
+		&i.Notes,
+		&i.CustomerNotes,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const listOrderEvents = `-- name: ListOrderEvents :many
 SELECT id, order_id, event_type, from_status, to_status, data, created_by, created_at FROM order_events WHERE order_id = $1 ORDER BY created_at DESC
 `
@@ -515,6 +647,66 @@ func (q *Queries) ListOrders(ctx context.Context, arg ListOrdersParams) ([]Order
 	return items, nil
 }
 
+const listOrdersByCustomer = `-- name: ListOrdersByCustomer :many
+SELECT id, order_number, customer_id, status, email, billing_address, shipping_address, subtotal, shipping_fee, shipping_extra_fees, discount_amount, vat_total, total, vat_number, vat_company_name, vat_reverse_charge, vat_country_code, stripe_payment_intent_id, stripe_checkout_session_id, payment_status, discount_id, coupon_id, discount_breakdown, shipping_method, tracking_number, shipped_at, delivered_at, notes, customer_notes, metadata, created_at, updated_at FROM orders
+WHERE customer_id = $1
+ORDER BY created_at DESC
+`
+
+// Returns a customer's orders, newest first.
+func (q *Queries) ListOrdersByCustomer(ctx context.Context, customerID pgtype.UUID) ([]Order, error) {
+	rows, err := q.db.Query(ctx, listOrdersByCustomer, customerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []Order{}
+	for rows.Next() {
+		var i Order
+		if err := rows.Scan(
+			&i.ID,
+			&i.OrderNumber,
+			&i.CustomerID,
+			&i.Status,
+			&i.Email,
+			&i.BillingAddress,
+			&i.ShippingAddress,
+			&i.Subtotal,
+			&i.ShippingFee,
+			&i.ShippingExtraFees,
+			&i.DiscountAmount,
+			&i.VatTotal,
+			&i.Total,
+			&i.VatNumber,
+			&i.VatCompanyName,
+			&i.VatReverseCharge,
+			&i.VatCountryCode,
+			&i.StripePaymentIntentID,
+			&i.StripeCheckoutSessionID,
+			&i.PaymentStatus,
+			&i.DiscountID,
+			&i.CouponID,
+			&i.DiscountBreakdown,
+			&i.ShippingMethod,
+			&i.TrackingNumber,
+			&i.ShippedAt,
+			&i.DeliveredAt,
+			&i.Notes,
+			&i.CustomerNotes,
+			&i.Metadata,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listRecentOrders = `-- name: ListRecentOrders :many
 SELECT id, order_number, email, status, payment_status, total, created_at
 FROM orders ORDER BY created_at DESC LIMIT $1
@@ -558,6 +750,34 @@ func (q *Queries) ListRecentOrders(ctx context.Context, limit int32) ([]ListRece
 	return items, nil
 }
 
+const setOrderDeliveredAt = `-- name: SetOrderDeliveredAt :exec
+UPDATE orders SET delivered_at = COALESCE(delivered_at, $2), updated_at = $2 WHERE id = $1
+`
+
+type SetOrderDeliveredAtParams struct {
+	ID          uuid.UUID          `json:"id"`
+	DeliveredAt pgtype.Timestamptz `json:"delivered_at"`
+}
+
+func (q *Queries) SetOrderDeliveredAt(ctx context.Context, arg SetOrderDeliveredAtParams) error {
+	_, err := q.db.Exec(ctx, setOrderDeliveredAt, arg.ID, arg.DeliveredAt)
+	return err
+}
+
+const setOrderShippedAt = `-- name: SetOrderShippedAt :exec
+UPDATE orders SET shipped_at = COALESCE(shipped_at, $2), updated_at = $2 WHERE id = $1
+`
+
+type SetOrderShippedAtParams struct {
+	ID        uuid.UUID          `json:"id"`
+	ShippedAt pgtype.Timestamptz `json:"shipped_at"`
+}
+
+func (q *Queries) SetOrderShippedAt(ctx context.Context, arg SetOrderShippedAtParams) error {
+	_, err := q.db.Exec(ctx, setOrderShippedAt, arg.ID, arg.ShippedAt)
+	return err
+}
+
 const sumRevenueMonth = `-- name: SumRevenueMonth :one
 SELECT COALESCE(SUM(total), 0) FROM orders
 WHERE created_at >= date_trunc('month', CURRENT_DATE) AND payment_status = 'paid'
@@ -570,6 +790,56 @@ func (q *Queries) SumRevenueMonth(ctx context.Context) (interface{}, error) {
 	return coalesce, err
 }
 
+const updateOrderPaymentStatus = `-- name: UpdateOrderPaymentStatus :one
+UPDATE orders SET payment_status = $2, updated_at = $3 WHERE id = $1 RETURNING id, order_number, customer_id, status, email, billing_address, shipping_address, subtotal, shipping_fee, shipping_extra_fees, discount_amount, vat_total, total, vat_number, vat_company_name, vat_reverse_charge, vat_country_code, stripe_payment_intent_id, stripe_checkout_session_id, payment_status, discount_id, coupon_id, discount_breakdown, shipping_method, tracking_number, shipped_at, delivered_at, notes, customer_notes, metadata, created_at, updated_at
+`
+
+type UpdateOrderPaymentStatusParams struct {
+	ID            uuid.UUID `json:"id"`
+	PaymentStatus string    `json:"payment_status"`
+	UpdatedAt     time.Time `json:"updated_at"`
+}
+
+func (q *Queries) UpdateOrderPaymentStatus(ctx context.Context, arg UpdateOrderPaymentStatusParams) (Order, error) {
+	row := q.db.QueryRow(ctx, updateOrderPaymentStatus, arg.ID, arg.PaymentStatus, arg.UpdatedAt)
+	var i Order
+	err := row.Scan(
+		&i.ID,
+		&i.OrderNumber,
+		&i.CustomerID,
+		&i.Status,
+		&i.Email,
+		&i.BillingAddress,
+		&i.ShippingAddress,
+		&i.Subtotal,
+		&i.ShippingFee,
+		&i.ShippingExtraFees,
+		&i.DiscountAmount,
+		&i.VatTotal,
+		&i.Total,
+		&i.VatNumber,
+		&i.VatCompanyName,
+		&i.VatReverseCharge,
+		&i.VatCountryCode,
+		&i.StripePaymentIntentID,
+		&i.StripeCheckoutSessionID,
+		&i.PaymentStatus,
+		&i.DiscountID,
+		&i.CouponID,
+		&i.DiscountBreakdown,
+		&i.ShippingMethod,
+		&i.TrackingNumber,
+		&i.ShippedAt,
+		&i.DeliveredAt,
+		&i.Notes,
+		&i.CustomerNotes,
+		&i.Metadata,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const updateOrderStatus = `-- name: UpdateOrderStatus :one
 UPDATE orders SET status = $2, updated_at = $3 WHERE id = $1 RETURNING id, order_number, customer_id, status, email, billing_address, shipping_address, subtotal, shipping_fee, shipping_extra_fees, discount_amount, vat_total, total, vat_number, vat_company_name, vat_reverse_charge, vat_country_code, stripe_payment_intent_id, stripe_checkout_session_id, payment_status, discount_id, coupon_id, discount_breakdown, shipping_method, tracking_number, shipped_at, delivered_at, notes, customer_notes, metadata, created_at, updated_at
 `
@@ -154,6 +154,32 @@ func (q *Queries) GetProductVariantBySKU(ctx context.Context, sku string) (Produ
 	return i, err
 }
 
+const getProductVariantForUpdate = `-- name: GetProductVariantForUpdate :one
+SELECT id, product_id, sku, price, compare_at_price, weight_grams, dimensions_mm, stock_quantity, low_stock_threshold, barcode, is_active, position, created_at, updated_at FROM product_variants WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) GetProductVariantForUpdate(ctx context.Context, id uuid.UUID) (ProductVariant, error) {
+	row := q.db.QueryRow(ctx, getProductVariantForUpdate, id)
+	var i ProductVariant
+	err := row.Scan(
+		&i.ID,
+		&i.ProductID,
+		&i.Sku,
+		&i.Price,
+		&i.CompareAtPrice,
+		&i.WeightGrams,
+		&i.DimensionsMm,
+		&i.StockQuantity,
+		&i.LowStockThreshold,
+		&i.Barcode,
+		&i.IsActive,
+		&i.Position,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const listLowStockVariants = `-- name: ListLowStockVariants :many
 SELECT pv.id, pv.product_id, pv.sku, pv.price, pv.compare_at_price, pv.weight_grams, pv.dimensions_mm, pv.stock_quantity, pv.low_stock_threshold, pv.barcode, pv.is_active, pv.position, pv.created_at, pv.updated_at, p.name as product_name FROM product_variants pv
 JOIN products p ON p.id = pv.product_id
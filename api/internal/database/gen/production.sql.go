@@ -433,6 +433,41 @@ func (q *Queries) NextBatchNumber(ctx context.Context) (int32, error) {
 	return next_num, err
 }
 
+const setProductionBatchCost = `-- name: SetProductionBatchCost :one
+UPDATE production_batches
+SET cost_total = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING id, batch_number, product_id, variant_id, planned_quantity, actual_quantity, status, scheduled_date, started_at, completed_at, notes, cost_total, created_by, created_at, updated_at
+`
+
+type SetProductionBatchCostParams struct {
+	ID        uuid.UUID      `json:"id"`
+	CostTotal pgtype.Numeric `json:"cost_total"`
+}
+
+func (q *Queries) SetProductionBatchCost(ctx context.Context, arg SetProductionBatchCostParams) (ProductionBatch, error) {
+	row := q.db.QueryRow(ctx, setProductionBatchCost, arg.ID, arg.CostTotal)
+	var i ProductionBatch
+	err := row.Scan(
+		&i.ID,
+		&i.BatchNumber,
+		&i.ProductID,
+		&i.VariantID,
+		&i.PlannedQuantity,
+		&i.ActualQuantity,
+		&i.Status,
+		&i.ScheduledDate,
+		&i.StartedAt,
+		&i.CompletedAt,
+		&i.Notes,
+		&i.CostTotal,
+		&i.CreatedBy,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const startProductionBatch = `-- name: StartProductionBatch :one
 UPDATE production_batches
 SET status = 'in_progress', started_at = NOW(), updated_at = NOW()
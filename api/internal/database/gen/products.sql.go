@@ -50,16 +50,16 @@ INSERT INTO products (
   base_weight_grams, base_dimensions_mm,
   shipping_extra_fee_per_unit, has_variants,
   seo_title, seo_description, metadata,
-  created_at, updated_at
+  created_at, updated_at, allow_backorder
 ) VALUES (
   $1, $2, $3, $4, $5, $6, $7,
   $8, $9, $10,
   $11, $12,
   $13, $14,
   $15, $16, $17,
-  $18, $18
+  $18, $18, $19
 )
-RETURNING id, name, slug, description, short_description, status, sku_prefix, base_price, compare_at_price, vat_category_id, base_weight_grams, base_dimensions_mm, shipping_extra_fee_per_unit, has_variants, seo_title, seo_description, metadata, created_at, updated_at
+RETURNING id, name, slug, description, short_description, status, sku_prefix, base_price, compare_at_price, vat_category_id, base_weight_grams, base_dimensions_mm, shipping_extra_fee_per_unit, has_variants, seo_title, seo_description, metadata, created_at, updated_at, allow_backorder
 `
 
 type CreateProductParams struct {
@@ -81,6 +81,7 @@ type CreateProductParams struct {
 	SeoDescription          *string         `json:"seo_description"`
 	Metadata                json.RawMessage `json:"metadata"`
 	CreatedAt               time.Time       `json:"created_at"`
+	AllowBackorder          bool            `json:"allow_backorder"`
 }
 
 func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (Product, error) {
@@ -103,6 +104,7 @@ func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (P
 		arg.SeoDescription,
 		arg.Metadata,
 		arg.CreatedAt,
+		arg.AllowBackorder,
 	)
 	var i Product
 	err := row.Scan(
@@ -125,6 +127,7 @@ func (q *Queries) CreateProduct(ctx context.Context, arg CreateProductParams) (P
 		&i.Metadata,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.AllowBackorder,
 	)
 	return i, err
 }
@@ -139,7 +142,7 @@ func (q *Queries) DeleteProduct(ctx context.Context, id uuid.UUID) error {
 }
 
 const getProduct = `-- name: GetProduct :one
-SELECT id, name, slug, description, short_description, status, sku_prefix, base_price, compare_at_price, vat_category_id, base_weight_grams, base_dimensions_mm, shipping_extra_fee_per_unit, has_variants, seo_title, seo_description, metadata, created_at, updated_at FROM products WHERE id = $1
+SELECT id, name, slug, description, short_description, status, sku_prefix, base_price, compare_at_price, vat_category_id, base_weight_grams, base_dimensions_mm, shipping_extra_fee_per_unit, has_variants, seo_title, seo_description, metadata, created_at, updated_at, allow_backorder FROM products WHERE id = $1
 `
 
 func (q *Queries) GetProduct(ctx context.Context, id uuid.UUID) (Product, error) {
@@ -165,12 +168,13 @@ func (q *Queries) GetProduct(ctx context.Context, id uuid.UUID) (Product, error)
 		&i.Metadata,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.AllowBackorder,
 	)
 	return i, err
 }
 
 const getProductBySlug = `-- name: GetProductBySlug :one
-SELECT id, name, slug, description, short_description, status, sku_prefix, base_price, compare_at_price, vat_category_id, base_weight_grams, base_dimensions_mm, shipping_extra_fee_per_unit, has_variants, seo_title, seo_description, metadata, created_at, updated_at FROM products WHERE slug = $1
+SELECT id, name, slug, description, short_description, status, sku_prefix, base_price, compare_at_price, vat_category_id, base_weight_grams, base_dimensions_mm, shipping_extra_fee_per_unit, has_variants, seo_title, seo_description, metadata, created_at, updated_at, allow_backorder FROM products WHERE slug = $1
 `
 
 func (q *Queries) GetProductBySlug(ctx context.Context, slug string) (Product, error) {
@@ -196,6 +200,7 @@ func (q *Queries) GetProductBySlug(ctx context.Context, slug string) (Product, e
 		&i.Metadata,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.AllowBackorder,
 	)
 	return i, err
 }
@@ -241,7 +246,7 @@ func (q *Queries) ListProductCategories(ctx context.Context, productID uuid.UUID
 }
 
 const listProducts = `-- name: ListProducts :many
-SELECT id, name, slug, description, short_description, status, sku_prefix, base_price, compare_at_price, vat_category_id, base_weight_grams, base_dimensions_mm, shipping_extra_fee_per_unit, has_variants, seo_title, seo_description, metadata, created_at, updated_at FROM products
+SELECT id, name, slug, description, short_description, status, sku_prefix, base_price, compare_at_price, vat_category_id, base_weight_grams, base_dimensions_mm, shipping_extra_fee_per_unit, has_variants, seo_title, seo_description, metadata, created_at, updated_at, allow_backorder FROM products
 WHERE ($1::text = '' OR status = $1::text)
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3
@@ -282,6 +287,7 @@ func (q *Queries) ListProducts(ctx context.Context, arg ListProductsParams) ([]P
 			&i.Metadata,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.AllowBackorder,
 		); err != nil {
 			return nil, err
 		}
@@ -294,7 +300,7 @@ func (q *Queries) ListProducts(ctx context.Context, arg ListProductsParams) ([]P
 }
 
 const searchProducts = `-- name: SearchProducts :many
-SELECT id, name, slug, description, short_description, status, sku_prefix, base_price, compare_at_price, vat_category_id, base_weight_grams, base_dimensions_mm, shipping_extra_fee_per_unit, has_variants, seo_title, seo_description, metadata, created_at, updated_at FROM products
+SELECT id, name, slug, description, short_description, status, sku_prefix, base_price, compare_at_price, vat_category_id, base_weight_grams, base_dimensions_mm, shipping_extra_fee_per_unit, has_variants, seo_title, seo_description, metadata, created_at, updated_at, allow_backorder FROM products
 WHERE (name ILIKE '%' || $1 || '%' OR sku_prefix ILIKE '%' || $1 || '%')
 AND ($2::text = '' OR status = $2::text)
 ORDER BY created_at DESC
@@ -342,6 +348,7 @@ func (q *Queries) SearchProducts(ctx context.Context, arg SearchProductsParams)
 			&i.Metadata,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.AllowBackorder,
 		); err != nil {
 			return nil, err
 		}
@@ -370,9 +377,9 @@ UPDATE products SET
   base_weight_grams = $11, base_dimensions_mm = $12,
   shipping_extra_fee_per_unit = $13, has_variants = $14,
   seo_title = $15, seo_description = $16, metadata = $17,
-  updated_at = $18
+  updated_at = $18, allow_backorder = $19
 WHERE id = $1
-RETURNING id, name, slug, description, short_description, status, sku_prefix, base_price, compare_at_price, vat_category_id, base_weight_grams, base_dimensions_mm, shipping_extra_fee_per_unit, has_variants, seo_title, seo_description, metadata, created_at, updated_at
+RETURNING id, name, slug, description, short_description, status, sku_prefix, base_price, compare_at_price, vat_category_id, base_weight_grams, base_dimensions_mm, shipping_extra_fee_per_unit, has_variants, seo_title, seo_description, metadata, created_at, updated_at, allow_backorder
 `
 
 type UpdateProductParams struct {
@@ -394,6 +401,7 @@ type UpdateProductParams struct {
 	SeoDescription          *string         `json:"seo_description"`
 	Metadata                json.RawMessage `json:"metadata"`
 	UpdatedAt               time.Time       `json:"updated_at"`
+	AllowBackorder          bool            `json:"allow_backorder"`
 }
 
 func (q *Queries) UpdateProduct(ctx context.Context, arg UpdateProductParams) (Product, error) {
@@ -416,6 +424,7 @@ func (q *Queries) UpdateProduct(ctx context.Context, arg UpdateProductParams) (P
 		arg.SeoDescription,
 		arg.Metadata,
 		arg.UpdatedAt,
+		arg.AllowBackorder,
 	)
 	var i Product
 	err := row.Scan(
@@ -438,6 +447,7 @@ func (q *Queries) UpdateProduct(ctx context.Context, arg UpdateProductParams) (P
 		&i.Metadata,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.AllowBackorder,
 	)
 	return i, err
 }
@@ -173,6 +173,34 @@ func (q *Queries) GetRawMaterial(ctx context.Context, id uuid.UUID) (RawMaterial
 	return i, err
 }
 
+const getRawMaterialForUpdate = `-- name: GetRawMaterialForUpdate :one
+SELECT id, name, sku, description, category_id, unit_of_measure, cost_per_unit, stock_quantity, low_stock_threshold, supplier_name, supplier_sku, lead_time_days, metadata, is_active, created_at, updated_at FROM raw_materials WHERE id = $1 FOR UPDATE
+`
+
+func (q *Queries) GetRawMaterialForUpdate(ctx context.Context, id uuid.UUID) (RawMaterial, error) {
+	row := q.db.QueryRow(ctx, getRawMaterialForUpdate, id)
+	var i RawMaterial
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Sku,
+		&i.Description,
+		&i.CategoryID,
+		&i.UnitOfMeasure,
+		&i.CostPerUnit,
+		&i.StockQuantity,
+		&i.LowStockThreshold,
+		&i.SupplierName,
+		&i.SupplierSku,
+		&i.LeadTimeDays,
+		&i.Metadata,
+		&i.IsActive,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
+
 const listLowStockRawMaterials = `-- name: ListLowStockRawMaterials :many
 SELECT id, name, sku, description, category_id, unit_of_measure, cost_per_unit, stock_quantity, low_stock_threshold, supplier_name, supplier_sku, lead_time_days, metadata, is_active, created_at, updated_at FROM raw_materials
 WHERE stock_quantity <= low_stock_threshold AND is_active = true
@@ -305,6 +333,49 @@ func (q *Queries) ListRawMaterials(ctx context.Context, arg ListRawMaterialsPara
 	return items, nil
 }
 
+const listRawMaterialsByIDs = `-- name: ListRawMaterialsByIDs :many
+SELECT id, name, sku, description, category_id, unit_of_measure, cost_per_unit, stock_quantity, low_stock_threshold, supplier_name, supplier_sku, lead_time_days, metadata, is_active, created_at, updated_at FROM raw_materials
+WHERE id = ANY($1::uuid[])
+ORDER BY name
+`
+
+func (q *Queries) ListRawMaterialsByIDs(ctx context.Context, ids []uuid.UUID) ([]RawMaterial, error) {
+	rows, err := q.db.Query(ctx, listRawMaterialsByIDs, ids)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []RawMaterial{}
+	for rows.Next() {
+		var i RawMaterial
+		if err := rows.Scan(
+			&i.ID,
+			&i.Name,
+			&i.Sku,
+			&i.Description,
+			&i.CategoryID,
+			&i.UnitOfMeasure,
+			&i.CostPerUnit,
+			&i.StockQuantity,
+			&i.LowStockThreshold,
+			&i.SupplierName,
+			&i.SupplierSku,
+			&i.LeadTimeDays,
+			&i.Metadata,
+			&i.IsActive,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const searchRawMaterials = `-- name: SearchRawMaterials :many
 SELECT id, name, sku, description, category_id, unit_of_measure, cost_per_unit, stock_quantity, low_stock_threshold, supplier_name, supplier_sku, lead_time_days, metadata, is_active, created_at, updated_at FROM raw_materials
 WHERE (name ILIKE '%' || $1 || '%' OR sku ILIKE '%' || $1 || '%')
@@ -422,3 +493,17 @@ func (q *Queries) UpdateRawMaterial(ctx context.Context, arg UpdateRawMaterialPa
 	)
 	return i, err
 }
+
+const updateRawMaterialStock = `-- name: UpdateRawMaterialStock :exec
+UPDATE raw_materials SET stock_quantity = $2, updated_at = now() WHERE id = $1
+`
+
+type UpdateRawMaterialStockParams struct {
+	ID            uuid.UUID      `json:"id"`
+	StockQuantity pgtype.Numeric `json:"stock_quantity"`
+}
+
+func (q *Queries) UpdateRawMaterialStock(ctx context.Context, arg UpdateRawMaterialStockParams) error {
+	_, err := q.db.Exec(ctx, updateRawMaterialStock, arg.ID, arg.StockQuantity)
+	return err
+}
@@ -13,6 +13,185 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 )
 
+const cartRecoverySummary = `-- name: CartRecoverySummary :one
+SELECT
+  COUNT(*) as emails_sent,
+  COUNT(r.restored_at) as carts_restored,
+  COUNT(o.id) as orders_recovered,
+  COALESCE(SUM(o.total), 0) as recovered_revenue
+FROM cart_recoveries r
+LEFT JOIN orders o ON o.id = r.order_id AND o.payment_status = 'paid'
+WHERE r.sent_at >= $1
+  AND r.sent_at < $2
+`
+
+type CartRecoverySummaryParams struct {
+	FromDate time.Time `json:"from_date"`
+	ToDate   time.Time `json:"to_date"`
+}
+
+type CartRecoverySummaryRow struct {
+	EmailsSent       int64       `json:"emails_sent"`
+	CartsRestored    int64       `json:"carts_restored"`
+	OrdersRecovered  int64       `json:"orders_recovered"`
+	RecoveredRevenue interface{} `json:"recovered_revenue"`
+}
+
+// Abandoned cart reminders sent in a period, how many were restored, and the
+// paid orders and revenue they led to.
+func (q *Queries) CartRecoverySummary(ctx context.Context, arg CartRecoverySummaryParams) (CartRecoverySummaryRow, error) {
+	row := q.db.QueryRow(ctx, cartRecoverySummary, arg.FromDate, arg.ToDate)
+	var i CartRecoverySummaryRow
+	err := row.Scan(
+		&i.EmailsSent,
+		&i.CartsRestored,
+		&i.OrdersRecovered,
+		&i.RecoveredRevenue,
+	)
+	return i, err
+}
+
+const oSSRefundsByCountry = `-- name: OSSRefundsByCountry :many
+SELECT
+  o.vat_country_code as country_code,
+  ec.name as country_name,
+  ri.vat_rate as rate,
+  EXTRACT(YEAR FROM o.created_at AT TIME ZONE 'UTC')::integer as order_year,
+  EXTRACT(QUARTER FROM o.created_at AT TIME ZONE 'UTC')::integer as order_quarter,
+  COALESCE(SUM(ri.net_amount), 0)::numeric as net_amount,
+  COALESCE(SUM(ri.vat_amount), 0)::numeric as vat_amount
+FROM refunds r
+JOIN refund_items ri ON ri.refund_id = r.id
+JOIN orders o ON o.id = r.order_id
+JOIN eu_countries ec ON ec.country_code = o.vat_country_code
+WHERE r.status = 'succeeded'
+  AND o.vat_reverse_charge = false
+  AND r.created_at >= $1
+  AND r.created_at < $2
+GROUP BY o.vat_country_code, ec.name, ri.vat_rate, order_year, order_quarter
+ORDER BY o.vat_country_code, ri.vat_rate DESC, order_year, order_quarter
+`
+
+type OSSRefundsByCountryParams struct {
+	FromDate time.Time `json:"from_date"`
+	ToDate   time.Time `json:"to_date"`
+}
+
+type OSSRefundsByCountryRow struct {
+	CountryCode  *string        `json:"country_code"`
+	CountryName  string         `json:"country_name"`
+	Rate         pgtype.Numeric `json:"rate"`
+	OrderYear    int32          `json:"order_year"`
+	OrderQuarter int32          `json:"order_quarter"`
+	NetAmount    pgtype.Numeric `json:"net_amount"`
+	VatAmount    pgtype.Numeric `json:"vat_amount"`
+}
+
+// Succeeded refunds of B2C orders made in a period, per EU destination
+// country and VAT rate, with the year and quarter the order was placed in.
+func (q *Queries) OSSRefundsByCountry(ctx context.Context, arg OSSRefundsByCountryParams) ([]OSSRefundsByCountryRow, error) {
+	rows, err := q.db.Query(ctx, oSSRefundsByCountry, arg.FromDate, arg.ToDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OSSRefundsByCountryRow{}
+	for rows.Next() {
+		var i OSSRefundsByCountryRow
+		if err := rows.Scan(
+			&i.CountryCode,
+			&i.CountryName,
+			&i.Rate,
+			&i.OrderYear,
+			&i.OrderQuarter,
+			&i.NetAmount,
+			&i.VatAmount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const oSSSalesByCountry = `-- name: OSSSalesByCountry :many
+WITH lines AS (
+  SELECT
+    o.vat_country_code,
+    oi.vat_rate_type,
+    oi.vat_rate,
+    oi.net_unit_price * oi.quantity as net,
+    oi.vat_amount as vat,
+    1 - LEAST(o.discount_amount, SUM(oi.total_price) OVER (PARTITION BY o.id))
+      / NULLIF(SUM(oi.total_price) OVER (PARTITION BY o.id), 0) as kept
+  FROM orders o
+  JOIN order_items oi ON oi.order_id = o.id
+  WHERE o.payment_status IN ('paid', 'partially_refunded', 'refunded')
+    AND o.vat_reverse_charge = false
+    AND o.created_at >= $1
+    AND o.created_at < $2
+)
+SELECT
+  l.vat_country_code as country_code,
+  ec.name as country_name,
+  l.vat_rate_type as rate_type,
+  l.vat_rate as rate,
+  COALESCE(ROUND(SUM(l.net * COALESCE(l.kept, 1)), 2), 0)::numeric as net_sales,
+  COALESCE(ROUND(SUM(l.vat * COALESCE(l.kept, 1)), 2), 0)::numeric as vat_amount
+FROM lines l
+JOIN eu_countries ec ON ec.country_code = l.vat_country_code
+GROUP BY l.vat_country_code, ec.name, l.vat_rate_type, l.vat_rate
+ORDER BY l.vat_country_code, l.vat_rate DESC
+`
+
+type OSSSalesByCountryParams struct {
+	FromDate time.Time `json:"from_date"`
+	ToDate   time.Time `json:"to_date"`
+}
+
+type OSSSalesByCountryRow struct {
+	CountryCode *string        `json:"country_code"`
+	CountryName string         `json:"country_name"`
+	RateType    *string        `json:"rate_type"`
+	Rate        pgtype.Numeric `json:"rate"`
+	NetSales    pgtype.Numeric `json:"net_sales"`
+	VatAmount   pgtype.Numeric `json:"vat_amount"`
+}
+
+// B2C sales per EU destination country and VAT rate for a period. Orders
+// refunded since are included; their refunds are reported separately. An
+// order's discount is spread over its lines by gross total, so each rate
+// reports what was actually charged.
+func (q *Queries) OSSSalesByCountry(ctx context.Context, arg OSSSalesByCountryParams) ([]OSSSalesByCountryRow, error) {
+	rows, err := q.db.Query(ctx, oSSSalesByCountry, arg.FromDate, arg.ToDate)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []OSSSalesByCountryRow{}
+	for rows.Next() {
+		var i OSSSalesByCountryRow
+		if err := rows.Scan(
+			&i.CountryCode,
+			&i.CountryName,
+			&i.RateType,
+			&i.Rate,
+			&i.NetSales,
+			&i.VatAmount,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const salesReportDaily = `-- name: SalesReportDaily :many
 SELECT
   DATE(created_at) as report_date,
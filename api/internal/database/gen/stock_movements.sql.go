@@ -74,10 +74,29 @@ func (q *Queries) CreateStockMovement(ctx context.Context, arg CreateStockMoveme
 	return i, err
 }
 
-const listStockMovements = `-- name: ListStockMovements :many
-SELECT id, entity_type, entity_id, movement_type, quantity_change, quantity_before, quantity_after, reference_type, reference_id, unit_cost, notes, created_by, created_at FROM stock_movements
+const countStockMovements = `-- name: CountStockMovements :one
+SELECT COUNT(*) FROM stock_movements
 WHERE entity_type = $1 AND entity_id = $2
-ORDER BY created_at DESC
+`
+
+type CountStockMovementsParams struct {
+	EntityType string    `json:"entity_type"`
+	EntityID   uuid.UUID `json:"entity_id"`
+}
+
+func (q *Queries) CountStockMovements(ctx context.Context, arg CountStockMovementsParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countStockMovements, arg.EntityType, arg.EntityID)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const listStockMovements = `-- name: ListStockMovements :many
+SELECT sm.id, sm.entity_type, sm.entity_id, sm.movement_type, sm.quantity_change, sm.quantity_before, sm.quantity_after, sm.reference_type, sm.reference_id, sm.unit_cost, sm.notes, sm.created_by, sm.created_at, au.name AS created_by_name
+FROM stock_movements sm
+LEFT JOIN admin_users au ON au.id = sm.created_by
+WHERE sm.entity_type = $1 AND sm.entity_id = $2
+ORDER BY sm.created_at DESC, sm.id
 LIMIT $3 OFFSET $4
 `
 
@@ -88,7 +107,24 @@ type ListStockMovementsParams struct {
 	Offset     int32     `json:"offset"`
 }
 
-func (q *Queries) ListStockMovements(ctx context.Context, arg ListStockMovementsParams) ([]StockMovement, error) {
+type ListStockMovementsRow struct {
+	ID             uuid.UUID      `json:"id"`
+	EntityType     string         `json:"entity_type"`
+	EntityID       uuid.UUID      `json:"entity_id"`
+	MovementType   string         `json:"movement_type"`
+	QuantityChange pgtype.Numeric `json:"quantity_change"`
+	QuantityBefore pgtype.Numeric `json:"quantity_before"`
+	QuantityAfter  pgtype.Numeric `json:"quantity_after"`
+	ReferenceType  *string        `json:"reference_type"`
+	ReferenceID    pgtype.UUID    `json:"reference_id"`
+	UnitCost       pgtype.Numeric `json:"unit_cost"`
+	Notes          *string        `json:"notes"`
+	CreatedBy      pgtype.UUID    `json:"created_by"`
+	CreatedAt      time.Time      `json:"created_at"`
+	CreatedByName  *string        `json:"created_by_name"`
+}
+
+func (q *Queries) ListStockMovements(ctx context.Context, arg ListStockMovementsParams) ([]ListStockMovementsRow, error) {
 	rows, err := q.db.Query(ctx, listStockMovements,
 		arg.EntityType,
 		arg.EntityID,
@@ -99,9 +135,9 @@ func (q *Queries) ListStockMovements(ctx context.Context, arg ListStockMovements
 		return nil, err
 	}
 	defer rows.Close()
-	items := []StockMovement{}
+	items := []ListStockMovementsRow{}
 	for rows.Next() {
-		var i StockMovement
+		var i ListStockMovementsRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.EntityType,
@@ -116,6 +152,7 @@ func (q *Queries) ListStockMovements(ctx context.Context, arg ListStockMovements
 			&i.Notes,
 			&i.CreatedBy,
 			&i.CreatedAt,
+			&i.CreatedByName,
 		); err != nil {
 			return nil, err
 		}
@@ -408,6 +408,21 @@ func (q *Queries) SetShippingCountryEnabled(ctx context.Context, arg SetShipping
 	return err
 }
 
+const updateStoreAddress = `-- name: UpdateStoreAddress :exec
+UPDATE store_settings SET store_address = $1, updated_at = $2
+WHERE id = (SELECT id FROM store_settings LIMIT 1)
+`
+
+type UpdateStoreAddressParams struct {
+	StoreAddress []byte    `json:"store_address"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+func (q *Queries) UpdateStoreAddress(ctx context.Context, arg UpdateStoreAddressParams) error {
+	_, err := q.db.Exec(ctx, updateStoreAddress, arg.StoreAddress, arg.UpdatedAt)
+	return err
+}
+
 const updateStoreVATSettings = `-- name: UpdateStoreVATSettings :exec
 UPDATE store_settings SET
   vat_enabled = $1, vat_number = $2, vat_country_code = $3,
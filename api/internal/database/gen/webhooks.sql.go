@@ -28,7 +28,7 @@ func (q *Queries) CountWebhookDeliveries(ctx context.Context, endpointID uuid.UU
 const createWebhookDelivery = `-- name: CreateWebhookDelivery :one
 INSERT INTO webhook_deliveries (endpoint_id, event_type, payload)
 VALUES ($1, $2, $3)
-RETURNING id, endpoint_id, event_type, payload, response_status, response_body, attempt, delivered_at, next_retry_at, created_at
+RETURNING id, endpoint_id, event_type, payload, response_status, response_body, attempt, delivered_at, next_retry_at, created_at, status, last_error, locked_until
 `
 
 type CreateWebhookDeliveryParams struct {
@@ -51,6 +51,9 @@ func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDe
 		&i.DeliveredAt,
 		&i.NextRetryAt,
 		&i.CreatedAt,
+		&i.Status,
+		&i.LastError,
+		&i.LockedUntil,
 	)
 	return i, err
 }
@@ -58,7 +61,7 @@ func (q *Queries) CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDe
 const createWebhookEndpoint = `-- name: CreateWebhookEndpoint :one
 INSERT INTO webhook_endpoints (url, secret, events, description, is_active)
 VALUES ($1, $2, $3, $4, $5)
-RETURNING id, url, secret, events, is_active, description, created_at, updated_at
+RETURNING id, url, secret, events, is_active, description, created_at, updated_at, consecutive_dead_letters, disabled_at, disabled_reason
 `
 
 type CreateWebhookEndpointParams struct {
@@ -87,6 +90,9 @@ func (q *Queries) CreateWebhookEndpoint(ctx context.Context, arg CreateWebhookEn
 		&i.Description,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ConsecutiveDeadLetters,
+		&i.DisabledAt,
+		&i.DisabledReason,
 	)
 	return i, err
 }
@@ -100,8 +106,49 @@ func (q *Queries) DeleteWebhookEndpoint(ctx context.Context, id uuid.UUID) error
 	return err
 }
 
+const disableWebhookEndpoint = `-- name: DisableWebhookEndpoint :exec
+UPDATE webhook_endpoints
+SET is_active = false, disabled_at = NOW(), disabled_reason = $2, updated_at = NOW()
+WHERE id = $1
+`
+
+type DisableWebhookEndpointParams struct {
+	ID             uuid.UUID `json:"id"`
+	DisabledReason *string   `json:"disabled_reason"`
+}
+
+func (q *Queries) DisableWebhookEndpoint(ctx context.Context, arg DisableWebhookEndpointParams) error {
+	_, err := q.db.Exec(ctx, disableWebhookEndpoint, arg.ID, arg.DisabledReason)
+	return err
+}
+
+const getWebhookDelivery = `-- name: GetWebhookDelivery :one
+SELECT id, endpoint_id, event_type, payload, response_status, response_body, attempt, delivered_at, next_retry_at, created_at, status, last_error, locked_until FROM webhook_deliveries WHERE id = $1
+`
+
+func (q *Queries) GetWebhookDelivery(ctx context.Context, id uuid.UUID) (WebhookDelivery, error) {
+	row := q.db.QueryRow(ctx, getWebhookDelivery, id)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.EndpointID,
+		&i.EventType,
+		&i.Payload,
+		&i.ResponseStatus,
+		&i.ResponseBody,
+		&i.Attempt,
+		&i.DeliveredAt,
+		&i.NextRetryAt,
+		&i.CreatedAt,
+		&i.Status,
+		&i.LastError,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
 const getWebhookEndpoint = `-- name: GetWebhookEndpoint :one
-SELECT id, url, secret, events, is_active, description, created_at, updated_at FROM webhook_endpoints WHERE id = $1
+SELECT id, url, secret, events, is_active, description, created_at, updated_at, consecutive_dead_letters, disabled_at, disabled_reason FROM webhook_endpoints WHERE id = $1
 `
 
 func (q *Queries) GetWebhookEndpoint(ctx context.Context, id uuid.UUID) (WebhookEndpoint, error) {
@@ -116,55 +163,53 @@ func (q *Queries) GetWebhookEndpoint(ctx context.Context, id uuid.UUID) (Webhook
 		&i.Description,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ConsecutiveDeadLetters,
+		&i.DisabledAt,
+		&i.DisabledReason,
 	)
 	return i, err
 }
 
-const listActiveWebhookEndpoints = `-- name: ListActiveWebhookEndpoints :many
-SELECT id, url, secret, events, is_active, description, created_at, updated_at FROM webhook_endpoints WHERE is_active = true ORDER BY created_at DESC
+const incrementWebhookEndpointDeadLetters = `-- name: IncrementWebhookEndpointDeadLetters :one
+UPDATE webhook_endpoints
+SET consecutive_dead_letters = consecutive_dead_letters + 1
+WHERE id = $1
+RETURNING consecutive_dead_letters
 `
 
-func (q *Queries) ListActiveWebhookEndpoints(ctx context.Context) ([]WebhookEndpoint, error) {
-	rows, err := q.db.Query(ctx, listActiveWebhookEndpoints)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-	items := []WebhookEndpoint{}
-	for rows.Next() {
-		var i WebhookEndpoint
-		if err := rows.Scan(
-			&i.ID,
-			&i.Url,
-			&i.Secret,
-			&i.Events,
-			&i.IsActive,
-			&i.Description,
-			&i.CreatedAt,
-			&i.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		items = append(items, i)
-	}
-	if err := rows.Err(); err != nil {
-		return nil, err
-	}
-	return items, nil
+func (q *Queries) IncrementWebhookEndpointDeadLetters(ctx context.Context, id uuid.UUID) (int32, error) {
+	row := q.db.QueryRow(ctx, incrementWebhookEndpointDeadLetters, id)
+	var consecutive_dead_letters int32
+	err := row.Scan(&consecutive_dead_letters)
+	return consecutive_dead_letters, err
 }
 
-const listPendingWebhookDeliveries = `-- name: ListPendingWebhookDeliveries :many
-SELECT wd.id, wd.endpoint_id, wd.event_type, wd.payload, wd.response_status, wd.response_body, wd.attempt, wd.delivered_at, wd.next_retry_at, wd.created_at, we.url, we.secret
-FROM webhook_deliveries wd
-JOIN webhook_endpoints we ON we.id = wd.endpoint_id
-WHERE wd.delivered_at IS NULL
-  AND (wd.next_retry_at IS NULL OR wd.next_retry_at <= NOW())
-  AND wd.attempt < 5
-ORDER BY wd.created_at ASC
-LIMIT $1
+const leaseWebhookDeliveries = `-- name: LeaseWebhookDeliveries :many
+WITH due AS (
+    SELECT wd.id
+    FROM webhook_deliveries wd
+    JOIN webhook_endpoints we ON we.id = wd.endpoint_id
+    WHERE wd.status = 'pending'
+      AND wd.next_retry_at <= NOW()
+      AND (wd.locked_until IS NULL OR wd.locked_until <= NOW())
+      AND we.is_active = true
+    ORDER BY wd.next_retry_at ASC
+    LIMIT $1
+    FOR UPDATE OF wd SKIP LOCKED
+)
+UPDATE webhook_deliveries d
+SET locked_until = $2
+FROM due, webhook_endpoints e
+WHERE d.id = due.id AND e.id = d.endpoint_id
+RETURNING d.id, d.endpoint_id, d.event_type, d.payload, d.response_status, d.response_body, d.attempt, d.delivered_at, d.next_retry_at, d.created_at, d.status, d.last_error, d.locked_until, e.url, e.secret
 `
 
-type ListPendingWebhookDeliveriesRow struct {
+type LeaseWebhookDeliveriesParams struct {
+	Limit       int32              `json:"limit"`
+	LockedUntil pgtype.Timestamptz `json:"locked_until"`
+}
+
+type LeaseWebhookDeliveriesRow struct {
 	ID             uuid.UUID          `json:"id"`
 	EndpointID     uuid.UUID          `json:"endpoint_id"`
 	EventType      string             `json:"event_type"`
@@ -175,19 +220,25 @@ type ListPendingWebhookDeliveriesRow struct {
 	DeliveredAt    pgtype.Timestamptz `json:"delivered_at"`
 	NextRetryAt    pgtype.Timestamptz `json:"next_retry_at"`
 	CreatedAt      time.Time          `json:"created_at"`
+	Status         string             `json:"status"`
+	LastError      *string            `json:"last_error"`
+	LockedUntil    pgtype.Timestamptz `json:"locked_until"`
 	Url            string             `json:"url"`
 	Secret         string             `json:"secret"`
 }
 
-func (q *Queries) ListPendingWebhookDeliveries(ctx context.Context, limit int32) ([]ListPendingWebhookDeliveriesRow, error) {
-	rows, err := q.db.Query(ctx, listPendingWebhookDeliveries, limit)
+// Claims due deliveries until locked_until. SKIP LOCKED lets several workers
+// lease concurrently; an expired lease (e.g. the process died mid-send)
+// makes the delivery due again.
+func (q *Queries) LeaseWebhookDeliveries(ctx context.Context, arg LeaseWebhookDeliveriesParams) ([]LeaseWebhookDeliveriesRow, error) {
+	rows, err := q.db.Query(ctx, leaseWebhookDeliveries, arg.Limit, arg.LockedUntil)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
-	items := []ListPendingWebhookDeliveriesRow{}
+	items := []LeaseWebhookDeliveriesRow{}
 	for rows.Next() {
-		var i ListPendingWebhookDeliveriesRow
+		var i LeaseWebhookDeliveriesRow
 		if err := rows.Scan(
 			&i.ID,
 			&i.EndpointID,
@@ -199,6 +250,9 @@ func (q *Queries) ListPendingWebhookDeliveries(ctx context.Context, limit int32)
 			&i.DeliveredAt,
 			&i.NextRetryAt,
 			&i.CreatedAt,
+			&i.Status,
+			&i.LastError,
+			&i.LockedUntil,
 			&i.Url,
 			&i.Secret,
 		); err != nil {
@@ -212,8 +266,44 @@ func (q *Queries) ListPendingWebhookDeliveries(ctx context.Context, limit int32)
 	return items, nil
 }
 
+const listActiveWebhookEndpoints = `-- name: ListActiveWebhookEndpoints :many
+SELECT id, url, secret, events, is_active, description, created_at, updated_at, consecutive_dead_letters, disabled_at, disabled_reason FROM webhook_endpoints WHERE is_active = true ORDER BY created_at DESC
+`
+
+func (q *Queries) ListActiveWebhookEndpoints(ctx context.Context) ([]WebhookEndpoint, error) {
+	rows, err := q.db.Query(ctx, listActiveWebhookEndpoints)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	items := []WebhookEndpoint{}
+	for rows.Next() {
+		var i WebhookEndpoint
+		if err := rows.Scan(
+			&i.ID,
+			&i.Url,
+			&i.Secret,
+			&i.Events,
+			&i.IsActive,
+			&i.Description,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.ConsecutiveDeadLetters,
+			&i.DisabledAt,
+			&i.DisabledReason,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
 const listWebhookDeliveries = `-- name: ListWebhookDeliveries :many
-SELECT id, endpoint_id, event_type, payload, response_status, response_body, attempt, delivered_at, next_retry_at, created_at FROM webhook_deliveries
+SELECT id, endpoint_id, event_type, payload, response_status, response_body, attempt, delivered_at, next_retry_at, created_at, status, last_error, locked_until FROM webhook_deliveries
 WHERE endpoint_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3
@@ -245,6 +335,9 @@ func (q *Queries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeli
 			&i.DeliveredAt,
 			&i.NextRetryAt,
 			&i.CreatedAt,
+			&i.Status,
+			&i.LastError,
+			&i.LockedUntil,
 		); err != nil {
 			return nil, err
 		}
@@ -257,7 +350,7 @@ func (q *Queries) ListWebhookDeliveries(ctx context.Context, arg ListWebhookDeli
 }
 
 const listWebhookEndpoints = `-- name: ListWebhookEndpoints :many
-SELECT id, url, secret, events, is_active, description, created_at, updated_at FROM webhook_endpoints ORDER BY created_at DESC
+SELECT id, url, secret, events, is_active, description, created_at, updated_at, consecutive_dead_letters, disabled_at, disabled_reason FROM webhook_endpoints ORDER BY created_at DESC
 `
 
 func (q *Queries) ListWebhookEndpoints(ctx context.Context) ([]WebhookEndpoint, error) {
@@ -278,6 +371,9 @@ func (q *Queries) ListWebhookEndpoints(ctx context.Context) ([]WebhookEndpoint,
 			&i.Description,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ConsecutiveDeadLetters,
+			&i.DisabledAt,
+			&i.DisabledReason,
 		); err != nil {
 			return nil, err
 		}
@@ -289,24 +385,77 @@ func (q *Queries) ListWebhookEndpoints(ctx context.Context) ([]WebhookEndpoint,
 	return items, nil
 }
 
+const redeliverWebhookDelivery = `-- name: RedeliverWebhookDelivery :one
+INSERT INTO webhook_deliveries (endpoint_id, event_type, payload)
+SELECT endpoint_id, event_type, payload
+FROM webhook_deliveries
+WHERE webhook_deliveries.id = $1 AND webhook_deliveries.endpoint_id = $2
+RETURNING id, endpoint_id, event_type, payload, response_status, response_body, attempt, delivered_at, next_retry_at, created_at, status, last_error, locked_until
+`
+
+type RedeliverWebhookDeliveryParams struct {
+	ID         uuid.UUID `json:"id"`
+	EndpointID uuid.UUID `json:"endpoint_id"`
+}
+
+// Queues a fresh copy of an earlier delivery so its history is kept intact.
+func (q *Queries) RedeliverWebhookDelivery(ctx context.Context, arg RedeliverWebhookDeliveryParams) (WebhookDelivery, error) {
+	row := q.db.QueryRow(ctx, redeliverWebhookDelivery, arg.ID, arg.EndpointID)
+	var i WebhookDelivery
+	err := row.Scan(
+		&i.ID,
+		&i.EndpointID,
+		&i.EventType,
+		&i.Payload,
+		&i.ResponseStatus,
+		&i.ResponseBody,
+		&i.Attempt,
+		&i.DeliveredAt,
+		&i.NextRetryAt,
+		&i.CreatedAt,
+		&i.Status,
+		&i.LastError,
+		&i.LockedUntil,
+	)
+	return i, err
+}
+
+const resetWebhookEndpointDeadLetters = `-- name: ResetWebhookEndpointDeadLetters :exec
+UPDATE webhook_endpoints
+SET consecutive_dead_letters = 0
+WHERE id = $1 AND consecutive_dead_letters <> 0
+`
+
+func (q *Queries) ResetWebhookEndpointDeadLetters(ctx context.Context, id uuid.UUID) error {
+	_, err := q.db.Exec(ctx, resetWebhookEndpointDeadLetters, id)
+	return err
+}
+
 const updateWebhookDeliveryFailed = `-- name: UpdateWebhookDeliveryFailed :exec
 UPDATE webhook_deliveries
-SET response_status = $2, response_body = $3, attempt = attempt + 1, next_retry_at = $4
+SET status = $2, response_status = $3, response_body = $4, last_error = $5,
+    attempt = attempt + 1, next_retry_at = $6, locked_until = NULL
 WHERE id = $1
 `
 
 type UpdateWebhookDeliveryFailedParams struct {
 	ID             uuid.UUID          `json:"id"`
+	Status         string             `json:"status"`
 	ResponseStatus *int32             `json:"response_status"`
 	ResponseBody   *string            `json:"response_body"`
+	LastError      *string            `json:"last_error"`
 	NextRetryAt    pgtype.Timestamptz `json:"next_retry_at"`
 }
 
+// status is 'pending' with a next_retry_at when another attempt is due,
+// or 'dead' with a NULL next_retry_at once the schedule is exhausted.
 func (q *Queries) UpdateWebhookDeliveryFailed(ctx context.Context, arg UpdateWebhookDeliveryFailedParams) error {
 	_, err := q.db.Exec(ctx, updateWebhookDeliveryFailed,
 		arg.ID,
+		arg.Status,
 		arg.ResponseStatus,
 		arg.ResponseBody,
+		arg.LastError,
 		arg.NextRetryAt,
 	)
 	return err
@@ -314,7 +463,8 @@ func (q *Queries) UpdateWebhookDeliveryFailed(ctx context.Context, arg UpdateWeb
 
 const updateWebhookDeliverySuccess = `-- name: UpdateWebhookDeliverySuccess :exec
 UPDATE webhook_deliveries
-SET response_status = $2, response_body = $3, delivered_at = NOW(), attempt = attempt + 1
+SET status = 'delivered', response_status = $2, response_body = $3, last_error = NULL,
+    delivered_at = NOW(), attempt = attempt + 1, next_retry_at = NULL, locked_until = NULL
 WHERE id = $1
 `
 
@@ -331,9 +481,13 @@ func (q *Queries) UpdateWebhookDeliverySuccess(ctx context.Context, arg UpdateWe
 
 const updateWebhookEndpoint = `-- name: UpdateWebhookEndpoint :one
 UPDATE webhook_endpoints
-SET url = $2, events = $3, description = $4, is_active = $5, updated_at = NOW()
+SET url = $2, events = $3, description = $4, is_active = $5,
+    consecutive_dead_letters = CASE WHEN $5 THEN 0 ELSE consecutive_dead_letters END,
+    disabled_at = CASE WHEN $5 THEN NULL ELSE disabled_at END,
+    disabled_reason = CASE WHEN $5 THEN NULL ELSE disabled_reason END,
+    updated_at = NOW()
 WHERE id = $1
-RETURNING id, url, secret, events, is_active, description, created_at, updated_at
+RETURNING id, url, secret, events, is_active, description, created_at, updated_at, consecutive_dead_letters, disabled_at, disabled_reason
 `
 
 type UpdateWebhookEndpointParams struct {
@@ -344,6 +498,7 @@ type UpdateWebhookEndpointParams struct {
 	IsActive    bool      `json:"is_active"`
 }
 
+// Re-activating an endpoint clears any automatic disable.
 func (q *Queries) UpdateWebhookEndpoint(ctx context.Context, arg UpdateWebhookEndpointParams) (WebhookEndpoint, error) {
 	row := q.db.QueryRow(ctx, updateWebhookEndpoint,
 		arg.ID,
@@ -362,6 +517,9 @@ func (q *Queries) UpdateWebhookEndpoint(ctx context.Context, arg UpdateWebhookEn
 		&i.Description,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ConsecutiveDeadLetters,
+		&i.DisabledAt,
+		&i.DisabledReason,
 	)
 	return i, err
 }
@@ -0,0 +1,5 @@
+-- 025_stock_reservations.down.sql
+DROP INDEX IF EXISTS idx_orders_stripe_checkout_session_id;
+DROP TABLE IF EXISTS checkout_snapshots;
+DROP TABLE IF EXISTS stock_reservations;
+ALTER TABLE products DROP COLUMN IF EXISTS allow_backorder;
@@ -0,0 +1,48 @@
+-- 025_stock_reservations.up.sql
+-- Stock held for carts while the customer is on the Stripe Checkout page,
+-- plus a per-product flag allowing orders beyond available stock, the lines
+-- each checkout session was priced with, and one order per paid checkout
+-- session.
+
+ALTER TABLE products ADD COLUMN allow_backorder BOOLEAN NOT NULL DEFAULT false;
+
+CREATE TABLE stock_reservations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    -- Kept when the cart is purged: committed rows are what RestockOrder returns.
+    cart_id UUID REFERENCES carts(id) ON DELETE SET NULL,
+    variant_id UUID NOT NULL REFERENCES product_variants(id) ON DELETE CASCADE,
+    quantity INTEGER NOT NULL CHECK (quantity > 0),
+    status TEXT NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'committed', 'released')),
+    stripe_checkout_session_id TEXT,
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Active, unexpired reservations are what checkout subtracts from stock.
+CREATE INDEX idx_stock_reservations_variant_active ON stock_reservations(variant_id, expires_at) WHERE status = 'active';
+CREATE INDEX idx_stock_reservations_cart_id ON stock_reservations(cart_id);
+CREATE INDEX idx_stock_reservations_session ON stock_reservations(stripe_checkout_session_id) WHERE stripe_checkout_session_id IS NOT NULL;
+
+-- The order lines and VAT a checkout session was priced with, in the format
+-- of order items. The payment webhook builds the order from them rather than
+-- from the cart, which the customer can still change while paying.
+CREATE TABLE checkout_snapshots (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    cart_id UUID NOT NULL REFERENCES carts(id) ON DELETE CASCADE,
+    items JSONB NOT NULL,
+    subtotal NUMERIC(12,2) NOT NULL,
+    vat_reverse_charge BOOLEAN NOT NULL DEFAULT false,
+    vat_company_name TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_checkout_snapshots_cart_id ON checkout_snapshots(cart_id);
+
+-- A checkout session pays for exactly one order. Stripe may deliver
+-- checkout.session.completed more than once; the unique index stops a
+-- redelivery from creating the order a second time.
+CREATE UNIQUE INDEX idx_orders_stripe_checkout_session_id
+    ON orders(stripe_checkout_session_id)
+    WHERE stripe_checkout_session_id IS NOT NULL;
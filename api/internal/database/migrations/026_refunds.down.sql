@@ -0,0 +1,4 @@
+-- 026_refunds.down.sql
+DROP TABLE IF EXISTS refund_items;
+DROP TABLE IF EXISTS refunds;
+DROP TABLE IF EXISTS credit_note_sequences;
@@ -0,0 +1,53 @@
+-- 026_refunds.up.sql
+-- Refunds against paid orders. Each refund doubles as a credit note: it
+-- carries its own number and the VAT share of the refunded amount, broken
+-- down by line and rate.
+--
+-- Credit note numbers must have no gaps, which a sequence cannot guarantee.
+-- A refund is numbered only when it succeeds, from a counter row bumped in
+-- the same transaction, as for invoices; pending and failed refunds have no
+-- number.
+
+CREATE TABLE credit_note_sequences (
+    id INTEGER PRIMARY KEY CHECK (id = 1),
+    last_number BIGINT NOT NULL CHECK (last_number > 0)
+);
+
+CREATE TABLE refunds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL REFERENCES orders(id) ON DELETE CASCADE,
+    credit_note_number BIGINT,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    source TEXT NOT NULL CHECK (source IN ('admin', 'stripe')),
+    amount NUMERIC(12,2) NOT NULL CHECK (amount > 0),
+    net_amount NUMERIC(12,2) NOT NULL,
+    vat_amount NUMERIC(12,2) NOT NULL,
+    reason TEXT,
+    restocked BOOLEAN NOT NULL DEFAULT false,
+    stripe_refund_id TEXT,
+    failure_message TEXT,
+    created_by UUID REFERENCES admin_users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX idx_refunds_credit_note_number ON refunds(credit_note_number);
+CREATE UNIQUE INDEX idx_refunds_stripe_refund_id ON refunds(stripe_refund_id) WHERE stripe_refund_id IS NOT NULL;
+CREATE INDEX idx_refunds_order_id ON refunds(order_id);
+
+-- Credit note lines. quantity is 0 for lines that carry a share of a custom
+-- amount rather than whole units.
+CREATE TABLE refund_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    refund_id UUID NOT NULL REFERENCES refunds(id) ON DELETE CASCADE,
+    order_item_id UUID REFERENCES order_items(id) ON DELETE SET NULL,
+    description TEXT NOT NULL,
+    quantity INTEGER NOT NULL DEFAULT 0 CHECK (quantity >= 0),
+    amount NUMERIC(12,2) NOT NULL,
+    net_amount NUMERIC(12,2) NOT NULL,
+    vat_rate NUMERIC(5,2) NOT NULL DEFAULT 0,
+    vat_amount NUMERIC(12,2) NOT NULL
+);
+
+CREATE INDEX idx_refund_items_refund_id ON refund_items(refund_id);
+CREATE INDEX idx_refund_items_order_item_id ON refund_items(order_item_id);
@@ -0,0 +1,6 @@
+-- 027_webhook_event_catalogue.down.sql
+UPDATE webhook_endpoints
+SET events = array_replace(array_replace(events,
+        'variant.stock_low', 'stock.low'),
+        'order.status_changed', 'order.updated')
+WHERE events && ARRAY['variant.stock_low', 'order.status_changed'];
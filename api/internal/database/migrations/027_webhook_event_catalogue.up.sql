@@ -0,0 +1,14 @@
+-- 027_webhook_event_catalogue.up.sql
+-- Move endpoint subscriptions onto the versioned event catalogue. The old
+-- names were never emitted; map them to their closest replacement.
+UPDATE webhook_endpoints
+SET events = array_replace(array_replace(array_replace(events,
+        'stock.low', 'variant.stock_low'),
+        'order.updated', 'order.status_changed'),
+        'order.completed', 'order.status_changed')
+WHERE events && ARRAY['stock.low', 'order.updated', 'order.completed'];
+
+-- array_replace can leave duplicates when both old names were subscribed.
+UPDATE webhook_endpoints
+SET events = ARRAY(SELECT DISTINCT unnest(events))
+WHERE cardinality(events) <> (SELECT count(DISTINCT e) FROM unnest(events) AS e);
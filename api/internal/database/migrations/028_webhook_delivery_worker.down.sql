@@ -0,0 +1,17 @@
+-- 028_webhook_delivery_worker.down.sql
+ALTER TABLE webhook_endpoints
+    DROP COLUMN IF EXISTS disabled_reason,
+    DROP COLUMN IF EXISTS disabled_at,
+    DROP COLUMN IF EXISTS consecutive_dead_letters;
+
+DROP INDEX IF EXISTS idx_webhook_deliveries_pending;
+CREATE INDEX idx_webhook_deliveries_next_retry_at ON webhook_deliveries(next_retry_at) WHERE next_retry_at IS NOT NULL;
+
+ALTER TABLE webhook_deliveries ALTER COLUMN next_retry_at DROP DEFAULT;
+ALTER TABLE webhook_deliveries ALTER COLUMN attempt SET DEFAULT 1;
+UPDATE webhook_deliveries SET attempt = attempt + 1;
+
+ALTER TABLE webhook_deliveries
+    DROP COLUMN IF EXISTS locked_until,
+    DROP COLUMN IF EXISTS last_error,
+    DROP COLUMN IF EXISTS status;
@@ -0,0 +1,29 @@
+-- 028_webhook_delivery_worker.up.sql
+-- Durable webhook delivery: deliveries are queued as 'pending' and leased by
+-- the background worker, retried with backoff and dead-lettered once the
+-- retry schedule is exhausted. Endpoints that keep dead-lettering are
+-- disabled automatically.
+
+ALTER TABLE webhook_deliveries
+    ADD COLUMN status TEXT NOT NULL DEFAULT 'pending'
+        CHECK (status IN ('pending', 'delivered', 'dead')),
+    ADD COLUMN last_error TEXT,
+    ADD COLUMN locked_until TIMESTAMPTZ;
+
+-- attempt now counts attempts made rather than the next attempt number.
+ALTER TABLE webhook_deliveries ALTER COLUMN attempt SET DEFAULT 0;
+UPDATE webhook_deliveries SET attempt = GREATEST(attempt - 1, 0);
+
+UPDATE webhook_deliveries SET status = 'delivered', next_retry_at = NULL WHERE delivered_at IS NOT NULL;
+UPDATE webhook_deliveries SET status = 'dead', next_retry_at = NULL WHERE delivered_at IS NULL AND attempt >= 5;
+UPDATE webhook_deliveries SET next_retry_at = created_at WHERE status = 'pending' AND next_retry_at IS NULL;
+
+ALTER TABLE webhook_deliveries ALTER COLUMN next_retry_at SET DEFAULT now();
+
+DROP INDEX IF EXISTS idx_webhook_deliveries_next_retry_at;
+CREATE INDEX idx_webhook_deliveries_pending ON webhook_deliveries(next_retry_at) WHERE status = 'pending';
+
+ALTER TABLE webhook_endpoints
+    ADD COLUMN consecutive_dead_letters INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN disabled_at TIMESTAMPTZ,
+    ADD COLUMN disabled_reason TEXT;
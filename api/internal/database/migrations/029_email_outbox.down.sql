@@ -0,0 +1,2 @@
+-- 029_email_outbox.down.sql
+DROP TABLE IF EXISTS email_outbox;
@@ -0,0 +1,25 @@
+-- 029_email_outbox.up.sql
+-- Transactional email outbox. Messages are rendered when queued and sent by
+-- a background worker, so an SMTP outage never fails the request that
+-- queued them.
+
+CREATE TABLE email_outbox (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    template TEXT NOT NULL,                           -- e.g. 'order_confirmation'
+    language TEXT NOT NULL DEFAULT 'en',
+    recipient TEXT NOT NULL,
+    subject TEXT NOT NULL,
+    html_body TEXT NOT NULL,
+    text_body TEXT NOT NULL,
+    dedupe_key TEXT UNIQUE,                           -- e.g. 'order_confirmation:<order id>'; NULL allows repeats
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMPTZ DEFAULT now(),
+    locked_until TIMESTAMPTZ,
+    sent_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_email_outbox_pending ON email_outbox(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_email_outbox_created_at ON email_outbox(created_at);
@@ -0,0 +1,4 @@
+-- 030_customer_tokens.down.sql
+DROP TABLE IF EXISTS customer_tokens;
+ALTER TABLE customers DROP COLUMN IF EXISTS token_version;
+ALTER TABLE customers DROP COLUMN IF EXISTS email_verified_at;
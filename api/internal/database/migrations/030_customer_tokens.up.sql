@@ -0,0 +1,23 @@
+-- 030_customer_tokens.up.sql
+-- Single-use tokens for customer password reset and email verification.
+-- Only a SHA-256 hash of each token is stored; the token itself is sent to
+-- the customer by email.
+
+ALTER TABLE customers ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- Carried in refresh tokens and bumped when the password changes, so a reset
+-- signs out every session that was using the old password.
+ALTER TABLE customers ADD COLUMN token_version INTEGER NOT NULL DEFAULT 0;
+
+CREATE TABLE customer_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    customer_id UUID NOT NULL REFERENCES customers(id) ON DELETE CASCADE,
+    purpose TEXT NOT NULL CHECK (purpose IN ('password_reset', 'email_verification')),
+    token_hash TEXT NOT NULL UNIQUE,                  -- hex SHA-256 of the token
+    email TEXT NOT NULL,                              -- address the token was sent to
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_customer_tokens_customer ON customer_tokens(customer_id, purpose);
@@ -0,0 +1,3 @@
+-- 031_coupon_usage_email.down.sql
+DROP INDEX IF EXISTS idx_coupon_usage_coupon_email;
+ALTER TABLE coupon_usage DROP COLUMN IF EXISTS email;
@@ -0,0 +1,11 @@
+-- 031_coupon_usage_email.up.sql
+-- Records the email address behind each coupon use so per-customer limits
+-- also apply to guest checkouts. Addresses are stored trimmed and lowercased.
+
+ALTER TABLE coupon_usage ADD COLUMN email TEXT;
+
+UPDATE coupon_usage u SET email = lower(trim(o.email))
+FROM orders o
+WHERE o.id = u.order_id;
+
+CREATE INDEX idx_coupon_usage_coupon_email ON coupon_usage(coupon_id, email);
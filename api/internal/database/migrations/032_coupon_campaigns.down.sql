@@ -0,0 +1,4 @@
+-- 032_coupon_campaigns.down.sql
+DROP INDEX IF EXISTS idx_coupons_campaign_id;
+ALTER TABLE coupons DROP COLUMN IF EXISTS campaign_id;
+DROP TABLE IF EXISTS coupon_campaigns;
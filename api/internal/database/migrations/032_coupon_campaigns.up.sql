@@ -0,0 +1,23 @@
+-- 032_coupon_campaigns.up.sql
+-- Coupon campaigns: batches of generated single-use codes for one discount,
+-- used for influencer and newsletter campaigns.
+
+CREATE TABLE coupon_campaigns (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name TEXT NOT NULL,
+    discount_id UUID NOT NULL REFERENCES discounts(id) ON DELETE CASCADE,
+    prefix TEXT NOT NULL DEFAULT '',
+    code_length INTEGER NOT NULL,                    -- random characters after the prefix
+    alphabet TEXT NOT NULL,
+    code_count INTEGER NOT NULL,
+    usage_limit_per_code INTEGER NOT NULL DEFAULT 1,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_coupon_campaigns_discount_id ON coupon_campaigns(discount_id);
+
+ALTER TABLE coupons
+    ADD COLUMN campaign_id UUID REFERENCES coupon_campaigns(id) ON DELETE CASCADE;
+
+CREATE INDEX idx_coupons_campaign_id ON coupons(campaign_id);
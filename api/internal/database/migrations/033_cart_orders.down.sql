@@ -0,0 +1,3 @@
+-- 033_cart_orders.down.sql
+DROP INDEX IF EXISTS idx_carts_customer_open;
+ALTER TABLE carts DROP COLUMN IF EXISTS order_id;
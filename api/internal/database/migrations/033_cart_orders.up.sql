@@ -0,0 +1,8 @@
+-- 033_cart_orders.up.sql
+-- Links a cart to the order it was checked out as. Carts with an order are
+-- no longer offered back to their customer or merged into on login.
+
+ALTER TABLE carts ADD COLUMN order_id UUID REFERENCES orders(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_carts_customer_open ON carts(customer_id, updated_at DESC)
+    WHERE customer_id IS NOT NULL AND order_id IS NULL;
@@ -0,0 +1,3 @@
+-- 034_cart_recoveries.down.sql
+DROP INDEX IF EXISTS idx_carts_abandoned;
+DROP TABLE IF EXISTS cart_recoveries;
@@ -0,0 +1,20 @@
+-- 034_cart_recoveries.up.sql
+-- Abandoned cart recovery emails. One row per cart that was sent a reminder,
+-- with the one-time coupon it offered and the order it turned into. Rows
+-- outlive the cart so recovered revenue can still be reported once expired
+-- carts are purged.
+
+CREATE TABLE cart_recoveries (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    cart_id UUID UNIQUE REFERENCES carts(id) ON DELETE SET NULL,
+    email TEXT NOT NULL,
+    coupon_id UUID REFERENCES coupons(id) ON DELETE SET NULL,
+    order_id UUID REFERENCES orders(id) ON DELETE SET NULL,
+    sent_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    restored_at TIMESTAMPTZ
+);
+
+CREATE INDEX idx_cart_recoveries_sent_at ON cart_recoveries(sent_at);
+
+CREATE INDEX idx_carts_abandoned ON carts(updated_at)
+    WHERE email IS NOT NULL AND order_id IS NULL;
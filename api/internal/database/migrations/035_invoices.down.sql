@@ -0,0 +1,3 @@
+-- 035_invoices.down.sql
+DROP TABLE IF EXISTS invoices;
+DROP TABLE IF EXISTS invoice_sequences;
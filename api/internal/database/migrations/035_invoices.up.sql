@@ -0,0 +1,29 @@
+-- 035_invoices.up.sql
+-- VAT invoices for paid orders. Invoice numbers restart every calendar year
+-- and must have no gaps, which a sequence cannot guarantee: the counter for
+-- a year is a row that is bumped in the same transaction that inserts the
+-- invoice, so a rolled-back invoice gives its number back. The number is
+-- committed before the PDF is uploaded, so the lock is not held during the
+-- upload: stored_at is set once the PDF is in storage, and until then the
+-- invoice is not shown and issuing it again retries the upload under the
+-- same number.
+
+CREATE TABLE invoice_sequences (
+    year INTEGER PRIMARY KEY,
+    last_number INTEGER NOT NULL CHECK (last_number > 0)
+);
+
+CREATE TABLE invoices (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    order_id UUID NOT NULL UNIQUE REFERENCES orders(id) ON DELETE RESTRICT,
+    year INTEGER NOT NULL,
+    sequence INTEGER NOT NULL,
+    invoice_number TEXT NOT NULL UNIQUE,
+    storage_key TEXT NOT NULL,
+    issued_at TIMESTAMPTZ NOT NULL,
+    stored_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    UNIQUE (year, sequence)
+);
+
+CREATE INDEX idx_invoices_issued_at ON invoices(issued_at);
@@ -0,0 +1,5 @@
+-- 036_admin_roles.down.sql
+ALTER TABLE admin_users ALTER COLUMN role SET DEFAULT 'admin';
+
+UPDATE admin_users SET role = 'super_admin' WHERE role = 'owner';
+UPDATE admin_users SET role = 'admin' WHERE role IN ('manager', 'warehouse', 'accountant');
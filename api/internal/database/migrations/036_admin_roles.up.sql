@@ -0,0 +1,14 @@
+-- 036_admin_roles.up.sql
+-- Admin users now have one of the predefined roles (owner, manager,
+-- warehouse, accountant) plus individually granted permissions from the
+-- catalogue in internal/auth/permissions.go.
+--
+-- Until now every admin could do everything, so existing users become
+-- owners and keep that access. Their old free-form permissions mean
+-- nothing in the catalogue and are cleared.
+
+UPDATE admin_users
+SET role = 'owner', permissions = '{}'
+WHERE role NOT IN ('owner', 'manager', 'warehouse', 'accountant');
+
+ALTER TABLE admin_users ALTER COLUMN role SET DEFAULT 'manager';
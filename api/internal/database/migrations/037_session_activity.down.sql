@@ -0,0 +1,6 @@
+-- 037_session_activity.down.sql
+DROP INDEX IF EXISTS idx_sessions_public_id;
+
+ALTER TABLE sessions
+    DROP COLUMN IF EXISTS last_seen_at,
+    DROP COLUMN IF EXISTS public_id;
@@ -0,0 +1,12 @@
+-- 037_session_activity.up.sql
+-- Admins can list and revoke their sessions, and sessions end after a period
+-- of inactivity as well as at their absolute expiry.
+--
+-- public_id identifies a session on the sessions page; the token in id is
+-- never shown. last_seen_at drives the idle timeout.
+
+ALTER TABLE sessions
+    ADD COLUMN public_id UUID NOT NULL DEFAULT gen_random_uuid(),
+    ADD COLUMN last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now();
+
+CREATE UNIQUE INDEX idx_sessions_public_id ON sessions(public_id);
@@ -0,0 +1,3 @@
+-- 038_admin_webauthn.down.sql
+DROP TABLE IF EXISTS admin_webauthn_challenges;
+DROP TABLE IF EXISTS admin_webauthn_credentials;
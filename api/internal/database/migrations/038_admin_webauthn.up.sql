@@ -0,0 +1,31 @@
+-- 038_admin_webauthn.up.sql
+-- Admin users can register WebAuthn security keys and passkeys as a second
+-- factor alongside TOTP. Each user may have several named credentials.
+--
+-- public_key is the credential's COSE_Key as sent by the authenticator.
+-- sign_count is the authenticator's signature counter, used to detect
+-- cloned keys.
+
+CREATE TABLE admin_webauthn_credentials (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    admin_user_id  UUID NOT NULL REFERENCES admin_users(id) ON DELETE CASCADE,
+    name           TEXT NOT NULL,
+    credential_id  BYTEA NOT NULL UNIQUE,
+    public_key     BYTEA NOT NULL,
+    sign_count     BIGINT NOT NULL DEFAULT 0,
+    transports     TEXT[] NOT NULL DEFAULT '{}',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT now(),
+    last_used_at   TIMESTAMPTZ
+);
+
+CREATE INDEX idx_admin_webauthn_credentials_user ON admin_webauthn_credentials(admin_user_id);
+
+-- One outstanding challenge per user and ceremony. A challenge is consumed
+-- (deleted) by the first response that uses it.
+CREATE TABLE admin_webauthn_challenges (
+    admin_user_id  UUID NOT NULL REFERENCES admin_users(id) ON DELETE CASCADE,
+    ceremony       TEXT NOT NULL CHECK (ceremony IN ('registration', 'login')),
+    challenge      BYTEA NOT NULL,
+    expires_at     TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (admin_user_id, ceremony)
+);
@@ -0,0 +1,2 @@
+-- 039_admin_api_keys.down.sql
+DROP TABLE IF EXISTS admin_api_keys;
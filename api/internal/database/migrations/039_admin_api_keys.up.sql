@@ -0,0 +1,24 @@
+-- 039_admin_api_keys.up.sql
+-- API keys let scripts (ERP, warehouse tooling) call the JSON admin API
+-- under /admin/api/v1 without a browser session.
+--
+-- Only a SHA-256 hash of each key is stored; the key itself is shown once
+-- when it is created. prefix holds the first characters of the key so it
+-- can be recognised in the UI. scopes is a subset of the admin permission
+-- names; a key never grants more than its creator currently holds.
+
+CREATE TABLE admin_api_keys (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name          TEXT NOT NULL,
+    prefix        TEXT NOT NULL,
+    key_hash      TEXT NOT NULL UNIQUE,
+    scopes        TEXT[] NOT NULL DEFAULT '{}',
+    created_by    UUID NOT NULL REFERENCES admin_users(id) ON DELETE CASCADE,
+    expires_at    TIMESTAMPTZ,
+    last_used_at  TIMESTAMPTZ,
+    last_used_ip  TEXT,
+    revoked_at    TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX idx_admin_api_keys_created_by ON admin_api_keys(created_by);
@@ -0,0 +1,35 @@
+-- name: CreateAuditLogEntry :exec
+INSERT INTO admin_audit_log (id, admin_user_id, action, entity_type, entity_id, changes, ip_address, created_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8);
+
+-- name: ListAuditLog :many
+-- Filters are optional; NULL matches everything. to_date is exclusive.
+SELECT l.id, l.admin_user_id, l.action, l.entity_type, l.entity_id, l.changes,
+  l.ip_address, l.created_at, u.name AS admin_name, u.email AS admin_email
+FROM admin_audit_log l
+LEFT JOIN admin_users u ON u.id = l.admin_user_id
+WHERE (sqlc.narg('admin_user_id')::uuid IS NULL OR l.admin_user_id = sqlc.narg('admin_user_id'))
+  AND (sqlc.narg('entity_type')::text IS NULL OR l.entity_type = sqlc.narg('entity_type'))
+  AND (sqlc.narg('entity_id')::text IS NULL OR l.entity_id = sqlc.narg('entity_id'))
+  AND (sqlc.narg('action')::text IS NULL OR l.action = sqlc.narg('action'))
+  AND (sqlc.narg('from_date')::timestamptz IS NULL OR l.created_at >= sqlc.narg('from_date'))
+  AND (sqlc.narg('to_date')::timestamptz IS NULL OR l.created_at < sqlc.narg('to_date'))
+ORDER BY l.created_at DESC, l.id DESC
+LIMIT @page_limit OFFSET @page_offset;
+
+-- name: CountAuditLog :one
+SELECT COUNT(*) FROM admin_audit_log l
+WHERE (sqlc.narg('admin_user_id')::uuid IS NULL OR l.admin_user_id = sqlc.narg('admin_user_id'))
+  AND (sqlc.narg('entity_type')::text IS NULL OR l.entity_type = sqlc.narg('entity_type'))
+  AND (sqlc.narg('entity_id')::text IS NULL OR l.entity_id = sqlc.narg('entity_id'))
+  AND (sqlc.narg('action')::text IS NULL OR l.action = sqlc.narg('action'))
+  AND (sqlc.narg('from_date')::timestamptz IS NULL OR l.created_at >= sqlc.narg('from_date'))
+  AND (sqlc.narg('to_date')::timestamptz IS NULL OR l.created_at < sqlc.narg('to_date'));
+
+-- name: ListAuditLogActions :many
+SELECT DISTINCT action FROM admin_audit_log ORDER BY action;
+
+-- name: ListAuditLogEntityTypes :many
+SELECT DISTINCT entity_type::text FROM admin_audit_log
+WHERE entity_type IS NOT NULL
+ORDER BY entity_type;
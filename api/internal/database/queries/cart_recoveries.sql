@@ -0,0 +1,36 @@
+-- name: ListAbandonedCarts :many
+-- Returns unordered, unexpired carts with an email and items that have not
+-- been touched since idle_before and have not been sent a reminder yet.
+SELECT c.* FROM carts c
+WHERE c.email IS NOT NULL AND c.email <> ''
+  AND c.order_id IS NULL
+  AND c.expires_at > @now
+  AND c.updated_at < @idle_before
+  AND EXISTS (SELECT 1 FROM cart_items ci WHERE ci.cart_id = c.id)
+  AND NOT EXISTS (
+    SELECT 1 FROM cart_items ci
+    WHERE ci.cart_id = c.id AND ci.updated_at >= @idle_before
+  )
+  AND NOT EXISTS (SELECT 1 FROM cart_recoveries r WHERE r.cart_id = c.id)
+ORDER BY c.updated_at
+LIMIT @max_results;
+
+-- name: ClaimCartRecovery :one
+-- Records that a reminder is being sent for a cart. Returns no rows if one
+-- was already recorded, so concurrent jobs send at most one per cart.
+INSERT INTO cart_recoveries (id, cart_id, email, sent_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (cart_id) DO NOTHING
+RETURNING *;
+
+-- name: GetCartRecoveryByCart :one
+SELECT * FROM cart_recoveries WHERE cart_id = $1;
+
+-- name: SetCartRecoveryCoupon :exec
+UPDATE cart_recoveries SET coupon_id = $2 WHERE id = $1;
+
+-- name: MarkCartRecoveryRestored :exec
+UPDATE cart_recoveries SET restored_at = COALESCE(restored_at, $2) WHERE cart_id = $1;
+
+-- name: SetCartRecoveryOrder :exec
+UPDATE cart_recoveries SET order_id = $2 WHERE cart_id = $1 AND order_id IS NULL;
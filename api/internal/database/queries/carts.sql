@@ -13,6 +13,28 @@ UPDATE carts SET
 WHERE id = $1
 RETURNING *;
 
+-- name: GetLatestCustomerCart :one
+-- Returns the customer's most recently updated cart that has not expired or
+-- been checked out, other than the given cart.
+SELECT * FROM carts
+WHERE customer_id = @customer_id AND id <> @exclude_id
+  AND order_id IS NULL AND expires_at > @now
+ORDER BY updated_at DESC
+LIMIT 1;
+
+-- name: SetCartCustomer :one
+-- Assigns a cart to a customer and pushes its expiry out to at least
+-- expires_at.
+UPDATE carts SET
+  customer_id = @customer_id,
+  expires_at = GREATEST(expires_at, @expires_at),
+  updated_at = @updated_at
+WHERE id = @id
+RETURNING *;
+
+-- name: SetCartOrder :exec
+UPDATE carts SET order_id = $2, updated_at = $3 WHERE id = $1;
+
 -- name: DeleteExpiredCarts :exec
 DELETE FROM carts WHERE expires_at < NOW();
 
@@ -38,7 +60,8 @@ SELECT
   p.slug AS product_slug,
   p.base_price AS product_base_price,
   p.status AS product_status,
-  p.vat_category_id AS product_vat_category_id
+  p.vat_category_id AS product_vat_category_id,
+  p.allow_backorder AS product_allow_backorder
 FROM cart_items ci
 JOIN product_variants pv ON pv.id = ci.variant_id
 JOIN products p ON p.id = pv.product_id
@@ -55,3 +78,12 @@ DELETE FROM cart_items WHERE id = $1;
 
 -- name: ClearCart :exec
 DELETE FROM cart_items WHERE cart_id = $1;
+
+-- name: SetCartItemQuantity :one
+-- Sets the quantity of a variant in a cart, adding the line if needed.
+INSERT INTO cart_items (id, cart_id, variant_id, quantity, created_at, updated_at)
+VALUES ($1, $2, $3, $4, $5, $5)
+ON CONFLICT (cart_id, variant_id) DO UPDATE SET
+  quantity = EXCLUDED.quantity,
+  updated_at = EXCLUDED.updated_at
+RETURNING *;
@@ -0,0 +1,6 @@
+-- name: CreateCheckoutSnapshot :exec
+INSERT INTO checkout_snapshots (id, cart_id, items, subtotal, vat_reverse_charge, vat_company_name)
+VALUES ($1, $2, $3, $4, $5, $6);
+
+-- name: GetCheckoutSnapshot :one
+SELECT * FROM checkout_snapshots WHERE id = $1;
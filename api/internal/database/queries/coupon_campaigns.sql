@@ -0,0 +1,64 @@
+-- name: CreateCouponCampaign :one
+INSERT INTO coupon_campaigns (
+  id, name, discount_id, prefix, code_length, alphabet, code_count,
+  usage_limit_per_code, created_at, updated_at
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $9)
+RETURNING *;
+
+-- name: GetCouponCampaign :one
+SELECT cc.*, d.name as discount_name FROM coupon_campaigns cc
+JOIN discounts d ON d.id = cc.discount_id
+WHERE cc.id = $1;
+
+-- name: ListCouponCampaigns :many
+SELECT cc.*, d.name as discount_name,
+  (SELECT COUNT(*) FROM coupon_usage u
+   JOIN coupons c ON c.id = u.coupon_id
+   WHERE c.campaign_id = cc.id) as redemption_count
+FROM coupon_campaigns cc
+JOIN discounts d ON d.id = cc.discount_id
+ORDER BY cc.created_at DESC LIMIT $1 OFFSET $2;
+
+-- name: InsertCampaignCoupons :execrows
+-- Inserts a batch of generated codes. Codes that already exist are skipped,
+-- so the caller can compare the row count with the batch size and generate
+-- replacements.
+INSERT INTO coupons (
+  id, code, discount_id, campaign_id, usage_limit, usage_count,
+  is_active, created_at, updated_at
+)
+SELECT gen_random_uuid(), code, @discount_id::uuid, @campaign_id::uuid, @usage_limit::integer, 0,
+  true, @created_at::timestamptz, @created_at::timestamptz
+FROM unnest(@codes::text[]) AS code
+ON CONFLICT (code) DO NOTHING;
+
+-- name: ListCampaignCoupons :many
+SELECT code, usage_count, usage_limit, is_active, created_at FROM coupons
+WHERE campaign_id = $1
+ORDER BY code;
+
+-- name: GetCampaignRedemptionSummary :one
+-- Aggregates coupon_usage for a campaign's codes. Order totals only include
+-- orders that still exist.
+SELECT
+  COUNT(u.id) as redemption_count,
+  COUNT(DISTINCT u.coupon_id) as codes_redeemed,
+  COUNT(DISTINCT COALESCE(u.customer_id::text, u.email)) as unique_customers,
+  COALESCE(SUM(o.total), 0)::numeric as order_total,
+  COALESCE(SUM(o.discount_amount), 0)::numeric as discount_total
+FROM coupon_usage u
+JOIN coupons c ON c.id = u.coupon_id
+LEFT JOIN orders o ON o.id = u.order_id
+WHERE c.campaign_id = $1;
+
+-- name: ListCampaignRedemptionsByDay :many
+SELECT
+  DATE(u.used_at) as usage_date,
+  COUNT(*) as redemption_count,
+  COALESCE(SUM(o.discount_amount), 0)::numeric as discount_total
+FROM coupon_usage u
+JOIN coupons c ON c.id = u.coupon_id
+LEFT JOIN orders o ON o.id = u.order_id
+WHERE c.campaign_id = $1
+GROUP BY DATE(u.used_at)
+ORDER BY usage_date;
@@ -0,0 +1,41 @@
+-- name: ListCustomerAddresses :many
+-- Default addresses first, then oldest first.
+SELECT * FROM customer_addresses
+WHERE customer_id = $1
+ORDER BY is_default_shipping DESC, is_default_billing DESC, created_at;
+
+-- name: GetCustomerAddress :one
+-- Addresses are always looked up through their owner, so one customer can
+-- never read another's address by ID.
+SELECT * FROM customer_addresses WHERE id = $1 AND customer_id = $2;
+
+-- name: CreateCustomerAddress :one
+INSERT INTO customer_addresses (
+  id, customer_id, label, first_name, last_name, company,
+  address_line1, address_line2, city, state_province, postal_code,
+  country_code, phone, is_default_billing, is_default_shipping,
+  created_at, updated_at
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $16)
+RETURNING *;
+
+-- name: UpdateCustomerAddress :one
+UPDATE customer_addresses SET
+  label = $3, first_name = $4, last_name = $5, company = $6,
+  address_line1 = $7, address_line2 = $8, city = $9, state_province = $10,
+  postal_code = $11, country_code = $12, phone = $13,
+  is_default_billing = $14, is_default_shipping = $15, updated_at = $16
+WHERE id = $1 AND customer_id = $2
+RETURNING *;
+
+-- name: DeleteCustomerAddress :execrows
+DELETE FROM customer_addresses WHERE id = $1 AND customer_id = $2;
+
+-- name: ClearDefaultBillingAddress :exec
+-- Unsets the default billing flag on the customer's other addresses.
+UPDATE customer_addresses SET is_default_billing = false, updated_at = $3
+WHERE customer_id = $1 AND id <> $2 AND is_default_billing;
+
+-- name: ClearDefaultShippingAddress :exec
+-- Unsets the default shipping flag on the customer's other addresses.
+UPDATE customer_addresses SET is_default_shipping = false, updated_at = $3
+WHERE customer_id = $1 AND id <> $2 AND is_default_shipping;
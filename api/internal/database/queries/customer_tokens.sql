@@ -0,0 +1,17 @@
+-- name: CreateCustomerToken :one
+INSERT INTO customer_tokens (customer_id, purpose, token_hash, email, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: RevokeCustomerTokens :exec
+-- Marks every unused token of a purpose as used, so only the newest token
+-- issued is valid.
+UPDATE customer_tokens SET used_at = NOW()
+WHERE customer_id = $1 AND purpose = $2 AND used_at IS NULL;
+
+-- name: ConsumeCustomerToken :one
+-- Atomically marks an unused, unexpired token as used. No row is returned
+-- for unknown, used or expired tokens.
+UPDATE customer_tokens SET used_at = NOW()
+WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > NOW()
+RETURNING *;
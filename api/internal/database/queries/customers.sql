@@ -26,3 +26,14 @@ UPDATE customers SET
   accepts_marketing = $7, vat_number = $8, notes = $9, updated_at = $10
 WHERE id = $1
 RETURNING *;
+
+-- name: UpdateCustomerPassword :exec
+-- Bumping token_version invalidates refresh tokens issued before the change.
+UPDATE customers SET password_hash = $2, token_version = token_version + 1, updated_at = NOW() WHERE id = $1;
+
+-- name: MarkCustomerEmailVerified :one
+-- Only verifies the address the token was issued for, so a token sent
+-- before an email change cannot verify the new address.
+UPDATE customers SET email_verified_at = COALESCE(email_verified_at, NOW()), updated_at = NOW()
+WHERE id = $1 AND email = $2
+RETURNING *;
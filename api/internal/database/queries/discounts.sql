@@ -2,15 +2,31 @@
 SELECT * FROM discounts WHERE id = $1;
 
 -- name: ListActiveDiscounts :many
+-- Returns the discounts applied automatically. Discounts that have coupons
+-- only apply when one of their codes is entered.
 SELECT * FROM discounts
 WHERE is_active = true
 AND (starts_at IS NULL OR starts_at <= NOW())
 AND (ends_at IS NULL OR ends_at > NOW())
+AND NOT EXISTS (SELECT 1 FROM coupons WHERE coupons.discount_id = discounts.id)
 ORDER BY priority DESC;
 
 -- name: ListDiscounts :many
 SELECT * FROM discounts ORDER BY created_at DESC LIMIT $1 OFFSET $2;
 
+-- name: ListProductCategoryIDs :many
+-- Returns the category memberships of the given products, for category
+-- conditions.
+SELECT product_id, category_id FROM product_categories
+WHERE product_id = ANY(@product_ids::uuid[]);
+
+-- name: CountCustomerOrders :one
+-- Counts the orders placed by a customer account or email address, for
+-- first-order conditions. Cancelled orders do not count.
+SELECT COUNT(*) FROM orders
+WHERE status <> 'cancelled'
+AND (customer_id = @customer_id OR lower(email) = lower(@email));
+
 -- name: CreateDiscount :one
 INSERT INTO discounts (
   id, name, type, value, scope, minimum_amount, maximum_discount,
@@ -35,8 +51,11 @@ SELECT * FROM coupons WHERE id = $1;
 SELECT * FROM coupons WHERE code = $1;
 
 -- name: ListCoupons :many
+-- Lists the coupons created one at a time. Codes generated by a campaign are
+-- listed with the campaign.
 SELECT c.*, d.name as discount_name FROM coupons c
 JOIN discounts d ON d.id = c.discount_id
+WHERE c.campaign_id IS NULL
 ORDER BY c.created_at DESC LIMIT $1 OFFSET $2;
 
 -- name: CreateCoupon :one
@@ -46,8 +65,23 @@ INSERT INTO coupons (
 ) VALUES ($1, $2, $3, $4, $5, 0, $6, $7, $8, $9, $9)
 RETURNING *;
 
--- name: IncrementCouponUsage :exec
-UPDATE coupons SET usage_count = usage_count + 1, updated_at = $2 WHERE id = $1;
+-- name: IncrementCouponUsage :execrows
+-- Counts one use of a coupon unless its usage limit is already reached. The
+-- limit check and increment are one statement, so concurrent redemptions
+-- cannot both take the last use.
+UPDATE coupons SET usage_count = usage_count + 1, updated_at = $2
+WHERE id = $1 AND (usage_limit IS NULL OR usage_count < usage_limit);
+
+-- name: CreateCouponUsage :one
+INSERT INTO coupon_usage (id, coupon_id, customer_id, order_id, used_at, email)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING *;
+
+-- name: CountCouponUsageByCustomer :one
+SELECT COUNT(*) FROM coupon_usage WHERE coupon_id = $1 AND customer_id = $2;
+
+-- name: CountCouponUsageByEmail :one
+SELECT COUNT(*) FROM coupon_usage WHERE coupon_id = $1 AND email = $2;
 
 -- name: DeleteCoupon :exec
 DELETE FROM coupons WHERE id = $1;
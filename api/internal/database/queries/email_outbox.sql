@@ -0,0 +1,42 @@
+-- name: ClearOutboxEmailBody :exec
+-- Removes the body of a message that carried a secret link once it no
+-- longer needs sending.
+UPDATE email_outbox SET html_body = '', text_body = '' WHERE id = $1;
+
+-- name: CreateOutboxEmail :one
+-- A message with a dedupe_key that was already queued is skipped and no row
+-- is returned.
+INSERT INTO email_outbox (template, language, recipient, subject, html_body, text_body, dedupe_key)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+ON CONFLICT (dedupe_key) DO NOTHING
+RETURNING *;
+
+-- name: LeaseOutboxEmails :many
+WITH due AS (
+    SELECT id
+    FROM email_outbox
+    WHERE status = 'pending'
+      AND next_attempt_at <= NOW()
+      AND (locked_until IS NULL OR locked_until <= NOW())
+    ORDER BY next_attempt_at ASC
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+)
+UPDATE email_outbox e
+SET locked_until = $2
+FROM due
+WHERE e.id = due.id
+RETURNING e.*;
+
+-- name: MarkOutboxEmailSent :exec
+UPDATE email_outbox
+SET status = 'sent', sent_at = NOW(), attempts = attempts + 1,
+    last_error = NULL, next_attempt_at = NULL, locked_until = NULL
+WHERE id = $1;
+
+-- name: MarkOutboxEmailFailed :exec
+UPDATE email_outbox
+SET status = $2, last_error = $3, attempts = attempts + 1,
+    next_attempt_at = $4, locked_until = NULL
+WHERE id = $1;
+
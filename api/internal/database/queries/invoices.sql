@@ -0,0 +1,21 @@
+-- name: NextInvoiceSequence :one
+-- Reserves the next invoice number for a year. The row stays locked until
+-- the transaction ends, so numbers are handed out one at a time and a
+-- rollback releases the number again.
+INSERT INTO invoice_sequences (year, last_number)
+VALUES ($1, 1)
+ON CONFLICT (year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+RETURNING last_number;
+
+-- name: CreateInvoice :one
+INSERT INTO invoices (id, order_id, year, sequence, invoice_number, storage_key, issued_at)
+VALUES ($1, $2, $3, $4, $5, $6, $7)
+RETURNING *;
+
+-- name: GetInvoiceByOrder :one
+SELECT * FROM invoices WHERE order_id = $1;
+
+-- name: MarkInvoiceStored :one
+UPDATE invoices SET stored_at = now()
+WHERE id = $1
+RETURNING *;
@@ -1,6 +1,15 @@
 -- name: GetOrder :one
 SELECT * FROM orders WHERE id = $1;
 
+-- name: GetOrderForUpdate :one
+SELECT * FROM orders WHERE id = $1 FOR UPDATE;
+
+-- name: GetOrderByPaymentIntent :one
+SELECT * FROM orders WHERE stripe_payment_intent_id = $1;
+
+-- name: GetOrderByCheckoutSession :one
+SELECT * FROM orders WHERE stripe_checkout_session_id = $1;
+
 -- name: GetOrderByNumber :one
 SELECT * FROM orders WHERE order_number = $1;
 
@@ -10,6 +19,12 @@ WHERE ($1::text IS NULL OR status = $1::text)
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3;
 
+-- name: ListOrdersByCustomer :many
+-- Returns a customer's orders, newest first.
+SELECT * FROM orders
+WHERE customer_id = $1
+ORDER BY created_at DESC;
+
 -- name: CountOrders :one
 SELECT COUNT(*) FROM orders
 WHERE ($1::text IS NULL OR status = $1::text);
@@ -41,9 +56,18 @@ RETURNING *;
 -- name: UpdateOrderStatus :one
 UPDATE orders SET status = $2, updated_at = $3 WHERE id = $1 RETURNING *;
 
+-- name: UpdateOrderPaymentStatus :one
+UPDATE orders SET payment_status = $2, updated_at = $3 WHERE id = $1 RETURNING *;
+
 -- name: UpdateOrderTracking :exec
 UPDATE orders SET tracking_number = $2, shipped_at = $3, updated_at = $4 WHERE id = $1;
 
+-- name: SetOrderShippedAt :exec
+UPDATE orders SET shipped_at = COALESCE(shipped_at, $2), updated_at = $2 WHERE id = $1;
+
+-- name: SetOrderDeliveredAt :exec
+UPDATE orders SET delivered_at = COALESCE(delivered_at, $2), updated_at = $2 WHERE id = $1;
+
 -- name: ListOrderItems :many
 SELECT * FROM order_items WHERE order_id = $1 ORDER BY id;
 
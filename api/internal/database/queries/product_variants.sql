@@ -55,3 +55,6 @@ LIMIT $1;
 -- name: CountLowStockVariants :one
 SELECT COUNT(*) FROM product_variants
 WHERE stock_quantity <= low_stock_threshold AND is_active = true;
+
+-- name: GetProductVariantForUpdate :one
+SELECT * FROM product_variants WHERE id = $1 FOR UPDATE;
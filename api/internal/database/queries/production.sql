@@ -79,3 +79,9 @@ WHERE id = $1;
 
 -- name: CountProductionBatchesByStatus :one
 SELECT COUNT(*) FROM production_batches WHERE status = $1;
+
+-- name: SetProductionBatchCost :one
+UPDATE production_batches
+SET cost_total = $2, updated_at = NOW()
+WHERE id = $1
+RETURNING *;
@@ -21,14 +21,14 @@ INSERT INTO products (
   base_weight_grams, base_dimensions_mm,
   shipping_extra_fee_per_unit, has_variants,
   seo_title, seo_description, metadata,
-  created_at, updated_at
+  created_at, updated_at, allow_backorder
 ) VALUES (
   $1, $2, $3, $4, $5, $6, $7,
   $8, $9, $10,
   $11, $12,
   $13, $14,
   $15, $16, $17,
-  $18, $18
+  $18, $18, $19
 )
 RETURNING *;
 
@@ -40,7 +40,7 @@ UPDATE products SET
   base_weight_grams = $11, base_dimensions_mm = $12,
   shipping_extra_fee_per_unit = $13, has_variants = $14,
   seo_title = $15, seo_description = $16, metadata = $17,
-  updated_at = $18
+  updated_at = $18, allow_backorder = $19
 WHERE id = $1
 RETURNING *;
 
@@ -53,3 +53,14 @@ SELECT * FROM raw_materials
 WHERE (name ILIKE '%' || $1 || '%' OR sku ILIKE '%' || $1 || '%')
 ORDER BY name
 LIMIT $2 OFFSET $3;
+
+-- name: ListRawMaterialsByIDs :many
+SELECT * FROM raw_materials
+WHERE id = ANY(@ids::uuid[])
+ORDER BY name;
+
+-- name: GetRawMaterialForUpdate :one
+SELECT * FROM raw_materials WHERE id = $1 FOR UPDATE;
+
+-- name: UpdateRawMaterialStock :exec
+UPDATE raw_materials SET stock_quantity = $2, updated_at = now() WHERE id = $1;
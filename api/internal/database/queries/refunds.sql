@@ -0,0 +1,54 @@
+-- name: NextCreditNoteNumber :one
+-- Reserves the next credit note number. The counter row stays locked until
+-- the transaction ends, so a rollback gives the number back.
+INSERT INTO credit_note_sequences (id, last_number)
+VALUES (1, 1)
+ON CONFLICT (id) DO UPDATE SET last_number = credit_note_sequences.last_number + 1
+RETURNING last_number;
+
+-- name: CreateRefund :one
+INSERT INTO refunds (
+  id, order_id, credit_note_number, status, source, amount, net_amount, vat_amount,
+  reason, restocked, stripe_refund_id, created_by, created_at, updated_at
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $13)
+RETURNING *;
+
+-- name: CreateRefundItem :one
+INSERT INTO refund_items (
+  id, refund_id, order_item_id, description, quantity,
+  amount, net_amount, vat_rate, vat_amount
+) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+RETURNING *;
+
+-- name: GetRefund :one
+SELECT * FROM refunds WHERE id = $1;
+
+-- name: GetRefundByStripeID :one
+SELECT * FROM refunds WHERE stripe_refund_id = $1;
+
+-- name: MarkRefundSucceeded :one
+UPDATE refunds SET status = 'succeeded', stripe_refund_id = $2, credit_note_number = $3, updated_at = now()
+WHERE id = $1
+RETURNING *;
+
+-- name: MarkRefundFailed :exec
+UPDATE refunds SET status = 'failed', failure_message = $2, updated_at = now()
+WHERE id = $1;
+
+-- name: ListOrderRefunds :many
+SELECT * FROM refunds WHERE order_id = $1 ORDER BY created_at DESC, credit_note_number DESC;
+
+-- name: ListRefundItems :many
+SELECT * FROM refund_items WHERE refund_id = $1 ORDER BY description, id;
+
+-- name: SumOrderRefunds :one
+SELECT COALESCE(SUM(amount), 0)::numeric AS refunded
+FROM refunds
+WHERE order_id = $1 AND status IN ('pending', 'succeeded');
+
+-- name: SumRefundedQuantities :many
+SELECT ri.order_item_id, SUM(ri.quantity)::integer AS quantity
+FROM refund_items ri
+JOIN refunds r ON r.id = ri.refund_id
+WHERE r.order_id = $1 AND r.status IN ('pending', 'succeeded') AND ri.order_item_id IS NOT NULL
+GROUP BY ri.order_item_id;
@@ -92,3 +92,71 @@ WHERE o.payment_status = 'paid'
 GROUP BY oi.product_name
 ORDER BY total_revenue DESC
 LIMIT @max_results;
+
+-- name: CartRecoverySummary :one
+-- Abandoned cart reminders sent in a period, how many were restored, and the
+-- paid orders and revenue they led to.
+SELECT
+  COUNT(*) as emails_sent,
+  COUNT(r.restored_at) as carts_restored,
+  COUNT(o.id) as orders_recovered,
+  COALESCE(SUM(o.total), 0) as recovered_revenue
+FROM cart_recoveries r
+LEFT JOIN orders o ON o.id = r.order_id AND o.payment_status = 'paid'
+WHERE r.sent_at >= @from_date
+  AND r.sent_at < @to_date;
+
+-- name: OSSSalesByCountry :many
+-- B2C sales per EU destination country and VAT rate for a period. Orders
+-- refunded since are included; their refunds are reported separately. An
+-- order's discount is spread over its lines by gross total, so each rate
+-- reports what was actually charged.
+WITH lines AS (
+  SELECT
+    o.vat_country_code,
+    oi.vat_rate_type,
+    oi.vat_rate,
+    oi.net_unit_price * oi.quantity as net,
+    oi.vat_amount as vat,
+    1 - LEAST(o.discount_amount, SUM(oi.total_price) OVER (PARTITION BY o.id))
+      / NULLIF(SUM(oi.total_price) OVER (PARTITION BY o.id), 0) as kept
+  FROM orders o
+  JOIN order_items oi ON oi.order_id = o.id
+  WHERE o.payment_status IN ('paid', 'partially_refunded', 'refunded')
+    AND o.vat_reverse_charge = false
+    AND o.created_at >= @from_date
+    AND o.created_at < @to_date
+)
+SELECT
+  l.vat_country_code as country_code,
+  ec.name as country_name,
+  l.vat_rate_type as rate_type,
+  l.vat_rate as rate,
+  COALESCE(ROUND(SUM(l.net * COALESCE(l.kept, 1)), 2), 0)::numeric as net_sales,
+  COALESCE(ROUND(SUM(l.vat * COALESCE(l.kept, 1)), 2), 0)::numeric as vat_amount
+FROM lines l
+JOIN eu_countries ec ON ec.country_code = l.vat_country_code
+GROUP BY l.vat_country_code, ec.name, l.vat_rate_type, l.vat_rate
+ORDER BY l.vat_country_code, l.vat_rate DESC;
+
+-- name: OSSRefundsByCountry :many
+-- Succeeded refunds of B2C orders made in a period, per EU destination
+-- country and VAT rate, with the year and quarter the order was placed in.
+SELECT
+  o.vat_country_code as country_code,
+  ec.name as country_name,
+  ri.vat_rate as rate,
+  EXTRACT(YEAR FROM o.created_at AT TIME ZONE 'UTC')::integer as order_year,
+  EXTRACT(QUARTER FROM o.created_at AT TIME ZONE 'UTC')::integer as order_quarter,
+  COALESCE(SUM(ri.net_amount), 0)::numeric as net_amount,
+  COALESCE(SUM(ri.vat_amount), 0)::numeric as vat_amount
+FROM refunds r
+JOIN refund_items ri ON ri.refund_id = r.id
+JOIN orders o ON o.id = r.order_id
+JOIN eu_countries ec ON ec.country_code = o.vat_country_code
+WHERE r.status = 'succeeded'
+  AND o.vat_reverse_charge = false
+  AND r.created_at >= @from_date
+  AND r.created_at < @to_date
+GROUP BY o.vat_country_code, ec.name, ri.vat_rate, order_year, order_quarter
+ORDER BY o.vat_country_code, ri.vat_rate DESC, order_year, order_quarter;
@@ -8,7 +8,13 @@ INSERT INTO stock_movements (
 RETURNING *;
 
 -- name: ListStockMovements :many
-SELECT * FROM stock_movements
-WHERE entity_type = $1 AND entity_id = $2
-ORDER BY created_at DESC
+SELECT sm.*, au.name AS created_by_name
+FROM stock_movements sm
+LEFT JOIN admin_users au ON au.id = sm.created_by
+WHERE sm.entity_type = $1 AND sm.entity_id = $2
+ORDER BY sm.created_at DESC, sm.id
 LIMIT $3 OFFSET $4;
+
+-- name: CountStockMovements :one
+SELECT COUNT(*) FROM stock_movements
+WHERE entity_type = $1 AND entity_id = $2;
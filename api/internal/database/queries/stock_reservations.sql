@@ -0,0 +1,63 @@
+-- name: GetVariantForReservation :one
+SELECT pv.id, pv.sku, pv.stock_quantity, pv.is_active, p.allow_backorder
+FROM product_variants pv
+JOIN products p ON p.id = pv.product_id
+WHERE pv.id = $1
+FOR UPDATE OF pv;
+
+-- name: SumActiveReservations :one
+SELECT COALESCE(SUM(quantity), 0)::integer AS reserved
+FROM stock_reservations
+WHERE variant_id = $1 AND cart_id IS DISTINCT FROM $2
+  AND status = 'active' AND expires_at > now();
+
+-- name: CreateStockReservation :one
+INSERT INTO stock_reservations (id, cart_id, variant_id, quantity, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING *;
+
+-- name: ListCartCheckoutSessions :many
+SELECT DISTINCT stripe_checkout_session_id::text AS stripe_checkout_session_id
+FROM stock_reservations
+WHERE cart_id = $1 AND status = 'active' AND stripe_checkout_session_id IS NOT NULL;
+
+-- name: ReleaseCartReservations :exec
+UPDATE stock_reservations SET status = 'released', updated_at = now()
+WHERE cart_id = $1 AND status = 'active';
+
+-- name: SetReservationCheckoutSession :exec
+UPDATE stock_reservations SET stripe_checkout_session_id = $2, updated_at = now()
+WHERE cart_id = $1 AND status = 'active';
+
+-- name: ReleaseSessionReservations :exec
+UPDATE stock_reservations SET status = 'released', updated_at = now()
+WHERE stripe_checkout_session_id = $1 AND status = 'active';
+
+-- name: ListActiveSessionReservationsForUpdate :many
+SELECT * FROM stock_reservations
+WHERE stripe_checkout_session_id = $1 AND status = 'active'
+ORDER BY variant_id
+FOR UPDATE;
+
+-- name: CreateCommittedReservation :one
+INSERT INTO stock_reservations (id, cart_id, variant_id, quantity, status, stripe_checkout_session_id, order_id, expires_at)
+VALUES ($1, $2, $3, $4, 'committed', $5, $6, now())
+RETURNING *;
+
+-- name: MarkReservationCommitted :exec
+UPDATE stock_reservations SET status = 'committed', order_id = $2, updated_at = now()
+WHERE id = $1;
+
+-- name: ListCommittedOrderReservationsForUpdate :many
+SELECT * FROM stock_reservations
+WHERE order_id = $1 AND status = 'committed'
+ORDER BY variant_id
+FOR UPDATE;
+
+-- name: DecrementReservation :exec
+UPDATE stock_reservations SET quantity = quantity - @quantity, updated_at = now()
+WHERE id = @id;
+
+-- name: MarkReservationReleased :exec
+UPDATE stock_reservations SET status = 'released', updated_at = now()
+WHERE id = $1;
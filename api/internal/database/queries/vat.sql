@@ -46,6 +46,10 @@ UPDATE store_settings SET
   vat_b2b_reverse_charge_enabled = $6, updated_at = $7
 WHERE id = (SELECT id FROM store_settings LIMIT 1);
 
+-- name: UpdateStoreAddress :exec
+UPDATE store_settings SET store_address = $1, updated_at = $2
+WHERE id = (SELECT id FROM store_settings LIMIT 1);
+
 -- name: ListProductVATOverrides :many
 SELECT pvo.*, vc.name as category_name, vc.display_name as category_display_name,
        ec.name as country_name
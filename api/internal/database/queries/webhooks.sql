@@ -13,27 +13,63 @@ SELECT * FROM webhook_endpoints ORDER BY created_at DESC;
 SELECT * FROM webhook_endpoints WHERE is_active = true ORDER BY created_at DESC;
 
 -- name: UpdateWebhookEndpoint :one
+-- Re-activating an endpoint clears any automatic disable.
 UPDATE webhook_endpoints
-SET url = $2, events = $3, description = $4, is_active = $5, updated_at = NOW()
+SET url = $2, events = $3, description = $4, is_active = $5,
+    consecutive_dead_letters = CASE WHEN $5 THEN 0 ELSE consecutive_dead_letters END,
+    disabled_at = CASE WHEN $5 THEN NULL ELSE disabled_at END,
+    disabled_reason = CASE WHEN $5 THEN NULL ELSE disabled_reason END,
+    updated_at = NOW()
 WHERE id = $1
 RETURNING *;
 
 -- name: DeleteWebhookEndpoint :exec
 DELETE FROM webhook_endpoints WHERE id = $1;
 
+-- name: ResetWebhookEndpointDeadLetters :exec
+UPDATE webhook_endpoints
+SET consecutive_dead_letters = 0
+WHERE id = $1 AND consecutive_dead_letters <> 0;
+
+-- name: IncrementWebhookEndpointDeadLetters :one
+UPDATE webhook_endpoints
+SET consecutive_dead_letters = consecutive_dead_letters + 1
+WHERE id = $1
+RETURNING consecutive_dead_letters;
+
+-- name: DisableWebhookEndpoint :exec
+UPDATE webhook_endpoints
+SET is_active = false, disabled_at = NOW(), disabled_reason = $2, updated_at = NOW()
+WHERE id = $1;
+
 -- name: CreateWebhookDelivery :one
 INSERT INTO webhook_deliveries (endpoint_id, event_type, payload)
 VALUES ($1, $2, $3)
 RETURNING *;
 
+-- name: RedeliverWebhookDelivery :one
+-- Queues a fresh copy of an earlier delivery so its history is kept intact.
+INSERT INTO webhook_deliveries (endpoint_id, event_type, payload)
+SELECT endpoint_id, event_type, payload
+FROM webhook_deliveries
+WHERE webhook_deliveries.id = $1 AND webhook_deliveries.endpoint_id = $2
+RETURNING *;
+
+-- name: GetWebhookDelivery :one
+SELECT * FROM webhook_deliveries WHERE id = $1;
+
 -- name: UpdateWebhookDeliverySuccess :exec
 UPDATE webhook_deliveries
-SET response_status = $2, response_body = $3, delivered_at = NOW(), attempt = attempt + 1
+SET status = 'delivered', response_status = $2, response_body = $3, last_error = NULL,
+    delivered_at = NOW(), attempt = attempt + 1, next_retry_at = NULL, locked_until = NULL
 WHERE id = $1;
 
 -- name: UpdateWebhookDeliveryFailed :exec
+-- status is 'pending' with a next_retry_at when another attempt is due,
+-- or 'dead' with a NULL next_retry_at once the schedule is exhausted.
 UPDATE webhook_deliveries
-SET response_status = $2, response_body = $3, attempt = attempt + 1, next_retry_at = $4
+SET status = $2, response_status = $3, response_body = $4, last_error = $5,
+    attempt = attempt + 1, next_retry_at = $6, locked_until = NULL
 WHERE id = $1;
 
 -- name: ListWebhookDeliveries :many
@@ -42,15 +78,27 @@ WHERE endpoint_id = $1
 ORDER BY created_at DESC
 LIMIT $2 OFFSET $3;
 
--- name: ListPendingWebhookDeliveries :many
-SELECT wd.*, we.url, we.secret
-FROM webhook_deliveries wd
-JOIN webhook_endpoints we ON we.id = wd.endpoint_id
-WHERE wd.delivered_at IS NULL
-  AND (wd.next_retry_at IS NULL OR wd.next_retry_at <= NOW())
-  AND wd.attempt < 5
-ORDER BY wd.created_at ASC
-LIMIT $1;
+-- name: LeaseWebhookDeliveries :many
+-- Claims due deliveries until locked_until. SKIP LOCKED lets several workers
+-- lease concurrently; an expired lease (e.g. the process died mid-send)
+-- makes the delivery due again.
+WITH due AS (
+    SELECT wd.id
+    FROM webhook_deliveries wd
+    JOIN webhook_endpoints we ON we.id = wd.endpoint_id
+    WHERE wd.status = 'pending'
+      AND wd.next_retry_at <= NOW()
+      AND (wd.locked_until IS NULL OR wd.locked_until <= NOW())
+      AND we.is_active = true
+    ORDER BY wd.next_retry_at ASC
+    LIMIT $1
+    FOR UPDATE OF wd SKIP LOCKED
+)
+UPDATE webhook_deliveries d
+SET locked_until = $2
+FROM due, webhook_endpoints e
+WHERE d.id = due.id AND e.id = d.endpoint_id
+RETURNING d.*, e.url, e.secret;
 
 -- name: CountWebhookDeliveries :one
 SELECT COUNT(*) FROM webhook_deliveries WHERE endpoint_id = $1;
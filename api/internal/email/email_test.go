@@ -0,0 +1,330 @@
+package email
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+// --------------------------------------------------------------------------
+// Tests for language selection
+// --------------------------------------------------------------------------
+
+func TestNormalizeLanguage(t *testing.T) {
+	tests := map[string]string{
+		"de":    "de",
+		"DE-at": "de",
+		"fr_CA": "fr",
+		" es ":  "es",
+		"it":    DefaultLanguage,
+		"":      DefaultLanguage,
+	}
+	for in, want := range tests {
+		if got := NormalizeLanguage(in); got != want {
+			t.Errorf("NormalizeLanguage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLanguageFromAcceptLanguage(t *testing.T) {
+	tests := map[string]string{
+		"de-DE,de;q=0.9,en;q=0.8": "de",
+		"it-IT,it;q=0.9,fr;q=0.8": "fr",
+		"en-GB,en;q=0.9":          "en",
+		"*":                       DefaultLanguage,
+		"":                        DefaultLanguage,
+	}
+	for in, want := range tests {
+		if got := LanguageFromAcceptLanguage(in); got != want {
+			t.Errorf("LanguageFromAcceptLanguage(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLanguageFromMetadata(t *testing.T) {
+	tests := map[string]string{
+		`{"language":"es-ES"}`: "es",
+		`{"language":"xx"}`:    DefaultLanguage,
+		`{}`:                   "",
+		`not json`:             "",
+		``:                     "",
+	}
+	for in, want := range tests {
+		if got := LanguageFromMetadata([]byte(in)); got != want {
+			t.Errorf("LanguageFromMetadata(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestMessages_AllLanguagesComplete(t *testing.T) {
+	for _, lang := range Languages {
+		for key := range messages[DefaultLanguage] {
+			if _, ok := messages[lang][key]; !ok {
+				t.Errorf("%s: missing translation for %q", lang, key)
+			}
+		}
+		for key := range messages[lang] {
+			if _, ok := messages[DefaultLanguage][key]; !ok {
+				t.Errorf("%s: translation %q has no %s original", lang, key, DefaultLanguage)
+			}
+		}
+	}
+}
+
+// --------------------------------------------------------------------------
+// Tests for formatMoney
+// --------------------------------------------------------------------------
+
+func TestFormatMoney(t *testing.T) {
+	tests := []struct {
+		lang, currency, amount, want string
+	}{
+		{"en", "EUR", "1234.5", "€1,234.50"},
+		{"en", "EUR", "0.00", "€0.00"},
+		{"en", "CHF", "12", "CHF 12.00"},
+		{"de", "EUR", "1234567.891", "1.234.567,89\u00a0€"},
+		{"es", "EUR", "999.99", "999,99\u00a0€"},
+		{"fr", "EUR", "1234.50", "1\u202f234,50\u00a0€"},
+		{"de", "EUR", "-5", "-5,00\u00a0€"},
+		{"en", "EUR", "not a number", "not a number"},
+	}
+	for _, tt := range tests {
+		if got := formatMoney(tt.lang, tt.currency, tt.amount); got != tt.want {
+			t.Errorf("formatMoney(%q, %q, %q) = %q, want %q", tt.lang, tt.currency, tt.amount, got, tt.want)
+		}
+	}
+}
+
+// --------------------------------------------------------------------------
+// Tests for Render
+// --------------------------------------------------------------------------
+
+func testOrder() Order {
+	return Order{
+		Number: 1042,
+		Items: []OrderLine{
+			{Name: "Leather Wallet – Brown", Quantity: 2, Total: "100.00"},
+		},
+		Subtotal:       "100.00",
+		ShippingFee:    "5.00",
+		DiscountAmount: "10.00",
+		VATTotal:       "21.00",
+		Total:          "116.00",
+		ShippingMethod: "DHL Express",
+		TrackingNumber: "JD014600003SE",
+	}
+}
+
+func testContents() []Content {
+	store := Store{Name: "Forge & Co", URL: "https://shop.example.com", Currency: "EUR"}
+	return []Content{
+		OrderConfirmation{Store: store, CustomerName: "Ana", Order: testOrder()},
+		ShippingNotification{Store: store, Order: testOrder()},
+		RefundNotice{Store: store, CustomerName: "Ana", Order: testOrder(), Amount: "60.50", CreditNoteNumber: 17, Reason: "Damaged"},
+		Welcome{Store: store, CustomerName: "Ana"},
+		LowStockAlert{Store: store, ProductName: "Leather Wallet", SKU: "WAL-BRN", StockQuantity: 4, LowStockThreshold: 5, ProductURL: "https://admin.example.com/admin/products/1"},
+		PasswordReset{Store: store, CustomerName: "Ana", URL: "https://shop.example.com/reset-password?token=abc-123"},
+		EmailVerification{Store: store, URL: "https://shop.example.com/verify-email?token=abc-123"},
+		CartRecovery{Store: store, CustomerName: "Ana", Items: testOrder().Items, Subtotal: "100.00", URL: "https://shop.example.com/restore-cart?token=abc-123", CouponCode: "BACK7K3MQ2XP"},
+	}
+}
+
+func TestRender_AllKindsAndLanguages(t *testing.T) {
+	for _, lang := range Languages {
+		for _, c := range testContents() {
+			r, err := Render(lang, c)
+			if err != nil {
+				t.Fatalf("Render(%s, %s): %v", lang, c.Kind(), err)
+			}
+			for part, body := range map[string]string{"subject": r.Subject, "html": r.HTML, "text": r.Text} {
+				if strings.TrimSpace(body) == "" {
+					t.Errorf("%s/%s: empty %s", lang, c.Kind(), part)
+				}
+				// Untranslated keys are rendered as the key itself and bad
+				// format arguments as %!verb.
+				if strings.Contains(body, "%!") || strings.Contains(body, c.Kind()+".") {
+					t.Errorf("%s/%s: %s has a formatting error:\n%s", lang, c.Kind(), part, body)
+				}
+			}
+			if !strings.Contains(r.Subject, "Forge & Co") {
+				t.Errorf("%s/%s: subject %q does not name the store", lang, c.Kind(), r.Subject)
+			}
+			if !strings.Contains(r.HTML, "Forge &amp; Co") {
+				t.Errorf("%s/%s: html does not escape the store name", lang, c.Kind())
+			}
+			if !strings.Contains(r.HTML, `<html lang="`+lang+`">`) {
+				t.Errorf("%s/%s: html lang attribute not set", lang, c.Kind())
+			}
+		}
+	}
+}
+
+func TestRender_ShippingNotificationIncludesTracking(t *testing.T) {
+	r, err := Render("de", testContents()[1])
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	for _, body := range []string{r.HTML, r.Text} {
+		if !strings.Contains(body, "Sendungsnummer: JD014600003SE") {
+			t.Errorf("tracking number missing:\n%s", body)
+		}
+	}
+	if r.Subject != "Bestellung #1042 wurde versandt – Forge & Co" {
+		t.Errorf("subject = %q", r.Subject)
+	}
+}
+
+func TestRender_TokenLinks(t *testing.T) {
+	for _, c := range testContents()[5:] {
+		r, err := Render("fr", c)
+		if err != nil {
+			t.Fatalf("Render: %v", err)
+		}
+		for _, body := range []string{r.HTML, r.Text} {
+			if !strings.Contains(body, "?token=abc-123") {
+				t.Errorf("%s: link missing:\n%s", c.Kind(), body)
+			}
+		}
+	}
+}
+
+func TestRender_CartRecoveryCoupon(t *testing.T) {
+	c := testContents()[7].(CartRecovery)
+	r, err := Render("en", c)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	for _, want := range []string{"2 × Leather Wallet – Brown  €100.00", "Subtotal: €100.00", "BACK7K3MQ2XP"} {
+		if !strings.Contains(r.Text, want) {
+			t.Errorf("text missing %q:\n%s", want, r.Text)
+		}
+	}
+
+	c.CouponCode = ""
+	r, err = Render("en", c)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if strings.Contains(r.Text, "one-time code") {
+		t.Errorf("coupon text shown without a coupon:\n%s", r.Text)
+	}
+}
+
+func TestRender_OrderSummary(t *testing.T) {
+	r, err := Render("en", testContents()[0])
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	for _, want := range []string{"2 × Leather Wallet – Brown  €100.00", "Discount: -€10.00", "Total: €116.00", "Hello Ana,"} {
+		if !strings.Contains(r.Text, want) {
+			t.Errorf("text missing %q:\n%s", want, r.Text)
+		}
+	}
+
+	noDiscount := testContents()[0].(OrderConfirmation)
+	noDiscount.Order.DiscountAmount = "0.00"
+	r, err = Render("en", noDiscount)
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if strings.Contains(r.Text, "Discount") {
+		t.Errorf("zero discount should be hidden:\n%s", r.Text)
+	}
+}
+
+func TestRender_UnsupportedLanguageFallsBack(t *testing.T) {
+	r, err := Render("it", Welcome{Store: Store{Name: "Shop"}})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if r.Subject != "Welcome to Shop" {
+		t.Errorf("subject = %q, want English fallback", r.Subject)
+	}
+}
+
+// --------------------------------------------------------------------------
+// Tests for buildMIME
+// --------------------------------------------------------------------------
+
+func TestBuildMIME(t *testing.T) {
+	raw, err := buildMIME("Shop <shop@example.com>", Message{
+		To:      "buyer@example.com",
+		Subject: "Bestellung #1 bestätigt",
+		HTML:    "<p>Grüße</p>",
+		Text:    "Grüße",
+	}, time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("buildMIME: %v", err)
+	}
+
+	msg, err := mail.ReadMessage(strings.NewReader(string(raw)))
+	if err != nil {
+		t.Fatalf("parsing message: %v", err)
+	}
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	if err != nil || subject != "Bestellung #1 bestätigt" {
+		t.Errorf("subject = %q (%v)", subject, err)
+	}
+	if !strings.HasSuffix(msg.Header.Get("Message-ID"), "@example.com>") {
+		t.Errorf("Message-ID = %q", msg.Header.Get("Message-ID"))
+	}
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err != nil || mediaType != "multipart/alternative" {
+		t.Fatalf("content type = %q (%v)", mediaType, err)
+	}
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	var parts []string
+	for {
+		p, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("reading part: %v", err)
+		}
+		// multipart.Reader decodes quoted-printable transparently.
+		body, _ := io.ReadAll(p)
+		parts = append(parts, p.Header.Get("Content-Type")+": "+string(body))
+	}
+	want := []string{
+		"text/plain; charset=utf-8: Grüße",
+		"text/html; charset=utf-8: <p>Grüße</p>",
+	}
+	if strings.Join(parts, "\n") != strings.Join(want, "\n") {
+		t.Errorf("parts:\n%s\nwant:\n%s", strings.Join(parts, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestBuildMIME_InvalidRecipient(t *testing.T) {
+	if _, err := buildMIME("shop@example.com", Message{To: "not an address"}, time.Now()); err == nil {
+		t.Error("expected an error for an invalid recipient")
+	}
+}
+
+// --------------------------------------------------------------------------
+// Tests for RetryDelay
+// --------------------------------------------------------------------------
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{7, 64 * time.Minute},
+		{8, 2 * time.Hour},
+		{20, 2 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := RetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("RetryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
+	}
+}
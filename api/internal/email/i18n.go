@@ -0,0 +1,323 @@
+package email
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// DefaultLanguage is used when a recipient's language is unknown or
+// unsupported.
+const DefaultLanguage = "en"
+
+// Languages lists the supported message languages.
+var Languages = []string{"en", "de", "es", "fr"}
+
+// NormalizeLanguage reduces a language tag such as "de-AT" to a supported
+// language code, or returns DefaultLanguage.
+func NormalizeLanguage(tag string) string {
+	if l, ok := supportedLanguage(tag); ok {
+		return l
+	}
+	return DefaultLanguage
+}
+
+func supportedLanguage(tag string) (string, bool) {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	for _, l := range Languages {
+		if tag == l {
+			return l, true
+		}
+	}
+	return "", false
+}
+
+// LanguageFromAcceptLanguage picks the first supported language from an
+// Accept-Language header value. Quality weights are not ranked; browsers list
+// languages in order of preference.
+func LanguageFromAcceptLanguage(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		if l, ok := supportedLanguage(tag); ok {
+			return l
+		}
+	}
+	return DefaultLanguage
+}
+
+// LanguageFromMetadata returns the "language" key of an order or customer
+// metadata object, or "" if it is not set.
+func LanguageFromMetadata(metadata []byte) string {
+	var m struct {
+		Language string `json:"language"`
+	}
+	if len(metadata) == 0 || json.Unmarshal(metadata, &m) != nil || m.Language == "" {
+		return ""
+	}
+	return NormalizeLanguage(m.Language)
+}
+
+// messages holds the translated strings for each language. Values are
+// fmt format strings; arguments are referenced by index where languages
+// order them differently.
+var messages = map[string]map[string]string{
+	"en": {
+		"label":                      "%s:",
+		"greeting":                   "Hello,",
+		"greeting_name":              "Hello %s,",
+		"signoff":                    "Thank you,\nThe %s team",
+		"order":                      "Order #%d",
+		"item":                       "Item",
+		"qty":                        "Qty",
+		"total":                      "Total",
+		"subtotal":                   "Subtotal",
+		"shipping":                   "Shipping",
+		"discount":                   "Discount",
+		"vat":                        "VAT",
+		"order_confirmation.subject": "Order #%[2]d confirmed – %[1]s",
+		"order_confirmation.intro":   "Thank you for your order. We have received your payment and will let you know when your order ships.",
+		"shipping.subject":           "Order #%[2]d has shipped – %[1]s",
+		"shipping.intro":             "Good news: your order is on its way.",
+		"shipping.method":            "Shipping method: %s",
+		"shipping.tracking":          "Tracking number: %s",
+		"refund.subject":             "Refund for order #%[2]d – %[1]s",
+		"refund.intro":               "We have refunded %s for your order. Depending on your bank it can take a few days to appear on your statement.",
+		"refund.credit_note":         "Credit note: CN-%d",
+		"refund.reason":              "Reason: %s",
+		"welcome.subject":            "Welcome to %s",
+		"welcome.intro":              "Your account has been created. You can now sign in to track your orders and check out faster.",
+		"welcome.cta":                "Visit the shop",
+		"low_stock.subject":          "Low stock: %[2]s – %[1]s",
+		"low_stock.intro":            "A variant has fallen to or below its low-stock threshold.",
+		"low_stock.product":          "Product: %s",
+		"low_stock.sku":              "SKU: %s",
+		"low_stock.level":            "In stock: %[1]d (threshold %[2]d)",
+		"low_stock.cta":              "View product",
+		"password_reset.subject":     "Reset your password – %s",
+		"password_reset.intro":       "We received a request to reset the password for your account. The link below is valid for one hour and can be used once.",
+		"password_reset.cta":         "Reset password",
+		"password_reset.ignore":      "If you did not ask to reset your password, you can ignore this email. Your password will not change.",
+		"email_verification.subject": "Confirm your email address – %s",
+		"email_verification.intro":   "Please confirm that this is your email address. The link below is valid for 48 hours.",
+		"email_verification.cta":     "Confirm email address",
+		"email_verification.ignore":  "If you did not create an account, you can ignore this email.",
+		"cart_recovery.subject":      "You left something in your cart – %s",
+		"cart_recovery.intro":        "You left these items in your cart. We have saved it for you, so you can pick up where you left off.",
+		"cart_recovery.coupon":       "As a thank you, here is a one-time code for a discount on this order:",
+		"cart_recovery.cta":          "Return to your cart",
+		"cart_recovery.ignore":       "Prices and availability may have changed since you added these items. If you have already ordered, you can ignore this email.",
+	},
+	"de": {
+		"label":                      "%s:",
+		"greeting":                   "Hallo,",
+		"greeting_name":              "Hallo %s,",
+		"signoff":                    "Vielen Dank,\nIhr %s-Team",
+		"order":                      "Bestellung #%d",
+		"item":                       "Artikel",
+		"qty":                        "Menge",
+		"total":                      "Gesamt",
+		"subtotal":                   "Zwischensumme",
+		"shipping":                   "Versand",
+		"discount":                   "Rabatt",
+		"vat":                        "MwSt.",
+		"order_confirmation.subject": "Bestellung #%[2]d bestätigt – %[1]s",
+		"order_confirmation.intro":   "Vielen Dank für Ihre Bestellung. Wir haben Ihre Zahlung erhalten und benachrichtigen Sie, sobald Ihre Bestellung versandt wird.",
+		"shipping.subject":           "Bestellung #%[2]d wurde versandt – %[1]s",
+		"shipping.intro":             "Gute Nachrichten: Ihre Bestellung ist unterwegs.",
+		"shipping.method":            "Versandart: %s",
+		"shipping.tracking":          "Sendungsnummer: %s",
+		"refund.subject":             "Erstattung für Bestellung #%[2]d – %[1]s",
+		"refund.intro":               "Wir haben %s für Ihre Bestellung erstattet. Je nach Bank kann es einige Tage dauern, bis der Betrag auf Ihrem Konto erscheint.",
+		"refund.credit_note":         "Gutschrift: CN-%d",
+		"refund.reason":              "Grund: %s",
+		"welcome.subject":            "Willkommen bei %s",
+		"welcome.intro":              "Ihr Konto wurde erstellt. Sie können sich jetzt anmelden, um Ihre Bestellungen zu verfolgen und schneller zu bezahlen.",
+		"welcome.cta":                "Zum Shop",
+		"low_stock.subject":          "Niedriger Bestand: %[2]s – %[1]s",
+		"low_stock.intro":            "Eine Variante hat ihren Mindestbestand erreicht oder unterschritten.",
+		"low_stock.product":          "Produkt: %s",
+		"low_stock.sku":              "SKU: %s",
+		"low_stock.level":            "Bestand: %[1]d (Schwelle %[2]d)",
+		"low_stock.cta":              "Produkt ansehen",
+		"password_reset.subject":     "Passwort zurücksetzen – %s",
+		"password_reset.intro":       "Wir haben eine Anfrage erhalten, das Passwort für Ihr Konto zurückzusetzen. Der folgende Link ist eine Stunde lang gültig und kann einmal verwendet werden.",
+		"password_reset.cta":         "Passwort zurücksetzen",
+		"password_reset.ignore":      "Wenn Sie das Zurücksetzen nicht angefordert haben, können Sie diese E-Mail ignorieren. Ihr Passwort bleibt unverändert.",
+		"email_verification.subject": "Bestätigen Sie Ihre E-Mail-Adresse – %s",
+		"email_verification.intro":   "Bitte bestätigen Sie, dass dies Ihre E-Mail-Adresse ist. Der folgende Link ist 48 Stunden lang gültig.",
+		"email_verification.cta":     "E-Mail-Adresse bestätigen",
+		"email_verification.ignore":  "Wenn Sie kein Konto erstellt haben, können Sie diese E-Mail ignorieren.",
+		"cart_recovery.subject":      "Sie haben etwas in Ihrem Warenkorb vergessen – %s",
+		"cart_recovery.intro":        "Diese Artikel liegen noch in Ihrem Warenkorb. Wir haben ihn für Sie gespeichert, sodass Sie dort weitermachen können, wo Sie aufgehört haben.",
+		"cart_recovery.coupon":       "Als kleines Dankeschön erhalten Sie einen einmaligen Rabattcode für diese Bestellung:",
+		"cart_recovery.cta":          "Zum Warenkorb",
+		"cart_recovery.ignore":       "Preise und Verfügbarkeit können sich seitdem geändert haben. Wenn Sie bereits bestellt haben, können Sie diese E-Mail ignorieren.",
+	},
+	"es": {
+		"label":                      "%s:",
+		"greeting":                   "Hola:",
+		"greeting_name":              "Hola, %s:",
+		"signoff":                    "Gracias,\nEl equipo de %s",
+		"order":                      "Pedido n.º %d",
+		"item":                       "Artículo",
+		"qty":                        "Cant.",
+		"total":                      "Total",
+		"subtotal":                   "Subtotal",
+		"shipping":                   "Envío",
+		"discount":                   "Descuento",
+		"vat":                        "IVA",
+		"order_confirmation.subject": "Pedido n.º %[2]d confirmado – %[1]s",
+		"order_confirmation.intro":   "Gracias por tu pedido. Hemos recibido el pago y te avisaremos cuando se envíe.",
+		"shipping.subject":           "Tu pedido n.º %[2]d ha sido enviado – %[1]s",
+		"shipping.intro":             "Buenas noticias: tu pedido está en camino.",
+		"shipping.method":            "Método de envío: %s",
+		"shipping.tracking":          "Número de seguimiento: %s",
+		"refund.subject":             "Reembolso del pedido n.º %[2]d – %[1]s",
+		"refund.intro":               "Hemos reembolsado %s de tu pedido. Según tu banco, puede tardar unos días en aparecer en tu cuenta.",
+		"refund.credit_note":         "Factura rectificativa: CN-%d",
+		"refund.reason":              "Motivo: %s",
+		"welcome.subject":            "Bienvenido a %s",
+		"welcome.intro":              "Tu cuenta se ha creado. Ya puedes iniciar sesión para seguir tus pedidos y pagar más rápido.",
+		"welcome.cta":                "Ir a la tienda",
+		"low_stock.subject":          "Stock bajo: %[2]s – %[1]s",
+		"low_stock.intro":            "Una variante ha alcanzado o bajado de su umbral de stock mínimo.",
+		"low_stock.product":          "Producto: %s",
+		"low_stock.sku":              "SKU: %s",
+		"low_stock.level":            "En stock: %[1]d (umbral %[2]d)",
+		"low_stock.cta":              "Ver producto",
+		"password_reset.subject":     "Restablece tu contraseña – %s",
+		"password_reset.intro":       "Hemos recibido una solicitud para restablecer la contraseña de tu cuenta. El siguiente enlace es válido durante una hora y solo puede usarse una vez.",
+		"password_reset.cta":         "Restablecer contraseña",
+		"password_reset.ignore":      "Si no has solicitado restablecer tu contraseña, puedes ignorar este correo. Tu contraseña no cambiará.",
+		"email_verification.subject": "Confirma tu dirección de correo – %s",
+		"email_verification.intro":   "Confirma que esta es tu dirección de correo electrónico. El siguiente enlace es válido durante 48 horas.",
+		"email_verification.cta":     "Confirmar correo electrónico",
+		"email_verification.ignore":  "Si no has creado una cuenta, puedes ignorar este correo.",
+		"cart_recovery.subject":      "Has dejado algo en tu carrito – %s",
+		"cart_recovery.intro":        "Estos artículos siguen en tu carrito. Lo hemos guardado para que puedas continuar donde lo dejaste.",
+		"cart_recovery.coupon":       "Como agradecimiento, aquí tienes un código de un solo uso con un descuento para este pedido:",
+		"cart_recovery.cta":          "Volver al carrito",
+		"cart_recovery.ignore":       "Los precios y la disponibilidad pueden haber cambiado desde entonces. Si ya has hecho el pedido, puedes ignorar este correo.",
+	},
+	"fr": {
+		"label":                      "%s :",
+		"greeting":                   "Bonjour,",
+		"greeting_name":              "Bonjour %s,",
+		"signoff":                    "Merci,\nL’équipe %s",
+		"order":                      "Commande n° %d",
+		"item":                       "Article",
+		"qty":                        "Qté",
+		"total":                      "Total",
+		"subtotal":                   "Sous-total",
+		"shipping":                   "Livraison",
+		"discount":                   "Remise",
+		"vat":                        "TVA",
+		"order_confirmation.subject": "Commande n° %[2]d confirmée – %[1]s",
+		"order_confirmation.intro":   "Merci pour votre commande. Nous avons bien reçu votre paiement et vous préviendrons dès son expédition.",
+		"shipping.subject":           "Votre commande n° %[2]d a été expédiée – %[1]s",
+		"shipping.intro":             "Bonne nouvelle : votre commande est en route.",
+		"shipping.method":            "Mode de livraison : %s",
+		"shipping.tracking":          "Numéro de suivi : %s",
+		"refund.subject":             "Remboursement de la commande n° %[2]d – %[1]s",
+		"refund.intro":               "Nous avons remboursé %s sur votre commande. Selon votre banque, le montant peut mettre quelques jours à apparaître sur votre relevé.",
+		"refund.credit_note":         "Avoir : CN-%d",
+		"refund.reason":              "Motif : %s",
+		"welcome.subject":            "Bienvenue chez %s",
+		"welcome.intro":              "Votre compte a été créé. Vous pouvez maintenant vous connecter pour suivre vos commandes et payer plus rapidement.",
+		"welcome.cta":                "Visiter la boutique",
+		"low_stock.subject":          "Stock faible : %[2]s – %[1]s",
+		"low_stock.intro":            "Une variante a atteint ou dépassé à la baisse son seuil de stock minimum.",
+		"low_stock.product":          "Produit : %s",
+		"low_stock.sku":              "SKU : %s",
+		"low_stock.level":            "En stock : %[1]d (seuil %[2]d)",
+		"low_stock.cta":              "Voir le produit",
+		"password_reset.subject":     "Réinitialisez votre mot de passe – %s",
+		"password_reset.intro":       "Nous avons reçu une demande de réinitialisation du mot de passe de votre compte. Le lien ci-dessous est valable une heure et utilisable une seule fois.",
+		"password_reset.cta":         "Réinitialiser le mot de passe",
+		"password_reset.ignore":      "Si vous n’avez pas demandé cette réinitialisation, vous pouvez ignorer cet e-mail. Votre mot de passe ne changera pas.",
+		"email_verification.subject": "Confirmez votre adresse e-mail – %s",
+		"email_verification.intro":   "Veuillez confirmer qu’il s’agit bien de votre adresse e-mail. Le lien ci-dessous est valable 48 heures.",
+		"email_verification.cta":     "Confirmer l’adresse e-mail",
+		"email_verification.ignore":  "Si vous n’avez pas créé de compte, vous pouvez ignorer cet e-mail.",
+		"cart_recovery.subject":      "Vous avez oublié quelque chose dans votre panier – %s",
+		"cart_recovery.intro":        "Ces articles vous attendent toujours dans votre panier. Nous l’avons conservé pour que vous puissiez reprendre là où vous vous êtes arrêté.",
+		"cart_recovery.coupon":       "Pour vous remercier, voici un code à usage unique offrant une réduction sur cette commande :",
+		"cart_recovery.cta":          "Retourner au panier",
+		"cart_recovery.ignore":       "Les prix et la disponibilité ont pu changer depuis. Si vous avez déjà commandé, vous pouvez ignorer cet e-mail.",
+	},
+}
+
+// translate formats the message key in lang, falling back to
+// DefaultLanguage and then to the key itself.
+func translate(lang, key string, args ...any) string {
+	format, ok := messages[lang][key]
+	if !ok {
+		format, ok = messages[DefaultLanguage][key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return format
+	}
+	return fmt.Sprintf(format, args...)
+}
+
+// currencySymbols maps ISO 4217 codes to the symbol shown in emails. Other
+// currencies are shown by their code.
+var currencySymbols = map[string]string{
+	"EUR": "€",
+	"GBP": "£",
+	"USD": "$",
+}
+
+// formatMoney formats a decimal amount string for lang: "€1,234.50" in
+// English and "1.234,50 €", with a non-breaking space, in the other
+// supported languages.
+func formatMoney(lang, currency, amount string) string {
+	d, err := decimal.NewFromString(amount)
+	if err != nil {
+		return amount
+	}
+	symbol, known := currencySymbols[strings.ToUpper(currency)]
+	if !known {
+		symbol = strings.ToUpper(currency)
+	}
+
+	neg := d.IsNegative()
+	whole, frac, _ := strings.Cut(d.Abs().StringFixed(2), ".")
+
+	thousands, point := ",", "."
+	switch lang {
+	case "de", "es":
+		thousands, point = ".", ","
+	case "fr":
+		thousands, point = "\u202f", ","
+	}
+
+	var grouped strings.Builder
+	for i, r := range whole {
+		if i > 0 && (len(whole)-i)%3 == 0 {
+			grouped.WriteString(thousands)
+		}
+		grouped.WriteRune(r)
+	}
+
+	number := grouped.String() + point + frac
+	sign := ""
+	if neg {
+		sign = "-"
+	}
+	if lang == "en" {
+		if !known {
+			return sign + symbol + " " + number
+		}
+		return sign + symbol + number
+	}
+	return sign + number + "\u00a0" + symbol
+}
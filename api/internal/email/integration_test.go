@@ -0,0 +1,364 @@
+package email_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/forgecommerce/api/internal/email"
+	"github.com/forgecommerce/api/internal/services/order"
+	"github.com/forgecommerce/api/internal/services/webhook"
+	"github.com/forgecommerce/api/internal/testutil"
+)
+
+var testDB *testutil.TestDB
+
+func TestMain(m *testing.M) {
+	var code int
+	defer func() { os.Exit(code) }()
+
+	db, err := testutil.SetupTestDB()
+	if err != nil {
+		log.Fatalf("setting up test database: %v", err)
+	}
+	defer db.Close()
+	testDB = db
+
+	code = m.Run()
+}
+
+// fakeSender records sent messages, or fails every send when err is set.
+type fakeSender struct {
+	mu   sync.Mutex
+	sent []email.Message
+	err  error
+}
+
+func (f *fakeSender) Send(_ context.Context, msg email.Message) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.err != nil {
+		return f.err
+	}
+	f.sent = append(f.sent, msg)
+	return nil
+}
+
+func newService(sender email.Sender) *email.Service {
+	return email.NewService(testDB.Pool, sender, email.Config{
+		From:     "shop@example.com",
+		ShopURL:  "https://shop.example.com",
+		AdminURL: "https://admin.example.com",
+	}, nil)
+}
+
+func numericFromCents(cents int64) pgtype.Numeric {
+	return pgtype.Numeric{Int: big.NewInt(cents), Exp: -2, Valid: true}
+}
+
+// createPaidOrder creates a paid order through the order service, which
+// publishes order.paid to events.
+func createPaidOrder(t *testing.T, events webhook.Publisher, metadata string) webhook.Order {
+	t.Helper()
+	zero := numericFromCents(0)
+	o, items, err := order.NewService(testDB.Pool, events, nil).Create(context.Background(), order.CreateOrderParams{
+		Status:            "pending",
+		Email:             "buyer@example.com",
+		PaymentStatus:     "paid",
+		BillingAddress:    json.RawMessage(`{}`),
+		ShippingAddress:   json.RawMessage(`{}`),
+		Subtotal:          numericFromCents(5000),
+		ShippingFee:       zero,
+		ShippingExtraFees: zero,
+		DiscountAmount:    zero,
+		VatTotal:          zero,
+		Total:             numericFromCents(5000),
+		Metadata:          json.RawMessage(metadata),
+		Items: []order.CreateOrderItemInput{
+			{
+				ProductName:    "Leather Wallet",
+				Quantity:       1,
+				UnitPrice:      numericFromCents(5000),
+				TotalPrice:     numericFromCents(5000),
+				VatRate:        zero,
+				VatAmount:      zero,
+				NetUnitPrice:   numericFromCents(5000),
+				GrossUnitPrice: numericFromCents(5000),
+				Metadata:       json.RawMessage(`{}`),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("creating order: %v", err)
+	}
+	return webhook.NewOrder(o, items)
+}
+
+type outboxRow struct {
+	Template  string
+	Language  string
+	Recipient string
+	Subject   string
+	TextBody  string
+	Status    string
+	Attempts  int32
+	LastError *string
+}
+
+func listOutbox(t *testing.T) []outboxRow {
+	t.Helper()
+	rows, err := testDB.Pool.Query(context.Background(), `
+		SELECT template, language, recipient, subject, text_body, status, attempts, last_error
+		FROM email_outbox ORDER BY created_at`)
+	if err != nil {
+		t.Fatalf("querying outbox: %v", err)
+	}
+	defer rows.Close()
+	var out []outboxRow
+	for rows.Next() {
+		var r outboxRow
+		if err := rows.Scan(&r.Template, &r.Language, &r.Recipient, &r.Subject, &r.TextBody, &r.Status, &r.Attempts, &r.LastError); err != nil {
+			t.Fatalf("scanning outbox: %v", err)
+		}
+		out = append(out, r)
+	}
+	return out
+}
+
+// --------------------------------------------------------------------------
+// Publish
+// --------------------------------------------------------------------------
+
+func TestPublish_OrderPaidQueuesConfirmationInOrderLanguage(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService(&fakeSender{})
+
+	o := createPaidOrder(t, svc, `{"language":"de"}`)
+
+	msgs := listOutbox(t)
+	if len(msgs) != 1 {
+		t.Fatalf("outbox has %d messages, want 1", len(msgs))
+	}
+	m := msgs[0]
+	if m.Template != email.KindOrderConfirmation || m.Language != "de" || m.Recipient != "buyer@example.com" {
+		t.Errorf("message = %+v", m)
+	}
+	if !strings.Contains(m.Subject, "bestätigt") || !strings.Contains(m.TextBody, "Leather Wallet") {
+		t.Errorf("subject %q / body %q not rendered in German with items", m.Subject, m.TextBody)
+	}
+
+	// A repeated event does not queue a second confirmation.
+	svc.Publish(context.Background(), webhook.EventOrderPaid, webhook.OrderData{Order: o})
+	if n := len(listOutbox(t)); n != 1 {
+		t.Errorf("outbox has %d messages after duplicate event, want 1", n)
+	}
+}
+
+func TestPublish_ShippedQueuesTrackingNotification(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService(&fakeSender{})
+	o := createPaidOrder(t, webhook.Discard, `{}`)
+
+	if _, err := testDB.Pool.Exec(context.Background(),
+		`UPDATE orders SET tracking_number = 'JD014600003SE' WHERE id = $1`, o.ID); err != nil {
+		t.Fatalf("setting tracking number: %v", err)
+	}
+
+	svc.Publish(context.Background(), webhook.EventOrderStatusChanged, webhook.OrderStatusChangedData{
+		Order: o, FromStatus: "processing", ToStatus: "delivered",
+	})
+	if n := len(listOutbox(t)); n != 0 {
+		t.Fatalf("non-shipping status change queued %d messages", n)
+	}
+
+	svc.Publish(context.Background(), webhook.EventOrderStatusChanged, webhook.OrderStatusChangedData{
+		Order: o, FromStatus: "processing", ToStatus: order.StatusShipped,
+	})
+	msgs := listOutbox(t)
+	if len(msgs) != 1 || msgs[0].Template != email.KindShippingNotification {
+		t.Fatalf("outbox = %+v, want one shipping notification", msgs)
+	}
+	if msgs[0].Language != email.DefaultLanguage {
+		t.Errorf("language = %q, want %q", msgs[0].Language, email.DefaultLanguage)
+	}
+	if !strings.Contains(msgs[0].TextBody, "Tracking number: JD014600003SE") {
+		t.Errorf("tracking number missing:\n%s", msgs[0].TextBody)
+	}
+}
+
+func TestPublish_LowStockAlertGoesToStoreEmail(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService(&fakeSender{})
+	ctx := context.Background()
+
+	p := testDB.FixtureProduct(t, "Leather Wallet", "leather-wallet")
+	if _, err := testDB.Pool.Exec(ctx, `UPDATE store_settings SET store_email = 'owner@example.com'`); err != nil {
+		t.Fatalf("setting store email: %v", err)
+	}
+	t.Cleanup(func() { testDB.Pool.Exec(ctx, `UPDATE store_settings SET store_email = NULL`) })
+
+	svc.Publish(ctx, webhook.EventVariantStockLow, webhook.VariantStockLowData{
+		ProductID: p.ID, SKU: "WAL-BRN", StockQuantity: 4, LowStockThreshold: 5,
+	})
+
+	msgs := listOutbox(t)
+	if len(msgs) != 1 {
+		t.Fatalf("outbox has %d messages, want 1", len(msgs))
+	}
+	m := msgs[0]
+	if m.Template != email.KindLowStockAlert || m.Recipient != "owner@example.com" {
+		t.Errorf("message = %+v", m)
+	}
+	if !strings.Contains(m.TextBody, "https://admin.example.com/admin/products/"+p.ID.String()) {
+		t.Errorf("product link missing:\n%s", m.TextBody)
+	}
+}
+
+func TestSendWelcome_UsesCustomerLanguageOnce(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService(&fakeSender{})
+	ctx := context.Background()
+
+	c := testDB.FixtureCustomer(t, "ana@example.com")
+	c.Metadata = json.RawMessage(`{"language":"es"}`)
+
+	for range 2 {
+		if err := svc.SendWelcome(ctx, c); err != nil {
+			t.Fatalf("SendWelcome: %v", err)
+		}
+	}
+
+	msgs := listOutbox(t)
+	if len(msgs) != 1 {
+		t.Fatalf("outbox has %d messages, want 1", len(msgs))
+	}
+	if msgs[0].Language != "es" || !strings.HasPrefix(msgs[0].Subject, "Bienvenido") {
+		t.Errorf("message = %+v", msgs[0])
+	}
+}
+
+func TestSendPasswordReset_BodyClearedAfterSending(t *testing.T) {
+	testDB.Truncate(t)
+	sender := &fakeSender{}
+	svc := newService(sender)
+	ctx := context.Background()
+
+	c := testDB.FixtureCustomer(t, "ana@example.com")
+	if err := svc.SendPasswordReset(ctx, c, "secret+token"); err != nil {
+		t.Fatalf("SendPasswordReset: %v", err)
+	}
+	if err := svc.ProcessOutbox(ctx); err != nil {
+		t.Fatalf("ProcessOutbox: %v", err)
+	}
+
+	if len(sender.sent) != 1 || !strings.Contains(sender.sent[0].Text, "/reset-password?token=secret%2Btoken") {
+		t.Fatalf("sent = %+v", sender.sent)
+	}
+	msgs := listOutbox(t)
+	if len(msgs) != 1 || msgs[0].Status != email.StatusSent || msgs[0].TextBody != "" {
+		t.Errorf("outbox = %+v, want a sent message without body", msgs)
+	}
+}
+
+// --------------------------------------------------------------------------
+// ProcessOutbox
+// --------------------------------------------------------------------------
+
+func TestProcessOutbox_SendsAndMarksSent(t *testing.T) {
+	testDB.Truncate(t)
+	sender := &fakeSender{}
+	svc := newService(sender)
+	ctx := context.Background()
+
+	createPaidOrder(t, svc, `{}`)
+
+	if err := svc.ProcessOutbox(ctx); err != nil {
+		t.Fatalf("ProcessOutbox: %v", err)
+	}
+	if len(sender.sent) != 1 || sender.sent[0].To != "buyer@example.com" || sender.sent[0].HTML == "" {
+		t.Fatalf("sent = %+v", sender.sent)
+	}
+	if m := listOutbox(t)[0]; m.Status != email.StatusSent || m.Attempts != 1 {
+		t.Errorf("after send: status %q attempts %d", m.Status, m.Attempts)
+	}
+
+	// Sent messages are not sent again.
+	if err := svc.ProcessOutbox(ctx); err != nil {
+		t.Fatalf("ProcessOutbox: %v", err)
+	}
+	if len(sender.sent) != 1 {
+		t.Errorf("message sent %d times", len(sender.sent))
+	}
+}
+
+func TestProcessOutbox_FailureIsRetriedLater(t *testing.T) {
+	testDB.Truncate(t)
+	sender := &fakeSender{err: errors.New("connection refused")}
+	svc := newService(sender)
+	ctx := context.Background()
+
+	// Queueing succeeds while the mail server is down.
+	createPaidOrder(t, svc, `{}`)
+
+	if err := svc.ProcessOutbox(ctx); err != nil {
+		t.Fatalf("ProcessOutbox: %v", err)
+	}
+	m := listOutbox(t)[0]
+	if m.Status != email.StatusPending || m.Attempts != 1 || m.LastError == nil || *m.LastError != "connection refused" {
+		t.Fatalf("after failure: %+v", m)
+	}
+
+	var next time.Time
+	if err := testDB.Pool.QueryRow(ctx, `SELECT next_attempt_at FROM email_outbox`).Scan(&next); err != nil {
+		t.Fatalf("reading next_attempt_at: %v", err)
+	}
+	if wait := time.Until(next); wait < 50*time.Second || wait > 70*time.Second {
+		t.Errorf("next attempt in %s, want about a minute", wait)
+	}
+
+	// Not due yet: a second pass does not retry.
+	sender.err = nil
+	if err := svc.ProcessOutbox(ctx); err != nil {
+		t.Fatalf("ProcessOutbox: %v", err)
+	}
+	if len(sender.sent) != 0 {
+		t.Fatal("message retried before it was due")
+	}
+
+	if _, err := testDB.Pool.Exec(ctx, `UPDATE email_outbox SET next_attempt_at = now()`); err != nil {
+		t.Fatalf("making message due: %v", err)
+	}
+	if err := svc.ProcessOutbox(ctx); err != nil {
+		t.Fatalf("ProcessOutbox: %v", err)
+	}
+	if len(sender.sent) != 1 {
+		t.Fatal("due message was not retried")
+	}
+}
+
+func TestProcessOutbox_GivesUpAfterMaxAttempts(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService(&fakeSender{err: errors.New("mailbox unavailable")})
+	ctx := context.Background()
+
+	createPaidOrder(t, svc, `{}`)
+	if _, err := testDB.Pool.Exec(ctx, `UPDATE email_outbox SET attempts = $1`, email.MaxAttempts-1); err != nil {
+		t.Fatalf("setting attempts: %v", err)
+	}
+
+	if err := svc.ProcessOutbox(ctx); err != nil {
+		t.Fatalf("ProcessOutbox: %v", err)
+	}
+	if m := listOutbox(t)[0]; m.Status != email.StatusFailed || m.Attempts != email.MaxAttempts {
+		t.Errorf("after last attempt: status %q attempts %d", m.Status, m.Attempts)
+	}
+}
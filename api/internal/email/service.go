@@ -0,0 +1,471 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/webhook"
+)
+
+// Outbox statuses.
+const (
+	StatusPending = "pending"
+	StatusSent    = "sent"
+	StatusFailed  = "failed"
+)
+
+const (
+	// MaxAttempts is the number of send attempts after which a message is
+	// marked failed. With RetryDelay this spans about six hours.
+	MaxAttempts = 10
+
+	retryBaseDelay = time.Minute
+	retryMaxDelay  = 2 * time.Hour
+
+	// leaseDuration must comfortably exceed the SMTP send timeout; a message
+	// whose lease expires is picked up again.
+	leaseDuration  = 2 * time.Minute
+	leaseBatchSize = 20
+	sendTimeout    = 30 * time.Second
+
+	// statusShipped is order.StatusShipped. The order package publishes to
+	// this one, so it cannot be imported here.
+	statusShipped = "shipped"
+)
+
+// Config holds the addresses used in messages.
+type Config struct {
+	// From is the sender address. It also receives admin alerts when the
+	// store has no email address configured.
+	From string
+	// ShopURL is the storefront base URL linked from customer messages.
+	ShopURL string
+	// AdminURL is the admin base URL linked from admin alerts.
+	AdminURL string
+}
+
+// Service queues transactional email in the outbox and sends it. It
+// implements webhook.Publisher so domain events trigger customer and admin
+// notifications.
+type Service struct {
+	queries *db.Queries
+	pool    *pgxpool.Pool
+	sender  Sender
+	cfg     Config
+	logger  *slog.Logger
+
+	// wake nudges the Worker when a message is queued.
+	wake chan struct{}
+}
+
+// NewService creates a new email service.
+func NewService(pool *pgxpool.Pool, sender Sender, cfg Config, logger *slog.Logger) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Service{
+		queries: db.New(pool),
+		pool:    pool,
+		sender:  sender,
+		cfg:     cfg,
+		logger:  logger,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Enqueue renders c in lang and queues it for to. A non-empty dedupeKey
+// makes the call idempotent: a message already queued under the same key is
+// not queued again.
+func (s *Service) Enqueue(ctx context.Context, to, lang string, c Content, dedupeKey string) error {
+	lang = NormalizeLanguage(lang)
+	r, err := Render(lang, c)
+	if err != nil {
+		return err
+	}
+
+	var key *string
+	if dedupeKey != "" {
+		key = &dedupeKey
+	}
+
+	_, err = s.queries.CreateOutboxEmail(ctx, db.CreateOutboxEmailParams{
+		Template:  c.Kind(),
+		Language:  lang,
+		Recipient: to,
+		Subject:   r.Subject,
+		HtmlBody:  r.HTML,
+		TextBody:  r.Text,
+		DedupeKey: key,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("queue %s email: %w", c.Kind(), err)
+	}
+
+	s.notify()
+	return nil
+}
+
+// notify wakes the worker without blocking.
+func (s *Service) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// SendWelcome queues the registration welcome message for a customer.
+func (s *Service) SendWelcome(ctx context.Context, customer db.Customer) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Enqueue(ctx, customer.Email, LanguageFromMetadata(customer.Metadata), Welcome{
+		Store:        store,
+		CustomerName: derefString(customer.FirstName),
+	}, "welcome:"+customer.ID.String())
+}
+
+// SendPasswordReset queues a password reset link for a customer. Token
+// emails have no dedupe key: each request issues a fresh token.
+func (s *Service) SendPasswordReset(ctx context.Context, customer db.Customer, token string) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Enqueue(ctx, customer.Email, LanguageFromMetadata(customer.Metadata), PasswordReset{
+		Store:        store,
+		CustomerName: derefString(customer.FirstName),
+		URL:          s.shopLink("/reset-password", token),
+	}, "")
+}
+
+// SendEmailVerification queues an email address confirmation link for a
+// customer.
+func (s *Service) SendEmailVerification(ctx context.Context, customer db.Customer, token string) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	return s.Enqueue(ctx, customer.Email, LanguageFromMetadata(customer.Metadata), EmailVerification{
+		Store:        store,
+		CustomerName: derefString(customer.FirstName),
+		URL:          s.shopLink("/verify-email", token),
+	}, "")
+}
+
+// SendCartRecovery queues the abandoned cart reminder for a cart. c carries
+// the cart contents and coupon; the store and the restore link built from
+// token are filled in here. At most one reminder is queued per cart.
+func (s *Service) SendCartRecovery(ctx context.Context, cartID uuid.UUID, to, lang string, c CartRecovery, token string) error {
+	store, err := s.store(ctx)
+	if err != nil {
+		return err
+	}
+	c.Store = store
+	c.URL = s.shopLink("/restore-cart", token)
+	return s.Enqueue(ctx, to, lang, c, "cart_recovery:"+cartID.String())
+}
+
+// shopLink returns a storefront URL carrying token as a query parameter.
+func (s *Service) shopLink(path, token string) string {
+	return strings.TrimRight(s.cfg.ShopURL, "/") + path + "?token=" + url.QueryEscape(token)
+}
+
+// Publish queues the notifications for a domain event. Events without an
+// email are ignored. Failures are logged; they never fail the caller.
+func (s *Service) Publish(ctx context.Context, eventType string, data any) {
+	// Events are published after the caller's transaction commits; queueing
+	// must not be abandoned when the request context ends.
+	ctx = context.WithoutCancel(ctx)
+
+	var err error
+	switch d := data.(type) {
+	case webhook.OrderData:
+		if eventType == webhook.EventOrderPaid {
+			err = s.queueOrderConfirmation(ctx, d.Order.ID)
+		}
+	case webhook.OrderStatusChangedData:
+		if d.ToStatus == statusShipped {
+			err = s.queueShippingNotification(ctx, d.Order.ID)
+		}
+	case webhook.OrderRefundedData:
+		err = s.queueRefundNotice(ctx, d.Order.ID, d.Refund)
+	case webhook.VariantStockLowData:
+		err = s.queueLowStockAlert(ctx, d)
+	}
+	if err != nil {
+		s.logger.Error("queue email for event", "error", err, "event", eventType)
+	}
+}
+
+func (s *Service) queueOrderConfirmation(ctx context.Context, orderID uuid.UUID) error {
+	r, err := s.recipient(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	return s.Enqueue(ctx, r.email, r.lang, OrderConfirmation{
+		Store:        r.store,
+		CustomerName: r.name,
+		Order:        r.order,
+	}, "order_confirmation:"+orderID.String())
+}
+
+func (s *Service) queueShippingNotification(ctx context.Context, orderID uuid.UUID) error {
+	r, err := s.recipient(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	return s.Enqueue(ctx, r.email, r.lang, ShippingNotification{
+		Store:        r.store,
+		CustomerName: r.name,
+		Order:        r.order,
+	}, "shipping_notification:"+orderID.String())
+}
+
+func (s *Service) queueRefundNotice(ctx context.Context, orderID uuid.UUID, refund webhook.Refund) error {
+	r, err := s.recipient(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	return s.Enqueue(ctx, r.email, r.lang, RefundNotice{
+		Store:            r.store,
+		CustomerName:     r.name,
+		Order:            r.order,
+		Amount:           refund.Amount,
+		CreditNoteNumber: refund.CreditNoteNumber,
+		Reason:           derefString(refund.Reason),
+	}, "refund_notice:"+refund.ID.String())
+}
+
+// queueLowStockAlert notifies the store email address, or the sender address
+// if none is set. There is one alert per threshold crossing, so no dedupe key.
+func (s *Service) queueLowStockAlert(ctx context.Context, d webhook.VariantStockLowData) error {
+	settings, err := s.queries.GetStoreSettings(ctx)
+	if err != nil {
+		return fmt.Errorf("get store settings: %w", err)
+	}
+	to := s.cfg.From
+	if settings.StoreEmail != nil && *settings.StoreEmail != "" {
+		to = *settings.StoreEmail
+	}
+
+	product, err := s.queries.GetProduct(ctx, d.ProductID)
+	if err != nil {
+		return fmt.Errorf("get product for low stock alert: %w", err)
+	}
+
+	alert := LowStockAlert{
+		Store:             s.storeFromSettings(settings),
+		ProductName:       product.Name,
+		SKU:               d.SKU,
+		StockQuantity:     d.StockQuantity,
+		LowStockThreshold: d.LowStockThreshold,
+	}
+	if s.cfg.AdminURL != "" {
+		alert.ProductURL = strings.TrimRight(s.cfg.AdminURL, "/") + "/admin/products/" + d.ProductID.String()
+	}
+	return s.Enqueue(ctx, to, DefaultLanguage, alert, "")
+}
+
+// orderRecipient is what a customer message about an order needs.
+type orderRecipient struct {
+	email string
+	name  string
+	lang  string
+	order Order
+	store Store
+}
+
+// recipient loads an order with its items and works out who to address and
+// in which language: the language stored on the order at checkout, then the
+// customer's, then DefaultLanguage.
+func (s *Service) recipient(ctx context.Context, orderID uuid.UUID) (orderRecipient, error) {
+	o, err := s.queries.GetOrder(ctx, orderID)
+	if err != nil {
+		return orderRecipient{}, fmt.Errorf("get order %s: %w", orderID, err)
+	}
+	items, err := s.queries.ListOrderItems(ctx, orderID)
+	if err != nil {
+		return orderRecipient{}, fmt.Errorf("list order items: %w", err)
+	}
+	store, err := s.store(ctx)
+	if err != nil {
+		return orderRecipient{}, err
+	}
+
+	r := orderRecipient{
+		email: o.Email,
+		lang:  LanguageFromMetadata(o.Metadata),
+		order: NewOrder(webhook.NewOrder(o, items)),
+		store: store,
+	}
+	if o.CustomerID.Valid {
+		customer, err := s.queries.GetCustomer(ctx, o.CustomerID.Bytes)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return orderRecipient{}, fmt.Errorf("get customer: %w", err)
+		}
+		if err == nil {
+			r.name = derefString(customer.FirstName)
+			if r.lang == "" {
+				r.lang = LanguageFromMetadata(customer.Metadata)
+			}
+		}
+	}
+	return r, nil
+}
+
+func (s *Service) store(ctx context.Context) (Store, error) {
+	settings, err := s.queries.GetStoreSettings(ctx)
+	if err != nil {
+		return Store{}, fmt.Errorf("get store settings: %w", err)
+	}
+	return s.storeFromSettings(settings), nil
+}
+
+func (s *Service) storeFromSettings(settings db.StoreSetting) Store {
+	return Store{
+		Name:     settings.StoreName,
+		URL:      s.cfg.ShopURL,
+		Currency: settings.DefaultCurrency,
+	}
+}
+
+// RetryDelay returns the wait before the next attempt after the given number
+// of failed attempts: one minute doubling per attempt, capped at two hours.
+func RetryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := retryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= retryMaxDelay {
+			return retryMaxDelay
+		}
+	}
+	return delay
+}
+
+// ProcessOutbox leases due messages in batches and sends them until none are
+// left or ctx is cancelled. It is run by the Worker, and is safe to call from
+// several processes at once.
+func (s *Service) ProcessOutbox(ctx context.Context) error {
+	// A message in flight is finished even if ctx is cancelled, so a
+	// shutdown does not count an interrupted send as a failed attempt.
+	sendCtx := context.WithoutCancel(ctx)
+
+	sent, failed := 0, 0
+	for {
+		msgs, err := s.queries.LeaseOutboxEmails(ctx, db.LeaseOutboxEmailsParams{
+			Limit:       leaseBatchSize,
+			LockedUntil: pgtype.Timestamptz{Time: time.Now().Add(leaseDuration), Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("lease outbox emails: %w", err)
+		}
+
+		for _, m := range msgs {
+			if s.send(sendCtx, m) {
+				sent++
+			} else {
+				failed++
+			}
+		}
+
+		if len(msgs) < leaseBatchSize || ctx.Err() != nil {
+			break
+		}
+	}
+
+	if sent+failed > 0 {
+		s.logger.Info("processed email outbox", "sent", sent, "failed", failed)
+	}
+	return nil
+}
+
+// send delivers one leased message and records the outcome. It reports
+// whether the message was sent.
+func (s *Service) send(ctx context.Context, m db.EmailOutbox) bool {
+	sendCtx, cancel := context.WithTimeout(ctx, sendTimeout)
+	err := s.sender.Send(sendCtx, Message{
+		To:      m.Recipient,
+		Subject: m.Subject,
+		HTML:    m.HtmlBody,
+		Text:    m.TextBody,
+	})
+	cancel()
+
+	if err == nil {
+		if err := s.queries.MarkOutboxEmailSent(ctx, m.ID); err != nil {
+			s.logger.Error("mark email sent", "error", err, "email_id", m.ID)
+		}
+		s.redact(ctx, m)
+		return true
+	}
+
+	errMsg := err.Error()
+	attempts := int(m.Attempts) + 1
+	params := db.MarkOutboxEmailFailedParams{
+		ID:        m.ID,
+		Status:    StatusPending,
+		LastError: &errMsg,
+		NextAttemptAt: pgtype.Timestamptz{
+			Time:  time.Now().Add(RetryDelay(attempts)),
+			Valid: true,
+		},
+	}
+	if attempts >= MaxAttempts {
+		params.Status = StatusFailed
+		params.NextAttemptAt = pgtype.Timestamptz{}
+		s.logger.Warn("giving up on email",
+			"email_id", m.ID,
+			"template", m.Template,
+			"recipient", m.Recipient,
+			"error", errMsg,
+		)
+	}
+	if err := s.queries.MarkOutboxEmailFailed(ctx, params); err != nil {
+		s.logger.Error("mark email failed", "error", err, "email_id", m.ID)
+	}
+	if params.Status == StatusFailed {
+		s.redact(ctx, m)
+	}
+	return false
+}
+
+// sensitiveKinds carry a secret link in the body; the body is dropped from
+// the outbox once the message will not be sent again.
+var sensitiveKinds = map[string]bool{
+	KindPasswordReset:     true,
+	KindEmailVerification: true,
+}
+
+func (s *Service) redact(ctx context.Context, m db.EmailOutbox) {
+	if !sensitiveKinds[m.Template] {
+		return
+	}
+	if err := s.queries.ClearOutboxEmailBody(ctx, m.ID); err != nil {
+		s.logger.Error("clear email body", "error", err, "email_id", m.ID)
+	}
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,302 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/forgecommerce/api/internal/services/webhook"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// Template kinds. Each has a <kind>.html.tmpl and a <kind>.txt.tmpl file
+// defining a "content" template that is rendered inside the layout.
+const (
+	KindOrderConfirmation    = "order_confirmation"
+	KindShippingNotification = "shipping_notification"
+	KindRefundNotice         = "refund_notice"
+	KindWelcome              = "welcome"
+	KindLowStockAlert        = "low_stock_alert"
+	KindPasswordReset        = "password_reset"
+	KindEmailVerification    = "email_verification"
+	KindCartRecovery         = "cart_recovery"
+)
+
+var kinds = []string{
+	KindOrderConfirmation,
+	KindShippingNotification,
+	KindRefundNotice,
+	KindWelcome,
+	KindLowStockAlert,
+	KindPasswordReset,
+	KindEmailVerification,
+	KindCartRecovery,
+}
+
+// Content is the data for one kind of message.
+type Content interface {
+	// Kind returns the template kind used to render the message.
+	Kind() string
+	subject(lang string) string
+	store() Store
+}
+
+// Store identifies the sending shop in every message. Currency is the ISO
+// 4217 code amounts are shown in.
+type Store struct {
+	Name     string
+	URL      string
+	Currency string
+}
+
+func (s Store) store() Store { return s }
+
+// OrderConfirmation is sent to the customer when an order is paid.
+type OrderConfirmation struct {
+	Store
+	CustomerName string
+	Order        Order
+}
+
+// ShippingNotification is sent to the customer when an order ships.
+type ShippingNotification struct {
+	Store
+	CustomerName string
+	Order        Order
+}
+
+// RefundNotice is sent to the customer when part or all of an order is
+// refunded.
+type RefundNotice struct {
+	Store
+	CustomerName     string
+	Order            Order
+	Amount           string
+	CreditNoteNumber int64
+	Reason           string
+}
+
+// Welcome is sent to a customer after registration.
+type Welcome struct {
+	Store
+	CustomerName string
+}
+
+// PasswordReset carries a single-use link to set a new password.
+type PasswordReset struct {
+	Store
+	CustomerName string
+	URL          string
+}
+
+// EmailVerification carries a link that confirms the customer owns their
+// email address.
+type EmailVerification struct {
+	Store
+	CustomerName string
+	URL          string
+}
+
+// CartRecovery reminds a shopper of a cart they left, with a link that
+// restores it and, optionally, a one-time coupon code.
+type CartRecovery struct {
+	Store
+	CustomerName string
+	Items        []OrderLine
+	Subtotal     string
+	URL          string
+	CouponCode   string
+}
+
+// LowStockAlert is sent to the store address when a variant falls to its
+// low-stock threshold.
+type LowStockAlert struct {
+	Store
+	ProductName       string
+	SKU               string
+	StockQuantity     int32
+	LowStockThreshold int32
+	ProductURL        string
+}
+
+func (OrderConfirmation) Kind() string    { return KindOrderConfirmation }
+func (ShippingNotification) Kind() string { return KindShippingNotification }
+func (RefundNotice) Kind() string         { return KindRefundNotice }
+func (Welcome) Kind() string              { return KindWelcome }
+func (LowStockAlert) Kind() string        { return KindLowStockAlert }
+func (PasswordReset) Kind() string        { return KindPasswordReset }
+func (EmailVerification) Kind() string    { return KindEmailVerification }
+func (CartRecovery) Kind() string         { return KindCartRecovery }
+
+func (c OrderConfirmation) subject(lang string) string {
+	return translate(lang, "order_confirmation.subject", c.Store.Name, c.Order.Number)
+}
+
+func (c ShippingNotification) subject(lang string) string {
+	return translate(lang, "shipping.subject", c.Store.Name, c.Order.Number)
+}
+
+func (c RefundNotice) subject(lang string) string {
+	return translate(lang, "refund.subject", c.Store.Name, c.Order.Number)
+}
+
+func (c Welcome) subject(lang string) string {
+	return translate(lang, "welcome.subject", c.Store.Name)
+}
+
+func (c LowStockAlert) subject(lang string) string {
+	return translate(lang, "low_stock.subject", c.Store.Name, c.SKU)
+}
+
+func (c PasswordReset) subject(lang string) string {
+	return translate(lang, "password_reset.subject", c.Store.Name)
+}
+
+func (c EmailVerification) subject(lang string) string {
+	return translate(lang, "email_verification.subject", c.Store.Name)
+}
+
+func (c CartRecovery) subject(lang string) string {
+	return translate(lang, "cart_recovery.subject", c.Store.Name)
+}
+
+// Order is the order summary shown in customer messages.
+type Order struct {
+	Number         int64
+	Items          []OrderLine
+	Subtotal       string
+	ShippingFee    string
+	DiscountAmount string
+	VATTotal       string
+	Total          string
+	ShippingMethod string
+	TrackingNumber string
+}
+
+// OrderLine is one line of an order summary.
+type OrderLine struct {
+	Name     string
+	Quantity int32
+	Total    string
+}
+
+// HasDiscount reports whether the order has a non-zero discount.
+func (o Order) HasDiscount() bool {
+	d, err := decimal.NewFromString(o.DiscountAmount)
+	return err == nil && !d.IsZero()
+}
+
+// NewOrder converts an event payload order to its email summary.
+func NewOrder(o webhook.Order) Order {
+	out := Order{
+		Number:         o.OrderNumber,
+		Items:          make([]OrderLine, 0, len(o.Items)),
+		Subtotal:       o.Subtotal,
+		ShippingFee:    o.ShippingFee,
+		DiscountAmount: o.DiscountAmount,
+		VATTotal:       o.VATTotal,
+		Total:          o.Total,
+	}
+	if o.ShippingMethod != nil {
+		out.ShippingMethod = *o.ShippingMethod
+	}
+	if o.TrackingNumber != nil {
+		out.TrackingNumber = *o.TrackingNumber
+	}
+	for _, it := range o.Items {
+		name := it.ProductName
+		if it.VariantName != nil && *it.VariantName != "" {
+			name += " – " + *it.VariantName
+		}
+		out.Items = append(out.Items, OrderLine{
+			Name:     name,
+			Quantity: it.Quantity,
+			Total:    it.TotalPrice,
+		})
+	}
+	return out
+}
+
+// Rendered is a message rendered in one language.
+type Rendered struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// placeholderFuncs declares the per-render functions so the templates parse;
+// Render replaces them with functions bound to the message language.
+var placeholderFuncs = map[string]any{
+	"t":       func(string, ...any) string { return "" },
+	"money":   func(string) string { return "" },
+	"lang":    func() string { return "" },
+	"subject": func() string { return "" },
+}
+
+var (
+	htmlTemplates = map[string]*htmltemplate.Template{}
+	textTemplates = map[string]*texttemplate.Template{}
+)
+
+func init() {
+	for _, kind := range kinds {
+		htmlTemplates[kind] = htmltemplate.Must(htmltemplate.New("layout.html.tmpl").
+			Funcs(placeholderFuncs).
+			ParseFS(templateFS, "templates/layout.html.tmpl", "templates/partials.html.tmpl", "templates/"+kind+".html.tmpl"))
+		textTemplates[kind] = texttemplate.Must(texttemplate.New("layout.txt.tmpl").
+			Funcs(placeholderFuncs).
+			ParseFS(templateFS, "templates/layout.txt.tmpl", "templates/partials.txt.tmpl", "templates/"+kind+".txt.tmpl"))
+	}
+}
+
+// Render renders c in lang. Unsupported languages fall back to
+// DefaultLanguage.
+func Render(lang string, c Content) (Rendered, error) {
+	lang = NormalizeLanguage(lang)
+	kind := c.Kind()
+
+	htmlTmpl, ok := htmlTemplates[kind]
+	if !ok {
+		return Rendered{}, fmt.Errorf("unknown email template %q", kind)
+	}
+
+	subject := c.subject(lang)
+	currency := c.store().Currency
+	funcs := map[string]any{
+		"t":       func(key string, args ...any) string { return translate(lang, key, args...) },
+		"money":   func(amount string) string { return formatMoney(lang, currency, amount) },
+		"lang":    func() string { return lang },
+		"subject": func() string { return subject },
+	}
+
+	h, err := htmlTmpl.Clone()
+	if err != nil {
+		return Rendered{}, fmt.Errorf("clone %s html template: %w", kind, err)
+	}
+	var html bytes.Buffer
+	if err := h.Funcs(funcs).Execute(&html, c); err != nil {
+		return Rendered{}, fmt.Errorf("render %s html: %w", kind, err)
+	}
+
+	t, err := textTemplates[kind].Clone()
+	if err != nil {
+		return Rendered{}, fmt.Errorf("clone %s text template: %w", kind, err)
+	}
+	var text bytes.Buffer
+	if err := t.Funcs(funcs).Execute(&text, c); err != nil {
+		return Rendered{}, fmt.Errorf("render %s text: %w", kind, err)
+	}
+
+	return Rendered{
+		Subject: subject,
+		HTML:    html.String(),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+	}, nil
+}
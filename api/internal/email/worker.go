@@ -0,0 +1,84 @@
+package email
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// workerPollInterval is how often the Worker looks for due retries when it
+// has not been woken by a newly queued message.
+const workerPollInterval = 15 * time.Second
+
+// Worker sends queued email in the background. It drains the outbox whenever
+// a message is queued and polls for due retries in between. Several workers,
+// in one or many processes, can run against the same database; leasing keeps
+// each message to one sender.
+type Worker struct {
+	svc    *Service
+	logger *slog.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewWorker creates an outbox worker for the given email service.
+func NewWorker(svc *Service, logger *slog.Logger) *Worker {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Worker{
+		svc:    svc,
+		logger: logger,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// Start runs the worker loop in a goroutine. Messages left pending by a
+// previous process are picked up on the first pass.
+func (w *Worker) Start() {
+	w.logger.Info("starting email outbox worker")
+	w.wg.Add(1)
+	go w.loop()
+}
+
+// Stop signals the worker to stop and waits for the batch in flight to
+// finish. It is safe to call Stop multiple times.
+func (w *Worker) Stop() {
+	if w.ctx.Err() == nil {
+		w.logger.Info("stopping email outbox worker")
+	}
+	w.cancel()
+	w.wg.Wait()
+}
+
+func (w *Worker) loop() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(workerPollInterval)
+	defer ticker.Stop()
+
+	for {
+		w.process()
+
+		select {
+		case <-ticker.C:
+		case <-w.svc.wake:
+		case <-w.ctx.Done():
+			w.logger.Info("email outbox worker stopped")
+			return
+		}
+	}
+}
+
+// process drains the due messages. Stopping the worker ends the drain after
+// the batch in flight.
+func (w *Worker) process() {
+	if err := w.svc.ProcessOutbox(w.ctx); err != nil && w.ctx.Err() == nil {
+		w.logger.Error("processing email outbox", "error", err)
+	}
+}
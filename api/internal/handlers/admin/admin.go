@@ -2,27 +2,31 @@ package admin
 
 import (
 	"encoding/base64"
+	"encoding/json"
 	"errors"
 	"log/slog"
 	"net/http"
 
 	"github.com/forgecommerce/api/internal/auth"
 	"github.com/forgecommerce/api/internal/middleware"
+	"github.com/forgecommerce/api/internal/services/audit"
 	"github.com/forgecommerce/api/templates/admin"
 	"github.com/google/uuid"
 )
 
 // Handler holds dependencies for admin HTTP handlers.
 type Handler struct {
-	auth   *auth.Service
-	logger *slog.Logger
+	auth     *auth.Service
+	webauthn *auth.WebAuthnService
+	logger   *slog.Logger
 }
 
 // NewHandler creates a new admin handler.
-func NewHandler(authService *auth.Service, logger *slog.Logger) *Handler {
+func NewHandler(authService *auth.Service, webauthnService *auth.WebAuthnService, logger *slog.Logger) *Handler {
 	return &Handler{
-		auth:   authService,
-		logger: logger,
+		auth:     authService,
+		webauthn: webauthnService,
+		logger:   logger,
 	}
 }
 
@@ -33,6 +37,8 @@ func (h *Handler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /admin/login", h.HandleLogin)
 	mux.HandleFunc("GET /admin/login/2fa", h.ShowTwoFactor)
 	mux.HandleFunc("POST /admin/login/2fa", h.HandleTwoFactor)
+	mux.HandleFunc("POST /admin/login/2fa/webauthn/options", h.WebAuthnLoginOptions)
+	mux.HandleFunc("POST /admin/login/2fa/webauthn", h.HandleWebAuthnLogin)
 
 	// Semi-public routes (require pending 2FA cookie, not full session)
 	mux.HandleFunc("GET /admin/setup-2fa", h.ShowSetup2FA)
@@ -46,6 +52,28 @@ func (h *Handler) RegisterProtectedRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /admin/logout", h.HandleLogout)
 }
 
+// requires wraps a route handler so it only runs for admin users with perm.
+func requires(perm string, h http.HandlerFunc) http.Handler {
+	return middleware.RequirePermission(perm)(h)
+}
+
+// recordAudit records an action by the signed-in admin user in the audit
+// log. changes is usually an audit.Diff of the entity before and after.
+func recordAudit(svc *audit.Service, r *http.Request, action, entityType, entityID string, changes map[string]any) {
+	if svc == nil {
+		return
+	}
+	userID, _ := middleware.AdminUserIDFromContext(r.Context())
+	svc.Record(r.Context(), audit.Entry{
+		AdminUserID: userID,
+		Action:      action,
+		EntityType:  entityType,
+		EntityID:    entityID,
+		Changes:     changes,
+		IPAddress:   middleware.ClientIP(r),
+	})
+}
+
 // ShowLogin renders the admin login page.
 func (h *Handler) ShowLogin(w http.ResponseWriter, r *http.Request) {
 	csrfToken := middleware.CSRFToken(r)
@@ -82,8 +110,15 @@ func (h *Handler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	hasKeys, err := h.webauthn.HasCredentials(r.Context(), user.ID)
+	if err != nil {
+		h.logger.Error("failed to check security keys", "error", err)
+		admin.LoginPage("Internal error. Please try again.", csrfToken).Render(r.Context(), w)
+		return
+	}
+
 	// If user has 2FA set up, redirect to 2FA page
-	if user.TOTPVerified {
+	if user.TOTPVerified || hasKeys {
 		token, err := h.createPending2FASession(w, r, user.ID)
 		if err != nil {
 			h.logger.Error("failed to create pending session", "error", err)
@@ -96,7 +131,7 @@ func (h *Handler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// No 2FA — create session directly (user has not set up 2FA yet)
-	ip := r.RemoteAddr
+	ip := middleware.ClientIP(r)
 	ua := r.UserAgent()
 	sessionToken, err := h.auth.CreateSessionDirect(r.Context(), user.ID, ip, ua)
 	if err != nil {
@@ -105,23 +140,41 @@ func (h *Handler) HandleLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	middleware.SetSessionCookie(w, sessionToken)
+	middleware.SetSessionCookie(w, sessionToken, h.auth.SessionTTL())
 	http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
 }
 
-// ShowTwoFactor renders the 2FA verification page.
+// ShowTwoFactor renders the 2FA verification page. The method query
+// parameter picks between the user's second factors.
 func (h *Handler) ShowTwoFactor(w http.ResponseWriter, r *http.Request) {
-	csrfToken := middleware.CSRFToken(r)
-	showRecovery := r.URL.Query().Get("recovery") == "true"
-	admin.TwoFactorPage("", csrfToken, showRecovery).Render(r.Context(), w)
+	userID, err := h.getPending2FAUserID(r)
+	if err != nil {
+		http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+		return
+	}
+
+	method := r.URL.Query().Get("method")
+	if r.URL.Query().Get("recovery") == "true" {
+		method = admin.TwoFactorRecovery
+	}
+
+	data, err := h.twoFactorData(r, userID, method)
+	if err != nil {
+		h.logger.Error("failed to load 2FA methods", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	admin.TwoFactorPage(data).Render(r.Context(), w)
 }
 
-// HandleTwoFactor processes the 2FA form submission.
+// HandleTwoFactor processes the 2FA form submission for TOTP and recovery
+// codes. Security keys use HandleWebAuthnLogin.
 func (h *Handler) HandleTwoFactor(w http.ResponseWriter, r *http.Request) {
-	csrfToken := middleware.CSRFToken(r)
 	code := r.FormValue("code")
-	codeType := r.FormValue("type")
-	showRecovery := codeType == "recovery"
+	method := admin.TwoFactorTOTP
+	if r.FormValue("type") == "recovery" {
+		method = admin.TwoFactorRecovery
+	}
 
 	userID, err := h.getPending2FAUserID(r)
 	if err != nil {
@@ -129,32 +182,96 @@ func (h *Handler) HandleTwoFactor(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	ip := r.RemoteAddr
+	ip := middleware.ClientIP(r)
 	ua := r.UserAgent()
 
 	var sessionToken string
-	if showRecovery {
+	if method == admin.TwoFactorRecovery {
 		sessionToken, err = h.auth.UseRecoveryCode(r.Context(), userID, code, ip, ua)
 	} else {
 		sessionToken, err = h.auth.CompleteTwoFactor(r.Context(), userID, code, ip, ua)
 	}
 
 	if err != nil {
-		msg := "Invalid code. Please try again."
+		data, dataErr := h.twoFactorData(r, userID, method)
+		if dataErr != nil {
+			h.logger.Error("failed to load 2FA methods", "error", dataErr)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		data.Error = "Invalid code. Please try again."
 		if errors.Is(err, auth.ErrInvalidRecoveryCode) {
-			msg = "Invalid recovery code."
+			data.Error = "Invalid recovery code."
 		}
 		w.WriteHeader(http.StatusUnauthorized)
-		admin.TwoFactorPage(msg, csrfToken, showRecovery).Render(r.Context(), w)
+		admin.TwoFactorPage(data).Render(r.Context(), w)
 		return
 	}
 
 	// Clear pending 2FA cookie, set session cookie
 	clearPending2FACookie(w)
-	middleware.SetSessionCookie(w, sessionToken)
+	middleware.SetSessionCookie(w, sessionToken, h.auth.SessionTTL())
 	http.Redirect(w, r, "/admin/dashboard", http.StatusSeeOther)
 }
 
+// WebAuthnLoginOptions handles POST /admin/login/2fa/webauthn/options. It
+// returns the options for navigator.credentials.get as JSON.
+func (h *Handler) WebAuthnLoginOptions(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getPending2FAUserID(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Your login has expired. Please sign in again."})
+		return
+	}
+
+	opts, err := h.webauthn.BeginLogin(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, auth.ErrWebAuthnNotSetup) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "No security keys are registered."})
+			return
+		}
+		h.logger.Error("failed to begin security key login", "error", err)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Internal error. Please try again."})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, opts)
+}
+
+// HandleWebAuthnLogin handles POST /admin/login/2fa/webauthn. It verifies
+// the security key's response, signs the user in and returns the page to
+// go to as JSON.
+func (h *Handler) HandleWebAuthnLogin(w http.ResponseWriter, r *http.Request) {
+	userID, err := h.getPending2FAUserID(r)
+	if err != nil {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Your login has expired. Please sign in again."})
+		return
+	}
+
+	var resp auth.AssertionResponse
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&resp); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body."})
+		return
+	}
+
+	sessionToken, err := h.webauthn.FinishLogin(r.Context(), userID, &resp, middleware.ClientIP(r), r.UserAgent())
+	if err != nil {
+		switch {
+		case errors.Is(err, auth.ErrWebAuthnNoChallenge):
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": err.Error()})
+		case errors.Is(err, auth.ErrWebAuthnVerification), errors.Is(err, auth.ErrWebAuthnCredentialNotFound):
+			writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Security key not recognised. Please try again."})
+		default:
+			h.logger.Error("failed to finish security key login", "error", err)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Internal error. Please try again."})
+		}
+		return
+	}
+
+	clearPending2FACookie(w)
+	middleware.SetSessionCookie(w, sessionToken, h.auth.SessionTTL())
+	writeJSON(w, http.StatusOK, map[string]string{"redirect": "/admin/dashboard"})
+}
+
 // ShowSetup2FA renders the 2FA setup page.
 func (h *Handler) ShowSetup2FA(w http.ResponseWriter, r *http.Request) {
 	csrfToken := middleware.CSRFToken(r)
@@ -268,6 +385,37 @@ func clearPending2FACookie(w http.ResponseWriter) {
 	})
 }
 
+// twoFactorData builds the 2FA page for the user's available methods,
+// falling back to the preferred one if method is not available.
+func (h *Handler) twoFactorData(r *http.Request, userID uuid.UUID, method string) (admin.TwoFactorData, error) {
+	user, err := h.auth.GetUserByID(r.Context(), userID)
+	if err != nil {
+		return admin.TwoFactorData{}, err
+	}
+	hasKeys, err := h.webauthn.HasCredentials(r.Context(), userID)
+	if err != nil {
+		return admin.TwoFactorData{}, err
+	}
+
+	data := admin.TwoFactorData{
+		CSRFToken:      middleware.CSRFToken(r),
+		HasTOTP:        user.TOTPVerified,
+		HasRecovery:    len(user.RecoveryCodes) > 0,
+		HasSecurityKey: hasKeys,
+	}
+	switch {
+	case method == admin.TwoFactorTOTP && data.HasTOTP,
+		method == admin.TwoFactorRecovery && data.HasRecovery,
+		method == admin.TwoFactorSecurityKey && data.HasSecurityKey:
+		data.Method = method
+	case data.HasSecurityKey:
+		data.Method = admin.TwoFactorSecurityKey
+	default:
+		data.Method = admin.TwoFactorTOTP
+	}
+	return data, nil
+}
+
 func (h *Handler) getPending2FAUserID(r *http.Request) (uuid.UUID, error) {
 	cookie, err := r.Cookie(pending2FACookieName)
 	if err != nil || cookie.Value == "" {
@@ -6,6 +6,7 @@ import (
 	"net/http"
 
 	"github.com/forgecommerce/api/internal/ai"
+	"github.com/forgecommerce/api/internal/auth"
 )
 
 // AIHandler serves AI content generation endpoints for the admin panel.
@@ -24,8 +25,8 @@ func NewAIHandler(aiSvc *ai.Service, logger *slog.Logger) *AIHandler {
 
 // RegisterRoutes registers AI admin routes on the given mux.
 func (h *AIHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("POST /admin/ai/generate", h.Generate)
-	mux.HandleFunc("GET /admin/ai/providers", h.ListProviders)
+	mux.Handle("POST /admin/ai/generate", requires(auth.PermProductsWrite, h.Generate))
+	mux.Handle("GET /admin/ai/providers", requires(auth.PermProductsWrite, h.ListProviders))
 }
 
 type generateRequest struct {
@@ -0,0 +1,917 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+
+	"github.com/forgecommerce/api/internal/auth"
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/middleware"
+	"github.com/forgecommerce/api/internal/services/audit"
+	"github.com/forgecommerce/api/internal/services/inventory"
+	"github.com/forgecommerce/api/internal/services/order"
+	"github.com/forgecommerce/api/internal/services/product"
+	"github.com/forgecommerce/api/internal/services/rawmaterial"
+	"github.com/forgecommerce/api/internal/services/variant"
+)
+
+const (
+	apiDefaultPageSize = 20
+	apiMaxPageSize     = 250
+
+	// apiMaxBodyBytes caps JSON request bodies.
+	apiMaxBodyBytes = 64 << 10
+)
+
+// APIHandler serves the JSON admin API under /admin/api/v1 for scripts
+// authenticated with an API key. It reuses the services behind the admin
+// pages; every route requires a scope on the key.
+type APIHandler struct {
+	products  *product.Service
+	variants  *variant.Service
+	orders    *order.Service
+	materials *rawmaterial.Service
+	inventory *inventory.Service
+	audit     *audit.Service
+	logger    *slog.Logger
+}
+
+// NewAPIHandler creates a new admin API handler.
+func NewAPIHandler(
+	products *product.Service,
+	variants *variant.Service,
+	orders *order.Service,
+	materials *rawmaterial.Service,
+	inv *inventory.Service,
+	auditSvc *audit.Service,
+	logger *slog.Logger,
+) *APIHandler {
+	return &APIHandler{
+		products:  products,
+		variants:  variants,
+		orders:    orders,
+		materials: materials,
+		inventory: inv,
+		audit:     auditSvc,
+		logger:    logger,
+	}
+}
+
+// RegisterRoutes registers admin API routes on the given mux. The mux must
+// be wrapped in middleware.RequireAPIKey.
+func (h *APIHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.Handle("GET /admin/api/v1/products", scoped(auth.PermProductsRead, h.ListProducts))
+	mux.Handle("GET /admin/api/v1/products/{id}", scoped(auth.PermProductsRead, h.GetProduct))
+	mux.Handle("GET /admin/api/v1/variants/{id}", scoped(auth.PermProductsRead, h.GetVariant))
+	mux.Handle("GET /admin/api/v1/variants/sku/{sku}", scoped(auth.PermProductsRead, h.GetVariantBySKU))
+	mux.Handle("PATCH /admin/api/v1/variants/{id}", scoped(auth.PermProductsWrite, h.UpdateVariant))
+	mux.Handle("POST /admin/api/v1/variants/{id}/stock", scoped(auth.PermInventoryWrite, h.ChangeVariantStock))
+	mux.Handle("GET /admin/api/v1/orders", scoped(auth.PermOrdersRead, h.ListOrders))
+	mux.Handle("GET /admin/api/v1/orders/{id}", scoped(auth.PermOrdersRead, h.GetOrder))
+	mux.Handle("POST /admin/api/v1/orders/{id}/status", scoped(auth.PermOrdersWrite, h.UpdateOrderStatus))
+	mux.Handle("PUT /admin/api/v1/orders/{id}/tracking", scoped(auth.PermOrdersWrite, h.UpdateOrderTracking))
+	mux.Handle("GET /admin/api/v1/raw-materials", scoped(auth.PermInventoryRead, h.ListRawMaterials))
+	mux.Handle("GET /admin/api/v1/raw-materials/{id}", scoped(auth.PermInventoryRead, h.GetRawMaterial))
+	mux.Handle("POST /admin/api/v1/raw-materials/{id}/stock", scoped(auth.PermInventoryWrite, h.ChangeRawMaterialStock))
+	mux.HandleFunc("/admin/api/v1/", func(w http.ResponseWriter, r *http.Request) {
+		writeAPIError(w, http.StatusNotFound, "not found")
+	})
+}
+
+// scoped wraps an admin API handler so it only runs for keys with scope.
+func scoped(scope string, h http.HandlerFunc) http.Handler {
+	return middleware.RequireScope(scope)(h)
+}
+
+// --- JSON types ---
+
+// apiListResponse is the paginated list wrapper.
+type apiListResponse struct {
+	Data       any   `json:"data"`
+	Page       int   `json:"page"`
+	TotalPages int   `json:"total_pages"`
+	Total      int64 `json:"total"`
+}
+
+type apiProductJSON struct {
+	ID             uuid.UUID        `json:"id"`
+	Name           string           `json:"name"`
+	Slug           string           `json:"slug"`
+	Status         string           `json:"status"`
+	SkuPrefix      *string          `json:"sku_prefix"`
+	BasePrice      pgtype.Numeric   `json:"base_price"`
+	CompareAtPrice pgtype.Numeric   `json:"compare_at_price"`
+	HasVariants    bool             `json:"has_variants"`
+	AllowBackorder bool             `json:"allow_backorder"`
+	CreatedAt      time.Time        `json:"created_at"`
+	UpdatedAt      time.Time        `json:"updated_at"`
+	Variants       []apiVariantJSON `json:"variants,omitempty"`
+}
+
+type apiVariantJSON struct {
+	ID                uuid.UUID      `json:"id"`
+	ProductID         uuid.UUID      `json:"product_id"`
+	Sku               string         `json:"sku"`
+	Price             pgtype.Numeric `json:"price"`
+	CompareAtPrice    pgtype.Numeric `json:"compare_at_price"`
+	StockQuantity     int32          `json:"stock_quantity"`
+	LowStockThreshold int32          `json:"low_stock_threshold"`
+	Barcode           *string        `json:"barcode"`
+	IsActive          bool           `json:"is_active"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+}
+
+type apiOrderJSON struct {
+	ID              uuid.UUID          `json:"id"`
+	OrderNumber     int64              `json:"order_number"`
+	Status          string             `json:"status"`
+	PaymentStatus   string             `json:"payment_status"`
+	Email           string             `json:"email"`
+	BillingAddress  json.RawMessage    `json:"billing_address"`
+	ShippingAddress json.RawMessage    `json:"shipping_address"`
+	Subtotal        pgtype.Numeric     `json:"subtotal"`
+	ShippingFee     pgtype.Numeric     `json:"shipping_fee"`
+	DiscountAmount  pgtype.Numeric     `json:"discount_amount"`
+	VatTotal        pgtype.Numeric     `json:"vat_total"`
+	Total           pgtype.Numeric     `json:"total"`
+	ShippingMethod  *string            `json:"shipping_method"`
+	TrackingNumber  *string            `json:"tracking_number"`
+	ShippedAt       *time.Time         `json:"shipped_at"`
+	DeliveredAt     *time.Time         `json:"delivered_at"`
+	CustomerNotes   *string            `json:"customer_notes"`
+	CreatedAt       time.Time          `json:"created_at"`
+	UpdatedAt       time.Time          `json:"updated_at"`
+	Items           []apiOrderItemJSON `json:"items,omitempty"`
+}
+
+type apiOrderItemJSON struct {
+	ID          uuid.UUID      `json:"id"`
+	ProductID   *uuid.UUID     `json:"product_id"`
+	VariantID   *uuid.UUID     `json:"variant_id"`
+	ProductName string         `json:"product_name"`
+	VariantName *string        `json:"variant_name"`
+	Sku         *string        `json:"sku"`
+	Quantity    int32          `json:"quantity"`
+	UnitPrice   pgtype.Numeric `json:"unit_price"`
+	TotalPrice  pgtype.Numeric `json:"total_price"`
+}
+
+type apiRawMaterialJSON struct {
+	ID                uuid.UUID      `json:"id"`
+	Name              string         `json:"name"`
+	Sku               string         `json:"sku"`
+	CategoryID        *uuid.UUID     `json:"category_id"`
+	UnitOfMeasure     string         `json:"unit_of_measure"`
+	CostPerUnit       pgtype.Numeric `json:"cost_per_unit"`
+	StockQuantity     pgtype.Numeric `json:"stock_quantity"`
+	LowStockThreshold pgtype.Numeric `json:"low_stock_threshold"`
+	SupplierName      *string        `json:"supplier_name"`
+	SupplierSku       *string        `json:"supplier_sku"`
+	LeadTimeDays      *int32         `json:"lead_time_days"`
+	IsActive          bool           `json:"is_active"`
+	UpdatedAt         time.Time      `json:"updated_at"`
+}
+
+// --- Products and variants ---
+
+// ListProducts handles GET /admin/api/v1/products.
+func (h *APIHandler) ListProducts(w http.ResponseWriter, r *http.Request) {
+	page, limit := apiPagination(r)
+
+	var status *string
+	if s := r.URL.Query().Get("status"); s != "" {
+		status = &s
+	}
+
+	products, total, err := h.products.List(r.Context(), status, page, limit)
+	if err != nil {
+		h.logger.Error("api: failed to list products", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	data := make([]apiProductJSON, len(products))
+	for i, p := range products {
+		data[i] = newAPIProductJSON(p)
+	}
+	writeJSON(w, http.StatusOK, newAPIListResponse(data, page, limit, total))
+}
+
+// GetProduct handles GET /admin/api/v1/products/{id}. The response
+// includes the product's variants.
+func (h *APIHandler) GetProduct(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiPathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	p, err := h.products.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, product.ErrNotFound) {
+			writeAPIError(w, http.StatusNotFound, "product not found")
+			return
+		}
+		h.logger.Error("api: failed to get product", "error", err, "product_id", id)
+		writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	variants, err := h.variants.List(r.Context(), id)
+	if err != nil {
+		h.logger.Error("api: failed to list variants", "error", err, "product_id", id)
+		writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	resp := newAPIProductJSON(p)
+	resp.Variants = make([]apiVariantJSON, len(variants))
+	for i, v := range variants {
+		resp.Variants[i] = newAPIVariantJSON(v)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetVariant handles GET /admin/api/v1/variants/{id}.
+func (h *APIHandler) GetVariant(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiPathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+	h.writeVariant(w, r, func() (db.ProductVariant, error) { return h.variants.Get(r.Context(), id) })
+}
+
+// GetVariantBySKU handles GET /admin/api/v1/variants/sku/{sku}.
+func (h *APIHandler) GetVariantBySKU(w http.ResponseWriter, r *http.Request) {
+	sku := r.PathValue("sku")
+	h.writeVariant(w, r, func() (db.ProductVariant, error) { return h.variants.GetBySKU(r.Context(), sku) })
+}
+
+func (h *APIHandler) writeVariant(w http.ResponseWriter, r *http.Request, get func() (db.ProductVariant, error)) {
+	v, err := get()
+	if err != nil {
+		if errors.Is(err, variant.ErrNotFound) {
+			writeAPIError(w, http.StatusNotFound, "variant not found")
+			return
+		}
+		h.logger.Error("api: failed to get variant", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	writeJSON(w, http.StatusOK, newAPIVariantJSON(v))
+}
+
+// updateVariantRequest is the body of PATCH /admin/api/v1/variants/{id}.
+// Omitted fields are left unchanged.
+type updateVariantRequest struct {
+	Price             *decimal.Decimal `json:"price"`
+	CompareAtPrice    *decimal.Decimal `json:"compare_at_price"`
+	LowStockThreshold *int32           `json:"low_stock_threshold"`
+	Barcode           *string          `json:"barcode"`
+	IsActive          *bool            `json:"is_active"`
+}
+
+// UpdateVariant handles PATCH /admin/api/v1/variants/{id}. Stock is changed
+// through the stock endpoint so that every change is a ledger movement.
+func (h *APIHandler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiPathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req updateVariantRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+	if (req.Price != nil && req.Price.IsNegative()) || (req.CompareAtPrice != nil && req.CompareAtPrice.IsNegative()) {
+		writeAPIError(w, http.StatusUnprocessableEntity, "prices must not be negative")
+		return
+	}
+	if req.LowStockThreshold != nil && *req.LowStockThreshold < 0 {
+		writeAPIError(w, http.StatusUnprocessableEntity, "low_stock_threshold must not be negative")
+		return
+	}
+
+	existing, err := h.variants.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, variant.ErrNotFound) {
+			writeAPIError(w, http.StatusNotFound, "variant not found")
+			return
+		}
+		h.logger.Error("api: failed to get variant", "error", err, "variant_id", id)
+		writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	params := variant.UpdateVariantParams{
+		Sku:               existing.Sku,
+		Price:             existing.Price,
+		CompareAtPrice:    existing.CompareAtPrice,
+		WeightGrams:       existing.WeightGrams,
+		DimensionsMm:      existing.DimensionsMm,
+		StockQuantity:     existing.StockQuantity,
+		LowStockThreshold: existing.LowStockThreshold,
+		Barcode:           existing.Barcode,
+		IsActive:          existing.IsActive,
+		Position:          existing.Position,
+		ChangedBy:         adminUserRef(r),
+	}
+	if req.Price != nil {
+		params.Price = parseNumeric(req.Price.String())
+	}
+	if req.CompareAtPrice != nil {
+		params.CompareAtPrice = parseNumeric(req.CompareAtPrice.String())
+	}
+	if req.LowStockThreshold != nil {
+		params.LowStockThreshold = *req.LowStockThreshold
+	}
+	if req.Barcode != nil {
+		params.Barcode = strPtr(*req.Barcode)
+	}
+	if req.IsActive != nil {
+		params.IsActive = *req.IsActive
+	}
+
+	updated, err := h.variants.Update(r.Context(), id, params)
+	if err != nil {
+		h.logger.Error("api: failed to update variant", "error", err, "variant_id", id)
+		writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	h.recordAudit(r, "variant.updated", "variant", id.String(), audit.Diff(existing, updated))
+
+	writeJSON(w, http.StatusOK, newAPIVariantJSON(updated))
+}
+
+// stockChangeRequest is the body of the stock endpoints. Exactly one of
+// quantity (a change) and set_to (an absolute level) is given.
+type stockChangeRequest struct {
+	MovementType string           `json:"movement_type"`
+	Quantity     *decimal.Decimal `json:"quantity"`
+	SetTo        *decimal.Decimal `json:"set_to"`
+	UnitCost     *decimal.Decimal `json:"unit_cost"`
+	Notes        string           `json:"notes"`
+}
+
+// change validates the request. It returns the movement to record and
+// either the signed change or, when set_to was given, nil. Signs follow the
+// admin stock form: purchases and returns add, damage removes. A non-empty
+// string is a client-facing validation error.
+func (req stockChangeRequest) change(r *http.Request) (inventory.Change, *decimal.Decimal, string) {
+	movementType := req.MovementType
+	if movementType == "" {
+		movementType = inventory.MovementAdjustment
+	}
+	if !manualMovementTypes[movementType] {
+		return inventory.Change{}, nil, "movement_type must be one of purchase, adjustment, return or damage"
+	}
+	if (req.Quantity == nil) == (req.SetTo == nil) {
+		return inventory.Change{}, nil, "give exactly one of quantity and set_to"
+	}
+
+	change := inventory.Change{
+		MovementType:  movementType,
+		ReferenceType: inventory.ReferenceManual,
+		Notes:         strings.TrimSpace(req.Notes),
+		CreatedBy:     adminUserRef(r),
+	}
+	if req.UnitCost != nil {
+		change.UnitCost = parseNumeric(req.UnitCost.String())
+	}
+
+	if req.SetTo != nil {
+		if movementType != inventory.MovementAdjustment {
+			return inventory.Change{}, nil, "set_to can only be used with the adjustment movement type"
+		}
+		if req.SetTo.IsNegative() {
+			return inventory.Change{}, nil, "set_to must not be negative"
+		}
+		return change, nil, ""
+	}
+
+	qty := *req.Quantity
+	if qty.IsZero() {
+		return inventory.Change{}, nil, "quantity must not be zero"
+	}
+	switch movementType {
+	case inventory.MovementPurchase, inventory.MovementReturn:
+		qty = qty.Abs()
+	case inventory.MovementDamage:
+		qty = qty.Abs().Neg()
+	}
+	return change, &qty, ""
+}
+
+// variantStockError checks that a variant stock change or level is a whole
+// number of units that fits the stock column. The level is used when delta
+// is nil.
+func variantStockError(delta, setTo *decimal.Decimal) string {
+	qty := setTo
+	if delta != nil {
+		qty = delta
+	}
+	if !qty.IsInteger() {
+		return "variant stock must change by whole units"
+	}
+	if qty.Abs().GreaterThan(decimal.NewFromInt(math.MaxInt32)) {
+		return "quantity is out of range"
+	}
+	return ""
+}
+
+// ChangeVariantStock handles POST /admin/api/v1/variants/{id}/stock.
+func (h *APIHandler) ChangeVariantStock(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiPathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req stockChangeRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+	change, delta, errMsg := req.change(r)
+	if errMsg == "" {
+		errMsg = variantStockError(delta, req.SetTo)
+	}
+	if errMsg != "" {
+		writeAPIError(w, http.StatusUnprocessableEntity, errMsg)
+		return
+	}
+
+	var movement db.StockMovement
+	var err error
+	if delta != nil {
+		movement, err = h.inventory.AdjustVariant(r.Context(), id, int32(delta.IntPart()), change)
+	} else {
+		movement, err = h.inventory.SetVariantStock(r.Context(), id, int32(req.SetTo.IntPart()), change)
+	}
+	if err != nil {
+		h.writeStockError(w, err, "variant", id)
+		return
+	}
+	h.recordStockAudit(r, "variant", id, movement)
+
+	h.writeVariant(w, r, func() (db.ProductVariant, error) { return h.variants.Get(r.Context(), id) })
+}
+
+// --- Orders ---
+
+// ListOrders handles GET /admin/api/v1/orders.
+func (h *APIHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
+	page, limit := apiPagination(r)
+
+	var status *string
+	if s := r.URL.Query().Get("status"); s != "" {
+		status = &s
+	}
+
+	orders, total, err := h.orders.List(r.Context(), status, page, limit)
+	if err != nil {
+		h.logger.Error("api: failed to list orders", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	data := make([]apiOrderJSON, len(orders))
+	for i, o := range orders {
+		data[i] = newAPIOrderJSON(o)
+	}
+	writeJSON(w, http.StatusOK, newAPIListResponse(data, page, limit, total))
+}
+
+// GetOrder handles GET /admin/api/v1/orders/{id}. {id} is the order's UUID
+// or its order number. The response includes the order's items.
+func (h *APIHandler) GetOrder(w http.ResponseWriter, r *http.Request) {
+	o, ok := h.lookupOrder(w, r)
+	if !ok {
+		return
+	}
+	h.writeOrder(w, r, o)
+}
+
+// updateOrderStatusRequest is the body of POST /admin/api/v1/orders/{id}/status.
+type updateOrderStatusRequest struct {
+	Status string `json:"status"`
+}
+
+// UpdateOrderStatus handles POST /admin/api/v1/orders/{id}/status.
+// Transitions follow the same rules as the admin order page.
+func (h *APIHandler) UpdateOrderStatus(w http.ResponseWriter, r *http.Request) {
+	o, ok := h.lookupOrder(w, r)
+	if !ok {
+		return
+	}
+
+	var req updateOrderStatusRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+	newStatus := strings.TrimSpace(req.Status)
+	if newStatus == "" {
+		writeAPIError(w, http.StatusUnprocessableEntity, "status is required")
+		return
+	}
+
+	updated, err := h.orders.UpdateStatus(r.Context(), o.ID, newStatus)
+	if err != nil {
+		var transitionErr *order.TransitionError
+		switch {
+		case errors.Is(err, order.ErrNotFound):
+			writeAPIError(w, http.StatusNotFound, "order not found")
+		case errors.As(err, &transitionErr):
+			writeAPIError(w, http.StatusUnprocessableEntity, transitionErr.Error())
+		default:
+			h.logger.Error("api: failed to update order status", "error", err, "order_id", o.ID, "new_status", newStatus)
+			writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		}
+		return
+	}
+	h.recordAudit(r, "order.status_changed", "order", o.ID.String(), map[string]any{
+		"status": map[string]any{"old": o.Status, "new": updated.Status},
+	})
+
+	h.writeOrder(w, r, updated)
+}
+
+// updateOrderTrackingRequest is the body of PUT /admin/api/v1/orders/{id}/tracking.
+type updateOrderTrackingRequest struct {
+	TrackingNumber string `json:"tracking_number"`
+}
+
+// UpdateOrderTracking handles PUT /admin/api/v1/orders/{id}/tracking. Like
+// the admin order page, setting a tracking number marks the order as
+// shipped now and an empty one clears both.
+func (h *APIHandler) UpdateOrderTracking(w http.ResponseWriter, r *http.Request) {
+	o, ok := h.lookupOrder(w, r)
+	if !ok {
+		return
+	}
+
+	var req updateOrderTrackingRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+
+	trackingNumber := strPtr(req.TrackingNumber)
+	var shippedAt pgtype.Timestamptz
+	if trackingNumber != nil {
+		shippedAt = pgtype.Timestamptz{Time: time.Now().UTC(), Valid: true}
+	}
+
+	if err := h.orders.UpdateTracking(r.Context(), o.ID, trackingNumber, shippedAt); err != nil {
+		if errors.Is(err, order.ErrNotFound) {
+			writeAPIError(w, http.StatusNotFound, "order not found")
+			return
+		}
+		h.logger.Error("api: failed to update order tracking", "error", err, "order_id", o.ID)
+		writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	h.recordAudit(r, "order.tracking_updated", "order", o.ID.String(), map[string]any{
+		"tracking_number": map[string]any{"old": derefString(o.TrackingNumber), "new": derefString(trackingNumber)},
+	})
+
+	updated, err := h.orders.Get(r.Context(), o.ID)
+	if err != nil {
+		h.logger.Error("api: failed to get order", "error", err, "order_id", o.ID)
+		writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	h.writeOrder(w, r, updated)
+}
+
+// lookupOrder fetches the order named by the {id} path value, which is
+// either a UUID or an order number. It writes the error response and
+// returns false if there is no such order.
+func (h *APIHandler) lookupOrder(w http.ResponseWriter, r *http.Request) (db.Order, bool) {
+	ref := r.PathValue("id")
+
+	var o db.Order
+	var err error
+	if id, parseErr := uuid.Parse(ref); parseErr == nil {
+		o, err = h.orders.Get(r.Context(), id)
+	} else if number, parseErr := strconv.ParseInt(ref, 10, 64); parseErr == nil {
+		o, err = h.orders.GetByNumber(r.Context(), number)
+	} else {
+		writeAPIError(w, http.StatusBadRequest, "invalid order ID")
+		return db.Order{}, false
+	}
+	if err != nil {
+		if errors.Is(err, order.ErrNotFound) {
+			writeAPIError(w, http.StatusNotFound, "order not found")
+			return db.Order{}, false
+		}
+		h.logger.Error("api: failed to get order", "error", err, "order", ref)
+		writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return db.Order{}, false
+	}
+	return o, true
+}
+
+// writeOrder writes o with its items.
+func (h *APIHandler) writeOrder(w http.ResponseWriter, r *http.Request, o db.Order) {
+	items, err := h.orders.ListItems(r.Context(), o.ID)
+	if err != nil {
+		h.logger.Error("api: failed to list order items", "error", err, "order_id", o.ID)
+		writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	resp := newAPIOrderJSON(o)
+	resp.Items = make([]apiOrderItemJSON, len(items))
+	for i, item := range items {
+		resp.Items[i] = apiOrderItemJSON{
+			ID:          item.ID,
+			ProductID:   uuidPtr(item.ProductID),
+			VariantID:   uuidPtr(item.VariantID),
+			ProductName: item.ProductName,
+			VariantName: item.VariantName,
+			Sku:         item.Sku,
+			Quantity:    item.Quantity,
+			UnitPrice:   item.UnitPrice,
+			TotalPrice:  item.TotalPrice,
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// --- Raw materials ---
+
+// ListRawMaterials handles GET /admin/api/v1/raw-materials. ?active=true
+// or ?active=false filters by active state.
+func (h *APIHandler) ListRawMaterials(w http.ResponseWriter, r *http.Request) {
+	page, limit := apiPagination(r)
+
+	var activeOnly *bool
+	if v := r.URL.Query().Get("active"); v != "" {
+		active, err := strconv.ParseBool(v)
+		if err != nil {
+			writeAPIError(w, http.StatusBadRequest, "active must be true or false")
+			return
+		}
+		activeOnly = &active
+	}
+
+	materials, total, err := h.materials.List(r.Context(), nil, activeOnly, page, limit)
+	if err != nil {
+		h.logger.Error("api: failed to list raw materials", "error", err)
+		writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+
+	data := make([]apiRawMaterialJSON, len(materials))
+	for i, m := range materials {
+		data[i] = newAPIRawMaterialJSON(m)
+	}
+	writeJSON(w, http.StatusOK, newAPIListResponse(data, page, limit, total))
+}
+
+// GetRawMaterial handles GET /admin/api/v1/raw-materials/{id}.
+func (h *APIHandler) GetRawMaterial(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiPathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+	h.writeRawMaterial(w, r, id)
+}
+
+// ChangeRawMaterialStock handles POST /admin/api/v1/raw-materials/{id}/stock.
+func (h *APIHandler) ChangeRawMaterialStock(w http.ResponseWriter, r *http.Request) {
+	id, ok := apiPathUUID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req stockChangeRequest
+	if !decodeAPIRequest(w, r, &req) {
+		return
+	}
+	change, delta, errMsg := req.change(r)
+	if errMsg != "" {
+		writeAPIError(w, http.StatusUnprocessableEntity, errMsg)
+		return
+	}
+
+	var movement db.StockMovement
+	var err error
+	if delta != nil {
+		movement, err = h.inventory.AdjustRawMaterial(r.Context(), id, *delta, change)
+	} else {
+		movement, err = h.inventory.SetRawMaterialStock(r.Context(), id, *req.SetTo, change)
+	}
+	if err != nil {
+		h.writeStockError(w, err, "raw material", id)
+		return
+	}
+	h.recordStockAudit(r, "raw_material", id, movement)
+
+	h.writeRawMaterial(w, r, id)
+}
+
+func (h *APIHandler) writeRawMaterial(w http.ResponseWriter, r *http.Request, id uuid.UUID) {
+	m, err := h.materials.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			writeAPIError(w, http.StatusNotFound, "raw material not found")
+			return
+		}
+		h.logger.Error("api: failed to get raw material", "error", err, "id", id)
+		writeAPIError(w, http.StatusInternalServerError, "internal server error")
+		return
+	}
+	writeJSON(w, http.StatusOK, newAPIRawMaterialJSON(m))
+}
+
+// --- Helpers ---
+
+// writeStockError writes the response for a failed stock change.
+func (h *APIHandler) writeStockError(w http.ResponseWriter, err error, entity string, id uuid.UUID) {
+	switch status := stockErrorStatus(err); status {
+	case http.StatusNotFound:
+		writeAPIError(w, status, entity+" not found")
+	case http.StatusConflict:
+		writeAPIError(w, status, err.Error())
+	default:
+		h.logger.Error("api: failed to change stock", "error", err, "entity", entity, "id", id)
+		writeAPIError(w, status, "internal server error")
+	}
+}
+
+// recordStockAudit records a stock change made through the API. Unchanged
+// stock writes no movement and is not recorded.
+func (h *APIHandler) recordStockAudit(r *http.Request, entityType string, id uuid.UUID, m db.StockMovement) {
+	if m.ID == uuid.Nil {
+		return
+	}
+	h.recordAudit(r, entityType+".stock_changed", entityType, id.String(), map[string]any{
+		"movement_type":   m.MovementType,
+		"quantity_change": formatNumeric(m.QuantityChange),
+		"stock_quantity": map[string]any{
+			"old": formatNumeric(m.QuantityBefore),
+			"new": formatNumeric(m.QuantityAfter),
+		},
+	})
+}
+
+// recordAudit records a change made through the API in the audit log,
+// attributed to the key's creator and noting the key used.
+func (h *APIHandler) recordAudit(r *http.Request, action, entityType, entityID string, changes map[string]any) {
+	if changes == nil {
+		changes = make(map[string]any)
+	}
+	if key, ok := auth.APIKeyFromContext(r.Context()); ok {
+		changes["api_key"] = key.Name + " (" + key.Prefix + "…)"
+	}
+	recordAudit(h.audit, r, action, entityType, entityID, changes)
+}
+
+// writeAPIError writes a JSON error response.
+func writeAPIError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
+
+// decodeAPIRequest decodes the JSON request body into v, rejecting unknown
+// fields. It writes a 400 response and returns false on failure.
+func decodeAPIRequest(w http.ResponseWriter, r *http.Request, v any) bool {
+	dec := json.NewDecoder(http.MaxBytesReader(w, r.Body, apiMaxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid request body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// apiPathUUID parses the named path value as a UUID. It writes a 400
+// response and returns false if it is not one.
+func apiPathUUID(w http.ResponseWriter, r *http.Request, name string) (uuid.UUID, bool) {
+	id, err := uuid.Parse(r.PathValue(name))
+	if err != nil {
+		writeAPIError(w, http.StatusBadRequest, "invalid "+name)
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+// apiPagination reads ?page and ?limit, defaulting to the first page of
+// 20 and capping limit at 250.
+func apiPagination(r *http.Request) (page, limit int) {
+	page, limit = 1, apiDefaultPageSize
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = min(l, apiMaxPageSize)
+	}
+	return page, limit
+}
+
+func newAPIListResponse(data any, page, limit int, total int64) apiListResponse {
+	return apiListResponse{
+		Data:       data,
+		Page:       page,
+		TotalPages: int((total + int64(limit) - 1) / int64(limit)),
+		Total:      total,
+	}
+}
+
+func newAPIProductJSON(p db.Product) apiProductJSON {
+	return apiProductJSON{
+		ID:             p.ID,
+		Name:           p.Name,
+		Slug:           p.Slug,
+		Status:         p.Status,
+		SkuPrefix:      p.SkuPrefix,
+		BasePrice:      p.BasePrice,
+		CompareAtPrice: p.CompareAtPrice,
+		HasVariants:    p.HasVariants,
+		AllowBackorder: p.AllowBackorder,
+		CreatedAt:      p.CreatedAt,
+		UpdatedAt:      p.UpdatedAt,
+	}
+}
+
+func newAPIVariantJSON(v db.ProductVariant) apiVariantJSON {
+	return apiVariantJSON{
+		ID:                v.ID,
+		ProductID:         v.ProductID,
+		Sku:               v.Sku,
+		Price:             v.Price,
+		CompareAtPrice:    v.CompareAtPrice,
+		StockQuantity:     v.StockQuantity,
+		LowStockThreshold: v.LowStockThreshold,
+		Barcode:           v.Barcode,
+		IsActive:          v.IsActive,
+		UpdatedAt:         v.UpdatedAt,
+	}
+}
+
+func newAPIOrderJSON(o db.Order) apiOrderJSON {
+	return apiOrderJSON{
+		ID:              o.ID,
+		OrderNumber:     o.OrderNumber,
+		Status:          o.Status,
+		PaymentStatus:   o.PaymentStatus,
+		Email:           o.Email,
+		BillingAddress:  o.BillingAddress,
+		ShippingAddress: o.ShippingAddress,
+		Subtotal:        o.Subtotal,
+		ShippingFee:     o.ShippingFee,
+		DiscountAmount:  o.DiscountAmount,
+		VatTotal:        o.VatTotal,
+		Total:           o.Total,
+		ShippingMethod:  o.ShippingMethod,
+		TrackingNumber:  o.TrackingNumber,
+		ShippedAt:       timePtr(o.ShippedAt),
+		DeliveredAt:     timePtr(o.DeliveredAt),
+		CustomerNotes:   o.CustomerNotes,
+		CreatedAt:       o.CreatedAt,
+		UpdatedAt:       o.UpdatedAt,
+	}
+}
+
+func newAPIRawMaterialJSON(m db.RawMaterial) apiRawMaterialJSON {
+	return apiRawMaterialJSON{
+		ID:                m.ID,
+		Name:              m.Name,
+		Sku:               m.Sku,
+		CategoryID:        uuidPtr(m.CategoryID),
+		UnitOfMeasure:     m.UnitOfMeasure,
+		CostPerUnit:       m.CostPerUnit,
+		StockQuantity:     m.StockQuantity,
+		LowStockThreshold: m.LowStockThreshold,
+		SupplierName:      m.SupplierName,
+		SupplierSku:       m.SupplierSku,
+		LeadTimeDays:      m.LeadTimeDays,
+		IsActive:          m.IsActive,
+		UpdatedAt:         m.UpdatedAt,
+	}
+}
+
+// uuidPtr converts a nullable UUID to a pointer, nil for NULL.
+func uuidPtr(id pgtype.UUID) *uuid.UUID {
+	if !id.Valid {
+		return nil
+	}
+	u := uuid.UUID(id.Bytes)
+	return &u
+}
+
+// timePtr converts a nullable timestamp to a pointer, nil for NULL.
+func timePtr(ts pgtype.Timestamptz) *time.Time {
+	if !ts.Valid {
+		return nil
+	}
+	return &ts.Time
+}
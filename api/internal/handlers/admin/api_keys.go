@@ -0,0 +1,223 @@
+package admin
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/forgecommerce/api/internal/auth"
+	"github.com/forgecommerce/api/internal/middleware"
+	"github.com/forgecommerce/api/internal/services/audit"
+	admin "github.com/forgecommerce/api/templates/admin"
+)
+
+// APIKeyHandler lets admin users issue and revoke keys for the JSON admin
+// API.
+type APIKeyHandler struct {
+	authSvc *auth.Service
+	keys    *auth.APIKeyService
+	audit   *audit.Service
+	logger  *slog.Logger
+}
+
+// NewAPIKeyHandler creates a new API key handler.
+func NewAPIKeyHandler(authSvc *auth.Service, keys *auth.APIKeyService, auditSvc *audit.Service, logger *slog.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		authSvc: authSvc,
+		keys:    keys,
+		audit:   auditSvc,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes registers API key management routes on the given mux.
+func (h *APIKeyHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.Handle("GET /admin/api-keys", requires(auth.PermAPIKeysManage, h.List))
+	mux.Handle("POST /admin/api-keys", requires(auth.PermAPIKeysManage, h.Create))
+	mux.Handle("POST /admin/api-keys/{id}/revoke", requires(auth.PermAPIKeysManage, h.Revoke))
+}
+
+// List handles GET /admin/api-keys.
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	data := admin.APIKeyListData{}
+	if r.URL.Query().Get("status") == "revoked" {
+		data.Success = "API key revoked."
+	}
+	h.render(w, r, data)
+}
+
+// Create handles POST /admin/api-keys. The new key is shown on the
+// response page only; it is never stored and cannot be shown again.
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	user, ok := auth.UserFromContext(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		h.render(w, r, admin.APIKeyListData{Error: "Invalid form data."})
+		return
+	}
+
+	form := admin.APIKeyForm{
+		Name:      strings.TrimSpace(r.FormValue("name")),
+		ExpiresOn: strings.TrimSpace(r.FormValue("expires_on")),
+		Scopes:    r.Form["scopes"],
+	}
+
+	var expiresAt *time.Time
+	if form.ExpiresOn != "" {
+		day, err := time.Parse("2006-01-02", form.ExpiresOn)
+		if err != nil {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			h.render(w, r, admin.APIKeyListData{Form: form, Error: "Enter the expiry as a date."})
+			return
+		}
+		// The key is valid through the chosen day (UTC).
+		end := day.AddDate(0, 0, 1)
+		expiresAt = &end
+	}
+
+	key, token, err := h.keys.Create(r.Context(), user, form.Name, form.Scopes, expiresAt)
+	if err != nil {
+		var msg string
+		switch {
+		case errors.Is(err, auth.ErrInvalidAPIKeyName):
+			msg = "Enter a name of at most 64 characters."
+		case errors.Is(err, auth.ErrInvalidAPIKeyScopes):
+			msg = "Choose at least one scope you have permission for."
+		case errors.Is(err, auth.ErrInvalidAPIKeyExpiry):
+			msg = "The expiry date must be in the future."
+		default:
+			h.logger.Error("failed to create API key", "error", err, "user_id", user.ID)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		h.render(w, r, admin.APIKeyListData{Form: form, Error: msg})
+		return
+	}
+	recordAudit(h.audit, r, "api_key.created", "api_key", key.ID.String(), audit.Diff(nil, apiKeyAuditFields(key)))
+
+	h.render(w, r, admin.APIKeyListData{
+		NewKey:  token,
+		Success: "API key \"" + key.Name + "\" created.",
+	})
+}
+
+// Revoke handles POST /admin/api-keys/{id}/revoke.
+func (h *APIKeyHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid API key ID", http.StatusBadRequest)
+		return
+	}
+
+	key, err := h.keys.Revoke(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, auth.ErrAPIKeyNotFound) {
+			http.Error(w, "API key not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to revoke API key", "error", err, "api_key_id", id)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, r, "api_key.revoked", "api_key", id.String(), map[string]any{
+		"name":   key.Name,
+		"prefix": key.Prefix,
+	})
+
+	http.Redirect(w, r, "/admin/api-keys?status=revoked", http.StatusSeeOther)
+}
+
+// render fills in the key list and grantable scopes and renders the page.
+func (h *APIKeyHandler) render(w http.ResponseWriter, r *http.Request, data admin.APIKeyListData) {
+	keys, err := h.keys.List(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list API keys", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	users, err := h.authSvc.ListUsers(r.Context())
+	if err != nil {
+		h.logger.Error("failed to list admin users", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	names := make(map[uuid.UUID]string, len(users))
+	for _, u := range users {
+		names[u.ID] = u.Name
+	}
+
+	user, _ := auth.UserFromContext(r.Context())
+	data.Keys = apiKeyItems(keys, names, time.Now())
+	data.Scopes = grantableScopes(user)
+	data.CSRFToken = middleware.CSRFToken(r)
+
+	admin.APIKeyListPage(data).Render(r.Context(), w)
+}
+
+// grantableScopes returns the API scopes user may put on a key.
+func grantableScopes(user *auth.AdminUser) []admin.APIScopeOption {
+	var scopes []admin.APIScopeOption
+	for _, p := range auth.AllPermissions {
+		if slices.Contains(auth.APIScopes, p.Name) && user.Can(p.Name) {
+			scopes = append(scopes, admin.APIScopeOption{Name: p.Name, Description: p.Description})
+		}
+	}
+	return scopes
+}
+
+// apiKeyItems converts API keys for display. creators maps admin user IDs
+// to names.
+func apiKeyItems(keys []*auth.APIKey, creators map[uuid.UUID]string, now time.Time) []admin.APIKeyItem {
+	items := make([]admin.APIKeyItem, 0, len(keys))
+	for _, k := range keys {
+		item := admin.APIKeyItem{
+			ID:         k.ID.String(),
+			Name:       k.Name,
+			Prefix:     k.Prefix,
+			Scopes:     k.Scopes,
+			CreatedBy:  creators[k.CreatedBy],
+			CreatedAt:  k.CreatedAt.Format("2006-01-02 15:04"),
+			LastUsedIP: k.LastUsedIP,
+			Status:     admin.APIKeyActive,
+		}
+		if k.ExpiresAt != nil {
+			item.ExpiresAt = k.ExpiresAt.Format("2006-01-02 15:04")
+		}
+		if k.LastUsedAt != nil {
+			item.LastUsedAt = k.LastUsedAt.Format("2006-01-02 15:04")
+		}
+		switch {
+		case k.RevokedAt != nil:
+			item.Status = admin.APIKeyRevoked
+		case !k.Active(now):
+			item.Status = admin.APIKeyExpired
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// apiKeyAuditFields returns the fields of an API key recorded in the audit
+// log.
+func apiKeyAuditFields(k *auth.APIKey) map[string]any {
+	fields := map[string]any{
+		"name":   k.Name,
+		"prefix": k.Prefix,
+		"scopes": k.Scopes,
+	}
+	if k.ExpiresAt != nil {
+		fields["expires_at"] = k.ExpiresAt.Format(time.RFC3339)
+	}
+	return fields
+}
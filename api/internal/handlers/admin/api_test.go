@@ -0,0 +1,210 @@
+package admin
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/forgecommerce/api/internal/auth"
+)
+
+// --------------------------------------------------------------------------
+// Tests for helper functions in api.go
+// --------------------------------------------------------------------------
+
+func TestStockChangeRequest_Change(t *testing.T) {
+	dec := func(s string) *decimal.Decimal {
+		d := decimal.RequireFromString(s)
+		return &d
+	}
+
+	tests := []struct {
+		name      string
+		req       stockChangeRequest
+		wantType  string
+		wantDelta string // empty when set_to is used
+		wantErr   bool
+	}{
+		{"default adjustment", stockChangeRequest{Quantity: dec("-3")}, "adjustment", "-3", false},
+		{"purchase is positive", stockChangeRequest{MovementType: "purchase", Quantity: dec("-5")}, "purchase", "5", false},
+		{"return is positive", stockChangeRequest{MovementType: "return", Quantity: dec("2")}, "return", "2", false},
+		{"damage is negative", stockChangeRequest{MovementType: "damage", Quantity: dec("4")}, "damage", "-4", false},
+		{"set to", stockChangeRequest{SetTo: dec("10")}, "adjustment", "", false},
+		{"set to zero", stockChangeRequest{SetTo: dec("0")}, "adjustment", "", false},
+		{"set to negative", stockChangeRequest{SetTo: dec("-1")}, "", "", true},
+		{"set to with purchase", stockChangeRequest{MovementType: "purchase", SetTo: dec("10")}, "", "", true},
+		{"both given", stockChangeRequest{Quantity: dec("1"), SetTo: dec("10")}, "", "", true},
+		{"neither given", stockChangeRequest{}, "", "", true},
+		{"zero quantity", stockChangeRequest{Quantity: dec("0")}, "", "", true},
+		{"sale not allowed", stockChangeRequest{MovementType: "sale", Quantity: dec("-1")}, "", "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/admin/api/v1/variants/x/stock", nil)
+			change, delta, errMsg := tt.req.change(r)
+			if tt.wantErr {
+				if errMsg == "" {
+					t.Fatal("expected a validation error")
+				}
+				return
+			}
+			if errMsg != "" {
+				t.Fatalf("unexpected error: %s", errMsg)
+			}
+			if change.MovementType != tt.wantType {
+				t.Errorf("movement type: got %q, want %q", change.MovementType, tt.wantType)
+			}
+			if tt.wantDelta == "" {
+				if delta != nil {
+					t.Errorf("delta: got %s, want nil", delta)
+				}
+				return
+			}
+			if delta == nil || delta.String() != tt.wantDelta {
+				t.Errorf("delta: got %v, want %s", delta, tt.wantDelta)
+			}
+		})
+	}
+}
+
+func TestVariantStockError(t *testing.T) {
+	dec := func(s string) *decimal.Decimal {
+		d := decimal.RequireFromString(s)
+		return &d
+	}
+
+	tests := []struct {
+		name    string
+		delta   *decimal.Decimal
+		setTo   *decimal.Decimal
+		wantErr bool
+	}{
+		{"whole delta", dec("-3"), nil, false},
+		{"fractional delta", dec("1.5"), nil, true},
+		{"whole level", nil, dec("12"), false},
+		{"fractional level", nil, dec("0.25"), true},
+		{"out of range", dec("3000000000"), nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := variantStockError(tt.delta, tt.setTo); (got != "") != tt.wantErr {
+				t.Errorf("variantStockError() = %q, wantErr %v", got, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAPIPagination(t *testing.T) {
+	tests := []struct {
+		query     string
+		wantPage  int
+		wantLimit int
+	}{
+		{"", 1, 20},
+		{"page=3&limit=50", 3, 50},
+		{"page=0&limit=-1", 1, 20},
+		{"limit=1000", 1, 250},
+		{"page=abc", 1, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "/admin/api/v1/orders?"+tt.query, nil)
+			page, limit := apiPagination(r)
+			if page != tt.wantPage || limit != tt.wantLimit {
+				t.Errorf("got page %d limit %d, want page %d limit %d", page, limit, tt.wantPage, tt.wantLimit)
+			}
+		})
+	}
+}
+
+func TestDecodeAPIRequest_RejectsUnknownFields(t *testing.T) {
+	r := httptest.NewRequest(http.MethodPost, "/admin/api/v1/orders/1/status", strings.NewReader(`{"status":"shipped","extra":1}`))
+	rr := httptest.NewRecorder()
+
+	var req updateOrderStatusRequest
+	if decodeAPIRequest(rr, r, &req) {
+		t.Fatal("expected decode to fail")
+	}
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("status: got %d, want 400", rr.Code)
+	}
+}
+
+// --------------------------------------------------------------------------
+// Route tests: scopes are checked before any service is used, so a handler
+// without services is enough.
+// --------------------------------------------------------------------------
+
+func TestAPIHandler_RequiresScope(t *testing.T) {
+	mux := http.NewServeMux()
+	NewAPIHandler(nil, nil, nil, nil, nil, nil, slog.Default()).RegisterRoutes(mux)
+
+	id := uuid.New().String()
+	tests := []struct {
+		method string
+		path   string
+		scope  string
+	}{
+		{http.MethodGet, "/admin/api/v1/products", auth.PermProductsRead},
+		{http.MethodGet, "/admin/api/v1/variants/sku/ABC-1", auth.PermProductsRead},
+		{http.MethodPatch, "/admin/api/v1/variants/" + id, auth.PermProductsWrite},
+		{http.MethodPost, "/admin/api/v1/variants/" + id + "/stock", auth.PermInventoryWrite},
+		{http.MethodGet, "/admin/api/v1/orders/1001", auth.PermOrdersRead},
+		{http.MethodPost, "/admin/api/v1/orders/1001/status", auth.PermOrdersWrite},
+		{http.MethodPut, "/admin/api/v1/orders/1001/tracking", auth.PermOrdersWrite},
+		{http.MethodGet, "/admin/api/v1/raw-materials", auth.PermInventoryRead},
+		{http.MethodPost, "/admin/api/v1/raw-materials/" + id + "/stock", auth.PermInventoryWrite},
+	}
+
+	// A key holding every scope except the one the route needs.
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.path, func(t *testing.T) {
+			var scopes []string
+			for _, s := range auth.APIScopes {
+				if s != tt.scope {
+					scopes = append(scopes, s)
+				}
+			}
+			key := &auth.APIKey{ID: uuid.New(), Scopes: scopes}
+
+			r := httptest.NewRequest(tt.method, tt.path, nil)
+			r = r.WithContext(auth.ContextWithAPIKey(r.Context(), key))
+			rr := httptest.NewRecorder()
+			mux.ServeHTTP(rr, r)
+
+			if rr.Code != http.StatusForbidden {
+				t.Fatalf("status: got %d, want 403", rr.Code)
+			}
+			var body map[string]string
+			json.NewDecoder(rr.Body).Decode(&body)
+			if !strings.Contains(body["error"], tt.scope) {
+				t.Errorf("error %q does not name scope %s", body["error"], tt.scope)
+			}
+		})
+	}
+}
+
+func TestAPIHandler_UnknownRoute(t *testing.T) {
+	mux := http.NewServeMux()
+	NewAPIHandler(nil, nil, nil, nil, nil, nil, slog.Default()).RegisterRoutes(mux)
+
+	r := httptest.NewRequest(http.MethodGet, "/admin/api/v1/customers", nil)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, r)
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("status: got %d, want 404", rr.Code)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+		t.Errorf("content type: got %q, want JSON", ct)
+	}
+}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/forgecommerce/api/internal/auth"
 	"github.com/forgecommerce/api/internal/middleware"
 	"github.com/forgecommerce/api/internal/services/attribute"
 	"github.com/forgecommerce/api/internal/services/product"
@@ -32,11 +33,11 @@ func NewAttributeHandler(attributes *attribute.Service, products *product.Servic
 
 // RegisterRoutes registers product attribute admin routes on the given mux.
 func (h *AttributeHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/products/{id}/attributes", h.ShowAttributes)
-	mux.HandleFunc("POST /admin/products/{id}/attributes", h.AddAttribute)
-	mux.HandleFunc("POST /admin/products/{id}/attributes/{attrId}/delete", h.DeleteAttribute)
-	mux.HandleFunc("POST /admin/products/{id}/attributes/{attrId}/options", h.AddOption)
-	mux.HandleFunc("POST /admin/products/{id}/attributes/{attrId}/options/{optId}/delete", h.DeleteOption)
+	mux.Handle("GET /admin/products/{id}/attributes", requires(auth.PermProductsWrite, h.ShowAttributes))
+	mux.Handle("POST /admin/products/{id}/attributes", requires(auth.PermProductsWrite, h.AddAttribute))
+	mux.Handle("POST /admin/products/{id}/attributes/{attrId}/delete", requires(auth.PermProductsWrite, h.DeleteAttribute))
+	mux.Handle("POST /admin/products/{id}/attributes/{attrId}/options", requires(auth.PermProductsWrite, h.AddOption))
+	mux.Handle("POST /admin/products/{id}/attributes/{attrId}/options/{optId}/delete", requires(auth.PermProductsWrite, h.DeleteOption))
 }
 
 // ShowAttributes handles GET /admin/products/{id}/attributes.
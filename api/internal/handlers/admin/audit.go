@@ -0,0 +1,229 @@
+package admin
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/forgecommerce/api/internal/auth"
+	"github.com/forgecommerce/api/internal/services/audit"
+	admin "github.com/forgecommerce/api/templates/admin"
+)
+
+// AuditHandler handles the admin audit log viewer.
+type AuditHandler struct {
+	audit   *audit.Service
+	authSvc *auth.Service
+	logger  *slog.Logger
+}
+
+// NewAuditHandler creates a new audit log handler.
+func NewAuditHandler(auditSvc *audit.Service, authSvc *auth.Service, logger *slog.Logger) *AuditHandler {
+	return &AuditHandler{
+		audit:   auditSvc,
+		authSvc: authSvc,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes registers audit log routes on the given mux.
+func (h *AuditHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.Handle("GET /admin/audit", requires(auth.PermAuditRead, h.ListEntries))
+	mux.Handle("GET /admin/audit/csv", requires(auth.PermAuditRead, h.ExportCSV))
+}
+
+// ListEntries handles GET /admin/audit.
+func (h *AuditHandler) ListEntries(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	form := auditFilterForm(r)
+
+	page := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+
+	entries, total, err := h.audit.List(ctx, auditFilter(form), page, defaultPageSize)
+	if err != nil {
+		h.logger.Error("failed to list audit log", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	users, err := h.authSvc.ListUsers(ctx)
+	if err != nil {
+		h.logger.Error("failed to list users for audit filter", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	actions, err := h.audit.Actions(ctx)
+	if err != nil {
+		h.logger.Error("failed to list audit actions", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	entityTypes, err := h.audit.EntityTypes(ctx)
+	if err != nil {
+		h.logger.Error("failed to list audit entity types", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	totalPages := int((total + int64(defaultPageSize) - 1) / int64(defaultPageSize))
+	if totalPages < 1 {
+		totalPages = 1
+	}
+
+	userOptions := make([]admin.AuditUserOption, 0, len(users))
+	for _, u := range users {
+		userOptions = append(userOptions, admin.AuditUserOption{
+			ID:   u.ID.String(),
+			Name: u.Name,
+		})
+	}
+
+	items := make([]admin.AuditEntryItem, 0, len(entries))
+	for _, e := range entries {
+		items = append(items, auditEntryItem(e))
+	}
+
+	data := admin.AuditLogData{
+		Entries:      items,
+		Filter:       form,
+		FilterQuery:  auditFilterQuery(form),
+		Users:        userOptions,
+		Actions:      actions,
+		EntityTypes:  entityTypes,
+		CurrentPage:  page,
+		TotalPages:   totalPages,
+		TotalEntries: int(total),
+	}
+
+	admin.AuditLogPage(data).Render(ctx, w)
+}
+
+// ExportCSV handles GET /admin/audit/csv. It exports every entry matching
+// the filter with one row per changed field.
+func (h *AuditHandler) ExportCSV(w http.ResponseWriter, r *http.Request) {
+	form := auditFilterForm(r)
+	filename := fmt.Sprintf("audit-log-%s.csv", time.Now().UTC().Format("2006-01-02"))
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	csvWriter := csv.NewWriter(w)
+	defer csvWriter.Flush()
+
+	// Header row.
+	csvWriter.Write([]string{"created_at", "user", "email", "action", "entity_type", "entity_id", "ip_address", "field", "before", "after"})
+
+	err := h.audit.Export(r.Context(), auditFilter(form), func(e audit.Entry) error {
+		row := []string{
+			e.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+			e.AdminName,
+			e.AdminEmail,
+			e.Action,
+			e.EntityType,
+			e.EntityID,
+			e.IPAddress,
+		}
+		fields := e.Fields()
+		if len(fields) == 0 {
+			return csvWriter.Write(append(row, "", "", ""))
+		}
+		for _, f := range fields {
+			if err := csvWriter.Write(append(row[:len(row):len(row)], f.Field, f.Before, f.After)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// Headers are already sent; the truncated file is the best we can do.
+		h.logger.Error("failed to export audit log", "error", err)
+	}
+}
+
+// auditFilterForm reads the audit log filter from the query string.
+func auditFilterForm(r *http.Request) admin.AuditFilterForm {
+	q := r.URL.Query()
+	return admin.AuditFilterForm{
+		UserID:     q.Get("user"),
+		EntityType: q.Get("entity_type"),
+		EntityID:   q.Get("entity_id"),
+		Action:     q.Get("action"),
+		From:       q.Get("from"),
+		To:         q.Get("to"),
+	}
+}
+
+// auditFilter converts the filter form to a service filter. Invalid values
+// are ignored; the "to" date is inclusive.
+func auditFilter(form admin.AuditFilterForm) audit.Filter {
+	f := audit.Filter{
+		EntityType: form.EntityType,
+		EntityID:   form.EntityID,
+		Action:     form.Action,
+	}
+	if id, err := uuid.Parse(form.UserID); err == nil {
+		f.AdminUserID = id
+	}
+	if from, err := time.Parse("2006-01-02", form.From); err == nil {
+		f.From = from
+	}
+	if to, err := time.Parse("2006-01-02", form.To); err == nil {
+		f.To = to.AddDate(0, 0, 1)
+	}
+	return f
+}
+
+// auditFilterQuery encodes the non-empty filter values for pagination and
+// export links.
+func auditFilterQuery(form admin.AuditFilterForm) string {
+	q := url.Values{}
+	for k, v := range map[string]string{
+		"user":        form.UserID,
+		"entity_type": form.EntityType,
+		"entity_id":   form.EntityID,
+		"action":      form.Action,
+		"from":        form.From,
+		"to":          form.To,
+	} {
+		if v != "" {
+			q.Set(k, v)
+		}
+	}
+	return q.Encode()
+}
+
+func auditEntryItem(e audit.Entry) admin.AuditEntryItem {
+	item := admin.AuditEntryItem{
+		ID:         e.ID.String(),
+		UserName:   e.AdminName,
+		UserEmail:  e.AdminEmail,
+		Action:     e.Action,
+		EntityType: e.EntityType,
+		EntityID:   e.EntityID,
+		IPAddress:  e.IPAddress,
+		CreatedAt:  e.CreatedAt.Format("2006-01-02 15:04:05"),
+	}
+	if item.UserName == "" {
+		item.UserName = "System"
+	}
+	for _, f := range e.Fields() {
+		item.Changes = append(item.Changes, admin.AuditFieldChange{
+			Field:  f.Field,
+			Before: f.Before,
+			After:  f.After,
+			IsDiff: f.IsDiff,
+		})
+	}
+	return item
+}
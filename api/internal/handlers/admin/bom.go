@@ -9,6 +9,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 
+	"github.com/forgecommerce/api/internal/auth"
 	"github.com/forgecommerce/api/internal/middleware"
 	"github.com/forgecommerce/api/internal/services/bom"
 	"github.com/forgecommerce/api/internal/services/product"
@@ -19,11 +20,11 @@ import (
 
 // BOMHandler handles admin product BOM (Bill of Materials) endpoints.
 type BOMHandler struct {
-	bom        *bom.Service
-	products   *product.Service
-	materials  *rawmaterial.Service
-	variants   *variant.Service
-	logger     *slog.Logger
+	bom       *bom.Service
+	products  *product.Service
+	materials *rawmaterial.Service
+	variants  *variant.Service
+	logger    *slog.Logger
 }
 
 // NewBOMHandler creates a new BOM handler.
@@ -39,11 +40,12 @@ func NewBOMHandler(bomSvc *bom.Service, products *product.Service, materials *ra
 
 // RegisterRoutes registers BOM admin routes on the given mux.
 func (h *BOMHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/products/{id}/bom", h.ShowBOM)
-	mux.HandleFunc("POST /admin/products/{id}/bom/entries", h.AddEntry)
-	mux.HandleFunc("POST /admin/products/{id}/bom/entries/{entryId}/delete", h.DeleteEntry)
-	mux.HandleFunc("POST /admin/products/{id}/bom/overrides", h.AddOverride)
-	mux.HandleFunc("POST /admin/products/{id}/bom/overrides/{overrideId}/delete", h.DeleteOverride)
+	mux.Handle("GET /admin/products/{id}/bom", requires(auth.PermProductsRead, h.ShowBOM))
+	mux.Handle("GET /admin/products/{id}/bom/resolved", requires(auth.PermProductsRead, h.ResolvedBOM))
+	mux.Handle("POST /admin/products/{id}/bom/entries", requires(auth.PermProductsWrite, h.AddEntry))
+	mux.Handle("POST /admin/products/{id}/bom/entries/{entryId}/delete", requires(auth.PermProductsWrite, h.DeleteEntry))
+	mux.Handle("POST /admin/products/{id}/bom/overrides", requires(auth.PermProductsWrite, h.AddOverride))
+	mux.Handle("POST /admin/products/{id}/bom/overrides/{overrideId}/delete", requires(auth.PermProductsWrite, h.DeleteOverride))
 }
 
 // ShowBOM handles GET /admin/products/{id}/bom.
@@ -152,19 +154,73 @@ func (h *BOMHandler) ShowBOM(w http.ResponseWriter, r *http.Request) {
 		})
 	}
 
+	// Resolve the final BOM of every variant for the producibility preview.
+	resolved, err := h.bom.ResolveProduct(ctx, productID)
+	if err != nil {
+		h.logger.Error("failed to resolve product BOM", "error", err, "product_id", productID)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	data := admin.ProductBOMData{
-		ProductID:   productID.String(),
-		ProductName: p.Name,
-		Entries:     entryItems,
-		Overrides:   overrideItems,
-		Materials:   materialItems,
-		Variants:    variantItems,
-		CSRFToken:   csrfToken,
+		ProductID:     productID.String(),
+		ProductName:   p.Name,
+		Entries:       entryItems,
+		Overrides:     overrideItems,
+		Materials:     materialItems,
+		Variants:      variantItems,
+		Producibility: resolvedBOMItems(resolved),
+		CSRFToken:     csrfToken,
 	}
 
 	admin.ProductBOMPage(data).Render(ctx, w)
 }
 
+// ResolvedBOM handles GET /admin/products/{id}/bom/resolved.
+// Returns the resolved BOM and producibility of every variant as JSON, or of a
+// single variant when the variant_id query parameter is set.
+func (h *BOMHandler) ResolvedBOM(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	productID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid product ID"})
+		return
+	}
+
+	if raw := r.URL.Query().Get("variant_id"); raw != "" {
+		variantID, err := uuid.Parse(raw)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid variant ID"})
+			return
+		}
+		resolved, err := h.bom.ResolveVariant(ctx, variantID)
+		if err != nil {
+			if errors.Is(err, bom.ErrVariantNotFound) {
+				writeJSON(w, http.StatusNotFound, map[string]string{"error": "variant not found"})
+				return
+			}
+			h.logger.Error("failed to resolve variant BOM", "error", err, "variant_id", variantID)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+			return
+		}
+		if resolved.ProductID != productID {
+			writeJSON(w, http.StatusNotFound, map[string]string{"error": "variant not found"})
+			return
+		}
+		writeJSON(w, http.StatusOK, resolved)
+		return
+	}
+
+	resolved, err := h.bom.ResolveProduct(ctx, productID)
+	if err != nil {
+		h.logger.Error("failed to resolve product BOM", "error", err, "product_id", productID)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "internal server error"})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"variants": resolved})
+}
+
 // AddEntry handles POST /admin/products/{id}/bom/entries.
 // Returns an HTMX fragment (table row) for appending.
 func (h *BOMHandler) AddEntry(w http.ResponseWriter, r *http.Request) {
@@ -368,6 +424,38 @@ func (h *BOMHandler) DeleteOverride(w http.ResponseWriter, r *http.Request) {
 
 // --- Helpers ---
 
+// resolvedBOMItems converts resolved variant BOMs into template items.
+func resolvedBOMItems(resolved []bom.ResolvedBOM) []admin.BOMProducibilityItem {
+	items := make([]admin.BOMProducibilityItem, 0, len(resolved))
+	for _, r := range resolved {
+		item := admin.BOMProducibilityItem{
+			VariantID:  r.VariantID.String(),
+			VariantSKU: r.SKU,
+			HasBOM:     r.HasMaterials(),
+			Producible: r.Producible,
+			UnitCost:   r.UnitCost.StringFixed(2),
+		}
+		if b, ok := r.Bottleneck(); ok {
+			item.BottleneckName = b.Name
+		}
+		for _, m := range r.Materials {
+			item.Materials = append(item.Materials, admin.BOMResolvedMaterialItem{
+				Name:            m.Name,
+				SKU:             m.SKU,
+				Quantity:        m.Quantity.String(),
+				UnitOfMeasure:   m.UnitOfMeasure,
+				Source:          m.Source,
+				IsRequired:      m.IsRequired,
+				Stock:           m.StockQuantity.String(),
+				ProducibleUnits: m.ProducibleUnits,
+				IsBottleneck:    r.BottleneckMaterialID != nil && *r.BottleneckMaterialID == m.RawMaterialID,
+			})
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
 // formatPgUUID formats a pgtype.UUID as a string, returning "" if invalid.
 func formatPgUUID(u pgtype.UUID) string {
 	if !u.Valid {
@@ -0,0 +1,282 @@
+package admin
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/forgecommerce/api/internal/middleware"
+	"github.com/forgecommerce/api/internal/services/audit"
+	"github.com/forgecommerce/api/internal/services/discount"
+	"github.com/forgecommerce/api/templates/admin"
+)
+
+// ListCampaigns handles GET /admin/coupon-campaigns.
+func (h *DiscountHandler) ListCampaigns(w http.ResponseWriter, r *http.Request) {
+	h.renderCampaignList(w, r, admin.CouponCampaignFormItem{}, "")
+}
+
+// GenerateCampaign handles POST /admin/coupon-campaigns.
+// Generates the campaign's codes and redirects to its report.
+func (h *DiscountHandler) GenerateCampaign(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		h.logger.Error("failed to parse campaign form", "error", err)
+		h.renderCampaignList(w, r, admin.CouponCampaignFormItem{}, "Invalid form data.")
+		return
+	}
+
+	form := admin.CouponCampaignFormItem{
+		Name:              strings.TrimSpace(r.FormValue("name")),
+		DiscountID:        r.FormValue("discount_id"),
+		Prefix:            strings.TrimSpace(r.FormValue("prefix")),
+		CodeLength:        strings.TrimSpace(r.FormValue("code_length")),
+		Alphabet:          strings.TrimSpace(r.FormValue("alphabet")),
+		Count:             strings.TrimSpace(r.FormValue("count")),
+		UsageLimitPerCode: strings.TrimSpace(r.FormValue("usage_limit_per_code")),
+	}
+
+	discountID, err := uuid.Parse(form.DiscountID)
+	if err != nil {
+		h.renderCampaignList(w, r, form, "Select a discount.")
+		return
+	}
+	count, err := strconv.Atoi(form.Count)
+	if err != nil {
+		h.renderCampaignList(w, r, form, "Number of codes must be a whole number.")
+		return
+	}
+	codeLength, err := parseOptionalInt(form.CodeLength)
+	if err != nil {
+		h.renderCampaignList(w, r, form, "Random characters must be a whole number.")
+		return
+	}
+	usageLimit, err := parseOptionalInt(form.UsageLimitPerCode)
+	if err != nil {
+		h.renderCampaignList(w, r, form, "Uses per code must be a whole number.")
+		return
+	}
+
+	campaign, err := h.discounts.GenerateCampaign(r.Context(), discount.GenerateCampaignParams{
+		Name:              form.Name,
+		DiscountID:        discountID,
+		Prefix:            form.Prefix,
+		CodeLength:        codeLength,
+		Alphabet:          form.Alphabet,
+		Count:             count,
+		UsageLimitPerCode: int32(usageLimit),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, discount.ErrInvalidCampaign):
+			h.renderCampaignList(w, r, form, campaignErrorMessage(err))
+		case errors.Is(err, discount.ErrNotFound):
+			h.renderCampaignList(w, r, form, "Selected discount does not exist.")
+		default:
+			h.logger.Error("failed to generate coupon campaign", "error", err)
+			h.renderCampaignList(w, r, form, "Failed to generate codes.")
+		}
+		return
+	}
+	recordAudit(h.audit, r, "coupon_campaign.created", "coupon_campaign", campaign.ID.String(), audit.Diff(nil, campaign))
+
+	http.Redirect(w, r, "/admin/coupon-campaigns/"+campaign.ID.String(), http.StatusSeeOther)
+}
+
+// CampaignReport handles GET /admin/coupon-campaigns/{id}.
+func (h *DiscountHandler) CampaignReport(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid campaign ID", http.StatusBadRequest)
+		return
+	}
+
+	report, err := h.discounts.GetCampaignReport(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, discount.ErrCampaignNotFound) {
+			http.Error(w, "Campaign not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get campaign report", "campaign_id", id, "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	c := report.Campaign
+	data := admin.CouponCampaignReportData{
+		ID:                c.ID.String(),
+		Name:              c.Name,
+		DiscountID:        c.DiscountID.String(),
+		DiscountName:      c.DiscountName,
+		Prefix:            c.Prefix,
+		CodeLength:        int(c.CodeLength),
+		Alphabet:          c.Alphabet,
+		CodeCount:         int(c.CodeCount),
+		UsageLimitPerCode: int(c.UsageLimitPerCode),
+		CreatedAt:         c.CreatedAt.Format("2006-01-02 15:04"),
+		RedemptionCount:   int(report.Summary.RedemptionCount),
+		CodesRedeemed:     int(report.Summary.CodesRedeemed),
+		UniqueCustomers:   int(report.Summary.UniqueCustomers),
+		OrderTotal:        formatNumericValue(report.Summary.OrderTotal),
+		DiscountTotal:     formatNumericValue(report.Summary.DiscountTotal),
+		Daily:             make([]admin.CouponCampaignDayItem, 0, len(report.Daily)),
+	}
+	for _, d := range report.Daily {
+		data.Daily = append(data.Daily, admin.CouponCampaignDayItem{
+			Date:            formatDate(d.UsageDate),
+			RedemptionCount: int(d.RedemptionCount),
+			DiscountTotal:   formatNumericValue(d.DiscountTotal),
+		})
+	}
+
+	admin.CouponCampaignReportPage(data).Render(r.Context(), w)
+}
+
+// CampaignCodesCSV handles GET /admin/coupon-campaigns/{id}/codes.csv.
+// Returns a CSV file download of every code in the campaign.
+func (h *DiscountHandler) CampaignCodesCSV(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid campaign ID", http.StatusBadRequest)
+		return
+	}
+
+	campaign, err := h.discounts.GetCampaign(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, discount.ErrCampaignNotFound) {
+			http.Error(w, "Campaign not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get campaign for CSV", "campaign_id", id, "error", err)
+		http.Error(w, "Failed to generate CSV", http.StatusInternalServerError)
+		return
+	}
+
+	codes, err := h.discounts.ListCampaignCodes(r.Context(), id)
+	if err != nil {
+		h.logger.Error("failed to list campaign codes for CSV", "campaign_id", id, "error", err)
+		http.Error(w, "Failed to generate CSV", http.StatusInternalServerError)
+		return
+	}
+
+	filename := fmt.Sprintf("coupon-campaign-%s.csv", campaign.ID.String())
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+
+	csvWriter := csv.NewWriter(w)
+	defer csvWriter.Flush()
+
+	// Header row.
+	csvWriter.Write([]string{"code", "usage_count", "usage_limit", "is_active", "created_at"})
+
+	for _, c := range codes {
+		csvWriter.Write([]string{
+			c.Code,
+			strconv.Itoa(int(c.UsageCount)),
+			formatOptionalInt32(c.UsageLimit),
+			strconv.FormatBool(c.IsActive),
+			c.CreatedAt.Format("2006-01-02T15:04:05Z07:00"),
+		})
+	}
+}
+
+// renderCampaignList renders the campaign list with the generate form. A
+// non-empty errMsg sets a 422 status and is shown above the form.
+func (h *DiscountHandler) renderCampaignList(w http.ResponseWriter, r *http.Request, form admin.CouponCampaignFormItem, errMsg string) {
+	ctx := r.Context()
+
+	page := 1
+	if p := r.URL.Query().Get("page"); p != "" {
+		if parsed, err := strconv.Atoi(p); err == nil && parsed > 0 {
+			page = parsed
+		}
+	}
+
+	limit := int32(defaultPageSize)
+	offset := int32((page - 1) * defaultPageSize)
+
+	// Fetch one extra row to detect if there is a next page.
+	campaigns, err := h.discounts.ListCampaigns(ctx, limit+1, offset)
+	if err != nil {
+		h.logger.Error("failed to list coupon campaigns", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	hasNextPage := len(campaigns) > int(limit)
+	if hasNextPage {
+		campaigns = campaigns[:limit]
+	}
+
+	totalPages := page
+	if hasNextPage {
+		totalPages = page + 1
+	}
+
+	items := make([]admin.CouponCampaignListItem, 0, len(campaigns))
+	for _, c := range campaigns {
+		items = append(items, admin.CouponCampaignListItem{
+			ID:              c.ID.String(),
+			Name:            c.Name,
+			DiscountName:    c.DiscountName,
+			Prefix:          c.Prefix,
+			CodeCount:       int(c.CodeCount),
+			RedemptionCount: int(c.RedemptionCount),
+			CreatedAt:       c.CreatedAt.Format("2006-01-02 15:04"),
+		})
+	}
+
+	// Fetch all discounts for the generate form dropdown.
+	allDiscounts, err := h.discounts.ListDiscounts(ctx, 1000, 0)
+	if err != nil {
+		h.logger.Error("failed to list discounts for campaign form", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	discountRefs := make([]admin.DiscountRefItem, 0, len(allDiscounts))
+	for _, d := range allDiscounts {
+		discountRefs = append(discountRefs, admin.DiscountRefItem{
+			ID:   d.ID.String(),
+			Name: d.Name,
+		})
+	}
+
+	data := admin.CouponCampaignListData{
+		Campaigns:   items,
+		Discounts:   discountRefs,
+		Form:        form,
+		CurrentPage: page,
+		TotalPages:  totalPages,
+		CSRFToken:   middleware.CSRFToken(r),
+		Error:       errMsg,
+	}
+
+	if errMsg != "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
+	admin.CouponCampaignListPage(data).Render(ctx, w)
+}
+
+// campaignErrorMessage turns an ErrInvalidCampaign error into a form message.
+func campaignErrorMessage(err error) string {
+	msg := strings.TrimPrefix(err.Error(), discount.ErrInvalidCampaign.Error()+": ")
+	if msg == "" {
+		return "Invalid campaign settings."
+	}
+	return strings.ToUpper(msg[:1]) + msg[1:] + "."
+}
+
+// parseOptionalInt parses a form string into an int. An empty string is 0,
+// which lets the service apply its default.
+func parseOptionalInt(s string) (int, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return strconv.Atoi(s)
+}
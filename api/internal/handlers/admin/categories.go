@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/forgecommerce/api/internal/auth"
 	"github.com/forgecommerce/api/internal/middleware"
 	"github.com/forgecommerce/api/internal/services/category"
 	"github.com/forgecommerce/api/templates/admin"
@@ -29,12 +30,12 @@ func NewCategoryHandler(categories *category.Service, logger *slog.Logger) *Cate
 
 // RegisterRoutes registers all category admin routes on the given mux.
 func (h *CategoryHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/categories", h.ListCategories)
-	mux.HandleFunc("GET /admin/categories/new", h.ShowNewCategory)
-	mux.HandleFunc("POST /admin/categories", h.CreateCategory)
-	mux.HandleFunc("GET /admin/categories/{id}", h.ShowEditCategory)
-	mux.HandleFunc("POST /admin/categories/{id}", h.UpdateCategory)
-	mux.HandleFunc("POST /admin/categories/{id}/delete", h.DeleteCategory)
+	mux.Handle("GET /admin/categories", requires(auth.PermProductsWrite, h.ListCategories))
+	mux.Handle("GET /admin/categories/new", requires(auth.PermProductsWrite, h.ShowNewCategory))
+	mux.Handle("POST /admin/categories", requires(auth.PermProductsWrite, h.CreateCategory))
+	mux.Handle("GET /admin/categories/{id}", requires(auth.PermProductsWrite, h.ShowEditCategory))
+	mux.Handle("POST /admin/categories/{id}", requires(auth.PermProductsWrite, h.UpdateCategory))
+	mux.Handle("POST /admin/categories/{id}/delete", requires(auth.PermProductsWrite, h.DeleteCategory))
 }
 
 // ListCategories renders the category list page.
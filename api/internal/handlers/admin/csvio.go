@@ -12,6 +12,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 
+	"github.com/forgecommerce/api/internal/auth"
 	"github.com/forgecommerce/api/internal/services/order"
 	"github.com/forgecommerce/api/internal/services/product"
 	"github.com/forgecommerce/api/internal/services/rawmaterial"
@@ -45,12 +46,12 @@ func NewCSVIOHandler(
 
 // RegisterRoutes registers CSV import/export admin routes on the given mux.
 func (h *CSVIOHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/export/products/csv", h.ExportProductsCSV)
-	mux.HandleFunc("GET /admin/export/raw-materials/csv", h.ExportRawMaterialsCSV)
-	mux.HandleFunc("GET /admin/export/orders/csv", h.ExportOrdersCSV)
-	mux.HandleFunc("POST /admin/import/products", h.ImportProducts)
-	mux.HandleFunc("POST /admin/import/raw-materials", h.ImportRawMaterials)
-	mux.HandleFunc("GET /admin/import", h.ImportPage)
+	mux.Handle("GET /admin/export/products/csv", requires(auth.PermProductsRead, h.ExportProductsCSV))
+	mux.Handle("GET /admin/export/raw-materials/csv", requires(auth.PermInventoryRead, h.ExportRawMaterialsCSV))
+	mux.Handle("GET /admin/export/orders/csv", requires(auth.PermOrdersRead, h.ExportOrdersCSV))
+	mux.Handle("POST /admin/import/products", requires(auth.PermProductsWrite, h.ImportProducts))
+	mux.Handle("POST /admin/import/raw-materials", requires(auth.PermInventoryWrite, h.ImportRawMaterials))
+	mux.Handle("GET /admin/import", requires(auth.PermProductsWrite, h.ImportPage))
 }
 
 // --- CSV Export: Products ---
@@ -9,6 +9,7 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/forgecommerce/api/internal/auth"
 	db "github.com/forgecommerce/api/internal/database/gen"
 	admintmpl "github.com/forgecommerce/api/templates/admin"
 )
@@ -32,11 +33,11 @@ func NewDashboardHandler(pool *pgxpool.Pool, queries *db.Queries, logger *slog.L
 // RegisterRoutes registers dashboard routes on the given mux.
 func (h *DashboardHandler) RegisterRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /admin/dashboard", h.ShowDashboard)
-	mux.HandleFunc("GET /admin/dashboard/stats/orders-today", h.StatOrdersToday)
-	mux.HandleFunc("GET /admin/dashboard/stats/revenue-month", h.StatRevenueMonth)
-	mux.HandleFunc("GET /admin/dashboard/stats/low-stock", h.StatLowStock)
-	mux.HandleFunc("GET /admin/dashboard/stats/pending-orders", h.StatPendingOrders)
-	mux.HandleFunc("GET /admin/dashboard/recent-orders", h.RecentOrders)
+	mux.Handle("GET /admin/dashboard/stats/orders-today", requires(auth.PermOrdersRead, h.StatOrdersToday))
+	mux.Handle("GET /admin/dashboard/stats/revenue-month", requires(auth.PermReportsRead, h.StatRevenueMonth))
+	mux.Handle("GET /admin/dashboard/stats/low-stock", requires(auth.PermInventoryRead, h.StatLowStock))
+	mux.Handle("GET /admin/dashboard/stats/pending-orders", requires(auth.PermOrdersRead, h.StatPendingOrders))
+	mux.Handle("GET /admin/dashboard/recent-orders", requires(auth.PermOrdersRead, h.RecentOrders))
 }
 
 // ShowDashboard handles GET /admin/dashboard.
@@ -1,6 +1,8 @@
 package admin
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -12,7 +14,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 
+	"github.com/forgecommerce/api/internal/auth"
 	"github.com/forgecommerce/api/internal/middleware"
+	"github.com/forgecommerce/api/internal/services/audit"
 	"github.com/forgecommerce/api/internal/services/discount"
 	"github.com/forgecommerce/api/templates/admin"
 )
@@ -20,27 +24,33 @@ import (
 // DiscountHandler handles admin discount and coupon management endpoints.
 type DiscountHandler struct {
 	discounts *discount.Service
+	audit     *audit.Service
 	logger    *slog.Logger
 }
 
 // NewDiscountHandler creates a new discount handler.
-func NewDiscountHandler(discounts *discount.Service, logger *slog.Logger) *DiscountHandler {
+func NewDiscountHandler(discounts *discount.Service, auditSvc *audit.Service, logger *slog.Logger) *DiscountHandler {
 	return &DiscountHandler{
 		discounts: discounts,
+		audit:     auditSvc,
 		logger:    logger,
 	}
 }
 
 // RegisterRoutes registers discount and coupon admin routes on the given mux.
 func (h *DiscountHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/discounts", h.ListDiscounts)
-	mux.HandleFunc("GET /admin/discounts/new", h.NewDiscount)
-	mux.HandleFunc("POST /admin/discounts", h.CreateDiscount)
-	mux.HandleFunc("GET /admin/discounts/{id}", h.EditDiscount)
-	mux.HandleFunc("POST /admin/discounts/{id}", h.UpdateDiscount)
-	mux.HandleFunc("GET /admin/coupons", h.ListCoupons)
-	mux.HandleFunc("POST /admin/coupons", h.CreateCoupon)
-	mux.HandleFunc("POST /admin/coupons/{id}/delete", h.DeleteCoupon)
+	mux.Handle("GET /admin/discounts", requires(auth.PermDiscountsManage, h.ListDiscounts))
+	mux.Handle("GET /admin/discounts/new", requires(auth.PermDiscountsManage, h.NewDiscount))
+	mux.Handle("POST /admin/discounts", requires(auth.PermDiscountsManage, h.CreateDiscount))
+	mux.Handle("GET /admin/discounts/{id}", requires(auth.PermDiscountsManage, h.EditDiscount))
+	mux.Handle("POST /admin/discounts/{id}", requires(auth.PermDiscountsManage, h.UpdateDiscount))
+	mux.Handle("GET /admin/coupons", requires(auth.PermDiscountsManage, h.ListCoupons))
+	mux.Handle("POST /admin/coupons", requires(auth.PermDiscountsManage, h.CreateCoupon))
+	mux.Handle("POST /admin/coupons/{id}/delete", requires(auth.PermDiscountsManage, h.DeleteCoupon))
+	mux.Handle("GET /admin/coupon-campaigns", requires(auth.PermDiscountsManage, h.ListCampaigns))
+	mux.Handle("POST /admin/coupon-campaigns", requires(auth.PermDiscountsManage, h.GenerateCampaign))
+	mux.Handle("GET /admin/coupon-campaigns/{id}", requires(auth.PermDiscountsManage, h.CampaignReport))
+	mux.Handle("GET /admin/coupon-campaigns/{id}/codes.csv", requires(auth.PermDiscountsManage, h.CampaignCodesCSV))
 }
 
 // ListDiscounts handles GET /admin/discounts.
@@ -155,14 +165,20 @@ func (h *DiscountHandler) CreateDiscount(w http.ResponseWriter, r *http.Request)
 		IsActive:        r.FormValue("is_active") == "true",
 		Priority:        parseInt32(r.FormValue("priority")),
 		Stackable:       r.FormValue("stackable") == "true",
+		Conditions:      formConditions(r.FormValue("conditions")),
 	}
 
-	_, err := h.discounts.CreateDiscount(r.Context(), params)
+	created, err := h.discounts.CreateDiscount(r.Context(), params)
 	if err != nil {
+		if errors.Is(err, discount.ErrInvalidConditions) {
+			h.renderDiscountFormWithError(w, r, discountFormDataFromRequest(r, csrfToken, false), conditionsErrorMessage(err))
+			return
+		}
 		h.logger.Error("failed to create discount", "error", err)
 		h.renderDiscountFormWithError(w, r, discountFormDataFromRequest(r, csrfToken, false), "Failed to create discount.")
 		return
 	}
+	recordAudit(h.audit, r, "discount.created", "discount", created.ID.String(), audit.Diff(nil, created))
 
 	http.Redirect(w, r, "/admin/discounts", http.StatusSeeOther)
 }
@@ -202,6 +218,7 @@ func (h *DiscountHandler) EditDiscount(w http.ResponseWriter, r *http.Request) {
 			IsActive:        d.IsActive,
 			Priority:        fmt.Sprintf("%d", d.Priority),
 			Stackable:       d.Stackable,
+			Conditions:      formatConditions(d.Conditions),
 		},
 		IsEdit:    true,
 		CSRFToken: csrfToken,
@@ -242,6 +259,17 @@ func (h *DiscountHandler) UpdateDiscount(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	before, err := h.discounts.GetDiscount(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, discount.ErrNotFound) {
+			http.Error(w, "Discount not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get discount", "error", err, "discount_id", id)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	params := discount.UpdateDiscountParams{
 		Name:            name,
 		Type:            r.FormValue("type"),
@@ -254,20 +282,26 @@ func (h *DiscountHandler) UpdateDiscount(w http.ResponseWriter, r *http.Request)
 		IsActive:        r.FormValue("is_active") == "true",
 		Priority:        parseInt32(r.FormValue("priority")),
 		Stackable:       r.FormValue("stackable") == "true",
+		Conditions:      formConditions(r.FormValue("conditions")),
 	}
 
-	_, err = h.discounts.UpdateDiscount(r.Context(), id, params)
+	updated, err := h.discounts.UpdateDiscount(r.Context(), id, params)
 	if err != nil {
 		if errors.Is(err, discount.ErrNotFound) {
 			http.Error(w, "Discount not found", http.StatusNotFound)
 			return
 		}
-		h.logger.Error("failed to update discount", "error", err, "discount_id", id)
 		formData := discountFormDataFromRequest(r, csrfToken, true)
 		formData.Discount.ID = id.String()
+		if errors.Is(err, discount.ErrInvalidConditions) {
+			h.renderDiscountFormWithError(w, r, formData, conditionsErrorMessage(err))
+			return
+		}
+		h.logger.Error("failed to update discount", "error", err, "discount_id", id)
 		h.renderDiscountFormWithError(w, r, formData, "Failed to update discount.")
 		return
 	}
+	recordAudit(h.audit, r, "discount.updated", "discount", id.String(), audit.Diff(before, updated))
 
 	http.Redirect(w, r, "/admin/discounts", http.StatusSeeOther)
 }
@@ -391,6 +425,7 @@ func (h *DiscountHandler) CreateCoupon(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to create coupon", http.StatusInternalServerError)
 		return
 	}
+	recordAudit(h.audit, r, "coupon.created", "coupon", coupon.ID.String(), audit.Diff(nil, coupon))
 
 	// Look up the discount name for the response row.
 	d, err := h.discounts.GetDiscount(ctx, discountID)
@@ -426,6 +461,17 @@ func (h *DiscountHandler) DeleteCoupon(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := h.discounts.GetCoupon(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, discount.ErrCouponNotFound) {
+			http.Error(w, "Coupon not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get coupon", "coupon_id", id, "error", err)
+		http.Error(w, "Failed to delete coupon", http.StatusInternalServerError)
+		return
+	}
+
 	if err := h.discounts.DeleteCoupon(r.Context(), id); err != nil {
 		h.logger.Error("failed to delete coupon", "coupon_id", id, "error", err)
 		http.Error(w, "Failed to delete coupon", http.StatusInternalServerError)
@@ -433,6 +479,7 @@ func (h *DiscountHandler) DeleteCoupon(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.logger.Info("coupon deleted", "coupon_id", id)
+	recordAudit(h.audit, r, "coupon.deleted", "coupon", id.String(), audit.Diff(before, nil))
 
 	// Return empty 200 OK so the HTMX hx-swap="outerHTML" removes the row.
 	w.Header().Set("Content-Type", "text/html")
@@ -464,12 +511,39 @@ func discountFormDataFromRequest(r *http.Request, csrfToken string, isEdit bool)
 			IsActive:        r.FormValue("is_active") == "true",
 			Priority:        r.FormValue("priority"),
 			Stackable:       r.FormValue("stackable") == "true",
+			Conditions:      r.FormValue("conditions"),
 		},
 		IsEdit:    isEdit,
 		CSRFToken: csrfToken,
 	}
 }
 
+// formConditions returns the conditions JSON submitted in the discount form,
+// or nil if the field is blank.
+func formConditions(s string) json.RawMessage {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil
+	}
+	return json.RawMessage(s)
+}
+
+// formatConditions indents stored conditions for the form. An empty object
+// is shown as a blank field.
+func formatConditions(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil || buf.String() == "{}" {
+		return ""
+	}
+	return buf.String()
+}
+
+// conditionsErrorMessage turns a conditions validation error into a form
+// error message.
+func conditionsErrorMessage(err error) string {
+	return "Conditions: " + strings.TrimPrefix(err.Error(), discount.ErrInvalidConditions.Error()+": ") + "."
+}
+
 // parseTimestamptzForm parses a datetime-local input value (2006-01-02T15:04)
 // into a pgtype.Timestamptz. Returns an invalid Timestamptz if the string is
 // empty or cannot be parsed.
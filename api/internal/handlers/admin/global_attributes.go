@@ -13,6 +13,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 
+	"github.com/forgecommerce/api/internal/auth"
 	db "github.com/forgecommerce/api/internal/database/gen"
 	"github.com/forgecommerce/api/internal/middleware"
 	"github.com/forgecommerce/api/internal/services/globalattr"
@@ -40,26 +41,26 @@ func NewGlobalAttributeHandler(globalAttrs *globalattr.Service, products *produc
 // RegisterRoutes registers global attribute admin routes on the given mux.
 func (h *GlobalAttributeHandler) RegisterRoutes(mux *http.ServeMux) {
 	// Global Attribute CRUD
-	mux.HandleFunc("GET /admin/global-attributes", h.List)
-	mux.HandleFunc("GET /admin/global-attributes/new", h.NewForm)
-	mux.HandleFunc("POST /admin/global-attributes", h.Create)
-	mux.HandleFunc("GET /admin/global-attributes/{id}", h.Edit)
-	mux.HandleFunc("POST /admin/global-attributes/{id}", h.Update)
-	mux.HandleFunc("POST /admin/global-attributes/{id}/delete", h.Delete)
+	mux.Handle("GET /admin/global-attributes", requires(auth.PermProductsWrite, h.List))
+	mux.Handle("GET /admin/global-attributes/new", requires(auth.PermProductsWrite, h.NewForm))
+	mux.Handle("POST /admin/global-attributes", requires(auth.PermProductsWrite, h.Create))
+	mux.Handle("GET /admin/global-attributes/{id}", requires(auth.PermProductsWrite, h.Edit))
+	mux.Handle("POST /admin/global-attributes/{id}", requires(auth.PermProductsWrite, h.Update))
+	mux.Handle("POST /admin/global-attributes/{id}/delete", requires(auth.PermProductsWrite, h.Delete))
 
 	// Metadata Fields
-	mux.HandleFunc("POST /admin/global-attributes/{id}/fields", h.AddField)
-	mux.HandleFunc("POST /admin/global-attributes/{id}/fields/{fieldId}/delete", h.DeleteField)
+	mux.Handle("POST /admin/global-attributes/{id}/fields", requires(auth.PermProductsWrite, h.AddField))
+	mux.Handle("POST /admin/global-attributes/{id}/fields/{fieldId}/delete", requires(auth.PermProductsWrite, h.DeleteField))
 
 	// Options
-	mux.HandleFunc("POST /admin/global-attributes/{id}/options", h.AddOption)
-	mux.HandleFunc("POST /admin/global-attributes/{id}/options/{optId}/delete", h.DeleteOption)
+	mux.Handle("POST /admin/global-attributes/{id}/options", requires(auth.PermProductsWrite, h.AddOption))
+	mux.Handle("POST /admin/global-attributes/{id}/options/{optId}/delete", requires(auth.PermProductsWrite, h.DeleteOption))
 
 	// Product Links
-	mux.HandleFunc("GET /admin/products/{id}/global-attributes", h.ShowProductLinks)
-	mux.HandleFunc("POST /admin/products/{id}/global-attributes", h.CreateProductLink)
-	mux.HandleFunc("POST /admin/products/{id}/global-attributes/{linkId}/delete", h.DeleteProductLink)
-	mux.HandleFunc("POST /admin/products/{id}/global-attributes/{linkId}/selections", h.SaveSelections)
+	mux.Handle("GET /admin/products/{id}/global-attributes", requires(auth.PermProductsWrite, h.ShowProductLinks))
+	mux.Handle("POST /admin/products/{id}/global-attributes", requires(auth.PermProductsWrite, h.CreateProductLink))
+	mux.Handle("POST /admin/products/{id}/global-attributes/{linkId}/delete", requires(auth.PermProductsWrite, h.DeleteProductLink))
+	mux.Handle("POST /admin/products/{id}/global-attributes/{linkId}/selections", requires(auth.PermProductsWrite, h.SaveSelections))
 }
 
 // ---------------------------------------------------------------------------
@@ -11,6 +11,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 
+	"github.com/forgecommerce/api/internal/auth"
 	db "github.com/forgecommerce/api/internal/database/gen"
 	"github.com/forgecommerce/api/internal/middleware"
 	"github.com/forgecommerce/api/internal/services/media"
@@ -36,13 +37,13 @@ func NewImageHandler(mediaSvc *media.Service, variantSvc *variant.Service, logge
 
 // RegisterRoutes registers product image admin routes on the given mux.
 func (h *ImageHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/products/{id}/images", h.ShowImages)
-	mux.HandleFunc("POST /admin/products/{id}/images/upload", h.UploadImage)
-	mux.HandleFunc("POST /admin/products/{id}/images/{imageId}/primary", h.SetPrimary)
-	mux.HandleFunc("POST /admin/products/{id}/images/{imageId}/delete", h.DeleteImage)
-	mux.HandleFunc("POST /admin/products/{id}/images/reorder", h.ReorderImages)
-	mux.HandleFunc("POST /admin/products/{id}/images/{imageId}/alt", h.UpdateAltText)
-	mux.HandleFunc("POST /admin/products/{id}/images/{imageId}/assign", h.AssignVariant)
+	mux.Handle("GET /admin/products/{id}/images", requires(auth.PermProductsWrite, h.ShowImages))
+	mux.Handle("POST /admin/products/{id}/images/upload", requires(auth.PermProductsWrite, h.UploadImage))
+	mux.Handle("POST /admin/products/{id}/images/{imageId}/primary", requires(auth.PermProductsWrite, h.SetPrimary))
+	mux.Handle("POST /admin/products/{id}/images/{imageId}/delete", requires(auth.PermProductsWrite, h.DeleteImage))
+	mux.Handle("POST /admin/products/{id}/images/reorder", requires(auth.PermProductsWrite, h.ReorderImages))
+	mux.Handle("POST /admin/products/{id}/images/{imageId}/alt", requires(auth.PermProductsWrite, h.UpdateAltText))
+	mux.Handle("POST /admin/products/{id}/images/{imageId}/assign", requires(auth.PermProductsWrite, h.AssignVariant))
 }
 
 // ShowImages handles GET /admin/products/{id}/images.
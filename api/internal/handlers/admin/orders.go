@@ -11,32 +11,44 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
 
+	"github.com/forgecommerce/api/internal/auth"
+	db "github.com/forgecommerce/api/internal/database/gen"
 	"github.com/forgecommerce/api/internal/middleware"
+	"github.com/forgecommerce/api/internal/services/invoice"
 	"github.com/forgecommerce/api/internal/services/order"
+	"github.com/forgecommerce/api/internal/services/refund"
 	"github.com/forgecommerce/api/templates/admin"
 )
 
 // OrderHandler handles admin order management endpoints.
 type OrderHandler struct {
-	orders *order.Service
-	logger *slog.Logger
+	orders   *order.Service
+	refunds  *refund.Service
+	invoices *invoice.Service
+	logger   *slog.Logger
 }
 
 // NewOrderHandler creates a new order handler.
-func NewOrderHandler(orders *order.Service, logger *slog.Logger) *OrderHandler {
+func NewOrderHandler(orders *order.Service, refunds *refund.Service, invoices *invoice.Service, logger *slog.Logger) *OrderHandler {
 	return &OrderHandler{
-		orders: orders,
-		logger: logger,
+		orders:   orders,
+		refunds:  refunds,
+		invoices: invoices,
+		logger:   logger,
 	}
 }
 
 // RegisterRoutes registers order admin routes on the given mux.
 func (h *OrderHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/orders", h.ListOrders)
-	mux.HandleFunc("GET /admin/orders/{id}", h.ShowOrder)
-	mux.HandleFunc("POST /admin/orders/{id}/status", h.UpdateStatus)
-	mux.HandleFunc("POST /admin/orders/{id}/tracking", h.UpdateTracking)
+	mux.Handle("GET /admin/orders", requires(auth.PermOrdersRead, h.ListOrders))
+	mux.Handle("GET /admin/orders/{id}", requires(auth.PermOrdersRead, h.ShowOrder))
+	mux.Handle("POST /admin/orders/{id}/status", requires(auth.PermOrdersWrite, h.UpdateStatus))
+	mux.Handle("POST /admin/orders/{id}/tracking", requires(auth.PermOrdersWrite, h.UpdateTracking))
+	mux.Handle("POST /admin/orders/{id}/refunds", requires(auth.PermOrdersRefund, h.CreateRefund))
+	mux.Handle("GET /admin/orders/{id}/invoice", requires(auth.PermOrdersRead, h.DownloadInvoice))
+	mux.Handle("POST /admin/orders/{id}/invoice", requires(auth.PermOrdersWrite, h.IssueInvoice))
 }
 
 // ListOrders handles GET /admin/orders.
@@ -98,14 +110,21 @@ func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
 
 // ShowOrder handles GET /admin/orders/{id}.
 func (h *OrderHandler) ShowOrder(w http.ResponseWriter, r *http.Request) {
-	csrfToken := middleware.CSRFToken(r)
-
 	id, err := uuid.Parse(r.PathValue("id"))
 	if err != nil {
 		http.Error(w, "Invalid order ID", http.StatusBadRequest)
 		return
 	}
 
+	h.renderOrder(w, r, id, "", "")
+}
+
+// renderOrder renders the order detail page. refundErr is shown on the
+// refunds card after a rejected refund, invoiceErr on the invoice card after
+// an invoice could not be issued.
+func (h *OrderHandler) renderOrder(w http.ResponseWriter, r *http.Request, id uuid.UUID, refundErr, invoiceErr string) {
+	csrfToken := middleware.CSRFToken(r)
+
 	o, err := h.orders.Get(r.Context(), id)
 	if err != nil {
 		if errors.Is(err, order.ErrNotFound) {
@@ -131,10 +150,26 @@ func (h *OrderHandler) ShowOrder(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	refunds, err := h.refunds.ListForOrder(r.Context(), id)
+	if err != nil {
+		h.logger.Error("failed to list order refunds", "error", err, "order_id", id)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	refundedQty, err := h.refunds.RefundedQuantities(r.Context(), id)
+	if err != nil {
+		h.logger.Error("failed to sum refunded quantities", "error", err, "order_id", id)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	// Build order items for the template.
 	orderItems := make([]admin.OrderDetailItemRow, 0, len(items))
 	for _, item := range items {
 		orderItems = append(orderItems, admin.OrderDetailItemRow{
+			ID:             item.ID.String(),
+			RefundableQty:  int(item.Quantity - refundedQty[item.ID]),
 			ProductName:    item.ProductName,
 			VariantName:    derefString(item.VariantName),
 			SKU:            derefString(item.Sku),
@@ -187,14 +222,223 @@ func (h *OrderHandler) ShowOrder(w http.ResponseWriter, r *http.Request) {
 			CustomerNotes:     derefString(o.CustomerNotes),
 			CreatedAt:         o.CreatedAt.Format("2006-01-02 15:04"),
 		},
-		Items:     orderItems,
-		Events:    orderEvents,
-		CSRFToken: csrfToken,
+		Items:        orderItems,
+		Events:       orderEvents,
+		NextStatuses: order.NextStatuses(o),
+		RefundError:  refundErr,
+		InvoiceError: invoiceErr,
+		CSRFToken:    csrfToken,
 	}
 
+	for _, rf := range refunds {
+		data.Refunds = append(data.Refunds, h.refundItem(r, rf))
+	}
+
+	inv, err := h.invoices.GetForOrder(r.Context(), id)
+	switch {
+	case err == nil:
+		data.Invoice = &admin.OrderInvoiceItem{
+			Number:   inv.InvoiceNumber,
+			IssuedAt: inv.IssuedAt.Format("2006-01-02 15:04"),
+		}
+	case errors.Is(err, invoice.ErrNotFound):
+		data.CanIssueInvoice = o.PaymentStatus != order.PaymentUnpaid
+	default:
+		h.logger.Error("failed to get invoice", "error", err, "order_id", id)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	remaining, err := h.refunds.Remaining(r.Context(), o)
+	if err != nil {
+		h.logger.Error("failed to compute refundable amount", "error", err, "order_id", id)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	data.RefundRemaining = remaining.StringFixed(2)
+	data.CanRefund = (o.PaymentStatus == order.PaymentPaid || o.PaymentStatus == order.PaymentPartiallyRefunded) &&
+		o.StripePaymentIntentID != nil && remaining.IsPositive()
+
+	if refundErr != "" || invoiceErr != "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+	}
 	admin.OrderDetailPage(data).Render(r.Context(), w)
 }
 
+// refundItem converts a refund and its lines for display.
+func (h *OrderHandler) refundItem(r *http.Request, rf db.Refund) admin.OrderRefundItem {
+	item := admin.OrderRefundItem{
+		CreatedAt:      rf.CreatedAt.Format("2006-01-02 15:04"),
+		Status:         rf.Status,
+		Source:         rf.Source,
+		Amount:         formatNumeric(rf.Amount),
+		NetAmount:      formatNumeric(rf.NetAmount),
+		VatAmount:      formatNumeric(rf.VatAmount),
+		Reason:         derefString(rf.Reason),
+		Restocked:      rf.Restocked,
+		FailureMessage: derefString(rf.FailureMessage),
+	}
+	// Pending and failed refunds have no credit note number.
+	if rf.CreditNoteNumber != nil {
+		item.CreditNoteNumber = fmt.Sprintf("CN-%d", *rf.CreditNoteNumber)
+	}
+
+	lines, err := h.refunds.ListItems(r.Context(), rf.ID)
+	if err != nil {
+		h.logger.Error("failed to list refund items", "error", err, "refund_id", rf.ID)
+		return item
+	}
+	for _, l := range lines {
+		item.Lines = append(item.Lines, admin.OrderRefundLine{
+			Description: l.Description,
+			Quantity:    int(l.Quantity),
+			Amount:      formatNumeric(l.Amount),
+			VatRate:     formatNumeric(l.VatRate),
+			VatAmount:   formatNumeric(l.VatAmount),
+		})
+	}
+	return item
+}
+
+// CreateRefund handles POST /admin/orders/{id}/refunds. Quantities arrive as
+// qty_<order item ID> fields; when none are set, amount is refunded instead.
+func (h *OrderHandler) CreateRefund(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		h.logger.Error("failed to parse form", "error", err)
+		http.Error(w, "Invalid form data", http.StatusBadRequest)
+		return
+	}
+
+	params := refund.CreateParams{
+		OrderID:   id,
+		Reason:    strings.TrimSpace(r.FormValue("reason")),
+		Restock:   r.FormValue("restock") == "true",
+		CreatedBy: adminUserRef(r),
+	}
+
+	for key, values := range r.PostForm {
+		itemIDStr, ok := strings.CutPrefix(key, "qty_")
+		if !ok || len(values) == 0 || strings.TrimSpace(values[0]) == "" {
+			continue
+		}
+		itemID, err := uuid.Parse(itemIDStr)
+		if err != nil {
+			h.renderOrder(w, r, id, "Invalid order item.", "")
+			return
+		}
+		qty, err := strconv.Atoi(strings.TrimSpace(values[0]))
+		if err != nil || qty < 0 {
+			h.renderOrder(w, r, id, "Quantities must be whole numbers.", "")
+			return
+		}
+		if qty > 0 {
+			params.Items = append(params.Items, refund.ItemLine{OrderItemID: itemID, Quantity: int32(qty)})
+		}
+	}
+
+	if len(params.Items) == 0 {
+		amountStr := strings.TrimSpace(r.FormValue("amount"))
+		if amountStr == "" {
+			h.renderOrder(w, r, id, "Choose items to refund or enter an amount.", "")
+			return
+		}
+		amount, err := decimal.NewFromString(amountStr)
+		if err != nil {
+			h.renderOrder(w, r, id, "Invalid refund amount.", "")
+			return
+		}
+		params.Amount = amount
+	}
+
+	if _, err := h.refunds.Create(r.Context(), params); err != nil {
+		switch {
+		case errors.Is(err, refund.ErrOrderNotFound):
+			http.Error(w, "Order not found", http.StatusNotFound)
+		case errors.Is(err, refund.ErrNotRefundable),
+			errors.Is(err, refund.ErrNoPayment),
+			errors.Is(err, refund.ErrInvalidAmount),
+			errors.Is(err, refund.ErrExceedsRemaining),
+			errors.Is(err, refund.ErrInvalidItem),
+			errors.Is(err, refund.ErrNoVATBasis),
+			errors.Is(err, refund.ErrAlreadyRestocked),
+			errors.Is(err, refund.ErrGateway):
+			h.logger.Warn("refund rejected", "error", err, "order_id", id)
+			h.renderOrder(w, r, id, err.Error(), "")
+		default:
+			h.logger.Error("failed to refund order", "error", err, "order_id", id)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	http.Redirect(w, r, "/admin/orders/"+id.String(), http.StatusSeeOther)
+}
+
+// IssueInvoice handles POST /admin/orders/{id}/invoice. Paid orders are
+// invoiced automatically; this covers orders paid while the store details
+// were incomplete or storage was unavailable.
+func (h *OrderHandler) IssueInvoice(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	if _, err := h.invoices.Issue(r.Context(), id); err != nil {
+		switch {
+		case errors.Is(err, invoice.ErrNotFound):
+			http.Error(w, "Order not found", http.StatusNotFound)
+		case errors.Is(err, invoice.ErrNotPaid),
+			errors.Is(err, invoice.ErrNoItems),
+			errors.Is(err, invoice.ErrSellerDetails),
+			errors.Is(err, invoice.ErrNoStorage):
+			h.logger.Warn("invoice rejected", "error", err, "order_id", id)
+			h.renderOrder(w, r, id, "", err.Error())
+		default:
+			h.logger.Error("failed to issue invoice", "error", err, "order_id", id)
+			h.renderOrder(w, r, id, "", "The invoice could not be issued. Please try again.")
+		}
+		return
+	}
+
+	http.Redirect(w, r, "/admin/orders/"+id.String(), http.StatusSeeOther)
+}
+
+// DownloadInvoice handles GET /admin/orders/{id}/invoice by redirecting to a
+// short-lived link to the PDF.
+func (h *OrderHandler) DownloadInvoice(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid order ID", http.StatusBadRequest)
+		return
+	}
+
+	inv, err := h.invoices.GetForOrder(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, invoice.ErrNotFound) {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get invoice", "error", err, "order_id", id)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	url, err := h.invoices.DownloadURL(r.Context(), inv)
+	if err != nil {
+		h.logger.Error("failed to sign invoice download", "error", err, "invoice_number", inv.InvoiceNumber)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, url, http.StatusFound)
+}
+
 // UpdateStatus handles POST /admin/orders/{id}/status.
 func (h *OrderHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 	id, err := uuid.Parse(r.PathValue("id"))
@@ -221,12 +465,52 @@ func (h *OrderHandler) UpdateStatus(w http.ResponseWriter, r *http.Request) {
 			http.Error(w, "Order not found", http.StatusNotFound)
 			return
 		}
+		var transitionErr *order.TransitionError
+		if errors.As(err, &transitionErr) {
+			h.renderStatusError(w, r, id, transitionErr)
+			return
+		}
 		h.logger.Error("failed to update order status", "error", err, "order_id", id, "new_status", newStatus)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
 
-	http.Redirect(w, r, "/admin/orders/"+id.String(), http.StatusSeeOther)
+	// The whole page changes (badge, timeline, actions), so reload it.
+	orderURL := "/admin/orders/" + id.String()
+	if r.Header.Get("HX-Request") == "true" {
+		w.Header().Set("HX-Redirect", orderURL)
+		return
+	}
+	http.Redirect(w, r, orderURL, http.StatusSeeOther)
+}
+
+// renderStatusError shows a rejected status change to the operator. HTMX
+// requests get the actions card back with the error; plain form posts get a
+// 422 with the message.
+func (h *OrderHandler) renderStatusError(w http.ResponseWriter, r *http.Request, id uuid.UUID, transitionErr *order.TransitionError) {
+	if r.Header.Get("HX-Request") != "true" {
+		http.Error(w, transitionErr.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	o, err := h.orders.Get(r.Context(), id)
+	if err != nil {
+		h.logger.Error("failed to get order", "error", err, "order_id", id)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := admin.OrderDetailData{
+		Order: admin.OrderDetailItem{
+			ID:             o.ID.String(),
+			Status:         o.Status,
+			TrackingNumber: derefString(o.TrackingNumber),
+		},
+		NextStatuses: order.NextStatuses(o),
+		StatusError:  transitionErr.Error(),
+		CSRFToken:    middleware.CSRFToken(r),
+	}
+	admin.OrderActionsCard(data).Render(r.Context(), w)
 }
 
 // UpdateTracking handles POST /admin/orders/{id}/tracking.
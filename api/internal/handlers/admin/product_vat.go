@@ -12,6 +12,7 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/forgecommerce/api/internal/auth"
 	db "github.com/forgecommerce/api/internal/database/gen"
 	"github.com/forgecommerce/api/internal/middleware"
 	"github.com/forgecommerce/api/internal/services/product"
@@ -37,10 +38,10 @@ func NewProductVATHandler(products *product.Service, pool *pgxpool.Pool, logger
 
 // RegisterRoutes registers product VAT admin routes on the given mux.
 func (h *ProductVATHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/products/{id}/vat", h.ShowProductVAT)
-	mux.HandleFunc("POST /admin/products/{id}/vat", h.UpdateProductVATCategory)
-	mux.HandleFunc("POST /admin/products/{id}/vat/overrides", h.AddOverride)
-	mux.HandleFunc("POST /admin/products/{id}/vat/overrides/{overrideId}/delete", h.DeleteOverride)
+	mux.Handle("GET /admin/products/{id}/vat", requires(auth.PermProductsWrite, h.ShowProductVAT))
+	mux.Handle("POST /admin/products/{id}/vat", requires(auth.PermProductsWrite, h.UpdateProductVATCategory))
+	mux.Handle("POST /admin/products/{id}/vat/overrides", requires(auth.PermProductsWrite, h.AddOverride))
+	mux.Handle("POST /admin/products/{id}/vat/overrides/{overrideId}/delete", requires(auth.PermProductsWrite, h.DeleteOverride))
 }
 
 // ShowProductVAT handles GET /admin/products/{id}/vat.
@@ -167,6 +168,7 @@ func (h *ProductVATHandler) UpdateProductVATCategory(w http.ResponseWriter, r *h
 		BaseDimensionsMm:        p.BaseDimensionsMm,
 		ShippingExtraFeePerUnit: p.ShippingExtraFeePerUnit,
 		HasVariants:             p.HasVariants,
+		AllowBackorder:          p.AllowBackorder,
 		SeoTitle:                p.SeoTitle,
 		SeoDescription:          p.SeoDescription,
 		Metadata:                p.Metadata,
@@ -5,10 +5,12 @@ import (
 	"log/slog"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 
+	"github.com/forgecommerce/api/internal/auth"
 	db "github.com/forgecommerce/api/internal/database/gen"
 	"github.com/forgecommerce/api/internal/middleware"
 	"github.com/forgecommerce/api/internal/services/product"
@@ -34,13 +36,13 @@ func NewProductionHandler(productionSvc *production.Service, productSvc *product
 
 // RegisterRoutes registers production admin routes on the given mux.
 func (h *ProductionHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/production", h.ListBatches)
-	mux.HandleFunc("GET /admin/production/new", h.NewBatch)
-	mux.HandleFunc("POST /admin/production", h.CreateBatch)
-	mux.HandleFunc("GET /admin/production/{id}", h.ShowBatch)
-	mux.HandleFunc("POST /admin/production/{id}/start", h.StartBatch)
-	mux.HandleFunc("POST /admin/production/{id}/complete", h.CompleteBatch)
-	mux.HandleFunc("POST /admin/production/{id}/cancel", h.CancelBatch)
+	mux.Handle("GET /admin/production", requires(auth.PermInventoryRead, h.ListBatches))
+	mux.Handle("GET /admin/production/new", requires(auth.PermInventoryWrite, h.NewBatch))
+	mux.Handle("POST /admin/production", requires(auth.PermInventoryWrite, h.CreateBatch))
+	mux.Handle("GET /admin/production/{id}", requires(auth.PermInventoryRead, h.ShowBatch))
+	mux.Handle("POST /admin/production/{id}/start", requires(auth.PermInventoryWrite, h.StartBatch))
+	mux.Handle("POST /admin/production/{id}/complete", requires(auth.PermInventoryWrite, h.CompleteBatch))
+	mux.Handle("POST /admin/production/{id}/cancel", requires(auth.PermInventoryWrite, h.CancelBatch))
 }
 
 // ListBatches handles GET /admin/production.
@@ -138,14 +140,24 @@ func (h *ProductionHandler) CreateBatch(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	var createdBy pgtype.UUID
+	if userID, ok := middleware.AdminUserIDFromContext(r.Context()); ok {
+		createdBy = pgtype.UUID{Bytes: userID, Valid: true}
+	}
+
 	batch, err := h.production.CreateBatch(r.Context(), production.CreateBatchParams{
 		ProductID:     productID,
 		VariantID:     variantID,
 		PlannedQty:    qty,
 		ScheduledDate: r.FormValue("scheduled_date"),
 		Notes:         r.FormValue("notes"),
+		CreatedBy:     createdBy,
 	})
 	if err != nil {
+		if errors.Is(err, production.ErrVariantMismatch) {
+			http.Error(w, "Variant does not belong to the selected product", http.StatusBadRequest)
+			return
+		}
 		h.logger.Error("failed to create production batch", "error", err)
 		http.Error(w, "Failed to create batch", http.StatusInternalServerError)
 		return
@@ -273,11 +285,44 @@ func (h *ProductionHandler) CompleteBatch(w http.ResponseWriter, r *http.Request
 		}
 	}
 
-	if _, err := h.production.Complete(r.Context(), id, actualQty, costTotal); err != nil {
+	// Optional per-material consumed quantities, submitted as consumed_{lineID}.
+	consumed := make(map[uuid.UUID]pgtype.Numeric)
+	for key, values := range r.PostForm {
+		lineID, ok := strings.CutPrefix(key, "consumed_")
+		if !ok || len(values) == 0 || strings.TrimSpace(values[0]) == "" {
+			continue
+		}
+		parsedID, err := uuid.Parse(lineID)
+		if err != nil {
+			continue
+		}
+		qty := parseNumeric(values[0])
+		if !qty.Valid {
+			http.Error(w, "Invalid consumed quantity", http.StatusBadRequest)
+			return
+		}
+		consumed[parsedID] = qty
+	}
+
+	var completedBy pgtype.UUID
+	if userID, ok := middleware.AdminUserIDFromContext(r.Context()); ok {
+		completedBy = pgtype.UUID{Bytes: userID, Valid: true}
+	}
+
+	if _, err := h.production.Complete(r.Context(), id, production.CompleteParams{
+		ActualQty:   actualQty,
+		CostTotal:   costTotal,
+		Consumed:    consumed,
+		CompletedBy: completedBy,
+	}); err != nil {
 		if errors.Is(err, production.ErrInvalidStatus) {
 			http.Error(w, "Batch cannot be completed from its current status", http.StatusBadRequest)
 			return
 		}
+		if errors.Is(err, production.ErrInsufficientMaterial) {
+			http.Error(w, err.Error(), http.StatusConflict)
+			return
+		}
 		h.logger.Error("failed to complete production batch", "error", err, "batch_id", id)
 		http.Error(w, "Failed to complete batch", http.StatusInternalServerError)
 		return
@@ -11,8 +11,10 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 
+	"github.com/forgecommerce/api/internal/auth"
 	db "github.com/forgecommerce/api/internal/database/gen"
 	"github.com/forgecommerce/api/internal/middleware"
+	"github.com/forgecommerce/api/internal/services/audit"
 	"github.com/forgecommerce/api/internal/services/category"
 	"github.com/forgecommerce/api/internal/services/product"
 	"github.com/forgecommerce/api/templates/admin"
@@ -24,26 +26,28 @@ const defaultPageSize = 20
 type ProductHandler struct {
 	products   *product.Service
 	categories *category.Service
+	audit      *audit.Service
 	logger     *slog.Logger
 }
 
 // NewProductHandler creates a new product handler.
-func NewProductHandler(products *product.Service, categories *category.Service, logger *slog.Logger) *ProductHandler {
+func NewProductHandler(products *product.Service, categories *category.Service, auditSvc *audit.Service, logger *slog.Logger) *ProductHandler {
 	return &ProductHandler{
 		products:   products,
 		categories: categories,
+		audit:      auditSvc,
 		logger:     logger,
 	}
 }
 
 // RegisterRoutes registers product admin routes on the given mux.
 func (h *ProductHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/products", h.ListProducts)
-	mux.HandleFunc("GET /admin/products/new", h.ShowNewProduct)
-	mux.HandleFunc("POST /admin/products", h.CreateProduct)
-	mux.HandleFunc("GET /admin/products/{id}", h.ShowEditProduct)
-	mux.HandleFunc("POST /admin/products/{id}", h.UpdateProduct)
-	mux.HandleFunc("POST /admin/products/{id}/delete", h.DeleteProduct)
+	mux.Handle("GET /admin/products", requires(auth.PermProductsRead, h.ListProducts))
+	mux.Handle("GET /admin/products/new", requires(auth.PermProductsWrite, h.ShowNewProduct))
+	mux.Handle("POST /admin/products", requires(auth.PermProductsWrite, h.CreateProduct))
+	mux.Handle("GET /admin/products/{id}", requires(auth.PermProductsRead, h.ShowEditProduct))
+	mux.Handle("POST /admin/products/{id}", requires(auth.PermProductsWrite, h.UpdateProduct))
+	mux.Handle("POST /admin/products/{id}/delete", requires(auth.PermProductsWrite, h.DeleteProduct))
 }
 
 // ListProducts handles GET /admin/products.
@@ -142,6 +146,7 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		CompareAtPrice:   parseNumeric(r.FormValue("compare_at_price")),
 		BaseWeightGrams:  parseInt32(r.FormValue("weight_grams")),
 		HasVariants:      r.FormValue("has_variants") == "on",
+		AllowBackorder:   r.FormValue("allow_backorder") == "true",
 		SeoTitle:         strPtr(r.FormValue("seo_title")),
 		SeoDescription:   strPtr(r.FormValue("seo_description")),
 	}
@@ -156,6 +161,7 @@ func (h *ProductHandler) CreateProduct(w http.ResponseWriter, r *http.Request) {
 		h.renderFormWithError(w, r, formDataFromRequest(r, csrfToken, true), msg)
 		return
 	}
+	recordAudit(h.audit, r, "product.created", "product", created.ID.String(), audit.Diff(nil, created))
 
 	http.Redirect(w, r, "/admin/products/"+created.ID.String()+"?created=1", http.StatusSeeOther)
 }
@@ -212,6 +218,17 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := h.products.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, product.ErrNotFound) {
+			http.Error(w, "Product not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get product", "error", err, "product_id", id)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	params := product.UpdateProductParams{
 		Name:             name,
 		Slug:             strings.TrimSpace(r.FormValue("slug")),
@@ -223,6 +240,7 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		CompareAtPrice:   parseNumeric(r.FormValue("compare_at_price")),
 		BaseWeightGrams:  parseInt32(r.FormValue("weight_grams")),
 		HasVariants:      r.FormValue("has_variants") == "on",
+		AllowBackorder:   r.FormValue("allow_backorder") == "true",
 		SeoTitle:         strPtr(r.FormValue("seo_title")),
 		SeoDescription:   strPtr(r.FormValue("seo_description")),
 	}
@@ -241,6 +259,7 @@ func (h *ProductHandler) UpdateProduct(w http.ResponseWriter, r *http.Request) {
 		h.renderFormWithError(w, r, formData, msg)
 		return
 	}
+	recordAudit(h.audit, r, "product.updated", "product", id.String(), audit.Diff(before, updated))
 
 	data := productToFormData(updated, csrfToken)
 	admin.ProductFormPage(data).Render(r.Context(), w)
@@ -254,6 +273,17 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := h.products.Get(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, product.ErrNotFound) {
+			http.Error(w, "Product not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get product", "error", err, "product_id", id)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	if err := h.products.Delete(r.Context(), id); err != nil {
 		if errors.Is(err, product.ErrNotFound) {
 			http.Error(w, "Product not found", http.StatusNotFound)
@@ -263,6 +293,7 @@ func (h *ProductHandler) DeleteProduct(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	recordAudit(h.audit, r, "product.deleted", "product", id.String(), audit.Diff(before, nil))
 
 	http.Redirect(w, r, "/admin/products", http.StatusSeeOther)
 }
@@ -289,6 +320,7 @@ func productToFormData(p db.Product, csrfToken string) admin.ProductFormData {
 		BasePrice:        formatNumeric(p.BasePrice),
 		CompareAtPrice:   formatNumeric(p.CompareAtPrice),
 		HasVariants:      p.HasVariants,
+		AllowBackorder:   p.AllowBackorder,
 		WeightGrams:      formatInt32(p.BaseWeightGrams),
 		SEOTitle:         derefString(p.SeoTitle),
 		SEODescription:   derefString(p.SeoDescription),
@@ -310,6 +342,7 @@ func formDataFromRequest(r *http.Request, csrfToken string, isNew bool) admin.Pr
 		BasePrice:        r.FormValue("base_price"),
 		CompareAtPrice:   r.FormValue("compare_at_price"),
 		HasVariants:      r.FormValue("has_variants") == "on",
+		AllowBackorder:   r.FormValue("allow_backorder") == "true",
 		WeightGrams:      r.FormValue("weight_grams"),
 		SEOTitle:         r.FormValue("seo_title"),
 		SEODescription:   r.FormValue("seo_description"),
@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/forgecommerce/api/internal/auth"
 	db "github.com/forgecommerce/api/internal/database/gen"
 	"github.com/forgecommerce/api/internal/middleware"
 	"github.com/forgecommerce/api/internal/services/rawmaterial"
@@ -31,12 +32,12 @@ func NewRawMaterialHandler(materials *rawmaterial.Service, logger *slog.Logger)
 
 // RegisterRoutes registers all raw material admin routes on the given mux.
 func (h *RawMaterialHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/inventory/raw-materials", h.ListRawMaterials)
-	mux.HandleFunc("GET /admin/inventory/raw-materials/new", h.ShowNewRawMaterial)
-	mux.HandleFunc("POST /admin/inventory/raw-materials", h.CreateRawMaterial)
-	mux.HandleFunc("GET /admin/inventory/raw-materials/{id}", h.ShowEditRawMaterial)
-	mux.HandleFunc("POST /admin/inventory/raw-materials/{id}", h.UpdateRawMaterial)
-	mux.HandleFunc("POST /admin/inventory/raw-materials/{id}/delete", h.DeleteRawMaterial)
+	mux.Handle("GET /admin/inventory/raw-materials", requires(auth.PermInventoryRead, h.ListRawMaterials))
+	mux.Handle("GET /admin/inventory/raw-materials/new", requires(auth.PermInventoryWrite, h.ShowNewRawMaterial))
+	mux.Handle("POST /admin/inventory/raw-materials", requires(auth.PermInventoryWrite, h.CreateRawMaterial))
+	mux.Handle("GET /admin/inventory/raw-materials/{id}", requires(auth.PermInventoryRead, h.ShowEditRawMaterial))
+	mux.Handle("POST /admin/inventory/raw-materials/{id}", requires(auth.PermInventoryWrite, h.UpdateRawMaterial))
+	mux.Handle("POST /admin/inventory/raw-materials/{id}/delete", requires(auth.PermInventoryWrite, h.DeleteRawMaterial))
 }
 
 // ListRawMaterials handles GET /admin/inventory/raw-materials.
@@ -255,6 +256,7 @@ func (h *RawMaterialHandler) UpdateRawMaterial(w http.ResponseWriter, r *http.Re
 		SupplierSku:       strPtr(r.FormValue("supplier_sku")),
 		LeadTimeDays:      parseInt32Ptr(r.FormValue("lead_time_days")),
 		IsActive:          r.FormValue("is_active") != "",
+		ChangedBy:         adminUserRef(r),
 	}
 
 	material, err := h.materials.Update(r.Context(), id, params)
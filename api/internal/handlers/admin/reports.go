@@ -6,10 +6,12 @@ import (
 	"log/slog"
 	"math/big"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgtype"
 
+	"github.com/forgecommerce/api/internal/auth"
 	"github.com/forgecommerce/api/internal/services/report"
 )
 
@@ -29,12 +31,15 @@ func NewReportHandler(reportSvc *report.Service, logger *slog.Logger) *ReportHan
 
 // RegisterRoutes registers report admin routes on the given mux.
 func (h *ReportHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/reports/sales", h.SalesReportPage)
-	mux.HandleFunc("GET /admin/reports/sales/data", h.SalesReportData)
-	mux.HandleFunc("GET /admin/reports/sales/csv", h.SalesReportCSV)
-	mux.HandleFunc("GET /admin/reports/vat", h.VATReportPage)
-	mux.HandleFunc("GET /admin/reports/vat/data", h.VATReportData)
-	mux.HandleFunc("GET /admin/reports/vat/csv", h.VATReportCSV)
+	mux.Handle("GET /admin/reports/sales", requires(auth.PermReportsRead, h.SalesReportPage))
+	mux.Handle("GET /admin/reports/sales/data", requires(auth.PermReportsRead, h.SalesReportData))
+	mux.Handle("GET /admin/reports/sales/csv", requires(auth.PermReportsRead, h.SalesReportCSV))
+	mux.Handle("GET /admin/reports/vat", requires(auth.PermReportsRead, h.VATReportPage))
+	mux.Handle("GET /admin/reports/vat/data", requires(auth.PermReportsRead, h.VATReportData))
+	mux.Handle("GET /admin/reports/vat/csv", requires(auth.PermReportsRead, h.VATReportCSV))
+	mux.Handle("GET /admin/reports/vat/oss", requires(auth.PermReportsRead, h.OSSReturnData))
+	mux.Handle("GET /admin/reports/vat/oss/xml", requires(auth.PermReportsRead, h.OSSReturnXML))
+	mux.Handle("GET /admin/reports/vat/oss/csv", requires(auth.PermReportsRead, h.OSSReturnCSV))
 }
 
 // parseDateRange extracts "from" and "to" query parameters in YYYY-MM-DD format.
@@ -173,6 +178,13 @@ func (h *ReportHandler) SalesReportData(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	recovery, err := h.reportSvc.GetCartRecoveryReport(r.Context(), from, to)
+	if err != nil {
+		h.logger.Error("failed to get cart recovery report", "error", err)
+		http.Error(w, "Failed to load cart recovery report", http.StatusInternalServerError)
+		return
+	}
+
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 
 	s := salesReport.Summary
@@ -212,6 +224,32 @@ func (h *ReportHandler) SalesReportData(w http.ResponseWriter, r *http.Request)
 		formatNumericValue(s.AverageOrderValue),
 	)
 
+	// Abandoned cart recovery, counted by the date the reminder was sent.
+	fmt.Fprintf(w, `<h2>Cart Recovery</h2>
+<div class="report-summary">
+  <div class="summary-card">
+    <span class="summary-label">Reminders Sent</span>
+    <span class="summary-value">%d</span>
+  </div>
+  <div class="summary-card">
+    <span class="summary-label">Carts Restored</span>
+    <span class="summary-value">%d</span>
+  </div>
+  <div class="summary-card">
+    <span class="summary-label">Orders Recovered</span>
+    <span class="summary-value">%d</span>
+  </div>
+  <div class="summary-card">
+    <span class="summary-label">Recovered Revenue</span>
+    <span class="summary-value">&euro;%s</span>
+  </div>
+</div>`,
+		recovery.EmailsSent,
+		recovery.CartsRestored,
+		recovery.OrdersRecovered,
+		formatNumericValue(recovery.RecoveredRevenue),
+	)
+
 	// Daily breakdown table.
 	fmt.Fprint(w, `<h2>Daily Breakdown</h2>
 <table class="table">
@@ -326,6 +364,7 @@ func (h *ReportHandler) VATReportPage(w http.ResponseWriter, r *http.Request) {
 	if period == "" {
 		period = "monthly"
 	}
+	ossYear, ossQuarter := parseQuarter(r)
 
 	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	fmt.Fprintf(w, `<!DOCTYPE html>
@@ -361,6 +400,26 @@ func (h *ReportHandler) VATReportPage(w http.ResponseWriter, r *http.Request) {
        hx-swap="innerHTML">
     <p>Loading VAT report data...</p>
   </div>
+
+  <h2>OSS Quarterly Return</h2>
+  <form id="oss-filter"
+        hx-get="/admin/reports/vat/oss"
+        hx-target="#oss-return-data"
+        hx-trigger="load, change">
+    <label for="oss-year">Year:</label>
+    <input type="number" id="oss-year" name="year" value="%d" min="2021">
+    <label for="oss-quarter">Quarter:</label>
+    <select id="oss-quarter" name="quarter">
+      <option value="1"%s>Q1</option>
+      <option value="2"%s>Q2</option>
+      <option value="3"%s>Q3</option>
+      <option value="4"%s>Q4</option>
+    </select>
+  </form>
+
+  <div id="oss-return-data">
+    <p>Loading OSS return...</p>
+  </div>
 </div>
 </body>
 </html>`,
@@ -375,6 +434,11 @@ func (h *ReportHandler) VATReportPage(w http.ResponseWriter, r *http.Request) {
 		from.Format("2006-01-02"),
 		displayTo.Format("2006-01-02"),
 		period,
+		ossYear,
+		selectedAttr(strconv.Itoa(ossQuarter), "1"),
+		selectedAttr(strconv.Itoa(ossQuarter), "2"),
+		selectedAttr(strconv.Itoa(ossQuarter), "3"),
+		selectedAttr(strconv.Itoa(ossQuarter), "4"),
 	)
 }
 
@@ -0,0 +1,297 @@
+package admin
+
+import (
+	"encoding/csv"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/forgecommerce/api/internal/services/report"
+)
+
+// parseQuarter reads the "year" and "quarter" query parameters. It defaults
+// to the last completed quarter, the one normally being filed.
+func parseQuarter(r *http.Request) (int, int) {
+	now := time.Now().UTC()
+	year, quarter := now.Year(), int(now.Month()-1)/3
+	if quarter == 0 {
+		year, quarter = year-1, 4
+	}
+
+	if y, err := strconv.Atoi(r.URL.Query().Get("year")); err == nil {
+		year = y
+	}
+	if q, err := strconv.Atoi(r.URL.Query().Get("quarter")); err == nil {
+		quarter = q
+	}
+	return year, quarter
+}
+
+// ossReturn loads the OSS return for the requested quarter and writes an
+// error response if that fails.
+func (h *ReportHandler) ossReturn(w http.ResponseWriter, r *http.Request) (*report.OSSReturn, bool) {
+	year, quarter := parseQuarter(r)
+	ret, err := h.reportSvc.GetOSSReturn(r.Context(), year, quarter)
+	if err != nil {
+		if errors.Is(err, report.ErrInvalidQuarter) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return nil, false
+		}
+		h.logger.Error("failed to get OSS return", "error", err)
+		http.Error(w, "Failed to load OSS return", http.StatusInternalServerError)
+		return nil, false
+	}
+	return ret, true
+}
+
+// OSSReturnData handles GET /admin/reports/vat/oss.
+// Returns an HTML fragment with the OSS return for a quarter and links to
+// export it.
+func (h *ReportHandler) OSSReturnData(w http.ResponseWriter, r *http.Request) {
+	ret, ok := h.ossReturn(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+
+	if ret.StoreCountry == "" || ret.VATNumber == "" {
+		fmt.Fprint(w, `<div class="alert alert-error">Set the store VAT number and country under <a href="/admin/settings/vat">Settings &rarr; VAT</a> before filing.</div>`)
+	}
+
+	fmt.Fprintf(w, `<div class="report-summary">
+  <div class="summary-card">
+    <span class="summary-label">Period</span>
+    <span class="summary-value">%d Q%d</span>
+  </div>
+  <div class="summary-card">
+    <span class="summary-label">Member State of Identification</span>
+    <span class="summary-value">%s</span>
+  </div>
+  <div class="summary-card">
+    <span class="summary-label">OSS VAT Due</span>
+    <span class="summary-value">&euro;%s</span>
+  </div>
+</div>
+<p>
+  <a href="/admin/reports/vat/oss/xml?year=%d&quarter=%d" class="btn btn-secondary">Export OSS XML</a>
+  <a href="/admin/reports/vat/oss/csv?year=%d&quarter=%d" class="btn btn-secondary">Export OSS CSV</a>
+</p>`,
+		ret.Year, ret.Quarter,
+		html.EscapeString(ret.StoreCountry),
+		ret.TotalVAT.StringFixed(2),
+		ret.Year, ret.Quarter,
+		ret.Year, ret.Quarter,
+	)
+
+	fmt.Fprint(w, `<h3>Cross-Border B2C Sales (OSS)</h3>`)
+	writeOSSLines(w, ret.CrossBorder, "No cross-border B2C sales for this quarter.")
+
+	fmt.Fprint(w, `<h3>Corrections of Earlier Quarters</h3>
+<table class="table">
+  <thead>
+    <tr>
+      <th>Country</th>
+      <th>Period</th>
+      <th>VAT</th>
+    </tr>
+  </thead>
+  <tbody>`)
+	if len(ret.Corrections) == 0 {
+		fmt.Fprint(w, `<tr><td colspan="3" class="text-muted">No corrections for this quarter.</td></tr>`)
+	}
+	for _, c := range ret.Corrections {
+		fmt.Fprintf(w, "<tr><td>%s (%s)</td><td>%d Q%d</td><td>&euro;%s</td></tr>",
+			html.EscapeString(c.CountryName), c.CountryCode, c.Year, c.Quarter, c.VAT.StringFixed(2))
+	}
+	fmt.Fprint(w, `</tbody></table>`)
+
+	fmt.Fprint(w, `<h3>Domestic B2C Sales (national return)</h3>`)
+	writeOSSLines(w, ret.Domestic, "No domestic B2C sales for this quarter.")
+}
+
+// writeOSSLines writes a table of OSS lines.
+func writeOSSLines(w http.ResponseWriter, lines []report.OSSLine, empty string) {
+	fmt.Fprint(w, `<table class="table">
+  <thead>
+    <tr>
+      <th>Country</th>
+      <th>Rate Type</th>
+      <th>Rate</th>
+      <th>Net Sales</th>
+      <th>Net Refunds</th>
+      <th>Taxable Amount</th>
+      <th>VAT</th>
+    </tr>
+  </thead>
+  <tbody>`)
+	if len(lines) == 0 {
+		fmt.Fprintf(w, `<tr><td colspan="7" class="text-muted">%s</td></tr>`, empty)
+	}
+	for _, l := range lines {
+		fmt.Fprintf(w,
+			"<tr><td>%s (%s)</td><td>%s</td><td>%s%%</td><td>&euro;%s</td><td>&euro;%s</td><td>&euro;%s</td><td>&euro;%s</td></tr>",
+			html.EscapeString(l.CountryName), l.CountryCode,
+			l.RateType,
+			l.Rate.StringFixed(2),
+			l.SalesNet.StringFixed(2),
+			l.RefundNet.StringFixed(2),
+			l.Taxable.StringFixed(2),
+			l.VAT.StringFixed(2),
+		)
+	}
+	fmt.Fprint(w, `</tbody></table>`)
+}
+
+// OSS return XML, following the data elements of the return in Annex III
+// of Implementing Regulation (EU) 2020/194.
+type ossReturnXML struct {
+	XMLName                     xml.Name            `xml:"OSSReturn"`
+	VATIdentificationNumber     string              `xml:"VATIdentificationNumber"`
+	MemberStateOfIdentification string              `xml:"MemberStateOfIdentification"`
+	Period                      ossPeriodXML        `xml:"Period"`
+	Consumption                 []ossConsumptionXML `xml:"MemberStateOfConsumption"`
+	Corrections                 []ossCorrectionXML  `xml:"Corrections>Correction,omitempty"`
+	TotalVATAmount              string              `xml:"TotalVATAmount"`
+}
+
+type ossPeriodXML struct {
+	Year    int `xml:"Year"`
+	Quarter int `xml:"Quarter"`
+}
+
+type ossConsumptionXML struct {
+	MemberState    string         `xml:"MemberState"`
+	Supplies       []ossSupplyXML `xml:"Supply"`
+	TotalVATAmount string         `xml:"TotalVATAmount"`
+}
+
+type ossSupplyXML struct {
+	SupplyType    string `xml:"SupplyType"`
+	VATRateType   string `xml:"VATRateType"`
+	VATRate       string `xml:"VATRate"`
+	TaxableAmount string `xml:"TaxableAmount"`
+	VATAmount     string `xml:"VATAmount"`
+}
+
+type ossCorrectionXML struct {
+	MemberState string       `xml:"MemberState"`
+	Period      ossPeriodXML `xml:"Period"`
+	VATAmount   string       `xml:"VATAmount"`
+}
+
+// OSSReturnXML handles GET /admin/reports/vat/oss/xml.
+// Returns the OSS return for a quarter as an XML file for upload.
+func (h *ReportHandler) OSSReturnXML(w http.ResponseWriter, r *http.Request) {
+	ret, ok := h.ossReturn(w, r)
+	if !ok {
+		return
+	}
+
+	doc := ossReturnXML{
+		VATIdentificationNumber:     ret.VATNumber,
+		MemberStateOfIdentification: ossMemberState(ret.StoreCountry),
+		Period:                      ossPeriodXML{Year: ret.Year, Quarter: ret.Quarter},
+		TotalVATAmount:              ret.TotalVAT.StringFixed(2),
+	}
+
+	// Lines are sorted by country; group them per member state.
+	var total decimal.Decimal
+	for i, l := range ret.CrossBorder {
+		if i == 0 || l.CountryCode != ret.CrossBorder[i-1].CountryCode {
+			doc.Consumption = append(doc.Consumption, ossConsumptionXML{MemberState: ossMemberState(l.CountryCode)})
+			total = decimal.Zero
+		}
+		c := &doc.Consumption[len(doc.Consumption)-1]
+		c.Supplies = append(c.Supplies, ossSupplyXML{
+			SupplyType:    "GOODS",
+			VATRateType:   ossRateType(l.RateType),
+			VATRate:       l.Rate.StringFixed(2),
+			TaxableAmount: l.Taxable.StringFixed(2),
+			VATAmount:     l.VAT.StringFixed(2),
+		})
+		total = total.Add(l.VAT)
+		c.TotalVATAmount = total.StringFixed(2)
+	}
+	for _, c := range ret.Corrections {
+		doc.Corrections = append(doc.Corrections, ossCorrectionXML{
+			MemberState: ossMemberState(c.CountryCode),
+			Period:      ossPeriodXML{Year: c.Year, Quarter: c.Quarter},
+			VATAmount:   c.VAT.StringFixed(2),
+		})
+	}
+
+	w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", ossFilename(ret, "xml")))
+
+	fmt.Fprint(w, xml.Header)
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		h.logger.Error("failed to write OSS return XML", "error", err)
+	}
+}
+
+// OSSReturnCSV handles GET /admin/reports/vat/oss/csv.
+// Returns the OSS return for a quarter as a CSV file with one row per
+// supply line and per correction.
+func (h *ReportHandler) OSSReturnCSV(w http.ResponseWriter, r *http.Request) {
+	ret, ok := h.ossReturn(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", ossFilename(ret, "csv")))
+
+	csvWriter := csv.NewWriter(w)
+	defer csvWriter.Flush()
+
+	period := fmt.Sprintf("%d-Q%d", ret.Year, ret.Quarter)
+
+	// Header row.
+	csvWriter.Write([]string{
+		"record_type", "vat_number", "period", "member_state", "supply_type",
+		"rate_type", "rate", "taxable_amount", "vat_amount", "corrected_period",
+	})
+
+	for _, l := range ret.CrossBorder {
+		csvWriter.Write([]string{
+			"supply", ret.VATNumber, period, ossMemberState(l.CountryCode), "GOODS",
+			ossRateType(l.RateType), l.Rate.StringFixed(2), l.Taxable.StringFixed(2), l.VAT.StringFixed(2), "",
+		})
+	}
+	for _, c := range ret.Corrections {
+		csvWriter.Write([]string{
+			"correction", ret.VATNumber, period, ossMemberState(c.CountryCode), "",
+			"", "", "", c.VAT.StringFixed(2), fmt.Sprintf("%d-Q%d", c.Year, c.Quarter),
+		})
+	}
+}
+
+func ossFilename(ret *report.OSSReturn, ext string) string {
+	return fmt.Sprintf("oss-return-%d-q%d.%s", ret.Year, ret.Quarter, ext)
+}
+
+// ossMemberState returns the code the OSS uses for a country, which is the
+// ISO code except for Greece.
+func ossMemberState(countryCode string) string {
+	if countryCode == "GR" {
+		return "EL"
+	}
+	return countryCode
+}
+
+// ossRateType maps our VAT rate types to the two the OSS return knows.
+func ossRateType(rateType string) string {
+	if rateType == "standard" {
+		return "STANDARD"
+	}
+	return "REDUCED"
+}
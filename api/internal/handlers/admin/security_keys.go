@@ -0,0 +1,179 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/forgecommerce/api/internal/auth"
+	"github.com/forgecommerce/api/internal/middleware"
+	admin "github.com/forgecommerce/api/templates/admin"
+)
+
+// SecurityKeyHandler lets admin users register and remove their own
+// WebAuthn security keys.
+type SecurityKeyHandler struct {
+	authSvc  *auth.Service
+	webauthn *auth.WebAuthnService
+	logger   *slog.Logger
+}
+
+// NewSecurityKeyHandler creates a new security key handler.
+func NewSecurityKeyHandler(authSvc *auth.Service, webauthnSvc *auth.WebAuthnService, logger *slog.Logger) *SecurityKeyHandler {
+	return &SecurityKeyHandler{
+		authSvc:  authSvc,
+		webauthn: webauthnSvc,
+		logger:   logger,
+	}
+}
+
+// RegisterRoutes registers security key routes on the given mux. Every
+// signed-in user may manage their own keys.
+func (h *SecurityKeyHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /admin/security-keys", h.List)
+	mux.HandleFunc("POST /admin/security-keys/options", h.RegistrationOptions)
+	mux.HandleFunc("POST /admin/security-keys", h.Register)
+	mux.HandleFunc("POST /admin/security-keys/{id}/delete", h.Delete)
+}
+
+// List handles GET /admin/security-keys.
+func (h *SecurityKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.AdminUserIDFromContext(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+		return
+	}
+
+	creds, err := h.webauthn.ListCredentials(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("failed to list security keys", "error", err, "user_id", userID)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := admin.SecurityKeyListData{
+		Keys:      securityKeyItems(creds),
+		CSRFToken: middleware.CSRFToken(r),
+	}
+	switch r.URL.Query().Get("status") {
+	case "added":
+		data.Success = "Security key added."
+	case "removed":
+		data.Success = "Security key removed."
+	}
+
+	admin.SecurityKeyListPage(data).Render(r.Context(), w)
+}
+
+// RegistrationOptions handles POST /admin/security-keys/options. It returns
+// the options for navigator.credentials.create as JSON.
+func (h *SecurityKeyHandler) RegistrationOptions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.AdminUserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Not signed in."})
+		return
+	}
+
+	user, err := h.authSvc.GetUserByID(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("failed to fetch user for security key registration", "error", err, "user_id", userID)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Internal error. Please try again."})
+		return
+	}
+
+	opts, err := h.webauthn.BeginRegistration(r.Context(), user)
+	if err != nil {
+		h.logger.Error("failed to begin security key registration", "error", err, "user_id", userID)
+		writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Internal error. Please try again."})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, opts)
+}
+
+// registerSecurityKeyRequest is the body of POST /admin/security-keys.
+type registerSecurityKeyRequest struct {
+	Name       string                    `json:"name"`
+	Credential auth.RegistrationResponse `json:"credential"`
+}
+
+// Register handles POST /admin/security-keys. It verifies the browser's
+// response to RegistrationOptions and stores the key.
+func (h *SecurityKeyHandler) Register(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.AdminUserIDFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "Not signed in."})
+		return
+	}
+
+	var req registerSecurityKeyRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 64<<10)).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "Invalid request body."})
+		return
+	}
+
+	if _, err := h.webauthn.FinishRegistration(r.Context(), userID, req.Name, &req.Credential); err != nil {
+		switch {
+		case errors.Is(err, auth.ErrInvalidCredentialName),
+			errors.Is(err, auth.ErrWebAuthnCredentialExists),
+			errors.Is(err, auth.ErrWebAuthnNoChallenge):
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": err.Error()})
+		case errors.Is(err, auth.ErrWebAuthnVerification):
+			h.logger.Warn("security key registration failed", "error", err, "user_id", userID)
+			writeJSON(w, http.StatusUnprocessableEntity, map[string]string{"error": "The security key could not be verified. Please try again."})
+		default:
+			h.logger.Error("failed to register security key", "error", err, "user_id", userID)
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "Internal error. Please try again."})
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]string{"redirect": "/admin/security-keys?status=added"})
+}
+
+// Delete handles POST /admin/security-keys/{id}/delete.
+func (h *SecurityKeyHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.AdminUserIDFromContext(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid security key ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.webauthn.DeleteCredential(r.Context(), userID, id); err != nil {
+		if errors.Is(err, auth.ErrWebAuthnCredentialNotFound) {
+			http.Error(w, "Security key not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to delete security key", "error", err, "user_id", userID)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, "/admin/security-keys?status=removed", http.StatusSeeOther)
+}
+
+// securityKeyItems converts security keys for display.
+func securityKeyItems(creds []*auth.WebAuthnCredential) []admin.SecurityKeyItem {
+	items := make([]admin.SecurityKeyItem, 0, len(creds))
+	for _, c := range creds {
+		item := admin.SecurityKeyItem{
+			ID:        c.ID.String(),
+			Name:      c.Name,
+			CreatedAt: c.CreatedAt.Format("2006-01-02 15:04"),
+		}
+		if c.LastUsedAt != nil {
+			item.LastUsedAt = c.LastUsedAt.Format("2006-01-02 15:04")
+		}
+		items = append(items, item)
+	}
+	return items
+}
@@ -0,0 +1,186 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+
+	"github.com/forgecommerce/api/internal/auth"
+	"github.com/forgecommerce/api/internal/middleware"
+	"github.com/forgecommerce/api/internal/services/audit"
+	admin "github.com/forgecommerce/api/templates/admin"
+)
+
+// SessionHandler lets admin users see and end their own sessions.
+type SessionHandler struct {
+	authSvc *auth.Service
+	audit   *audit.Service
+	logger  *slog.Logger
+}
+
+// NewSessionHandler creates a new session handler.
+func NewSessionHandler(authSvc *auth.Service, auditSvc *audit.Service, logger *slog.Logger) *SessionHandler {
+	return &SessionHandler{
+		authSvc: authSvc,
+		audit:   auditSvc,
+		logger:  logger,
+	}
+}
+
+// RegisterRoutes registers session routes on the given mux. Every signed-in
+// user may manage their own sessions.
+func (h *SessionHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /admin/sessions", h.ListSessions)
+	mux.HandleFunc("POST /admin/sessions/revoke-others", h.RevokeOthers)
+	mux.HandleFunc("POST /admin/sessions/{id}/revoke", h.Revoke)
+}
+
+// ListSessions handles GET /admin/sessions.
+func (h *SessionHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.AdminUserIDFromContext(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+		return
+	}
+
+	sessions, err := h.authSvc.ListSessions(r.Context(), userID)
+	if err != nil {
+		h.logger.Error("failed to list sessions", "error", err, "user_id", userID)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	currentToken, _ := r.Context().Value(middleware.SessionTokenKey).(string)
+	data := admin.SessionListData{
+		Sessions:  sessionItems(sessions, currentToken),
+		CSRFToken: middleware.CSRFToken(r),
+	}
+	if r.URL.Query().Get("revoked") == "1" {
+		data.Success = "Session signed out."
+	}
+	if n, err := strconv.Atoi(r.URL.Query().Get("revoked_others")); err == nil {
+		data.Success = fmt.Sprintf("Signed out of %d other session(s).", n)
+	}
+
+	admin.SessionListPage(data).Render(r.Context(), w)
+}
+
+// Revoke handles POST /admin/sessions/{id}/revoke.
+func (h *SessionHandler) Revoke(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.AdminUserIDFromContext(r.Context())
+	if !ok {
+		http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+		return
+	}
+
+	publicID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid session ID", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.authSvc.RevokeSession(r.Context(), userID, publicID); err != nil {
+		if errors.Is(err, auth.ErrSessionNotFound) {
+			http.Error(w, "Session not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to revoke session", "error", err, "user_id", userID)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, r, "admin_session.revoked", "admin_user", userID.String(), map[string]any{
+		"session": publicID.String(),
+	})
+
+	http.Redirect(w, r, "/admin/sessions?revoked=1", http.StatusSeeOther)
+}
+
+// RevokeOthers handles POST /admin/sessions/revoke-others. It signs the
+// user out everywhere except the current browser.
+func (h *SessionHandler) RevokeOthers(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.AdminUserIDFromContext(r.Context())
+	currentToken, hasToken := r.Context().Value(middleware.SessionTokenKey).(string)
+	if !ok || !hasToken {
+		http.Redirect(w, r, "/admin/login", http.StatusSeeOther)
+		return
+	}
+
+	n, err := h.authSvc.RevokeOtherSessions(r.Context(), userID, currentToken)
+	if err != nil {
+		h.logger.Error("failed to revoke other sessions", "error", err, "user_id", userID)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+	recordAudit(h.audit, r, "admin_session.revoked_others", "admin_user", userID.String(), map[string]any{
+		"sessions_revoked": n,
+	})
+
+	http.Redirect(w, r, fmt.Sprintf("/admin/sessions?revoked_others=%d", n), http.StatusSeeOther)
+}
+
+// sessionItems converts sessions for display, marking the one identified by
+// currentToken.
+func sessionItems(sessions []*auth.Session, currentToken string) []admin.SessionItem {
+	items := make([]admin.SessionItem, 0, len(sessions))
+	for _, s := range sessions {
+		items = append(items, admin.SessionItem{
+			ID:         s.PublicID.String(),
+			IPAddress:  s.IPAddress,
+			Browser:    describeUserAgent(s.UserAgent),
+			UserAgent:  s.UserAgent,
+			CreatedAt:  s.CreatedAt.Format("2006-01-02 15:04"),
+			LastSeenAt: s.LastSeenAt.Format("2006-01-02 15:04"),
+			ExpiresAt:  s.ExpiresAt.Format("2006-01-02 15:04"),
+			Current:    s.ID == currentToken,
+		})
+	}
+	return items
+}
+
+// describeUserAgent returns a short "Browser on OS" description of a
+// User-Agent header, or "Unknown browser" if it is not recognised.
+func describeUserAgent(ua string) string {
+	browser := ""
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		browser = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		browser = "Opera"
+	case strings.Contains(ua, "Firefox/"):
+		browser = "Firefox"
+	case strings.Contains(ua, "Chrome/"):
+		browser = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		browser = "Safari"
+	}
+
+	os := ""
+	switch {
+	case strings.Contains(ua, "iPhone"), strings.Contains(ua, "iPad"):
+		os = "iOS"
+	case strings.Contains(ua, "Android"):
+		os = "Android"
+	case strings.Contains(ua, "Windows"):
+		os = "Windows"
+	case strings.Contains(ua, "Mac OS X"):
+		os = "macOS"
+	case strings.Contains(ua, "Linux"):
+		os = "Linux"
+	}
+
+	switch {
+	case browser != "" && os != "":
+		return browser + " on " + os
+	case browser != "":
+		return browser
+	case os != "":
+		return "Unknown browser on " + os
+	default:
+		return "Unknown browser"
+	}
+}
@@ -1,15 +1,20 @@
 package admin
 
 import (
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgxpool"
 
+	"github.com/forgecommerce/api/internal/auth"
 	db "github.com/forgecommerce/api/internal/database/gen"
 	"github.com/forgecommerce/api/internal/middleware"
+	"github.com/forgecommerce/api/internal/services/audit"
+	"github.com/forgecommerce/api/internal/services/invoice"
 	"github.com/forgecommerce/api/internal/vat"
 	"github.com/forgecommerce/api/templates/admin"
 )
@@ -19,24 +24,27 @@ import (
 type SettingsHandler struct {
 	queries *db.Queries
 	syncer  *vat.RateSyncer
+	audit   *audit.Service
 	logger  *slog.Logger
 }
 
 // NewSettingsHandler creates a new settings handler.
-func NewSettingsHandler(pool *pgxpool.Pool, syncer *vat.RateSyncer, logger *slog.Logger) *SettingsHandler {
+func NewSettingsHandler(pool *pgxpool.Pool, syncer *vat.RateSyncer, auditSvc *audit.Service, logger *slog.Logger) *SettingsHandler {
 	return &SettingsHandler{
 		queries: db.New(pool),
 		syncer:  syncer,
+		audit:   auditSvc,
 		logger:  logger,
 	}
 }
 
 // RegisterRoutes registers all settings admin routes on the given mux.
 func (h *SettingsHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/settings/vat", h.ShowVATSettings)
-	mux.HandleFunc("POST /admin/settings/vat", h.UpdateVATSettings)
-	mux.HandleFunc("POST /admin/settings/countries", h.UpdateCountries)
-	mux.HandleFunc("POST /admin/settings/vat/sync", h.SyncVATRates)
+	mux.Handle("GET /admin/settings/vat", requires(auth.PermSettingsVAT, h.ShowVATSettings))
+	mux.Handle("POST /admin/settings/vat", requires(auth.PermSettingsVAT, h.UpdateVATSettings))
+	mux.Handle("POST /admin/settings/invoice", requires(auth.PermSettingsVAT, h.UpdateInvoiceDetails))
+	mux.Handle("POST /admin/settings/countries", requires(auth.PermSettingsVAT, h.UpdateCountries))
+	mux.Handle("POST /admin/settings/vat/sync", requires(auth.PermSettingsVAT, h.SyncVATRates))
 }
 
 // ShowVATSettings handles GET /admin/settings/vat.
@@ -219,6 +227,7 @@ func (h *SettingsHandler) ShowVATSettings(w http.ResponseWriter, r *http.Request
 		LastSyncTime:        lastSyncTimeStr,
 		LastSyncSource:      lastSyncSource,
 	}
+	setInvoiceDetails(&data, sellerAddress(settings.StoreAddress))
 
 	admin.VATSettingsPage(data).Render(ctx, w)
 }
@@ -234,6 +243,13 @@ func (h *SettingsHandler) UpdateVATSettings(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	before, err := h.queries.GetStoreSettings(ctx)
+	if err != nil {
+		h.logger.Error("failed to get store settings", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	params := db.UpdateStoreVATSettingsParams{
 		VatEnabled:                 r.FormValue("vat_enabled") != "",
 		VatNumber:                  strPtr(r.FormValue("vat_number")),
@@ -256,6 +272,51 @@ func (h *SettingsHandler) UpdateVATSettings(w http.ResponseWriter, r *http.Reque
 		"vat_enabled", params.VatEnabled,
 		"vat_country_code", derefString(params.VatCountryCode),
 	)
+	if after, err := h.queries.GetStoreSettings(ctx); err == nil {
+		recordAudit(h.audit, r, "store_settings.vat_updated", "store_settings", after.ID.String(), audit.Diff(before, after))
+	}
+
+	http.Redirect(w, r, "/admin/settings/vat", http.StatusSeeOther)
+}
+
+// UpdateInvoiceDetails handles POST /admin/settings/invoice.
+// It saves the seller address printed on invoices.
+func (h *SettingsHandler) UpdateInvoiceDetails(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := r.ParseForm(); err != nil {
+		h.logger.Error("failed to parse form", "error", err)
+		http.Error(w, "Bad request", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.queries.GetStoreSettings(ctx)
+	if err != nil {
+		h.logger.Error("failed to get store settings", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	details := invoiceDetailsFromForm(r)
+	address, err := json.Marshal(details)
+	if err != nil {
+		h.logger.Error("failed to encode store address", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if err := h.queries.UpdateStoreAddress(ctx, db.UpdateStoreAddressParams{
+		StoreAddress: address,
+		UpdatedAt:    time.Now(),
+	}); err != nil {
+		h.logger.Error("failed to update store address", "error", err)
+		h.showVATSettingsWithError(w, r, "Failed to save invoice details. Please try again.")
+		return
+	}
+
+	h.logger.Info("invoice details updated")
+	recordAudit(h.audit, r, "store_settings.invoice_updated", "store_settings", settings.ID.String(),
+		audit.Diff(sellerAddress(settings.StoreAddress), details))
 
 	http.Redirect(w, r, "/admin/settings/vat", http.StatusSeeOther)
 }
@@ -286,8 +347,12 @@ func (h *SettingsHandler) UpdateCountries(w http.ResponseWriter, r *http.Request
 	}
 
 	// Update each country's enabled status.
+	before := make(map[string]bool, len(shippingCountries))
+	after := make(map[string]bool, len(shippingCountries))
 	for _, sc := range shippingCountries {
 		isEnabled := selectedCountries[sc.CountryCode]
+		before[sc.CountryCode] = sc.IsEnabled
+		after[sc.CountryCode] = isEnabled
 		if err := h.queries.SetShippingCountryEnabled(ctx, db.SetShippingCountryEnabledParams{
 			CountryCode: sc.CountryCode,
 			IsEnabled:   isEnabled,
@@ -302,6 +367,9 @@ func (h *SettingsHandler) UpdateCountries(w http.ResponseWriter, r *http.Request
 	}
 
 	h.logger.Info("shipping countries updated", "enabled_count", len(selectedCountries))
+	if changes := audit.Diff(before, after); len(changes) > 0 {
+		recordAudit(h.audit, r, "selling_countries.updated", "selling_countries", "", changes)
+	}
 
 	http.Redirect(w, r, "/admin/settings/vat", http.StatusSeeOther)
 }
@@ -332,6 +400,11 @@ func (h *SettingsHandler) SyncVATRates(w http.ResponseWriter, r *http.Request) {
 		"rates_loaded", result.RatesLoaded,
 		"rates_changed", result.RatesChanged,
 	)
+	recordAudit(h.audit, r, "vat_rates.synced", "vat_rate", "", map[string]any{
+		"source":        result.Source,
+		"rates_loaded":  result.RatesLoaded,
+		"rates_changed": result.RatesChanged,
+	})
 
 	fmt.Fprintf(w,
 		`<div class="alert alert-success" style="margin-top: 8px;">Synced %d rates from %s. %d rates changed.</div>`,
@@ -390,6 +463,42 @@ func (h *SettingsHandler) showVATSettingsWithError(w http.ResponseWriter, r *htt
 		ShippingCountries:   shippingCountryItems,
 	}
 
+	// Keep the invoice details that were submitted; for the other forms show
+	// the saved ones.
+	if _, ok := r.PostForm["invoice_line1"]; ok {
+		setInvoiceDetails(&data, invoiceDetailsFromForm(r))
+	} else if settings, err := h.queries.GetStoreSettings(ctx); err == nil {
+		setInvoiceDetails(&data, sellerAddress(settings.StoreAddress))
+	}
+
 	w.WriteHeader(http.StatusUnprocessableEntity)
 	admin.VATSettingsPage(data).Render(ctx, w)
 }
+
+// sellerAddress decodes the stored seller address. An unreadable address
+// shows as empty so it can be entered again.
+func sellerAddress(raw []byte) invoice.SellerAddress {
+	addr, err := invoice.ParseSellerAddress(raw)
+	if err != nil {
+		return invoice.SellerAddress{}
+	}
+	return addr
+}
+
+func invoiceDetailsFromForm(r *http.Request) invoice.SellerAddress {
+	return invoice.SellerAddress{
+		LegalName:  strings.TrimSpace(r.FormValue("invoice_legal_name")),
+		Line1:      strings.TrimSpace(r.FormValue("invoice_line1")),
+		Line2:      strings.TrimSpace(r.FormValue("invoice_line2")),
+		PostalCode: strings.TrimSpace(r.FormValue("invoice_postal_code")),
+		City:       strings.TrimSpace(r.FormValue("invoice_city")),
+	}
+}
+
+func setInvoiceDetails(data *admin.VATSettingsData, addr invoice.SellerAddress) {
+	data.InvoiceLegalName = addr.LegalName
+	data.InvoiceLine1 = addr.Line1
+	data.InvoiceLine2 = addr.Line2
+	data.InvoicePostalCode = addr.PostalCode
+	data.InvoiceCity = addr.City
+}
@@ -2,6 +2,7 @@ package admin
 
 import (
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"strings"
@@ -9,7 +10,9 @@ import (
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 
+	"github.com/forgecommerce/api/internal/auth"
 	"github.com/forgecommerce/api/internal/middleware"
+	"github.com/forgecommerce/api/internal/services/audit"
 	"github.com/forgecommerce/api/internal/services/shipping"
 	"github.com/forgecommerce/api/templates/admin"
 )
@@ -17,23 +20,25 @@ import (
 // ShippingHandler handles admin shipping configuration and zone management endpoints.
 type ShippingHandler struct {
 	shipping *shipping.Service
+	audit    *audit.Service
 	logger   *slog.Logger
 }
 
 // NewShippingHandler creates a new shipping handler.
-func NewShippingHandler(shippingSvc *shipping.Service, logger *slog.Logger) *ShippingHandler {
+func NewShippingHandler(shippingSvc *shipping.Service, auditSvc *audit.Service, logger *slog.Logger) *ShippingHandler {
 	return &ShippingHandler{
 		shipping: shippingSvc,
+		audit:    auditSvc,
 		logger:   logger,
 	}
 }
 
 // RegisterRoutes registers all shipping admin routes on the given mux.
 func (h *ShippingHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/settings/shipping", h.ShowShipping)
-	mux.HandleFunc("POST /admin/settings/shipping", h.UpdateConfig)
-	mux.HandleFunc("POST /admin/settings/shipping/zones", h.CreateZone)
-	mux.HandleFunc("POST /admin/settings/shipping/zones/{id}/delete", h.DeleteZone)
+	mux.Handle("GET /admin/settings/shipping", requires(auth.PermSettingsShipping, h.ShowShipping))
+	mux.Handle("POST /admin/settings/shipping", requires(auth.PermSettingsShipping, h.UpdateConfig))
+	mux.Handle("POST /admin/settings/shipping/zones", requires(auth.PermSettingsShipping, h.CreateZone))
+	mux.Handle("POST /admin/settings/shipping/zones/{id}/delete", requires(auth.PermSettingsShipping, h.DeleteZone))
 }
 
 // ShowShipping handles GET /admin/settings/shipping.
@@ -118,6 +123,13 @@ func (h *ShippingHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 		freeShippingThreshold = parsed
 	}
 
+	before, err := h.shipping.GetConfig(ctx)
+	if err != nil && !errors.Is(err, shipping.ErrConfigNotFound) {
+		h.logger.Error("failed to get shipping config", "error", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
 	params := shipping.UpdateConfigParams{
 		Enabled:               r.FormValue("enabled") != "",
 		CalculationMethod:     r.FormValue("calculation_method"),
@@ -126,7 +138,8 @@ func (h *ShippingHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 		DefaultCurrency:       "EUR",
 	}
 
-	if _, err := h.shipping.UpdateConfig(ctx, params); err != nil {
+	after, err := h.shipping.UpdateConfig(ctx, params)
+	if err != nil {
 		h.logger.Error("failed to update shipping config", "error", err)
 		h.showShippingWithError(w, r, "Failed to save shipping configuration. Please try again.")
 		return
@@ -136,6 +149,7 @@ func (h *ShippingHandler) UpdateConfig(w http.ResponseWriter, r *http.Request) {
 		"enabled", params.Enabled,
 		"method", params.CalculationMethod,
 	)
+	recordAudit(h.audit, r, "shipping_config.updated", "shipping_config", after.ID.String(), audit.Diff(before, after))
 
 	http.Redirect(w, r, "/admin/settings/shipping", http.StatusSeeOther)
 }
@@ -204,6 +218,7 @@ func (h *ShippingHandler) CreateZone(w http.ResponseWriter, r *http.Request) {
 		"name", zone.Name,
 		"countries", countries,
 	)
+	recordAudit(h.audit, r, "shipping_zone.created", "shipping_zone", zone.ID.String(), audit.Diff(nil, zone))
 
 	// Return the HTMX fragment for the new zone row.
 	zoneItem := admin.ShippingZoneItem{
@@ -230,6 +245,17 @@ func (h *ShippingHandler) DeleteZone(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := h.shipping.GetZone(ctx, id)
+	if err != nil {
+		h.logger.Error("failed to get shipping zone", "error", err, "zone_id", id)
+		if errors.Is(err, shipping.ErrZoneNotFound) {
+			http.Error(w, "Shipping zone not found", http.StatusNotFound)
+			return
+		}
+		http.Error(w, "Failed to delete shipping zone", http.StatusInternalServerError)
+		return
+	}
+
 	if err := h.shipping.DeleteZone(ctx, id); err != nil {
 		h.logger.Error("failed to delete shipping zone", "error", err, "zone_id", id)
 		if err == shipping.ErrZoneNotFound {
@@ -241,6 +267,7 @@ func (h *ShippingHandler) DeleteZone(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.logger.Info("shipping zone deleted", "zone_id", id.String())
+	recordAudit(h.audit, r, "shipping_zone.deleted", "shipping_zone", id.String(), audit.Diff(before, nil))
 
 	// Return 200 with empty body — HTMX outerHTML swap removes the row.
 	w.WriteHeader(http.StatusOK)
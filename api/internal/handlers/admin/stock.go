@@ -0,0 +1,349 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+
+	"github.com/forgecommerce/api/internal/auth"
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/middleware"
+	"github.com/forgecommerce/api/internal/services/inventory"
+	"github.com/forgecommerce/api/internal/services/product"
+	"github.com/forgecommerce/api/internal/services/rawmaterial"
+	"github.com/forgecommerce/api/internal/services/variant"
+	"github.com/forgecommerce/api/templates/admin"
+)
+
+const stockMovementPageSize = 50
+
+// StockHandler serves the stock movement history for variants and raw
+// materials and records manual stock movements (purchases, counts, damage).
+type StockHandler struct {
+	inventory *inventory.Service
+	variants  *variant.Service
+	materials *rawmaterial.Service
+	products  *product.Service
+	logger    *slog.Logger
+}
+
+// NewStockHandler creates a new stock handler.
+func NewStockHandler(inv *inventory.Service, variants *variant.Service, materials *rawmaterial.Service, products *product.Service, logger *slog.Logger) *StockHandler {
+	return &StockHandler{
+		inventory: inv,
+		variants:  variants,
+		materials: materials,
+		products:  products,
+		logger:    logger,
+	}
+}
+
+// RegisterRoutes registers stock history routes on the given mux.
+func (h *StockHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.Handle("GET /admin/products/{id}/variants/{variantId}/stock", requires(auth.PermInventoryRead, h.ShowVariantStock))
+	mux.Handle("POST /admin/products/{id}/variants/{variantId}/stock", requires(auth.PermInventoryWrite, h.RecordVariantMovement))
+	mux.Handle("GET /admin/inventory/raw-materials/{id}/stock", requires(auth.PermInventoryRead, h.ShowMaterialStock))
+	mux.Handle("POST /admin/inventory/raw-materials/{id}/stock", requires(auth.PermInventoryWrite, h.RecordMaterialMovement))
+}
+
+// ShowVariantStock handles GET /admin/products/{id}/variants/{variantId}/stock.
+func (h *StockHandler) ShowVariantStock(w http.ResponseWriter, r *http.Request) {
+	h.renderVariantStock(w, r, "")
+}
+
+// RecordVariantMovement handles POST /admin/products/{id}/variants/{variantId}/stock.
+func (h *StockHandler) RecordVariantMovement(w http.ResponseWriter, r *http.Request) {
+	variantID, err := uuid.Parse(r.PathValue("variantId"))
+	if err != nil {
+		http.Error(w, "Invalid variant ID", http.StatusBadRequest)
+		return
+	}
+
+	change, qty, errMsg := parseStockMovementForm(r)
+	if errMsg == "" && !qty.IsInteger() {
+		errMsg = "Variant stock must change by whole units."
+	}
+	if errMsg != "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		h.renderVariantStock(w, r, errMsg)
+		return
+	}
+
+	if _, err := h.inventory.AdjustVariant(r.Context(), variantID, int32(qty.IntPart()), change); err != nil {
+		h.logger.Error("failed to record variant stock movement", "error", err, "variant_id", variantID)
+		w.WriteHeader(stockErrorStatus(err))
+		h.renderVariantStock(w, r, fmt.Sprintf("Failed to record movement: %v", err))
+		return
+	}
+
+	http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+}
+
+// ShowMaterialStock handles GET /admin/inventory/raw-materials/{id}/stock.
+func (h *StockHandler) ShowMaterialStock(w http.ResponseWriter, r *http.Request) {
+	h.renderMaterialStock(w, r, "")
+}
+
+// RecordMaterialMovement handles POST /admin/inventory/raw-materials/{id}/stock.
+func (h *StockHandler) RecordMaterialMovement(w http.ResponseWriter, r *http.Request) {
+	materialID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	change, qty, errMsg := parseStockMovementForm(r)
+	if errMsg != "" {
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		h.renderMaterialStock(w, r, errMsg)
+		return
+	}
+
+	if _, err := h.inventory.AdjustRawMaterial(r.Context(), materialID, qty, change); err != nil {
+		h.logger.Error("failed to record raw material stock movement", "error", err, "id", materialID)
+		w.WriteHeader(stockErrorStatus(err))
+		h.renderMaterialStock(w, r, fmt.Sprintf("Failed to record movement: %v", err))
+		return
+	}
+
+	http.Redirect(w, r, r.URL.Path, http.StatusSeeOther)
+}
+
+// --- Rendering ---
+
+func (h *StockHandler) renderVariantStock(w http.ResponseWriter, r *http.Request, errMsg string) {
+	ctx := r.Context()
+
+	productID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid product ID", http.StatusBadRequest)
+		return
+	}
+	variantID, err := uuid.Parse(r.PathValue("variantId"))
+	if err != nil {
+		http.Error(w, "Invalid variant ID", http.StatusBadRequest)
+		return
+	}
+
+	v, err := h.variants.Get(ctx, variantID)
+	if err != nil || v.ProductID != productID {
+		if err != nil && !errors.Is(err, variant.ErrNotFound) {
+			h.logger.Error("failed to get variant", "error", err, "variant_id", variantID)
+		}
+		http.Error(w, "Variant not found", http.StatusNotFound)
+		return
+	}
+
+	title := v.Sku
+	if p, err := h.products.Get(ctx, productID); err == nil {
+		title = p.Name
+	}
+
+	page := stockPageParam(r)
+	movements, total, err := h.inventory.ListMovements(ctx, inventory.EntityVariant, variantID, page, stockMovementPageSize)
+	if err != nil {
+		h.logger.Error("failed to list stock movements", "error", err, "variant_id", variantID)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	baseURL := fmt.Sprintf("/admin/products/%s/variants/%s", productID, variantID)
+	admin.StockMovementsPage(admin.StockMovementsData{
+		Title:        title,
+		SKU:          v.Sku,
+		CurrentStock: strconv.Itoa(int(v.StockQuantity)),
+		NavPath:      "/admin/products",
+		BackURL:      baseURL,
+		BackLabel:    "Back to Variant",
+		ProductID:    productID.String(),
+		BaseURL:      baseURL + "/stock",
+		QuantityStep: "1",
+		Movements:    toStockMovementItems(movements),
+		Page:         page,
+		TotalPages:   stockTotalPages(total),
+		Total:        int(total),
+		CSRFToken:    middleware.CSRFToken(r),
+		Error:        errMsg,
+	}).Render(ctx, w)
+}
+
+func (h *StockHandler) renderMaterialStock(w http.ResponseWriter, r *http.Request, errMsg string) {
+	ctx := r.Context()
+
+	materialID, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	m, err := h.materials.Get(ctx, materialID)
+	if err != nil {
+		h.logger.Error("failed to get raw material", "error", err, "id", materialID)
+		http.NotFound(w, r)
+		return
+	}
+
+	page := stockPageParam(r)
+	movements, total, err := h.inventory.ListMovements(ctx, inventory.EntityRawMaterial, materialID, page, stockMovementPageSize)
+	if err != nil {
+		h.logger.Error("failed to list stock movements", "error", err, "id", materialID)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	baseURL := "/admin/inventory/raw-materials/" + materialID.String()
+	admin.StockMovementsPage(admin.StockMovementsData{
+		Title:        m.Name,
+		SKU:          m.Sku,
+		Unit:         m.UnitOfMeasure,
+		CurrentStock: formatQuantity(m.StockQuantity),
+		NavPath:      "/admin/inventory/raw-materials",
+		BackURL:      baseURL,
+		BackLabel:    "Back to Material",
+		BaseURL:      baseURL + "/stock",
+		QuantityStep: "0.0001",
+		Movements:    toStockMovementItems(movements),
+		Page:         page,
+		TotalPages:   stockTotalPages(total),
+		Total:        int(total),
+		CSRFToken:    middleware.CSRFToken(r),
+		Error:        errMsg,
+	}).Render(ctx, w)
+}
+
+// --- Helpers ---
+
+// manualMovementTypes are the movement types an admin may record by hand.
+// Sales and production movements are written by their own workflows.
+var manualMovementTypes = map[string]bool{
+	inventory.MovementPurchase:   true,
+	inventory.MovementAdjustment: true,
+	inventory.MovementReturn:     true,
+	inventory.MovementDamage:     true,
+}
+
+// parseStockMovementForm reads a manual stock movement from the form. The
+// returned quantity is signed: purchases and returns are positive, damage is
+// negative, adjustments keep the sign entered. A non-empty string is a
+// user-facing validation error.
+func parseStockMovementForm(r *http.Request) (inventory.Change, decimal.Decimal, string) {
+	if err := r.ParseForm(); err != nil {
+		return inventory.Change{}, decimal.Zero, "Invalid form data."
+	}
+
+	movementType := r.FormValue("movement_type")
+	if !manualMovementTypes[movementType] {
+		return inventory.Change{}, decimal.Zero, "Choose a valid movement type."
+	}
+
+	qty, err := decimal.NewFromString(strings.TrimSpace(r.FormValue("quantity")))
+	if err != nil || qty.IsZero() {
+		return inventory.Change{}, decimal.Zero, "Enter a non-zero quantity."
+	}
+	switch movementType {
+	case inventory.MovementPurchase, inventory.MovementReturn:
+		qty = qty.Abs()
+	case inventory.MovementDamage:
+		qty = qty.Abs().Neg()
+	}
+
+	change := inventory.Change{
+		MovementType:  movementType,
+		ReferenceType: inventory.ReferenceManual,
+		UnitCost:      parseNumeric(r.FormValue("unit_cost")),
+		Notes:         strings.TrimSpace(r.FormValue("notes")),
+		CreatedBy:     adminUserRef(r),
+	}
+	return change, qty, ""
+}
+
+// adminUserRef returns the logged-in admin user as a nullable UUID.
+func adminUserRef(r *http.Request) pgtype.UUID {
+	if userID, ok := middleware.AdminUserIDFromContext(r.Context()); ok {
+		return pgtype.UUID{Bytes: userID, Valid: true}
+	}
+	return pgtype.UUID{}
+}
+
+func stockErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, inventory.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, inventory.ErrInsufficientStock):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func stockPageParam(r *http.Request) int {
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		return p
+	}
+	return 1
+}
+
+func stockTotalPages(total int64) int {
+	pages := int((total + stockMovementPageSize - 1) / stockMovementPageSize)
+	if pages < 1 {
+		pages = 1
+	}
+	return pages
+}
+
+// formatQuantity formats a stock quantity without trailing zeros.
+func formatQuantity(n pgtype.Numeric) string {
+	if !n.Valid || n.Int == nil {
+		return ""
+	}
+	return decimal.NewFromBigInt(n.Int, n.Exp).String()
+}
+
+func toStockMovementItems(movements []db.ListStockMovementsRow) []admin.StockMovementItem {
+	items := make([]admin.StockMovementItem, 0, len(movements))
+	for _, m := range movements {
+		change := formatQuantity(m.QuantityChange)
+		increase := !strings.HasPrefix(change, "-")
+		if increase {
+			change = "+" + change
+		}
+
+		item := admin.StockMovementItem{
+			CreatedAt:      m.CreatedAt.Format("2006-01-02 15:04"),
+			MovementType:   m.MovementType,
+			QuantityChange: change,
+			IsIncrease:     increase,
+			QuantityBefore: formatQuantity(m.QuantityBefore),
+			QuantityAfter:  formatQuantity(m.QuantityAfter),
+			UnitCost:       formatQuantity(m.UnitCost),
+			Notes:          derefString(m.Notes),
+			CreatedBy:      derefString(m.CreatedByName),
+		}
+		if item.CreatedBy == "" {
+			item.CreatedBy = "system"
+		}
+
+		refType := derefString(m.ReferenceType)
+		item.Reference = refType
+		if m.ReferenceID.Valid {
+			refID := uuid.UUID(m.ReferenceID.Bytes).String()
+			switch refType {
+			case inventory.ReferenceOrder:
+				item.Reference = "Order"
+				item.ReferenceURL = "/admin/orders/" + refID
+			case inventory.ReferenceProductionBatch:
+				item.Reference = "Production batch"
+				item.ReferenceURL = "/admin/production/" + refID
+			}
+		}
+		items = append(items, item)
+	}
+	return items
+}
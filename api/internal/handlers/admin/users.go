@@ -10,31 +10,35 @@ import (
 
 	"github.com/forgecommerce/api/internal/auth"
 	"github.com/forgecommerce/api/internal/middleware"
+	"github.com/forgecommerce/api/internal/services/audit"
 	admin "github.com/forgecommerce/api/templates/admin"
 )
 
 // UserHandler handles admin user management endpoints.
 type UserHandler struct {
 	authSvc *auth.Service
+	audit   *audit.Service
 	logger  *slog.Logger
 }
 
 // NewUserHandler creates a new user handler.
-func NewUserHandler(authSvc *auth.Service, logger *slog.Logger) *UserHandler {
+func NewUserHandler(authSvc *auth.Service, auditSvc *audit.Service, logger *slog.Logger) *UserHandler {
 	return &UserHandler{
 		authSvc: authSvc,
+		audit:   auditSvc,
 		logger:  logger,
 	}
 }
 
 // RegisterRoutes registers user management routes on the given mux.
 func (h *UserHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/users", h.ListUsers)
-	mux.HandleFunc("GET /admin/users/new", h.NewUserForm)
-	mux.HandleFunc("POST /admin/users", h.CreateUser)
-	mux.HandleFunc("GET /admin/users/{id}", h.EditUserForm)
-	mux.HandleFunc("POST /admin/users/{id}", h.UpdateUser)
-	mux.HandleFunc("POST /admin/users/{id}/toggle-active", h.ToggleActive)
+	mux.Handle("GET /admin/users", requires(auth.PermUsersManage, h.ListUsers))
+	mux.Handle("GET /admin/users/new", requires(auth.PermUsersManage, h.NewUserForm))
+	mux.Handle("POST /admin/users", requires(auth.PermUsersManage, h.CreateUser))
+	mux.Handle("GET /admin/users/{id}", requires(auth.PermUsersManage, h.EditUserForm))
+	mux.Handle("POST /admin/users/{id}", requires(auth.PermUsersManage, h.UpdateUser))
+	mux.Handle("POST /admin/users/{id}/toggle-active", requires(auth.PermUsersManage, h.ToggleActive))
+	mux.Handle("POST /admin/users/{id}/logout", requires(auth.PermUsersManage, h.ForceLogout))
 }
 
 // ListUsers handles GET /admin/users.
@@ -93,17 +97,18 @@ func (h *UserHandler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if role != "admin" && role != "super_admin" {
+	if !auth.IsValidRole(role) {
 		h.renderUserFormWithError(w, r, nil, false, csrfToken, "Invalid role selected.")
 		return
 	}
 
-	_, err := h.authSvc.CreateUser(r.Context(), email, name, password, role, []string{})
+	created, err := h.authSvc.CreateUser(r.Context(), email, name, password, role, permissionsFromForm(r))
 	if err != nil {
 		h.logger.Error("failed to create user", "error", err)
 		h.renderUserFormWithError(w, r, nil, false, csrfToken, "Failed to create user: "+err.Error())
 		return
 	}
+	recordAudit(h.audit, r, "admin_user.created", "admin_user", created.ID.String(), audit.Diff(nil, userAuditFields(created)))
 
 	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
 }
@@ -132,8 +137,13 @@ func (h *UserHandler) EditUserForm(w http.ResponseWriter, r *http.Request) {
 	data := admin.UserFormData{
 		User:      user,
 		IsEdit:    true,
+		IsSelf:    isCurrentUser(r, id),
+		Sessions:  h.userSessions(r, id),
 		CSRFToken: csrfToken,
 	}
+	if r.URL.Query().Get("logged_out") == "1" {
+		data.Success = user.Name + " has been signed out of all sessions."
+	}
 
 	admin.UserFormPage(data).Render(r.Context(), w)
 }
@@ -175,17 +185,24 @@ func (h *UserHandler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if role != "admin" && role != "super_admin" {
+	if !auth.IsValidRole(role) {
 		h.renderUserFormWithError(w, r, user, true, csrfToken, "Invalid role selected.")
 		return
 	}
 
-	_, err = h.authSvc.UpdateUser(r.Context(), id, name, role, []string{}, isActive)
+	// Users cannot lock themselves out.
+	if isCurrentUser(r, id) && (role != user.Role || !isActive) {
+		h.renderUserFormWithError(w, r, user, true, csrfToken, "You cannot change your own role or deactivate yourself.")
+		return
+	}
+
+	updated, err := h.authSvc.UpdateUser(r.Context(), id, name, role, permissionsFromForm(r), isActive)
 	if err != nil {
 		h.logger.Error("failed to update user", "error", err, "user_id", id)
 		h.renderUserFormWithError(w, r, user, true, csrfToken, "Failed to update user.")
 		return
 	}
+	recordAudit(h.audit, r, "admin_user.updated", "admin_user", id.String(), audit.Diff(userAuditFields(user), userAuditFields(updated)))
 
 	http.Redirect(w, r, "/admin/users", http.StatusSeeOther)
 }
@@ -212,6 +229,11 @@ func (h *UserHandler) ToggleActive(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if isCurrentUser(r, id) {
+		http.Error(w, "You cannot deactivate yourself", http.StatusBadRequest)
+		return
+	}
+
 	newActive := !user.IsActive
 	if err := h.authSvc.SetUserActive(r.Context(), id, newActive); err != nil {
 		h.logger.Error("failed to toggle user active", "error", err, "user_id", id)
@@ -220,17 +242,97 @@ func (h *UserHandler) ToggleActive(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Refresh the user to get the updated state.
+	before := userAuditFields(user)
 	user, err = h.authSvc.GetUserByID(r.Context(), id)
 	if err != nil {
 		h.logger.Error("failed to reload user after toggle", "error", err, "user_id", id)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 		return
 	}
+	recordAudit(h.audit, r, "admin_user.updated", "admin_user", id.String(), audit.Diff(before, userAuditFields(user)))
 
 	// Render just the table row as an HTMX fragment.
 	admin.UserTableRow(user, csrfToken).Render(r.Context(), w)
 }
 
+// ForceLogout handles POST /admin/users/{id}/logout. It ends every session
+// of another admin user, for example after a lost laptop.
+func (h *UserHandler) ForceLogout(w http.ResponseWriter, r *http.Request) {
+	csrfToken := middleware.CSRFToken(r)
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid user ID", http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.authSvc.GetUserByID(r.Context(), id)
+	if err != nil {
+		if errors.Is(err, auth.ErrUserNotFound) {
+			http.Error(w, "User not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get user", "error", err, "user_id", id)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	if isCurrentUser(r, id) {
+		h.renderUserFormWithError(w, r, user, true, csrfToken, "Use My Sessions to sign out your own sessions.")
+		return
+	}
+
+	if err := h.authSvc.ForceLogout(r.Context(), id); err != nil {
+		h.logger.Error("failed to force logout", "error", err, "user_id", id)
+		h.renderUserFormWithError(w, r, user, true, csrfToken, "Failed to sign the user out.")
+		return
+	}
+	recordAudit(h.audit, r, "admin_user.logged_out", "admin_user", id.String(), nil)
+
+	http.Redirect(w, r, "/admin/users/"+id.String()+"?logged_out=1", http.StatusSeeOther)
+}
+
+// userSessions returns the active sessions of an admin user for the edit
+// form. Failures are logged and show as no sessions.
+func (h *UserHandler) userSessions(r *http.Request, id uuid.UUID) []admin.SessionItem {
+	sessions, err := h.authSvc.ListSessions(r.Context(), id)
+	if err != nil {
+		h.logger.Error("failed to list user sessions", "error", err, "user_id", id)
+		return nil
+	}
+	return sessionItems(sessions, "")
+}
+
+// permissionsFromForm returns the individual permissions ticked on the user
+// form, ignoring any that are not in the catalogue.
+func permissionsFromForm(r *http.Request) []string {
+	perms := []string{}
+	for _, p := range r.Form["permissions"] {
+		if auth.IsValidPermission(p) {
+			perms = append(perms, p)
+		}
+	}
+	return perms
+}
+
+// isCurrentUser reports whether id is the signed-in admin user.
+func isCurrentUser(r *http.Request, id uuid.UUID) bool {
+	current, ok := middleware.AdminUserIDFromContext(r.Context())
+	return ok && current == id
+}
+
+// userAuditFields returns the fields of an admin user that are recorded in
+// the audit log. Credentials are left out.
+func userAuditFields(u *auth.AdminUser) map[string]any {
+	return map[string]any{
+		"name":        u.Name,
+		"email":       u.Email,
+		"role":        u.Role,
+		"permissions": u.Permissions,
+		"is_active":   u.IsActive,
+	}
+}
+
 // renderUserFormWithError renders the user form with a 422 status and an error message.
 func (h *UserHandler) renderUserFormWithError(w http.ResponseWriter, r *http.Request, user *auth.AdminUser, isEdit bool, csrfToken, errMsg string) {
 	data := admin.UserFormData{
@@ -239,6 +341,10 @@ func (h *UserHandler) renderUserFormWithError(w http.ResponseWriter, r *http.Req
 		CSRFToken: csrfToken,
 		Error:     errMsg,
 	}
+	if isEdit && user != nil {
+		data.IsSelf = isCurrentUser(r, user.ID)
+		data.Sessions = h.userSessions(r, user.ID)
+	}
 	w.WriteHeader(http.StatusUnprocessableEntity)
 	admin.UserFormPage(data).Render(r.Context(), w)
 }
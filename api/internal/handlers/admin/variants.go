@@ -9,9 +9,11 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/forgecommerce/api/internal/auth"
 	db "github.com/forgecommerce/api/internal/database/gen"
 	"github.com/forgecommerce/api/internal/middleware"
 	"github.com/forgecommerce/api/internal/services/attribute"
+	"github.com/forgecommerce/api/internal/services/audit"
 	"github.com/forgecommerce/api/internal/services/product"
 	"github.com/forgecommerce/api/internal/services/variant"
 	"github.com/forgecommerce/api/templates/admin"
@@ -22,26 +24,28 @@ type VariantHandler struct {
 	variants   *variant.Service
 	attributes *attribute.Service
 	products   *product.Service
+	audit      *audit.Service
 	logger     *slog.Logger
 }
 
 // NewVariantHandler creates a new variant handler.
-func NewVariantHandler(variants *variant.Service, attributes *attribute.Service, products *product.Service, logger *slog.Logger) *VariantHandler {
+func NewVariantHandler(variants *variant.Service, attributes *attribute.Service, products *product.Service, auditSvc *audit.Service, logger *slog.Logger) *VariantHandler {
 	return &VariantHandler{
 		variants:   variants,
 		attributes: attributes,
 		products:   products,
+		audit:      auditSvc,
 		logger:     logger,
 	}
 }
 
 // RegisterRoutes registers product variant admin routes on the given mux.
 func (h *VariantHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/products/{id}/variants", h.ShowVariants)
-	mux.HandleFunc("POST /admin/products/{id}/variants/generate", h.GenerateVariants)
-	mux.HandleFunc("GET /admin/products/{id}/variants/{variantId}", h.ShowEditVariant)
-	mux.HandleFunc("POST /admin/products/{id}/variants/{variantId}", h.UpdateVariant)
-	mux.HandleFunc("POST /admin/products/{id}/variants/{variantId}/delete", h.DeleteVariant)
+	mux.Handle("GET /admin/products/{id}/variants", requires(auth.PermProductsRead, h.ShowVariants))
+	mux.Handle("POST /admin/products/{id}/variants/generate", requires(auth.PermProductsWrite, h.GenerateVariants))
+	mux.Handle("GET /admin/products/{id}/variants/{variantId}", requires(auth.PermProductsRead, h.ShowEditVariant))
+	mux.Handle("POST /admin/products/{id}/variants/{variantId}", requires(auth.PermProductsWrite, h.UpdateVariant))
+	mux.Handle("POST /admin/products/{id}/variants/{variantId}/delete", requires(auth.PermProductsWrite, h.DeleteVariant))
 }
 
 // ShowVariants handles GET /admin/products/{id}/variants.
@@ -153,7 +157,7 @@ func (h *VariantHandler) GenerateVariants(w http.ResponseWriter, r *http.Request
 		}
 	}
 
-	_, err = h.variants.GenerateVariants(ctx, productID, skuPrefix)
+	generated, err := h.variants.GenerateVariants(ctx, productID, skuPrefix)
 	if err != nil {
 		if errors.Is(err, variant.ErrNoAttributes) {
 			http.Error(w, "No attributes or active options defined", http.StatusBadRequest)
@@ -163,6 +167,9 @@ func (h *VariantHandler) GenerateVariants(w http.ResponseWriter, r *http.Request
 		http.Error(w, "Failed to generate variants", http.StatusInternalServerError)
 		return
 	}
+	for _, v := range generated {
+		recordAudit(h.audit, r, "variant.created", "variant", v.ID.String(), audit.Diff(nil, v))
+	}
 
 	// Reload all variants to return the full table body.
 	allVariants, err := h.variants.List(ctx, productID)
@@ -303,9 +310,10 @@ func (h *VariantHandler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
 		Barcode:           strPtr(r.FormValue("barcode")),
 		IsActive:          r.FormValue("is_active") == "true",
 		Position:          existing.Position,
+		ChangedBy:         adminUserRef(r),
 	}
 
-	_, err = h.variants.Update(ctx, variantID, params)
+	updated, err := h.variants.Update(ctx, variantID, params)
 	if err != nil {
 		h.logger.Error("failed to update variant", "error", err, "variant_id", variantID)
 		p, _ := h.products.Get(ctx, productID)
@@ -334,6 +342,7 @@ func (h *VariantHandler) UpdateVariant(w http.ResponseWriter, r *http.Request) {
 		admin.ProductVariantEditPage(data).Render(ctx, w)
 		return
 	}
+	recordAudit(h.audit, r, "variant.updated", "variant", variantID.String(), audit.Diff(existing, updated))
 
 	http.Redirect(w, r, fmt.Sprintf("/admin/products/%s/variants", productID), http.StatusSeeOther)
 }
@@ -349,6 +358,17 @@ func (h *VariantHandler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	before, err := h.variants.Get(ctx, variantID)
+	if err != nil {
+		if errors.Is(err, variant.ErrNotFound) {
+			http.Error(w, "Variant not found", http.StatusNotFound)
+			return
+		}
+		h.logger.Error("failed to get variant", "error", err, "variant_id", variantID)
+		http.Error(w, "Failed to delete variant", http.StatusInternalServerError)
+		return
+	}
+
 	if err := h.variants.Delete(ctx, variantID); err != nil {
 		if errors.Is(err, variant.ErrNotFound) {
 			http.Error(w, "Variant not found", http.StatusNotFound)
@@ -358,6 +378,7 @@ func (h *VariantHandler) DeleteVariant(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Failed to delete variant", http.StatusInternalServerError)
 		return
 	}
+	recordAudit(h.audit, r, "variant.deleted", "variant", variantID.String(), audit.Diff(before, nil))
 
 	h.logger.Info("variant deleted via admin", "variant_id", variantID)
 	w.Header().Set("Content-Type", "text/html")
@@ -10,21 +10,14 @@ import (
 
 	"github.com/google/uuid"
 
+	"github.com/forgecommerce/api/internal/auth"
 	"github.com/forgecommerce/api/internal/middleware"
 	"github.com/forgecommerce/api/internal/services/webhook"
 	"github.com/forgecommerce/api/templates/admin"
 )
 
 // allWebhookEvents lists the available event types for webhook subscriptions.
-var allWebhookEvents = []string{
-	webhook.EventOrderCreated,
-	webhook.EventOrderUpdated,
-	webhook.EventOrderCompleted,
-	webhook.EventProductCreated,
-	webhook.EventProductUpdated,
-	webhook.EventProductDeleted,
-	webhook.EventStockLow,
-}
+var allWebhookEvents = webhook.EventTypes()
 
 // WebhookHandler handles admin webhook management endpoints.
 type WebhookHandler struct {
@@ -42,13 +35,14 @@ func NewWebhookHandler(webhooks *webhook.Service, logger *slog.Logger) *WebhookH
 
 // RegisterRoutes registers webhook admin routes on the given mux.
 func (h *WebhookHandler) RegisterRoutes(mux *http.ServeMux) {
-	mux.HandleFunc("GET /admin/webhooks", h.ListEndpoints)
-	mux.HandleFunc("GET /admin/webhooks/new", h.NewEndpoint)
-	mux.HandleFunc("POST /admin/webhooks", h.CreateEndpoint)
-	mux.HandleFunc("GET /admin/webhooks/{id}/edit", h.EditEndpoint)
-	mux.HandleFunc("POST /admin/webhooks/{id}", h.UpdateEndpoint)
-	mux.HandleFunc("POST /admin/webhooks/{id}/delete", h.DeleteEndpoint)
-	mux.HandleFunc("GET /admin/webhooks/{id}", h.ShowEndpoint)
+	mux.Handle("GET /admin/webhooks", requires(auth.PermWebhooksManage, h.ListEndpoints))
+	mux.Handle("GET /admin/webhooks/new", requires(auth.PermWebhooksManage, h.NewEndpoint))
+	mux.Handle("POST /admin/webhooks", requires(auth.PermWebhooksManage, h.CreateEndpoint))
+	mux.Handle("GET /admin/webhooks/{id}/edit", requires(auth.PermWebhooksManage, h.EditEndpoint))
+	mux.Handle("POST /admin/webhooks/{id}", requires(auth.PermWebhooksManage, h.UpdateEndpoint))
+	mux.Handle("POST /admin/webhooks/{id}/delete", requires(auth.PermWebhooksManage, h.DeleteEndpoint))
+	mux.Handle("GET /admin/webhooks/{id}", requires(auth.PermWebhooksManage, h.ShowEndpoint))
+	mux.Handle("POST /admin/webhooks/{id}/deliveries/{deliveryID}/redeliver", requires(auth.PermWebhooksManage, h.RedeliverDelivery))
 }
 
 // ListEndpoints handles GET /admin/webhooks.
@@ -70,6 +64,7 @@ func (h *WebhookHandler) ListEndpoints(w http.ResponseWriter, r *http.Request) {
 			Description: derefString(ep.Description),
 			Events:      strings.Join(ep.Events, ", "),
 			IsActive:    ep.IsActive,
+			Disabled:    ep.DisabledAt.Valid,
 			CreatedAt:   ep.CreatedAt.Format("2006-01-02 15:04"),
 		})
 	}
@@ -346,11 +341,19 @@ func (h *WebhookHandler) ShowEndpoint(w http.ResponseWriter, r *http.Request) {
 			deliveredAt = d.DeliveredAt.Time.Format("2006-01-02 15:04")
 		}
 
+		var nextRetryAt string
+		if d.Status == webhook.DeliveryPending && d.Attempt > 0 && d.NextRetryAt.Valid {
+			nextRetryAt = d.NextRetryAt.Time.Format("2006-01-02 15:04")
+		}
+
 		deliveryItems = append(deliveryItems, admin.WebhookDeliveryItem{
 			ID:             d.ID.String(),
 			EventType:      d.EventType,
+			Status:         d.Status,
 			ResponseStatus: responseStatus,
+			LastError:      derefString(d.LastError),
 			Attempt:        int(d.Attempt),
+			NextRetryAt:    nextRetryAt,
 			DeliveredAt:    deliveredAt,
 			CreatedAt:      d.CreatedAt.Format("2006-01-02 15:04"),
 		})
@@ -358,12 +361,14 @@ func (h *WebhookHandler) ShowEndpoint(w http.ResponseWriter, r *http.Request) {
 
 	data := admin.WebhookDetailData{
 		Endpoint: admin.WebhookEndpointItem{
-			ID:          ep.ID.String(),
-			Url:         ep.Url,
-			Description: derefString(ep.Description),
-			Events:      strings.Join(ep.Events, ", "),
-			IsActive:    ep.IsActive,
-			CreatedAt:   ep.CreatedAt.Format("2006-01-02 15:04"),
+			ID:             ep.ID.String(),
+			Url:            ep.Url,
+			Description:    derefString(ep.Description),
+			Events:         strings.Join(ep.Events, ", "),
+			IsActive:       ep.IsActive,
+			Disabled:       ep.DisabledAt.Valid,
+			DisabledReason: derefString(ep.DisabledReason),
+			CreatedAt:      ep.CreatedAt.Format("2006-01-02 15:04"),
 		},
 		Deliveries: deliveryItems,
 		CSRFToken:  csrfToken,
@@ -371,5 +376,49 @@ func (h *WebhookHandler) ShowEndpoint(w http.ResponseWriter, r *http.Request) {
 		TotalPages: totalPages,
 	}
 
+	switch r.URL.Query().Get("redeliver") {
+	case "queued":
+		data.Success = "Delivery queued for redelivery."
+	case "inactive":
+		data.Error = "Activate the endpoint before redelivering."
+	}
+
 	admin.WebhookDetailPage(data).Render(r.Context(), w)
 }
+
+// RedeliverDelivery handles POST /admin/webhooks/{id}/deliveries/{deliveryID}/redeliver.
+// It queues a new delivery with the original payload and returns to the detail page.
+func (h *WebhookHandler) RedeliverDelivery(w http.ResponseWriter, r *http.Request) {
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid endpoint ID", http.StatusBadRequest)
+		return
+	}
+
+	deliveryID, err := uuid.Parse(r.PathValue("deliveryID"))
+	if err != nil {
+		http.Error(w, "Invalid delivery ID", http.StatusBadRequest)
+		return
+	}
+
+	detailURL := "/admin/webhooks/" + id.String()
+
+	_, err = h.webhooks.Redeliver(r.Context(), id, deliveryID)
+	switch {
+	case errors.Is(err, webhook.ErrNotFound):
+		http.Error(w, "Webhook endpoint not found", http.StatusNotFound)
+		return
+	case errors.Is(err, webhook.ErrDeliveryNotFound):
+		http.Error(w, "Webhook delivery not found", http.StatusNotFound)
+		return
+	case errors.Is(err, webhook.ErrEndpointInactive):
+		http.Redirect(w, r, detailURL+"?redeliver=inactive", http.StatusSeeOther)
+		return
+	case err != nil:
+		h.logger.Error("failed to redeliver webhook", "error", err, "endpoint_id", id, "delivery_id", deliveryID)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	http.Redirect(w, r, detailURL+"?redeliver=queued", http.StatusSeeOther)
+}
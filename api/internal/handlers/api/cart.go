@@ -5,27 +5,33 @@ import (
 	"errors"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
 
+	db "github.com/forgecommerce/api/internal/database/gen"
 	"github.com/forgecommerce/api/internal/services/cart"
+	"github.com/forgecommerce/api/internal/services/discount"
 )
 
 // CartHandler holds dependencies for cart API endpoints.
 type CartHandler struct {
-	cartSvc *cart.Service
-	logger  *slog.Logger
+	cartSvc     *cart.Service
+	discountSvc *discount.Service
+	logger      *slog.Logger
 }
 
 // NewCartHandler creates a new cart handler.
-func NewCartHandler(cartSvc *cart.Service, logger *slog.Logger) *CartHandler {
+func NewCartHandler(cartSvc *cart.Service, discountSvc *discount.Service, logger *slog.Logger) *CartHandler {
 	if logger == nil {
 		logger = slog.Default()
 	}
 	return &CartHandler{
-		cartSvc: cartSvc,
-		logger:  logger,
+		cartSvc:     cartSvc,
+		discountSvc: discountSvc,
+		logger:      logger,
 	}
 }
 
@@ -42,22 +48,29 @@ func (h *CartHandler) RegisterRoutes(mux *http.ServeMux) {
 // --- JSON request/response types ---
 
 type createCartResponse struct {
-	ID        uuid.UUID  `json:"id"`
-	ExpiresAt string     `json:"expires_at"`
-	CreatedAt string     `json:"created_at"`
+	ID        uuid.UUID `json:"id"`
+	ExpiresAt string    `json:"expires_at"`
+	CreatedAt string    `json:"created_at"`
 }
 
 type cartResponse struct {
-	ID          uuid.UUID       `json:"id"`
-	CustomerID  *uuid.UUID      `json:"customer_id"`
-	Email       *string         `json:"email"`
-	CountryCode *string         `json:"country_code"`
-	VatNumber   *string         `json:"vat_number"`
-	CouponCode  *string         `json:"coupon_code"`
-	ExpiresAt   string          `json:"expires_at"`
-	CreatedAt   string          `json:"created_at"`
-	UpdatedAt   string          `json:"updated_at"`
-	Items       []cartItemJSON  `json:"items"`
+	ID          uuid.UUID      `json:"id"`
+	CustomerID  *uuid.UUID     `json:"customer_id"`
+	Email       *string        `json:"email"`
+	CountryCode *string        `json:"country_code"`
+	VatNumber   *string        `json:"vat_number"`
+	CouponCode  *string        `json:"coupon_code"`
+	ExpiresAt   string         `json:"expires_at"`
+	CreatedAt   string         `json:"created_at"`
+	UpdatedAt   string         `json:"updated_at"`
+	Items       []cartItemJSON `json:"items"`
+	// Subtotal is the sum of item prices before VAT and shipping, which
+	// depend on the destination. DiscountAmount only covers discounts that
+	// can be computed without them; checkout/calculate has the full totals.
+	Subtotal       string         `json:"subtotal"`
+	DiscountAmount string         `json:"discount_amount"`
+	Discounts      []discountJSON `json:"discounts"`
+	CouponError    *string        `json:"coupon_error,omitempty"`
 }
 
 type cartItemJSON struct {
@@ -142,24 +155,7 @@ func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	cartItems := make([]cartItemJSON, len(items))
-	for i, item := range items {
-		cartItems[i] = cartItemJSON{
-			ID:                 item.ID,
-			VariantID:          item.VariantID,
-			Quantity:           item.Quantity,
-			VariantSku:         item.VariantSku,
-			VariantPrice:       item.VariantPrice,
-			VariantStock:       item.VariantStock,
-			VariantWeightGrams: item.VariantWeightGrams,
-			VariantIsActive:    item.VariantIsActive,
-			ProductID:          item.ProductID,
-			ProductName:        item.ProductName,
-			ProductSlug:        item.ProductSlug,
-			ProductBasePrice:   item.ProductBasePrice,
-			ProductVatCategoryID: pgtypeUUIDToPtr(item.ProductVatCategoryID),
-		}
-	}
+	cartItems, subtotal := newCartItemsJSON(items)
 
 	resp := cartResponse{
 		ID:          c.ID,
@@ -172,6 +168,21 @@ func (h *CartHandler) GetCart(w http.ResponseWriter, r *http.Request) {
 		CreatedAt:   c.CreatedAt.Format("2006-01-02T15:04:05Z"),
 		UpdatedAt:   c.UpdatedAt.Format("2006-01-02T15:04:05Z"),
 		Items:       cartItems,
+		Subtotal:    subtotal.StringFixed(2),
+	}
+
+	params := cartDiscountParams(c, items, nil, "", "", subtotal, decimal.Zero)
+	discounts, couponErr, err := applyCartDiscounts(r.Context(), h.discountSvc, params)
+	if err != nil {
+		h.logger.Error("failed to apply cart discounts", "error", err, "cart_id", cartID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return
+	}
+	resp.DiscountAmount = numericToDecimal(discounts.TotalDiscount).StringFixed(2)
+	resp.Discounts = discountsJSON(discounts)
+	if couponErr != nil {
+		msg := couponErrorMessage(couponErr)
+		resp.CouponError = &msg
 	}
 
 	writeJSON(w, http.StatusOK, resp)
@@ -191,6 +202,37 @@ func (h *CartHandler) UpdateCart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A coupon is checked when it is entered so the customer hears about a
+	// typo straight away. An empty code removes the coupon.
+	if req.CouponCode != nil {
+		code := strings.TrimSpace(strings.ToUpper(*req.CouponCode))
+		if code == "" {
+			req.CouponCode = nil
+		} else {
+			req.CouponCode = &code
+			existing, err := h.cartSvc.Get(r.Context(), cartID)
+			if err != nil {
+				if errors.Is(err, cart.ErrNotFound) {
+					writeJSON(w, http.StatusNotFound, errorJSON{Error: "cart not found"})
+					return
+				}
+				h.logger.Error("failed to get cart", "error", err, "cart_id", cartID)
+				writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+				return
+			}
+			customerID, email := couponShopper(existing, req.Email)
+			if _, err := h.discountSvc.ValidateCoupon(r.Context(), code, customerID, email); err != nil {
+				if isCouponError(err) {
+					writeJSON(w, http.StatusBadRequest, errorJSON{Error: couponErrorMessage(err)})
+					return
+				}
+				h.logger.Error("failed to validate coupon", "error", err, "cart_id", cartID)
+				writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+				return
+			}
+		}
+	}
+
 	c, err := h.cartSvc.Update(r.Context(), cartID, cart.UpdateParams{
 		Email:       req.Email,
 		CountryCode: req.CountryCode,
@@ -213,6 +255,47 @@ func (h *CartHandler) UpdateCart(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// newCartItemsJSON converts cart items for the API and sums their prices.
+func newCartItemsJSON(items []db.GetCartItemsRow) ([]cartItemJSON, decimal.Decimal) {
+	subtotal := decimal.Zero
+	cartItems := make([]cartItemJSON, len(items))
+	for i, item := range items {
+		subtotal = subtotal.Add(itemPrice(item).Mul(decimal.NewFromInt32(item.Quantity)))
+		cartItems[i] = cartItemJSON{
+			ID:                   item.ID,
+			VariantID:            item.VariantID,
+			Quantity:             item.Quantity,
+			VariantSku:           item.VariantSku,
+			VariantPrice:         item.VariantPrice,
+			VariantStock:         item.VariantStock,
+			VariantWeightGrams:   item.VariantWeightGrams,
+			VariantIsActive:      item.VariantIsActive,
+			ProductID:            item.ProductID,
+			ProductName:          item.ProductName,
+			ProductSlug:          item.ProductSlug,
+			ProductBasePrice:     item.ProductBasePrice,
+			ProductVatCategoryID: pgtypeUUIDToPtr(item.ProductVatCategoryID),
+		}
+	}
+	return cartItems, subtotal
+}
+
+// couponShopper identifies who is using a cart's coupon, for per-customer
+// limits. An email in the same update takes precedence over the stored one.
+func couponShopper(c db.Cart, email *string) (uuid.UUID, string) {
+	var customerID uuid.UUID
+	if c.CustomerID.Valid {
+		customerID = c.CustomerID.Bytes
+	}
+	if email == nil {
+		email = c.Email
+	}
+	if email == nil {
+		return customerID, ""
+	}
+	return customerID, *email
+}
+
 // AddItem handles POST /api/v1/cart/{id}/items
 func (h *CartHandler) AddItem(w http.ResponseWriter, r *http.Request) {
 	cartID, err := uuid.Parse(r.PathValue("id"))
@@ -0,0 +1,75 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/forgecommerce/api/internal/services/recovery"
+)
+
+// CartRecoveryHandler restores carts from the link in an abandoned cart
+// reminder.
+type CartRecoveryHandler struct {
+	recoverySvc *recovery.Service
+	logger      *slog.Logger
+}
+
+// NewCartRecoveryHandler creates a new cart recovery handler.
+func NewCartRecoveryHandler(recoverySvc *recovery.Service, logger *slog.Logger) *CartRecoveryHandler {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &CartRecoveryHandler{
+		recoverySvc: recoverySvc,
+		logger:      logger,
+	}
+}
+
+// RegisterRoutes registers the cart restore route on the given mux.
+func (h *CartRecoveryHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("POST /api/v1/cart/restore", h.Restore)
+}
+
+type restoreCartRequest struct {
+	Token string `json:"token"`
+}
+
+type restoreCartResponse struct {
+	CartID     uuid.UUID `json:"cart_id"`
+	CouponCode *string   `json:"coupon_code"`
+	ExpiresAt  string    `json:"expires_at"`
+}
+
+// Restore handles POST /api/v1/cart/restore. The storefront calls it with
+// the token from the reminder link and then loads the returned cart.
+func (h *CartRecoveryHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	var req restoreCartRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "token is required"})
+		return
+	}
+
+	c, err := h.recoverySvc.Restore(r.Context(), req.Token)
+	if err != nil {
+		switch {
+		case errors.Is(err, recovery.ErrInvalidLink):
+			writeJSON(w, http.StatusBadRequest, errorJSON{Error: "invalid or expired restore link"})
+		case errors.Is(err, recovery.ErrCartUnavailable):
+			writeJSON(w, http.StatusNotFound, errorJSON{Error: "cart is no longer available"})
+		default:
+			h.logger.Error("failed to restore cart", "error", err)
+			writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		}
+		return
+	}
+
+	writeJSON(w, http.StatusOK, restoreCartResponse{
+		CartID:     c.ID,
+		CouponCode: c.CouponCode,
+		ExpiresAt:  c.ExpiresAt.Format("2006-01-02T15:04:05Z"),
+	})
+}
@@ -0,0 +1,34 @@
+package api_test
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/forgecommerce/api/internal/auth"
+	"github.com/forgecommerce/api/internal/email"
+	"github.com/forgecommerce/api/internal/handlers/api"
+	"github.com/forgecommerce/api/internal/services/discount"
+	"github.com/forgecommerce/api/internal/services/recovery"
+)
+
+func TestRestoreCart_InvalidToken(t *testing.T) {
+	testDB.Truncate(t)
+
+	emailSvc := email.NewService(testDB.Pool, nil, email.Config{}, nil)
+	recoverySvc := recovery.NewService(testDB.Pool, discount.NewService(testDB.Pool, nil), emailSvc, auth.NewJWTManager(testJWTSecret), recovery.Config{}, nil)
+	mux := http.NewServeMux()
+	api.NewCartRecoveryHandler(recoverySvc, slog.Default()).RegisterRoutes(mux)
+
+	for _, body := range []string{`{}`, `{"token":"not-a-token"}`} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/cart/restore", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusBadRequest {
+			t.Errorf("%s: status got %d, want %d", body, rr.Code, http.StatusBadRequest)
+		}
+	}
+}
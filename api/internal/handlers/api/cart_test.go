@@ -2,6 +2,7 @@ package api_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -13,11 +14,12 @@ import (
 
 	"github.com/forgecommerce/api/internal/handlers/api"
 	"github.com/forgecommerce/api/internal/services/cart"
+	"github.com/forgecommerce/api/internal/services/discount"
 )
 
 func newCartHandler() *api.CartHandler {
 	cartSvc := cart.NewService(testDB.Pool, nil)
-	return api.NewCartHandler(cartSvc, nil)
+	return api.NewCartHandler(cartSvc, discount.NewService(testDB.Pool, nil), nil)
 }
 
 func cartMux() *http.ServeMux {
@@ -1068,6 +1070,7 @@ func TestUpdateCart_AllFields(t *testing.T) {
 		ID string `json:"id"`
 	}
 	json.NewDecoder(createRR.Body).Decode(&created)
+	createCoupon(t, "SAVE10")
 
 	body, _ := json.Marshal(map[string]string{
 		"email":        "full@test.com",
@@ -1097,6 +1100,158 @@ func TestUpdateCart_AllFields(t *testing.T) {
 	}
 }
 
+func TestUpdateCart_InvalidCoupon(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	mux := cartMux()
+
+	createRR := httptest.NewRecorder()
+	mux.ServeHTTP(createRR, httptest.NewRequest(http.MethodPost, "/api/v1/cart", nil))
+	var created struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(createRR.Body).Decode(&created)
+
+	body, _ := json.Marshal(map[string]string{"coupon_code": "NOSUCH"})
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/cart/"+created.ID, bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status: got %d, want %d\nbody: %s", rr.Code, http.StatusBadRequest, rr.Body.String())
+	}
+
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(rr.Body).Decode(&errResp)
+	if errResp.Error != "coupon code is not valid" {
+		t.Errorf("error: got %q, want %q", errResp.Error, "coupon code is not valid")
+	}
+}
+
+func TestUpdateCart_CouponAlreadyUsedByGuest(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	mux := cartMux()
+	ctx := context.Background()
+
+	discountID, _ := createCoupon(t, "FIRSTONLY")
+	svc := discount.NewService(testDB.Pool, nil)
+	perCustomer := int32(1)
+	c, err := svc.CreateCoupon(ctx, discount.CreateCouponParams{
+		Code: "ONCEEACH", DiscountID: discountID, UsageLimitPerCustomer: &perCustomer, IsActive: true,
+	})
+	if err != nil {
+		t.Fatalf("creating coupon: %v", err)
+	}
+	if _, err := testDB.Pool.Exec(ctx,
+		`INSERT INTO coupon_usage (coupon_id, email) VALUES ($1, 'repeat@example.com')`, c.ID); err != nil {
+		t.Fatalf("recording coupon usage: %v", err)
+	}
+
+	createRR := httptest.NewRecorder()
+	mux.ServeHTTP(createRR, httptest.NewRequest(http.MethodPost, "/api/v1/cart", nil))
+	var created struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(createRR.Body).Decode(&created)
+
+	body, _ := json.Marshal(map[string]string{
+		"email":       "Repeat@Example.com",
+		"coupon_code": "onceeach",
+	})
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/cart/"+created.ID, bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status: got %d, want %d\nbody: %s", rr.Code, http.StatusBadRequest, rr.Body.String())
+	}
+	var errResp struct {
+		Error string `json:"error"`
+	}
+	json.NewDecoder(rr.Body).Decode(&errResp)
+	if errResp.Error != "you have already used this coupon" {
+		t.Errorf("error: got %q, want %q", errResp.Error, "you have already used this coupon")
+	}
+}
+
+func TestGetCart_CouponDiscount(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	mux := cartMux()
+
+	p := testDB.FixtureProduct(t, "Cart Coupon", "cart-coupon")
+	v := testDB.FixtureVariant(t, p.ID, "CART-CPN", 10)
+	cartID := createCartWithItem(t, v.ID) // 2 x 25.00
+	createCoupon(t, "SAVE10")
+
+	body, _ := json.Marshal(map[string]string{"coupon_code": " save10 "})
+	req := httptest.NewRequest(http.MethodPatch, "/api/v1/cart/"+cartID.String(), bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("PATCH status: got %d, want %d\nbody: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/api/v1/cart/"+cartID.String(), nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("GET status: got %d, want %d\nbody: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var resp struct {
+		CouponCode     *string `json:"coupon_code"`
+		Subtotal       string  `json:"subtotal"`
+		DiscountAmount string  `json:"discount_amount"`
+		Discounts      []struct {
+			Name string `json:"name"`
+		} `json:"discounts"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+
+	if resp.CouponCode == nil || *resp.CouponCode != "SAVE10" {
+		t.Errorf("coupon_code: got %v, want SAVE10", resp.CouponCode)
+	}
+	if resp.Subtotal != "50.00" {
+		t.Errorf("subtotal: got %q, want %q", resp.Subtotal, "50.00")
+	}
+	if resp.DiscountAmount != "10.00" {
+		t.Errorf("discount_amount: got %q, want %q", resp.DiscountAmount, "10.00")
+	}
+	if len(resp.Discounts) != 1 {
+		t.Errorf("discounts: got %d entries, want 1", len(resp.Discounts))
+	}
+}
+
+// createCoupon creates an active coupon worth 10.00 off the subtotal and
+// returns the discount and coupon IDs.
+func createCoupon(t *testing.T, code string) (discountID, couponID uuid.UUID) {
+	t.Helper()
+	ctx := context.Background()
+	svc := discount.NewService(testDB.Pool, nil)
+	d, err := svc.CreateDiscount(ctx, discount.CreateDiscountParams{
+		Name:     code,
+		Type:     "fixed_amount",
+		Value:    decimalToNumeric(t, "10.00"),
+		Scope:    "subtotal",
+		IsActive: true,
+	})
+	if err != nil {
+		t.Fatalf("creating discount: %v", err)
+	}
+	c, err := svc.CreateCoupon(ctx, discount.CreateCouponParams{
+		Code:       code,
+		DiscountID: d.ID,
+		IsActive:   true,
+	})
+	if err != nil {
+		t.Fatalf("creating coupon: %v", err)
+	}
+	return d.ID, c.ID
+}
+
 // --------------------------------------------------------------------------
 // Error response body validation
 // --------------------------------------------------------------------------
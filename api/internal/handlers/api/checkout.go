@@ -7,30 +7,44 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/shopspring/decimal"
 	stripe "github.com/stripe/stripe-go/v82"
 	checkoutsession "github.com/stripe/stripe-go/v82/checkout/session"
+	stripecoupon "github.com/stripe/stripe-go/v82/coupon"
 
 	db "github.com/forgecommerce/api/internal/database/gen"
 	"github.com/forgecommerce/api/internal/services/cart"
+	"github.com/forgecommerce/api/internal/services/customer"
+	"github.com/forgecommerce/api/internal/services/discount"
+	"github.com/forgecommerce/api/internal/services/inventory"
 	"github.com/forgecommerce/api/internal/services/order"
 	"github.com/forgecommerce/api/internal/services/shipping"
 	"github.com/forgecommerce/api/internal/vat"
 )
 
+// checkoutSessionTTL is how long a Stripe Checkout Session stays open, and
+// therefore how long its stock reservation is held. Stripe requires at
+// least 30 minutes.
+const checkoutSessionTTL = time.Hour
+
 // CheckoutHandler holds dependencies for checkout API endpoints.
 type CheckoutHandler struct {
-	cartSvc     *cart.Service
-	orderSvc    *order.Service
-	vatSvc      *vat.VATService
-	shippingSvc *shipping.Service
-	queries     *db.Queries
-	logger      *slog.Logger
-	successURL  string
-	cancelURL   string
+	cartSvc      *cart.Service
+	orderSvc     *order.Service
+	vatSvc       *vat.VATService
+	shippingSvc  *shipping.Service
+	discountSvc  *discount.Service
+	inventorySvc *inventory.Service
+	customerSvc  *customer.Service
+	queries      *db.Queries
+	logger       *slog.Logger
+	successURL   string
+	cancelURL    string
 }
 
 // NewCheckoutHandler creates a new checkout handler with all required dependencies.
@@ -39,6 +53,9 @@ func NewCheckoutHandler(
 	orderSvc *order.Service,
 	vatSvc *vat.VATService,
 	shippingSvc *shipping.Service,
+	discountSvc *discount.Service,
+	inventorySvc *inventory.Service,
+	customerSvc *customer.Service,
 	queries *db.Queries,
 	logger *slog.Logger,
 	successURL string,
@@ -48,14 +65,17 @@ func NewCheckoutHandler(
 		logger = slog.Default()
 	}
 	return &CheckoutHandler{
-		cartSvc:     cartSvc,
-		orderSvc:    orderSvc,
-		vatSvc:      vatSvc,
-		shippingSvc: shippingSvc,
-		queries:     queries,
-		logger:      logger,
-		successURL:  successURL,
-		cancelURL:   cancelURL,
+		cartSvc:      cartSvc,
+		orderSvc:     orderSvc,
+		vatSvc:       vatSvc,
+		shippingSvc:  shippingSvc,
+		discountSvc:  discountSvc,
+		inventorySvc: inventorySvc,
+		customerSvc:  customerSvc,
+		queries:      queries,
+		logger:       logger,
+		successURL:   successURL,
+		cancelURL:    cancelURL,
 	}
 }
 
@@ -74,12 +94,32 @@ type createCheckoutRequest struct {
 	VatNumber       string          `json:"vat_number"`
 	BillingAddress  json.RawMessage `json:"billing_address"`
 	ShippingAddress json.RawMessage `json:"shipping_address"`
+	// BillingAddressID and ShippingAddressID select saved addresses of the
+	// cart's customer instead of raw addresses. With a shipping address ID,
+	// country_code defaults to the address's country.
+	BillingAddressID  *uuid.UUID `json:"billing_address_id"`
+	ShippingAddressID *uuid.UUID `json:"shipping_address_id"`
+	// Language is the language for order emails, e.g. "de". Defaults to the
+	// Accept-Language header.
+	Language string `json:"language"`
 }
 
 type createCheckoutResponse struct {
 	CheckoutURL string `json:"checkout_url"`
 }
 
+type outOfStockResponse struct {
+	Error string           `json:"error"`
+	Items []outOfStockItem `json:"items"`
+}
+
+type outOfStockItem struct {
+	VariantID uuid.UUID `json:"variant_id"`
+	SKU       string    `json:"sku"`
+	Requested int32     `json:"requested"`
+	Available int32     `json:"available"`
+}
+
 type calculateRequest struct {
 	CartID      uuid.UUID `json:"cart_id"`
 	CountryCode string    `json:"country_code"`
@@ -91,11 +131,21 @@ type calculateResponse struct {
 	VatTotal       string             `json:"vat_total"`
 	ShippingFee    string             `json:"shipping_fee"`
 	DiscountAmount string             `json:"discount_amount"`
+	Discounts      []discountJSON     `json:"discounts"`
+	CouponError    *string            `json:"coupon_error,omitempty"`
 	Total          string             `json:"total"`
 	VatBreakdown   []vatBreakdownItem `json:"vat_breakdown"`
 	ReverseCharge  bool               `json:"reverse_charge"`
 }
 
+// discountJSON is one applied discount in cart and checkout totals.
+type discountJSON struct {
+	DiscountID uuid.UUID `json:"discount_id"`
+	Name       string    `json:"name"`
+	Scope      string    `json:"scope"`
+	Amount     string    `json:"amount"`
+}
+
 type vatBreakdownItem struct {
 	ProductName string `json:"product_name"`
 	Rate        string `json:"rate"`
@@ -123,10 +173,18 @@ func (h *CheckoutHandler) CreateCheckout(w http.ResponseWriter, r *http.Request)
 		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "email is required"})
 		return
 	}
-	if req.CountryCode == "" {
+	if req.CountryCode == "" && req.ShippingAddressID == nil {
 		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "country_code is required"})
 		return
 	}
+	if req.BillingAddressID != nil && len(req.BillingAddress) > 0 {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "send either billing_address or billing_address_id"})
+		return
+	}
+	if req.ShippingAddressID != nil && len(req.ShippingAddress) > 0 {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "send either shipping_address or shipping_address_id"})
+		return
+	}
 
 	ctx := r.Context()
 
@@ -142,6 +200,23 @@ func (h *CheckoutHandler) CreateCheckout(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	// Replace saved address IDs with snapshots of the addresses, so the
+	// order keeps them even if the address book changes later.
+	if err := h.resolveSavedAddresses(ctx, c, &req); err != nil {
+		switch {
+		case errors.Is(err, errSavedAddressNeedsCustomer):
+			writeJSON(w, http.StatusBadRequest, errorJSON{Error: "saved addresses can only be used with a customer's cart"})
+		case errors.Is(err, customer.ErrAddressNotFound):
+			writeJSON(w, http.StatusNotFound, errorJSON{Error: "address not found"})
+		case errors.Is(err, errShippingAddressCountry):
+			writeJSON(w, http.StatusBadRequest, errorJSON{Error: "country_code does not match the shipping address"})
+		default:
+			h.logger.Error("failed to load saved addresses for checkout", "error", err, "cart_id", c.ID)
+			writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		}
+		return
+	}
+
 	// Step 2: Load cart items.
 	items, err := h.cartSvc.ListItems(ctx, c.ID)
 	if err != nil {
@@ -188,7 +263,22 @@ func (h *CheckoutHandler) CreateCheckout(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	// Step 7: Build Stripe line items.
+	// Step 7: Apply automatic discounts and the cart's coupon. A coupon that
+	// has become unusable since it was added fails the checkout rather than
+	// silently charging more than the customer was shown.
+	discounts, couponErr, err := h.applyDiscounts(ctx, c, items, vatResults, req.CountryCode, req.Email, vatSummary.TotalGross, shippingResult.TotalFee)
+	if err != nil {
+		h.logger.Error("discount calculation failed during checkout", "error", err, "cart_id", c.ID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "failed to calculate discounts"})
+		return
+	}
+	if couponErr != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: couponErrorMessage(couponErr)})
+		return
+	}
+	discountAmount := numericToDecimal(discounts.TotalDiscount)
+
+	// Step 8: Build Stripe line items.
 	// Each item's gross price (including VAT) is sent to Stripe as the unit amount.
 	stripeLineItems := make([]*stripe.CheckoutSessionLineItemParams, 0, len(items)+1)
 	for i, item := range items {
@@ -221,12 +311,93 @@ func (h *CheckoutHandler) CreateCheckout(w http.ResponseWriter, r *http.Request)
 		})
 	}
 
-	// Step 8: Create the Stripe Checkout Session.
+	// Step 9: Reserve stock for the cart. The reservation lives as long as
+	// the Stripe session and is committed by the payment webhook. A session
+	// the customer left open would keep its payment page after its stock is
+	// handed to the new one, so it is expired first.
+	if err := h.expireOpenCheckouts(ctx, c.ID); err != nil {
+		if errors.Is(err, errCheckoutAlreadyPaid) {
+			writeJSON(w, http.StatusConflict, errorJSON{Error: "this cart has already been paid for"})
+			return
+		}
+		h.logger.Error("failed to expire previous checkout session", "error", err, "cart_id", c.ID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "failed to create checkout session"})
+		return
+	}
+	expiresAt := time.Now().Add(checkoutSessionTTL)
+	lines := make([]inventory.ReservationLine, len(items))
+	for i, item := range items {
+		lines[i] = inventory.ReservationLine{VariantID: item.VariantID, Quantity: item.Quantity}
+	}
+	if err := h.inventorySvc.ReserveCart(ctx, c.ID, lines, expiresAt); err != nil {
+		var shortage *inventory.ShortageError
+		if errors.As(err, &shortage) {
+			resp := outOfStockResponse{Error: "insufficient stock"}
+			for _, sh := range shortage.Shortages {
+				resp.Items = append(resp.Items, outOfStockItem{
+					VariantID: sh.VariantID,
+					SKU:       sh.SKU,
+					Requested: sh.Requested,
+					Available: sh.Available,
+				})
+			}
+			writeJSON(w, http.StatusConflict, resp)
+			return
+		}
+		h.logger.Error("failed to reserve stock for checkout", "error", err, "cart_id", c.ID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return
+	}
+
+	// Stripe Checkout has no negative line items; the discount is a one-off
+	// Stripe coupon for the exact amount. It is created once the stock is
+	// held, so a shortage leaves no coupon behind.
+	var stripeDiscounts []*stripe.CheckoutSessionDiscountParams
+	couponID := ""
+	if discountAmount.IsPositive() {
+		couponID, err = createStripeDiscount(discountAmount)
+		if err != nil {
+			h.logger.Error("failed to create Stripe discount coupon", "error", err, "cart_id", c.ID)
+			if relErr := h.inventorySvc.ReleaseCart(ctx, c.ID); relErr != nil {
+				h.logger.Error("failed to release stock reservation", "error", relErr, "cart_id", c.ID)
+			}
+			writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "failed to create checkout session"})
+			return
+		}
+		stripeDiscounts = []*stripe.CheckoutSessionDiscountParams{{Coupon: stripe.String(couponID)}}
+	}
+
+	// Step 10: Fix the lines and the VAT on each as the customer is about to
+	// pay them. The webhook creates the order from this snapshot rather than
+	// from the cart, which can still change while the Stripe page is open.
+	orderLines, reverseCharge, companyName := orderItemInputs(items, vatResults)
+	checkoutID := uuid.New()
+	if err := h.orderSvc.SaveCheckout(ctx, order.Checkout{
+		ID:            checkoutID,
+		CartID:        c.ID,
+		Items:         orderLines,
+		Subtotal:      decimalToNumeric(vatSummary.TotalNet),
+		ReverseCharge: reverseCharge,
+		CompanyName:   strPtrOrNil(companyName),
+	}); err != nil {
+		h.logger.Error("failed to save checkout lines", "error", err, "cart_id", c.ID)
+		h.abandonCheckout(ctx, c.ID, couponID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "failed to create checkout session"})
+		return
+	}
+
+	// Step 11: Create the Stripe Checkout Session.
 	// Metadata carries all info needed to reconstruct the order in the webhook.
 	metadata := map[string]string{
 		"cart_id":      c.ID.String(),
+		"checkout_id":  checkoutID.String(),
 		"country_code": req.CountryCode,
 		"vat_number":   req.VatNumber,
+		"language":     requestLanguage(r, req.Language),
+		"shipping_fee": shippingResult.TotalFee.StringFixed(2),
+	}
+	if shippingResult.Method != "" {
+		metadata["shipping_method"] = shippingResult.Method
 	}
 	if len(req.BillingAddress) > 0 {
 		metadata["billing_address"] = string(req.BillingAddress)
@@ -234,6 +405,18 @@ func (h *CheckoutHandler) CreateCheckout(w http.ResponseWriter, r *http.Request)
 	if len(req.ShippingAddress) > 0 {
 		metadata["shipping_address"] = string(req.ShippingAddress)
 	}
+	if c.CustomerID.Valid {
+		metadata["customer_id"] = uuid.UUID(c.CustomerID.Bytes).String()
+	}
+	if err := setDiscountMetadata(metadata, discounts); errors.Is(err, errDiscountBreakdownTooLarge) {
+		// The order still records which discount and coupon applied.
+		h.logger.Warn("discount breakdown left out of checkout metadata", "error", err, "cart_id", c.ID)
+	} else if err != nil {
+		h.logger.Error("failed to encode discount metadata", "error", err, "cart_id", c.ID)
+		h.abandonCheckout(ctx, c.ID, couponID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return
+	}
 
 	sessionParams := &stripe.CheckoutSessionParams{
 		Mode:          stripe.String(string(stripe.CheckoutSessionModePayment)),
@@ -242,6 +425,8 @@ func (h *CheckoutHandler) CreateCheckout(w http.ResponseWriter, r *http.Request)
 		CancelURL:     stripe.String(h.cancelURL),
 		LineItems:     stripeLineItems,
 		Metadata:      metadata,
+		ExpiresAt:     stripe.Int64(expiresAt.Unix()),
+		Discounts:     stripeDiscounts,
 	}
 
 	session, err := checkoutsession.New(sessionParams)
@@ -251,10 +436,21 @@ func (h *CheckoutHandler) CreateCheckout(w http.ResponseWriter, r *http.Request)
 			"cart_id", c.ID,
 			"email", req.Email,
 		)
+		h.abandonCheckout(ctx, c.ID, couponID)
 		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "failed to create checkout session"})
 		return
 	}
 
+	// Without the session ID the webhook cannot commit the reservation; it
+	// still expires on its own, so log and carry on.
+	if err := h.inventorySvc.AttachCheckoutSession(ctx, c.ID, session.ID); err != nil {
+		h.logger.Error("failed to link stock reservation to checkout session",
+			"error", err,
+			"cart_id", c.ID,
+			"stripe_session_id", session.ID,
+		)
+	}
+
 	h.logger.Info("checkout session created",
 		slog.String("cart_id", c.ID.String()),
 		slog.String("stripe_session_id", session.ID),
@@ -264,6 +460,7 @@ func (h *CheckoutHandler) CreateCheckout(w http.ResponseWriter, r *http.Request)
 		slog.String("subtotal", vatSummary.TotalNet.StringFixed(2)),
 		slog.String("vat_total", vatSummary.TotalVAT.StringFixed(2)),
 		slog.String("shipping", shippingResult.TotalFee.StringFixed(2)),
+		slog.String("discount", discountAmount.StringFixed(2)),
 	)
 
 	writeJSON(w, http.StatusOK, createCheckoutResponse{
@@ -360,25 +557,86 @@ func (h *CheckoutHandler) Calculate(w http.ResponseWriter, r *http.Request) {
 		reverseCharge = true
 	}
 
+	// Apply discounts. An unusable coupon is reported alongside totals
+	// computed without it, so the preview still renders.
+	discounts, couponErr, err := h.applyDiscounts(ctx, c, items, vatResults, req.CountryCode, "", vatSummary.TotalGross, shippingResult.TotalFee)
+	if err != nil {
+		h.logger.Error("discount calculation failed during calculate preview", "error", err, "cart_id", c.ID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "failed to calculate discounts"})
+		return
+	}
+
 	// Compute grand total: gross (net + VAT) + shipping - discounts.
-	discountAmount := decimal.Zero
+	discountAmount := numericToDecimal(discounts.TotalDiscount)
 	total := vatSummary.TotalGross.Add(shippingResult.TotalFee).Sub(discountAmount)
 
-	writeJSON(w, http.StatusOK, calculateResponse{
+	resp := calculateResponse{
 		Subtotal:       vatSummary.TotalNet.StringFixed(2),
 		VatTotal:       vatSummary.TotalVAT.StringFixed(2),
 		ShippingFee:    shippingResult.TotalFee.StringFixed(2),
 		DiscountAmount: discountAmount.StringFixed(2),
+		Discounts:      discountsJSON(discounts),
 		Total:          total.StringFixed(2),
 		VatBreakdown:   breakdown,
 		ReverseCharge:  reverseCharge,
-	})
+	}
+	if couponErr != nil {
+		msg := couponErrorMessage(couponErr)
+		resp.CouponError = &msg
+	}
+	writeJSON(w, http.StatusOK, resp)
 }
 
 // --- Internal helpers ---
 
 var errCountryNotEnabled = errors.New("country not enabled for shipping")
 
+var (
+	errSavedAddressNeedsCustomer = errors.New("saved addresses need a customer cart")
+	errShippingAddressCountry    = errors.New("country code does not match shipping address")
+)
+
+// resolveSavedAddresses replaces the billing and shipping address IDs in req
+// with the addresses they refer to. The addresses must belong to the cart's
+// customer. A saved shipping address also sets the destination country.
+func (h *CheckoutHandler) resolveSavedAddresses(ctx context.Context, c db.Cart, req *createCheckoutRequest) error {
+	if req.BillingAddressID == nil && req.ShippingAddressID == nil {
+		return nil
+	}
+	if !c.CustomerID.Valid {
+		return errSavedAddressNeedsCustomer
+	}
+	customerID := uuid.UUID(c.CustomerID.Bytes)
+
+	if req.BillingAddressID != nil {
+		address, err := h.customerSvc.GetAddress(ctx, customerID, *req.BillingAddressID)
+		if err != nil {
+			return err
+		}
+		if req.BillingAddress, err = customer.OrderAddressJSON(address); err != nil {
+			return err
+		}
+	}
+
+	if req.ShippingAddressID != nil {
+		address, err := h.customerSvc.GetAddress(ctx, customerID, *req.ShippingAddressID)
+		if err != nil {
+			return err
+		}
+		switch {
+		case req.CountryCode == "":
+			req.CountryCode = address.CountryCode
+		case !strings.EqualFold(req.CountryCode, address.CountryCode):
+			return errShippingAddressCountry
+		}
+		if req.ShippingAddress, err = customer.OrderAddressJSON(address); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 // validateCountryEnabled checks that the given country code exists in the
 // store's enabled shipping countries.
 func (h *CheckoutHandler) validateCountryEnabled(ctx context.Context, countryCode string) error {
@@ -398,11 +656,7 @@ func (h *CheckoutHandler) validateCountryEnabled(ctx context.Context, countryCod
 func buildVATInputs(items []db.GetCartItemsRow, countryCode, vatNumber string) []vat.VATInput {
 	inputs := make([]vat.VATInput, len(items))
 	for i, item := range items {
-		// Effective price: variant price if set, otherwise product base price.
-		price := numericToDecimal(item.VariantPrice)
-		if price.IsZero() {
-			price = numericToDecimal(item.ProductBasePrice)
-		}
+		price := itemPrice(item)
 
 		var vatCategoryID *uuid.UUID
 		if item.ProductVatCategoryID.Valid {
@@ -422,6 +676,273 @@ func buildVATInputs(items []db.GetCartItemsRow, countryCode, vatNumber string) [
 	return inputs
 }
 
+// orderItemInputs builds the order lines for a cart from the VAT charged on
+// each line, and reports whether the VAT was reverse charged and to which
+// company.
+func orderItemInputs(items []db.GetCartItemsRow, results []vat.VATResult) ([]order.CreateOrderItemInput, bool, string) {
+	var (
+		reverseCharge bool
+		companyName   string
+	)
+	lines := make([]order.CreateOrderItemInput, len(items))
+	for i, item := range items {
+		res := results[i]
+		if res.ReverseCharge {
+			reverseCharge = true
+			companyName = res.CompanyName
+		}
+
+		sku := item.VariantSku
+		lines[i] = order.CreateOrderItemInput{
+			ProductID:        pgtype.UUID{Bytes: item.ProductID, Valid: true},
+			VariantID:        pgtype.UUID{Bytes: item.VariantID, Valid: true},
+			ProductName:      item.ProductName,
+			Sku:              &sku,
+			Quantity:         item.Quantity,
+			UnitPrice:        decimalToNumeric(res.GrossPrice),
+			TotalPrice:       decimalToNumeric(res.LineGrossTotal),
+			VatRate:          decimalToNumeric(res.Rate),
+			VatRateType:      strPtrOrNil(res.RateType),
+			VatAmount:        decimalToNumeric(res.LineVATTotal),
+			PriceIncludesVat: itemPrice(item).Equal(res.GrossPrice),
+			NetUnitPrice:     decimalToNumeric(res.NetPrice),
+			GrossUnitPrice:   decimalToNumeric(res.GrossPrice),
+			WeightGrams:      item.VariantWeightGrams,
+			Metadata:         json.RawMessage(`{}`),
+		}
+	}
+	return lines, reverseCharge, companyName
+}
+
+// itemPrice returns the effective unit price of a cart item: the variant
+// price if set, otherwise the product base price.
+func itemPrice(item db.GetCartItemsRow) decimal.Decimal {
+	price := numericToDecimal(item.VariantPrice)
+	if price.IsZero() {
+		price = numericToDecimal(item.ProductBasePrice)
+	}
+	return price
+}
+
+// applyDiscounts runs the discount engine over a cart's totals and lines
+// with the cart's coupon. If the coupon can no longer be used, the discounts
+// are applied without it and couponErr says why.
+func (h *CheckoutHandler) applyDiscounts(
+	ctx context.Context,
+	c db.Cart,
+	items []db.GetCartItemsRow,
+	vatResults []vat.VATResult,
+	countryCode, email string,
+	subtotal, shippingFee decimal.Decimal,
+) (result discount.ApplyResult, couponErr error, err error) {
+	params := cartDiscountParams(c, items, vatResults, countryCode, email, subtotal, shippingFee)
+	return applyCartDiscounts(ctx, h.discountSvc, params)
+}
+
+// cartDiscountParams builds the discount engine inputs for a cart. An empty
+// countryCode or email falls back to the one stored on the cart. Lines are
+// priced at the gross prices in vatResults, the same base as a gross
+// subtotal; without VAT results the catalogue price is used.
+func cartDiscountParams(
+	c db.Cart,
+	items []db.GetCartItemsRow,
+	vatResults []vat.VATResult,
+	countryCode, email string,
+	subtotal, shippingFee decimal.Decimal,
+) discount.ApplyParams {
+	params := discount.ApplyParams{
+		Subtotal:    decimalToNumeric(subtotal),
+		ShippingFee: decimalToNumeric(shippingFee),
+		CountryCode: countryCode,
+		Email:       email,
+		Lines:       make([]discount.Line, len(items)),
+	}
+	if c.CustomerID.Valid {
+		params.CustomerID = c.CustomerID.Bytes
+	}
+	if c.CouponCode != nil {
+		params.CouponCode = *c.CouponCode
+	}
+	if params.CountryCode == "" && c.CountryCode != nil {
+		params.CountryCode = *c.CountryCode
+	}
+	if params.Email == "" && c.Email != nil {
+		params.Email = *c.Email
+	}
+	for i, item := range items {
+		price := itemPrice(item)
+		if i < len(vatResults) {
+			price = vatResults[i].GrossPrice
+		}
+		params.Lines[i] = discount.Line{
+			ProductID: item.ProductID,
+			VariantID: item.VariantID,
+			Quantity:  item.Quantity,
+			UnitPrice: decimalToNumeric(price),
+		}
+	}
+	return params
+}
+
+func applyCartDiscounts(
+	ctx context.Context,
+	svc *discount.Service,
+	params discount.ApplyParams,
+) (discount.ApplyResult, error, error) {
+	result, err := svc.Apply(ctx, params)
+	if err == nil || !isCouponError(err) {
+		return result, nil, err
+	}
+
+	couponErr := err
+	params.CouponCode = ""
+	result, err = svc.Apply(ctx, params)
+	return result, couponErr, err
+}
+
+// isCouponError reports whether err means a coupon cannot be used, as
+// opposed to a failure to evaluate it.
+func isCouponError(err error) bool {
+	return errors.Is(err, discount.ErrCouponNotFound) ||
+		errors.Is(err, discount.ErrCouponExpired) ||
+		errors.Is(err, discount.ErrCouponUsageLimitReached) ||
+		errors.Is(err, discount.ErrCouponCustomerLimitReached) ||
+		errors.Is(err, discount.ErrNotFound)
+}
+
+// couponErrorMessage returns the customer-facing message for a coupon error.
+func couponErrorMessage(err error) string {
+	switch {
+	case errors.Is(err, discount.ErrCouponExpired):
+		return "coupon has expired"
+	case errors.Is(err, discount.ErrCouponUsageLimitReached):
+		return "coupon is no longer available"
+	case errors.Is(err, discount.ErrCouponCustomerLimitReached):
+		return "you have already used this coupon"
+	default:
+		return "coupon code is not valid"
+	}
+}
+
+// discountsJSON converts an engine result to its JSON form.
+func discountsJSON(result discount.ApplyResult) []discountJSON {
+	out := make([]discountJSON, len(result.Breakdown))
+	for i, app := range result.Breakdown {
+		out[i] = discountJSON{
+			DiscountID: app.DiscountID,
+			Name:       app.DiscountName,
+			Scope:      app.Scope,
+			Amount:     numericToDecimal(app.Amount).StringFixed(2),
+		}
+	}
+	return out
+}
+
+// createStripeDiscount creates a single-use Stripe coupon worth amount and
+// returns its ID.
+func createStripeDiscount(amount decimal.Decimal) (string, error) {
+	cpn, err := stripecoupon.New(&stripe.CouponParams{
+		AmountOff:      stripe.Int64(decimalToCents(amount)),
+		Currency:       stripe.String("eur"),
+		Duration:       stripe.String(string(stripe.CouponDurationOnce)),
+		MaxRedemptions: stripe.Int64(1),
+		Name:           stripe.String("Discount"),
+	})
+	if err != nil {
+		return "", fmt.Errorf("creating stripe coupon: %w", err)
+	}
+	return cpn.ID, nil
+}
+
+// errCheckoutAlreadyPaid is returned by expireOpenCheckouts when an earlier
+// checkout session for the cart has been paid.
+var errCheckoutAlreadyPaid = errors.New("cart already paid for")
+
+// expireOpenCheckouts expires the Stripe Checkout Sessions still holding stock
+// for a cart, so none of them can be paid once the stock is reserved again.
+func (h *CheckoutHandler) expireOpenCheckouts(ctx context.Context, cartID uuid.UUID) error {
+	sessionIDs, err := h.inventorySvc.CartCheckoutSessions(ctx, cartID)
+	if err != nil {
+		return err
+	}
+	for _, id := range sessionIDs {
+		sess, err := checkoutsession.Get(id, nil)
+		if err != nil {
+			return fmt.Errorf("getting checkout session %s: %w", id, err)
+		}
+		switch sess.Status {
+		case stripe.CheckoutSessionStatusComplete:
+			return errCheckoutAlreadyPaid
+		case stripe.CheckoutSessionStatusOpen:
+			if _, err := checkoutsession.Expire(id, nil); err != nil {
+				return fmt.Errorf("expiring checkout session %s: %w", id, err)
+			}
+			h.logger.Info("expired previous checkout session", "cart_id", cartID, "stripe_session_id", id)
+		}
+	}
+	return nil
+}
+
+// abandonCheckout undoes the stock reservation and Stripe coupon made for a
+// checkout session that could not be created.
+func (h *CheckoutHandler) abandonCheckout(ctx context.Context, cartID uuid.UUID, couponID string) {
+	if err := h.inventorySvc.ReleaseCart(ctx, cartID); err != nil {
+		h.logger.Error("failed to release stock reservation", "error", err, "cart_id", cartID)
+	}
+	if couponID == "" {
+		return
+	}
+	if _, err := stripecoupon.Del(couponID, nil); err != nil {
+		h.logger.Error("failed to delete Stripe discount coupon", "error", err, "cart_id", cartID, "coupon_id", couponID)
+	}
+}
+
+const (
+	// stripeMetadataValueLimit is the longest value Stripe accepts in metadata.
+	stripeMetadataValueLimit = 500
+
+	// maxDiscountBreakdownParts caps the metadata keys the discount breakdown
+	// may use, leaving room under Stripe's 50-key limit for the rest of the
+	// checkout metadata.
+	maxDiscountBreakdownParts = 30
+)
+
+// errDiscountBreakdownTooLarge is returned when the discount breakdown would
+// need more than maxDiscountBreakdownParts metadata keys.
+var errDiscountBreakdownTooLarge = errors.New("discount breakdown too large for checkout metadata")
+
+// setDiscountMetadata records the applied discounts in checkout session
+// metadata so the payment webhook can store them on the order. The
+// breakdown can exceed Stripe's value limit, so it is split over
+// discount_breakdown_0, discount_breakdown_1, ... If that takes too many
+// keys, only the discount and coupon IDs are recorded and
+// errDiscountBreakdownTooLarge is returned.
+func setDiscountMetadata(metadata map[string]string, result discount.ApplyResult) error {
+	if len(result.Breakdown) == 0 {
+		return nil
+	}
+	if result.DiscountID.Valid {
+		metadata["discount_id"] = uuid.UUID(result.DiscountID.Bytes).String()
+	}
+	if result.CouponID.Valid {
+		metadata["coupon_id"] = uuid.UUID(result.CouponID.Bytes).String()
+	}
+
+	b, err := json.Marshal(result.Breakdown)
+	if err != nil {
+		return fmt.Errorf("encoding discount breakdown: %w", err)
+	}
+	if parts := (len(b) + stripeMetadataValueLimit - 1) / stripeMetadataValueLimit; parts > maxDiscountBreakdownParts {
+		return fmt.Errorf("%w: %d parts", errDiscountBreakdownTooLarge, parts)
+	}
+	for i := 0; len(b) > 0; i++ {
+		n := min(len(b), stripeMetadataValueLimit)
+		metadata[fmt.Sprintf("discount_breakdown_%d", i)] = string(b[:n])
+		b = b[n:]
+	}
+	return nil
+}
+
 // calculateShipping delegates to the shipping service to compute the shipping
 // fee for the given cart items and destination country.
 func (h *CheckoutHandler) calculateShipping(
@@ -9,11 +9,15 @@ import (
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/shopspring/decimal"
 
 	db "github.com/forgecommerce/api/internal/database/gen"
 	"github.com/forgecommerce/api/internal/handlers/api"
 	"github.com/forgecommerce/api/internal/services/cart"
+	"github.com/forgecommerce/api/internal/services/customer"
+	"github.com/forgecommerce/api/internal/services/discount"
+	"github.com/forgecommerce/api/internal/services/inventory"
 	"github.com/forgecommerce/api/internal/services/order"
 	"github.com/forgecommerce/api/internal/services/shipping"
 	"github.com/forgecommerce/api/internal/vat"
@@ -23,7 +27,7 @@ import (
 // The VAT rate cache is pre-loaded with standard rates for ES and DE.
 func newCheckoutHandler() *api.CheckoutHandler {
 	cartSvc := cart.NewService(testDB.Pool, nil)
-	orderSvc := order.NewService(testDB.Pool, nil)
+	orderSvc := order.NewService(testDB.Pool, nil, nil)
 	cache := vat.NewRateCache()
 	cache.Load([]vat.VATRate{
 		{CountryCode: "ES", RateType: "standard", Rate: decimal.NewFromFloat(21.0)},
@@ -34,7 +38,8 @@ func newCheckoutHandler() *api.CheckoutHandler {
 	shippingSvc := shipping.NewService(testDB.Pool, nil)
 	queries := db.New(testDB.Pool)
 	return api.NewCheckoutHandler(
-		cartSvc, orderSvc, vatSvc, shippingSvc, queries, nil,
+		cartSvc, orderSvc, vatSvc, shippingSvc, discount.NewService(testDB.Pool, nil),
+		inventory.NewService(testDB.Pool, nil, nil), customer.NewService(testDB.Pool, nil), queries, nil,
 		"https://example.com/success", "https://example.com/cancel",
 	)
 }
@@ -398,6 +403,105 @@ func TestCalculate_HappyPath_VerifyAmounts(t *testing.T) {
 	}
 }
 
+func TestCalculate_AutomaticDiscount(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	seedCheckoutDeps(t)
+	testDB.FixtureShippingCountry(t, "ES")
+	mux := checkoutMux()
+
+	// 10% off the subtotal: gross 50.00 -> 5.00 off, total 50.00 + 5.00 - 5.00.
+	_, err := discount.NewService(testDB.Pool, nil).CreateDiscount(context.Background(), discount.CreateDiscountParams{
+		Name:     "Ten Percent",
+		Type:     "percentage",
+		Value:    decimalToNumeric(t, "10"),
+		Scope:    "subtotal",
+		IsActive: true,
+	})
+	if err != nil {
+		t.Fatalf("creating discount: %v", err)
+	}
+
+	p := testDB.FixtureProduct(t, "Discounted", "discounted")
+	v := testDB.FixtureVariant(t, p.ID, "DISC-001", 10)
+	cartID := createCartWithItem(t, v.ID)
+
+	body, _ := json.Marshal(map[string]string{
+		"cart_id":      cartID.String(),
+		"country_code": "ES",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/checkout/calculate", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want %d\nbody: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var resp struct {
+		DiscountAmount string `json:"discount_amount"`
+		Total          string `json:"total"`
+		Discounts      []struct {
+			Name   string `json:"name"`
+			Amount string `json:"amount"`
+		} `json:"discounts"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+
+	if got := decimalParse(t, resp.DiscountAmount); !got.Equal(decimal.NewFromInt(5)) {
+		t.Errorf("discount_amount: got %s, want 5.00", got)
+	}
+	if got := decimalParse(t, resp.Total); !got.Equal(decimal.NewFromInt(50)) {
+		t.Errorf("total: got %s, want 50.00", got)
+	}
+	if len(resp.Discounts) != 1 || resp.Discounts[0].Name != "Ten Percent" {
+		t.Errorf("discounts: got %+v, want one entry for %q", resp.Discounts, "Ten Percent")
+	}
+}
+
+func TestCalculate_InvalidCouponReported(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	seedCheckoutDeps(t)
+	testDB.FixtureShippingCountry(t, "ES")
+	mux := checkoutMux()
+
+	p := testDB.FixtureProduct(t, "Coupon Product", "coupon-product")
+	v := testDB.FixtureVariant(t, p.ID, "CPN-001", 10)
+	cartID := createCartWithItem(t, v.ID)
+
+	code := "GONE"
+	if _, err := testDB.Pool.Exec(context.Background(),
+		`UPDATE carts SET coupon_code = $2 WHERE id = $1`, cartID, code); err != nil {
+		t.Fatalf("setting coupon code: %v", err)
+	}
+
+	body, _ := json.Marshal(map[string]string{
+		"cart_id":      cartID.String(),
+		"country_code": "ES",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/checkout/calculate", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want %d\nbody: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var resp struct {
+		Total       string  `json:"total"`
+		CouponError *string `json:"coupon_error"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+
+	if resp.CouponError == nil || *resp.CouponError != "coupon code is not valid" {
+		t.Errorf("coupon_error: got %v, want %q", resp.CouponError, "coupon code is not valid")
+	}
+	if got := decimalParse(t, resp.Total); !got.Equal(decimal.NewFromInt(55)) {
+		t.Errorf("total: got %s, want 55.00", got)
+	}
+}
+
 func TestCalculate_CountryNotEnabled(t *testing.T) {
 	testDB.Truncate(t)
 	testDB.SeedEssentials(t)
@@ -694,6 +798,120 @@ func TestCreateCheckout_CountryNotEnabled(t *testing.T) {
 	}
 }
 
+func TestCreateCheckout_SavedAddressRequiresCustomerCart(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	seedCheckoutDeps(t)
+	testDB.FixtureShippingCountry(t, "ES")
+	mux := checkoutMux()
+
+	p := testDB.FixtureProduct(t, "Checkout Guest Address", "checkout-guest-address")
+	v := testDB.FixtureVariant(t, p.ID, "CKGA-001", 10)
+	cartID := createCartWithItem(t, v.ID)
+
+	body, _ := json.Marshal(map[string]string{
+		"cart_id":             cartID.String(),
+		"email":               "test@example.com",
+		"shipping_address_id": uuid.New().String(),
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/checkout", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status: got %d, want %d\nbody: %s", rr.Code, http.StatusBadRequest, rr.Body.String())
+	}
+}
+
+func TestCreateCheckout_SavedAddressOfOtherCustomer(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	seedCheckoutDeps(t)
+	testDB.FixtureShippingCountry(t, "ES")
+	mux := checkoutMux()
+
+	ctx := context.Background()
+	owner := testDB.FixtureCustomer(t, "owner@example.com")
+	other := testDB.FixtureCustomer(t, "other@example.com")
+
+	customerSvc := customer.NewService(testDB.Pool, nil)
+	address, err := customerSvc.CreateAddress(ctx, other.ID, customer.AddressParams{
+		FirstName:    "Ana",
+		LastName:     "Garcia",
+		AddressLine1: "Calle Mayor 1",
+		City:         "Madrid",
+		PostalCode:   "28013",
+		CountryCode:  "ES",
+	})
+	if err != nil {
+		t.Fatalf("creating address: %v", err)
+	}
+
+	p := testDB.FixtureProduct(t, "Checkout Other Address", "checkout-other-address")
+	v := testDB.FixtureVariant(t, p.ID, "CKOA-001", 10)
+	cartID := createCartWithItem(t, v.ID)
+	if _, err := cart.NewService(testDB.Pool, nil).SetCustomer(ctx, cartID, owner.ID); err != nil {
+		t.Fatalf("setting cart customer: %v", err)
+	}
+
+	body, _ := json.Marshal(map[string]string{
+		"cart_id":             cartID.String(),
+		"email":               "owner@example.com",
+		"shipping_address_id": address.ID.String(),
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/checkout", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("status: got %d, want %d\nbody: %s", rr.Code, http.StatusNotFound, rr.Body.String())
+	}
+}
+
+func TestCreateCheckout_InsufficientStock(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	seedCheckoutDeps(t)
+	testDB.FixtureShippingCountry(t, "ES")
+	mux := checkoutMux()
+
+	// The cart holds 2 units but only 1 is in stock.
+	p := testDB.FixtureProduct(t, "Scarce Product", "scarce-product")
+	v := testDB.FixtureVariant(t, p.ID, "SCARCE-001", 1)
+	cartID := createCartWithItem(t, v.ID)
+
+	body, _ := json.Marshal(map[string]string{
+		"cart_id":      cartID.String(),
+		"email":        "test@example.com",
+		"country_code": "ES",
+	})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/checkout", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("status: got %d, want %d\nbody: %s", rr.Code, http.StatusConflict, rr.Body.String())
+	}
+
+	var resp struct {
+		Error string `json:"error"`
+		Items []struct {
+			SKU       string `json:"sku"`
+			Requested int32  `json:"requested"`
+			Available int32  `json:"available"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(rr.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Error != "insufficient stock" {
+		t.Errorf("error message: got %q, want %q", resp.Error, "insufficient stock")
+	}
+	if len(resp.Items) != 1 || resp.Items[0].SKU != "SCARCE-001" || resp.Items[0].Requested != 2 || resp.Items[0].Available != 1 {
+		t.Errorf("unexpected items: %+v", resp.Items)
+	}
+}
+
 func TestCreateCheckout_StripeAPIError(t *testing.T) {
 	testDB.Truncate(t)
 	testDB.SeedEssentials(t)
@@ -727,6 +945,17 @@ func TestCreateCheckout_StripeAPIError(t *testing.T) {
 	if resp.Error != "failed to create checkout session" {
 		t.Errorf("error message: got %q, want %q", resp.Error, "failed to create checkout session")
 	}
+
+	// The stock reserved before the Stripe call must be released again.
+	var active int
+	if err := testDB.Pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM stock_reservations WHERE cart_id = $1 AND status = 'active'`, cartID,
+	).Scan(&active); err != nil {
+		t.Fatalf("counting reservations: %v", err)
+	}
+	if active != 0 {
+		t.Errorf("active reservations after Stripe error: got %d, want 0", active)
+	}
 }
 
 // --------------------------------------------------------------------------
@@ -742,3 +971,12 @@ func decimalParse(t *testing.T, s string) decimal.Decimal {
 	}
 	return d
 }
+
+func decimalToNumeric(t *testing.T, s string) pgtype.Numeric {
+	t.Helper()
+	var n pgtype.Numeric
+	if err := n.Scan(s); err != nil {
+		t.Fatalf("parsing numeric %q: %v", s, err)
+	}
+	return n
+}
@@ -1,52 +1,81 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"log/slog"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/forgecommerce/api/internal/auth"
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/email"
 	"github.com/forgecommerce/api/internal/middleware"
+	"github.com/forgecommerce/api/internal/services/cart"
 	"github.com/forgecommerce/api/internal/services/customer"
+	"github.com/forgecommerce/api/internal/services/order"
 )
 
 // CustomerHandler handles customer authentication and profile endpoints.
 type CustomerHandler struct {
 	customerSvc *customer.Service
+	cartSvc     *cart.Service
+	orderSvc    *order.Service
+	emailSvc    *email.Service
 	jwtMgr      *auth.JWTManager
 	logger      *slog.Logger
 }
 
-// NewCustomerHandler creates a new customer handler.
+// NewCustomerHandler creates a new customer handler. emailSvc may be nil, in
+// which case no welcome, verification or password reset email is sent.
 func NewCustomerHandler(
 	customerSvc *customer.Service,
+	cartSvc *cart.Service,
+	orderSvc *order.Service,
+	emailSvc *email.Service,
 	jwtMgr *auth.JWTManager,
 	logger *slog.Logger,
 ) *CustomerHandler {
 	return &CustomerHandler{
 		customerSvc: customerSvc,
+		cartSvc:     cartSvc,
+		orderSvc:    orderSvc,
+		emailSvc:    emailSvc,
 		jwtMgr:      jwtMgr,
 		logger:      logger,
 	}
 }
 
-// RegisterPublicRoutes registers unauthenticated customer routes (login, register).
+// RegisterPublicRoutes registers unauthenticated customer routes (login,
+// register, password reset, email verification). The token endpoints are
+// rate limited like the admin login so tokens cannot be guessed and reset
+// emails cannot be used to flood an inbox.
 func (h *CustomerHandler) RegisterPublicRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("POST /api/v1/customers/register", h.Register)
 	mux.HandleFunc("POST /api/v1/customers/login", h.Login)
 	mux.HandleFunc("POST /api/v1/customers/refresh", h.RefreshToken)
+	mux.Handle("POST /api/v1/customers/password/forgot", middleware.LoginRateLimiter()(http.HandlerFunc(h.ForgotPassword)))
+	mux.Handle("POST /api/v1/customers/password/reset", middleware.LoginRateLimiter()(http.HandlerFunc(h.ResetPassword)))
+	mux.Handle("POST /api/v1/customers/verify-email", middleware.LoginRateLimiter()(http.HandlerFunc(h.VerifyEmail)))
 }
 
-// RegisterProtectedRoutes registers authenticated customer routes (profile, orders).
+// RegisterProtectedRoutes registers authenticated customer routes (profile,
+// orders, address book).
 func (h *CustomerHandler) RegisterProtectedRoutes(mux *http.ServeMux) {
 	mux.HandleFunc("GET /api/v1/customers/me", h.GetProfile)
 	mux.HandleFunc("PATCH /api/v1/customers/me", h.UpdateProfile)
 	mux.HandleFunc("GET /api/v1/customers/me/orders", h.ListOrders)
+	mux.HandleFunc("POST /api/v1/customers/me/email-verification", h.ResendVerification)
+	mux.HandleFunc("GET /api/v1/customers/me/addresses", h.ListAddresses)
+	mux.HandleFunc("POST /api/v1/customers/me/addresses", h.CreateAddress)
+	mux.HandleFunc("GET /api/v1/customers/me/addresses/{id}", h.GetAddress)
+	mux.HandleFunc("PUT /api/v1/customers/me/addresses/{id}", h.UpdateAddress)
+	mux.HandleFunc("DELETE /api/v1/customers/me/addresses/{id}", h.DeleteAddress)
 }
 
 // --- Request/Response types ---
@@ -56,11 +85,20 @@ type registerRequest struct {
 	Password  string  `json:"password"`
 	FirstName *string `json:"first_name"`
 	LastName  *string `json:"last_name"`
+	// Language is the preferred email language, e.g. "de". Defaults to the
+	// Accept-Language header.
+	Language string `json:"language"`
+	// CartID is the cart the shopper filled before signing up. It is
+	// assigned to the new account.
+	CartID *uuid.UUID `json:"cart_id"`
 }
 
 type loginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// CartID is the cart the shopper filled before signing in. Its items
+	// are merged into the customer's latest cart.
+	CartID *uuid.UUID `json:"cart_id"`
 }
 
 type refreshRequest struct {
@@ -71,15 +109,50 @@ type authResponse struct {
 	AccessToken  string       `json:"access_token"`
 	RefreshToken string       `json:"refresh_token"`
 	Customer     customerJSON `json:"customer"`
+	// Cart is the customer's current cart after merging, if they have one.
+	Cart *authCartJSON `json:"cart,omitempty"`
+}
+
+type authCartJSON struct {
+	ID        uuid.UUID      `json:"id"`
+	ExpiresAt string         `json:"expires_at"`
+	Items     []cartItemJSON `json:"items"`
+	Subtotal  string         `json:"subtotal"`
 }
 
 type customerJSON struct {
-	ID        uuid.UUID `json:"id"`
-	Email     string    `json:"email"`
-	FirstName *string   `json:"first_name"`
-	LastName  *string   `json:"last_name"`
-	Phone     *string   `json:"phone"`
-	VatNumber *string   `json:"vat_number,omitempty"`
+	ID            uuid.UUID `json:"id"`
+	Email         string    `json:"email"`
+	EmailVerified bool      `json:"email_verified"`
+	FirstName     *string   `json:"first_name"`
+	LastName      *string   `json:"last_name"`
+	Phone         *string   `json:"phone"`
+	VatNumber     *string   `json:"vat_number,omitempty"`
+}
+
+func newCustomerJSON(c db.Customer) customerJSON {
+	return customerJSON{
+		ID:            c.ID,
+		Email:         c.Email,
+		EmailVerified: c.EmailVerifiedAt.Valid,
+		FirstName:     c.FirstName,
+		LastName:      c.LastName,
+		Phone:         c.Phone,
+		VatNumber:     c.VatNumber,
+	}
+}
+
+type forgotPasswordRequest struct {
+	Email string `json:"email"`
+}
+
+type resetPasswordRequest struct {
+	Token    string `json:"token"`
+	Password string `json:"password"`
+}
+
+type verifyEmailRequest struct {
+	Token string `json:"token"`
 }
 
 type updateProfileRequest struct {
@@ -89,6 +162,85 @@ type updateProfileRequest struct {
 	VatNumber *string `json:"vat_number"`
 }
 
+type addressRequest struct {
+	Label             *string `json:"label"`
+	FirstName         string  `json:"first_name"`
+	LastName          string  `json:"last_name"`
+	Company           *string `json:"company"`
+	AddressLine1      string  `json:"address_line1"`
+	AddressLine2      *string `json:"address_line2"`
+	City              string  `json:"city"`
+	StateProvince     *string `json:"state_province"`
+	PostalCode        string  `json:"postal_code"`
+	CountryCode       string  `json:"country_code"`
+	Phone             *string `json:"phone"`
+	IsDefaultBilling  bool    `json:"is_default_billing"`
+	IsDefaultShipping bool    `json:"is_default_shipping"`
+}
+
+func (req addressRequest) params() customer.AddressParams {
+	return customer.AddressParams{
+		Label:             req.Label,
+		FirstName:         req.FirstName,
+		LastName:          req.LastName,
+		Company:           req.Company,
+		AddressLine1:      req.AddressLine1,
+		AddressLine2:      req.AddressLine2,
+		City:              req.City,
+		StateProvince:     req.StateProvince,
+		PostalCode:        req.PostalCode,
+		CountryCode:       req.CountryCode,
+		Phone:             req.Phone,
+		IsDefaultBilling:  req.IsDefaultBilling,
+		IsDefaultShipping: req.IsDefaultShipping,
+	}
+}
+
+// customerOrderJSON summarises an order in the customer's order history.
+type customerOrderJSON struct {
+	OrderNumber   int64     `json:"order_number"`
+	Status        string    `json:"status"`
+	PaymentStatus string    `json:"payment_status"`
+	Total         string    `json:"total"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+type addressJSON struct {
+	ID                uuid.UUID `json:"id"`
+	Label             *string   `json:"label"`
+	FirstName         string    `json:"first_name"`
+	LastName          string    `json:"last_name"`
+	Company           *string   `json:"company"`
+	AddressLine1      string    `json:"address_line1"`
+	AddressLine2      *string   `json:"address_line2"`
+	City              string    `json:"city"`
+	StateProvince     *string   `json:"state_province"`
+	PostalCode        string    `json:"postal_code"`
+	CountryCode       string    `json:"country_code"`
+	Phone             *string   `json:"phone"`
+	IsDefaultBilling  bool      `json:"is_default_billing"`
+	IsDefaultShipping bool      `json:"is_default_shipping"`
+}
+
+func newAddressJSON(a db.CustomerAddress) addressJSON {
+	return addressJSON{
+		ID:                a.ID,
+		Label:             a.Label,
+		FirstName:         a.FirstName,
+		LastName:          a.LastName,
+		Company:           a.Company,
+		AddressLine1:      a.AddressLine1,
+		AddressLine2:      a.AddressLine2,
+		City:              a.City,
+		StateProvince:     a.StateProvince,
+		PostalCode:        a.PostalCode,
+		CountryCode:       a.CountryCode,
+		Phone:             a.Phone,
+		IsDefaultBilling:  a.IsDefaultBilling,
+		IsDefaultShipping: a.IsDefaultShipping,
+	}
+}
+
 // --- Handlers ---
 
 // Register handles POST /api/v1/customers/register
@@ -119,12 +271,15 @@ func (h *CustomerHandler) Register(w http.ResponseWriter, r *http.Request) {
 	}
 	hashStr := string(hash)
 
+	metadata, _ := json.Marshal(map[string]string{"language": requestLanguage(r, req.Language)})
+
 	// Create the customer.
 	cust, err := h.customerSvc.Create(r.Context(), customer.CreateCustomerParams{
 		Email:        req.Email,
 		PasswordHash: &hashStr,
 		FirstName:    req.FirstName,
 		LastName:     req.LastName,
+		Metadata:     metadata,
 	})
 	if err != nil {
 		if errors.Is(err, customer.ErrEmailTaken) {
@@ -136,6 +291,13 @@ func (h *CustomerHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if h.emailSvc != nil {
+		if err := h.emailSvc.SendWelcome(r.Context(), cust); err != nil {
+			h.logger.Error("failed to queue welcome email", "error", err, "customer_id", cust.ID)
+		}
+		h.sendVerification(r.Context(), cust)
+	}
+
 	// Generate tokens.
 	accessToken, err := h.jwtMgr.GenerateAccessToken(cust.ID, cust.Email)
 	if err != nil {
@@ -144,7 +306,7 @@ func (h *CustomerHandler) Register(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	refreshToken, err := h.jwtMgr.GenerateRefreshToken(cust.ID, cust.Email)
+	refreshToken, err := h.jwtMgr.GenerateRefreshToken(cust.ID, cust.Email, cust.TokenVersion)
 	if err != nil {
 		h.logger.Error("failed to generate refresh token", "error", err)
 		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
@@ -154,14 +316,8 @@ func (h *CustomerHandler) Register(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, authResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		Customer: customerJSON{
-			ID:        cust.ID,
-			Email:     cust.Email,
-			FirstName: cust.FirstName,
-			LastName:  cust.LastName,
-			Phone:     cust.Phone,
-			VatNumber: cust.VatNumber,
-		},
+		Customer:     newCustomerJSON(cust),
+		Cart:         h.customerCart(r.Context(), cust.ID, req.CartID),
 	})
 }
 
@@ -209,7 +365,7 @@ func (h *CustomerHandler) Login(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	refreshToken, err := h.jwtMgr.GenerateRefreshToken(cust.ID, cust.Email)
+	refreshToken, err := h.jwtMgr.GenerateRefreshToken(cust.ID, cust.Email, cust.TokenVersion)
 	if err != nil {
 		h.logger.Error("failed to generate refresh token", "error", err)
 		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
@@ -219,17 +375,49 @@ func (h *CustomerHandler) Login(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusOK, authResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
-		Customer: customerJSON{
-			ID:        cust.ID,
-			Email:     cust.Email,
-			FirstName: cust.FirstName,
-			LastName:  cust.LastName,
-			Phone:     cust.Phone,
-			VatNumber: cust.VatNumber,
-		},
+		Customer:     newCustomerJSON(cust),
+		Cart:         h.customerCart(r.Context(), cust.ID, req.CartID),
 	})
 }
 
+// customerCart returns the cart a customer continues with after signing in:
+// the anonymous cart merged into their latest cart if cartID is set, and
+// their latest cart otherwise. An anonymous cart that cannot be merged is
+// ignored. Cart errors are logged rather than failing the sign-in, and give
+// a nil cart.
+func (h *CustomerHandler) customerCart(ctx context.Context, customerID uuid.UUID, cartID *uuid.UUID) *authCartJSON {
+	var (
+		c   db.Cart
+		err error
+	)
+	if cartID != nil {
+		c, err = h.cartSvc.Merge(ctx, customerID, *cartID)
+	}
+	if cartID == nil || errors.Is(err, cart.ErrNotFound) || errors.Is(err, cart.ErrCheckoutInProgress) {
+		c, err = h.cartSvc.CustomerCart(ctx, customerID)
+	}
+	if err != nil {
+		if !errors.Is(err, cart.ErrNotFound) {
+			h.logger.Error("failed to load customer cart", "error", err, "customer_id", customerID)
+		}
+		return nil
+	}
+
+	items, err := h.cartSvc.ListItems(ctx, c.ID)
+	if err != nil {
+		h.logger.Error("failed to list cart items", "error", err, "cart_id", c.ID)
+		return nil
+	}
+
+	cartItems, subtotal := newCartItemsJSON(items)
+	return &authCartJSON{
+		ID:        c.ID,
+		ExpiresAt: c.ExpiresAt.Format("2006-01-02T15:04:05Z"),
+		Items:     cartItems,
+		Subtotal:  subtotal.StringFixed(2),
+	}
+}
+
 // RefreshToken handles POST /api/v1/customers/refresh
 func (h *CustomerHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	var req refreshRequest
@@ -250,8 +438,24 @@ func (h *CustomerHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// A password change since the token was issued invalidates it.
+	cust, err := h.customerSvc.Get(r.Context(), claims.CustomerID)
+	if err != nil {
+		if errors.Is(err, customer.ErrNotFound) {
+			writeJSON(w, http.StatusUnauthorized, errorJSON{Error: "invalid or expired refresh token"})
+			return
+		}
+		h.logger.Error("failed to look up customer", "error", err, "customer_id", claims.CustomerID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return
+	}
+	if claims.TokenVersion != cust.TokenVersion {
+		writeJSON(w, http.StatusUnauthorized, errorJSON{Error: "invalid or expired refresh token"})
+		return
+	}
+
 	// Generate a new access token.
-	accessToken, err := h.jwtMgr.GenerateAccessToken(claims.CustomerID, claims.Email)
+	accessToken, err := h.jwtMgr.GenerateAccessToken(cust.ID, cust.Email)
 	if err != nil {
 		h.logger.Error("failed to generate access token", "error", err)
 		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
@@ -259,7 +463,7 @@ func (h *CustomerHandler) RefreshToken(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Generate a new refresh token (rotate).
-	refreshToken, err := h.jwtMgr.GenerateRefreshToken(claims.CustomerID, claims.Email)
+	refreshToken, err := h.jwtMgr.GenerateRefreshToken(cust.ID, cust.Email, cust.TokenVersion)
 	if err != nil {
 		h.logger.Error("failed to generate refresh token", "error", err)
 		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
@@ -291,14 +495,7 @@ func (h *CustomerHandler) GetProfile(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	writeJSON(w, http.StatusOK, customerJSON{
-		ID:        cust.ID,
-		Email:     cust.Email,
-		FirstName: cust.FirstName,
-		LastName:  cust.LastName,
-		Phone:     cust.Phone,
-		VatNumber: cust.VatNumber,
-	})
+	writeJSON(w, http.StatusOK, newCustomerJSON(cust))
 }
 
 // UpdateProfile handles PATCH /api/v1/customers/me
@@ -331,26 +528,323 @@ func (h *CustomerHandler) UpdateProfile(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	writeJSON(w, http.StatusOK, customerJSON{
-		ID:        cust.ID,
-		Email:     cust.Email,
-		FirstName: cust.FirstName,
-		LastName:  cust.LastName,
-		Phone:     cust.Phone,
-		VatNumber: cust.VatNumber,
-	})
+	writeJSON(w, http.StatusOK, newCustomerJSON(cust))
+}
+
+// ForgotPassword handles POST /api/v1/customers/password/forgot
+// It always answers 202 so the response does not reveal whether an account
+// exists for the address.
+func (h *CustomerHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req forgotPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "invalid request body"})
+		return
+	}
+
+	req.Email = strings.TrimSpace(strings.ToLower(req.Email))
+	if req.Email == "" {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "email is required"})
+		return
+	}
+
+	accepted := map[string]string{
+		"message": "if an account exists for this address, a password reset link has been sent",
+	}
+
+	cust, err := h.customerSvc.GetByEmail(r.Context(), req.Email)
+	if err != nil {
+		if !errors.Is(err, customer.ErrNotFound) {
+			h.logger.Error("failed to look up customer", "error", err)
+		}
+		writeJSON(w, http.StatusAccepted, accepted)
+		return
+	}
+
+	if h.emailSvc != nil {
+		token, err := h.customerSvc.IssuePasswordReset(r.Context(), cust)
+		if err != nil {
+			h.logger.Error("failed to issue password reset token", "error", err, "customer_id", cust.ID)
+		} else if err := h.emailSvc.SendPasswordReset(r.Context(), cust, token); err != nil {
+			h.logger.Error("failed to queue password reset email", "error", err, "customer_id", cust.ID)
+		}
+	}
+
+	writeJSON(w, http.StatusAccepted, accepted)
+}
+
+// ResetPassword handles POST /api/v1/customers/password/reset
+func (h *CustomerHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req resetPasswordRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "invalid request body"})
+		return
+	}
+
+	if req.Token == "" || req.Password == "" {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "token and password are required"})
+		return
+	}
+
+	if len(req.Password) < 8 {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "password must be at least 8 characters"})
+		return
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		h.logger.Error("failed to hash password", "error", err)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return
+	}
+
+	cust, err := h.customerSvc.ResetPassword(r.Context(), req.Token, string(hash))
+	if err != nil {
+		if errors.Is(err, customer.ErrInvalidToken) {
+			writeJSON(w, http.StatusBadRequest, errorJSON{Error: "reset link is invalid or has expired"})
+			return
+		}
+		h.logger.Error("failed to reset password", "error", err)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newCustomerJSON(cust))
+}
+
+// VerifyEmail handles POST /api/v1/customers/verify-email
+func (h *CustomerHandler) VerifyEmail(w http.ResponseWriter, r *http.Request) {
+	var req verifyEmailRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "invalid request body"})
+		return
+	}
+
+	if req.Token == "" {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "token is required"})
+		return
+	}
+
+	cust, err := h.customerSvc.VerifyEmail(r.Context(), req.Token)
+	if err != nil {
+		if errors.Is(err, customer.ErrInvalidToken) {
+			writeJSON(w, http.StatusBadRequest, errorJSON{Error: "verification link is invalid or has expired"})
+			return
+		}
+		h.logger.Error("failed to verify email", "error", err)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newCustomerJSON(cust))
+}
+
+// ResendVerification handles POST /api/v1/customers/me/email-verification
+// It sends a new verification link, invalidating earlier ones.
+func (h *CustomerHandler) ResendVerification(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := middleware.CustomerFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorJSON{Error: "not authenticated"})
+		return
+	}
+
+	cust, err := h.customerSvc.Get(r.Context(), customerID)
+	if err != nil {
+		if errors.Is(err, customer.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorJSON{Error: "customer not found"})
+			return
+		}
+		h.logger.Error("failed to get customer", "error", err, "customer_id", customerID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return
+	}
+
+	if cust.EmailVerifiedAt.Valid {
+		writeJSON(w, http.StatusConflict, errorJSON{Error: "email address is already verified"})
+		return
+	}
+
+	if h.emailSvc != nil {
+		h.sendVerification(r.Context(), cust)
+	}
+
+	writeJSON(w, http.StatusAccepted, map[string]string{"message": "verification email sent"})
+}
+
+// sendVerification issues a verification token and queues the email.
+// Failures are logged; they never fail the request.
+func (h *CustomerHandler) sendVerification(ctx context.Context, cust db.Customer) {
+	token, err := h.customerSvc.IssueEmailVerification(ctx, cust)
+	if err != nil {
+		h.logger.Error("failed to issue email verification token", "error", err, "customer_id", cust.ID)
+		return
+	}
+	if err := h.emailSvc.SendEmailVerification(ctx, cust, token); err != nil {
+		h.logger.Error("failed to queue verification email", "error", err, "customer_id", cust.ID)
+	}
 }
 
 // ListOrders handles GET /api/v1/customers/me/orders
 // Returns the authenticated customer's order history.
 func (h *CustomerHandler) ListOrders(w http.ResponseWriter, r *http.Request) {
-	_, ok := middleware.CustomerFromContext(r.Context())
+	customerID, ok := middleware.CustomerFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorJSON{Error: "not authenticated"})
+		return
+	}
+
+	orders, err := h.orderSvc.ListByCustomer(r.Context(), customerID)
+	if err != nil {
+		h.logger.Error("failed to list customer orders", "error", err, "customer_id", customerID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return
+	}
+
+	resp := make([]customerOrderJSON, len(orders))
+	for i, o := range orders {
+		resp[i] = customerOrderJSON{
+			OrderNumber:   o.OrderNumber,
+			Status:        o.Status,
+			PaymentStatus: o.PaymentStatus,
+			Total:         numericToDecimal(o.Total).StringFixed(2),
+			CreatedAt:     o.CreatedAt,
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// ListAddresses handles GET /api/v1/customers/me/addresses
+func (h *CustomerHandler) ListAddresses(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := middleware.CustomerFromContext(r.Context())
 	if !ok {
 		writeJSON(w, http.StatusUnauthorized, errorJSON{Error: "not authenticated"})
 		return
 	}
 
-	// TODO: Implement customer order listing.
-	// The order service needs a ListByCustomer method.
-	writeJSON(w, http.StatusOK, []any{})
+	addresses, err := h.customerSvc.ListAddresses(r.Context(), customerID)
+	if err != nil {
+		h.logger.Error("failed to list customer addresses", "error", err, "customer_id", customerID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return
+	}
+
+	resp := make([]addressJSON, 0, len(addresses))
+	for _, a := range addresses {
+		resp = append(resp, newAddressJSON(a))
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// GetAddress handles GET /api/v1/customers/me/addresses/{id}
+func (h *CustomerHandler) GetAddress(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := middleware.CustomerFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorJSON{Error: "not authenticated"})
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "invalid address ID"})
+		return
+	}
+
+	address, err := h.customerSvc.GetAddress(r.Context(), customerID, id)
+	if err != nil {
+		h.writeAddressError(w, err, customerID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newAddressJSON(address))
+}
+
+// CreateAddress handles POST /api/v1/customers/me/addresses
+func (h *CustomerHandler) CreateAddress(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := middleware.CustomerFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorJSON{Error: "not authenticated"})
+		return
+	}
+
+	var req addressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "invalid request body"})
+		return
+	}
+
+	address, err := h.customerSvc.CreateAddress(r.Context(), customerID, req.params())
+	if err != nil {
+		h.writeAddressError(w, err, customerID)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, newAddressJSON(address))
+}
+
+// UpdateAddress handles PUT /api/v1/customers/me/addresses/{id}
+// The request replaces every field of the address.
+func (h *CustomerHandler) UpdateAddress(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := middleware.CustomerFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorJSON{Error: "not authenticated"})
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "invalid address ID"})
+		return
+	}
+
+	var req addressRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "invalid request body"})
+		return
+	}
+
+	address, err := h.customerSvc.UpdateAddress(r.Context(), customerID, id, req.params())
+	if err != nil {
+		h.writeAddressError(w, err, customerID)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newAddressJSON(address))
+}
+
+// DeleteAddress handles DELETE /api/v1/customers/me/addresses/{id}
+func (h *CustomerHandler) DeleteAddress(w http.ResponseWriter, r *http.Request) {
+	customerID, ok := middleware.CustomerFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorJSON{Error: "not authenticated"})
+		return
+	}
+
+	id, err := uuid.Parse(r.PathValue("id"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "invalid address ID"})
+		return
+	}
+
+	if err := h.customerSvc.DeleteAddress(r.Context(), customerID, id); err != nil {
+		h.writeAddressError(w, err, customerID)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeAddressError maps address book errors to responses.
+func (h *CustomerHandler) writeAddressError(w http.ResponseWriter, err error, customerID uuid.UUID) {
+	switch {
+	case errors.Is(err, customer.ErrAddressNotFound):
+		writeJSON(w, http.StatusNotFound, errorJSON{Error: "address not found"})
+	case errors.Is(err, customer.ErrInvalidAddress):
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: strings.TrimPrefix(err.Error(), customer.ErrInvalidAddress.Error()+": ")})
+	case errors.Is(err, customer.ErrUnknownCountry):
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "country_code is not a supported country"})
+	case errors.Is(err, customer.ErrCountryNotShippable):
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "shipping to this country is not enabled"})
+	default:
+		h.logger.Error("customer address operation failed", "error", err, "customer_id", customerID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+	}
 }
@@ -11,11 +11,14 @@ import (
 	"testing"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/forgecommerce/api/internal/auth"
 	"github.com/forgecommerce/api/internal/handlers/api"
 	"github.com/forgecommerce/api/internal/middleware"
+	"github.com/forgecommerce/api/internal/services/cart"
 	"github.com/forgecommerce/api/internal/services/customer"
+	"github.com/forgecommerce/api/internal/services/order"
 )
 
 const testJWTSecret = "test-secret-key-for-handler-tests-minimum-length"
@@ -23,7 +26,7 @@ const testJWTSecret = "test-secret-key-for-handler-tests-minimum-length"
 func newCustomerHandler() *api.CustomerHandler {
 	customerSvc := customer.NewService(testDB.Pool, nil)
 	jwtMgr := auth.NewJWTManager(testJWTSecret)
-	return api.NewCustomerHandler(customerSvc, jwtMgr, slog.Default())
+	return api.NewCustomerHandler(customerSvc, cart.NewService(testDB.Pool, nil), order.NewService(testDB.Pool, nil, nil), nil, jwtMgr, slog.Default())
 }
 
 func customerMux() *http.ServeMux {
@@ -202,6 +205,67 @@ func TestLogin(t *testing.T) {
 	}
 }
 
+func TestLogin_MergesCart(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	mux := customerMux()
+	ctx := context.Background()
+	cartSvc := cart.NewService(testDB.Pool, nil)
+
+	p := testDB.FixtureProduct(t, "Login Cart", "login-cart")
+	v := testDB.FixtureVariant(t, p.ID, "LOGIN-CART-1", 10)
+
+	// A cart from an earlier visit, signed in.
+	regBody, _ := json.Marshal(map[string]string{
+		"email":    "cart-login@example.com",
+		"password": "securepassword123",
+	})
+	mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/api/v1/customers/register", bytes.NewReader(regBody)))
+	cust, err := customer.NewService(testDB.Pool, nil).GetByEmail(ctx, "cart-login@example.com")
+	if err != nil {
+		t.Fatalf("getting registered customer: %v", err)
+	}
+	saved, _ := cartSvc.Create(ctx)
+	cartSvc.SetCustomer(ctx, saved.ID, cust.ID)
+	cartSvc.AddItem(ctx, saved.ID, v.ID, 1)
+
+	// A cart filled this visit before signing in.
+	anon, _ := cartSvc.Create(ctx)
+	cartSvc.AddItem(ctx, anon.ID, v.ID, 2)
+
+	loginBody, _ := json.Marshal(map[string]string{
+		"email":    "cart-login@example.com",
+		"password": "securepassword123",
+		"cart_id":  anon.ID.String(),
+	})
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/customers/login", bytes.NewReader(loginBody)))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want %d\nbody: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var resp struct {
+		Cart *struct {
+			ID    string `json:"id"`
+			Items []struct {
+				Quantity int32 `json:"quantity"`
+			} `json:"items"`
+		} `json:"cart"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+
+	if resp.Cart == nil {
+		t.Fatal("expected a cart in the response")
+	}
+	if resp.Cart.ID != saved.ID.String() {
+		t.Errorf("cart: got %s, want %s", resp.Cart.ID, saved.ID)
+	}
+	if len(resp.Cart.Items) != 1 || resp.Cart.Items[0].Quantity != 3 {
+		t.Errorf("items: got %+v, want one line of 3", resp.Cart.Items)
+	}
+}
+
 func TestLogin_WrongPassword(t *testing.T) {
 	testDB.Truncate(t)
 	mux := customerMux()
@@ -325,7 +389,7 @@ func TestGetProfile(t *testing.T) {
 
 	customerSvc := customer.NewService(testDB.Pool, nil)
 	jwtMgr := auth.NewJWTManager(testJWTSecret)
-	h := api.NewCustomerHandler(customerSvc, jwtMgr, slog.Default())
+	h := api.NewCustomerHandler(customerSvc, cart.NewService(testDB.Pool, nil), order.NewService(testDB.Pool, nil, nil), nil, jwtMgr, slog.Default())
 
 	// Register a customer directly via handler to get a real customer in DB.
 	regMux := http.NewServeMux()
@@ -375,7 +439,7 @@ func TestGetProfile_Unauthenticated(t *testing.T) {
 
 	customerSvc := customer.NewService(testDB.Pool, nil)
 	jwtMgr := auth.NewJWTManager(testJWTSecret)
-	h := api.NewCustomerHandler(customerSvc, jwtMgr, slog.Default())
+	h := api.NewCustomerHandler(customerSvc, cart.NewService(testDB.Pool, nil), order.NewService(testDB.Pool, nil, nil), nil, jwtMgr, slog.Default())
 
 	mux := http.NewServeMux()
 	h.RegisterProtectedRoutes(mux)
@@ -399,7 +463,7 @@ func TestUpdateProfile(t *testing.T) {
 
 	customerSvc := customer.NewService(testDB.Pool, nil)
 	jwtMgr := auth.NewJWTManager(testJWTSecret)
-	h := api.NewCustomerHandler(customerSvc, jwtMgr, slog.Default())
+	h := api.NewCustomerHandler(customerSvc, cart.NewService(testDB.Pool, nil), order.NewService(testDB.Pool, nil, nil), nil, jwtMgr, slog.Default())
 
 	// Register a customer.
 	regMux := http.NewServeMux()
@@ -507,7 +571,7 @@ func protectedMux(t *testing.T) (*http.ServeMux, *api.CustomerHandler) {
 	t.Helper()
 	customerSvc := customer.NewService(testDB.Pool, nil)
 	jwtMgr := auth.NewJWTManager(testJWTSecret)
-	h := api.NewCustomerHandler(customerSvc, jwtMgr, slog.Default())
+	h := api.NewCustomerHandler(customerSvc, cart.NewService(testDB.Pool, nil), order.NewService(testDB.Pool, nil, nil), nil, jwtMgr, slog.Default())
 
 	mux := http.NewServeMux()
 	h.RegisterProtectedRoutes(mux)
@@ -777,7 +841,7 @@ func TestRefreshToken_WrongSecret(t *testing.T) {
 
 	// Generate a token signed with a different secret.
 	otherJWT := auth.NewJWTManager("completely-different-secret-key-long-enough")
-	badToken, err := otherJWT.GenerateRefreshToken(uuid.New(), "bad@example.com")
+	badToken, err := otherJWT.GenerateRefreshToken(uuid.New(), "bad@example.com", 0)
 	if err != nil {
 		t.Fatalf("generating token: %v", err)
 	}
@@ -1184,3 +1248,279 @@ func TestListOrders_ViaBearer(t *testing.T) {
 		t.Errorf("expected 0 orders, got %d", len(resp))
 	}
 }
+
+func TestListOrders_OwnOrdersOnly(t *testing.T) {
+	testDB.Truncate(t)
+	mux := customerMux()
+
+	reg := registerCustomerViaHandler(t, mux, "history@example.com", "securepassword123")
+	own := pgtype.UUID{Bytes: reg.CustomerID, Valid: true}
+	first := createTestOrder(t, "history@example.com", own)
+	second := createTestOrder(t, "history@example.com", own)
+	createTestOrder(t, "history@example.com", pgtype.UUID{}) // guest order
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/customers/me/orders", nil)
+	req.Header.Set("Authorization", "Bearer "+reg.AccessToken)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want %d\nbody: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var resp []struct {
+		OrderNumber int64  `json:"order_number"`
+		Status      string `json:"status"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+
+	if len(resp) != 2 {
+		t.Fatalf("expected 2 orders, got %d", len(resp))
+	}
+	if resp[0].OrderNumber != second.OrderNumber || resp[1].OrderNumber != first.OrderNumber {
+		t.Errorf("order numbers: got %d, %d; want %d, %d (newest first)",
+			resp[0].OrderNumber, resp[1].OrderNumber, second.OrderNumber, first.OrderNumber)
+	}
+	if resp[0].Status != "confirmed" {
+		t.Errorf("status: got %q, want %q", resp[0].Status, "confirmed")
+	}
+}
+
+// --------------------------------------------------------------------------
+// Password reset and email verification
+// --------------------------------------------------------------------------
+
+func TestForgotPassword_UnknownEmail(t *testing.T) {
+	testDB.Truncate(t)
+	mux := customerMux()
+
+	body, _ := json.Marshal(map[string]string{"email": "nobody@example.com"})
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/customers/password/forgot", bytes.NewReader(body)))
+
+	// Unknown addresses get the same answer as known ones.
+	if rr.Code != http.StatusAccepted {
+		t.Fatalf("status: got %d, want %d\nbody: %s", rr.Code, http.StatusAccepted, rr.Body.String())
+	}
+}
+
+func TestForgotPassword_RateLimited(t *testing.T) {
+	testDB.Truncate(t)
+	mux := customerMux()
+
+	var last int
+	for i := 0; i < 6; i++ {
+		body, _ := json.Marshal(map[string]string{"email": "nobody@example.com"})
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/customers/password/forgot", bytes.NewReader(body)))
+		last = rr.Code
+	}
+	if last != http.StatusTooManyRequests {
+		t.Errorf("sixth request: got %d, want %d", last, http.StatusTooManyRequests)
+	}
+}
+
+func TestResetPassword_Handler(t *testing.T) {
+	testDB.Truncate(t)
+	mux := customerMux()
+	ctx := context.Background()
+
+	reg := registerCustomerViaHandler(t, mux, "reset@example.com", "securepassword123")
+
+	svc := customer.NewService(testDB.Pool, nil)
+	cust, err := svc.Get(ctx, reg.CustomerID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	token, err := svc.IssuePasswordReset(ctx, cust)
+	if err != nil {
+		t.Fatalf("IssuePasswordReset: %v", err)
+	}
+
+	body, _ := json.Marshal(map[string]string{"token": token, "password": "brandnewpassword"})
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/customers/password/reset", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want %d\nbody: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	// The new password works, the old one does not.
+	for password, want := range map[string]int{
+		"brandnewpassword":  http.StatusOK,
+		"securepassword123": http.StatusUnauthorized,
+	} {
+		body, _ := json.Marshal(map[string]string{"email": "reset@example.com", "password": password})
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/customers/login", bytes.NewReader(body)))
+		if rr.Code != want {
+			t.Errorf("login with %q: got %d, want %d", password, rr.Code, want)
+		}
+	}
+
+	// The token cannot be reused.
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/customers/password/reset", bytes.NewReader(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("reuse: got %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+
+	// Refresh tokens issued before the reset no longer work.
+	body, _ = json.Marshal(map[string]string{"refresh_token": reg.RefreshToken})
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/customers/refresh", bytes.NewReader(body)))
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("refresh with pre-reset token: got %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+}
+
+func TestResetPassword_ShortPassword(t *testing.T) {
+	testDB.Truncate(t)
+	mux := customerMux()
+
+	body, _ := json.Marshal(map[string]string{"token": "whatever", "password": "short"})
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/customers/password/reset", bytes.NewReader(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("status: got %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestVerifyEmail_Handler(t *testing.T) {
+	testDB.Truncate(t)
+	mux := customerMux()
+	ctx := context.Background()
+
+	reg := registerCustomerViaHandler(t, mux, "verify@example.com", "securepassword123")
+
+	svc := customer.NewService(testDB.Pool, nil)
+	cust, err := svc.Get(ctx, reg.CustomerID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	token, err := svc.IssueEmailVerification(ctx, cust)
+	if err != nil {
+		t.Fatalf("IssueEmailVerification: %v", err)
+	}
+
+	body, _ := json.Marshal(map[string]string{"token": token})
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/customers/verify-email", bytes.NewReader(body)))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want %d\nbody: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var resp struct {
+		EmailVerified bool `json:"email_verified"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if !resp.EmailVerified {
+		t.Error("expected email_verified to be true")
+	}
+}
+
+func TestVerifyEmail_InvalidToken(t *testing.T) {
+	testDB.Truncate(t)
+	mux := customerMux()
+
+	body, _ := json.Marshal(map[string]string{"token": "not-a-token"})
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, httptest.NewRequest(http.MethodPost, "/api/v1/customers/verify-email", bytes.NewReader(body)))
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("status: got %d, want %d", rr.Code, http.StatusBadRequest)
+	}
+}
+
+// --------------------------------------------------------------------------
+// Addresses (protected)
+// --------------------------------------------------------------------------
+
+func addressRequest(method, target string, customerID uuid.UUID, body any) *http.Request {
+	var buf bytes.Buffer
+	if body != nil {
+		json.NewEncoder(&buf).Encode(body)
+	}
+	req := httptest.NewRequest(method, target, &buf)
+	return req.WithContext(context.WithValue(req.Context(), middleware.CustomerIDKey, customerID))
+}
+
+func TestAddresses_CRUD(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	testDB.FixtureShippingCountry(t, "DE")
+
+	c := testDB.FixtureCustomer(t, "addresses@example.com")
+	mux := http.NewServeMux()
+	newCustomerHandler().RegisterProtectedRoutes(mux)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, addressRequest(http.MethodPost, "/api/v1/customers/me/addresses", c.ID, map[string]any{
+		"first_name":          "Max",
+		"last_name":           "Mustermann",
+		"address_line1":       "Hauptstr. 1",
+		"city":                "Berlin",
+		"postal_code":         "10115",
+		"country_code":        "de",
+		"is_default_shipping": true,
+	}))
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("create status: got %d, want %d\nbody: %s", rr.Code, http.StatusCreated, rr.Body.String())
+	}
+	var created struct {
+		ID          string `json:"id"`
+		CountryCode string `json:"country_code"`
+	}
+	json.NewDecoder(rr.Body).Decode(&created)
+	if created.CountryCode != "DE" {
+		t.Errorf("country_code: got %q, want %q", created.CountryCode, "DE")
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, addressRequest(http.MethodGet, "/api/v1/customers/me/addresses", c.ID, nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list status: got %d, want %d", rr.Code, http.StatusOK)
+	}
+	var list []struct {
+		ID string `json:"id"`
+	}
+	json.NewDecoder(rr.Body).Decode(&list)
+	if len(list) != 1 || list[0].ID != created.ID {
+		t.Fatalf("list: got %+v, want one address %s", list, created.ID)
+	}
+
+	// Another customer cannot see or delete the address.
+	other := testDB.FixtureCustomer(t, "other@example.com")
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, addressRequest(http.MethodDelete, "/api/v1/customers/me/addresses/"+created.ID, other.ID, nil))
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("delete by other customer: got %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, addressRequest(http.MethodDelete, "/api/v1/customers/me/addresses/"+created.ID, c.ID, nil))
+	if rr.Code != http.StatusNoContent {
+		t.Errorf("delete status: got %d, want %d", rr.Code, http.StatusNoContent)
+	}
+}
+
+func TestCreateAddress_UnknownCountry(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+
+	c := testDB.FixtureCustomer(t, "unknown-country@example.com")
+	mux := http.NewServeMux()
+	newCustomerHandler().RegisterProtectedRoutes(mux)
+
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, addressRequest(http.MethodPost, "/api/v1/customers/me/addresses", c.ID, map[string]any{
+		"first_name":    "John",
+		"last_name":     "Smith",
+		"address_line1": "1 High Street",
+		"city":          "London",
+		"postal_code":   "SW1A 1AA",
+		"country_code":  "GB",
+	}))
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("status: got %d, want %d\nbody: %s", rr.Code, http.StatusBadRequest, rr.Body.String())
+	}
+}
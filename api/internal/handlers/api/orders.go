@@ -0,0 +1,412 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/forgecommerce/api/internal/auth"
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/middleware"
+	"github.com/forgecommerce/api/internal/services/invoice"
+	"github.com/forgecommerce/api/internal/services/order"
+)
+
+// OrderHandler serves order details to the customers who placed them:
+// signed-in customers through their account, guests through an order
+// access token obtained with the order number and email.
+type OrderHandler struct {
+	orderSvc   *order.Service
+	invoiceSvc *invoice.Service
+	jwtMgr     *auth.JWTManager
+	logger     *slog.Logger
+}
+
+// NewOrderHandler creates a new storefront order handler.
+func NewOrderHandler(orderSvc *order.Service, invoiceSvc *invoice.Service, jwtMgr *auth.JWTManager, logger *slog.Logger) *OrderHandler {
+	return &OrderHandler{
+		orderSvc:   orderSvc,
+		invoiceSvc: invoiceSvc,
+		jwtMgr:     jwtMgr,
+		logger:     logger,
+	}
+}
+
+// RegisterRoutes registers the guest order routes. The lookup is rate
+// limited like the customer login so order numbers and emails cannot be
+// guessed.
+func (h *OrderHandler) RegisterRoutes(mux *http.ServeMux) {
+	mux.Handle("POST /api/v1/orders/lookup", middleware.LoginRateLimiter()(http.HandlerFunc(h.Lookup)))
+	mux.HandleFunc("GET /api/v1/orders/{number}", h.GetGuestOrder)
+	mux.HandleFunc("GET /api/v1/orders/{number}/invoice", h.GetGuestInvoice)
+}
+
+// RegisterProtectedRoutes registers the customer order routes. They must be
+// mounted behind RequireCustomerAuth.
+func (h *OrderHandler) RegisterProtectedRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/v1/customers/me/orders/{number}", h.GetCustomerOrder)
+	mux.HandleFunc("GET /api/v1/customers/me/orders/{number}/invoice", h.GetCustomerInvoice)
+}
+
+// --- Request/Response types ---
+
+type orderLookupRequest struct {
+	OrderNumber int64  `json:"order_number"`
+	Email       string `json:"email"`
+}
+
+type orderLookupResponse struct {
+	OrderNumber int64     `json:"order_number"`
+	AccessToken string    `json:"access_token"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+type orderDetailJSON struct {
+	OrderNumber       int64              `json:"order_number"`
+	Status            string             `json:"status"`
+	PaymentStatus     string             `json:"payment_status"`
+	Email             string             `json:"email"`
+	BillingAddress    json.RawMessage    `json:"billing_address"`
+	ShippingAddress   json.RawMessage    `json:"shipping_address"`
+	Subtotal          string             `json:"subtotal"`
+	ShippingFee       string             `json:"shipping_fee"`
+	ShippingExtraFees string             `json:"shipping_extra_fees"`
+	DiscountAmount    string             `json:"discount_amount"`
+	VatTotal          string             `json:"vat_total"`
+	Total             string             `json:"total"`
+	VatNumber         *string            `json:"vat_number"`
+	VatCompanyName    *string            `json:"vat_company_name"`
+	VatReverseCharge  bool               `json:"vat_reverse_charge"`
+	ShippingMethod    *string            `json:"shipping_method"`
+	TrackingNumber    *string            `json:"tracking_number"`
+	ShippedAt         *time.Time         `json:"shipped_at"`
+	DeliveredAt       *time.Time         `json:"delivered_at"`
+	CustomerNotes     *string            `json:"customer_notes"`
+	CreatedAt         time.Time          `json:"created_at"`
+	Items             []orderItemJSON    `json:"items"`
+	VatBreakdown      []vatBreakdownJSON `json:"vat_breakdown"`
+	Events            []orderEventJSON   `json:"events"`
+}
+
+type orderItemJSON struct {
+	ProductName string  `json:"product_name"`
+	VariantName *string `json:"variant_name"`
+	Sku         *string `json:"sku"`
+	Quantity    int32   `json:"quantity"`
+	UnitPrice   string  `json:"unit_price"`
+	TotalPrice  string  `json:"total_price"`
+	VatRate     string  `json:"vat_rate"`
+	VatRateType *string `json:"vat_rate_type"`
+	VatAmount   string  `json:"vat_amount"`
+}
+
+// vatBreakdownJSON totals the order lines taxed at one VAT rate.
+type vatBreakdownJSON struct {
+	Rate      string `json:"rate"`
+	NetAmount string `json:"net_amount"`
+	VatAmount string `json:"vat_amount"`
+}
+
+// invoiceJSON links the invoice PDF; the URL stops working at ExpiresAt.
+type invoiceJSON struct {
+	InvoiceNumber string    `json:"invoice_number"`
+	IssuedAt      time.Time `json:"issued_at"`
+	URL           string    `json:"url"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+type orderEventJSON struct {
+	EventType  string    `json:"event_type"`
+	FromStatus *string   `json:"from_status"`
+	ToStatus   *string   `json:"to_status"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// --- Handlers ---
+
+// Lookup handles POST /api/v1/orders/lookup
+// Exchanges an order number and the email it was placed with for an order
+// access token. A wrong email gets the same 404 as an unknown order.
+func (h *OrderHandler) Lookup(w http.ResponseWriter, r *http.Request) {
+	var req orderLookupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "invalid request body"})
+		return
+	}
+
+	email := strings.TrimSpace(req.Email)
+	if req.OrderNumber <= 0 || email == "" {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "order_number and email are required"})
+		return
+	}
+
+	o, err := h.orderSvc.GetByNumber(r.Context(), req.OrderNumber)
+	if err != nil && !errors.Is(err, order.ErrNotFound) {
+		h.logger.Error("failed to look up order", "error", err, "order_number", req.OrderNumber)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return
+	}
+	if err != nil || !strings.EqualFold(o.Email, email) {
+		writeJSON(w, http.StatusNotFound, errorJSON{Error: "order not found"})
+		return
+	}
+
+	token, expiresAt, err := h.jwtMgr.GenerateOrderAccessToken(o.ID, o.OrderNumber)
+	if err != nil {
+		h.logger.Error("failed to generate order access token", "error", err, "order_id", o.ID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, orderLookupResponse{
+		OrderNumber: o.OrderNumber,
+		AccessToken: token,
+		ExpiresAt:   expiresAt,
+	})
+}
+
+// GetGuestOrder handles GET /api/v1/orders/{number}
+// Requires an order access token from Lookup as a Bearer token.
+func (h *OrderHandler) GetGuestOrder(w http.ResponseWriter, r *http.Request) {
+	if o, ok := h.guestOrder(w, r); ok {
+		h.writeOrderDetail(w, r, o)
+	}
+}
+
+// GetCustomerOrder handles GET /api/v1/customers/me/orders/{number}
+// Orders of other customers and guest orders return 404.
+func (h *OrderHandler) GetCustomerOrder(w http.ResponseWriter, r *http.Request) {
+	if o, ok := h.customerOrder(w, r); ok {
+		h.writeOrderDetail(w, r, o)
+	}
+}
+
+// GetGuestInvoice handles GET /api/v1/orders/{number}/invoice
+// Requires an order access token from Lookup as a Bearer token.
+func (h *OrderHandler) GetGuestInvoice(w http.ResponseWriter, r *http.Request) {
+	if o, ok := h.guestOrder(w, r); ok {
+		h.writeInvoice(w, r, o)
+	}
+}
+
+// GetCustomerInvoice handles GET /api/v1/customers/me/orders/{number}/invoice
+func (h *OrderHandler) GetCustomerInvoice(w http.ResponseWriter, r *http.Request) {
+	if o, ok := h.customerOrder(w, r); ok {
+		h.writeInvoice(w, r, o)
+	}
+}
+
+// guestOrder loads the order named in the path for the holder of its order
+// access token. It writes the error response and returns false on failure.
+func (h *OrderHandler) guestOrder(w http.ResponseWriter, r *http.Request) (db.Order, bool) {
+	number, err := strconv.ParseInt(r.PathValue("number"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "invalid order number"})
+		return db.Order{}, false
+	}
+
+	parts := strings.SplitN(r.Header.Get("Authorization"), " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+		writeJSON(w, http.StatusUnauthorized, errorJSON{Error: "missing order access token"})
+		return db.Order{}, false
+	}
+	claims, err := h.jwtMgr.ValidateOrderAccessToken(parts[1])
+	if err != nil || claims.OrderNumber != number {
+		writeJSON(w, http.StatusUnauthorized, errorJSON{Error: "invalid or expired token"})
+		return db.Order{}, false
+	}
+
+	o, err := h.orderSvc.Get(r.Context(), claims.OrderID)
+	if err != nil {
+		if errors.Is(err, order.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorJSON{Error: "order not found"})
+			return db.Order{}, false
+		}
+		h.logger.Error("failed to get order", "error", err, "order_id", claims.OrderID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return db.Order{}, false
+	}
+	return o, true
+}
+
+// customerOrder loads the order named in the path if it belongs to the
+// signed-in customer. It writes the error response and returns false on
+// failure.
+func (h *OrderHandler) customerOrder(w http.ResponseWriter, r *http.Request) (db.Order, bool) {
+	customerID, ok := middleware.CustomerFromContext(r.Context())
+	if !ok {
+		writeJSON(w, http.StatusUnauthorized, errorJSON{Error: "not authenticated"})
+		return db.Order{}, false
+	}
+
+	number, err := strconv.ParseInt(r.PathValue("number"), 10, 64)
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, errorJSON{Error: "invalid order number"})
+		return db.Order{}, false
+	}
+
+	o, err := h.orderSvc.GetByNumber(r.Context(), number)
+	if err != nil && !errors.Is(err, order.ErrNotFound) {
+		h.logger.Error("failed to get order", "error", err, "order_number", number)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return db.Order{}, false
+	}
+	if err != nil || !o.CustomerID.Valid || o.CustomerID.Bytes != customerID {
+		writeJSON(w, http.StatusNotFound, errorJSON{Error: "order not found"})
+		return db.Order{}, false
+	}
+	return o, true
+}
+
+// writeInvoice writes a short-lived download link for the invoice of o.
+func (h *OrderHandler) writeInvoice(w http.ResponseWriter, r *http.Request, o db.Order) {
+	inv, err := h.invoiceSvc.GetForOrder(r.Context(), o.ID)
+	if err != nil {
+		if errors.Is(err, invoice.ErrNotFound) {
+			writeJSON(w, http.StatusNotFound, errorJSON{Error: "no invoice has been issued for this order"})
+			return
+		}
+		h.logger.Error("failed to get invoice", "error", err, "order_id", o.ID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return
+	}
+
+	url, err := h.invoiceSvc.DownloadURL(r.Context(), inv)
+	if err != nil {
+		h.logger.Error("failed to sign invoice download", "error", err, "invoice_number", inv.InvoiceNumber)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, invoiceJSON{
+		InvoiceNumber: inv.InvoiceNumber,
+		IssuedAt:      inv.IssuedAt,
+		URL:           url,
+		ExpiresAt:     time.Now().UTC().Add(invoice.DownloadExpiry),
+	})
+}
+
+// writeOrderDetail loads the items and events of o and writes the order
+// detail response.
+func (h *OrderHandler) writeOrderDetail(w http.ResponseWriter, r *http.Request, o db.Order) {
+	items, err := h.orderSvc.ListItems(r.Context(), o.ID)
+	if err != nil {
+		h.logger.Error("failed to list order items", "error", err, "order_id", o.ID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return
+	}
+
+	events, err := h.orderSvc.ListEvents(r.Context(), o.ID)
+	if err != nil {
+		h.logger.Error("failed to list order events", "error", err, "order_id", o.ID)
+		writeJSON(w, http.StatusInternalServerError, errorJSON{Error: "internal server error"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newOrderDetailJSON(o, items, events))
+}
+
+// --- Helpers ---
+
+func newOrderDetailJSON(o db.Order, items []db.OrderItem, events []db.OrderEvent) orderDetailJSON {
+	resp := orderDetailJSON{
+		OrderNumber:       o.OrderNumber,
+		Status:            o.Status,
+		PaymentStatus:     o.PaymentStatus,
+		Email:             o.Email,
+		BillingAddress:    o.BillingAddress,
+		ShippingAddress:   o.ShippingAddress,
+		Subtotal:          numericToDecimal(o.Subtotal).StringFixed(2),
+		ShippingFee:       numericToDecimal(o.ShippingFee).StringFixed(2),
+		ShippingExtraFees: numericToDecimal(o.ShippingExtraFees).StringFixed(2),
+		DiscountAmount:    numericToDecimal(o.DiscountAmount).StringFixed(2),
+		VatTotal:          numericToDecimal(o.VatTotal).StringFixed(2),
+		Total:             numericToDecimal(o.Total).StringFixed(2),
+		VatNumber:         o.VatNumber,
+		VatCompanyName:    o.VatCompanyName,
+		VatReverseCharge:  o.VatReverseCharge,
+		ShippingMethod:    o.ShippingMethod,
+		TrackingNumber:    o.TrackingNumber,
+		CustomerNotes:     o.CustomerNotes,
+		CreatedAt:         o.CreatedAt,
+		Items:             make([]orderItemJSON, 0, len(items)),
+		VatBreakdown:      orderVATBreakdown(items),
+		Events:            make([]orderEventJSON, 0, len(events)),
+	}
+	if o.ShippedAt.Valid {
+		resp.ShippedAt = &o.ShippedAt.Time
+	}
+	if o.DeliveredAt.Valid {
+		resp.DeliveredAt = &o.DeliveredAt.Time
+	}
+
+	for _, item := range items {
+		resp.Items = append(resp.Items, orderItemJSON{
+			ProductName: item.ProductName,
+			VariantName: item.VariantName,
+			Sku:         item.Sku,
+			Quantity:    item.Quantity,
+			UnitPrice:   numericToDecimal(item.UnitPrice).StringFixed(2),
+			TotalPrice:  numericToDecimal(item.TotalPrice).StringFixed(2),
+			VatRate:     numericToDecimal(item.VatRate).StringFixed(2),
+			VatRateType: item.VatRateType,
+			VatAmount:   numericToDecimal(item.VatAmount).StringFixed(2),
+		})
+	}
+
+	for _, ev := range events {
+		resp.Events = append(resp.Events, orderEventJSON{
+			EventType:  ev.EventType,
+			FromStatus: ev.FromStatus,
+			ToStatus:   ev.ToStatus,
+			CreatedAt:  ev.CreatedAt,
+		})
+	}
+
+	return resp
+}
+
+// orderVATBreakdown groups the order lines by VAT rate, highest rate first.
+// The net amount of a line is its net unit price times the quantity.
+func orderVATBreakdown(items []db.OrderItem) []vatBreakdownJSON {
+	type totals struct {
+		rate     decimal.Decimal
+		net, vat decimal.Decimal
+	}
+
+	byRate := make(map[string]*totals)
+	for _, item := range items {
+		rate := numericToDecimal(item.VatRate)
+		key := rate.StringFixed(2)
+		t, ok := byRate[key]
+		if !ok {
+			t = &totals{rate: rate}
+			byRate[key] = t
+		}
+		t.net = t.net.Add(numericToDecimal(item.NetUnitPrice).Mul(decimal.NewFromInt32(item.Quantity)))
+		t.vat = t.vat.Add(numericToDecimal(item.VatAmount))
+	}
+
+	rates := make([]*totals, 0, len(byRate))
+	for _, t := range byRate {
+		rates = append(rates, t)
+	}
+	sort.Slice(rates, func(i, j int) bool { return rates[i].rate.GreaterThan(rates[j].rate) })
+
+	breakdown := make([]vatBreakdownJSON, 0, len(rates))
+	for _, t := range rates {
+		breakdown = append(breakdown, vatBreakdownJSON{
+			Rate:      t.rate.StringFixed(2),
+			NetAmount: t.net.StringFixed(2),
+			VatAmount: t.vat.StringFixed(2),
+		})
+	}
+	return breakdown
+}
@@ -0,0 +1,271 @@
+package api_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/forgecommerce/api/internal/auth"
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/handlers/api"
+	"github.com/forgecommerce/api/internal/middleware"
+	"github.com/forgecommerce/api/internal/services/invoice"
+	"github.com/forgecommerce/api/internal/services/order"
+	"github.com/forgecommerce/api/internal/storage"
+)
+
+func newOrderHandler() *api.OrderHandler {
+	orderSvc := order.NewService(testDB.Pool, nil, nil)
+	return api.NewOrderHandler(orderSvc, invoice.NewService(testDB.Pool, nil, nil), auth.NewJWTManager(testJWTSecret), slog.Default())
+}
+
+func numeric(t *testing.T, s string) pgtype.Numeric {
+	t.Helper()
+	var n pgtype.Numeric
+	if err := n.Scan(s); err != nil {
+		t.Fatalf("scanning numeric %q: %v", s, err)
+	}
+	return n
+}
+
+// createTestOrder creates a paid order with a 21% and a 10% VAT line.
+func createTestOrder(t *testing.T, email string, customerID pgtype.UUID) db.Order {
+	t.Helper()
+	orderSvc := order.NewService(testDB.Pool, nil, nil)
+
+	line := func(name, gross, total, net, rate, vat string) order.CreateOrderItemInput {
+		return order.CreateOrderItemInput{
+			ProductName:      name,
+			Quantity:         2,
+			UnitPrice:        numeric(t, gross),
+			TotalPrice:       numeric(t, total),
+			VatRate:          numeric(t, rate),
+			VatAmount:        numeric(t, vat),
+			PriceIncludesVat: true,
+			NetUnitPrice:     numeric(t, net),
+			GrossUnitPrice:   numeric(t, gross),
+			Metadata:         json.RawMessage(`{}`),
+		}
+	}
+
+	o, _, err := orderSvc.Create(context.Background(), order.CreateOrderParams{
+		CustomerID:        customerID,
+		Status:            "confirmed",
+		Email:             email,
+		PaymentStatus:     "paid",
+		BillingAddress:    json.RawMessage(`{"city":"Madrid"}`),
+		ShippingAddress:   json.RawMessage(`{"city":"Madrid"}`),
+		Subtotal:          numeric(t, "0"),
+		ShippingFee:       numeric(t, "0"),
+		ShippingExtraFees: numeric(t, "0"),
+		DiscountAmount:    numeric(t, "0"),
+		VatTotal:          numeric(t, "0"),
+		Total:             numeric(t, "0"),
+		Metadata:          json.RawMessage(`{}`),
+		Items: []order.CreateOrderItemInput{
+			line("Bag", "12.10", "24.20", "10.00", "21.00", "4.20"),
+			line("Book", "11.00", "22.00", "10.00", "10.00", "2.00"),
+		},
+	})
+	if err != nil {
+		t.Fatalf("creating order: %v", err)
+	}
+	return o
+}
+
+func lookupOrder(t *testing.T, mux http.Handler, number int64, email string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(map[string]any{"order_number": number, "email": email})
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/orders/lookup", bytes.NewReader(body))
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+	return rr
+}
+
+// --------------------------------------------------------------------------
+// Guest lookup
+// --------------------------------------------------------------------------
+
+func TestOrderLookup_ThenGetOrder(t *testing.T) {
+	testDB.Truncate(t)
+	o := createTestOrder(t, "guest@example.com", pgtype.UUID{})
+
+	mux := http.NewServeMux()
+	newOrderHandler().RegisterRoutes(mux)
+
+	rr := lookupOrder(t, mux, o.OrderNumber, "Guest@Example.com")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("lookup status: got %d, want %d\nbody: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var lookup struct {
+		AccessToken string `json:"access_token"`
+	}
+	json.NewDecoder(rr.Body).Decode(&lookup)
+	if lookup.AccessToken == "" {
+		t.Fatal("expected an access token")
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/orders/"+strconv.FormatInt(o.OrderNumber, 10), nil)
+	req.Header.Set("Authorization", "Bearer "+lookup.AccessToken)
+	rr = httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("get status: got %d, want %d\nbody: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+
+	var detail struct {
+		OrderNumber  int64             `json:"order_number"`
+		Items        []json.RawMessage `json:"items"`
+		Events       []json.RawMessage `json:"events"`
+		VatBreakdown []struct {
+			Rate      string `json:"rate"`
+			NetAmount string `json:"net_amount"`
+			VatAmount string `json:"vat_amount"`
+		} `json:"vat_breakdown"`
+	}
+	json.NewDecoder(rr.Body).Decode(&detail)
+
+	if detail.OrderNumber != o.OrderNumber {
+		t.Errorf("order_number: got %d, want %d", detail.OrderNumber, o.OrderNumber)
+	}
+	if len(detail.Items) != 2 {
+		t.Errorf("items: got %d, want 2", len(detail.Items))
+	}
+	if len(detail.Events) == 0 {
+		t.Error("expected the order_created event")
+	}
+	if len(detail.VatBreakdown) != 2 {
+		t.Fatalf("vat_breakdown: got %d rates, want 2", len(detail.VatBreakdown))
+	}
+	first := detail.VatBreakdown[0]
+	if first.Rate != "21.00" || first.NetAmount != "20.00" || first.VatAmount != "4.20" {
+		t.Errorf("first rate: got %+v, want 21.00 / 20.00 / 4.20", first)
+	}
+}
+
+func TestOrderLookup_WrongEmail(t *testing.T) {
+	testDB.Truncate(t)
+	o := createTestOrder(t, "guest@example.com", pgtype.UUID{})
+
+	mux := http.NewServeMux()
+	newOrderHandler().RegisterRoutes(mux)
+
+	rr := lookupOrder(t, mux, o.OrderNumber, "someone@example.com")
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("status: got %d, want %d", rr.Code, http.StatusNotFound)
+	}
+}
+
+func TestGetGuestOrder_TokenForOtherOrder(t *testing.T) {
+	testDB.Truncate(t)
+	o1 := createTestOrder(t, "first@example.com", pgtype.UUID{})
+	o2 := createTestOrder(t, "second@example.com", pgtype.UUID{})
+
+	mux := http.NewServeMux()
+	newOrderHandler().RegisterRoutes(mux)
+
+	token, _, err := auth.NewJWTManager(testJWTSecret).GenerateOrderAccessToken(o1.ID, o1.OrderNumber)
+	if err != nil {
+		t.Fatalf("generating token: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/orders/"+strconv.FormatInt(o2.OrderNumber, 10), nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("status: got %d, want %d", rr.Code, http.StatusUnauthorized)
+	}
+}
+
+// --------------------------------------------------------------------------
+// Customer order detail (protected)
+// --------------------------------------------------------------------------
+
+func TestGetCustomerOrder(t *testing.T) {
+	testDB.Truncate(t)
+	owner := testDB.FixtureCustomer(t, "owner@example.com")
+	other := testDB.FixtureCustomer(t, "other@example.com")
+	o := createTestOrder(t, "owner@example.com", pgtype.UUID{Bytes: owner.ID, Valid: true})
+
+	mux := http.NewServeMux()
+	newOrderHandler().RegisterProtectedRoutes(mux)
+
+	get := func(customerID uuid.UUID) int {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/customers/me/orders/"+strconv.FormatInt(o.OrderNumber, 10), nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.CustomerIDKey, customerID))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	if code := get(owner.ID); code != http.StatusOK {
+		t.Errorf("owner: got %d, want %d", code, http.StatusOK)
+	}
+	if code := get(other.ID); code != http.StatusNotFound {
+		t.Errorf("other customer: got %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestGetCustomerInvoice(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	ctx := context.Background()
+	if _, err := testDB.Pool.Exec(ctx, `
+		UPDATE store_settings SET
+			vat_enabled = true, vat_number = 'DE123456789', vat_country_code = 'DE',
+			store_address = '{"legal_name":"Forge GmbH","line1":"Hauptstraße 1","postal_code":"10115","city":"Berlin"}'`,
+	); err != nil {
+		t.Fatalf("setting store details: %v", err)
+	}
+	owner := testDB.FixtureCustomer(t, "invoice@example.com")
+	o := createTestOrder(t, "invoice@example.com", pgtype.UUID{Bytes: owner.ID, Valid: true})
+
+	invoiceSvc := invoice.NewService(testDB.Pool, storage.NewLocal(t.TempDir(), "/media/private"), nil)
+	mux := http.NewServeMux()
+	api.NewOrderHandler(order.NewService(testDB.Pool, nil, nil), invoiceSvc, auth.NewJWTManager(testJWTSecret), slog.Default()).
+		RegisterProtectedRoutes(mux)
+
+	get := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/customers/me/orders/"+strconv.FormatInt(o.OrderNumber, 10)+"/invoice", nil)
+		req = req.WithContext(context.WithValue(req.Context(), middleware.CustomerIDKey, owner.ID))
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+		return rr
+	}
+
+	if rr := get(); rr.Code != http.StatusNotFound {
+		t.Fatalf("before issuing: got %d, want %d", rr.Code, http.StatusNotFound)
+	}
+
+	inv, err := invoiceSvc.Issue(ctx, o.ID)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+
+	rr := get()
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want %d\nbody: %s", rr.Code, http.StatusOK, rr.Body.String())
+	}
+	var resp struct {
+		InvoiceNumber string `json:"invoice_number"`
+		URL           string `json:"url"`
+	}
+	json.NewDecoder(rr.Body).Decode(&resp)
+	if resp.InvoiceNumber != inv.InvoiceNumber {
+		t.Errorf("invoice_number: got %q, want %q", resp.InvoiceNumber, inv.InvoiceNumber)
+	}
+	if resp.URL != "/media/private/"+inv.StorageKey {
+		t.Errorf("url: got %q, want %q", resp.URL, "/media/private/"+inv.StorageKey)
+	}
+}
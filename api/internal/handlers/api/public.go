@@ -14,6 +14,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/email"
 	"github.com/forgecommerce/api/internal/services/category"
 	"github.com/forgecommerce/api/internal/services/product"
 	"github.com/forgecommerce/api/internal/services/variant"
@@ -560,6 +561,15 @@ func parsePagination(r *http.Request) (page, limit int) {
 	return page, limit
 }
 
+// requestLanguage returns the email language for a request: the explicit
+// language field if given, otherwise the best match for Accept-Language.
+func requestLanguage(r *http.Request, explicit string) string {
+	if explicit != "" {
+		return email.NormalizeLanguage(explicit)
+	}
+	return email.LanguageFromAcceptLanguage(r.Header.Get("Accept-Language"))
+}
+
 // pgtypeUUIDToPtr converts a pgtype.UUID to a *uuid.UUID pointer.
 // Returns nil when the pgtype.UUID is not valid (SQL NULL).
 func pgtypeUUIDToPtr(pg pgtype.UUID) *uuid.UUID {
@@ -41,9 +41,9 @@ func TestMain(m *testing.M) {
 
 func newPublicHandler() *api.PublicHandler {
 	logger := slog.Default()
-	productSvc := product.NewService(testDB.Pool, logger)
+	productSvc := product.NewService(testDB.Pool, nil, logger)
 	categorySvc := category.NewService(testDB.Pool, logger)
-	variantSvc := variant.NewService(testDB.Pool, logger)
+	variantSvc := variant.NewService(testDB.Pool, nil, logger)
 	return api.NewPublicHandler(productSvc, categorySvc, variantSvc, testDB.Pool, logger)
 }
 
@@ -403,9 +403,9 @@ func TestResponseContentType(t *testing.T) {
 // --------------------------------------------------------------------------
 
 func TestNewPublicHandler_NilLogger(t *testing.T) {
-	productSvc := product.NewService(testDB.Pool, nil)
+	productSvc := product.NewService(testDB.Pool, nil, nil)
 	categorySvc := category.NewService(testDB.Pool, nil)
-	variantSvc := variant.NewService(testDB.Pool, nil)
+	variantSvc := variant.NewService(testDB.Pool, nil, nil)
 
 	// Should not panic; uses slog.Default() internally.
 	h := api.NewPublicHandler(productSvc, categorySvc, variantSvc, testDB.Pool, nil)
@@ -1,40 +1,60 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"strings"
 
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
 	stripe "github.com/stripe/stripe-go/v82"
 
+	"github.com/forgecommerce/api/internal/services/cart"
+	"github.com/forgecommerce/api/internal/services/discount"
+	"github.com/forgecommerce/api/internal/services/inventory"
 	"github.com/forgecommerce/api/internal/services/order"
+	"github.com/forgecommerce/api/internal/services/refund"
 	forgestripe "github.com/forgecommerce/api/internal/stripe"
 )
 
 // WebhookHandler handles incoming Stripe webhook events.
 type WebhookHandler struct {
-	stripeSvc *forgestripe.Service
-	orderSvc  *order.Service
-	logger    *slog.Logger
-	secret    string // webhook signing secret
+	stripeSvc    *forgestripe.Service
+	orderSvc     *order.Service
+	cartSvc      *cart.Service
+	inventorySvc *inventory.Service
+	refundSvc    *refund.Service
+	discountSvc  *discount.Service
+	logger       *slog.Logger
+	secret       string // webhook signing secret
 }
 
 // NewWebhookHandler creates a new Stripe webhook handler.
 func NewWebhookHandler(
 	stripeSvc *forgestripe.Service,
 	orderSvc *order.Service,
+	cartSvc *cart.Service,
+	inventorySvc *inventory.Service,
+	refundSvc *refund.Service,
+	discountSvc *discount.Service,
 	logger *slog.Logger,
 	webhookSecret string,
 ) *WebhookHandler {
 	return &WebhookHandler{
-		stripeSvc: stripeSvc,
-		orderSvc:  orderSvc,
-		logger:    logger,
-		secret:    webhookSecret,
+		stripeSvc:    stripeSvc,
+		orderSvc:     orderSvc,
+		cartSvc:      cartSvc,
+		inventorySvc: inventorySvc,
+		refundSvc:    refundSvc,
+		discountSvc:  discountSvc,
+		logger:       logger,
+		secret:       webhookSecret,
 	}
 }
 
@@ -72,10 +92,14 @@ func (h *WebhookHandler) HandleStripeWebhook(w http.ResponseWriter, r *http.Requ
 	switch event.Type {
 	case "checkout.session.completed":
 		h.handleCheckoutSessionCompleted(r, event)
+	case "checkout.session.expired":
+		h.handleCheckoutSessionExpired(r, event)
 	case "payment_intent.succeeded":
 		h.handlePaymentIntentSucceeded(r, event)
 	case "payment_intent.payment_failed":
 		h.handlePaymentIntentFailed(r, event)
+	case "charge.refunded":
+		h.handleChargeRefunded(r, event)
 	default:
 		h.logger.Debug("unhandled webhook event type", "type", string(event.Type))
 	}
@@ -94,6 +118,19 @@ func (h *WebhookHandler) handleCheckoutSessionCompleted(r *http.Request, event s
 		return
 	}
 
+	// Stripe redelivers events it did not see acknowledged; the order, coupon
+	// redemption and stock for a session are only handled once.
+	if existing, err := h.orderSvc.GetByCheckoutSession(r.Context(), session.ID); err == nil {
+		h.logger.Info("checkout session already has an order",
+			slog.String("session_id", session.ID),
+			slog.String("order_id", existing.ID.String()),
+		)
+		return
+	} else if !errors.Is(err, order.ErrNotFound) {
+		h.logger.Error("failed to look up order for checkout session", "error", err, "session_id", session.ID)
+		return
+	}
+
 	cartIDStr, ok := session.Metadata["cart_id"]
 	if !ok || cartIDStr == "" {
 		h.logger.Error("checkout session missing cart_id metadata", "session_id", session.ID)
@@ -137,12 +174,18 @@ func (h *WebhookHandler) handleCheckoutSessionCompleted(r *http.Request, event s
 		slog.String("payment_intent_id", paymentIntentID),
 	)
 
-	// Build order params — amounts will be populated from the session totals.
-	// Note: In a production system, we'd recalculate from cart items for accuracy.
-	// Here we use the Stripe session amounts as the source of truth since
-	// the customer has already paid.
+	// The amount paid comes from the Stripe session, since the customer has
+	// already paid it.
 	total := numericFromStripeAmount(session.AmountTotal)
 	subtotal := numericFromStripeAmount(session.AmountSubtotal)
+	discountAmount := zeroNumeric()
+	if session.TotalDetails != nil {
+		discountAmount = numericFromStripeAmount(session.TotalDetails.AmountDiscount)
+	}
+	shippingFee := zeroNumeric()
+	if fee, err := decimal.NewFromString(session.Metadata["shipping_fee"]); err == nil {
+		shippingFee = decimalToNumeric(fee)
+	}
 
 	params := order.CreateOrderParams{
 		Status:                  "pending",
@@ -150,17 +193,46 @@ func (h *WebhookHandler) handleCheckoutSessionCompleted(r *http.Request, event s
 		BillingAddress:          billingAddress,
 		ShippingAddress:         shippingAddress,
 		Subtotal:                subtotal,
-		ShippingFee:             zeroNumeric(),
+		ShippingFee:             shippingFee,
 		ShippingExtraFees:       zeroNumeric(),
-		DiscountAmount:          zeroNumeric(),
+		DiscountAmount:          discountAmount,
 		VatTotal:                zeroNumeric(),
 		Total:                   total,
 		VatReverseCharge:        vatNumber != "",
 		StripePaymentIntentID:   strPtrOrNil(paymentIntentID),
 		StripeCheckoutSessionID: &sessionID,
 		PaymentStatus:           "paid",
-		Items:                   []order.CreateOrderItemInput{},
-		Metadata:                json.RawMessage(`{}`),
+		CustomerID:              metadataUUID(session.Metadata, "customer_id"),
+		DiscountID:              metadataUUID(session.Metadata, "discount_id"),
+		CouponID:                metadataUUID(session.Metadata, "coupon_id"),
+		DiscountBreakdown:       discountBreakdown(session.Metadata),
+		ShippingMethod:          strPtrOrNil(session.Metadata["shipping_method"]),
+		Metadata:                orderMetadata(session.Metadata),
+	}
+
+	// The lines and the VAT on each were fixed when the checkout session
+	// was created; invoices, credit notes and VAT returns are built from
+	// them. Without them the order is still recorded, since it has been
+	// paid, but it cannot be invoiced.
+	checkout, err := h.checkoutSnapshot(r.Context(), session.Metadata)
+	switch {
+	case err != nil:
+		h.logger.Error("failed to load checkout lines for order",
+			"error", err,
+			"session_id", session.ID,
+			"cart_id", cartID.String(),
+		)
+	case len(checkout.Items) == 0:
+		h.logger.Error("paid checkout session has no lines",
+			slog.String("session_id", session.ID),
+			slog.String("cart_id", cartID.String()),
+		)
+	default:
+		params.Items = checkout.Items
+		params.Subtotal = checkout.Subtotal
+		params.VatTotal = decimalToNumeric(discountedVAT(checkout.Items, numericToDecimal(discountAmount)))
+		params.VatReverseCharge = checkout.ReverseCharge
+		params.VatCompanyName = checkout.CompanyName
 	}
 
 	if countryCode != "" {
@@ -170,7 +242,12 @@ func (h *WebhookHandler) handleCheckoutSessionCompleted(r *http.Request, event s
 		params.VatNumber = &vatNumber
 	}
 
-	_, _, err = h.orderSvc.Create(r.Context(), params)
+	created, _, err := h.orderSvc.Create(r.Context(), params)
+	if errors.Is(err, order.ErrDuplicateCheckoutSession) {
+		// A concurrent delivery of the same event got there first.
+		h.logger.Info("checkout session already has an order", slog.String("session_id", session.ID))
+		return
+	}
 	if err != nil {
 		h.logger.Error("failed to create order from checkout",
 			"error", err,
@@ -183,6 +260,133 @@ func (h *WebhookHandler) handleCheckoutSessionCompleted(r *http.Request, event s
 	h.logger.Info("order created from checkout session",
 		slog.String("session_id", session.ID),
 		slog.String("cart_id", cartID.String()),
+		slog.String("order_id", created.ID.String()),
+	)
+
+	// The cart has been bought; stop offering it back to the customer.
+	if err := h.cartSvc.MarkOrdered(r.Context(), cartID, created.ID); err != nil {
+		h.logger.Error("failed to mark cart as ordered",
+			"error", err,
+			"cart_id", cartID.String(),
+			"order_id", created.ID.String(),
+		)
+	}
+
+	if params.CouponID.Valid {
+		h.redeemCoupon(r, params, created.ID)
+	}
+
+	// Turn the stock reserved at checkout into sales against the order.
+	committed, err := h.inventorySvc.CommitCheckoutSession(r.Context(), session.ID, created.ID)
+	if err != nil {
+		h.logger.Error("failed to commit stock reservation",
+			"error", err,
+			"session_id", session.ID,
+			"order_id", created.ID.String(),
+		)
+		return
+	}
+	if committed > 0 {
+		return
+	}
+
+	// The order is paid but its reservations are gone, for example released
+	// by an expiry that raced the payment. Sell the ordered lines anyway so
+	// stock is not oversold again.
+	h.logger.Error("no stock reservation found for paid checkout session",
+		slog.String("session_id", session.ID),
+		slog.String("order_id", created.ID.String()),
+	)
+	var lines []inventory.ReservationLine
+	for _, item := range params.Items {
+		if item.VariantID.Valid {
+			lines = append(lines, inventory.ReservationLine{VariantID: item.VariantID.Bytes, Quantity: item.Quantity})
+		}
+	}
+	if len(lines) == 0 {
+		return
+	}
+	if _, err := h.inventorySvc.CommitCartLines(r.Context(), cartID, session.ID, created.ID, lines); err != nil {
+		h.logger.Error("failed to commit stock from order lines",
+			"error", err,
+			"session_id", session.ID,
+			"order_id", created.ID.String(),
+		)
+	}
+}
+
+// checkoutSnapshot loads the checkout snapshot named in a checkout
+// session's metadata.
+func (h *WebhookHandler) checkoutSnapshot(ctx context.Context, metadata map[string]string) (order.Checkout, error) {
+	id := metadataUUID(metadata, "checkout_id")
+	if !id.Valid {
+		return order.Checkout{}, errors.New("checkout session has no checkout_id")
+	}
+	return h.orderSvc.GetCheckout(ctx, id.Bytes)
+}
+
+// discountedVAT is the VAT charged on an order's lines once the order's
+// discount is spread over them.
+func discountedVAT(items []order.CreateOrderItemInput, discount decimal.Decimal) decimal.Decimal {
+	lines := make([]order.VATLine, len(items))
+	for i, item := range items {
+		lines[i] = order.VATLine{
+			Total: numericToDecimal(item.TotalPrice),
+			Rate:  numericToDecimal(item.VatRate),
+			VAT:   numericToDecimal(item.VatAmount),
+		}
+	}
+	total := decimal.Zero
+	for _, l := range order.DiscountVAT(lines, discount) {
+		total = total.Add(l.VAT)
+	}
+	return total
+}
+
+// redeemCoupon counts the coupon used by a paid order against its limit.
+// The customer has already paid, so an exhausted coupon is only logged.
+func (h *WebhookHandler) redeemCoupon(r *http.Request, params order.CreateOrderParams, orderID uuid.UUID) {
+	err := h.discountSvc.RedeemCoupon(r.Context(), discount.RedeemCouponParams{
+		CouponID:   params.CouponID.Bytes,
+		CustomerID: params.CustomerID,
+		Email:      params.Email,
+		OrderID:    orderID,
+	})
+	if errors.Is(err, discount.ErrCouponUsageLimitReached) {
+		h.logger.Warn("paid order used a coupon past its usage limit",
+			slog.String("coupon_id", uuid.UUID(params.CouponID.Bytes).String()),
+			slog.String("order_id", orderID.String()),
+		)
+		return
+	}
+	if err != nil {
+		h.logger.Error("failed to redeem coupon",
+			"error", err,
+			"coupon_id", uuid.UUID(params.CouponID.Bytes).String(),
+			"order_id", orderID.String(),
+		)
+	}
+}
+
+// handleCheckoutSessionExpired releases the stock held for a checkout
+// session the customer never completed.
+func (h *WebhookHandler) handleCheckoutSessionExpired(r *http.Request, event stripe.Event) {
+	var session stripe.CheckoutSession
+	if err := json.Unmarshal(event.Data.Raw, &session); err != nil {
+		h.logger.Error("failed to unmarshal checkout session", "error", err, "event_id", event.ID)
+		return
+	}
+
+	if err := h.inventorySvc.ReleaseCheckoutSession(r.Context(), session.ID); err != nil {
+		h.logger.Error("failed to release stock reservation",
+			"error", err,
+			"session_id", session.ID,
+		)
+		return
+	}
+
+	h.logger.Info("stock reservation released for expired checkout session",
+		slog.String("session_id", session.ID),
 	)
 }
 
@@ -221,6 +425,54 @@ func (h *WebhookHandler) handlePaymentIntentFailed(r *http.Request, event stripe
 	)
 }
 
+// handleChargeRefunded records refunds made outside the admin, such as in the
+// Stripe dashboard. Refunds issued from the admin are already recorded and
+// are not counted twice.
+func (h *WebhookHandler) handleChargeRefunded(r *http.Request, event stripe.Event) {
+	var charge stripe.Charge
+	if err := json.Unmarshal(event.Data.Raw, &charge); err != nil {
+		h.logger.Error("failed to unmarshal charge", "error", err, "event_id", event.ID)
+		return
+	}
+	if charge.PaymentIntent == nil || charge.PaymentIntent.ID == "" {
+		h.logger.Warn("refunded charge has no payment intent", "charge_id", charge.ID)
+		return
+	}
+
+	// Stripe lists refunds newest first.
+	latestRefundID := ""
+	if charge.Refunds != nil && len(charge.Refunds.Data) > 0 {
+		latestRefundID = charge.Refunds.Data[0].ID
+	}
+
+	rf, recorded, err := h.refundSvc.SyncCharge(r.Context(), charge.PaymentIntent.ID, charge.AmountRefunded, latestRefundID)
+	if err != nil {
+		if errors.Is(err, refund.ErrOrderNotFound) {
+			h.logger.Warn("no order for refunded charge",
+				"charge_id", charge.ID,
+				"payment_intent_id", charge.PaymentIntent.ID,
+			)
+			return
+		}
+		h.logger.Error("failed to record stripe refund",
+			"error", err,
+			"charge_id", charge.ID,
+			"payment_intent_id", charge.PaymentIntent.ID,
+		)
+		return
+	}
+	if !recorded {
+		h.logger.Debug("charge refund already recorded", "charge_id", charge.ID)
+		return
+	}
+
+	h.logger.Info("refund from stripe recorded",
+		slog.String("charge_id", charge.ID),
+		slog.String("refund_id", rf.ID.String()),
+		slog.String("order_id", rf.OrderID.String()),
+	)
+}
+
 // --- Helpers ---
 
 // parseMaybeJSON returns the raw JSON bytes if the string is valid JSON,
@@ -237,6 +489,45 @@ func parseMaybeJSON(s string) []byte {
 	return []byte(s)
 }
 
+// orderMetadata builds the order metadata from the checkout session
+// metadata. It records the customer's email language.
+func orderMetadata(session map[string]string) json.RawMessage {
+	if lang := session["language"]; lang != "" {
+		if b, err := json.Marshal(map[string]string{"language": lang}); err == nil {
+			return b
+		}
+	}
+	return json.RawMessage(`{}`)
+}
+
+// metadataUUID parses a UUID from checkout session metadata. Missing or
+// malformed values give an invalid pgtype.UUID.
+func metadataUUID(metadata map[string]string, key string) pgtype.UUID {
+	id, err := uuid.Parse(metadata[key])
+	if err != nil {
+		return pgtype.UUID{}
+	}
+	return pgtype.UUID{Bytes: id, Valid: true}
+}
+
+// discountBreakdown reassembles the discount breakdown that checkout split
+// over discount_breakdown_0, discount_breakdown_1, ... It returns nil if
+// there is none or it is not valid JSON.
+func discountBreakdown(metadata map[string]string) []byte {
+	var b strings.Builder
+	for i := 0; ; i++ {
+		part, ok := metadata[fmt.Sprintf("discount_breakdown_%d", i)]
+		if !ok {
+			break
+		}
+		b.WriteString(part)
+	}
+	if b.Len() == 0 || !json.Valid([]byte(b.String())) {
+		return nil
+	}
+	return []byte(b.String())
+}
+
 // numericFromStripeAmount converts a Stripe amount (in cents) to pgtype.Numeric
 // representing the value in the base currency unit (e.g., 4250 -> 42.50).
 func numericFromStripeAmount(cents int64) pgtype.Numeric {
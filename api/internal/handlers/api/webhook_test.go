@@ -3,6 +3,7 @@ package api_test
 import (
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"log/slog"
 	"net/http"
@@ -11,11 +12,17 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
 	gostripe "github.com/stripe/stripe-go/v82"
 	"github.com/stripe/stripe-go/v82/webhook"
 
 	"github.com/forgecommerce/api/internal/handlers/api"
+	"github.com/forgecommerce/api/internal/services/cart"
+	"github.com/forgecommerce/api/internal/services/discount"
+	"github.com/forgecommerce/api/internal/services/inventory"
 	"github.com/forgecommerce/api/internal/services/order"
+	"github.com/forgecommerce/api/internal/services/refund"
 	forgestripe "github.com/forgecommerce/api/internal/stripe"
 )
 
@@ -25,8 +32,11 @@ const testWebhookSecret = "whsec_test_webhook_handler_secret"
 func newWebhookHandler() *api.WebhookHandler {
 	logger := slog.Default()
 	stripeSvc := forgestripe.NewService("sk_test_webhook_handler", logger)
-	orderSvc := order.NewService(testDB.Pool, logger)
-	return api.NewWebhookHandler(stripeSvc, orderSvc, logger, testWebhookSecret)
+	orderSvc := order.NewService(testDB.Pool, nil, logger)
+	inventorySvc := inventory.NewService(testDB.Pool, nil, logger)
+	refundSvc := refund.NewService(testDB.Pool, stripeSvc, orderSvc, nil, logger)
+	discountSvc := discount.NewService(testDB.Pool, logger)
+	return api.NewWebhookHandler(stripeSvc, orderSvc, cart.NewService(testDB.Pool, logger), inventorySvc, refundSvc, discountSvc, logger, testWebhookSecret)
 }
 
 // webhookMux registers the webhook handler on a fresh ServeMux.
@@ -213,6 +223,183 @@ func TestWebhookHandler_CheckoutSessionCompleted(t *testing.T) {
 	}
 }
 
+// --------------------------------------------------------------------------
+// TestWebhookHandler_CheckoutSessionCompleted_Redelivered
+// --------------------------------------------------------------------------
+
+func TestWebhookHandler_CheckoutSessionCompleted_Redelivered(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+
+	mux := webhookMux()
+
+	payload := []byte(fmt.Sprintf(`{
+		"id": "evt_test_checkout_twice",
+		"type": "checkout.session.completed",
+		"api_version": %q,
+		"data": {
+			"object": {
+				"id": "cs_test_session_twice",
+				"customer_email": "buyer@example.com",
+				"payment_intent": {"id": "pi_test_intent_twice"},
+				"amount_total": 12500,
+				"metadata": {"cart_id": %q, "country_code": "ES"}
+			}
+		}
+	}`, gostripe.APIVersion, uuid.New().String()))
+
+	// Stripe retries an event until it sees it acknowledged.
+	for i := 0; i < 2; i++ {
+		body, sigHeader := signPayload(t, payload)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/stripe", bytes.NewReader(body))
+		req.Header.Set("Stripe-Signature", sigHeader)
+		rr := httptest.NewRecorder()
+		mux.ServeHTTP(rr, req)
+
+		if rr.Code != http.StatusOK {
+			t.Fatalf("delivery %d: status got %d, want %d", i+1, rr.Code, http.StatusOK)
+		}
+	}
+
+	var orderCount int64
+	if err := testDB.Pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM orders").Scan(&orderCount); err != nil {
+		t.Fatalf("counting orders: %v", err)
+	}
+	if orderCount != 1 {
+		t.Errorf("expected 1 order after redelivery, got %d", orderCount)
+	}
+}
+
+// --------------------------------------------------------------------------
+// TestWebhookHandler_CheckoutSessionCompleted_SnapshotsCartLines
+// --------------------------------------------------------------------------
+
+func TestWebhookHandler_CheckoutSessionCompleted_SnapshotsCartLines(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	seedCheckoutDeps(t)
+
+	mux := webhookMux()
+
+	product := testDB.FixtureProduct(t, "Snapshot Product", "snapshot-product")
+	variant := testDB.FixtureVariant(t, product.ID, "SNAP-001", 10)
+	cartID := createCartWithItem(t, variant.ID)
+
+	// The lines as priced when the checkout session was created.
+	sku := "SNAP-001"
+	rateType := "standard"
+	checkoutID := uuid.New()
+	err := order.NewService(testDB.Pool, nil, nil).SaveCheckout(context.Background(), order.Checkout{
+		ID:     checkoutID,
+		CartID: cartID,
+		Items: []order.CreateOrderItemInput{{
+			ProductID:        pgtype.UUID{Bytes: product.ID, Valid: true},
+			VariantID:        pgtype.UUID{Bytes: variant.ID, Valid: true},
+			ProductName:      "Snapshot Product",
+			Sku:              &sku,
+			Quantity:         2,
+			UnitPrice:        decimalToNumeric(t, "25.00"),
+			TotalPrice:       decimalToNumeric(t, "50.00"),
+			VatRate:          decimalToNumeric(t, "21.00"),
+			VatRateType:      &rateType,
+			VatAmount:        decimalToNumeric(t, "8.68"),
+			PriceIncludesVat: true,
+			NetUnitPrice:     decimalToNumeric(t, "20.66"),
+			GrossUnitPrice:   decimalToNumeric(t, "25.00"),
+			Metadata:         json.RawMessage(`{}`),
+		}},
+		Subtotal: decimalToNumeric(t, "41.32"),
+	})
+	if err != nil {
+		t.Fatalf("saving checkout: %v", err)
+	}
+
+	// The customer changes the cart while on the Stripe page.
+	if _, err := cart.NewService(testDB.Pool, nil).AddItem(context.Background(), cartID, variant.ID, 3); err != nil {
+		t.Fatalf("adding item to cart: %v", err)
+	}
+
+	payload := []byte(fmt.Sprintf(`{
+		"id": "evt_test_checkout_lines",
+		"type": "checkout.session.completed",
+		"api_version": %q,
+		"data": {
+			"object": {
+				"id": "cs_test_session_lines",
+				"customer_email": "buyer@example.com",
+				"payment_intent": {"id": "pi_test_intent_lines"},
+				"amount_total": 5500,
+				"amount_subtotal": 5000,
+				"metadata": {
+					"cart_id": %q,
+					"checkout_id": %q,
+					"country_code": "ES",
+					"shipping_fee": "5.00",
+					"shipping_method": "fixed"
+				}
+			}
+		}
+	}`, gostripe.APIVersion, cartID.String(), checkoutID.String()))
+
+	body, sigHeader := signPayload(t, payload)
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/stripe", bytes.NewReader(body))
+	req.Header.Set("Stripe-Signature", sigHeader)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	ctx := context.Background()
+	var (
+		quantity                                    int32
+		vatRate, netUnit, grossUnit, lineVAT        string
+		subtotal, vatTotal, shippingFee, orderTotal string
+	)
+	err = testDB.Pool.QueryRow(ctx,
+		`SELECT oi.quantity, oi.vat_rate::text, oi.net_unit_price::text, oi.gross_unit_price::text,
+		        oi.vat_amount::text, o.subtotal::text, o.vat_total::text, o.shipping_fee::text, o.total::text
+		 FROM order_items oi JOIN orders o ON o.id = oi.order_id`,
+	).Scan(&quantity, &vatRate, &netUnit, &grossUnit, &lineVAT, &subtotal, &vatTotal, &shippingFee, &orderTotal)
+	if err != nil {
+		t.Fatalf("scanning order line: %v", err)
+	}
+
+	if quantity != 2 {
+		t.Errorf("quantity: got %d, want 2", quantity)
+	}
+	dec := decimal.RequireFromString
+	if !dec(vatRate).Equal(decimal.NewFromInt(21)) {
+		t.Errorf("vat_rate: got %s, want 21", vatRate)
+	}
+	if !dec(grossUnit).Equal(dec("25.00")) {
+		t.Errorf("gross_unit_price: got %s, want 25.00", grossUnit)
+	}
+	if !dec(lineVAT).IsPositive() || !dec(vatTotal).Equal(dec(lineVAT)) {
+		t.Errorf("vat: line %s, order %s, want equal and positive", lineVAT, vatTotal)
+	}
+	if !dec(subtotal).Equal(dec(netUnit).Mul(decimal.NewFromInt(2))) {
+		t.Errorf("subtotal: got %s, want 2 x %s", subtotal, netUnit)
+	}
+	if !dec(shippingFee).Equal(dec("5.00")) {
+		t.Errorf("shipping_fee: got %s, want 5.00", shippingFee)
+	}
+	if !dec(orderTotal).Equal(dec("55.00")) {
+		t.Errorf("total: got %s, want 55.00", orderTotal)
+	}
+
+	// No reservation was linked to the session, so the order's lines are
+	// sold directly.
+	var stock int32
+	if err := testDB.Pool.QueryRow(ctx, "SELECT stock_quantity FROM product_variants WHERE id = $1", variant.ID).Scan(&stock); err != nil {
+		t.Fatalf("reading stock: %v", err)
+	}
+	if stock != 8 {
+		t.Errorf("stock: got %d, want 8", stock)
+	}
+}
+
 // --------------------------------------------------------------------------
 // TestWebhookHandler_CheckoutSessionCompleted_WithVATNumber
 // --------------------------------------------------------------------------
@@ -283,6 +470,100 @@ func TestWebhookHandler_CheckoutSessionCompleted_WithVATNumber(t *testing.T) {
 	}
 }
 
+// --------------------------------------------------------------------------
+// TestWebhookHandler_CheckoutSessionCompleted_WithCoupon
+// --------------------------------------------------------------------------
+
+func TestWebhookHandler_CheckoutSessionCompleted_WithCoupon(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+
+	mux := webhookMux()
+
+	discountID, couponID := createCoupon(t, "SAVE10")
+	cartID := uuid.New()
+	breakdown := fmt.Sprintf(`[{"discount_id":%q,"discount_name":"SAVE10","type":"fixed_amount","value":10,"scope":"subtotal","amount":10}]`, discountID)
+	payload := []byte(fmt.Sprintf(`{
+		"id": "evt_test_checkout_coupon",
+		"type": "checkout.session.completed",
+		"api_version": %q,
+		"data": {
+			"object": {
+				"id": "cs_test_coupon_session",
+				"customer_email": "coupon@example.com",
+				"payment_intent": {"id": "pi_test_coupon_intent"},
+				"amount_total": 4000,
+				"amount_subtotal": 5000,
+				"total_details": {"amount_discount": 1000},
+				"metadata": {
+					"cart_id": %q,
+					"country_code": "DE",
+					"discount_id": %q,
+					"coupon_id": %q,
+					"discount_breakdown_0": %q,
+					"billing_address": "{\"city\":\"Berlin\",\"country\":\"DE\"}",
+					"shipping_address": "{\"city\":\"Berlin\",\"country\":\"DE\"}"
+				}
+			}
+		}
+	}`, gostripe.APIVersion, cartID, discountID, couponID, breakdown))
+
+	body, sigHeader := signPayload(t, payload)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/webhooks/stripe", bytes.NewReader(body))
+	req.Header.Set("Stripe-Signature", sigHeader)
+	rr := httptest.NewRecorder()
+	mux.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want %d", rr.Code, http.StatusOK)
+	}
+
+	ctx := context.Background()
+	var (
+		orderID          uuid.UUID
+		gotDiscountID    *uuid.UUID
+		gotCouponID      *uuid.UUID
+		discountAmount   string
+		breakdownEntries int
+	)
+	err := testDB.Pool.QueryRow(ctx, `
+		SELECT id, discount_id, coupon_id, discount_amount::text, jsonb_array_length(discount_breakdown)
+		FROM orders LIMIT 1`,
+	).Scan(&orderID, &gotDiscountID, &gotCouponID, &discountAmount, &breakdownEntries)
+	if err != nil {
+		t.Fatalf("scanning order: %v", err)
+	}
+
+	if gotDiscountID == nil || *gotDiscountID != discountID {
+		t.Errorf("discount_id: got %v, want %s", gotDiscountID, discountID)
+	}
+	if gotCouponID == nil || *gotCouponID != couponID {
+		t.Errorf("coupon_id: got %v, want %s", gotCouponID, couponID)
+	}
+	if discountAmount != "10.00" {
+		t.Errorf("discount_amount: got %q, want %q", discountAmount, "10.00")
+	}
+	if breakdownEntries != 1 {
+		t.Errorf("discount_breakdown entries: got %d, want 1", breakdownEntries)
+	}
+
+	var usageCount, usageRows int
+	err = testDB.Pool.QueryRow(ctx, `
+		SELECT c.usage_count, (SELECT count(*) FROM coupon_usage u WHERE u.coupon_id = c.id AND u.order_id = $2)
+		FROM coupons c WHERE c.id = $1`, couponID, orderID,
+	).Scan(&usageCount, &usageRows)
+	if err != nil {
+		t.Fatalf("scanning coupon usage: %v", err)
+	}
+	if usageCount != 1 {
+		t.Errorf("usage_count: got %d, want 1", usageCount)
+	}
+	if usageRows != 1 {
+		t.Errorf("coupon_usage rows: got %d, want 1", usageRows)
+	}
+}
+
 // --------------------------------------------------------------------------
 // TestWebhookHandler_CheckoutSessionCompleted_MissingCartID
 // --------------------------------------------------------------------------
@@ -0,0 +1,70 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/forgecommerce/api/internal/auth"
+)
+
+// APIKeyAuthenticator validates admin API keys.
+// *auth.APIKeyService satisfies this interface.
+type APIKeyAuthenticator interface {
+	Authenticate(ctx context.Context, token, ipAddress string) (*auth.APIKey, error)
+}
+
+// RequireAPIKey checks for a valid API key in the Authorization: Bearer
+// header. Unauthenticated requests receive a 401 JSON response.
+// On success, stores the key in context, and its creator's ID as the admin
+// user ID so that changes made with the key are attributed to them.
+func RequireAPIKey(keys APIKeyAuthenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeJSONError(w, http.StatusUnauthorized, "missing authorization header")
+				return
+			}
+
+			parts := strings.SplitN(authHeader, " ", 2)
+			if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				writeJSONError(w, http.StatusUnauthorized, "invalid authorization format")
+				return
+			}
+
+			key, err := keys.Authenticate(r.Context(), strings.TrimSpace(parts[1]), extractIP(r))
+			if err != nil {
+				if errors.Is(err, auth.ErrInvalidAPIKey) {
+					w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+					writeJSONError(w, http.StatusUnauthorized, "invalid, expired or revoked API key")
+					return
+				}
+				writeJSONError(w, http.StatusInternalServerError, "internal server error")
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), AdminUserIDKey, key.CreatedBy.String())
+			ctx = auth.ContextWithAPIKey(ctx, key)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireScope responds 403 Forbidden unless the API key stored by
+// RequireAPIKey grants scope. It must run inside RequireAPIKey.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key, _ := auth.APIKeyFromContext(r.Context())
+			if !key.HasScope(scope) {
+				writeJSONError(w, http.StatusForbidden, "API key lacks the "+scope+" scope")
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
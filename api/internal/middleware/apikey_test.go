@@ -0,0 +1,178 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/uuid"
+
+	"github.com/forgecommerce/api/internal/auth"
+)
+
+// mockAPIKeyAuthenticator implements APIKeyAuthenticator for testing
+// RequireAPIKey.
+type mockAPIKeyAuthenticator struct {
+	key       *auth.APIKey
+	err       error
+	gotToken  string
+	gotIPAddr string
+}
+
+func (m *mockAPIKeyAuthenticator) Authenticate(_ context.Context, token, ipAddress string) (*auth.APIKey, error) {
+	m.gotToken = token
+	m.gotIPAddr = ipAddress
+	return m.key, m.err
+}
+
+func TestRequireAPIKey_Rejects(t *testing.T) {
+	tests := []struct {
+		name       string
+		header     string
+		err        error
+		wantStatus int
+		wantError  string
+	}{
+		{"missing header", "", nil, http.StatusUnauthorized, "missing authorization header"},
+		{"basic auth", "Basic dXNlcjpwYXNz", nil, http.StatusUnauthorized, "invalid authorization format"},
+		{"invalid key", "Bearer fck_nope", auth.ErrInvalidAPIKey, http.StatusUnauthorized, "invalid, expired or revoked API key"},
+		{"lookup failure", "Bearer fck_nope", errors.New("db down"), http.StatusInternalServerError, "internal server error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys := &mockAPIKeyAuthenticator{err: tt.err}
+			handler := RequireAPIKey(keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				t.Error("handler should not be called")
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/api/v1/orders", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status: got %d, want %d", rr.Code, tt.wantStatus)
+			}
+			if tt.wantStatus == http.StatusUnauthorized && rr.Header().Get("WWW-Authenticate") == "" {
+				t.Error("expected WWW-Authenticate header on 401")
+			}
+
+			var body map[string]string
+			json.NewDecoder(rr.Body).Decode(&body)
+			if body["error"] != tt.wantError {
+				t.Errorf("error: got %q, want %q", body["error"], tt.wantError)
+			}
+		})
+	}
+}
+
+func TestRequireAPIKey_ValidKey_SetsContext(t *testing.T) {
+	creatorID := uuid.New()
+	key := &auth.APIKey{ID: uuid.New(), CreatedBy: creatorID, Scopes: []string{auth.PermOrdersRead}}
+	keys := &mockAPIKeyAuthenticator{key: key}
+
+	var called bool
+	handler := RequireAPIKey(keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		got, ok := auth.APIKeyFromContext(r.Context())
+		if !ok || got != key {
+			t.Errorf("APIKeyFromContext: got %v, %v", got, ok)
+		}
+		userID, ok := AdminUserIDFromContext(r.Context())
+		if !ok || userID != creatorID {
+			t.Errorf("AdminUserIDFromContext: got %s, %v; want %s", userID, ok, creatorID)
+		}
+		if _, ok := auth.UserFromContext(r.Context()); ok {
+			t.Error("API key requests must not carry a signed-in user")
+		}
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/api/v1/orders", nil)
+	req.Header.Set("Authorization", "bearer fck_secret")
+	req.Header.Set("X-Forwarded-For", "203.0.113.9")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if !called {
+		t.Fatalf("handler not called, status %d", rr.Code)
+	}
+	if keys.gotToken != "fck_secret" {
+		t.Errorf("token: got %q", keys.gotToken)
+	}
+	if keys.gotIPAddr != "203.0.113.9" {
+		t.Errorf("ip address: got %q", keys.gotIPAddr)
+	}
+}
+
+func TestRequireScope(t *testing.T) {
+	key := &auth.APIKey{ID: uuid.New(), Scopes: []string{auth.PermOrdersRead}}
+
+	tests := []struct {
+		name       string
+		key        *auth.APIKey
+		scope      string
+		wantStatus int
+	}{
+		{"granted", key, auth.PermOrdersRead, http.StatusOK},
+		{"not granted", key, auth.PermOrdersWrite, http.StatusForbidden},
+		{"no key", nil, auth.PermOrdersRead, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequireScope(tt.scope)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodGet, "/admin/api/v1/orders", nil)
+			if tt.key != nil {
+				req = req.WithContext(auth.ContextWithAPIKey(req.Context(), tt.key))
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantStatus {
+				t.Errorf("status: got %d, want %d", rr.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestAPIKeyRateLimiter_LimitsPerKey(t *testing.T) {
+	limiter := APIKeyRateLimiter(0.001, 2)
+	handler := limiter(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	send := func(key *auth.APIKey) int {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api/v1/orders", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		req = req.WithContext(auth.ContextWithAPIKey(req.Context(), key))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	first := &auth.APIKey{ID: uuid.New()}
+	second := &auth.APIKey{ID: uuid.New()}
+
+	for i := 0; i < 2; i++ {
+		if code := send(first); code != http.StatusOK {
+			t.Fatalf("request %d with first key: got %d, want 200", i+1, code)
+		}
+	}
+	if code := send(first); code != http.StatusTooManyRequests {
+		t.Errorf("third request with first key: got %d, want 429", code)
+	}
+
+	// Same IP, different key: separate bucket.
+	if code := send(second); code != http.StatusOK {
+		t.Errorf("request with second key: got %d, want 200", code)
+	}
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"context"
 	"net/http"
+	"time"
 
 	"github.com/forgecommerce/api/internal/auth"
 	"github.com/google/uuid"
@@ -18,7 +19,8 @@ type SessionValidator interface {
 
 // RequireAuth checks for a valid admin session cookie.
 // If invalid, redirects to /admin/login.
-// On success, stores the admin user ID and session token in context.
+// On success, stores the admin user, their ID and the session token in
+// context.
 func RequireAuth(authService SessionValidator) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -52,13 +54,29 @@ func RequireAuth(authService SessionValidator) func(http.Handler) http.Handler {
 
 			ctx := context.WithValue(r.Context(), AdminUserIDKey, user.ID.String())
 			ctx = context.WithValue(ctx, SessionTokenKey, cookie.Value)
+			ctx = auth.ContextWithUser(ctx, user)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
 
-// SetSessionCookie sets the admin session cookie on the response.
-func SetSessionCookie(w http.ResponseWriter, token string) {
+// RequirePermission responds 403 Forbidden unless the admin user stored by
+// RequireAuth has perm. It must run inside RequireAuth.
+func RequirePermission(perm string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !auth.Can(r.Context(), perm) {
+				http.Error(w, "You do not have permission to do this.", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SetSessionCookie sets the admin session cookie on the response. maxAge
+// should match the session's absolute lifetime.
+func SetSessionCookie(w http.ResponseWriter, token string, maxAge time.Duration) {
 	http.SetCookie(w, &http.Cookie{
 		Name:     sessionCookieName,
 		Value:    token,
@@ -66,7 +84,7 @@ func SetSessionCookie(w http.ResponseWriter, token string) {
 		HttpOnly: true,
 		Secure:   true,
 		SameSite: http.SameSiteStrictMode,
-		MaxAge:   8 * 60 * 60, // 8 hours
+		MaxAge:   int(maxAge.Seconds()),
 	})
 }
 
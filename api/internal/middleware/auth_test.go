@@ -6,6 +6,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/forgecommerce/api/internal/auth"
 	"github.com/google/uuid"
@@ -154,9 +155,11 @@ func TestRequireAuth_ValidSession_SetsContextAndCallsNext(t *testing.T) {
 
 	var capturedUserID string
 	var capturedToken string
+	var capturedUser *auth.AdminUser
 	handler := RequireAuth(validator)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		capturedUserID, _ = r.Context().Value(AdminUserIDKey).(string)
 		capturedToken, _ = r.Context().Value(SessionTokenKey).(string)
+		capturedUser, _ = auth.UserFromContext(r.Context())
 		w.WriteHeader(http.StatusOK)
 	}))
 
@@ -174,11 +177,49 @@ func TestRequireAuth_ValidSession_SetsContextAndCallsNext(t *testing.T) {
 	if capturedToken != sessionToken {
 		t.Errorf("session token in context: got %q, want %q", capturedToken, sessionToken)
 	}
+	if capturedUser != validator.user {
+		t.Errorf("admin user in context: got %v, want %v", capturedUser, validator.user)
+	}
+}
+
+// --- RequirePermission middleware tests ---
+
+func TestRequirePermission(t *testing.T) {
+	tests := []struct {
+		name string
+		user *auth.AdminUser
+		want int
+	}{
+		{"no user", nil, http.StatusForbidden},
+		{"role grants", &auth.AdminUser{Role: auth.RoleAccountant}, http.StatusOK},
+		{"role lacks", &auth.AdminUser{Role: auth.RoleWarehouse}, http.StatusForbidden},
+		{"individual grant", &auth.AdminUser{Role: auth.RoleWarehouse, Permissions: []string{auth.PermOrdersRefund}}, http.StatusOK},
+		{"unknown role", &auth.AdminUser{Role: "admin"}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := RequirePermission(auth.PermOrdersRefund)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest(http.MethodPost, "/admin/orders/1/refunds", nil)
+			if tt.user != nil {
+				req = req.WithContext(auth.ContextWithUser(req.Context(), tt.user))
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.want {
+				t.Errorf("status: got %d, want %d", rr.Code, tt.want)
+			}
+		})
+	}
 }
 
 func TestSetSessionCookie(t *testing.T) {
 	rr := httptest.NewRecorder()
-	SetSessionCookie(rr, "mytoken123")
+	SetSessionCookie(rr, "mytoken123", 8*time.Hour)
 
 	cookies := rr.Result().Cookies()
 	var found *http.Cookie
@@ -9,6 +9,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/forgecommerce/api/internal/auth"
 )
 
 // bucket represents a token bucket for a single client (IP address).
@@ -130,6 +132,12 @@ func (s *rateLimiterState) startCleanup() {
 //	ratelimited := middleware.RateLimiter(10, 20) // 10 req/sec, burst 20
 //	mux.Handle("/api/v1/", ratelimited(apiHandler))
 func RateLimiter(rate float64, burst int) func(http.Handler) http.Handler {
+	return keyedRateLimiter(rate, burst, extractIP)
+}
+
+// keyedRateLimiter creates rate limiting middleware with one token bucket
+// per value of keyFunc.
+func keyedRateLimiter(rate float64, burst int, keyFunc func(*http.Request) string) func(http.Handler) http.Handler {
 	state := &rateLimiterState{
 		rate:  rate,
 		burst: burst,
@@ -145,15 +153,15 @@ func RateLimiter(rate float64, burst int) func(http.Handler) http.Handler {
 				return
 			}
 
-			ip := extractIP(r)
+			key := keyFunc(r)
 
-			allowed, remaining, limit := state.allow(ip)
+			allowed, remaining, limit := state.allow(key)
 
 			w.Header().Set("X-RateLimit-Limit", strconv.Itoa(limit))
 			w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
 
 			if !allowed {
-				retryAfter := state.retryAfter(ip)
+				retryAfter := state.retryAfter(key)
 				w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
 				w.Header().Set("Content-Type", "application/json")
 				w.WriteHeader(http.StatusTooManyRequests)
@@ -176,6 +184,18 @@ func LoginRateLimiter() func(http.Handler) http.Handler {
 	return RateLimiter(5.0/60.0, 5)
 }
 
+// APIKeyRateLimiter limits requests per API key rather than per IP, so
+// scripts sharing an address do not starve each other. It must run inside
+// RequireAPIKey; requests without a key are limited per IP.
+func APIKeyRateLimiter(rate float64, burst int) func(http.Handler) http.Handler {
+	return keyedRateLimiter(rate, burst, func(r *http.Request) string {
+		if key, ok := auth.APIKeyFromContext(r.Context()); ok {
+			return "api_key:" + key.ID.String()
+		}
+		return extractIP(r)
+	})
+}
+
 // extractIP retrieves the client IP from the request, preferring
 // X-Forwarded-For and X-Real-IP headers (for reverse proxy setups),
 // and falling back to RemoteAddr.
@@ -202,3 +222,9 @@ func extractIP(r *http.Request) string {
 	}
 	return host
 }
+
+// ClientIP returns the client IP of the request, taking reverse proxy
+// headers into account the same way the rate limiter does.
+func ClientIP(r *http.Request) string {
+	return extractIP(r)
+}
@@ -0,0 +1,106 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+)
+
+// ignoredFields change on every write or must never be stored.
+var ignoredFields = map[string]bool{
+	"created_at":     true,
+	"updated_at":     true,
+	"password_hash":  true,
+	"totp_secret":    true,
+	"recovery_codes": true,
+	"secret":         true,
+}
+
+// Diff returns the fields that differ between before and after as
+// {"field": {"old": ..., "new": ...}}. before and after are structs or maps
+// and are compared by their JSON form, so db models diff by column name.
+// Pass nil as before for a created entity and as after for a deleted one.
+func Diff(before, after any) map[string]any {
+	b, a := jsonFields(before), jsonFields(after)
+	changes := make(map[string]any)
+	for k, v := range a {
+		if !ignoredFields[k] && !reflect.DeepEqual(b[k], v) {
+			changes[k] = map[string]any{"old": b[k], "new": v}
+		}
+	}
+	for k, v := range b {
+		if _, ok := a[k]; !ok && !ignoredFields[k] && v != nil {
+			changes[k] = map[string]any{"old": v, "new": nil}
+		}
+	}
+	return changes
+}
+
+// jsonFields returns the top-level JSON fields of v. Numbers are kept as
+// json.Number so decimal amounts compare and print exactly.
+func jsonFields(v any) map[string]any {
+	if v == nil {
+		return nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var fields map[string]any
+	if err := dec.Decode(&fields); err != nil {
+		return nil
+	}
+	return fields
+}
+
+// FieldChange is one field of an entry's changes, formatted for display.
+// Details that are not a before/after pair, such as those written by older
+// entries, only have After.
+type FieldChange struct {
+	Field  string
+	Before string
+	After  string
+	IsDiff bool
+}
+
+// Fields returns the entry's changes sorted by field name.
+func (e Entry) Fields() []FieldChange {
+	fields := make([]FieldChange, 0, len(e.Changes))
+	for k, v := range e.Changes {
+		fc := FieldChange{Field: k}
+		if m, ok := v.(map[string]any); ok && isOldNew(m) {
+			fc.Before, fc.After, fc.IsDiff = formatValue(m["old"]), formatValue(m["new"]), true
+		} else {
+			fc.After = formatValue(v)
+		}
+		fields = append(fields, fc)
+	}
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Field < fields[j].Field })
+	return fields
+}
+
+func isOldNew(m map[string]any) bool {
+	_, hasOld := m["old"]
+	_, hasNew := m["new"]
+	return len(m) == 2 && hasOld && hasNew
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return ""
+		}
+		return string(data)
+	}
+}
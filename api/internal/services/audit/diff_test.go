@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type testProduct struct {
+	Name      string          `json:"name"`
+	Price     decimal.Decimal `json:"price"`
+	Stock     int             `json:"stock"`
+	Tags      []string        `json:"tags"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+func TestDiff(t *testing.T) {
+	before := testProduct{Name: "Mug", Price: decimal.RequireFromString("12.50"), Stock: 3, Tags: []string{"a"}, UpdatedAt: time.Unix(0, 0)}
+	after := before
+	after.Price = decimal.RequireFromString("14.00")
+	after.Tags = []string{"a", "b"}
+	after.UpdatedAt = time.Now()
+
+	changes := Diff(before, after)
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changed fields, got %d: %v", len(changes), changes)
+	}
+	price := changes["price"].(map[string]any)
+	if price["old"] != "12.5" || price["new"] != "14" {
+		t.Errorf("price = %v", price)
+	}
+	if _, ok := changes["updated_at"]; ok {
+		t.Error("updated_at should be ignored")
+	}
+}
+
+func TestDiff_CreateAndDelete(t *testing.T) {
+	p := testProduct{Name: "Mug", Stock: 1}
+
+	created := Diff(nil, p)
+	name, ok := created["name"].(map[string]any)
+	if !ok || name["old"] != nil || name["new"] != "Mug" {
+		t.Errorf("created name = %v", created["name"])
+	}
+
+	deleted := Diff(p, nil)
+	name, ok = deleted["name"].(map[string]any)
+	if !ok || name["old"] != "Mug" || name["new"] != nil {
+		t.Errorf("deleted name = %v", deleted["name"])
+	}
+	if _, ok := deleted["tags"]; ok {
+		t.Error("nil field should not be reported on delete")
+	}
+}
+
+func TestDiff_Unchanged(t *testing.T) {
+	p := testProduct{Name: "Mug"}
+	if changes := Diff(p, p); len(changes) != 0 {
+		t.Errorf("expected no changes, got %v", changes)
+	}
+}
+
+func TestEntryFields(t *testing.T) {
+	var changes map[string]any
+	if err := json.Unmarshal([]byte(`{
+		"stock": {"old": 1, "new": 2},
+		"name": {"old": "Mug", "new": null},
+		"source": "manual"
+	}`), &changes); err != nil {
+		t.Fatal(err)
+	}
+
+	got := Entry{Changes: changes}.Fields()
+	want := []FieldChange{
+		{Field: "name", Before: "Mug", After: "", IsDiff: true},
+		{Field: "source", After: "manual"},
+		{Field: "stock", Before: "1", After: "2", IsDiff: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d fields, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("field %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
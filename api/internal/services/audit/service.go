@@ -0,0 +1,216 @@
+// Package audit records what admin users change and reads the audit log
+// back for the admin panel.
+package audit
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+)
+
+// Entry is one recorded admin action.
+type Entry struct {
+	ID          uuid.UUID
+	AdminUserID uuid.UUID // uuid.Nil for system actions and deleted users
+	AdminName   string
+	AdminEmail  string
+	Action      string
+	EntityType  string
+	EntityID    string
+	Changes     map[string]any
+	IPAddress   string
+	CreatedAt   time.Time
+}
+
+// Filter narrows the audit log. Zero values match everything; To is
+// exclusive.
+type Filter struct {
+	AdminUserID uuid.UUID
+	EntityType  string
+	EntityID    string
+	Action      string
+	From        time.Time
+	To          time.Time
+}
+
+// Service writes and reads the admin audit log.
+type Service struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+	logger  *slog.Logger
+}
+
+// NewService creates a new audit service.
+func NewService(pool *pgxpool.Pool, logger *slog.Logger) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Service{
+		pool:    pool,
+		queries: db.New(pool),
+		logger:  logger,
+	}
+}
+
+// Record writes an entry to the audit log. Failures are logged rather than
+// returned: the change it describes has already been made.
+func (s *Service) Record(ctx context.Context, e Entry) {
+	var changes []byte
+	if len(e.Changes) > 0 {
+		var err error
+		if changes, err = json.Marshal(e.Changes); err != nil {
+			s.logger.Error("failed to encode audit changes", "error", err, "action", e.Action)
+		}
+	}
+
+	err := s.queries.CreateAuditLogEntry(ctx, db.CreateAuditLogEntryParams{
+		ID:          uuid.New(),
+		AdminUserID: pgtype.UUID{Bytes: e.AdminUserID, Valid: e.AdminUserID != uuid.Nil},
+		Action:      e.Action,
+		EntityType:  nilIfEmpty(e.EntityType),
+		EntityID:    nilIfEmpty(e.EntityID),
+		Changes:     changes,
+		IpAddress:   nilIfEmpty(e.IPAddress),
+		CreatedAt:   time.Now().UTC(),
+	})
+	if err != nil {
+		s.logger.Error("failed to write audit log",
+			slog.String("action", e.Action),
+			slog.String("entity_type", e.EntityType),
+			slog.String("entity_id", e.EntityID),
+			slog.String("error", err.Error()),
+		)
+	}
+}
+
+// List returns a page of entries matching f, newest first, and the total
+// number of matching entries.
+func (s *Service) List(ctx context.Context, f Filter, page, pageSize int) ([]Entry, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	params := listParams(f)
+	params.PageLimit = int32(pageSize)
+	params.PageOffset = int32((page - 1) * pageSize)
+
+	rows, err := s.queries.ListAuditLog(ctx, params)
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing audit log: %w", err)
+	}
+	total, err := s.queries.CountAuditLog(ctx, db.CountAuditLogParams{
+		AdminUserID: params.AdminUserID,
+		EntityType:  params.EntityType,
+		EntityID:    params.EntityID,
+		Action:      params.Action,
+		FromDate:    params.FromDate,
+		ToDate:      params.ToDate,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("counting audit log: %w", err)
+	}
+
+	entries := make([]Entry, len(rows))
+	for i, row := range rows {
+		entries[i] = entryFromRow(row)
+	}
+	return entries, total, nil
+}
+
+// exportBatchSize is how many entries Export reads at a time.
+const exportBatchSize = 500
+
+// Export calls fn for every entry matching f, newest first.
+func (s *Service) Export(ctx context.Context, f Filter, fn func(Entry) error) error {
+	params := listParams(f)
+	params.PageLimit = exportBatchSize
+	for {
+		rows, err := s.queries.ListAuditLog(ctx, params)
+		if err != nil {
+			return fmt.Errorf("listing audit log: %w", err)
+		}
+		for _, row := range rows {
+			if err := fn(entryFromRow(row)); err != nil {
+				return err
+			}
+		}
+		if len(rows) < exportBatchSize {
+			return nil
+		}
+		params.PageOffset += exportBatchSize
+	}
+}
+
+// Actions returns every action recorded so far, for filtering.
+func (s *Service) Actions(ctx context.Context) ([]string, error) {
+	actions, err := s.queries.ListAuditLogActions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing audit actions: %w", err)
+	}
+	return actions, nil
+}
+
+// EntityTypes returns every entity type recorded so far, for filtering.
+func (s *Service) EntityTypes(ctx context.Context) ([]string, error) {
+	types, err := s.queries.ListAuditLogEntityTypes(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing audit entity types: %w", err)
+	}
+	return types, nil
+}
+
+func listParams(f Filter) db.ListAuditLogParams {
+	return db.ListAuditLogParams{
+		AdminUserID: pgtype.UUID{Bytes: f.AdminUserID, Valid: f.AdminUserID != uuid.Nil},
+		EntityType:  nilIfEmpty(f.EntityType),
+		EntityID:    nilIfEmpty(f.EntityID),
+		Action:      nilIfEmpty(f.Action),
+		FromDate:    pgtype.Timestamptz{Time: f.From, Valid: !f.From.IsZero()},
+		ToDate:      pgtype.Timestamptz{Time: f.To, Valid: !f.To.IsZero()},
+	}
+}
+
+func entryFromRow(row db.ListAuditLogRow) Entry {
+	e := Entry{
+		ID:         row.ID,
+		AdminName:  derefString(row.AdminName),
+		AdminEmail: derefString(row.AdminEmail),
+		Action:     row.Action,
+		EntityType: derefString(row.EntityType),
+		EntityID:   derefString(row.EntityID),
+		IPAddress:  derefString(row.IpAddress),
+		CreatedAt:  row.CreatedAt,
+	}
+	if row.AdminUserID.Valid {
+		e.AdminUserID = row.AdminUserID.Bytes
+	}
+	if len(row.Changes) > 0 {
+		// Entries written by older code may hold any JSON; keep what parses.
+		dec := json.NewDecoder(bytes.NewReader(row.Changes))
+		dec.UseNumber()
+		_ = dec.Decode(&e.Changes)
+	}
+	return e
+}
+
+func nilIfEmpty(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,420 @@
+package bom
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+)
+
+// ErrVariantNotFound is returned when the variant to resolve does not exist.
+var ErrVariantNotFound = errors.New("variant not found")
+
+// Material sources — which BOM layer last contributed to a resolved line.
+const (
+	SourceProduct  = "product"  // Layer 1
+	SourceOption   = "option"   // Layer 2a
+	SourceOverride = "override" // Layer 3
+)
+
+// ResolvedMaterial is a single line of a fully resolved variant BOM,
+// joined with the current raw material stock and cost.
+type ResolvedMaterial struct {
+	RawMaterialID   uuid.UUID       `json:"raw_material_id"`
+	Name            string          `json:"name"`
+	SKU             string          `json:"sku"`
+	UnitOfMeasure   string          `json:"unit_of_measure"`
+	Quantity        decimal.Decimal `json:"quantity"`
+	IsRequired      bool            `json:"is_required"`
+	Source          string          `json:"source"`
+	StockQuantity   decimal.Decimal `json:"stock_quantity"`
+	CostPerUnit     decimal.Decimal `json:"cost_per_unit"`
+	ProducibleUnits int64           `json:"producible_units"`
+}
+
+// ResolvedBOM is the final bill of materials for a single variant after all
+// four layers have been merged, together with its producibility.
+type ResolvedBOM struct {
+	VariantID  uuid.UUID          `json:"variant_id"`
+	ProductID  uuid.UUID          `json:"product_id"`
+	SKU        string             `json:"sku"`
+	Materials  []ResolvedMaterial `json:"materials"`
+	UnitCost   decimal.Decimal    `json:"unit_cost"`
+	Producible int64              `json:"producible"`
+	// BottleneckMaterialID is the required material limiting producibility.
+	// It is nil when the BOM has no required materials.
+	BottleneckMaterialID *uuid.UUID `json:"bottleneck_material_id"`
+}
+
+// HasMaterials reports whether the variant has any materials in its BOM.
+func (r ResolvedBOM) HasMaterials() bool {
+	return len(r.Materials) > 0
+}
+
+// Bottleneck returns the limiting material, if any.
+func (r ResolvedBOM) Bottleneck() (ResolvedMaterial, bool) {
+	if r.BottleneckMaterialID == nil {
+		return ResolvedMaterial{}, false
+	}
+	for _, m := range r.Materials {
+		if m.RawMaterialID == *r.BottleneckMaterialID {
+			return m, true
+		}
+	}
+	return ResolvedMaterial{}, false
+}
+
+// ResolveVariant merges Layer 1 product entries, Layer 2a option materials,
+// Layer 2b option modifiers and Layer 3 variant overrides into the final BOM
+// for a variant, and computes how many units are producible from current
+// raw material stock.
+func (s *Service) ResolveVariant(ctx context.Context, variantID uuid.UUID) (ResolvedBOM, error) {
+	v, err := s.queries.GetProductVariant(ctx, variantID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ResolvedBOM{}, ErrVariantNotFound
+		}
+		return ResolvedBOM{}, fmt.Errorf("getting variant %s: %w", variantID, err)
+	}
+
+	entries, err := s.queries.ListProductBOMEntries(ctx, v.ProductID)
+	if err != nil {
+		return ResolvedBOM{}, fmt.Errorf("listing product BOM entries for product %s: %w", v.ProductID, err)
+	}
+
+	return s.resolveVariant(ctx, v, entries)
+}
+
+// ResolveProduct resolves the BOM for every variant of a product, in variant
+// position order.
+func (s *Service) ResolveProduct(ctx context.Context, productID uuid.UUID) ([]ResolvedBOM, error) {
+	variants, err := s.queries.ListProductVariants(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("listing variants for product %s: %w", productID, err)
+	}
+
+	entries, err := s.queries.ListProductBOMEntries(ctx, productID)
+	if err != nil {
+		return nil, fmt.Errorf("listing product BOM entries for product %s: %w", productID, err)
+	}
+
+	results := make([]ResolvedBOM, 0, len(variants))
+	for _, v := range variants {
+		resolved, err := s.resolveVariant(ctx, v, entries)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, resolved)
+	}
+	return results, nil
+}
+
+// resolveVariant loads the option and override layers for a variant and
+// resolves them on top of the given Layer 1 entries.
+func (s *Service) resolveVariant(ctx context.Context, v db.ProductVariant, entries []db.ListProductBOMEntriesRow) (ResolvedBOM, error) {
+	// Options are returned in attribute position order, which is the order
+	// in which modifiers must be applied.
+	options, err := s.queries.ListVariantOptions(ctx, v.ID)
+	if err != nil {
+		return ResolvedBOM{}, fmt.Errorf("listing options for variant %s: %w", v.ID, err)
+	}
+
+	layers := make([]optionLayer, 0, len(options))
+	for _, o := range options {
+		optEntries, err := s.queries.ListOptionBOMEntries(ctx, o.OptionID)
+		if err != nil {
+			return ResolvedBOM{}, fmt.Errorf("listing option BOM entries for option %s: %w", o.OptionID, err)
+		}
+		modifiers, err := s.queries.ListOptionBOMModifiers(ctx, o.OptionID)
+		if err != nil {
+			return ResolvedBOM{}, fmt.Errorf("listing option BOM modifiers for option %s: %w", o.OptionID, err)
+		}
+		layers = append(layers, optionLayer{entries: optEntries, modifiers: modifiers})
+	}
+
+	overrides, err := s.queries.ListVariantBOMOverrides(ctx, v.ID)
+	if err != nil {
+		return ResolvedBOM{}, fmt.Errorf("listing variant BOM overrides for variant %s: %w", v.ID, err)
+	}
+
+	lines := mergeLayers(entries, layers, overrides)
+
+	ids := make([]uuid.UUID, 0, len(lines))
+	for _, l := range lines {
+		ids = append(ids, l.materialID)
+	}
+	materials := make(map[uuid.UUID]db.RawMaterial, len(ids))
+	if len(ids) > 0 {
+		rows, err := s.queries.ListRawMaterialsByIDs(ctx, ids)
+		if err != nil {
+			return ResolvedBOM{}, fmt.Errorf("loading raw materials for variant %s: %w", v.ID, err)
+		}
+		for _, m := range rows {
+			materials[m.ID] = m
+		}
+	}
+
+	resolved := ResolvedBOM{
+		VariantID: v.ID,
+		ProductID: v.ProductID,
+		SKU:       v.Sku,
+	}
+	resolved.Materials, resolved.UnitCost, resolved.Producible, resolved.BottleneckMaterialID = computeProducibility(lines, materials)
+	return resolved, nil
+}
+
+// ---------------------------------------------------------------------------
+// Resolution algorithm
+// ---------------------------------------------------------------------------
+
+// optionLayer holds the Layer 2a entries and Layer 2b modifiers of a single
+// option selected by the variant.
+type optionLayer struct {
+	entries   []db.ListOptionBOMEntriesRow
+	modifiers []db.ListOptionBOMModifiersRow
+}
+
+// bomLine is an intermediate line during resolution. Layer 2b modifiers only
+// act on the Layer 1 base quantity; Layer 2a additions are kept separately so
+// that a multiplier on a product entry does not scale option materials.
+type bomLine struct {
+	materialID    uuid.UUID
+	entryID       uuid.UUID // Layer 1 entry ID, zero for non-product lines
+	base          decimal.Decimal
+	extra         decimal.Decimal
+	unitOfMeasure string
+	isRequired    bool
+	source        string
+}
+
+func (l *bomLine) quantity() decimal.Decimal {
+	return l.base.Add(l.extra)
+}
+
+// mergeLayers applies the resolution order documented in
+// docs/attributes-variants.md and returns the resulting lines.
+func mergeLayers(entries []db.ListProductBOMEntriesRow, options []optionLayer, overrides []db.ListVariantBOMOverridesRow) []*bomLine {
+	var lines []*bomLine
+	byMaterial := make(map[uuid.UUID]*bomLine)
+	byEntry := make(map[uuid.UUID]*bomLine)
+
+	// Layer 1: product entries.
+	for _, e := range entries {
+		l := &bomLine{
+			materialID:    e.RawMaterialID,
+			entryID:       e.ID,
+			base:          numericToDecimal(e.Quantity),
+			unitOfMeasure: e.UnitOfMeasure,
+			isRequired:    e.IsRequired,
+			source:        SourceProduct,
+		}
+		lines = append(lines, l)
+		byMaterial[e.RawMaterialID] = l
+		byEntry[e.ID] = l
+	}
+
+	// Layer 2: per option, in attribute position order.
+	for _, opt := range options {
+		// 2a: additional materials.
+		for _, e := range opt.entries {
+			qty := numericToDecimal(e.Quantity)
+			if l, ok := byMaterial[e.RawMaterialID]; ok {
+				l.extra = l.extra.Add(qty)
+				continue
+			}
+			l := &bomLine{
+				materialID:    e.RawMaterialID,
+				extra:         qty,
+				unitOfMeasure: e.UnitOfMeasure,
+				isRequired:    true,
+				source:        SourceOption,
+			}
+			lines = append(lines, l)
+			byMaterial[e.RawMaterialID] = l
+		}
+
+		// 2b: modifiers on Layer 1 entries.
+		for _, m := range opt.modifiers {
+			l, ok := byEntry[m.ProductBomEntryID]
+			if !ok {
+				continue
+			}
+			value := numericToDecimal(m.ModifierValue)
+			switch m.ModifierType {
+			case "multiply":
+				l.base = l.base.Mul(value)
+			case "add":
+				l.base = l.base.Add(value)
+			case "set":
+				l.base = value
+			}
+		}
+	}
+
+	// Layer 3: variant overrides.
+	for _, o := range overrides {
+		switch o.OverrideType {
+		case "replace":
+			if !o.ReplacesMaterialID.Valid {
+				continue
+			}
+			replaced := uuid.UUID(o.ReplacesMaterialID.Bytes)
+			old, ok := byMaterial[replaced]
+			if !ok {
+				continue
+			}
+			qty := old.quantity()
+			if o.Quantity.Valid {
+				qty = numericToDecimal(o.Quantity)
+			}
+			uom := old.unitOfMeasure
+			if o.UnitOfMeasure != nil && *o.UnitOfMeasure != "" {
+				uom = *o.UnitOfMeasure
+			}
+			lines = removeLine(lines, old)
+			delete(byMaterial, replaced)
+			if l, ok := byMaterial[o.RawMaterialID]; ok {
+				l.extra = l.extra.Add(qty)
+				l.source = SourceOverride
+				continue
+			}
+			l := &bomLine{
+				materialID:    o.RawMaterialID,
+				extra:         qty,
+				unitOfMeasure: uom,
+				isRequired:    old.isRequired,
+				source:        SourceOverride,
+			}
+			lines = append(lines, l)
+			byMaterial[o.RawMaterialID] = l
+
+		case "add":
+			qty := numericToDecimal(o.Quantity)
+			if l, ok := byMaterial[o.RawMaterialID]; ok {
+				l.extra = l.extra.Add(qty)
+				l.source = SourceOverride
+				continue
+			}
+			l := &bomLine{
+				materialID:    o.RawMaterialID,
+				extra:         qty,
+				unitOfMeasure: derefOr(o.UnitOfMeasure, "unit"),
+				isRequired:    true,
+				source:        SourceOverride,
+			}
+			lines = append(lines, l)
+			byMaterial[o.RawMaterialID] = l
+
+		case "remove":
+			if l, ok := byMaterial[o.RawMaterialID]; ok {
+				lines = removeLine(lines, l)
+				delete(byMaterial, o.RawMaterialID)
+			}
+
+		case "set_quantity":
+			if !o.Quantity.Valid {
+				continue
+			}
+			qty := numericToDecimal(o.Quantity)
+			if l, ok := byMaterial[o.RawMaterialID]; ok {
+				l.base = qty
+				l.extra = decimal.Zero
+				l.source = SourceOverride
+				continue
+			}
+			l := &bomLine{
+				materialID:    o.RawMaterialID,
+				base:          qty,
+				unitOfMeasure: derefOr(o.UnitOfMeasure, "unit"),
+				isRequired:    true,
+				source:        SourceOverride,
+			}
+			lines = append(lines, l)
+			byMaterial[o.RawMaterialID] = l
+		}
+	}
+
+	return lines
+}
+
+// computeProducibility joins resolved lines with raw material stock and cost.
+// Producibility is MIN(floor(stock / quantity)) across required materials with
+// a positive quantity; the material reaching that minimum is the bottleneck.
+func computeProducibility(lines []*bomLine, materials map[uuid.UUID]db.RawMaterial) ([]ResolvedMaterial, decimal.Decimal, int64, *uuid.UUID) {
+	result := make([]ResolvedMaterial, 0, len(lines))
+	unitCost := decimal.Zero
+	var producible int64
+	var bottleneck *uuid.UUID
+
+	for _, l := range lines {
+		m := materials[l.materialID]
+		qty := l.quantity()
+		rm := ResolvedMaterial{
+			RawMaterialID: l.materialID,
+			Name:          m.Name,
+			SKU:           m.Sku,
+			UnitOfMeasure: l.unitOfMeasure,
+			Quantity:      qty,
+			IsRequired:    l.isRequired,
+			Source:        l.source,
+			StockQuantity: numericToDecimal(m.StockQuantity),
+			CostPerUnit:   numericToDecimal(m.CostPerUnit),
+		}
+		unitCost = unitCost.Add(qty.Mul(rm.CostPerUnit))
+
+		if qty.IsPositive() && rm.StockQuantity.IsPositive() {
+			rm.ProducibleUnits = rm.StockQuantity.Div(qty).Floor().IntPart()
+		}
+
+		if rm.IsRequired && qty.IsPositive() {
+			if bottleneck == nil || rm.ProducibleUnits < producible {
+				id := rm.RawMaterialID
+				bottleneck = &id
+				producible = rm.ProducibleUnits
+			}
+		}
+		result = append(result, rm)
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+
+	return result, unitCost, producible, bottleneck
+}
+
+// ---------------------------------------------------------------------------
+// Helpers
+// ---------------------------------------------------------------------------
+
+func removeLine(lines []*bomLine, target *bomLine) []*bomLine {
+	for i, l := range lines {
+		if l == target {
+			return append(lines[:i], lines[i+1:]...)
+		}
+	}
+	return lines
+}
+
+func derefOr(s *string, fallback string) string {
+	if s == nil || *s == "" {
+		return fallback
+	}
+	return *s
+}
+
+// numericToDecimal converts a pgtype.Numeric to a shopspring Decimal.
+// Returns decimal.Zero if the Numeric is not valid.
+func numericToDecimal(n pgtype.Numeric) decimal.Decimal {
+	if !n.Valid || n.Int == nil {
+		return decimal.Zero
+	}
+	return decimal.NewFromBigInt(n.Int, n.Exp)
+}
@@ -2,6 +2,7 @@ package bom_test
 
 import (
 	"context"
+	"errors"
 	"log"
 	"math/big"
 	"os"
@@ -10,6 +11,7 @@ import (
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 
+	db "github.com/forgecommerce/api/internal/database/gen"
 	"github.com/forgecommerce/api/internal/services/bom"
 	"github.com/forgecommerce/api/internal/testutil"
 )
@@ -561,6 +563,209 @@ func TestDeleteVariantOverride(t *testing.T) {
 	}
 }
 
+// --------------------------------------------------------------------------
+// BOM resolution & producibility
+// --------------------------------------------------------------------------
+
+func setMaterialStock(t *testing.T, id uuid.UUID, stock int64) {
+	t.Helper()
+	if _, err := testDB.Pool.Exec(context.Background(),
+		`UPDATE raw_materials SET stock_quantity = $2 WHERE id = $1`, id, stock); err != nil {
+		t.Fatalf("setting stock for material %s: %v", id, err)
+	}
+}
+
+func findResolved(t *testing.T, r bom.ResolvedBOM, materialID uuid.UUID) bom.ResolvedMaterial {
+	t.Helper()
+	for _, m := range r.Materials {
+		if m.RawMaterialID == materialID {
+			return m
+		}
+	}
+	t.Fatalf("material %s not in resolved BOM", materialID)
+	return bom.ResolvedMaterial{}
+}
+
+func TestResolveVariant_AllLayers(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+	q := db.New(testDB.Pool)
+
+	product := testDB.FixtureProduct(t, "Messenger Bag", "messenger-bag")
+	variant := testDB.FixtureVariant(t, product.ID, "BAG-LG", 0)
+	large := testDB.FixtureAttributeOption(t, product.ID, "size", "large")
+	if err := q.SetVariantOption(ctx, db.SetVariantOptionParams{
+		VariantID:   variant.ID,
+		AttributeID: large.AttributeID,
+		OptionID:    large.ID,
+	}); err != nil {
+		t.Fatalf("SetVariantOption: %v", err)
+	}
+
+	thread := testDB.FixtureRawMaterial(t, "Thread", "THR-001")
+	buckle := testDB.FixtureRawMaterial(t, "Buckle", "BCK-001")
+	strap := testDB.FixtureRawMaterial(t, "Wide Strap", "STR-001")
+	setMaterialStock(t, buckle.ID, 50)
+	setMaterialStock(t, strap.ID, 8)
+
+	threadEntry, _ := svc.CreateProductEntry(ctx, bom.CreateProductEntryParams{
+		ProductID: product.ID, RawMaterialID: thread.ID, Quantity: numeric(3), UnitOfMeasure: "m", IsRequired: true,
+	})
+	svc.CreateProductEntry(ctx, bom.CreateProductEntryParams{
+		ProductID: product.ID, RawMaterialID: buckle.ID, Quantity: numeric(1), UnitOfMeasure: "unit", IsRequired: true,
+	})
+	svc.CreateOptionEntry(ctx, bom.CreateOptionEntryParams{
+		OptionID: large.ID, RawMaterialID: strap.ID, Quantity: numeric(1), UnitOfMeasure: "unit",
+	})
+	svc.CreateOptionModifier(ctx, bom.CreateOptionModifierParams{
+		OptionID: large.ID, ProductBomEntryID: threadEntry.ID, ModifierType: "multiply", ModifierValue: numericDecimal(13, -1),
+	})
+
+	resolved, err := svc.ResolveVariant(ctx, variant.ID)
+	if err != nil {
+		t.Fatalf("ResolveVariant: %v", err)
+	}
+
+	if len(resolved.Materials) != 3 {
+		t.Fatalf("expected 3 materials, got %d", len(resolved.Materials))
+	}
+	if got := findResolved(t, resolved, thread.ID).Quantity.String(); got != "3.9" {
+		t.Errorf("thread quantity: got %s, want 3.9", got)
+	}
+	if got := findResolved(t, resolved, strap.ID).Source; got != bom.SourceOption {
+		t.Errorf("strap source: got %q, want %q", got, bom.SourceOption)
+	}
+
+	// thread: floor(100/3.9)=25, buckle: 50, strap: 8 → 8, limited by strap.
+	if resolved.Producible != 8 {
+		t.Errorf("producible: got %d, want 8", resolved.Producible)
+	}
+	if resolved.BottleneckMaterialID == nil || *resolved.BottleneckMaterialID != strap.ID {
+		t.Errorf("expected strap as bottleneck, got %v", resolved.BottleneckMaterialID)
+	}
+	// Unit cost: (3.9 + 1 + 1) * 5.00 = 29.50.
+	if got := resolved.UnitCost.StringFixed(2); got != "29.50" {
+		t.Errorf("unit cost: got %s, want 29.50", got)
+	}
+}
+
+func TestResolveVariant_Overrides(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Override Bag", "override-bag")
+	variant := testDB.FixtureVariant(t, product.ID, "BAG-BRN-LG", 0)
+
+	thread := testDB.FixtureRawMaterial(t, "Thread", "THR-001")
+	buckle := testDB.FixtureRawMaterial(t, "Brass Buckle", "BCK-001")
+	antique := testDB.FixtureRawMaterial(t, "Antique Buckle", "BCK-ANT")
+	clasp := testDB.FixtureRawMaterial(t, "Clasp", "CLS-001")
+	setMaterialStock(t, antique.ID, 10)
+
+	for _, e := range []bom.CreateProductEntryParams{
+		{ProductID: product.ID, RawMaterialID: thread.ID, Quantity: numeric(3), UnitOfMeasure: "m", IsRequired: true},
+		{ProductID: product.ID, RawMaterialID: buckle.ID, Quantity: numeric(2), UnitOfMeasure: "unit", IsRequired: true},
+		{ProductID: product.ID, RawMaterialID: clasp.ID, Quantity: numeric(1), UnitOfMeasure: "unit", IsRequired: true},
+	} {
+		if _, err := svc.CreateProductEntry(ctx, e); err != nil {
+			t.Fatalf("CreateProductEntry: %v", err)
+		}
+	}
+
+	for _, o := range []bom.CreateVariantOverrideParams{
+		{VariantID: variant.ID, RawMaterialID: antique.ID, OverrideType: "replace",
+			ReplacesMaterialID: pgtype.UUID{Bytes: buckle.ID, Valid: true}},
+		{VariantID: variant.ID, RawMaterialID: clasp.ID, OverrideType: "remove"},
+		{VariantID: variant.ID, RawMaterialID: thread.ID, OverrideType: "set_quantity", Quantity: numeric(5)},
+	} {
+		if _, err := svc.CreateVariantOverride(ctx, o); err != nil {
+			t.Fatalf("CreateVariantOverride: %v", err)
+		}
+	}
+
+	resolved, err := svc.ResolveVariant(ctx, variant.ID)
+	if err != nil {
+		t.Fatalf("ResolveVariant: %v", err)
+	}
+
+	if len(resolved.Materials) != 2 {
+		t.Fatalf("expected 2 materials (thread, antique buckle), got %d", len(resolved.Materials))
+	}
+	if got := findResolved(t, resolved, antique.ID).Quantity.String(); got != "2" {
+		t.Errorf("antique buckle should inherit replaced quantity: got %s, want 2", got)
+	}
+	if got := findResolved(t, resolved, thread.ID).Quantity.String(); got != "5" {
+		t.Errorf("thread quantity: got %s, want 5", got)
+	}
+	// thread: 100/5=20, antique: 10/2=5 → 5.
+	if resolved.Producible != 5 {
+		t.Errorf("producible: got %d, want 5", resolved.Producible)
+	}
+}
+
+func TestResolveVariant_NoBOM(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Plain", "plain")
+	variant := testDB.FixtureVariant(t, product.ID, "PLAIN-1", 0)
+
+	resolved, err := svc.ResolveVariant(ctx, variant.ID)
+	if err != nil {
+		t.Fatalf("ResolveVariant: %v", err)
+	}
+	if resolved.HasMaterials() {
+		t.Error("expected no materials")
+	}
+	if resolved.BottleneckMaterialID != nil {
+		t.Error("expected no bottleneck")
+	}
+}
+
+func TestResolveVariant_NotFound(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+
+	_, err := svc.ResolveVariant(context.Background(), uuid.New())
+	if !errors.Is(err, bom.ErrVariantNotFound) {
+		t.Errorf("expected ErrVariantNotFound, got %v", err)
+	}
+}
+
+func TestResolveProduct(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Multi", "multi")
+	testDB.FixtureVariant(t, product.ID, "MULTI-1", 0)
+	testDB.FixtureVariant(t, product.ID, "MULTI-2", 0)
+	material := testDB.FixtureRawMaterial(t, "Leather", "LTH-001")
+	svc.CreateProductEntry(ctx, bom.CreateProductEntryParams{
+		ProductID: product.ID, RawMaterialID: material.ID, Quantity: numeric(4), UnitOfMeasure: "m2", IsRequired: true,
+	})
+
+	results, err := svc.ResolveProduct(ctx, product.ID)
+	if err != nil {
+		t.Fatalf("ResolveProduct: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("expected 2 variants, got %d", len(results))
+	}
+	for _, r := range results {
+		if r.Producible != 25 {
+			t.Errorf("%s producible: got %d, want 25", r.SKU, r.Producible)
+		}
+	}
+}
+
 // --------------------------------------------------------------------------
 // Error paths: FK constraint violations on Create operations
 // --------------------------------------------------------------------------
@@ -0,0 +1,183 @@
+package cart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+)
+
+// CustomerCart returns the customer's most recently updated cart that has
+// not expired or been checked out. It returns ErrNotFound if there is none.
+func (s *Service) CustomerCart(ctx context.Context, customerID uuid.UUID) (db.Cart, error) {
+	cart, err := s.queries.GetLatestCustomerCart(ctx, db.GetLatestCustomerCartParams{
+		CustomerID: pgtype.UUID{Bytes: customerID, Valid: true},
+		Now:        time.Now().UTC(),
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Cart{}, ErrNotFound
+		}
+		return db.Cart{}, fmt.Errorf("getting cart for customer %s: %w", customerID, err)
+	}
+	return cart, nil
+}
+
+// Merge hands the cart a shopper built before signing in over to the
+// customer. If the customer has no other open cart, the cart is simply
+// assigned to them. Otherwise its items are added to the customer's latest
+// cart, with quantities summed but capped at the variant's stock unless the
+// product allows backorders, and inactive variants dropped. Email, country,
+// VAT number and coupon fill in any the customer's cart is missing. The
+// anonymous cart is emptied so it cannot be checked out twice.
+//
+// Merge returns ErrNotFound if the cart does not exist, has expired, has
+// been checked out or belongs to another customer, and
+// ErrCheckoutInProgress if its items would be moved while a checkout
+// session still holds stock for them: that session can still be paid for
+// the anonymous cart. The returned cart expires
+// no sooner than the customer cart lifetime.
+func (s *Service) Merge(ctx context.Context, customerID, cartID uuid.UUID) (db.Cart, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.Cart{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	now := time.Now().UTC()
+	owner := pgtype.UUID{Bytes: customerID, Valid: true}
+
+	anon, err := qtx.GetCart(ctx, cartID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Cart{}, ErrNotFound
+		}
+		return db.Cart{}, fmt.Errorf("getting cart %s: %w", cartID, err)
+	}
+	if anon.OrderID.Valid || !anon.ExpiresAt.After(now) ||
+		(anon.CustomerID.Valid && anon.CustomerID.Bytes != customerID) {
+		return db.Cart{}, ErrNotFound
+	}
+
+	target, err := qtx.GetLatestCustomerCart(ctx, db.GetLatestCustomerCartParams{
+		CustomerID: owner,
+		ExcludeID:  anon.ID,
+		Now:        now,
+	})
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		target = anon
+	case err != nil:
+		return db.Cart{}, fmt.Errorf("getting cart for customer %s: %w", customerID, err)
+	default:
+		sessions, err := qtx.ListCartCheckoutSessions(ctx, pgtype.UUID{Bytes: anon.ID, Valid: true})
+		if err != nil {
+			return db.Cart{}, fmt.Errorf("listing checkout sessions of cart %s: %w", anon.ID, err)
+		}
+		if len(sessions) > 0 {
+			return db.Cart{}, ErrCheckoutInProgress
+		}
+		if err := mergeItems(ctx, qtx, anon.ID, target.ID, now); err != nil {
+			return db.Cart{}, err
+		}
+		if _, err := qtx.UpdateCart(ctx, db.UpdateCartParams{
+			ID:          target.ID,
+			Email:       firstNonNil(target.Email, anon.Email),
+			CountryCode: firstNonNil(target.CountryCode, anon.CountryCode),
+			VatNumber:   firstNonNil(target.VatNumber, anon.VatNumber),
+			CouponCode:  firstNonNil(target.CouponCode, anon.CouponCode),
+			UpdatedAt:   now,
+		}); err != nil {
+			return db.Cart{}, fmt.Errorf("updating cart %s: %w", target.ID, err)
+		}
+		if err := qtx.ClearCart(ctx, anon.ID); err != nil {
+			return db.Cart{}, fmt.Errorf("clearing cart %s: %w", anon.ID, err)
+		}
+	}
+
+	merged, err := qtx.SetCartCustomer(ctx, db.SetCartCustomerParams{
+		CustomerID: owner,
+		ExpiresAt:  now.Add(customerCartTTL),
+		UpdatedAt:  now,
+		ID:         target.ID,
+	})
+	if err != nil {
+		return db.Cart{}, fmt.Errorf("setting customer on cart %s: %w", target.ID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.Cart{}, fmt.Errorf("committing cart merge: %w", err)
+	}
+
+	s.logger.Info("cart merged into customer cart",
+		slog.String("customer_id", customerID.String()),
+		slog.String("from_cart_id", anon.ID.String()),
+		slog.String("cart_id", merged.ID.String()),
+	)
+
+	return merged, nil
+}
+
+// mergeItems adds the items of cart from to cart to.
+func mergeItems(ctx context.Context, q *db.Queries, from, to uuid.UUID, now time.Time) error {
+	fromItems, err := q.GetCartItems(ctx, from)
+	if err != nil {
+		return fmt.Errorf("listing items of cart %s: %w", from, err)
+	}
+	toItems, err := q.GetCartItems(ctx, to)
+	if err != nil {
+		return fmt.Errorf("listing items of cart %s: %w", to, err)
+	}
+
+	existing := make(map[uuid.UUID]int32, len(toItems))
+	for _, item := range toItems {
+		existing[item.VariantID] = item.Quantity
+	}
+
+	for _, item := range fromItems {
+		if !item.VariantIsActive {
+			continue
+		}
+		have := existing[item.VariantID]
+		quantity := mergedQuantity(have, item.Quantity, item.VariantStock, item.ProductAllowBackorder)
+		if quantity <= have {
+			continue
+		}
+		if _, err := q.SetCartItemQuantity(ctx, db.SetCartItemQuantityParams{
+			ID:        uuid.New(),
+			CartID:    to,
+			VariantID: item.VariantID,
+			Quantity:  quantity,
+			CreatedAt: now,
+		}); err != nil {
+			return fmt.Errorf("merging variant %s into cart %s: %w", item.VariantID, to, err)
+		}
+	}
+	return nil
+}
+
+// mergedQuantity sums the quantities of a variant in two carts. Without
+// backorders the sum is capped at the stock, but a quantity the customer
+// already had is never reduced.
+func mergedQuantity(have, add, stock int32, allowBackorder bool) int32 {
+	sum := have + add
+	if allowBackorder || sum <= stock {
+		return sum
+	}
+	return max(have, stock)
+}
+
+func firstNonNil(a, b *string) *string {
+	if a != nil {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,164 @@
+package cart_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/forgecommerce/api/internal/services/cart"
+	"github.com/forgecommerce/api/internal/services/inventory"
+)
+
+func TestMerge_IntoCustomerCart(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+
+	customer := testDB.FixtureCustomer(t, "merge@example.com")
+	p := testDB.FixtureProduct(t, "Merge Product", "merge-product")
+	shared := testDB.FixtureVariant(t, p.ID, "MERGE-SHARED", 5)
+	extra := testDB.FixtureVariant(t, p.ID, "MERGE-EXTRA", 10)
+
+	saved, _ := svc.Create(ctx)
+	if _, err := svc.SetCustomer(ctx, saved.ID, customer.ID); err != nil {
+		t.Fatalf("SetCustomer: %v", err)
+	}
+	svc.AddItem(ctx, saved.ID, shared.ID, 3)
+
+	email := "merge@example.com"
+	anon, _ := svc.Create(ctx)
+	svc.Update(ctx, anon.ID, cart.UpdateParams{Email: &email})
+	svc.AddItem(ctx, anon.ID, shared.ID, 4)
+	svc.AddItem(ctx, anon.ID, extra.ID, 2)
+
+	merged, err := svc.Merge(ctx, customer.ID, anon.ID)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if merged.ID != saved.ID {
+		t.Fatalf("merged cart: got %s, want customer cart %s", merged.ID, saved.ID)
+	}
+	if merged.Email == nil || *merged.Email != email {
+		t.Errorf("email: got %v, want %q", merged.Email, email)
+	}
+	if time.Until(merged.ExpiresAt) < 29*24*time.Hour {
+		t.Errorf("expected expiry extended to 30 days, got %v", merged.ExpiresAt)
+	}
+
+	items, err := svc.ListItems(ctx, merged.ID)
+	if err != nil {
+		t.Fatalf("ListItems: %v", err)
+	}
+	quantities := make(map[string]int32)
+	for _, item := range items {
+		quantities[item.VariantSku] = item.Quantity
+	}
+	// 3 + 4 is capped at the 5 in stock.
+	if quantities["MERGE-SHARED"] != 5 {
+		t.Errorf("shared quantity: got %d, want 5", quantities["MERGE-SHARED"])
+	}
+	if quantities["MERGE-EXTRA"] != 2 {
+		t.Errorf("extra quantity: got %d, want 2", quantities["MERGE-EXTRA"])
+	}
+
+	anonItems, _ := svc.ListItems(ctx, anon.ID)
+	if len(anonItems) != 0 {
+		t.Errorf("expected anonymous cart to be emptied, has %d items", len(anonItems))
+	}
+}
+
+func TestMerge_NoCustomerCart(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+
+	customer := testDB.FixtureCustomer(t, "first-cart@example.com")
+	anon, _ := svc.Create(ctx)
+
+	merged, err := svc.Merge(ctx, customer.ID, anon.ID)
+	if err != nil {
+		t.Fatalf("Merge: %v", err)
+	}
+	if merged.ID != anon.ID {
+		t.Errorf("cart: got %s, want %s", merged.ID, anon.ID)
+	}
+	if !merged.CustomerID.Valid || merged.CustomerID.Bytes != customer.ID {
+		t.Errorf("customer ID: got %v, want %s", merged.CustomerID, customer.ID)
+	}
+
+	latest, err := svc.CustomerCart(ctx, customer.ID)
+	if err != nil {
+		t.Fatalf("CustomerCart: %v", err)
+	}
+	if latest.ID != anon.ID {
+		t.Errorf("customer cart: got %s, want %s", latest.ID, anon.ID)
+	}
+}
+
+func TestMerge_OpenCheckoutSession(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	inv := inventory.NewService(testDB.Pool, nil, nil)
+	ctx := context.Background()
+
+	customer := testDB.FixtureCustomer(t, "checkout-open@example.com")
+	p := testDB.FixtureProduct(t, "Open Checkout Product", "open-checkout-product")
+	v := testDB.FixtureVariant(t, p.ID, "OPEN-CHECKOUT", 5)
+
+	saved, _ := svc.Create(ctx)
+	svc.SetCustomer(ctx, saved.ID, customer.ID)
+
+	anon, _ := svc.Create(ctx)
+	svc.AddItem(ctx, anon.ID, v.ID, 2)
+	lines := []inventory.ReservationLine{{VariantID: v.ID, Quantity: 2}}
+	if err := inv.ReserveCart(ctx, anon.ID, lines, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("ReserveCart: %v", err)
+	}
+	if err := inv.AttachCheckoutSession(ctx, anon.ID, "cs_test_open"); err != nil {
+		t.Fatalf("AttachCheckoutSession: %v", err)
+	}
+
+	if _, err := svc.Merge(ctx, customer.ID, anon.ID); !errors.Is(err, cart.ErrCheckoutInProgress) {
+		t.Fatalf("expected ErrCheckoutInProgress, got %v", err)
+	}
+
+	anonItems, _ := svc.ListItems(ctx, anon.ID)
+	if len(anonItems) != 1 {
+		t.Errorf("expected anonymous cart to keep its item, has %d items", len(anonItems))
+	}
+	savedItems, _ := svc.ListItems(ctx, saved.ID)
+	if len(savedItems) != 0 {
+		t.Errorf("expected customer cart to stay empty, has %d items", len(savedItems))
+	}
+}
+
+func TestMerge_OtherCustomersCart(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+
+	owner := testDB.FixtureCustomer(t, "owner@example.com")
+	other := testDB.FixtureCustomer(t, "other@example.com")
+	c, _ := svc.Create(ctx)
+	svc.SetCustomer(ctx, c.ID, owner.ID)
+
+	if _, err := svc.Merge(ctx, other.ID, c.ID); !errors.Is(err, cart.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestCustomerCart_NotFound(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+
+	customer := testDB.FixtureCustomer(t, "no-cart@example.com")
+	if _, err := svc.CustomerCart(context.Background(), customer.ID); !errors.Is(err, cart.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
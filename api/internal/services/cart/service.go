@@ -18,6 +18,9 @@ import (
 var (
 	// ErrNotFound is returned when a cart does not exist.
 	ErrNotFound = errors.New("cart not found")
+	// ErrCheckoutInProgress is returned by Merge when the cart has a Stripe
+	// Checkout Session that may still be paid.
+	ErrCheckoutInProgress = errors.New("cart has a checkout in progress")
 	// ErrItemNotFound is returned when a cart item does not exist.
 	ErrItemNotFound = errors.New("cart item not found")
 	// ErrInvalidQuantity is returned when quantity is less than 1.
@@ -45,6 +48,10 @@ func NewService(pool *pgxpool.Pool, logger *slog.Logger) *Service {
 	}
 }
 
+// customerCartTTL is how long a cart assigned to a customer is kept, counted
+// from the customer's last login or merge.
+const customerCartTTL = 30 * 24 * time.Hour
+
 // defaultExpiry returns the default cart expiry time (7 days from now).
 func defaultExpiry() time.Time {
 	return time.Now().UTC().Add(7 * 24 * time.Hour)
@@ -178,29 +185,54 @@ func (s *Service) DeleteExpired(ctx context.Context) error {
 	return nil
 }
 
-// SetCustomer associates a customer with a cart (e.g., after login).
+// SetCustomer associates a customer with a cart (e.g., after login) and
+// extends its expiry to the customer cart lifetime.
 func (s *Service) SetCustomer(ctx context.Context, cartID uuid.UUID, customerID uuid.UUID) (db.Cart, error) {
-	// Get the cart first so we can preserve existing fields.
-	cart, err := s.queries.GetCart(ctx, cartID)
+	now := time.Now().UTC()
+	cart, err := s.queries.SetCartCustomer(ctx, db.SetCartCustomerParams{
+		CustomerID: pgtype.UUID{Bytes: customerID, Valid: true},
+		ExpiresAt:  now.Add(customerCartTTL),
+		UpdatedAt:  now,
+		ID:         cartID,
+	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return db.Cart{}, ErrNotFound
 		}
-		return db.Cart{}, fmt.Errorf("getting cart for customer association: %w", err)
+		return db.Cart{}, fmt.Errorf("setting customer on cart: %w", err)
 	}
+	return cart, nil
+}
 
-	// We need a raw query since UpdateCart doesn't include customer_id.
-	_, err = s.pool.Exec(ctx,
-		`UPDATE carts SET customer_id = $1, updated_at = $2 WHERE id = $3`,
-		pgtype.UUID{Bytes: customerID, Valid: true},
-		time.Now().UTC(),
-		cartID,
-	)
+// MarkOrdered records the order a cart was checked out as. The cart is then
+// no longer returned by CustomerCart or merged into by Merge, and a recovery
+// reminder sent for it counts the order as recovered.
+func (s *Service) MarkOrdered(ctx context.Context, cartID, orderID uuid.UUID) error {
+	tx, err := s.pool.Begin(ctx)
 	if err != nil {
-		return db.Cart{}, fmt.Errorf("setting customer on cart: %w", err)
+		return fmt.Errorf("beginning transaction: %w", err)
 	}
+	defer tx.Rollback(ctx)
 
-	// Re-fetch to return updated cart.
-	cart.CustomerID = pgtype.UUID{Bytes: customerID, Valid: true}
-	return cart, nil
+	qtx := s.queries.WithTx(tx)
+	order := pgtype.UUID{Bytes: orderID, Valid: true}
+
+	if err := qtx.SetCartOrder(ctx, db.SetCartOrderParams{
+		ID:        cartID,
+		OrderID:   order,
+		UpdatedAt: time.Now().UTC(),
+	}); err != nil {
+		return fmt.Errorf("marking cart %s as ordered: %w", cartID, err)
+	}
+	if err := qtx.SetCartRecoveryOrder(ctx, db.SetCartRecoveryOrderParams{
+		CartID:  pgtype.UUID{Bytes: cartID, Valid: true},
+		OrderID: order,
+	}); err != nil {
+		return fmt.Errorf("linking cart %s recovery to order: %w", cartID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing cart order: %w", err)
+	}
+	return nil
 }
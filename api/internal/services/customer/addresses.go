@@ -0,0 +1,331 @@
+package customer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+)
+
+var (
+	// ErrAddressNotFound is returned when an address does not exist or
+	// belongs to another customer.
+	ErrAddressNotFound = errors.New("address not found")
+
+	// ErrInvalidAddress is returned when a required address field is missing.
+	ErrInvalidAddress = errors.New("invalid address")
+
+	// ErrUnknownCountry is returned when an address country is not an EU
+	// country known to the store.
+	ErrUnknownCountry = errors.New("unknown country")
+
+	// ErrCountryNotShippable is returned when a shipping address is in a
+	// country the store does not ship to.
+	ErrCountryNotShippable = errors.New("shipping to this country is not enabled")
+)
+
+// AddressParams contains the fields of an address book entry.
+type AddressParams struct {
+	Label             *string
+	FirstName         string
+	LastName          string
+	Company           *string
+	AddressLine1      string
+	AddressLine2      *string
+	City              string
+	StateProvince     *string
+	PostalCode        string
+	CountryCode       string
+	Phone             *string
+	IsDefaultBilling  bool
+	IsDefaultShipping bool
+}
+
+// ListAddresses returns a customer's saved addresses, defaults first.
+func (s *Service) ListAddresses(ctx context.Context, customerID uuid.UUID) ([]db.CustomerAddress, error) {
+	addresses, err := s.queries.ListCustomerAddresses(ctx, customerID)
+	if err != nil {
+		return nil, fmt.Errorf("listing addresses for customer %s: %w", customerID, err)
+	}
+	return addresses, nil
+}
+
+// GetAddress returns one of a customer's saved addresses. It returns
+// ErrAddressNotFound if the address belongs to someone else.
+func (s *Service) GetAddress(ctx context.Context, customerID, id uuid.UUID) (db.CustomerAddress, error) {
+	address, err := s.queries.GetCustomerAddress(ctx, db.GetCustomerAddressParams{
+		ID:         id,
+		CustomerID: customerID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.CustomerAddress{}, ErrAddressNotFound
+		}
+		return db.CustomerAddress{}, fmt.Errorf("getting address %s: %w", id, err)
+	}
+	return address, nil
+}
+
+// CreateAddress saves a new address for a customer. Marking it as the
+// default billing or shipping address clears that flag on the others.
+func (s *Service) CreateAddress(ctx context.Context, customerID uuid.UUID, params AddressParams) (db.CustomerAddress, error) {
+	params, err := s.validateAddress(ctx, params)
+	if err != nil {
+		return db.CustomerAddress{}, err
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.CustomerAddress{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	now := time.Now().UTC()
+
+	address, err := qtx.CreateCustomerAddress(ctx, db.CreateCustomerAddressParams{
+		ID:                uuid.New(),
+		CustomerID:        customerID,
+		Label:             params.Label,
+		FirstName:         params.FirstName,
+		LastName:          params.LastName,
+		Company:           params.Company,
+		AddressLine1:      params.AddressLine1,
+		AddressLine2:      params.AddressLine2,
+		City:              params.City,
+		StateProvince:     params.StateProvince,
+		PostalCode:        params.PostalCode,
+		CountryCode:       params.CountryCode,
+		Phone:             params.Phone,
+		IsDefaultBilling:  params.IsDefaultBilling,
+		IsDefaultShipping: params.IsDefaultShipping,
+		CreatedAt:         now,
+	})
+	if err != nil {
+		return db.CustomerAddress{}, fmt.Errorf("creating address: %w", err)
+	}
+
+	if err := clearOtherDefaults(ctx, qtx, address, now); err != nil {
+		return db.CustomerAddress{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.CustomerAddress{}, fmt.Errorf("committing address: %w", err)
+	}
+
+	s.logger.Info("customer address created",
+		slog.String("customer_id", customerID.String()),
+		slog.String("address_id", address.ID.String()),
+	)
+
+	return address, nil
+}
+
+// UpdateAddress replaces the fields of one of a customer's saved addresses.
+func (s *Service) UpdateAddress(ctx context.Context, customerID, id uuid.UUID, params AddressParams) (db.CustomerAddress, error) {
+	params, err := s.validateAddress(ctx, params)
+	if err != nil {
+		return db.CustomerAddress{}, err
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.CustomerAddress{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	now := time.Now().UTC()
+
+	address, err := qtx.UpdateCustomerAddress(ctx, db.UpdateCustomerAddressParams{
+		ID:                id,
+		CustomerID:        customerID,
+		Label:             params.Label,
+		FirstName:         params.FirstName,
+		LastName:          params.LastName,
+		Company:           params.Company,
+		AddressLine1:      params.AddressLine1,
+		AddressLine2:      params.AddressLine2,
+		City:              params.City,
+		StateProvince:     params.StateProvince,
+		PostalCode:        params.PostalCode,
+		CountryCode:       params.CountryCode,
+		Phone:             params.Phone,
+		IsDefaultBilling:  params.IsDefaultBilling,
+		IsDefaultShipping: params.IsDefaultShipping,
+		UpdatedAt:         now,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.CustomerAddress{}, ErrAddressNotFound
+		}
+		return db.CustomerAddress{}, fmt.Errorf("updating address %s: %w", id, err)
+	}
+
+	if err := clearOtherDefaults(ctx, qtx, address, now); err != nil {
+		return db.CustomerAddress{}, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.CustomerAddress{}, fmt.Errorf("committing address: %w", err)
+	}
+
+	s.logger.Info("customer address updated",
+		slog.String("customer_id", customerID.String()),
+		slog.String("address_id", address.ID.String()),
+	)
+
+	return address, nil
+}
+
+// DeleteAddress removes one of a customer's saved addresses.
+func (s *Service) DeleteAddress(ctx context.Context, customerID, id uuid.UUID) error {
+	n, err := s.queries.DeleteCustomerAddress(ctx, db.DeleteCustomerAddressParams{
+		ID:         id,
+		CustomerID: customerID,
+	})
+	if err != nil {
+		return fmt.Errorf("deleting address %s: %w", id, err)
+	}
+	if n == 0 {
+		return ErrAddressNotFound
+	}
+
+	s.logger.Info("customer address deleted",
+		slog.String("customer_id", customerID.String()),
+		slog.String("address_id", id.String()),
+	)
+
+	return nil
+}
+
+// ValidateShippingCountry returns ErrCountryNotShippable unless the store
+// ships to the given country.
+func (s *Service) ValidateShippingCountry(ctx context.Context, countryCode string) error {
+	countries, err := s.queries.ListEnabledShippingCountries(ctx)
+	if err != nil {
+		return fmt.Errorf("listing enabled shipping countries: %w", err)
+	}
+	for _, c := range countries {
+		if c.CountryCode == countryCode {
+			return nil
+		}
+	}
+	return ErrCountryNotShippable
+}
+
+// validateAddress trims and checks the address fields. The country must be
+// a known EU country, and a default shipping address must be in a country
+// the store ships to.
+func (s *Service) validateAddress(ctx context.Context, p AddressParams) (AddressParams, error) {
+	p.FirstName = strings.TrimSpace(p.FirstName)
+	p.LastName = strings.TrimSpace(p.LastName)
+	p.AddressLine1 = strings.TrimSpace(p.AddressLine1)
+	p.City = strings.TrimSpace(p.City)
+	p.PostalCode = strings.TrimSpace(p.PostalCode)
+	p.CountryCode = strings.ToUpper(strings.TrimSpace(p.CountryCode))
+
+	required := []struct{ name, value string }{
+		{"first_name", p.FirstName},
+		{"last_name", p.LastName},
+		{"address_line1", p.AddressLine1},
+		{"city", p.City},
+		{"postal_code", p.PostalCode},
+		{"country_code", p.CountryCode},
+	}
+	for _, f := range required {
+		if f.value == "" {
+			return p, fmt.Errorf("%w: %s is required", ErrInvalidAddress, f.name)
+		}
+	}
+
+	if _, err := s.queries.GetEUCountry(ctx, p.CountryCode); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return p, ErrUnknownCountry
+		}
+		return p, fmt.Errorf("looking up country %q: %w", p.CountryCode, err)
+	}
+
+	if p.IsDefaultShipping {
+		if err := s.ValidateShippingCountry(ctx, p.CountryCode); err != nil {
+			return p, err
+		}
+	}
+
+	return p, nil
+}
+
+// clearOtherDefaults keeps at most one default billing and one default
+// shipping address per customer.
+func clearOtherDefaults(ctx context.Context, q *db.Queries, address db.CustomerAddress, now time.Time) error {
+	if address.IsDefaultBilling {
+		if err := q.ClearDefaultBillingAddress(ctx, db.ClearDefaultBillingAddressParams{
+			CustomerID: address.CustomerID,
+			ID:         address.ID,
+			UpdatedAt:  now,
+		}); err != nil {
+			return fmt.Errorf("clearing default billing address: %w", err)
+		}
+	}
+	if address.IsDefaultShipping {
+		if err := q.ClearDefaultShippingAddress(ctx, db.ClearDefaultShippingAddressParams{
+			CustomerID: address.CustomerID,
+			ID:         address.ID,
+			UpdatedAt:  now,
+		}); err != nil {
+			return fmt.Errorf("clearing default shipping address: %w", err)
+		}
+	}
+	return nil
+}
+
+// orderAddress is the address format stored on orders and passed to Stripe
+// metadata, matching the raw addresses accepted at checkout.
+type orderAddress struct {
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	Company    string `json:"company,omitempty"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2,omitempty"`
+	City       string `json:"city"`
+	State      string `json:"state,omitempty"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+	Phone      string `json:"phone,omitempty"`
+}
+
+// OrderAddressJSON returns a saved address in the order address format, so
+// an order keeps a snapshot that later address book edits do not change.
+func OrderAddressJSON(a db.CustomerAddress) (json.RawMessage, error) {
+	b, err := json.Marshal(orderAddress{
+		FirstName:  a.FirstName,
+		LastName:   a.LastName,
+		Company:    deref(a.Company),
+		Line1:      a.AddressLine1,
+		Line2:      deref(a.AddressLine2),
+		City:       a.City,
+		State:      deref(a.StateProvince),
+		PostalCode: a.PostalCode,
+		Country:    a.CountryCode,
+		Phone:      deref(a.Phone),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("encoding address %s: %w", a.ID, err)
+	}
+	return b, nil
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,233 @@
+package customer_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/customer"
+)
+
+func createAddressCustomer(t *testing.T, svc *customer.Service, email string) db.Customer {
+	t.Helper()
+	cust, err := svc.Create(context.Background(), customer.CreateCustomerParams{Email: email})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return cust
+}
+
+func addressParams(country string) customer.AddressParams {
+	return customer.AddressParams{
+		Label:        strPtr("Home"),
+		FirstName:    "Maria",
+		LastName:     "Garcia",
+		AddressLine1: "Calle Mayor 1",
+		City:         "Madrid",
+		PostalCode:   "28001",
+		CountryCode:  country,
+	}
+}
+
+func TestCreateAddress(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+	cust := createAddressCustomer(t, svc, "maria@example.com")
+
+	p := addressParams(" es ")
+	p.FirstName = "  Maria "
+	addr, err := svc.CreateAddress(ctx, cust.ID, p)
+	if err != nil {
+		t.Fatalf("CreateAddress: %v", err)
+	}
+	if addr.CountryCode != "ES" {
+		t.Errorf("country_code: got %q, want ES", addr.CountryCode)
+	}
+	if addr.FirstName != "Maria" {
+		t.Errorf("first_name: got %q, want Maria", addr.FirstName)
+	}
+
+	got, err := svc.GetAddress(ctx, cust.ID, addr.ID)
+	if err != nil {
+		t.Fatalf("GetAddress: %v", err)
+	}
+	if got.City != "Madrid" {
+		t.Errorf("city: got %q, want Madrid", got.City)
+	}
+}
+
+func TestCreateAddress_Invalid(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+	cust := createAddressCustomer(t, svc, "maria@example.com")
+
+	p := addressParams("ES")
+	p.City = " "
+	if _, err := svc.CreateAddress(ctx, cust.ID, p); !errors.Is(err, customer.ErrInvalidAddress) {
+		t.Errorf("missing city: expected ErrInvalidAddress, got %v", err)
+	}
+
+	if _, err := svc.CreateAddress(ctx, cust.ID, addressParams("US")); !errors.Is(err, customer.ErrUnknownCountry) {
+		t.Errorf("non-EU country: expected ErrUnknownCountry, got %v", err)
+	}
+
+	// Spain is seeded but not enabled for shipping.
+	p = addressParams("ES")
+	p.IsDefaultShipping = true
+	if _, err := svc.CreateAddress(ctx, cust.ID, p); !errors.Is(err, customer.ErrCountryNotShippable) {
+		t.Errorf("default shipping outside shipping countries: expected ErrCountryNotShippable, got %v", err)
+	}
+}
+
+func TestCreateAddress_SingleDefault(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	testDB.FixtureShippingCountry(t, "ES")
+	svc := newService()
+	ctx := context.Background()
+	cust := createAddressCustomer(t, svc, "maria@example.com")
+
+	p := addressParams("ES")
+	p.IsDefaultBilling = true
+	p.IsDefaultShipping = true
+	first, err := svc.CreateAddress(ctx, cust.ID, p)
+	if err != nil {
+		t.Fatalf("CreateAddress first: %v", err)
+	}
+
+	p = addressParams("ES")
+	p.Label = strPtr("Office")
+	p.IsDefaultShipping = true
+	second, err := svc.CreateAddress(ctx, cust.ID, p)
+	if err != nil {
+		t.Fatalf("CreateAddress second: %v", err)
+	}
+
+	first, _ = svc.GetAddress(ctx, cust.ID, first.ID)
+	if first.IsDefaultShipping {
+		t.Error("first address should no longer be the default shipping address")
+	}
+	if !first.IsDefaultBilling {
+		t.Error("first address should still be the default billing address")
+	}
+
+	list, err := svc.ListAddresses(ctx, cust.ID)
+	if err != nil {
+		t.Fatalf("ListAddresses: %v", err)
+	}
+	if len(list) != 2 || list[0].ID != second.ID {
+		t.Errorf("ListAddresses: want the default shipping address first, got %d addresses", len(list))
+	}
+}
+
+func TestUpdateAddress(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+	cust := createAddressCustomer(t, svc, "maria@example.com")
+
+	addr, err := svc.CreateAddress(ctx, cust.ID, addressParams("ES"))
+	if err != nil {
+		t.Fatalf("CreateAddress: %v", err)
+	}
+
+	p := addressParams("DE")
+	p.City = "Berlin"
+	p.PostalCode = "10115"
+	updated, err := svc.UpdateAddress(ctx, cust.ID, addr.ID, p)
+	if err != nil {
+		t.Fatalf("UpdateAddress: %v", err)
+	}
+	if updated.City != "Berlin" || updated.CountryCode != "DE" {
+		t.Errorf("got %s, %s; want Berlin, DE", updated.City, updated.CountryCode)
+	}
+}
+
+func TestAddress_OtherCustomer(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+	owner := createAddressCustomer(t, svc, "owner@example.com")
+	other := createAddressCustomer(t, svc, "other@example.com")
+
+	addr, err := svc.CreateAddress(ctx, owner.ID, addressParams("ES"))
+	if err != nil {
+		t.Fatalf("CreateAddress: %v", err)
+	}
+
+	if _, err := svc.GetAddress(ctx, other.ID, addr.ID); !errors.Is(err, customer.ErrAddressNotFound) {
+		t.Errorf("GetAddress: expected ErrAddressNotFound, got %v", err)
+	}
+	if _, err := svc.UpdateAddress(ctx, other.ID, addr.ID, addressParams("ES")); !errors.Is(err, customer.ErrAddressNotFound) {
+		t.Errorf("UpdateAddress: expected ErrAddressNotFound, got %v", err)
+	}
+	if err := svc.DeleteAddress(ctx, other.ID, addr.ID); !errors.Is(err, customer.ErrAddressNotFound) {
+		t.Errorf("DeleteAddress: expected ErrAddressNotFound, got %v", err)
+	}
+
+	list, _ := svc.ListAddresses(ctx, other.ID)
+	if len(list) != 0 {
+		t.Errorf("ListAddresses for other customer: got %d, want 0", len(list))
+	}
+}
+
+func TestDeleteAddress(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+	cust := createAddressCustomer(t, svc, "maria@example.com")
+
+	addr, err := svc.CreateAddress(ctx, cust.ID, addressParams("ES"))
+	if err != nil {
+		t.Fatalf("CreateAddress: %v", err)
+	}
+	if err := svc.DeleteAddress(ctx, cust.ID, addr.ID); err != nil {
+		t.Fatalf("DeleteAddress: %v", err)
+	}
+	if err := svc.DeleteAddress(ctx, cust.ID, uuid.New()); !errors.Is(err, customer.ErrAddressNotFound) {
+		t.Errorf("second delete: expected ErrAddressNotFound, got %v", err)
+	}
+}
+
+func TestOrderAddressJSON(t *testing.T) {
+	raw, err := customer.OrderAddressJSON(db.CustomerAddress{
+		FirstName:    "Maria",
+		LastName:     "Garcia",
+		Company:      strPtr("Forge SL"),
+		AddressLine1: "Calle Mayor 1",
+		City:         "Madrid",
+		PostalCode:   "28001",
+		CountryCode:  "ES",
+	})
+	if err != nil {
+		t.Fatalf("OrderAddressJSON: %v", err)
+	}
+
+	var got map[string]string
+	if err := json.Unmarshal(raw, &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	want := map[string]string{
+		"first_name": "Maria", "last_name": "Garcia", "company": "Forge SL",
+		"line1": "Calle Mayor 1", "city": "Madrid", "postal_code": "28001", "country": "ES",
+	}
+	if len(got) != len(want) {
+		t.Errorf("got keys %v, want %v", got, want)
+	}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s: got %q, want %q", k, got[k], v)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package customer
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+)
+
+// Token purposes.
+const (
+	TokenPasswordReset     = "password_reset"
+	TokenEmailVerification = "email_verification"
+)
+
+const (
+	// PasswordResetTTL is how long a password reset token is valid.
+	PasswordResetTTL = time.Hour
+
+	// EmailVerificationTTL is how long an email verification token is valid.
+	EmailVerificationTTL = 48 * time.Hour
+
+	tokenBytes = 32
+)
+
+// ErrInvalidToken is returned when a token is unknown, already used or expired.
+var ErrInvalidToken = errors.New("token is invalid or has expired")
+
+// IssuePasswordReset creates a password reset token for the customer and
+// revokes any earlier ones. The returned token is only ever held by the
+// caller; the database stores its hash.
+func (s *Service) IssuePasswordReset(ctx context.Context, customer db.Customer) (string, error) {
+	return s.issueToken(ctx, customer, TokenPasswordReset, PasswordResetTTL)
+}
+
+// IssueEmailVerification creates an email verification token for the
+// customer's current email address and revokes any earlier ones.
+func (s *Service) IssueEmailVerification(ctx context.Context, customer db.Customer) (string, error) {
+	return s.issueToken(ctx, customer, TokenEmailVerification, EmailVerificationTTL)
+}
+
+func (s *Service) issueToken(ctx context.Context, customer db.Customer, purpose string, ttl time.Duration) (string, error) {
+	token, err := generateToken()
+	if err != nil {
+		return "", err
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return "", fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	if err := qtx.RevokeCustomerTokens(ctx, db.RevokeCustomerTokensParams{
+		CustomerID: customer.ID,
+		Purpose:    purpose,
+	}); err != nil {
+		return "", fmt.Errorf("revoking earlier %s tokens: %w", purpose, err)
+	}
+
+	if _, err := qtx.CreateCustomerToken(ctx, db.CreateCustomerTokenParams{
+		CustomerID: customer.ID,
+		Purpose:    purpose,
+		TokenHash:  hashToken(token),
+		Email:      customer.Email,
+		ExpiresAt:  time.Now().UTC().Add(ttl),
+	}); err != nil {
+		return "", fmt.Errorf("creating %s token: %w", purpose, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return "", fmt.Errorf("commit transaction: %w", err)
+	}
+	return token, nil
+}
+
+// ResetPassword consumes a password reset token and sets the customer's
+// password hash. Completing a reset proves the customer controls the address
+// the token was sent to, so that address is marked verified as well.
+func (s *Service) ResetPassword(ctx context.Context, token, passwordHash string) (db.Customer, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.Customer{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	tok, err := consumeToken(ctx, qtx, token, TokenPasswordReset)
+	if err != nil {
+		return db.Customer{}, err
+	}
+
+	if err := qtx.UpdateCustomerPassword(ctx, db.UpdateCustomerPasswordParams{
+		ID:           tok.CustomerID,
+		PasswordHash: &passwordHash,
+	}); err != nil {
+		return db.Customer{}, fmt.Errorf("updating password: %w", err)
+	}
+
+	customer, err := qtx.MarkCustomerEmailVerified(ctx, db.MarkCustomerEmailVerifiedParams{
+		ID:    tok.CustomerID,
+		Email: tok.Email,
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		// The email changed after the token was issued.
+		customer, err = qtx.GetCustomer(ctx, tok.CustomerID)
+	}
+	if err != nil {
+		return db.Customer{}, fmt.Errorf("loading customer %s: %w", tok.CustomerID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.Customer{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	s.logger.Info("customer password reset", slog.String("customer_id", customer.ID.String()))
+	return customer, nil
+}
+
+// VerifyEmail consumes an email verification token and marks the address it
+// was issued for as verified. A token issued before the customer changed
+// their email is rejected with ErrInvalidToken.
+func (s *Service) VerifyEmail(ctx context.Context, token string) (db.Customer, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.Customer{}, fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	tok, err := consumeToken(ctx, qtx, token, TokenEmailVerification)
+	if err != nil {
+		return db.Customer{}, err
+	}
+
+	customer, err := qtx.MarkCustomerEmailVerified(ctx, db.MarkCustomerEmailVerifiedParams{
+		ID:    tok.CustomerID,
+		Email: tok.Email,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Customer{}, ErrInvalidToken
+		}
+		return db.Customer{}, fmt.Errorf("marking email verified: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.Customer{}, fmt.Errorf("commit transaction: %w", err)
+	}
+
+	s.logger.Info("customer email verified", slog.String("customer_id", customer.ID.String()))
+	return customer, nil
+}
+
+func consumeToken(ctx context.Context, q *db.Queries, token, purpose string) (db.CustomerToken, error) {
+	if token == "" {
+		return db.CustomerToken{}, ErrInvalidToken
+	}
+	tok, err := q.ConsumeCustomerToken(ctx, db.ConsumeCustomerTokenParams{
+		TokenHash: hashToken(token),
+		Purpose:   purpose,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.CustomerToken{}, ErrInvalidToken
+		}
+		return db.CustomerToken{}, fmt.Errorf("consuming %s token: %w", purpose, err)
+	}
+	return tok, nil
+}
+
+// generateToken returns a random URL-safe token.
+func generateToken() (string, error) {
+	b := make([]byte, tokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("reading random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the hex SHA-256 of a token. Tokens carry 256 bits of
+// randomness, so a fast unsalted hash is enough to make a leaked table
+// useless.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,181 @@
+package customer_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/customer"
+)
+
+func createTokenCustomer(t *testing.T, svc *customer.Service) db.Customer {
+	t.Helper()
+	cust, err := svc.Create(context.Background(), customer.CreateCustomerParams{
+		Email:        "token@example.com",
+		PasswordHash: strPtr("old-hash"),
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	return cust
+}
+
+func TestResetPassword(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+	cust := createTokenCustomer(t, svc)
+
+	token, err := svc.IssuePasswordReset(ctx, cust)
+	if err != nil {
+		t.Fatalf("IssuePasswordReset: %v", err)
+	}
+
+	var stored string
+	if err := testDB.Pool.QueryRow(ctx, "SELECT token_hash FROM customer_tokens WHERE customer_id = $1", cust.ID).Scan(&stored); err != nil {
+		t.Fatalf("reading token: %v", err)
+	}
+	if stored == token {
+		t.Error("token stored in plaintext")
+	}
+
+	updated, err := svc.ResetPassword(ctx, token, "new-hash")
+	if err != nil {
+		t.Fatalf("ResetPassword: %v", err)
+	}
+	if updated.PasswordHash == nil || *updated.PasswordHash != "new-hash" {
+		t.Errorf("password hash: got %v, want new-hash", updated.PasswordHash)
+	}
+	if !updated.EmailVerifiedAt.Valid {
+		t.Error("expected reset to verify the email address")
+	}
+
+	// Tokens are single use.
+	if _, err := svc.ResetPassword(ctx, token, "other-hash"); !errors.Is(err, customer.ErrInvalidToken) {
+		t.Errorf("second use: got %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestResetPassword_InvalidToken(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	for _, token := range []string{"", "not-a-token"} {
+		if _, err := svc.ResetPassword(ctx, token, "new-hash"); !errors.Is(err, customer.ErrInvalidToken) {
+			t.Errorf("token %q: got %v, want ErrInvalidToken", token, err)
+		}
+	}
+}
+
+func TestResetPassword_Expired(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+	cust := createTokenCustomer(t, svc)
+
+	token, err := svc.IssuePasswordReset(ctx, cust)
+	if err != nil {
+		t.Fatalf("IssuePasswordReset: %v", err)
+	}
+	if _, err := testDB.Pool.Exec(ctx, "UPDATE customer_tokens SET expires_at = NOW() - INTERVAL '1 minute'"); err != nil {
+		t.Fatalf("expiring token: %v", err)
+	}
+
+	if _, err := svc.ResetPassword(ctx, token, "new-hash"); !errors.Is(err, customer.ErrInvalidToken) {
+		t.Errorf("got %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestIssuePasswordReset_RevokesEarlierTokens(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+	cust := createTokenCustomer(t, svc)
+
+	first, err := svc.IssuePasswordReset(ctx, cust)
+	if err != nil {
+		t.Fatalf("IssuePasswordReset: %v", err)
+	}
+	second, err := svc.IssuePasswordReset(ctx, cust)
+	if err != nil {
+		t.Fatalf("IssuePasswordReset: %v", err)
+	}
+
+	if _, err := svc.ResetPassword(ctx, first, "new-hash"); !errors.Is(err, customer.ErrInvalidToken) {
+		t.Errorf("first token: got %v, want ErrInvalidToken", err)
+	}
+	if _, err := svc.ResetPassword(ctx, second, "new-hash"); err != nil {
+		t.Errorf("second token: %v", err)
+	}
+}
+
+func TestResetPassword_WrongPurpose(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+	cust := createTokenCustomer(t, svc)
+
+	token, err := svc.IssueEmailVerification(ctx, cust)
+	if err != nil {
+		t.Fatalf("IssueEmailVerification: %v", err)
+	}
+	if _, err := svc.ResetPassword(ctx, token, "new-hash"); !errors.Is(err, customer.ErrInvalidToken) {
+		t.Errorf("got %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestVerifyEmail(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+	cust := createTokenCustomer(t, svc)
+
+	if cust.EmailVerifiedAt.Valid {
+		t.Fatal("new customer should not be verified")
+	}
+
+	token, err := svc.IssueEmailVerification(ctx, cust)
+	if err != nil {
+		t.Fatalf("IssueEmailVerification: %v", err)
+	}
+
+	verified, err := svc.VerifyEmail(ctx, token)
+	if err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+	if !verified.EmailVerifiedAt.Valid {
+		t.Error("expected email_verified_at to be set")
+	}
+
+	if _, err := svc.VerifyEmail(ctx, token); !errors.Is(err, customer.ErrInvalidToken) {
+		t.Errorf("second use: got %v, want ErrInvalidToken", err)
+	}
+}
+
+func TestVerifyEmail_EmailChanged(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+	cust := createTokenCustomer(t, svc)
+
+	token, err := svc.IssueEmailVerification(ctx, cust)
+	if err != nil {
+		t.Fatalf("IssueEmailVerification: %v", err)
+	}
+	if _, err := testDB.Pool.Exec(ctx, "UPDATE customers SET email = 'changed@example.com' WHERE id = $1", cust.ID); err != nil {
+		t.Fatalf("changing email: %v", err)
+	}
+
+	if _, err := svc.VerifyEmail(ctx, token); !errors.Is(err, customer.ErrInvalidToken) {
+		t.Errorf("got %v, want ErrInvalidToken", err)
+	}
+	got, err := svc.Get(ctx, cust.ID)
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	if got.EmailVerifiedAt.Valid {
+		t.Error("changed address must not be marked verified")
+	}
+}
@@ -0,0 +1,417 @@
+package discount
+
+import (
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+)
+
+const (
+	// DefaultCampaignAlphabet leaves out characters that are easily confused
+	// when codes are typed from print or video: 0/O, 1/I and L.
+	DefaultCampaignAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+
+	// DefaultCampaignCodeLength is the number of random characters in a
+	// generated code when no length is given.
+	DefaultCampaignCodeLength = 8
+
+	// MaxCampaignCodes is the largest number of codes one campaign can
+	// generate.
+	MaxCampaignCodes = 100000
+
+	minCampaignCodeLength = 4
+	maxCampaignCodeLength = 32
+	maxCampaignPrefix     = 16
+
+	// campaignBatchSize is the number of codes inserted per statement.
+	campaignBatchSize = 1000
+
+	// campaignCodeSpaceFactor is how many possible codes there must be per
+	// requested code, which keeps collisions with existing codes rare.
+	campaignCodeSpaceFactor = 10
+
+	// maxEmptyBatches is the number of consecutive batches in which every
+	// code collides before generation gives up.
+	maxEmptyBatches = 5
+)
+
+// GenerateCampaignParams contains the settings for a coupon campaign.
+type GenerateCampaignParams struct {
+	Name       string
+	DiscountID uuid.UUID
+	Prefix     string
+	CodeLength int    // random characters after the prefix; 0 uses DefaultCampaignCodeLength
+	Alphabet   string // "" uses DefaultCampaignAlphabet
+	Count      int
+
+	// UsageLimitPerCode is the number of times each code can be used; 0
+	// means single-use.
+	UsageLimitPerCode int32
+}
+
+// CampaignReport summarises the redemptions of a campaign's codes.
+type CampaignReport struct {
+	Campaign db.GetCouponCampaignRow
+	Summary  db.GetCampaignRedemptionSummaryRow
+	Daily    []db.ListCampaignRedemptionsByDayRow
+}
+
+// GenerateCampaign creates a campaign and generates its codes for the given
+// discount. Codes are the uppercased prefix followed by CodeLength random
+// characters from the alphabet, drawn with crypto/rand. They are inserted in
+// batches within one transaction; codes that collide with existing coupons
+// are replaced, so the campaign always has exactly Count codes.
+func (s *Service) GenerateCampaign(ctx context.Context, p GenerateCampaignParams) (db.CouponCampaign, error) {
+	p, err := normalizeCampaignParams(p)
+	if err != nil {
+		return db.CouponCampaign{}, err
+	}
+
+	if _, err := s.queries.GetDiscount(ctx, p.DiscountID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.CouponCampaign{}, ErrNotFound
+		}
+		return db.CouponCampaign{}, fmt.Errorf("verifying discount for campaign: %w", err)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.CouponCampaign{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	now := time.Now().UTC()
+
+	campaign, err := qtx.CreateCouponCampaign(ctx, db.CreateCouponCampaignParams{
+		ID:                uuid.New(),
+		Name:              p.Name,
+		DiscountID:        p.DiscountID,
+		Prefix:            p.Prefix,
+		CodeLength:        int32(p.CodeLength),
+		Alphabet:          p.Alphabet,
+		CodeCount:         int32(p.Count),
+		UsageLimitPerCode: p.UsageLimitPerCode,
+		CreatedAt:         now,
+	})
+	if err != nil {
+		return db.CouponCampaign{}, fmt.Errorf("creating coupon campaign: %w", err)
+	}
+
+	// seen holds every code generated so far, so a batch never repeats a
+	// code from itself or an earlier batch.
+	seen := make(map[string]struct{}, p.Count)
+	remaining := p.Count
+	emptyBatches := 0
+	for remaining > 0 {
+		codes := make([]string, 0, min(remaining, campaignBatchSize))
+		for len(codes) < cap(codes) {
+			code, err := randomCode(p.Prefix, p.Alphabet, p.CodeLength)
+			if err != nil {
+				return db.CouponCampaign{}, err
+			}
+			if _, ok := seen[code]; ok {
+				continue
+			}
+			seen[code] = struct{}{}
+			codes = append(codes, code)
+		}
+
+		inserted, err := qtx.InsertCampaignCoupons(ctx, db.InsertCampaignCouponsParams{
+			DiscountID: p.DiscountID,
+			CampaignID: campaign.ID,
+			UsageLimit: p.UsageLimitPerCode,
+			CreatedAt:  now,
+			Codes:      codes,
+		})
+		if err != nil {
+			return db.CouponCampaign{}, fmt.Errorf("inserting campaign coupons: %w", err)
+		}
+
+		if inserted == 0 {
+			emptyBatches++
+			if emptyBatches >= maxEmptyBatches {
+				return db.CouponCampaign{}, fmt.Errorf("%w: too many generated codes already exist", ErrInvalidCampaign)
+			}
+		} else {
+			emptyBatches = 0
+		}
+		remaining -= int(inserted)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.CouponCampaign{}, fmt.Errorf("committing coupon campaign: %w", err)
+	}
+
+	s.logger.Info("coupon campaign generated",
+		slog.String("id", campaign.ID.String()),
+		slog.String("name", campaign.Name),
+		slog.String("discount_id", campaign.DiscountID.String()),
+		slog.Int("codes", p.Count),
+	)
+	return campaign, nil
+}
+
+// normalizeCampaignParams applies defaults, uppercases the prefix and
+// alphabet, and checks that the settings can produce Count unique codes.
+func normalizeCampaignParams(p GenerateCampaignParams) (GenerateCampaignParams, error) {
+	p.Name = strings.TrimSpace(p.Name)
+	p.Prefix = strings.ToUpper(strings.TrimSpace(p.Prefix))
+	p.Alphabet = strings.ToUpper(strings.TrimSpace(p.Alphabet))
+	if p.Alphabet == "" {
+		p.Alphabet = DefaultCampaignAlphabet
+	}
+	if p.CodeLength == 0 {
+		p.CodeLength = DefaultCampaignCodeLength
+	}
+	if p.UsageLimitPerCode == 0 {
+		p.UsageLimitPerCode = 1
+	}
+
+	if p.Name == "" {
+		return p, fmt.Errorf("%w: name is required", ErrInvalidCampaign)
+	}
+	if p.Count < 1 || p.Count > MaxCampaignCodes {
+		return p, fmt.Errorf("%w: code count must be between 1 and %d", ErrInvalidCampaign, MaxCampaignCodes)
+	}
+	if p.CodeLength < minCampaignCodeLength || p.CodeLength > maxCampaignCodeLength {
+		return p, fmt.Errorf("%w: code length must be between %d and %d", ErrInvalidCampaign, minCampaignCodeLength, maxCampaignCodeLength)
+	}
+	if p.UsageLimitPerCode < 0 {
+		return p, fmt.Errorf("%w: usage limit per code must be positive", ErrInvalidCampaign)
+	}
+	if len(p.Prefix) > maxCampaignPrefix {
+		return p, fmt.Errorf("%w: prefix must be at most %d characters", ErrInvalidCampaign, maxCampaignPrefix)
+	}
+	for _, r := range p.Prefix {
+		if !isCodeChar(r) && r != '-' && r != '_' {
+			return p, fmt.Errorf("%w: prefix may only contain letters, digits, '-' and '_'", ErrInvalidCampaign)
+		}
+	}
+
+	seen := make(map[rune]bool, len(p.Alphabet))
+	for _, r := range p.Alphabet {
+		if !isCodeChar(r) {
+			return p, fmt.Errorf("%w: alphabet may only contain letters and digits", ErrInvalidCampaign)
+		}
+		if seen[r] {
+			return p, fmt.Errorf("%w: alphabet repeats %q", ErrInvalidCampaign, r)
+		}
+		seen[r] = true
+	}
+	if len(p.Alphabet) < 2 {
+		return p, fmt.Errorf("%w: alphabet needs at least two characters", ErrInvalidCampaign)
+	}
+
+	space := new(big.Int).Exp(big.NewInt(int64(len(p.Alphabet))), big.NewInt(int64(p.CodeLength)), nil)
+	needed := big.NewInt(int64(p.Count) * campaignCodeSpaceFactor)
+	if space.Cmp(needed) < 0 {
+		return p, fmt.Errorf("%w: %d characters of length %d are too few for %d codes; use a longer code or alphabet",
+			ErrInvalidCampaign, len(p.Alphabet), p.CodeLength, p.Count)
+	}
+	return p, nil
+}
+
+func isCodeChar(r rune) bool {
+	return (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9')
+}
+
+// randomCode returns prefix followed by length characters drawn uniformly
+// from alphabet, which must be ASCII. Random bytes that would bias the
+// result towards the start of the alphabet are discarded.
+func randomCode(prefix, alphabet string, length int) (string, error) {
+	n := len(alphabet)
+	limit := 256 - 256%n
+
+	var b strings.Builder
+	b.Grow(len(prefix) + length)
+	b.WriteString(prefix)
+
+	buf := make([]byte, length*2)
+	for written := 0; written < length; {
+		if _, err := rand.Read(buf); err != nil {
+			return "", fmt.Errorf("generating coupon code: %w", err)
+		}
+		for _, c := range buf {
+			if int(c) >= limit {
+				continue
+			}
+			b.WriteByte(alphabet[int(c)%n])
+			written++
+			if written == length {
+				break
+			}
+		}
+	}
+	return b.String(), nil
+}
+
+// GenerateCoupon creates a single-use coupon for the given discount with a
+// random code after prefix, valid until endsAt. It is meant for one-off
+// offers such as abandoned cart reminders; a code that collides with an
+// existing coupon is drawn again.
+func (s *Service) GenerateCoupon(ctx context.Context, discountID uuid.UUID, prefix string, endsAt time.Time) (db.Coupon, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.Coupon{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	c, err := s.GenerateCouponTx(ctx, tx, discountID, prefix, endsAt)
+	if err != nil {
+		return db.Coupon{}, err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return db.Coupon{}, fmt.Errorf("committing coupon: %w", err)
+	}
+	return c, nil
+}
+
+// GenerateCouponTx is GenerateCoupon inside the caller's transaction, so the
+// coupon only exists if the caller commits. Each attempt runs in a savepoint,
+// so a colliding code does not abort tx.
+func (s *Service) GenerateCouponTx(ctx context.Context, tx pgx.Tx, discountID uuid.UUID, prefix string, endsAt time.Time) (db.Coupon, error) {
+	qtx := s.queries.WithTx(tx)
+	if _, err := qtx.GetDiscount(ctx, discountID); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Coupon{}, ErrNotFound
+		}
+		return db.Coupon{}, fmt.Errorf("verifying discount for coupon: %w", err)
+	}
+
+	prefix = strings.ToUpper(strings.TrimSpace(prefix))
+	one := int32(1)
+	for attempt := 0; attempt < maxEmptyBatches; attempt++ {
+		code, err := randomCode(prefix, DefaultCampaignAlphabet, DefaultCampaignCodeLength)
+		if err != nil {
+			return db.Coupon{}, err
+		}
+
+		c, err := s.createCouponSavepoint(ctx, tx, db.CreateCouponParams{
+			ID:                    uuid.New(),
+			Code:                  code,
+			DiscountID:            discountID,
+			UsageLimit:            &one,
+			UsageLimitPerCustomer: &one,
+			EndsAt:                pgtype.Timestamptz{Time: endsAt, Valid: true},
+			IsActive:              true,
+			CreatedAt:             time.Now().UTC(),
+		})
+		if err != nil {
+			if isDuplicateKeyError(err) {
+				continue
+			}
+			return db.Coupon{}, fmt.Errorf("creating coupon: %w", err)
+		}
+
+		s.logger.Info("coupon generated",
+			slog.String("id", c.ID.String()),
+			slog.String("code", c.Code),
+			slog.String("discount_id", c.DiscountID.String()),
+		)
+		return c, nil
+	}
+	return db.Coupon{}, fmt.Errorf("generating coupon: %d codes in a row already exist", maxEmptyBatches)
+}
+
+// createCouponSavepoint inserts a coupon inside a savepoint of tx, rolling
+// back to it if the insert fails.
+func (s *Service) createCouponSavepoint(ctx context.Context, tx pgx.Tx, arg db.CreateCouponParams) (db.Coupon, error) {
+	sp, err := tx.Begin(ctx)
+	if err != nil {
+		return db.Coupon{}, fmt.Errorf("beginning savepoint: %w", err)
+	}
+	defer sp.Rollback(ctx)
+
+	c, err := s.queries.WithTx(sp).CreateCoupon(ctx, arg)
+	if err != nil {
+		return db.Coupon{}, err
+	}
+	if err := sp.Commit(ctx); err != nil {
+		return db.Coupon{}, fmt.Errorf("releasing savepoint: %w", err)
+	}
+	return c, nil
+}
+
+// isDuplicateKeyError reports whether err is a PostgreSQL unique constraint
+// violation (23505).
+func isDuplicateKeyError(err error) bool {
+	var pgErr interface{ SQLState() string }
+	return errors.As(err, &pgErr) && pgErr.SQLState() == "23505"
+}
+
+// GetCampaign retrieves a coupon campaign with its discount name.
+func (s *Service) GetCampaign(ctx context.Context, id uuid.UUID) (db.GetCouponCampaignRow, error) {
+	c, err := s.queries.GetCouponCampaign(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.GetCouponCampaignRow{}, ErrCampaignNotFound
+		}
+		return db.GetCouponCampaignRow{}, fmt.Errorf("getting coupon campaign: %w", err)
+	}
+	return c, nil
+}
+
+// ListCampaigns retrieves a paginated list of coupon campaigns with their
+// discount name and redemption count.
+func (s *Service) ListCampaigns(ctx context.Context, limit, offset int32) ([]db.ListCouponCampaignsRow, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	campaigns, err := s.queries.ListCouponCampaigns(ctx, db.ListCouponCampaignsParams{
+		Limit:  limit,
+		Offset: offset,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing coupon campaigns: %w", err)
+	}
+	return campaigns, nil
+}
+
+// ListCampaignCodes returns every code of a campaign, ordered by code, for
+// export.
+func (s *Service) ListCampaignCodes(ctx context.Context, id uuid.UUID) ([]db.ListCampaignCouponsRow, error) {
+	codes, err := s.queries.ListCampaignCoupons(ctx, pgtype.UUID{Bytes: id, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("listing campaign coupons: %w", err)
+	}
+	return codes, nil
+}
+
+// GetCampaignReport builds a campaign's redemption report from coupon_usage.
+func (s *Service) GetCampaignReport(ctx context.Context, id uuid.UUID) (CampaignReport, error) {
+	campaign, err := s.GetCampaign(ctx, id)
+	if err != nil {
+		return CampaignReport{}, err
+	}
+
+	campaignID := pgtype.UUID{Bytes: id, Valid: true}
+	summary, err := s.queries.GetCampaignRedemptionSummary(ctx, campaignID)
+	if err != nil {
+		return CampaignReport{}, fmt.Errorf("summarising campaign redemptions: %w", err)
+	}
+	daily, err := s.queries.ListCampaignRedemptionsByDay(ctx, campaignID)
+	if err != nil {
+		return CampaignReport{}, fmt.Errorf("listing campaign redemptions by day: %w", err)
+	}
+
+	return CampaignReport{
+		Campaign: campaign,
+		Summary:  summary,
+		Daily:    daily,
+	}, nil
+}
@@ -0,0 +1,98 @@
+package discount
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestNormalizeCampaignParams(t *testing.T) {
+	valid := GenerateCampaignParams{Name: "Spring", DiscountID: uuid.New(), Count: 100}
+
+	tests := []struct {
+		name    string
+		modify  func(p *GenerateCampaignParams)
+		wantErr bool
+	}{
+		{name: "defaults", modify: func(p *GenerateCampaignParams) {}},
+		{name: "custom alphabet", modify: func(p *GenerateCampaignParams) { p.Alphabet = "abc123" }},
+		{name: "prefix with dash", modify: func(p *GenerateCampaignParams) { p.Prefix = "ig-anna_" }},
+		{name: "missing name", modify: func(p *GenerateCampaignParams) { p.Name = " " }, wantErr: true},
+		{name: "zero count", modify: func(p *GenerateCampaignParams) { p.Count = 0 }, wantErr: true},
+		{name: "too many codes", modify: func(p *GenerateCampaignParams) { p.Count = MaxCampaignCodes + 1 }, wantErr: true},
+		{name: "too short", modify: func(p *GenerateCampaignParams) { p.CodeLength = 3 }, wantErr: true},
+		{name: "too long", modify: func(p *GenerateCampaignParams) { p.CodeLength = 33 }, wantErr: true},
+		{name: "one character alphabet", modify: func(p *GenerateCampaignParams) { p.Alphabet = "A" }, wantErr: true},
+		{name: "repeated character", modify: func(p *GenerateCampaignParams) { p.Alphabet = "ABCa" }, wantErr: true},
+		{name: "symbol in alphabet", modify: func(p *GenerateCampaignParams) { p.Alphabet = "AB#" }, wantErr: true},
+		{name: "space in prefix", modify: func(p *GenerateCampaignParams) { p.Prefix = "A B" }, wantErr: true},
+		{name: "prefix too long", modify: func(p *GenerateCampaignParams) { p.Prefix = strings.Repeat("A", 17) }, wantErr: true},
+		{name: "negative usage limit", modify: func(p *GenerateCampaignParams) { p.UsageLimitPerCode = -1 }, wantErr: true},
+		// 2^4 = 16 possible codes cannot hold 100 unique ones.
+		{name: "code space too small", modify: func(p *GenerateCampaignParams) { p.Alphabet = "AB"; p.CodeLength = 4 }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := valid
+			tt.modify(&p)
+			_, err := normalizeCampaignParams(p)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCampaign) {
+					t.Errorf("expected ErrInvalidCampaign, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestNormalizeCampaignParams_Defaults(t *testing.T) {
+	p, err := normalizeCampaignParams(GenerateCampaignParams{Name: "Spring", Prefix: " spring- ", Count: 10})
+	if err != nil {
+		t.Fatalf("normalizeCampaignParams: %v", err)
+	}
+	if p.Prefix != "SPRING-" {
+		t.Errorf("prefix: got %q, want SPRING-", p.Prefix)
+	}
+	if p.Alphabet != DefaultCampaignAlphabet {
+		t.Errorf("alphabet: got %q, want %q", p.Alphabet, DefaultCampaignAlphabet)
+	}
+	if p.CodeLength != DefaultCampaignCodeLength {
+		t.Errorf("code length: got %d, want %d", p.CodeLength, DefaultCampaignCodeLength)
+	}
+	if p.UsageLimitPerCode != 1 {
+		t.Errorf("usage limit per code: got %d, want 1", p.UsageLimitPerCode)
+	}
+}
+
+func TestRandomCode(t *testing.T) {
+	const alphabet = "ABC"
+	counts := map[rune]int{}
+	for i := 0; i < 200; i++ {
+		code, err := randomCode("PRE-", alphabet, 12)
+		if err != nil {
+			t.Fatalf("randomCode: %v", err)
+		}
+		if !strings.HasPrefix(code, "PRE-") || len(code) != len("PRE-")+12 {
+			t.Fatalf("unexpected code %q", code)
+		}
+		for _, r := range code[len("PRE-"):] {
+			if !strings.ContainsRune(alphabet, r) {
+				t.Fatalf("code %q has %q outside the alphabet", code, r)
+			}
+			counts[r]++
+		}
+	}
+	// 2400 draws: each character is expected 800 times.
+	for _, r := range alphabet {
+		if counts[r] < 600 || counts[r] > 1000 {
+			t.Errorf("character %q drawn %d times, want about 800", r, counts[r])
+		}
+	}
+}
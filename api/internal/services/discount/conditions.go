@@ -0,0 +1,270 @@
+package discount
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Conditions restricts which orders a discount applies to and, for line
+// conditions, which cart lines it is computed from. It is stored in the
+// discounts.conditions column. The zero value has no restrictions.
+//
+// Order conditions (customers, countries, first order) decide whether the
+// discount applies at all. Line conditions (products, categories, variants
+// and their exclusions) select the qualifying lines: a subtotal-scope
+// discount is computed from those lines only, while shipping and total scope
+// discounts just require at least one of them.
+type Conditions struct {
+	ProductIDs          []uuid.UUID `json:"product_ids,omitempty"`
+	ExcludedProductIDs  []uuid.UUID `json:"excluded_product_ids,omitempty"`
+	CategoryIDs         []uuid.UUID `json:"category_ids,omitempty"`
+	ExcludedCategoryIDs []uuid.UUID `json:"excluded_category_ids,omitempty"`
+	VariantIDs          []uuid.UUID `json:"variant_ids,omitempty"`
+	ExcludedVariantIDs  []uuid.UUID `json:"excluded_variant_ids,omitempty"`
+	CustomerIDs         []uuid.UUID `json:"customer_ids,omitempty"`
+	CountryCodes        []string    `json:"country_codes,omitempty"`
+	FirstOrderOnly      bool        `json:"first_order_only,omitempty"`
+
+	// MinQuantity is the number of qualifying units the cart must contain.
+	MinQuantity int32 `json:"min_quantity,omitempty"`
+
+	// BuyQuantity and GetQuantity turn the discount into a "buy X get Y"
+	// rule: qualifying units are ordered by price, most expensive first, and
+	// in every complete group of BuyQuantity+GetQuantity units the last
+	// GetQuantity are discounted. A 100% discount makes them free.
+	BuyQuantity int32 `json:"buy_quantity,omitempty"`
+	GetQuantity int32 `json:"get_quantity,omitempty"`
+}
+
+// ParseConditions decodes and validates a discount's conditions for the given
+// scope. An empty document means no conditions.
+func ParseConditions(raw json.RawMessage, scope string) (Conditions, error) {
+	var c Conditions
+	if len(bytes.TrimSpace(raw)) == 0 {
+		return c, nil
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return Conditions{}, fmt.Errorf("%w: %v", ErrInvalidConditions, err)
+	}
+
+	for i, code := range c.CountryCodes {
+		c.CountryCodes[i] = strings.ToUpper(strings.TrimSpace(code))
+	}
+
+	if err := c.validate(scope); err != nil {
+		return Conditions{}, fmt.Errorf("%w: %v", ErrInvalidConditions, err)
+	}
+	return c, nil
+}
+
+// normalizeConditions validates conditions for storage and returns them in
+// canonical form; nil becomes an empty object.
+func normalizeConditions(raw json.RawMessage, scope string) (json.RawMessage, error) {
+	c, err := ParseConditions(raw, scope)
+	if err != nil {
+		return nil, err
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return nil, fmt.Errorf("encoding conditions: %w", err)
+	}
+	return b, nil
+}
+
+func (c Conditions) validate(scope string) error {
+	for _, code := range c.CountryCodes {
+		if len(code) != 2 {
+			return fmt.Errorf("country code %q must have two letters", code)
+		}
+	}
+	if c.MinQuantity < 0 {
+		return errors.New("min_quantity must not be negative")
+	}
+	if c.BuyQuantity < 0 || c.GetQuantity < 0 {
+		return errors.New("buy_quantity and get_quantity must not be negative")
+	}
+	if (c.BuyQuantity > 0) != (c.GetQuantity > 0) {
+		return errors.New("buy_quantity and get_quantity must be set together")
+	}
+	if c.isBuyXGetY() && scope != "subtotal" {
+		return errors.New("buy X get Y discounts must have subtotal scope")
+	}
+	if id, ok := overlap(c.ProductIDs, c.ExcludedProductIDs); ok {
+		return fmt.Errorf("product %s is both included and excluded", id)
+	}
+	if id, ok := overlap(c.CategoryIDs, c.ExcludedCategoryIDs); ok {
+		return fmt.Errorf("category %s is both included and excluded", id)
+	}
+	if id, ok := overlap(c.VariantIDs, c.ExcludedVariantIDs); ok {
+		return fmt.Errorf("variant %s is both included and excluded", id)
+	}
+	return nil
+}
+
+func overlap(a, b []uuid.UUID) (uuid.UUID, bool) {
+	for _, x := range a {
+		for _, y := range b {
+			if x == y {
+				return x, true
+			}
+		}
+	}
+	return uuid.UUID{}, false
+}
+
+// isBuyXGetY reports whether the conditions describe a "buy X get Y" rule.
+func (c Conditions) isBuyXGetY() bool {
+	return c.BuyQuantity > 0 && c.GetQuantity > 0
+}
+
+// targetsLines reports whether only some cart lines qualify.
+func (c Conditions) targetsLines() bool {
+	return len(c.ProductIDs) > 0 || len(c.ExcludedProductIDs) > 0 ||
+		len(c.CategoryIDs) > 0 || len(c.ExcludedCategoryIDs) > 0 ||
+		len(c.VariantIDs) > 0 || len(c.ExcludedVariantIDs) > 0
+}
+
+// needsLines reports whether the discount can only be evaluated against the
+// cart's lines.
+func (c Conditions) needsLines() bool {
+	return c.targetsLines() || c.MinQuantity > 0 || c.isBuyXGetY()
+}
+
+func (c Conditions) usesCategories() bool {
+	return len(c.CategoryIDs) > 0 || len(c.ExcludedCategoryIDs) > 0
+}
+
+// orderQualifies checks the order conditions. firstOrder reports whether the
+// shopper has no earlier orders.
+func (c Conditions) orderQualifies(p ApplyParams, firstOrder bool) bool {
+	if len(c.CustomerIDs) > 0 && !containsUUID(c.CustomerIDs, p.CustomerID) {
+		return false
+	}
+	if len(c.CountryCodes) > 0 {
+		country := strings.ToUpper(p.CountryCode)
+		found := false
+		for _, code := range c.CountryCodes {
+			if code == country {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if c.FirstOrderOnly && !firstOrder {
+		return false
+	}
+	return true
+}
+
+// qualifyingLines returns the lines the discount applies to. categories maps
+// product IDs to their category IDs.
+func (c Conditions) qualifyingLines(lines []Line, categories map[uuid.UUID][]uuid.UUID) []Line {
+	if !c.targetsLines() {
+		return lines
+	}
+
+	var out []Line
+	for _, l := range lines {
+		if containsUUID(c.ExcludedProductIDs, l.ProductID) ||
+			containsUUID(c.ExcludedVariantIDs, l.VariantID) ||
+			containsAnyUUID(c.ExcludedCategoryIDs, categories[l.ProductID]) {
+			continue
+		}
+
+		included := len(c.ProductIDs) == 0 && len(c.VariantIDs) == 0 && len(c.CategoryIDs) == 0
+		if containsUUID(c.ProductIDs, l.ProductID) ||
+			containsUUID(c.VariantIDs, l.VariantID) ||
+			containsAnyUUID(c.CategoryIDs, categories[l.ProductID]) {
+			included = true
+		}
+		if included {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+func containsUUID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, x := range ids {
+		if x == id {
+			return true
+		}
+	}
+	return false
+}
+
+func containsAnyUUID(ids, candidates []uuid.UUID) bool {
+	for _, c := range candidates {
+		if containsUUID(ids, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// lineQuantity returns the total number of units in lines.
+func lineQuantity(lines []Line) int64 {
+	var n int64
+	for _, l := range lines {
+		n += int64(l.Quantity)
+	}
+	return n
+}
+
+// lineTotalCents returns the sum of unit price times quantity in cents.
+func lineTotalCents(lines []Line) *big.Int {
+	total := big.NewInt(0)
+	for _, l := range lines {
+		lineCents := numericToCents(l.UnitPrice)
+		total.Add(total, lineCents.Mul(lineCents, big.NewInt(int64(l.Quantity))))
+	}
+	return total
+}
+
+// rewardCents returns the value in cents of the units a "buy X get Y" rule
+// discounts: units are ordered by price, most expensive first, and the last
+// get units of every complete group of buy+get are rewarded.
+func rewardCents(lines []Line, buy, get int32) *big.Int {
+	sorted := make([]Line, len(lines))
+	copy(sorted, lines)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return numericToCents(sorted[i].UnitPrice).Cmp(numericToCents(sorted[j].UnitPrice)) > 0
+	})
+
+	group := int64(buy) + int64(get)
+	limit := lineQuantity(sorted) / group * group
+
+	// rewarded counts the rewarded positions in [0, n).
+	rewarded := func(n int64) int64 {
+		if n > limit {
+			n = limit
+		}
+		return n/group*int64(get) + max(0, n%group-int64(buy))
+	}
+
+	total := big.NewInt(0)
+	var pos int64
+	for _, l := range sorted {
+		end := pos + int64(l.Quantity)
+		units := rewarded(end) - rewarded(pos)
+		if units > 0 {
+			lineCents := numericToCents(l.UnitPrice)
+			total.Add(total, lineCents.Mul(lineCents, big.NewInt(units)))
+		}
+		pos = end
+	}
+	return total
+}
@@ -0,0 +1,167 @@
+package discount
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestParseConditions(t *testing.T) {
+	productID := uuid.New()
+
+	tests := []struct {
+		name    string
+		raw     string
+		scope   string
+		wantErr bool
+	}{
+		{name: "empty", raw: "", scope: "subtotal"},
+		{name: "empty object", raw: "{}", scope: "subtotal"},
+		{name: "null", raw: "null", scope: "subtotal"},
+		{name: "all keys", raw: `{"product_ids":["` + productID.String() + `"],"country_codes":["es"],"first_order_only":true,"min_quantity":2,"buy_quantity":2,"get_quantity":1}`, scope: "subtotal"},
+		{name: "unknown key", raw: `{"customer_groups":["vip"]}`, scope: "subtotal", wantErr: true},
+		{name: "malformed", raw: `{"product_ids":`, scope: "subtotal", wantErr: true},
+		{name: "bad uuid", raw: `{"product_ids":["nope"]}`, scope: "subtotal", wantErr: true},
+		{name: "bad country", raw: `{"country_codes":["ESP"]}`, scope: "subtotal", wantErr: true},
+		{name: "negative min quantity", raw: `{"min_quantity":-1}`, scope: "subtotal", wantErr: true},
+		{name: "buy without get", raw: `{"buy_quantity":2}`, scope: "subtotal", wantErr: true},
+		{name: "buy x get y on shipping", raw: `{"buy_quantity":2,"get_quantity":1}`, scope: "shipping", wantErr: true},
+		{name: "included and excluded", raw: `{"product_ids":["` + productID.String() + `"],"excluded_product_ids":["` + productID.String() + `"]}`, scope: "subtotal", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseConditions(json.RawMessage(tt.raw), tt.scope)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidConditions) {
+					t.Errorf("expected ErrInvalidConditions, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+			}
+		})
+	}
+}
+
+func TestParseConditions_NormalizesCountryCodes(t *testing.T) {
+	c, err := ParseConditions(json.RawMessage(`{"country_codes":[" es ","De"]}`), "subtotal")
+	if err != nil {
+		t.Fatalf("ParseConditions: %v", err)
+	}
+	if c.CountryCodes[0] != "ES" || c.CountryCodes[1] != "DE" {
+		t.Errorf("country_codes: got %v, want [ES DE]", c.CountryCodes)
+	}
+}
+
+func TestNormalizeConditions_Empty(t *testing.T) {
+	got, err := normalizeConditions(nil, "subtotal")
+	if err != nil {
+		t.Fatalf("normalizeConditions: %v", err)
+	}
+	if string(got) != "{}" {
+		t.Errorf("got %s, want {}", got)
+	}
+}
+
+func TestQualifyingLines(t *testing.T) {
+	shirt, mug, hat := uuid.New(), uuid.New(), uuid.New()
+	clothing := uuid.New()
+	redShirt := uuid.New()
+
+	lines := []Line{
+		{ProductID: shirt, VariantID: redShirt, Quantity: 1},
+		{ProductID: shirt, VariantID: uuid.New(), Quantity: 1},
+		{ProductID: mug, VariantID: uuid.New(), Quantity: 1},
+		{ProductID: hat, VariantID: uuid.New(), Quantity: 1},
+	}
+	categories := map[uuid.UUID][]uuid.UUID{
+		shirt: {clothing},
+		hat:   {clothing},
+	}
+
+	tests := []struct {
+		name string
+		cond Conditions
+		want int
+	}{
+		{name: "no line conditions", cond: Conditions{}, want: 4},
+		{name: "product", cond: Conditions{ProductIDs: []uuid.UUID{mug}}, want: 1},
+		{name: "category", cond: Conditions{CategoryIDs: []uuid.UUID{clothing}}, want: 3},
+		{name: "category minus product", cond: Conditions{CategoryIDs: []uuid.UUID{clothing}, ExcludedProductIDs: []uuid.UUID{hat}}, want: 2},
+		{name: "category minus variant", cond: Conditions{CategoryIDs: []uuid.UUID{clothing}, ExcludedVariantIDs: []uuid.UUID{redShirt}}, want: 2},
+		{name: "variant", cond: Conditions{VariantIDs: []uuid.UUID{redShirt}}, want: 1},
+		{name: "exclusion only", cond: Conditions{ExcludedCategoryIDs: []uuid.UUID{clothing}}, want: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cond.qualifyingLines(lines, categories)
+			if len(got) != tt.want {
+				t.Errorf("got %d lines, want %d", len(got), tt.want)
+			}
+		})
+	}
+}
+
+func TestOrderQualifies(t *testing.T) {
+	customer := uuid.New()
+
+	tests := []struct {
+		name       string
+		cond       Conditions
+		params     ApplyParams
+		firstOrder bool
+		want       bool
+	}{
+		{name: "no conditions", want: true},
+		{name: "customer listed", cond: Conditions{CustomerIDs: []uuid.UUID{customer}}, params: ApplyParams{CustomerID: customer}, want: true},
+		{name: "customer not listed", cond: Conditions{CustomerIDs: []uuid.UUID{customer}}, params: ApplyParams{CustomerID: uuid.New()}, want: false},
+		{name: "guest with customer condition", cond: Conditions{CustomerIDs: []uuid.UUID{customer}}, want: false},
+		{name: "country matches", cond: Conditions{CountryCodes: []string{"ES"}}, params: ApplyParams{CountryCode: "es"}, want: true},
+		{name: "country differs", cond: Conditions{CountryCodes: []string{"ES"}}, params: ApplyParams{CountryCode: "DE"}, want: false},
+		{name: "first order", cond: Conditions{FirstOrderOnly: true}, firstOrder: true, want: true},
+		{name: "not first order", cond: Conditions{FirstOrderOnly: true}, want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cond.orderQualifies(tt.params, tt.firstOrder); got != tt.want {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRewardCents(t *testing.T) {
+	line := func(cents int64, qty int32) Line {
+		return Line{ProductID: uuid.New(), Quantity: qty, UnitPrice: makeNumeric(cents, -2)}
+	}
+
+	tests := []struct {
+		name      string
+		lines     []Line
+		buy, get  int32
+		wantCents int64
+	}{
+		{name: "buy 2 get 1, three units", lines: []Line{line(1000, 3)}, buy: 2, get: 1, wantCents: 1000},
+		{name: "buy 2 get 1, two units", lines: []Line{line(1000, 2)}, buy: 2, get: 1, wantCents: 0},
+		{name: "buy 2 get 1, cheapest of group", lines: []Line{line(1000, 1), line(500, 1), line(800, 1)}, buy: 2, get: 1, wantCents: 500},
+		// Sorted: 10,9,8 | 7,6,5 -> 8 and 5 are rewarded.
+		{name: "buy 2 get 1, two groups", lines: []Line{line(500, 1), line(600, 1), line(700, 1), line(800, 1), line(900, 1), line(1000, 1)}, buy: 2, get: 1, wantCents: 1300},
+		{name: "incomplete group ignored", lines: []Line{line(1000, 7)}, buy: 2, get: 1, wantCents: 2000},
+		{name: "buy 1 get 1 across lines", lines: []Line{line(2000, 1), line(1000, 3)}, buy: 1, get: 1, wantCents: 2000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := rewardCents(tt.lines, tt.buy, tt.get)
+			if got.Int64() != tt.wantCents {
+				t.Errorf("got %d cents, want %d", got.Int64(), tt.wantCents)
+			}
+		})
+	}
+}
@@ -2,6 +2,9 @@ package discount_test
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"log"
 	"math/big"
 	"os"
@@ -510,6 +513,137 @@ func TestIncrementCouponUsage(t *testing.T) {
 	}
 }
 
+func TestIncrementCouponUsage_LimitReached(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	d, _ := svc.CreateDiscount(ctx, discount.CreateDiscountParams{
+		Name: "D", Type: "percentage", Value: num(500, -2), Scope: "subtotal", IsActive: true,
+	})
+	limit := int32(1)
+	c, _ := svc.CreateCoupon(ctx, discount.CreateCouponParams{
+		Code: "ONCE", DiscountID: d.ID, UsageLimit: &limit, IsActive: true,
+	})
+
+	if err := svc.IncrementCouponUsage(ctx, c.ID); err != nil {
+		t.Fatalf("IncrementCouponUsage: %v", err)
+	}
+	if err := svc.IncrementCouponUsage(ctx, c.ID); err != discount.ErrCouponUsageLimitReached {
+		t.Errorf("expected ErrCouponUsageLimitReached, got %v", err)
+	}
+
+	got, _ := svc.GetCoupon(ctx, c.ID)
+	if got.UsageCount != 1 {
+		t.Errorf("usage_count: got %d, want 1", got.UsageCount)
+	}
+}
+
+func TestRedeemCoupon(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	d, _ := svc.CreateDiscount(ctx, discount.CreateDiscountParams{
+		Name: "D", Type: "percentage", Value: num(500, -2), Scope: "subtotal", IsActive: true,
+	})
+	limit := int32(1)
+	c, _ := svc.CreateCoupon(ctx, discount.CreateCouponParams{
+		Code: "REDEEM", DiscountID: d.ID, UsageLimit: &limit, IsActive: true,
+	})
+	orderID := createOrder(t)
+
+	if err := svc.RedeemCoupon(ctx, discount.RedeemCouponParams{
+		CouponID: c.ID,
+		OrderID:  orderID,
+	}); err != nil {
+		t.Fatalf("RedeemCoupon: %v", err)
+	}
+
+	got, _ := svc.GetCoupon(ctx, c.ID)
+	if got.UsageCount != 1 {
+		t.Errorf("usage_count: got %d, want 1", got.UsageCount)
+	}
+	if n := countCouponUsage(t, c.ID); n != 1 {
+		t.Errorf("coupon_usage rows: got %d, want 1", n)
+	}
+
+	// The coupon is used up: a second redemption records nothing.
+	err := svc.RedeemCoupon(ctx, discount.RedeemCouponParams{
+		CouponID: c.ID,
+		OrderID:  createOrder(t),
+	})
+	if err != discount.ErrCouponUsageLimitReached {
+		t.Errorf("expected ErrCouponUsageLimitReached, got %v", err)
+	}
+	if n := countCouponUsage(t, c.ID); n != 1 {
+		t.Errorf("coupon_usage rows after limit: got %d, want 1", n)
+	}
+}
+
+func TestValidateCoupon_PerCustomerLimit(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	d, _ := svc.CreateDiscount(ctx, discount.CreateDiscountParams{
+		Name: "D", Type: "percentage", Value: num(500, -2), Scope: "subtotal", IsActive: true,
+	})
+	perCustomer := int32(1)
+	c, _ := svc.CreateCoupon(ctx, discount.CreateCouponParams{
+		Code: "ONEEACH", DiscountID: d.ID, UsageLimitPerCustomer: &perCustomer, IsActive: true,
+	})
+
+	customer := testDB.FixtureCustomer(t, "member@example.com")
+	if err := svc.RedeemCoupon(ctx, discount.RedeemCouponParams{
+		CouponID:   c.ID,
+		CustomerID: pgtype.UUID{Bytes: customer.ID, Valid: true},
+		Email:      customer.Email,
+		OrderID:    createOrder(t),
+	}); err != nil {
+		t.Fatalf("RedeemCoupon (customer): %v", err)
+	}
+	if err := svc.RedeemCoupon(ctx, discount.RedeemCouponParams{
+		CouponID: c.ID,
+		Email:    " Guest@Example.com ",
+		OrderID:  createOrder(t),
+	}); err != nil {
+		t.Fatalf("RedeemCoupon (guest): %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		customerID uuid.UUID
+		email      string
+		wantErr    error
+	}{
+		{name: "customer who used it", customerID: customer.ID, wantErr: discount.ErrCouponCustomerLimitReached},
+		{name: "other customer", customerID: uuid.New(), email: "guest@example.com"},
+		{name: "guest who used it", email: "GUEST@example.com", wantErr: discount.ErrCouponCustomerLimitReached},
+		{name: "other guest", email: "someone@example.com"},
+		{name: "unknown guest", email: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.ValidateCoupon(ctx, "oneeach", tt.customerID, tt.email)
+			if !errors.Is(err, tt.wantErr) {
+				t.Errorf("got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Apply reports the same error.
+	_, err := svc.Apply(ctx, discount.ApplyParams{
+		Subtotal:    num(10000, -2),
+		ShippingFee: num(0, -2),
+		CouponCode:  "ONEEACH",
+		CustomerID:  customer.ID,
+	})
+	if !errors.Is(err, discount.ErrCouponCustomerLimitReached) {
+		t.Errorf("Apply: expected ErrCouponCustomerLimitReached, got %v", err)
+	}
+}
+
 func TestDeleteCoupon(t *testing.T) {
 	testDB.Truncate(t)
 	svc := newService()
@@ -1124,3 +1258,561 @@ func TestApply_DateFilteredDiscount(t *testing.T) {
 
 	assertCentsEqual(t, "total_discount", result.TotalDiscount, 0)
 }
+
+func TestApply_CouponDiscountNotAutomatic(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	d := createActiveDiscount(t, svc, "Coupon-Only", "percentage", "subtotal", num(1000, -2), 10, true)
+	svc.CreateCoupon(ctx, discount.CreateCouponParams{
+		Code:       "ONLYCODE",
+		DiscountID: d,
+		IsActive:   true,
+	})
+
+	result, err := svc.Apply(ctx, discount.ApplyParams{
+		Subtotal:    num(10000, -2),
+		ShippingFee: num(0, -2),
+	})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	assertCentsEqual(t, "total_discount", result.TotalDiscount, 0)
+	if len(result.Breakdown) != 0 {
+		t.Errorf("breakdown: got %d entries, want 0", len(result.Breakdown))
+	}
+}
+
+func TestApply_CouponMinimumNotMet_NoCouponID(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	d, _ := svc.CreateDiscount(ctx, discount.CreateDiscountParams{
+		Name:          "Big Orders",
+		Type:          "fixed_amount",
+		Value:         num(1000, -2),
+		Scope:         "subtotal",
+		MinimumAmount: num(20000, -2), // 200.00
+		IsActive:      true,
+	})
+	svc.CreateCoupon(ctx, discount.CreateCouponParams{
+		Code:       "BIG10",
+		DiscountID: d.ID,
+		IsActive:   true,
+	})
+
+	result, err := svc.Apply(ctx, discount.ApplyParams{
+		Subtotal:    num(10000, -2),
+		ShippingFee: num(0, -2),
+		CouponCode:  "BIG10",
+	})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	assertCentsEqual(t, "total_discount", result.TotalDiscount, 0)
+	if result.CouponID.Valid {
+		t.Error("expected CouponID to be unset when the coupon's discount did not apply")
+	}
+}
+
+// createOrder inserts a minimal order for coupon redemption tests.
+func createOrder(t *testing.T) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	_, err := testDB.Pool.Exec(context.Background(), `
+		INSERT INTO orders (id, email, billing_address, shipping_address)
+		VALUES ($1, 'buyer@example.com', '{}', '{}')
+	`, id)
+	if err != nil {
+		t.Fatalf("inserting order: %v", err)
+	}
+	return id
+}
+
+func countCouponUsage(t *testing.T, couponID uuid.UUID) int {
+	t.Helper()
+	var n int
+	err := testDB.Pool.QueryRow(context.Background(),
+		`SELECT count(*) FROM coupon_usage WHERE coupon_id = $1`, couponID).Scan(&n)
+	if err != nil {
+		t.Fatalf("counting coupon usage: %v", err)
+	}
+	return n
+}
+
+// --------------------------------------------------------------------------
+// Conditions
+// --------------------------------------------------------------------------
+
+func TestCreateDiscount_InvalidConditions(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+
+	_, err := svc.CreateDiscount(context.Background(), discount.CreateDiscountParams{
+		Name:       "Bad",
+		Type:       "percentage",
+		Value:      num(1000, -2),
+		Scope:      "shipping",
+		IsActive:   true,
+		Conditions: json.RawMessage(`{"buy_quantity": 2, "get_quantity": 1}`),
+	})
+	if !errors.Is(err, discount.ErrInvalidConditions) {
+		t.Errorf("expected ErrInvalidConditions, got %v", err)
+	}
+}
+
+func TestApply_ProductCondition(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	mug, shirt := uuid.New(), uuid.New()
+	createConditionalDiscount(t, svc, "Mugs 50%", num(5000, -2), fmt.Sprintf(`{"product_ids": [%q]}`, mug))
+
+	// 2 mugs at 10.00 and 1 shirt at 30.00: 50% of the mugs only.
+	result, err := svc.Apply(ctx, discount.ApplyParams{
+		Subtotal:    num(5000, -2),
+		ShippingFee: num(0, -2),
+		Lines: []discount.Line{
+			{ProductID: mug, VariantID: uuid.New(), Quantity: 2, UnitPrice: num(1000, -2)},
+			{ProductID: shirt, VariantID: uuid.New(), Quantity: 1, UnitPrice: num(3000, -2)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	assertCentsEqual(t, "total_discount", result.TotalDiscount, 1000)
+
+	// Without a mug in the cart nothing applies.
+	result, err = svc.Apply(ctx, discount.ApplyParams{
+		Subtotal:    num(3000, -2),
+		ShippingFee: num(0, -2),
+		Lines: []discount.Line{
+			{ProductID: shirt, VariantID: uuid.New(), Quantity: 1, UnitPrice: num(3000, -2)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	assertCentsEqual(t, "total_discount without mug", result.TotalDiscount, 0)
+}
+
+func TestApply_CategoryCondition(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+
+	cat := testDB.FixtureCategory(t, "Clothing", "clothing")
+	shirt := testDB.FixtureProduct(t, "Shirt", "shirt")
+	mug := testDB.FixtureProduct(t, "Mug", "mug")
+	if _, err := testDB.Pool.Exec(ctx,
+		`INSERT INTO product_categories (product_id, category_id, position) VALUES ($1, $2, 0)`,
+		shirt.ID, cat.ID); err != nil {
+		t.Fatalf("linking category: %v", err)
+	}
+
+	createConditionalDiscount(t, svc, "Clothing 10%", num(1000, -2), fmt.Sprintf(`{"category_ids": [%q]}`, cat.ID))
+
+	result, err := svc.Apply(ctx, discount.ApplyParams{
+		Subtotal:    num(5000, -2),
+		ShippingFee: num(0, -2),
+		Lines: []discount.Line{
+			{ProductID: shirt.ID, VariantID: uuid.New(), Quantity: 1, UnitPrice: num(3000, -2)},
+			{ProductID: mug.ID, VariantID: uuid.New(), Quantity: 2, UnitPrice: num(1000, -2)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	assertCentsEqual(t, "total_discount", result.TotalDiscount, 300)
+}
+
+func TestApply_BuyTwoGetOne(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	createConditionalDiscount(t, svc, "3 for 2", num(10000, -2), `{"buy_quantity": 2, "get_quantity": 1}`)
+
+	// Three units at 12.00, 10.00 and 8.00: the cheapest is free.
+	result, err := svc.Apply(ctx, discount.ApplyParams{
+		Subtotal:    num(3000, -2),
+		ShippingFee: num(0, -2),
+		Lines: []discount.Line{
+			{ProductID: uuid.New(), VariantID: uuid.New(), Quantity: 1, UnitPrice: num(1200, -2)},
+			{ProductID: uuid.New(), VariantID: uuid.New(), Quantity: 1, UnitPrice: num(800, -2)},
+			{ProductID: uuid.New(), VariantID: uuid.New(), Quantity: 1, UnitPrice: num(1000, -2)},
+		},
+	})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	assertCentsEqual(t, "total_discount", result.TotalDiscount, 800)
+}
+
+func TestApply_MinQuantityCondition(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	createConditionalDiscount(t, svc, "Bulk", num(1000, -2), `{"min_quantity": 3}`)
+
+	lines := []discount.Line{
+		{ProductID: uuid.New(), VariantID: uuid.New(), Quantity: 2, UnitPrice: num(1000, -2)},
+	}
+	result, err := svc.Apply(ctx, discount.ApplyParams{
+		Subtotal: num(2000, -2), ShippingFee: num(0, -2), Lines: lines,
+	})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	assertCentsEqual(t, "total_discount with 2 units", result.TotalDiscount, 0)
+
+	lines[0].Quantity = 3
+	result, err = svc.Apply(ctx, discount.ApplyParams{
+		Subtotal: num(3000, -2), ShippingFee: num(0, -2), Lines: lines,
+	})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+	assertCentsEqual(t, "total_discount with 3 units", result.TotalDiscount, 300)
+}
+
+func TestApply_CountryAndCustomerConditions(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	customerID := uuid.New()
+	createConditionalDiscount(t, svc, "VIP Spain", num(1000, -2),
+		fmt.Sprintf(`{"country_codes": ["ES"], "customer_ids": [%q]}`, customerID))
+
+	tests := []struct {
+		name       string
+		country    string
+		customerID uuid.UUID
+		wantCents  int64
+	}{
+		{name: "matching", country: "ES", customerID: customerID, wantCents: 1000},
+		{name: "other country", country: "DE", customerID: customerID, wantCents: 0},
+		{name: "other customer", country: "ES", customerID: uuid.New(), wantCents: 0},
+		{name: "guest", country: "ES", wantCents: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := svc.Apply(ctx, discount.ApplyParams{
+				Subtotal:    num(10000, -2),
+				ShippingFee: num(0, -2),
+				CountryCode: tt.country,
+				CustomerID:  tt.customerID,
+			})
+			if err != nil {
+				t.Fatalf("Apply: %v", err)
+			}
+			assertCentsEqual(t, "total_discount", result.TotalDiscount, tt.wantCents)
+		})
+	}
+}
+
+func TestApply_FirstOrderOnly(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	createConditionalDiscount(t, svc, "Welcome", num(1000, -2), `{"first_order_only": true}`)
+
+	apply := func(email string) pgtype.Numeric {
+		t.Helper()
+		result, err := svc.Apply(ctx, discount.ApplyParams{
+			Subtotal:    num(10000, -2),
+			ShippingFee: num(0, -2),
+			Email:       email,
+		})
+		if err != nil {
+			t.Fatalf("Apply: %v", err)
+		}
+		return result.TotalDiscount
+	}
+
+	assertCentsEqual(t, "new shopper", apply("new@example.com"), 1000)
+	assertCentsEqual(t, "unknown shopper", apply(""), 0)
+
+	createOrder(t) // buyer@example.com
+	assertCentsEqual(t, "returning shopper", apply("Buyer@Example.com"), 0)
+}
+
+func createConditionalDiscount(t *testing.T, svc *discount.Service, name string, percent pgtype.Numeric, conditions string) {
+	t.Helper()
+	_, err := svc.CreateDiscount(context.Background(), discount.CreateDiscountParams{
+		Name:       name,
+		Type:       "percentage",
+		Value:      percent,
+		Scope:      "subtotal",
+		IsActive:   true,
+		Conditions: json.RawMessage(conditions),
+	})
+	if err != nil {
+		t.Fatalf("creating discount %q: %v", name, err)
+	}
+}
+
+// --------------------------------------------------------------------------
+// Coupon campaigns
+// --------------------------------------------------------------------------
+
+func TestGenerateCampaign(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	d, _ := svc.CreateDiscount(ctx, discount.CreateDiscountParams{
+		Name: "Newsletter", Type: "percentage", Value: num(1000, -2), Scope: "subtotal", IsActive: true,
+	})
+
+	campaign, err := svc.GenerateCampaign(ctx, discount.GenerateCampaignParams{
+		Name:       "Spring newsletter",
+		DiscountID: d.ID,
+		Prefix:     "spring-",
+		CodeLength: 6,
+		Count:      2500,
+	})
+	if err != nil {
+		t.Fatalf("GenerateCampaign: %v", err)
+	}
+	if campaign.Prefix != "SPRING-" {
+		t.Errorf("prefix: got %q, want SPRING-", campaign.Prefix)
+	}
+	if campaign.Alphabet != discount.DefaultCampaignAlphabet {
+		t.Errorf("alphabet: got %q, want default", campaign.Alphabet)
+	}
+	if campaign.UsageLimitPerCode != 1 {
+		t.Errorf("usage_limit_per_code: got %d, want 1", campaign.UsageLimitPerCode)
+	}
+
+	codes, err := svc.ListCampaignCodes(ctx, campaign.ID)
+	if err != nil {
+		t.Fatalf("ListCampaignCodes: %v", err)
+	}
+	if len(codes) != 2500 {
+		t.Fatalf("codes: got %d, want 2500", len(codes))
+	}
+	for _, c := range codes {
+		if len(c.Code) != len("SPRING-")+6 || c.Code[:7] != "SPRING-" {
+			t.Fatalf("unexpected code %q", c.Code)
+		}
+		if c.UsageLimit == nil || *c.UsageLimit != 1 {
+			t.Fatalf("code %q: usage_limit %v, want 1", c.Code, c.UsageLimit)
+		}
+	}
+
+	// Generated codes are redeemable like any other coupon.
+	if _, err := svc.ValidateCoupon(ctx, codes[0].Code, uuid.Nil, ""); err != nil {
+		t.Errorf("ValidateCoupon: %v", err)
+	}
+
+	// Campaign codes are not listed with the individual coupons.
+	rows, err := svc.ListCoupons(ctx, 20, 0)
+	if err != nil {
+		t.Fatalf("ListCoupons: %v", err)
+	}
+	if len(rows) != 0 {
+		t.Errorf("ListCoupons: got %d rows, want 0", len(rows))
+	}
+}
+
+func TestGenerateCampaign_SkipsExistingCodes(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	d, _ := svc.CreateDiscount(ctx, discount.CreateDiscountParams{
+		Name: "D", Type: "percentage", Value: num(500, -2), Scope: "subtotal", IsActive: true,
+	})
+
+	// Occupy part of a small code space so generated codes can collide.
+	for _, a := range "AB" {
+		for _, b := range "AB" {
+			for _, c := range "AB" {
+				for _, e := range "AB" {
+					code := "X" + string([]rune{a, b, c, e})
+					if _, err := svc.CreateCoupon(ctx, discount.CreateCouponParams{Code: code, DiscountID: d.ID, IsActive: true}); err != nil {
+						t.Fatalf("CreateCoupon: %v", err)
+					}
+				}
+			}
+		}
+	}
+
+	campaign, err := svc.GenerateCampaign(ctx, discount.GenerateCampaignParams{
+		Name:       "Small",
+		DiscountID: d.ID,
+		Prefix:     "X",
+		CodeLength: 4,
+		Alphabet:   "ABCDEF",
+		Count:      100,
+	})
+	if err != nil {
+		t.Fatalf("GenerateCampaign: %v", err)
+	}
+
+	codes, _ := svc.ListCampaignCodes(ctx, campaign.ID)
+	if len(codes) != 100 {
+		t.Errorf("codes: got %d, want 100", len(codes))
+	}
+}
+
+func TestGenerateCampaign_Invalid(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	_, err := svc.GenerateCampaign(ctx, discount.GenerateCampaignParams{
+		Name: "Missing", DiscountID: uuid.New(), Count: 10,
+	})
+	if !errors.Is(err, discount.ErrNotFound) {
+		t.Errorf("unknown discount: expected ErrNotFound, got %v", err)
+	}
+
+	d, _ := svc.CreateDiscount(ctx, discount.CreateDiscountParams{
+		Name: "D", Type: "percentage", Value: num(500, -2), Scope: "subtotal", IsActive: true,
+	})
+	_, err = svc.GenerateCampaign(ctx, discount.GenerateCampaignParams{
+		Name: "Too many", DiscountID: d.ID, CodeLength: 4, Alphabet: "AB", Count: 10,
+	})
+	if !errors.Is(err, discount.ErrInvalidCampaign) {
+		t.Errorf("small code space: expected ErrInvalidCampaign, got %v", err)
+	}
+
+	campaigns, _ := svc.ListCampaigns(ctx, 20, 0)
+	if len(campaigns) != 0 {
+		t.Errorf("campaigns: got %d, want 0", len(campaigns))
+	}
+}
+
+func TestGetCampaignReport(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	d, _ := svc.CreateDiscount(ctx, discount.CreateDiscountParams{
+		Name: "Influencer", Type: "percentage", Value: num(1500, -2), Scope: "subtotal", IsActive: true,
+	})
+	campaign, err := svc.GenerateCampaign(ctx, discount.GenerateCampaignParams{
+		Name: "Influencer", DiscountID: d.ID, Count: 5, UsageLimitPerCode: 2,
+	})
+	if err != nil {
+		t.Fatalf("GenerateCampaign: %v", err)
+	}
+	codes, _ := svc.ListCampaignCodes(ctx, campaign.ID)
+
+	// Two uses of the first code by the same buyer, one of the second.
+	for _, code := range []string{codes[0].Code, codes[0].Code, codes[1].Code} {
+		c, err := svc.GetCouponByCode(ctx, code)
+		if err != nil {
+			t.Fatalf("GetCouponByCode: %v", err)
+		}
+		if err := svc.RedeemCoupon(ctx, discount.RedeemCouponParams{
+			CouponID: c.ID,
+			Email:    "buyer@example.com",
+			OrderID:  createOrder(t),
+		}); err != nil {
+			t.Fatalf("RedeemCoupon: %v", err)
+		}
+	}
+
+	report, err := svc.GetCampaignReport(ctx, campaign.ID)
+	if err != nil {
+		t.Fatalf("GetCampaignReport: %v", err)
+	}
+	if report.Campaign.DiscountName != "Influencer" {
+		t.Errorf("discount_name: got %q", report.Campaign.DiscountName)
+	}
+	if report.Summary.RedemptionCount != 3 {
+		t.Errorf("redemption_count: got %d, want 3", report.Summary.RedemptionCount)
+	}
+	if report.Summary.CodesRedeemed != 2 {
+		t.Errorf("codes_redeemed: got %d, want 2", report.Summary.CodesRedeemed)
+	}
+	if report.Summary.UniqueCustomers != 1 {
+		t.Errorf("unique_customers: got %d, want 1", report.Summary.UniqueCustomers)
+	}
+	if len(report.Daily) != 1 || report.Daily[0].RedemptionCount != 3 {
+		t.Errorf("daily: got %+v, want one day with 3 redemptions", report.Daily)
+	}
+
+	campaigns, _ := svc.ListCampaigns(ctx, 20, 0)
+	if len(campaigns) != 1 || campaigns[0].RedemptionCount != 3 {
+		t.Errorf("ListCampaigns: got %+v, want one campaign with 3 redemptions", campaigns)
+	}
+}
+
+func TestGetCampaignReport_NotFound(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+
+	_, err := svc.GetCampaignReport(context.Background(), uuid.New())
+	if !errors.Is(err, discount.ErrCampaignNotFound) {
+		t.Errorf("expected ErrCampaignNotFound, got %v", err)
+	}
+}
+
+func TestGenerateCoupon(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	d, _ := svc.CreateDiscount(ctx, discount.CreateDiscountParams{
+		Name: "Come back", Type: "percentage", Value: num(1000, -2), Scope: "subtotal", IsActive: true,
+	})
+
+	endsAt := time.Now().Add(72 * time.Hour)
+	c, err := svc.GenerateCoupon(ctx, d.ID, "back-", endsAt)
+	if err != nil {
+		t.Fatalf("GenerateCoupon: %v", err)
+	}
+	if len(c.Code) != len("BACK-")+discount.DefaultCampaignCodeLength || c.Code[:5] != "BACK-" {
+		t.Errorf("unexpected code %q", c.Code)
+	}
+	if c.UsageLimit == nil || *c.UsageLimit != 1 {
+		t.Errorf("usage_limit: got %v, want 1", c.UsageLimit)
+	}
+	if !c.EndsAt.Valid || c.EndsAt.Time.Sub(endsAt).Abs() > time.Second {
+		t.Errorf("ends_at: got %v, want %v", c.EndsAt, endsAt)
+	}
+	if _, err := svc.ValidateCoupon(ctx, c.Code, uuid.Nil, ""); err != nil {
+		t.Errorf("ValidateCoupon: %v", err)
+	}
+
+	if _, err := svc.GenerateCoupon(ctx, uuid.New(), "BACK-", endsAt); !errors.Is(err, discount.ErrNotFound) {
+		t.Errorf("unknown discount: expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestGenerateCouponTx_RolledBack(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	d, _ := svc.CreateDiscount(ctx, discount.CreateDiscountParams{
+		Name: "Come back", Type: "percentage", Value: num(1000, -2), Scope: "subtotal", IsActive: true,
+	})
+
+	tx, err := testDB.Pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("beginning transaction: %v", err)
+	}
+	c, err := svc.GenerateCouponTx(ctx, tx, d.ID, "BACK-", time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("GenerateCouponTx: %v", err)
+	}
+	tx.Rollback(ctx)
+
+	if _, err := svc.GetCouponByCode(ctx, c.Code); !errors.Is(err, discount.ErrCouponNotFound) {
+		t.Errorf("expected the coupon to be rolled back, got %v", err)
+	}
+}
@@ -33,8 +33,23 @@ var (
 	// ErrCouponUsageLimitReached is returned when a coupon has reached its usage limit.
 	ErrCouponUsageLimitReached = errors.New("coupon usage limit reached")
 
+	// ErrCouponCustomerLimitReached is returned when a customer has already
+	// used a coupon as many times as it allows per customer.
+	ErrCouponCustomerLimitReached = errors.New("coupon usage limit per customer reached")
+
 	// ErrMinimumNotMet is returned when the order amount does not meet the discount minimum.
 	ErrMinimumNotMet = errors.New("minimum order amount not met")
+
+	// ErrInvalidConditions is returned when a discount's conditions JSON is
+	// malformed or contradictory.
+	ErrInvalidConditions = errors.New("invalid discount conditions")
+
+	// ErrCampaignNotFound is returned when a coupon campaign does not exist.
+	ErrCampaignNotFound = errors.New("coupon campaign not found")
+
+	// ErrInvalidCampaign is returned when coupon campaign settings cannot
+	// produce the requested codes.
+	ErrInvalidCampaign = errors.New("invalid coupon campaign")
 )
 
 // bigZero is a reusable zero value for big.Int comparisons.
@@ -69,6 +84,17 @@ type ApplyParams struct {
 	ShippingFee pgtype.Numeric
 	CouponCode  string    // optional — empty string means no coupon
 	CustomerID  uuid.UUID // zero value for guest customers
+	Email       string    // identifies guests for first-order conditions
+	CountryCode string    // destination country, for country conditions
+	Lines       []Line    // cart lines, for line conditions
+}
+
+// Line is one cart line, as seen by line conditions.
+type Line struct {
+	ProductID uuid.UUID
+	VariantID uuid.UUID
+	Quantity  int32
+	UnitPrice pgtype.Numeric // gross price of one unit
 }
 
 // ApplyResult holds the output of the discount calculation.
@@ -80,13 +106,14 @@ type ApplyResult struct {
 }
 
 // DiscountApplication describes a single discount that was applied.
+// Its JSON form is what orders store in discount_breakdown.
 type DiscountApplication struct {
-	DiscountID   uuid.UUID
-	DiscountName string
-	Type         string         // "percentage" or "fixed_amount"
-	Value        pgtype.Numeric // the configured value (e.g. 10 for 10% or 5.00 for fixed)
-	Scope        string         // "subtotal", "shipping", or "total"
-	Amount       pgtype.Numeric // actual monetary amount deducted
+	DiscountID   uuid.UUID      `json:"discount_id"`
+	DiscountName string         `json:"discount_name"`
+	Type         string         `json:"type"`   // "percentage" or "fixed_amount"
+	Value        pgtype.Numeric `json:"value"`  // the configured value (e.g. 10 for 10% or 5.00 for fixed)
+	Scope        string         `json:"scope"`  // "subtotal", "shipping", or "total"
+	Amount       pgtype.Numeric `json:"amount"` // actual monetary amount deducted
 }
 
 // CreateDiscountParams contains the input fields for creating a discount.
@@ -168,12 +195,12 @@ func (s *Service) ListDiscounts(ctx context.Context, limit, offset int32) ([]db.
 
 // CreateDiscount persists a new discount and returns it.
 func (s *Service) CreateDiscount(ctx context.Context, p CreateDiscountParams) (db.Discount, error) {
-	now := time.Now().UTC()
-	conditions := p.Conditions
-	if conditions == nil {
-		conditions = json.RawMessage(`{}`)
+	conditions, err := normalizeConditions(p.Conditions, p.Scope)
+	if err != nil {
+		return db.Discount{}, err
 	}
 
+	now := time.Now().UTC()
 	d, err := s.queries.CreateDiscount(ctx, db.CreateDiscountParams{
 		ID:              uuid.New(),
 		Name:            p.Name,
@@ -212,12 +239,12 @@ func (s *Service) UpdateDiscount(ctx context.Context, id uuid.UUID, p UpdateDisc
 		return db.Discount{}, fmt.Errorf("checking discount existence: %w", err)
 	}
 
-	now := time.Now().UTC()
-	conditions := p.Conditions
-	if conditions == nil {
-		conditions = json.RawMessage(`{}`)
+	conditions, err := normalizeConditions(p.Conditions, p.Scope)
+	if err != nil {
+		return db.Discount{}, err
 	}
 
+	now := time.Now().UTC()
 	d, err := s.queries.UpdateDiscount(ctx, db.UpdateDiscountParams{
 		ID:              id,
 		Name:            p.Name,
@@ -329,15 +356,71 @@ func (s *Service) CreateCoupon(ctx context.Context, p CreateCouponParams) (db.Co
 	return c, nil
 }
 
-// IncrementCouponUsage increments the usage counter for a coupon after a successful order.
+// IncrementCouponUsage increments the usage counter for a coupon after a
+// successful order. It returns ErrCouponUsageLimitReached if the coupon has
+// no uses left.
 func (s *Service) IncrementCouponUsage(ctx context.Context, couponID uuid.UUID) error {
-	err := s.queries.IncrementCouponUsage(ctx, db.IncrementCouponUsageParams{
+	return incrementCouponUsage(ctx, s.queries, couponID)
+}
+
+func incrementCouponUsage(ctx context.Context, q *db.Queries, couponID uuid.UUID) error {
+	n, err := q.IncrementCouponUsage(ctx, db.IncrementCouponUsageParams{
 		ID:        couponID,
 		UpdatedAt: time.Now().UTC(),
 	})
 	if err != nil {
 		return fmt.Errorf("incrementing coupon usage: %w", err)
 	}
+	if n == 0 {
+		return ErrCouponUsageLimitReached
+	}
+	return nil
+}
+
+// RedeemCouponParams identifies a paid order that used a coupon.
+type RedeemCouponParams struct {
+	CouponID   uuid.UUID
+	CustomerID pgtype.UUID // invalid for guest orders
+	Email      string      // the order's email, for per-customer limits
+	OrderID    uuid.UUID
+}
+
+// RedeemCoupon records the use of a coupon by a paid order: it counts the use
+// against the coupon's limit and logs a coupon_usage row, in one transaction.
+// It returns ErrCouponUsageLimitReached, recording nothing, if a concurrent
+// order took the last use.
+func (s *Service) RedeemCoupon(ctx context.Context, p RedeemCouponParams) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	if err := incrementCouponUsage(ctx, qtx, p.CouponID); err != nil {
+		return err
+	}
+
+	if _, err := qtx.CreateCouponUsage(ctx, db.CreateCouponUsageParams{
+		ID:         uuid.New(),
+		CouponID:   p.CouponID,
+		CustomerID: p.CustomerID,
+		OrderID:    pgtype.UUID{Bytes: p.OrderID, Valid: true},
+		UsedAt:     time.Now().UTC(),
+		Email:      optionalEmail(p.Email),
+	}); err != nil {
+		return fmt.Errorf("recording coupon usage: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing coupon redemption: %w", err)
+	}
+
+	s.logger.Info("coupon redeemed",
+		slog.String("coupon_id", p.CouponID.String()),
+		slog.String("order_id", p.OrderID.String()),
+	)
 	return nil
 }
 
@@ -368,12 +451,15 @@ func (s *Service) DeleteDiscount(ctx context.Context, id uuid.UUID) error {
 // order parameters and returns the total discount with a per-discount breakdown.
 //
 // Evaluation order follows the CLAUDE.md spec:
-//  1. Collect active discounts (date-valid, is_active=true).
+//  1. Collect active automatic discounts (date-valid, is_active=true, no coupons).
 //  2. If a coupon code is provided, validate and include its linked discount.
-//  3. Evaluate by scope: subtotal -> shipping -> total.
+//  3. Evaluate by scope: subtotal -> shipping -> total, skipping discounts
+//     whose Conditions the order does not meet.
 //  4. Within each scope, apply stackable discounts in priority order (DESC);
 //     stop at the first non-stackable discount.
 //  5. Check minimum_amount and cap with maximum_discount.
+//
+// ApplyResult.CouponID is only set if the coupon's discount was applied.
 func (s *Service) Apply(ctx context.Context, params ApplyParams) (ApplyResult, error) {
 	result := ApplyResult{
 		TotalDiscount: numericZero(),
@@ -393,12 +479,14 @@ func (s *Service) Apply(ctx context.Context, params ApplyParams) (ApplyResult, e
 	}
 
 	// If a coupon code was provided, validate and add its linked discount.
+	var couponDiscountID uuid.UUID
 	if params.CouponCode != "" {
-		coupon, couponDiscount, err := s.validateCoupon(ctx, params.CouponCode)
+		coupon, couponDiscount, err := s.validateCoupon(ctx, params.CouponCode, params.CustomerID, params.Email)
 		if err != nil {
 			return result, err
 		}
 		result.CouponID = pgtype.UUID{Bytes: coupon.ID, Valid: true}
+		couponDiscountID = couponDiscount.ID
 
 		// Add the coupon's discount if it is not already in the active set.
 		if !seen[couponDiscount.ID] {
@@ -407,11 +495,34 @@ func (s *Service) Apply(ctx context.Context, params ApplyParams) (ApplyResult, e
 		}
 	}
 
+	// Decode each candidate's conditions. A discount whose stored conditions
+	// no longer parse is skipped rather than applied without them.
+	conditions := make(map[uuid.UUID]Conditions, len(activeDiscounts))
+	candidates := activeDiscounts[:0]
+	for _, d := range activeDiscounts {
+		c, err := ParseConditions(d.Conditions, d.Scope)
+		if err != nil {
+			s.logger.Warn("skipping discount with invalid conditions",
+				slog.String("discount_id", d.ID.String()),
+				slog.String("error", err.Error()),
+			)
+			continue
+		}
+		conditions[d.ID] = c
+		candidates = append(candidates, d)
+	}
+	activeDiscounts = candidates
+
 	// Nothing to apply.
 	if len(activeDiscounts) == 0 {
 		return result, nil
 	}
 
+	facts, err := s.loadOrderFacts(ctx, params, conditions)
+	if err != nil {
+		return result, err
+	}
+
 	// Sort by priority descending (higher priority first).
 	sort.Slice(activeDiscounts, func(i, j int) bool {
 		return activeDiscounts[i].Priority > activeDiscounts[j].Priority
@@ -464,8 +575,29 @@ func (s *Service) Apply(ctx context.Context, params ApplyParams) (ApplyResult, e
 				}
 			}
 
-			// Compute the raw discount amount in cents.
+			cond := conditions[d.ID]
+			if !cond.orderQualifies(params, facts.firstOrder) {
+				continue
+			}
+
+			// Line conditions narrow a subtotal discount to the qualifying
+			// lines, or to the rewarded units of a "buy X get Y" rule.
 			base := scopeBase[scope]
+			if cond.needsLines() {
+				lines := cond.qualifyingLines(params.Lines, facts.categories)
+				qty := lineQuantity(lines)
+				if qty == 0 || qty < int64(cond.MinQuantity) {
+					continue
+				}
+				switch {
+				case cond.isBuyXGetY():
+					base = rewardCents(lines, cond.BuyQuantity, cond.GetQuantity)
+				case scope == "subtotal" && cond.targetsLines():
+					base = lineTotalCents(lines)
+				}
+			}
+
+			// Compute the raw discount amount in cents.
 			rawAmountCents := computeAmountCents(d, base)
 
 			// Cap by maximum_discount if set.
@@ -516,6 +648,21 @@ func (s *Service) Apply(ctx context.Context, params ApplyParams) (ApplyResult, e
 	}
 	result.TotalDiscount = centsToNumeric(totalDiscountCents)
 
+	// A coupon whose discount did not apply (e.g. minimum not met) was not
+	// used and must not be redeemed.
+	if result.CouponID.Valid {
+		used := false
+		for _, app := range result.Breakdown {
+			if app.DiscountID == couponDiscountID {
+				used = true
+				break
+			}
+		}
+		if !used {
+			result.CouponID = pgtype.UUID{}
+		}
+	}
+
 	// Set the primary discount ID from the first entry in the breakdown.
 	if len(result.Breakdown) > 0 {
 		result.DiscountID = pgtype.UUID{
@@ -532,13 +679,24 @@ func (s *Service) Apply(ctx context.Context, params ApplyParams) (ApplyResult, e
 	return result, nil
 }
 
+// ValidateCoupon checks that a coupon code can be used now by the given
+// shopper, returning the coupon or one of ErrCouponNotFound,
+// ErrCouponExpired, ErrCouponUsageLimitReached and
+// ErrCouponCustomerLimitReached. customerID is zero for guests, who are
+// identified by email; a guest without an email is not checked against the
+// per-customer limit.
+func (s *Service) ValidateCoupon(ctx context.Context, code string, customerID uuid.UUID, email string) (db.Coupon, error) {
+	coupon, _, err := s.validateCoupon(ctx, code, customerID, email)
+	return coupon, err
+}
+
 // --------------------------------------------------------------------------
 // Internal helpers
 // --------------------------------------------------------------------------
 
 // validateCoupon checks that a coupon is valid for use: it must be active,
-// within its date range, and not have exceeded its usage limit.
-func (s *Service) validateCoupon(ctx context.Context, code string) (db.Coupon, db.Discount, error) {
+// within its date range, and not have exceeded its usage limits.
+func (s *Service) validateCoupon(ctx context.Context, code string, customerID uuid.UUID, email string) (db.Coupon, db.Discount, error) {
 	code = strings.TrimSpace(strings.ToUpper(code))
 
 	coupon, err := s.queries.GetCouponByCode(ctx, code)
@@ -567,6 +725,15 @@ func (s *Service) validateCoupon(ctx context.Context, code string) (db.Coupon, d
 	if coupon.UsageLimit != nil && coupon.UsageCount >= *coupon.UsageLimit {
 		return db.Coupon{}, db.Discount{}, ErrCouponUsageLimitReached
 	}
+	if coupon.UsageLimitPerCustomer != nil {
+		used, err := s.customerCouponUses(ctx, coupon.ID, customerID, email)
+		if err != nil {
+			return db.Coupon{}, db.Discount{}, err
+		}
+		if used >= int64(*coupon.UsageLimitPerCustomer) {
+			return db.Coupon{}, db.Discount{}, ErrCouponCustomerLimitReached
+		}
+	}
 
 	// Fetch the linked discount.
 	discount, err := s.queries.GetDiscount(ctx, coupon.DiscountID)
@@ -591,6 +758,89 @@ func (s *Service) validateCoupon(ctx context.Context, code string) (db.Coupon, d
 	return coupon, discount, nil
 }
 
+// customerCouponUses counts a shopper's earlier uses of a coupon: by account
+// for logged-in customers and by email for guests.
+func (s *Service) customerCouponUses(ctx context.Context, couponID, customerID uuid.UUID, email string) (int64, error) {
+	var (
+		n   int64
+		err error
+	)
+	switch {
+	case customerID != uuid.Nil:
+		n, err = s.queries.CountCouponUsageByCustomer(ctx, db.CountCouponUsageByCustomerParams{
+			CouponID:   couponID,
+			CustomerID: pgtype.UUID{Bytes: customerID, Valid: true},
+		})
+	case optionalEmail(email) != nil:
+		n, err = s.queries.CountCouponUsageByEmail(ctx, db.CountCouponUsageByEmailParams{
+			CouponID: couponID,
+			Email:    optionalEmail(email),
+		})
+	}
+	if err != nil {
+		return 0, fmt.Errorf("counting customer coupon usage: %w", err)
+	}
+	return n, nil
+}
+
+// optionalEmail normalizes an email address for coupon usage records, or
+// returns nil if it is blank.
+func optionalEmail(email string) *string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil
+	}
+	return &email
+}
+
+// orderFacts holds what discount conditions need to know about an order
+// beyond ApplyParams.
+type orderFacts struct {
+	firstOrder bool                      // the shopper has no earlier orders
+	categories map[uuid.UUID][]uuid.UUID // product ID -> category IDs
+}
+
+// loadOrderFacts looks up the facts the given conditions depend on, skipping
+// queries none of them need. A shopper with neither an account nor an email
+// address is not treated as placing a first order.
+func (s *Service) loadOrderFacts(ctx context.Context, params ApplyParams, conditions map[uuid.UUID]Conditions) (orderFacts, error) {
+	var needFirstOrder, needCategories bool
+	for _, c := range conditions {
+		needFirstOrder = needFirstOrder || c.FirstOrderOnly
+		needCategories = needCategories || c.usesCategories()
+	}
+
+	var facts orderFacts
+	email := strings.TrimSpace(params.Email)
+	if needFirstOrder && (params.CustomerID != uuid.Nil || email != "") {
+		n, err := s.queries.CountCustomerOrders(ctx, db.CountCustomerOrdersParams{
+			CustomerID: pgtype.UUID{Bytes: params.CustomerID, Valid: params.CustomerID != uuid.Nil},
+			Email:      email,
+		})
+		if err != nil {
+			return facts, fmt.Errorf("counting earlier orders: %w", err)
+		}
+		facts.firstOrder = n == 0
+	}
+
+	if needCategories && len(params.Lines) > 0 {
+		productIDs := make([]uuid.UUID, 0, len(params.Lines))
+		for _, l := range params.Lines {
+			productIDs = append(productIDs, l.ProductID)
+		}
+		rows, err := s.queries.ListProductCategoryIDs(ctx, productIDs)
+		if err != nil {
+			return facts, fmt.Errorf("listing product categories: %w", err)
+		}
+		facts.categories = make(map[uuid.UUID][]uuid.UUID, len(rows))
+		for _, r := range rows {
+			facts.categories[r.ProductID] = append(facts.categories[r.ProductID], r.CategoryID)
+		}
+	}
+
+	return facts, nil
+}
+
 // computeAmountCents calculates the raw discount amount in cents for a single discount
 // against a base value in cents.
 //
@@ -0,0 +1,410 @@
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+)
+
+// ReservationLine is a quantity of one variant to hold for a cart.
+type ReservationLine struct {
+	VariantID uuid.UUID
+	Quantity  int32
+}
+
+// Shortage describes a cart line that cannot be reserved.
+type Shortage struct {
+	VariantID uuid.UUID
+	SKU       string
+	Requested int32
+	Available int32
+}
+
+// ShortageError is returned by ReserveCart when one or more lines exceed the
+// available stock. It matches ErrInsufficientStock with errors.Is.
+type ShortageError struct {
+	Shortages []Shortage
+}
+
+func (e *ShortageError) Error() string {
+	parts := make([]string, len(e.Shortages))
+	for i, sh := range e.Shortages {
+		parts[i] = fmt.Sprintf("%s (requested %d, available %d)", sh.SKU, sh.Requested, sh.Available)
+	}
+	return "insufficient stock: " + strings.Join(parts, ", ")
+}
+
+func (e *ShortageError) Unwrap() error {
+	return ErrInsufficientStock
+}
+
+// ReserveCart holds stock for a cart until expiresAt. Any active reservations
+// the cart already holds are released first, so re-entering checkout does not
+// double-count; callers expire the checkout session they were held for before
+// reserving again. Available stock is the variant's stock minus active,
+// unexpired reservations held by other carts. Lines for products that allow
+// backorders are reserved even when stock is short.
+//
+// If any line cannot be satisfied nothing is reserved and a *ShortageError is
+// returned listing every short line.
+func (s *Service) ReserveCart(ctx context.Context, cartID uuid.UUID, lines []ReservationLine, expiresAt time.Time) error {
+	// Lock variants in a stable order so concurrent checkouts cannot deadlock.
+	sorted := append([]ReservationLine(nil), lines...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].VariantID.String() < sorted[j].VariantID.String()
+	})
+
+	cartRef := pgtype.UUID{Bytes: cartID, Valid: true}
+	err := s.inTx(ctx, func(q *db.Queries) error {
+		if err := q.ReleaseCartReservations(ctx, cartRef); err != nil {
+			return fmt.Errorf("releasing previous reservations for cart %s: %w", cartID, err)
+		}
+
+		var shortages []Shortage
+		for _, line := range sorted {
+			if line.Quantity <= 0 {
+				continue
+			}
+
+			v, err := q.GetVariantForReservation(ctx, line.VariantID)
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return ErrNotFound
+				}
+				return fmt.Errorf("locking variant %s: %w", line.VariantID, err)
+			}
+
+			reserved, err := q.SumActiveReservations(ctx, db.SumActiveReservationsParams{
+				VariantID: line.VariantID,
+				CartID:    cartRef,
+			})
+			if err != nil {
+				return fmt.Errorf("summing reservations for variant %s: %w", line.VariantID, err)
+			}
+
+			available := max(v.StockQuantity-reserved, 0)
+			if !v.IsActive {
+				available = 0
+			}
+			if line.Quantity > available && !(v.AllowBackorder && v.IsActive) {
+				shortages = append(shortages, Shortage{
+					VariantID: v.ID,
+					SKU:       v.Sku,
+					Requested: line.Quantity,
+					Available: available,
+				})
+				continue
+			}
+
+			if _, err := q.CreateStockReservation(ctx, db.CreateStockReservationParams{
+				ID:        uuid.New(),
+				CartID:    cartRef,
+				VariantID: line.VariantID,
+				Quantity:  line.Quantity,
+				ExpiresAt: expiresAt,
+			}); err != nil {
+				return fmt.Errorf("reserving variant %s: %w", line.VariantID, err)
+			}
+		}
+
+		if len(shortages) > 0 {
+			return &ShortageError{Shortages: shortages}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("stock reserved for cart",
+		slog.String("cart_id", cartID.String()),
+		slog.Int("lines", len(sorted)),
+		slog.Time("expires_at", expiresAt),
+	)
+	return nil
+}
+
+// AttachCheckoutSession links a cart's active reservations to the Stripe
+// Checkout Session created for it, so the payment webhook can find them.
+func (s *Service) AttachCheckoutSession(ctx context.Context, cartID uuid.UUID, sessionID string) error {
+	if err := s.queries.SetReservationCheckoutSession(ctx, db.SetReservationCheckoutSessionParams{
+		CartID:                  pgtype.UUID{Bytes: cartID, Valid: true},
+		StripeCheckoutSessionID: &sessionID,
+	}); err != nil {
+		return fmt.Errorf("attaching checkout session to reservations for cart %s: %w", cartID, err)
+	}
+	return nil
+}
+
+// CartCheckoutSessions returns the Stripe Checkout Sessions that a cart's
+// active reservations are linked to.
+func (s *Service) CartCheckoutSessions(ctx context.Context, cartID uuid.UUID) ([]string, error) {
+	ids, err := s.queries.ListCartCheckoutSessions(ctx, pgtype.UUID{Bytes: cartID, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("listing checkout sessions for cart %s: %w", cartID, err)
+	}
+	return ids, nil
+}
+
+// ReleaseCart releases a cart's active reservations.
+func (s *Service) ReleaseCart(ctx context.Context, cartID uuid.UUID) error {
+	if err := s.queries.ReleaseCartReservations(ctx, pgtype.UUID{Bytes: cartID, Valid: true}); err != nil {
+		return fmt.Errorf("releasing reservations for cart %s: %w", cartID, err)
+	}
+	return nil
+}
+
+// ReleaseCheckoutSession releases the active reservations of an abandoned
+// or expired checkout session.
+func (s *Service) ReleaseCheckoutSession(ctx context.Context, sessionID string) error {
+	if err := s.queries.ReleaseSessionReservations(ctx, &sessionID); err != nil {
+		return fmt.Errorf("releasing reservations for session %s: %w", sessionID, err)
+	}
+	return nil
+}
+
+// CommitCheckoutSession turns the active reservations of a paid checkout
+// session into sale movements against orderID, decrementing variant stock.
+// The customer has already paid, so reservations are committed even if they
+// expired in the meantime, and stock may go negative. It is safe to call more
+// than once; already committed reservations are skipped. It returns the
+// number of reservations committed.
+func (s *Service) CommitCheckoutSession(ctx context.Context, sessionID string, orderID uuid.UUID) (int, error) {
+	var movements []db.StockMovement
+	err := s.inTx(ctx, func(q *db.Queries) error {
+		reservations, err := q.ListActiveSessionReservationsForUpdate(ctx, &sessionID)
+		if err != nil {
+			return fmt.Errorf("listing reservations for session %s: %w", sessionID, err)
+		}
+
+		orderRef := pgtype.UUID{Bytes: orderID, Valid: true}
+		for _, r := range reservations {
+			qty := r.Quantity
+			m, err := changeVariantStock(ctx, q, r.VariantID, Change{
+				MovementType:  MovementSale,
+				ReferenceType: ReferenceOrder,
+				ReferenceID:   orderRef,
+				AllowNegative: true,
+			}, func(before int32) int32 { return before - qty })
+			if err != nil {
+				return fmt.Errorf("committing reservation %s: %w", r.ID, err)
+			}
+			movements = append(movements, m)
+
+			if err := q.MarkReservationCommitted(ctx, db.MarkReservationCommittedParams{
+				ID:      r.ID,
+				OrderID: orderRef,
+			}); err != nil {
+				return fmt.Errorf("marking reservation %s committed: %w", r.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range movements {
+		s.variantChanged(ctx, m)
+	}
+	return len(movements), nil
+}
+
+// CommitCartLines records sales against orderID for a paid checkout session
+// whose reservations were lost, for example released by an early expiry. Each
+// line is stored as a committed reservation of the cart so that RestockOrder
+// can return it later. Like CommitCheckoutSession it allows stock to go
+// negative. It returns the number of lines committed.
+func (s *Service) CommitCartLines(ctx context.Context, cartID uuid.UUID, sessionID string, orderID uuid.UUID, lines []ReservationLine) (int, error) {
+	sorted := append([]ReservationLine(nil), lines...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].VariantID.String() < sorted[j].VariantID.String()
+	})
+
+	var movements []db.StockMovement
+	err := s.inTx(ctx, func(q *db.Queries) error {
+		orderRef := pgtype.UUID{Bytes: orderID, Valid: true}
+		for _, line := range sorted {
+			if line.Quantity <= 0 {
+				continue
+			}
+			qty := line.Quantity
+			m, err := changeVariantStock(ctx, q, line.VariantID, Change{
+				MovementType:  MovementSale,
+				ReferenceType: ReferenceOrder,
+				ReferenceID:   orderRef,
+				AllowNegative: true,
+			}, func(before int32) int32 { return before - qty })
+			if err != nil {
+				return fmt.Errorf("committing variant %s: %w", line.VariantID, err)
+			}
+			movements = append(movements, m)
+
+			if _, err := q.CreateCommittedReservation(ctx, db.CreateCommittedReservationParams{
+				ID:                      uuid.New(),
+				CartID:                  pgtype.UUID{Bytes: cartID, Valid: true},
+				VariantID:               line.VariantID,
+				Quantity:                qty,
+				StripeCheckoutSessionID: &sessionID,
+				OrderID:                 orderRef,
+			}); err != nil {
+				return fmt.Errorf("recording sale of variant %s: %w", line.VariantID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range movements {
+		s.variantChanged(ctx, m)
+	}
+	return len(movements), nil
+}
+
+// RestockOrder returns the stock committed to orderID at checkout, recording
+// a return movement per line, and releases the reservations so a second call
+// restocks nothing. It returns the number of reservations restocked.
+func (s *Service) RestockOrder(ctx context.Context, orderID uuid.UUID, c Change) (int, error) {
+	if c.MovementType == "" {
+		c.MovementType = MovementReturn
+	}
+	if !ValidMovementType(c.MovementType) {
+		return 0, ErrInvalidMovementType
+	}
+	orderRef := pgtype.UUID{Bytes: orderID, Valid: true}
+	c.ReferenceType = ReferenceOrder
+	c.ReferenceID = orderRef
+
+	var movements []db.StockMovement
+	err := s.inTx(ctx, func(q *db.Queries) error {
+		reservations, err := q.ListCommittedOrderReservationsForUpdate(ctx, orderRef)
+		if err != nil {
+			return fmt.Errorf("listing reservations for order %s: %w", orderID, err)
+		}
+
+		for _, r := range reservations {
+			qty := r.Quantity
+			m, err := changeVariantStock(ctx, q, r.VariantID, c, func(before int32) int32 { return before + qty })
+			if err != nil {
+				return fmt.Errorf("restocking reservation %s: %w", r.ID, err)
+			}
+			movements = append(movements, m)
+
+			if err := q.MarkReservationReleased(ctx, r.ID); err != nil {
+				return fmt.Errorf("releasing reservation %s: %w", r.ID, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range movements {
+		s.variantChanged(ctx, m)
+	}
+	return len(movements), nil
+}
+
+// CommittedOrderUnits returns how many units of each variant are still
+// committed to orderID: sold at checkout and not yet returned to stock.
+func (s *Service) CommittedOrderUnits(ctx context.Context, orderID uuid.UUID) (map[uuid.UUID]int32, error) {
+	reservations, err := s.queries.ListCommittedOrderReservationsForUpdate(ctx, pgtype.UUID{Bytes: orderID, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("listing reservations for order %s: %w", orderID, err)
+	}
+	units := make(map[uuid.UUID]int32, len(reservations))
+	for _, r := range reservations {
+		units[r.VariantID] += r.Quantity
+	}
+	return units, nil
+}
+
+// RestockOrderLines returns some of the units committed to orderID to stock,
+// such as those of a refund, recording a return movement per line. The units
+// are taken off the order's committed reservations so that a later
+// RestockOrder does not return them again; units no longer committed to the
+// order are skipped. It returns the number of units restocked.
+func (s *Service) RestockOrderLines(ctx context.Context, orderID uuid.UUID, lines []ReservationLine, c Change) (int32, error) {
+	if c.MovementType == "" {
+		c.MovementType = MovementReturn
+	}
+	if !ValidMovementType(c.MovementType) {
+		return 0, ErrInvalidMovementType
+	}
+	orderRef := pgtype.UUID{Bytes: orderID, Valid: true}
+	c.ReferenceType = ReferenceOrder
+	c.ReferenceID = orderRef
+
+	sorted := append([]ReservationLine(nil), lines...)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].VariantID.String() < sorted[j].VariantID.String()
+	})
+
+	var (
+		movements []db.StockMovement
+		restocked int32
+	)
+	err := s.inTx(ctx, func(q *db.Queries) error {
+		reservations, err := q.ListCommittedOrderReservationsForUpdate(ctx, orderRef)
+		if err != nil {
+			return fmt.Errorf("listing reservations for order %s: %w", orderID, err)
+		}
+
+		for _, line := range sorted {
+			var qty int32
+			for i := range reservations {
+				r := &reservations[i]
+				want := line.Quantity - qty
+				if want <= 0 {
+					break
+				}
+				if r.VariantID != line.VariantID || r.Quantity == 0 {
+					continue
+				}
+				take := min(r.Quantity, want)
+				if take == r.Quantity {
+					err = q.MarkReservationReleased(ctx, r.ID)
+				} else {
+					err = q.DecrementReservation(ctx, db.DecrementReservationParams{ID: r.ID, Quantity: take})
+				}
+				if err != nil {
+					return fmt.Errorf("releasing reservation %s: %w", r.ID, err)
+				}
+				r.Quantity -= take
+				qty += take
+			}
+			if qty == 0 {
+				continue
+			}
+
+			m, err := changeVariantStock(ctx, q, line.VariantID, c, func(before int32) int32 { return before + qty })
+			if err != nil {
+				return fmt.Errorf("restocking variant %s: %w", line.VariantID, err)
+			}
+			movements = append(movements, m)
+			restocked += qty
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	for _, m := range movements {
+		s.variantChanged(ctx, m)
+	}
+	return restocked, nil
+}
@@ -0,0 +1,295 @@
+package inventory_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/forgecommerce/api/internal/services/inventory"
+	"github.com/forgecommerce/api/internal/services/order"
+)
+
+func fixtureCart(t *testing.T) uuid.UUID {
+	t.Helper()
+	id := uuid.New()
+	if _, err := testDB.Pool.Exec(context.Background(), `INSERT INTO carts (id) VALUES ($1)`, id); err != nil {
+		t.Fatalf("creating cart: %v", err)
+	}
+	return id
+}
+
+func fixtureOrder(t *testing.T) uuid.UUID {
+	t.Helper()
+	zero := pgtype.Numeric{Int: big.NewInt(0), Valid: true}
+	o, _, err := order.NewService(testDB.Pool, nil, nil).Create(context.Background(), order.CreateOrderParams{
+		Status:            "pending",
+		Email:             "buyer@example.com",
+		PaymentStatus:     "paid",
+		BillingAddress:    json.RawMessage(`{}`),
+		ShippingAddress:   json.RawMessage(`{}`),
+		Subtotal:          zero,
+		ShippingFee:       zero,
+		ShippingExtraFees: zero,
+		DiscountAmount:    zero,
+		VatTotal:          zero,
+		Total:             zero,
+		Metadata:          json.RawMessage(`{}`),
+	})
+	if err != nil {
+		t.Fatalf("creating order: %v", err)
+	}
+	return o.ID
+}
+
+func variantStock(t *testing.T, id uuid.UUID) int32 {
+	t.Helper()
+	var stock int32
+	if err := testDB.Pool.QueryRow(context.Background(),
+		`SELECT stock_quantity FROM product_variants WHERE id = $1`, id).Scan(&stock); err != nil {
+		t.Fatalf("reading stock: %v", err)
+	}
+	return stock
+}
+
+func TestReserveCart_CountsOtherCarts(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Wallet", "wallet")
+	variant := testDB.FixtureVariant(t, product.ID, "WAL-1", 5)
+	cartA := fixtureCart(t)
+	cartB := fixtureCart(t)
+	expires := time.Now().Add(time.Hour)
+
+	if err := svc.ReserveCart(ctx, cartA, []inventory.ReservationLine{{VariantID: variant.ID, Quantity: 3}}, expires); err != nil {
+		t.Fatalf("ReserveCart A: %v", err)
+	}
+
+	// Re-reserving the same cart replaces its previous hold.
+	if err := svc.ReserveCart(ctx, cartA, []inventory.ReservationLine{{VariantID: variant.ID, Quantity: 4}}, expires); err != nil {
+		t.Fatalf("ReserveCart A again: %v", err)
+	}
+
+	err := svc.ReserveCart(ctx, cartB, []inventory.ReservationLine{{VariantID: variant.ID, Quantity: 2}}, expires)
+	var shortage *inventory.ShortageError
+	if !errors.As(err, &shortage) {
+		t.Fatalf("expected ShortageError, got %v", err)
+	}
+	if !errors.Is(err, inventory.ErrInsufficientStock) {
+		t.Error("ShortageError should match ErrInsufficientStock")
+	}
+	if len(shortage.Shortages) != 1 || shortage.Shortages[0].Available != 1 || shortage.Shortages[0].SKU != "WAL-1" {
+		t.Errorf("unexpected shortages: %+v", shortage.Shortages)
+	}
+
+	// Once cart A lets go, cart B fits.
+	if err := svc.ReleaseCart(ctx, cartA); err != nil {
+		t.Fatalf("ReleaseCart: %v", err)
+	}
+	if err := svc.ReserveCart(ctx, cartB, []inventory.ReservationLine{{VariantID: variant.ID, Quantity: 2}}, expires); err != nil {
+		t.Fatalf("ReserveCart B after release: %v", err)
+	}
+}
+
+func TestReserveCart_IgnoresExpiredReservations(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Strap", "strap")
+	variant := testDB.FixtureVariant(t, product.ID, "STR-1", 2)
+	cartA := fixtureCart(t)
+	cartB := fixtureCart(t)
+
+	if err := svc.ReserveCart(ctx, cartA, []inventory.ReservationLine{{VariantID: variant.ID, Quantity: 2}}, time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("ReserveCart A: %v", err)
+	}
+	if err := svc.ReserveCart(ctx, cartB, []inventory.ReservationLine{{VariantID: variant.ID, Quantity: 2}}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("expired reservation should not block: %v", err)
+	}
+}
+
+func TestReserveCart_Backorder(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Satchel", "satchel")
+	if _, err := testDB.Pool.Exec(ctx, `UPDATE products SET allow_backorder = true WHERE id = $1`, product.ID); err != nil {
+		t.Fatalf("enabling backorder: %v", err)
+	}
+	variant := testDB.FixtureVariant(t, product.ID, "SAT-1", 1)
+	cart := fixtureCart(t)
+
+	if err := svc.ReserveCart(ctx, cart, []inventory.ReservationLine{{VariantID: variant.ID, Quantity: 3}}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("backorder product should reserve: %v", err)
+	}
+}
+
+func TestCommitCheckoutSession_RecordsSales(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Card Holder", "card-holder")
+	variant := testDB.FixtureVariant(t, product.ID, "CH-1", 10)
+	cart := fixtureCart(t)
+	orderID := fixtureOrder(t)
+
+	if err := svc.ReserveCart(ctx, cart, []inventory.ReservationLine{{VariantID: variant.ID, Quantity: 4}}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("ReserveCart: %v", err)
+	}
+	if err := svc.AttachCheckoutSession(ctx, cart, "cs_test_123"); err != nil {
+		t.Fatalf("AttachCheckoutSession: %v", err)
+	}
+
+	n, err := svc.CommitCheckoutSession(ctx, "cs_test_123", orderID)
+	if err != nil {
+		t.Fatalf("CommitCheckoutSession: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("committed: got %d, want 1", n)
+	}
+	if got := variantStock(t, variant.ID); got != 6 {
+		t.Errorf("stock: got %d, want 6", got)
+	}
+
+	movements, _, err := svc.ListMovements(ctx, inventory.EntityVariant, variant.ID, 1, 10)
+	if err != nil {
+		t.Fatalf("ListMovements: %v", err)
+	}
+	if len(movements) == 0 || movements[0].MovementType != inventory.MovementSale {
+		t.Fatalf("expected a sale movement, got %+v", movements)
+	}
+	if uuid.UUID(movements[0].ReferenceID.Bytes) != orderID {
+		t.Error("sale movement should reference the order")
+	}
+
+	// A redelivered webhook must not decrement stock twice.
+	n, err = svc.CommitCheckoutSession(ctx, "cs_test_123", orderID)
+	if err != nil {
+		t.Fatalf("second CommitCheckoutSession: %v", err)
+	}
+	if n != 0 {
+		t.Errorf("second commit: got %d, want 0", n)
+	}
+	if got := variantStock(t, variant.ID); got != 6 {
+		t.Errorf("stock after second commit: got %d, want 6", got)
+	}
+}
+
+func TestRestockOrder_AfterCartPurged(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Satchel", "satchel")
+	variant := testDB.FixtureVariant(t, product.ID, "SA-1", 5)
+	cart := fixtureCart(t)
+	orderID := fixtureOrder(t)
+
+	if err := svc.ReserveCart(ctx, cart, []inventory.ReservationLine{{VariantID: variant.ID, Quantity: 2}}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("ReserveCart: %v", err)
+	}
+	if err := svc.AttachCheckoutSession(ctx, cart, "cs_test_purged"); err != nil {
+		t.Fatalf("AttachCheckoutSession: %v", err)
+	}
+	if _, err := svc.CommitCheckoutSession(ctx, "cs_test_purged", orderID); err != nil {
+		t.Fatalf("CommitCheckoutSession: %v", err)
+	}
+
+	// Expired carts are purged; the order's committed stock must survive.
+	if _, err := testDB.Pool.Exec(ctx, `DELETE FROM carts WHERE id = $1`, cart); err != nil {
+		t.Fatalf("deleting cart: %v", err)
+	}
+
+	n, err := svc.RestockOrder(ctx, orderID, inventory.Change{})
+	if err != nil {
+		t.Fatalf("RestockOrder: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("restocked: got %d, want 1", n)
+	}
+	if got := variantStock(t, variant.ID); got != 5 {
+		t.Errorf("stock after restock: got %d, want 5", got)
+	}
+}
+
+func TestCommitCartLines_RestocksLikeReservations(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Belt", "belt")
+	variant := testDB.FixtureVariant(t, product.ID, "BL-1", 5)
+	cart := fixtureCart(t)
+	orderID := fixtureOrder(t)
+
+	// The session's reservation was released before the payment arrived.
+	if err := svc.ReserveCart(ctx, cart, []inventory.ReservationLine{{VariantID: variant.ID, Quantity: 2}}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("ReserveCart: %v", err)
+	}
+	if err := svc.AttachCheckoutSession(ctx, cart, "cs_test_lost"); err != nil {
+		t.Fatalf("AttachCheckoutSession: %v", err)
+	}
+	if err := svc.ReleaseCheckoutSession(ctx, "cs_test_lost"); err != nil {
+		t.Fatalf("ReleaseCheckoutSession: %v", err)
+	}
+	if n, err := svc.CommitCheckoutSession(ctx, "cs_test_lost", orderID); err != nil || n != 0 {
+		t.Fatalf("CommitCheckoutSession: got %d, %v; want 0, nil", n, err)
+	}
+
+	n, err := svc.CommitCartLines(ctx, cart, "cs_test_lost", orderID, []inventory.ReservationLine{{VariantID: variant.ID, Quantity: 2}})
+	if err != nil {
+		t.Fatalf("CommitCartLines: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("committed: got %d, want 1", n)
+	}
+	if got := variantStock(t, variant.ID); got != 3 {
+		t.Errorf("stock: got %d, want 3", got)
+	}
+
+	n, err = svc.RestockOrder(ctx, orderID, inventory.Change{})
+	if err != nil {
+		t.Fatalf("RestockOrder: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("restocked: got %d, want 1", n)
+	}
+	if got := variantStock(t, variant.ID); got != 5 {
+		t.Errorf("stock after restock: got %d, want 5", got)
+	}
+}
+
+func TestReleaseCheckoutSession(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Keyring", "keyring")
+	variant := testDB.FixtureVariant(t, product.ID, "KR-1", 1)
+	cartA := fixtureCart(t)
+	cartB := fixtureCart(t)
+	expires := time.Now().Add(time.Hour)
+
+	if err := svc.ReserveCart(ctx, cartA, []inventory.ReservationLine{{VariantID: variant.ID, Quantity: 1}}, expires); err != nil {
+		t.Fatalf("ReserveCart: %v", err)
+	}
+	if err := svc.AttachCheckoutSession(ctx, cartA, "cs_test_expired"); err != nil {
+		t.Fatalf("AttachCheckoutSession: %v", err)
+	}
+	if err := svc.ReleaseCheckoutSession(ctx, "cs_test_expired"); err != nil {
+		t.Fatalf("ReleaseCheckoutSession: %v", err)
+	}
+	if err := svc.ReserveCart(ctx, cartB, []inventory.ReservationLine{{VariantID: variant.ID, Quantity: 1}}, expires); err != nil {
+		t.Fatalf("released stock should be available: %v", err)
+	}
+}
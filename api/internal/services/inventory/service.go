@@ -0,0 +1,419 @@
+// Package inventory is the single entry point for changing stock levels.
+//
+// Every change to a product variant's or raw material's stock quantity goes
+// through this package so that a stock_movements row is written alongside it,
+// recording the quantity before and after, the unit cost, what caused the
+// change (order, production batch, manual adjustment) and who made it.
+package inventory
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/webhook"
+)
+
+// Entity types stored in stock_movements.entity_type.
+const (
+	EntityVariant     = "product_variant"
+	EntityRawMaterial = "raw_material"
+)
+
+// Movement types stored in stock_movements.movement_type.
+const (
+	MovementPurchase          = "purchase"
+	MovementSale              = "sale"
+	MovementAdjustment        = "adjustment"
+	MovementProductionConsume = "production_consume"
+	MovementProductionOutput  = "production_output"
+	MovementReturn            = "return"
+	MovementDamage            = "damage"
+)
+
+// Reference types stored in stock_movements.reference_type.
+const (
+	ReferenceOrder           = "order"
+	ReferenceProductionBatch = "production_batch"
+	ReferenceManual          = "manual"
+)
+
+var (
+	// ErrNotFound is returned when the variant or raw material does not exist.
+	ErrNotFound = errors.New("stock item not found")
+
+	// ErrInsufficientStock is returned when a change would take stock below
+	// zero and the change does not allow negative stock.
+	ErrInsufficientStock = errors.New("insufficient stock")
+
+	// ErrInvalidMovementType is returned for an unknown movement type.
+	ErrInvalidMovementType = errors.New("invalid movement type")
+)
+
+// validMovementTypes mirrors the CHECK constraint on stock_movements.movement_type.
+var validMovementTypes = map[string]bool{
+	MovementPurchase:          true,
+	MovementSale:              true,
+	MovementAdjustment:        true,
+	MovementProductionConsume: true,
+	MovementProductionOutput:  true,
+	MovementReturn:            true,
+	MovementDamage:            true,
+}
+
+// ValidMovementType reports whether t is a known movement type.
+func ValidMovementType(t string) bool {
+	return validMovementTypes[t]
+}
+
+// Change describes why a stock level is changing. It is recorded verbatim on
+// the resulting stock movement.
+type Change struct {
+	MovementType  string
+	ReferenceType string      // optional, e.g. ReferenceOrder
+	ReferenceID   pgtype.UUID // optional
+	UnitCost      pgtype.Numeric
+	Notes         string
+	CreatedBy     pgtype.UUID // admin user, if any
+
+	// AllowNegative permits the resulting quantity to drop below zero
+	// (e.g. backorders). Otherwise ErrInsufficientStock is returned.
+	AllowNegative bool
+}
+
+// Service applies stock changes and records them in the stock ledger.
+type Service struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+	tx      pgx.Tx
+	events  webhook.Publisher
+	logger  *slog.Logger
+
+	// pending holds the low-stock events of a service bound to a caller's
+	// transaction until the caller has committed it.
+	pending []webhook.VariantStockLowData
+}
+
+// NewService creates a new inventory service. Variants dropping to their
+// low-stock threshold are published to events; pass nil to run without
+// webhooks.
+func NewService(pool *pgxpool.Pool, events webhook.Publisher, logger *slog.Logger) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if events == nil {
+		events = webhook.Discard
+	}
+	return &Service{
+		pool:    pool,
+		queries: db.New(pool),
+		events:  events,
+		logger:  logger,
+	}
+}
+
+// WithTx returns a copy of the service whose operations run inside tx
+// instead of opening their own transaction. The caller commits or rolls back,
+// and after a commit calls PublishPending to send the events held back
+// meanwhile.
+func (s *Service) WithTx(tx pgx.Tx) *Service {
+	return &Service{
+		pool:    s.pool,
+		queries: s.queries.WithTx(tx),
+		tx:      tx,
+		events:  s.events,
+		logger:  s.logger,
+	}
+}
+
+// PublishPending publishes the events a service returned by WithTx held back
+// until its transaction committed. A rolled-back service is simply dropped.
+func (s *Service) PublishPending(ctx context.Context) {
+	for _, data := range s.pending {
+		s.events.Publish(ctx, webhook.EventVariantStockLow, data)
+	}
+	s.pending = nil
+}
+
+// inTx runs fn inside the service's transaction, or a new one when the
+// service is not bound to a transaction.
+func (s *Service) inTx(ctx context.Context, fn func(q *db.Queries) error) error {
+	if s.tx != nil {
+		return fn(s.queries)
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(s.queries.WithTx(tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+// AdjustVariant changes a variant's stock by delta (positive or negative)
+// and records the movement.
+func (s *Service) AdjustVariant(ctx context.Context, variantID uuid.UUID, delta int32, c Change) (db.StockMovement, error) {
+	return s.changeVariant(ctx, variantID, c, func(before int32) int32 { return before + delta })
+}
+
+// SetVariantStock sets a variant's stock to an absolute quantity, recording
+// the difference as a movement. No movement is written when the quantity is
+// unchanged; the returned movement then has a nil ID.
+func (s *Service) SetVariantStock(ctx context.Context, variantID uuid.UUID, quantity int32, c Change) (db.StockMovement, error) {
+	return s.changeVariant(ctx, variantID, c, func(int32) int32 { return quantity })
+}
+
+func (s *Service) changeVariant(ctx context.Context, variantID uuid.UUID, c Change, apply func(before int32) int32) (db.StockMovement, error) {
+	if !ValidMovementType(c.MovementType) {
+		return db.StockMovement{}, fmt.Errorf("%w: %q", ErrInvalidMovementType, c.MovementType)
+	}
+
+	var movement db.StockMovement
+	err := s.inTx(ctx, func(q *db.Queries) error {
+		var err error
+		movement, err = changeVariantStock(ctx, q, variantID, c, apply)
+		return err
+	})
+	if err != nil {
+		return db.StockMovement{}, err
+	}
+
+	s.variantChanged(ctx, movement)
+	return movement, nil
+}
+
+// changeVariantStock locks the variant, applies the change and records the
+// movement using q, which must be bound to a transaction.
+func changeVariantStock(ctx context.Context, q *db.Queries, variantID uuid.UUID, c Change, apply func(before int32) int32) (db.StockMovement, error) {
+	v, err := q.GetProductVariantForUpdate(ctx, variantID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.StockMovement{}, ErrNotFound
+		}
+		return db.StockMovement{}, fmt.Errorf("locking variant %s: %w", variantID, err)
+	}
+
+	before := v.StockQuantity
+	after := apply(before)
+	if after == before {
+		return db.StockMovement{}, nil
+	}
+	if after < 0 && !c.AllowNegative {
+		return db.StockMovement{}, fmt.Errorf("%w: %s has %d, change of %d requested",
+			ErrInsufficientStock, v.Sku, before, after-before)
+	}
+
+	if err := q.UpdateVariantStock(ctx, db.UpdateVariantStockParams{
+		ID:            variantID,
+		StockQuantity: after,
+	}); err != nil {
+		return db.StockMovement{}, fmt.Errorf("updating stock for variant %s: %w", variantID, err)
+	}
+
+	return recordMovement(ctx, q, EntityVariant, variantID,
+		decimal.NewFromInt32(before), decimal.NewFromInt32(after), c)
+}
+
+// variantChanged logs a variant stock movement and publishes
+// variant.stock_low when it took stock from above the variant's low-stock
+// threshold to at or below it. Inside a caller's transaction the event waits
+// for PublishPending, so a rollback publishes nothing.
+func (s *Service) variantChanged(ctx context.Context, m db.StockMovement) {
+	if m.ID == uuid.Nil {
+		return
+	}
+	s.logger.Info("variant stock changed",
+		slog.String("variant_id", m.EntityID.String()),
+		slog.String("movement_type", m.MovementType),
+		slog.String("quantity_after", numericToDecimal(m.QuantityAfter).String()),
+	)
+
+	before := numericToDecimal(m.QuantityBefore).IntPart()
+	after := numericToDecimal(m.QuantityAfter).IntPart()
+	if after >= before {
+		return
+	}
+
+	v, err := s.queries.GetProductVariant(ctx, m.EntityID)
+	if err != nil {
+		s.logger.Warn("loading variant for low-stock check",
+			slog.String("variant_id", m.EntityID.String()),
+			slog.String("error", err.Error()),
+		)
+		return
+	}
+	threshold := int64(v.LowStockThreshold)
+	if before <= threshold || after > threshold {
+		return
+	}
+	data := webhook.VariantStockLowData{
+		VariantID:         v.ID,
+		ProductID:         v.ProductID,
+		SKU:               v.Sku,
+		StockQuantity:     v.StockQuantity,
+		LowStockThreshold: v.LowStockThreshold,
+	}
+	if s.tx != nil {
+		s.pending = append(s.pending, data)
+		return
+	}
+	s.events.Publish(ctx, webhook.EventVariantStockLow, data)
+}
+
+// AdjustRawMaterial changes a raw material's stock by delta (positive or
+// negative) and records the movement.
+func (s *Service) AdjustRawMaterial(ctx context.Context, materialID uuid.UUID, delta decimal.Decimal, c Change) (db.StockMovement, error) {
+	return s.changeRawMaterial(ctx, materialID, c, func(before decimal.Decimal) decimal.Decimal { return before.Add(delta) })
+}
+
+// SetRawMaterialStock sets a raw material's stock to an absolute quantity,
+// recording the difference as a movement. No movement is written when the
+// quantity is unchanged; the returned movement then has a nil ID.
+func (s *Service) SetRawMaterialStock(ctx context.Context, materialID uuid.UUID, quantity decimal.Decimal, c Change) (db.StockMovement, error) {
+	return s.changeRawMaterial(ctx, materialID, c, func(decimal.Decimal) decimal.Decimal { return quantity })
+}
+
+func (s *Service) changeRawMaterial(ctx context.Context, materialID uuid.UUID, c Change, apply func(before decimal.Decimal) decimal.Decimal) (db.StockMovement, error) {
+	if !ValidMovementType(c.MovementType) {
+		return db.StockMovement{}, fmt.Errorf("%w: %q", ErrInvalidMovementType, c.MovementType)
+	}
+
+	var movement db.StockMovement
+	err := s.inTx(ctx, func(q *db.Queries) error {
+		m, err := q.GetRawMaterialForUpdate(ctx, materialID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrNotFound
+			}
+			return fmt.Errorf("locking raw material %s: %w", materialID, err)
+		}
+
+		before := numericToDecimal(m.StockQuantity)
+		after := apply(before)
+		if after.Equal(before) {
+			return nil
+		}
+		if after.IsNegative() && !c.AllowNegative {
+			return fmt.Errorf("%w: %s has %s, change of %s requested",
+				ErrInsufficientStock, m.Sku, before.String(), after.Sub(before).String())
+		}
+
+		if err := q.UpdateRawMaterialStock(ctx, db.UpdateRawMaterialStockParams{
+			ID:            materialID,
+			StockQuantity: decimalToNumeric(after),
+		}); err != nil {
+			return fmt.Errorf("updating stock for raw material %s: %w", materialID, err)
+		}
+
+		// Default the unit cost to the material's current cost so purchases
+		// and consumption can be valued later.
+		if !c.UnitCost.Valid {
+			c.UnitCost = m.CostPerUnit
+		}
+
+		movement, err = recordMovement(ctx, q, EntityRawMaterial, materialID, before, after, c)
+		return err
+	})
+	if err != nil {
+		return db.StockMovement{}, err
+	}
+
+	if movement.ID != uuid.Nil {
+		s.logger.Info("raw material stock changed",
+			slog.String("raw_material_id", materialID.String()),
+			slog.String("movement_type", c.MovementType),
+			slog.String("quantity_after", numericToDecimal(movement.QuantityAfter).String()),
+		)
+	}
+	return movement, nil
+}
+
+// recordMovement writes a single stock_movements row.
+func recordMovement(ctx context.Context, q *db.Queries, entityType string, entityID uuid.UUID, before, after decimal.Decimal, c Change) (db.StockMovement, error) {
+	movement, err := q.CreateStockMovement(ctx, db.CreateStockMovementParams{
+		ID:             uuid.New(),
+		EntityType:     entityType,
+		EntityID:       entityID,
+		MovementType:   c.MovementType,
+		QuantityChange: decimalToNumeric(after.Sub(before)),
+		QuantityBefore: decimalToNumeric(before),
+		QuantityAfter:  decimalToNumeric(after),
+		ReferenceType:  strPtr(c.ReferenceType),
+		ReferenceID:    c.ReferenceID,
+		UnitCost:       c.UnitCost,
+		Notes:          strPtr(c.Notes),
+		CreatedBy:      c.CreatedBy,
+		CreatedAt:      time.Now().UTC(),
+	})
+	if err != nil {
+		return db.StockMovement{}, fmt.Errorf("recording %s movement for %s %s: %w", c.MovementType, entityType, entityID, err)
+	}
+	return movement, nil
+}
+
+// ListMovements returns a page of stock movements for a variant or raw
+// material, newest first, together with the total count.
+func (s *Service) ListMovements(ctx context.Context, entityType string, entityID uuid.UUID, page, pageSize int) ([]db.ListStockMovementsRow, int64, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize < 1 {
+		pageSize = 50
+	}
+
+	movements, err := s.queries.ListStockMovements(ctx, db.ListStockMovementsParams{
+		EntityType: entityType,
+		EntityID:   entityID,
+		Limit:      int32(pageSize),
+		Offset:     int32((page - 1) * pageSize),
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("listing stock movements for %s %s: %w", entityType, entityID, err)
+	}
+
+	total, err := s.queries.CountStockMovements(ctx, db.CountStockMovementsParams{
+		EntityType: entityType,
+		EntityID:   entityID,
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("counting stock movements for %s %s: %w", entityType, entityID, err)
+	}
+
+	return movements, total, nil
+}
+
+// --- helpers ---
+
+func numericToDecimal(n pgtype.Numeric) decimal.Decimal {
+	if !n.Valid || n.Int == nil {
+		return decimal.Zero
+	}
+	return decimal.NewFromBigInt(n.Int, n.Exp)
+}
+
+func decimalToNumeric(d decimal.Decimal) pgtype.Numeric {
+	return pgtype.Numeric{Int: d.Coefficient(), Exp: d.Exponent(), Valid: true}
+}
+
+func strPtr(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
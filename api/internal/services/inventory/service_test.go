@@ -0,0 +1,343 @@
+package inventory_test
+
+import (
+	"context"
+	"errors"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/inventory"
+	"github.com/forgecommerce/api/internal/services/webhook"
+	"github.com/forgecommerce/api/internal/testutil"
+)
+
+var testDB *testutil.TestDB
+
+func TestMain(m *testing.M) {
+	var code int
+	defer func() { os.Exit(code) }()
+
+	db, err := testutil.SetupTestDB()
+	if err != nil {
+		log.Fatalf("setting up test database: %v", err)
+	}
+	defer db.Close()
+	testDB = db
+
+	code = m.Run()
+}
+
+func newService() *inventory.Service {
+	return inventory.NewService(testDB.Pool, nil, nil)
+}
+
+func numericString(n pgtype.Numeric) string {
+	return decimal.NewFromBigInt(n.Int, n.Exp).String()
+}
+
+// --------------------------------------------------------------------------
+// Variants
+// --------------------------------------------------------------------------
+
+func TestAdjustVariant_RecordsMovement(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Belt", "belt")
+	variant := testDB.FixtureVariant(t, product.ID, "BELT-1", 10)
+	orderID := uuid.New()
+
+	movement, err := svc.AdjustVariant(ctx, variant.ID, -3, inventory.Change{
+		MovementType:  inventory.MovementSale,
+		ReferenceType: inventory.ReferenceOrder,
+		ReferenceID:   pgtype.UUID{Bytes: orderID, Valid: true},
+	})
+	if err != nil {
+		t.Fatalf("AdjustVariant: %v", err)
+	}
+
+	if got := numericString(movement.QuantityChange); got != "-3" {
+		t.Errorf("quantity change: got %s, want -3", got)
+	}
+	if got := numericString(movement.QuantityBefore); got != "10" {
+		t.Errorf("quantity before: got %s, want 10", got)
+	}
+	if got := numericString(movement.QuantityAfter); got != "7" {
+		t.Errorf("quantity after: got %s, want 7", got)
+	}
+	if movement.ReferenceType == nil || *movement.ReferenceType != inventory.ReferenceOrder {
+		t.Errorf("reference type: got %v, want order", movement.ReferenceType)
+	}
+	if uuid.UUID(movement.ReferenceID.Bytes) != orderID {
+		t.Error("reference ID mismatch")
+	}
+
+	v, _ := db.New(testDB.Pool).GetProductVariant(ctx, variant.ID)
+	if v.StockQuantity != 7 {
+		t.Errorf("variant stock: got %d, want 7", v.StockQuantity)
+	}
+}
+
+func TestAdjustVariant_InsufficientStock(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Belt", "belt")
+	variant := testDB.FixtureVariant(t, product.ID, "BELT-1", 2)
+
+	_, err := svc.AdjustVariant(ctx, variant.ID, -5, inventory.Change{MovementType: inventory.MovementDamage})
+	if !errors.Is(err, inventory.ErrInsufficientStock) {
+		t.Fatalf("expected ErrInsufficientStock, got %v", err)
+	}
+
+	movements, total, err := svc.ListMovements(ctx, inventory.EntityVariant, variant.ID, 1, 10)
+	if err != nil {
+		t.Fatalf("ListMovements: %v", err)
+	}
+	if total != 0 || len(movements) != 0 {
+		t.Errorf("expected no movements, got %d", total)
+	}
+
+	// Backorders may take stock negative when explicitly allowed.
+	if _, err := svc.AdjustVariant(ctx, variant.ID, -5, inventory.Change{
+		MovementType:  inventory.MovementSale,
+		AllowNegative: true,
+	}); err != nil {
+		t.Fatalf("AdjustVariant with AllowNegative: %v", err)
+	}
+	v, _ := db.New(testDB.Pool).GetProductVariant(ctx, variant.ID)
+	if v.StockQuantity != -3 {
+		t.Errorf("variant stock: got %d, want -3", v.StockQuantity)
+	}
+}
+
+func TestSetVariantStock_Unchanged(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Belt", "belt")
+	variant := testDB.FixtureVariant(t, product.ID, "BELT-1", 4)
+
+	movement, err := svc.SetVariantStock(ctx, variant.ID, 4, inventory.Change{MovementType: inventory.MovementAdjustment})
+	if err != nil {
+		t.Fatalf("SetVariantStock: %v", err)
+	}
+	if movement.ID != uuid.Nil {
+		t.Error("expected no movement for unchanged quantity")
+	}
+}
+
+func TestAdjustVariant_Errors(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	_, err := svc.AdjustVariant(ctx, uuid.New(), 1, inventory.Change{MovementType: inventory.MovementPurchase})
+	if !errors.Is(err, inventory.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+
+	_, err = svc.AdjustVariant(ctx, uuid.New(), 1, inventory.Change{MovementType: "gift"})
+	if !errors.Is(err, inventory.ErrInvalidMovementType) {
+		t.Errorf("expected ErrInvalidMovementType, got %v", err)
+	}
+}
+
+// --------------------------------------------------------------------------
+// Raw materials
+// --------------------------------------------------------------------------
+
+func TestAdjustRawMaterial_DefaultsUnitCost(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	material := testDB.FixtureRawMaterial(t, "Leather", "LTH-001") // 100 in stock at 5.00
+
+	movement, err := svc.AdjustRawMaterial(ctx, material.ID, decimal.RequireFromString("12.5"), inventory.Change{
+		MovementType: inventory.MovementPurchase,
+		Notes:        "Invoice 42",
+	})
+	if err != nil {
+		t.Fatalf("AdjustRawMaterial: %v", err)
+	}
+	if got := numericString(movement.QuantityAfter); got != "112.5" {
+		t.Errorf("quantity after: got %s, want 112.5", got)
+	}
+	if got := numericString(movement.UnitCost); got != "5" {
+		t.Errorf("unit cost: got %s, want 5", got)
+	}
+
+	m, _ := db.New(testDB.Pool).GetRawMaterial(ctx, material.ID)
+	if got := numericString(m.StockQuantity); got != "112.5" {
+		t.Errorf("material stock: got %s, want 112.5", got)
+	}
+}
+
+// --------------------------------------------------------------------------
+// Transactions and history
+// --------------------------------------------------------------------------
+
+func TestWithTx_RollsBackWithCaller(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	material := testDB.FixtureRawMaterial(t, "Leather", "LTH-001")
+
+	tx, err := testDB.Pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err := svc.WithTx(tx).AdjustRawMaterial(ctx, material.ID, decimal.NewFromInt(-10), inventory.Change{
+		MovementType: inventory.MovementDamage,
+	}); err != nil {
+		t.Fatalf("AdjustRawMaterial: %v", err)
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+
+	m, _ := db.New(testDB.Pool).GetRawMaterial(ctx, material.ID)
+	if got := numericString(m.StockQuantity); got != "100" {
+		t.Errorf("material stock: got %s, want 100", got)
+	}
+	_, total, _ := svc.ListMovements(ctx, inventory.EntityRawMaterial, material.ID, 1, 10)
+	if total != 0 {
+		t.Errorf("expected movement to be rolled back, got %d", total)
+	}
+}
+
+func TestListMovements_Paginates(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Belt", "belt")
+	variant := testDB.FixtureVariant(t, product.ID, "BELT-1", 0)
+
+	for i := 0; i < 3; i++ {
+		if _, err := svc.AdjustVariant(ctx, variant.ID, 1, inventory.Change{MovementType: inventory.MovementPurchase}); err != nil {
+			t.Fatalf("AdjustVariant: %v", err)
+		}
+	}
+
+	movements, total, err := svc.ListMovements(ctx, inventory.EntityVariant, variant.ID, 1, 2)
+	if err != nil {
+		t.Fatalf("ListMovements: %v", err)
+	}
+	if total != 3 {
+		t.Errorf("total: got %d, want 3", total)
+	}
+	if len(movements) != 2 {
+		t.Errorf("page size: got %d, want 2", len(movements))
+	}
+}
+
+// --------------------------------------------------------------------------
+// Low-stock events
+// --------------------------------------------------------------------------
+
+func TestAdjustVariant_PublishesStockLowOnce(t *testing.T) {
+	testDB.Truncate(t)
+	events := &testutil.Events{}
+	svc := inventory.NewService(testDB.Pool, events, nil)
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Pouch", "pouch")
+	variant := testDB.FixtureVariant(t, product.ID, "POU-1", 8)
+	if _, err := testDB.Pool.Exec(ctx, `UPDATE product_variants SET low_stock_threshold = 5 WHERE id = $1`, variant.ID); err != nil {
+		t.Fatalf("setting threshold: %v", err)
+	}
+	sale := inventory.Change{MovementType: inventory.MovementSale}
+
+	// 8 -> 6 stays above the threshold.
+	if _, err := svc.AdjustVariant(ctx, variant.ID, -2, sale); err != nil {
+		t.Fatalf("AdjustVariant: %v", err)
+	}
+	if n := len(events.OfType(webhook.EventVariantStockLow)); n != 0 {
+		t.Fatalf("above threshold: got %d stock_low events", n)
+	}
+
+	// 6 -> 4 crosses it.
+	if _, err := svc.AdjustVariant(ctx, variant.ID, -2, sale); err != nil {
+		t.Fatalf("AdjustVariant: %v", err)
+	}
+	low := events.OfType(webhook.EventVariantStockLow)
+	if len(low) != 1 {
+		t.Fatalf("crossing threshold: got %d stock_low events, want 1", len(low))
+	}
+	data := low[0].(webhook.VariantStockLowData)
+	if data.VariantID != variant.ID || data.SKU != "POU-1" || data.StockQuantity != 4 || data.LowStockThreshold != 5 {
+		t.Errorf("unexpected payload: %+v", data)
+	}
+
+	// 4 -> 3 is already below; no repeat until stock recovers.
+	if _, err := svc.AdjustVariant(ctx, variant.ID, -1, sale); err != nil {
+		t.Fatalf("AdjustVariant: %v", err)
+	}
+	if _, err := svc.AdjustVariant(ctx, variant.ID, 10, inventory.Change{MovementType: inventory.MovementPurchase}); err != nil {
+		t.Fatalf("AdjustVariant restock: %v", err)
+	}
+	if n := len(events.OfType(webhook.EventVariantStockLow)); n != 1 {
+		t.Errorf("got %d stock_low events, want 1", n)
+	}
+}
+
+func TestWithTx_HoldsStockLowUntilCommit(t *testing.T) {
+	testDB.Truncate(t)
+	events := &testutil.Events{}
+	svc := inventory.NewService(testDB.Pool, events, nil)
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Strap", "strap")
+	variant := testDB.FixtureVariant(t, product.ID, "STR-1", 8)
+	if _, err := testDB.Pool.Exec(ctx, `UPDATE product_variants SET low_stock_threshold = 5 WHERE id = $1`, variant.ID); err != nil {
+		t.Fatalf("setting threshold: %v", err)
+	}
+	sale := inventory.Change{MovementType: inventory.MovementSale}
+
+	// A rolled-back change publishes nothing.
+	tx, err := testDB.Pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	if _, err := svc.WithTx(tx).AdjustVariant(ctx, variant.ID, -4, sale); err != nil {
+		t.Fatalf("AdjustVariant: %v", err)
+	}
+	if err := tx.Rollback(ctx); err != nil {
+		t.Fatalf("Rollback: %v", err)
+	}
+	if n := len(events.OfType(webhook.EventVariantStockLow)); n != 0 {
+		t.Fatalf("after rollback: got %d stock_low events", n)
+	}
+
+	// A committed one publishes once the caller asks.
+	tx, err = testDB.Pool.Begin(ctx)
+	if err != nil {
+		t.Fatalf("Begin: %v", err)
+	}
+	inv := svc.WithTx(tx)
+	if _, err := inv.AdjustVariant(ctx, variant.ID, -4, sale); err != nil {
+		t.Fatalf("AdjustVariant: %v", err)
+	}
+	if n := len(events.OfType(webhook.EventVariantStockLow)); n != 0 {
+		t.Fatalf("before commit: got %d stock_low events", n)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	inv.PublishPending(ctx)
+	if n := len(events.OfType(webhook.EventVariantStockLow)); n != 1 {
+		t.Errorf("after commit: got %d stock_low events, want 1", n)
+	}
+}
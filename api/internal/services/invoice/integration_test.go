@@ -0,0 +1,308 @@
+package invoice_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/invoice"
+	"github.com/forgecommerce/api/internal/services/order"
+	"github.com/forgecommerce/api/internal/storage"
+	"github.com/forgecommerce/api/internal/testutil"
+)
+
+var testDB *testutil.TestDB
+
+func TestMain(m *testing.M) {
+	var code int
+	defer func() { os.Exit(code) }()
+
+	db, err := testutil.SetupTestDB()
+	if err != nil {
+		log.Fatalf("setting up test database: %v", err)
+	}
+	defer db.Close()
+	testDB = db
+
+	code = m.Run()
+}
+
+// ---------------------------------------------------------------------------
+// Mock storage — in-memory implementation of storage.Storage
+// ---------------------------------------------------------------------------
+
+type mockStorage struct {
+	mu    sync.Mutex
+	files map[string][]byte
+
+	// Optional error injection.
+	putErr error
+}
+
+func newMockStorage() *mockStorage {
+	return &mockStorage{files: make(map[string][]byte)}
+}
+
+func (m *mockStorage) Put(_ context.Context, key string, body io.Reader, _ string) (string, error) {
+	if m.putErr != nil {
+		return "", m.putErr
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+	m.mu.Lock()
+	m.files[key] = data
+	m.mu.Unlock()
+	return "", nil
+}
+
+func (m *mockStorage) Delete(_ context.Context, key string) error {
+	m.mu.Lock()
+	delete(m.files, key)
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *mockStorage) PresignGet(_ context.Context, key string, expiry time.Duration) (string, error) {
+	return fmt.Sprintf("https://private.example.com/%s?expires=%d", key, int(expiry.Seconds())), nil
+}
+
+var _ storage.Storage = (*mockStorage)(nil)
+
+// setSellerDetails fills in the store details every invoice must show.
+func setSellerDetails(t *testing.T) {
+	t.Helper()
+	if _, err := testDB.Pool.Exec(context.Background(), `
+		UPDATE store_settings SET
+			vat_enabled = true, vat_number = 'DE123456789', vat_country_code = 'DE',
+			store_address = '{"legal_name":"Forge GmbH","line1":"Hauptstraße 1","postal_code":"10115","city":"Berlin"}'`,
+	); err != nil {
+		t.Fatalf("setting store details: %v", err)
+	}
+}
+
+func numericFromCents(cents int64) pgtype.Numeric {
+	return pgtype.Numeric{Int: big.NewInt(cents), Exp: -2, Valid: true}
+}
+
+func createOrder(t *testing.T, paymentStatus string, vatNumber *string) db.Order {
+	t.Helper()
+	zero := numericFromCents(0)
+	o, _, err := order.NewService(testDB.Pool, nil, nil).Create(context.Background(), order.CreateOrderParams{
+		Email:             "buyer@example.com",
+		PaymentStatus:     paymentStatus,
+		BillingAddress:    json.RawMessage(`{"first_name":"Ana","last_name":"García","line1":"Calle Mayor 5","postal_code":"28013","city":"Madrid","country":"ES"}`),
+		ShippingAddress:   json.RawMessage(`{"city":"Madrid"}`),
+		Subtotal:          numericFromCents(4000),
+		ShippingFee:       zero,
+		ShippingExtraFees: zero,
+		DiscountAmount:    zero,
+		VatTotal:          numericFromCents(840),
+		Total:             numericFromCents(4840),
+		VatNumber:         vatNumber,
+		VatReverseCharge:  vatNumber != nil,
+		Metadata:          json.RawMessage(`{}`),
+		Items: []order.CreateOrderItemInput{{
+			ProductName:    "Invoice Product",
+			Quantity:       2,
+			UnitPrice:      numericFromCents(2000),
+			TotalPrice:     numericFromCents(4840),
+			VatRate:        numericFromCents(2100),
+			VatAmount:      numericFromCents(840),
+			NetUnitPrice:   numericFromCents(2000),
+			GrossUnitPrice: numericFromCents(2420),
+			Metadata:       json.RawMessage(`{}`),
+		}},
+	})
+	if err != nil {
+		t.Fatalf("creating order: %v", err)
+	}
+	return o
+}
+
+func TestIssue_SequentialNumbers(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	setSellerDetails(t)
+	store := newMockStorage()
+	svc := invoice.NewService(testDB.Pool, store, nil)
+	ctx := context.Background()
+	year := int32(time.Now().UTC().Year())
+
+	first, err := svc.Issue(ctx, createOrder(t, "paid", nil).ID)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	vatNumber := "ESB12345678"
+	second, err := svc.Issue(ctx, createOrder(t, "paid", &vatNumber).ID)
+	if err != nil {
+		t.Fatalf("Issue second: %v", err)
+	}
+
+	if first.InvoiceNumber != invoice.Number(year, 1) || second.InvoiceNumber != invoice.Number(year, 2) {
+		t.Errorf("numbers: got %s, %s; want %s, %s",
+			first.InvoiceNumber, second.InvoiceNumber, invoice.Number(year, 1), invoice.Number(year, 2))
+	}
+
+	pdf, ok := store.files[second.StorageKey]
+	if !ok {
+		t.Fatalf("invoice PDF not stored at %s", second.StorageKey)
+	}
+	if !bytes.HasPrefix(pdf, []byte("%PDF-")) || !bytes.Contains(pdf, []byte("Reverse charge")) {
+		t.Error("expected a PDF with the reverse charge note")
+	}
+	if bytes.Contains(store.files[first.StorageKey], []byte("Reverse charge")) {
+		t.Error("B2C invoice should not carry the reverse charge note")
+	}
+
+	// Issuing again returns the same invoice.
+	again, err := svc.Issue(ctx, first.OrderID)
+	if err != nil {
+		t.Fatalf("Issue again: %v", err)
+	}
+	if again.ID != first.ID {
+		t.Errorf("re-issue: got invoice %s, want %s", again.InvoiceNumber, first.InvoiceNumber)
+	}
+}
+
+func TestIssue_FailedUploadLeavesNoGap(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	setSellerDetails(t)
+	store := newMockStorage()
+	svc := invoice.NewService(testDB.Pool, store, nil)
+	ctx := context.Background()
+	o := createOrder(t, "paid", nil)
+
+	store.putErr = errors.New("bucket unavailable")
+	if _, err := svc.Issue(ctx, o.ID); err == nil {
+		t.Fatal("expected an error when the upload fails")
+	}
+	if _, err := svc.GetForOrder(ctx, o.ID); !errors.Is(err, invoice.ErrNotFound) {
+		t.Errorf("GetForOrder before upload: got %v, want ErrNotFound", err)
+	}
+
+	store.putErr = nil
+	inv, err := svc.Issue(ctx, o.ID)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	if inv.Sequence != 1 {
+		t.Errorf("sequence: got %d, want 1", inv.Sequence)
+	}
+	if !inv.StoredAt.Valid {
+		t.Error("stored_at not set after the upload")
+	}
+	if _, ok := store.files[inv.StorageKey]; !ok {
+		t.Errorf("PDF not stored under %s", inv.StorageKey)
+	}
+}
+
+func TestIssue_Rejected(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	ctx := context.Background()
+	svc := invoice.NewService(testDB.Pool, newMockStorage(), nil)
+
+	if _, err := testDB.Pool.Exec(ctx, `UPDATE store_settings SET store_address = NULL`); err != nil {
+		t.Fatalf("clearing store address: %v", err)
+	}
+	if _, err := svc.Issue(ctx, createOrder(t, "paid", nil).ID); !errors.Is(err, invoice.ErrSellerDetails) {
+		t.Errorf("missing seller details: got %v, want ErrSellerDetails", err)
+	}
+
+	setSellerDetails(t)
+	if _, err := svc.Issue(ctx, createOrder(t, "unpaid", nil).ID); !errors.Is(err, invoice.ErrNotPaid) {
+		t.Errorf("unpaid order: got %v, want ErrNotPaid", err)
+	}
+
+	zero := numericFromCents(0)
+	noLines, _, err := order.NewService(testDB.Pool, nil, nil).Create(ctx, order.CreateOrderParams{
+		Email:             "nolines@example.com",
+		PaymentStatus:     "paid",
+		BillingAddress:    json.RawMessage(`{}`),
+		ShippingAddress:   json.RawMessage(`{}`),
+		Subtotal:          numericFromCents(1000),
+		ShippingFee:       zero,
+		ShippingExtraFees: zero,
+		DiscountAmount:    zero,
+		VatTotal:          zero,
+		Total:             numericFromCents(1000),
+		Metadata:          json.RawMessage(`{}`),
+	})
+	if err != nil {
+		t.Fatalf("creating order: %v", err)
+	}
+	if _, err := svc.Issue(ctx, noLines.ID); !errors.Is(err, invoice.ErrNoItems) {
+		t.Errorf("order without lines: got %v, want ErrNoItems", err)
+	}
+	var issued int
+	if err := testDB.Pool.QueryRow(ctx, `SELECT COUNT(*) FROM invoices`).Scan(&issued); err != nil {
+		t.Fatalf("counting invoices: %v", err)
+	}
+	if issued != 0 {
+		t.Errorf("rejected orders took %d invoice numbers", issued)
+	}
+}
+
+func TestPublish_IssuesOnPayment(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	setSellerDetails(t)
+	ctx := context.Background()
+	svc := invoice.NewService(testDB.Pool, newMockStorage(), nil)
+
+	o, _, err := order.NewService(testDB.Pool, svc, nil).Create(ctx, order.CreateOrderParams{
+		Email:             "paid@example.com",
+		PaymentStatus:     "paid",
+		BillingAddress:    json.RawMessage(`{}`),
+		ShippingAddress:   json.RawMessage(`{}`),
+		Subtotal:          numericFromCents(1000),
+		ShippingFee:       numericFromCents(0),
+		ShippingExtraFees: numericFromCents(0),
+		DiscountAmount:    numericFromCents(0),
+		VatTotal:          numericFromCents(0),
+		Total:             numericFromCents(1000),
+		Metadata:          json.RawMessage(`{}`),
+		Items: []order.CreateOrderItemInput{{
+			ProductName:    "Zero-rated Book",
+			Quantity:       1,
+			UnitPrice:      numericFromCents(1000),
+			TotalPrice:     numericFromCents(1000),
+			VatRate:        numericFromCents(0),
+			VatAmount:      numericFromCents(0),
+			NetUnitPrice:   numericFromCents(1000),
+			GrossUnitPrice: numericFromCents(1000),
+			Metadata:       json.RawMessage(`{}`),
+		}},
+	})
+	if err != nil {
+		t.Fatalf("creating order: %v", err)
+	}
+
+	inv, err := svc.GetForOrder(ctx, o.ID)
+	if err != nil {
+		t.Fatalf("GetForOrder: %v", err)
+	}
+	url, err := svc.DownloadURL(ctx, inv)
+	if err != nil {
+		t.Fatalf("DownloadURL: %v", err)
+	}
+	if want := fmt.Sprintf("expires=%d", int(invoice.DownloadExpiry.Seconds())); !strings.Contains(url, want) {
+		t.Errorf("download URL %q should expire after %s", url, invoice.DownloadExpiry)
+	}
+}
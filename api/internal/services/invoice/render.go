@@ -0,0 +1,397 @@
+package invoice
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/order"
+)
+
+// reverseChargeNote is printed on invoices for B2B customers in another
+// member state, who account for the VAT themselves.
+const reverseChargeNote = "Reverse charge: intra-Community supply exempt from VAT under Article 138 " +
+	"of Directive 2006/112/EC. VAT is to be accounted for by the customer (Article 196)."
+
+// SellerAddress is the store's postal address printed on invoices. It is
+// kept in store_settings.store_address; the country is the store's VAT
+// country.
+type SellerAddress struct {
+	LegalName  string `json:"legal_name"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2,omitempty"`
+	PostalCode string `json:"postal_code"`
+	City       string `json:"city"`
+}
+
+// ParseSellerAddress decodes store_settings.store_address. An empty column
+// gives an empty address.
+func ParseSellerAddress(raw []byte) (SellerAddress, error) {
+	var a SellerAddress
+	if len(raw) == 0 || string(raw) == "null" {
+		return a, nil
+	}
+	if err := json.Unmarshal(raw, &a); err != nil {
+		return a, fmt.Errorf("decoding store address: %w", err)
+	}
+	return a, nil
+}
+
+// orderAddress is the address format stored on orders.
+type orderAddress struct {
+	FirstName  string `json:"first_name"`
+	LastName   string `json:"last_name"`
+	Company    string `json:"company"`
+	Line1      string `json:"line1"`
+	Line2      string `json:"line2"`
+	City       string `json:"city"`
+	State      string `json:"state"`
+	PostalCode string `json:"postal_code"`
+	Country    string `json:"country"`
+}
+
+// party is the seller or the buyer.
+type party struct {
+	Name      string
+	Lines     []string
+	VATNumber string
+}
+
+type invoiceLine struct {
+	Description string
+	Quantity    int32
+	UnitNet     decimal.Decimal
+	VATRate     decimal.Decimal
+	Net         decimal.Decimal
+	VAT         decimal.Decimal
+}
+
+// vatLine is the taxable amount and VAT at one rate.
+type vatLine struct {
+	Rate decimal.Decimal
+	Net  decimal.Decimal
+	VAT  decimal.Decimal
+}
+
+// document holds everything printed on an invoice.
+type document struct {
+	Number        string
+	IssuedAt      time.Time
+	SuppliedAt    time.Time
+	OrderNumber   int64
+	Currency      string
+	Seller        party
+	Buyer         party
+	Lines         []invoiceLine
+	VAT           []vatLine
+	Subtotal      decimal.Decimal
+	Shipping      decimal.Decimal
+	Discount      decimal.Decimal
+	VATTotal      decimal.Decimal
+	Total         decimal.Decimal
+	ReverseCharge bool
+}
+
+// newDocument builds the invoice for an order from its VAT snapshot.
+// countries maps country codes to names for the addresses.
+func newDocument(number string, issuedAt time.Time, o db.Order, items []db.OrderItem, settings db.StoreSetting, seller SellerAddress, countries map[string]string) document {
+	doc := document{
+		Number:        number,
+		IssuedAt:      issuedAt,
+		SuppliedAt:    o.CreatedAt,
+		OrderNumber:   o.OrderNumber,
+		Currency:      settings.DefaultCurrency,
+		Subtotal:      numericToDecimal(o.Subtotal),
+		Shipping:      numericToDecimal(o.ShippingFee).Add(numericToDecimal(o.ShippingExtraFees)),
+		Total:         numericToDecimal(o.Total),
+		ReverseCharge: o.VatReverseCharge,
+	}
+
+	sellerCountry := derefString(settings.VatCountryCode)
+	doc.Seller = party{
+		Name:      seller.LegalName,
+		VATNumber: derefString(settings.VatNumber),
+	}
+	if doc.Seller.Name == "" {
+		doc.Seller.Name = settings.StoreName
+	}
+	doc.Seller.Lines = nonEmpty(
+		seller.Line1,
+		seller.Line2,
+		strings.TrimSpace(seller.PostalCode+" "+seller.City),
+		countryName(countries, sellerCountry),
+	)
+
+	var billing orderAddress
+	_ = json.Unmarshal(o.BillingAddress, &billing)
+	doc.Buyer = party{
+		Name:      strings.TrimSpace(billing.FirstName + " " + billing.LastName),
+		VATNumber: derefString(o.VatNumber),
+	}
+	company := billing.Company
+	if o.VatCompanyName != nil && *o.VatCompanyName != "" {
+		company = *o.VatCompanyName
+	}
+	if company != "" {
+		doc.Buyer.Lines = append(doc.Buyer.Lines, company)
+	}
+	doc.Buyer.Lines = append(doc.Buyer.Lines, nonEmpty(
+		billing.Line1,
+		billing.Line2,
+		strings.TrimSpace(billing.PostalCode+" "+billing.City),
+		billing.State,
+		countryName(countries, billing.Country),
+	)...)
+	if doc.Buyer.Name == "" {
+		doc.Buyer.Name = o.Email
+	}
+
+	// The order's discount is spread over its lines, so the taxable amount
+	// and VAT at each rate are what was charged. The discount is shown net,
+	// since the subtotal is.
+	vatLines := make([]order.VATLine, len(items))
+	lineVAT := decimal.Zero
+	for i, item := range items {
+		vatLines[i] = order.VATLine{
+			Total: numericToDecimal(item.TotalPrice),
+			Rate:  numericToDecimal(item.VatRate),
+			VAT:   numericToDecimal(item.VatAmount),
+		}
+		lineVAT = lineVAT.Add(vatLines[i].VAT)
+	}
+	discount := numericToDecimal(o.DiscountAmount)
+	discounted := order.DiscountVAT(vatLines, discount)
+
+	byRate := make(map[string]*vatLine)
+	for i, item := range items {
+		qty := decimal.NewFromInt32(item.Quantity)
+		line := invoiceLine{
+			Description: itemDescription(item),
+			Quantity:    item.Quantity,
+			UnitNet:     numericToDecimal(item.NetUnitPrice),
+			VATRate:     numericToDecimal(item.VatRate),
+			VAT:         numericToDecimal(item.VatAmount),
+		}
+		line.Net = line.UnitNet.Mul(qty).Round(2)
+		doc.Lines = append(doc.Lines, line)
+
+		key := line.VATRate.StringFixed(2)
+		v, ok := byRate[key]
+		if !ok {
+			v = &vatLine{Rate: line.VATRate}
+			byRate[key] = v
+		}
+		d := discounted[i]
+		if d.Total.Equal(vatLines[i].Total) {
+			v.Net = v.Net.Add(line.Net)
+		} else {
+			v.Net = v.Net.Add(d.Total.Sub(d.VAT))
+		}
+		v.VAT = v.VAT.Add(d.VAT)
+		doc.VATTotal = doc.VATTotal.Add(d.VAT)
+	}
+	doc.Discount = discount.Sub(lineVAT.Sub(doc.VATTotal))
+	if len(items) == 0 {
+		doc.VATTotal = numericToDecimal(o.VatTotal)
+	}
+	for _, v := range byRate {
+		doc.VAT = append(doc.VAT, *v)
+	}
+	sort.Slice(doc.VAT, func(i, j int) bool { return doc.VAT[i].Rate.GreaterThan(doc.VAT[j].Rate) })
+
+	return doc
+}
+
+// Layout, in points.
+const (
+	marginLeft   = 50.0
+	marginRight  = pageWidth - 50
+	pageBottom   = pageHeight - 60
+	bodySize     = 9.0
+	lineHeight   = 13.0
+	descWidth    = 250.0
+	colQuantity  = 340.0
+	colUnitPrice = 420.0
+	colVATRate   = 470.0
+)
+
+// render lays out doc as a PDF.
+func render(doc document) []byte {
+	pdf := newPDF("Invoice " + doc.Number)
+	y := 60.0
+
+	// Seller, top left.
+	pdf.text(marginLeft, y, 14, true, doc.Seller.Name)
+	sy := y + 16
+	for _, l := range doc.Seller.Lines {
+		pdf.text(marginLeft, sy, bodySize, false, l)
+		sy += lineHeight
+	}
+	if doc.Seller.VATNumber != "" {
+		pdf.text(marginLeft, sy, bodySize, false, "VAT ID: "+doc.Seller.VATNumber)
+		sy += lineHeight
+	}
+
+	// Invoice details, top right.
+	pdf.textRight(marginRight, y, 18, true, "INVOICE")
+	dy := y + 18
+	for _, kv := range [][2]string{
+		{"Invoice number", doc.Number},
+		{"Invoice date", doc.IssuedAt.Format("2006-01-02")},
+		{"Date of supply", doc.SuppliedAt.Format("2006-01-02")},
+		{"Order", fmt.Sprintf("#%d", doc.OrderNumber)},
+	} {
+		pdf.textRight(marginRight-110, dy, bodySize, false, kv[0]+":")
+		pdf.textRight(marginRight, dy, bodySize, true, kv[1])
+		dy += lineHeight
+	}
+
+	// Buyer.
+	y = max(sy, dy) + 24
+	pdf.text(marginLeft, y, bodySize, true, "Bill to")
+	y += lineHeight
+	pdf.text(marginLeft, y, bodySize, false, doc.Buyer.Name)
+	y += lineHeight
+	for _, l := range doc.Buyer.Lines {
+		pdf.text(marginLeft, y, bodySize, false, l)
+		y += lineHeight
+	}
+	if doc.Buyer.VATNumber != "" {
+		pdf.text(marginLeft, y, bodySize, false, "VAT ID: "+doc.Buyer.VATNumber)
+		y += lineHeight
+	}
+
+	// Lines.
+	y += 20
+	header := func() {
+		pdf.text(marginLeft, y, bodySize, true, "Description")
+		pdf.textRight(colQuantity, y, bodySize, true, "Qty")
+		pdf.textRight(colUnitPrice, y, bodySize, true, "Unit price (net)")
+		pdf.textRight(colVATRate, y, bodySize, true, "VAT")
+		pdf.textRight(marginRight, y, bodySize, true, "Amount (net)")
+		pdf.rule(marginLeft, marginRight, y+5)
+		y += lineHeight + 4
+	}
+	header()
+	for _, line := range doc.Lines {
+		desc := wrapText(line.Description, descWidth, bodySize, false)
+		if y+float64(len(desc))*lineHeight > pageBottom {
+			pdf.addPage()
+			y = 60
+			header()
+		}
+		pdf.textRight(colQuantity, y, bodySize, false, fmt.Sprintf("%d", line.Quantity))
+		pdf.textRight(colUnitPrice, y, bodySize, false, doc.money(line.UnitNet))
+		pdf.textRight(colVATRate, y, bodySize, false, percent(line.VATRate))
+		pdf.textRight(marginRight, y, bodySize, false, doc.money(line.Net))
+		for _, d := range desc {
+			pdf.text(marginLeft, y, bodySize, false, d)
+			y += lineHeight
+		}
+	}
+	pdf.rule(marginLeft, marginRight, y-8)
+
+	// VAT breakdown and totals need about 12 lines plus the note.
+	if y+16*lineHeight > pageBottom {
+		pdf.addPage()
+		y = 60
+	}
+
+	y += 12
+	pdf.text(marginLeft, y, bodySize, true, "VAT rate")
+	pdf.textRight(marginLeft+150, y, bodySize, true, "Taxable amount")
+	pdf.textRight(marginLeft+230, y, bodySize, true, "VAT")
+	ty := y
+	y += lineHeight
+	for _, v := range doc.VAT {
+		pdf.text(marginLeft, y, bodySize, false, percent(v.Rate))
+		pdf.textRight(marginLeft+150, y, bodySize, false, doc.money(v.Net))
+		pdf.textRight(marginLeft+230, y, bodySize, false, doc.money(v.VAT))
+		y += lineHeight
+	}
+
+	totals := [][2]string{{"Subtotal", doc.money(doc.Subtotal)}}
+	if doc.Shipping.IsPositive() {
+		totals = append(totals, [2]string{"Shipping", doc.money(doc.Shipping)})
+	}
+	if doc.Discount.IsPositive() {
+		totals = append(totals, [2]string{"Discount", "-" + doc.money(doc.Discount)})
+	}
+	totals = append(totals, [2]string{"VAT", doc.money(doc.VATTotal)})
+	for _, kv := range totals {
+		pdf.textRight(marginRight-110, ty, bodySize, false, kv[0])
+		pdf.textRight(marginRight, ty, bodySize, false, kv[1])
+		ty += lineHeight
+	}
+	pdf.rule(marginRight-200, marginRight, ty-8)
+	ty += 4
+	pdf.textRight(marginRight-110, ty, 11, true, "Total")
+	pdf.textRight(marginRight, ty, 11, true, doc.money(doc.Total))
+	y = max(y, ty) + 30
+
+	if doc.ReverseCharge {
+		for _, l := range wrapText(reverseChargeNote, marginRight-marginLeft, bodySize, true) {
+			pdf.text(marginLeft, y, bodySize, true, l)
+			y += lineHeight
+		}
+	}
+
+	return pdf.bytes()
+}
+
+func (doc document) money(d decimal.Decimal) string {
+	return d.StringFixed(2) + " " + doc.Currency
+}
+
+func percent(d decimal.Decimal) string {
+	return d.Round(2).String() + "%"
+}
+
+// itemDescription is the product name, with the variant and SKU when set.
+func itemDescription(item db.OrderItem) string {
+	desc := item.ProductName
+	if item.VariantName != nil && *item.VariantName != "" {
+		desc += " - " + *item.VariantName
+	}
+	if item.Sku != nil && *item.Sku != "" {
+		desc += " (" + *item.Sku + ")"
+	}
+	return desc
+}
+
+func countryName(countries map[string]string, code string) string {
+	if name, ok := countries[code]; ok {
+		return name
+	}
+	return code
+}
+
+func nonEmpty(ss ...string) []string {
+	out := make([]string, 0, len(ss))
+	for _, s := range ss {
+		if s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+func numericToDecimal(n pgtype.Numeric) decimal.Decimal {
+	if !n.Valid || n.Int == nil {
+		return decimal.Zero
+	}
+	return decimal.NewFromBigInt(n.Int, n.Exp)
+}
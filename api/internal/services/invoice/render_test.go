@@ -0,0 +1,203 @@
+package invoice
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+)
+
+func testDocument() document {
+	return document{
+		Number:      "INV-2026-000042",
+		IssuedAt:    time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC),
+		SuppliedAt:  time.Date(2026, 3, 1, 18, 30, 0, 0, time.UTC),
+		OrderNumber: 1042,
+		Currency:    "EUR",
+		Seller: party{
+			Name:      "Forge GmbH",
+			Lines:     []string{"Hauptstraße 1", "10115 Berlin", "Germany"},
+			VATNumber: "DE123456789",
+		},
+		Buyer: party{
+			Name:      "Ana García",
+			Lines:     []string{"Ejemplo S.L.", "Calle Mayor 5", "28013 Madrid", "Spain"},
+			VATNumber: "ESB12345678",
+		},
+		Lines: []invoiceLine{{
+			Description: "Walnut Desk Organiser - Large (WDO-L)",
+			Quantity:    2,
+			UnitNet:     decimal.RequireFromString("40.00"),
+			VATRate:     decimal.Zero,
+			Net:         decimal.RequireFromString("80.00"),
+			VAT:         decimal.Zero,
+		}},
+		VAT:           []vatLine{{Rate: decimal.Zero, Net: decimal.RequireFromString("80.00"), VAT: decimal.Zero}},
+		Subtotal:      decimal.RequireFromString("80.00"),
+		Shipping:      decimal.RequireFromString("5.00"),
+		VATTotal:      decimal.Zero,
+		Total:         decimal.RequireFromString("85.00"),
+		ReverseCharge: true,
+	}
+}
+
+func TestNewDocument_SpreadsDiscount(t *testing.T) {
+	num := func(s string) pgtype.Numeric {
+		var n pgtype.Numeric
+		if err := n.Scan(s); err != nil {
+			t.Fatalf("scanning numeric %q: %v", s, err)
+		}
+		return n
+	}
+	item := func(name, net, total, rate, vat string) db.OrderItem {
+		return db.OrderItem{
+			ProductName:  name,
+			Quantity:     2,
+			NetUnitPrice: num(net),
+			TotalPrice:   num(total),
+			VatRate:      num(rate),
+			VatAmount:    num(vat),
+		}
+	}
+	o := db.Order{
+		OrderNumber:       1042,
+		Subtotal:          num("40.00"),
+		ShippingFee:       num("0"),
+		ShippingExtraFees: num("0"),
+		DiscountAmount:    num("4.62"),
+		VatTotal:          num("5.58"),
+		Total:             num("41.58"),
+	}
+	items := []db.OrderItem{
+		item("Bag", "10.00", "24.20", "21.00", "4.20"),
+		item("Book", "10.00", "22.00", "10.00", "2.00"),
+	}
+
+	doc := newDocument("INV-2026-000042", time.Now(), o, items, db.StoreSetting{}, SellerAddress{}, nil)
+
+	// 4.62 off 46.20 takes 2.42 off the bag and 2.20 off the book.
+	want := []vatLine{
+		{Rate: decimal.RequireFromString("21"), Net: decimal.RequireFromString("18.00"), VAT: decimal.RequireFromString("3.78")},
+		{Rate: decimal.RequireFromString("10"), Net: decimal.RequireFromString("18.00"), VAT: decimal.RequireFromString("1.80")},
+	}
+	if len(doc.VAT) != len(want) {
+		t.Fatalf("VAT lines: got %d, want %d", len(doc.VAT), len(want))
+	}
+	for i, w := range want {
+		got := doc.VAT[i]
+		if !got.Rate.Equal(w.Rate) || !got.Net.Equal(w.Net) || !got.VAT.Equal(w.VAT) {
+			t.Errorf("VAT line %d: got %s%% %s + %s, want %s%% %s + %s",
+				i, got.Rate, got.Net, got.VAT, w.Rate, w.Net, w.VAT)
+		}
+	}
+	if !doc.VATTotal.Equal(decimal.RequireFromString("5.58")) {
+		t.Errorf("VAT total: got %s, want 5.58", doc.VATTotal)
+	}
+	// Shown net, so subtotal - discount + VAT is the total.
+	if !doc.Discount.Equal(decimal.RequireFromString("4.00")) {
+		t.Errorf("discount: got %s, want 4.00", doc.Discount)
+	}
+	if sum := doc.Subtotal.Add(doc.Shipping).Sub(doc.Discount).Add(doc.VATTotal); !sum.Equal(doc.Total) {
+		t.Errorf("totals add up to %s, want %s", sum, doc.Total)
+	}
+}
+
+func TestRender_MandatoryFields(t *testing.T) {
+	pdf := render(testDocument())
+
+	if !bytes.HasPrefix(pdf, []byte("%PDF-1.4")) {
+		t.Fatalf("missing PDF header: %q", pdf[:16])
+	}
+	for _, want := range []string{
+		"INV-2026-000042",
+		"Invoice date:", "2026-03-02",
+		"Date of supply:", "2026-03-01",
+		"Forge GmbH", "VAT ID: DE123456789",
+		"Ana Garc\xeda", "VAT ID: ESB12345678",
+		"Walnut Desk Organiser",
+		"40.00 EUR", "85.00 EUR",
+		"Reverse charge",
+	} {
+		if !bytes.Contains(pdf, []byte(want)) {
+			t.Errorf("invoice is missing %q", want)
+		}
+	}
+}
+
+func TestRender_NoReverseChargeNote(t *testing.T) {
+	doc := testDocument()
+	doc.ReverseCharge = false
+	if bytes.Contains(render(doc), []byte("Reverse charge")) {
+		t.Error("B2C invoice should not carry the reverse charge note")
+	}
+}
+
+func TestRender_XrefOffsets(t *testing.T) {
+	doc := testDocument()
+	// Enough lines to need a second page.
+	for i := 0; i < 80; i++ {
+		doc.Lines = append(doc.Lines, doc.Lines[0])
+	}
+	pdf := render(doc)
+
+	if n := bytes.Count(pdf, []byte("/Type /Page ")); n < 2 {
+		t.Errorf("expected at least 2 pages, got %d", n)
+	}
+
+	start := bytes.LastIndex(pdf, []byte("startxref\n"))
+	xref, err := strconv.Atoi(strings.Fields(string(pdf[start+len("startxref\n"):]))[0])
+	if err != nil {
+		t.Fatalf("parsing startxref: %v", err)
+	}
+	if !bytes.HasPrefix(pdf[xref:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xref)
+	}
+
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(pdf[xref:], -1)
+	for i, e := range entries {
+		off, _ := strconv.Atoi(string(e[1]))
+		want := fmt.Sprintf("%d 0 obj", i+1)
+		if !bytes.HasPrefix(pdf[off:], []byte(want)) {
+			t.Errorf("xref entry %d points at %q, want %q", i+1, pdf[off:off+10], want)
+		}
+	}
+}
+
+func TestPDFString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"Total (net)", `Total \(net\)`},
+		{`C:\path`, `C:\\path`},
+		{"Müller €", "M\xfcller \x80"},
+		{"日本", "??"},
+	}
+	for _, tt := range tests {
+		if got := pdfString(tt.in); got != tt.want {
+			t.Errorf("pdfString(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	lines := wrapText("one two three four five six seven eight nine ten", 60, 9, false)
+	if len(lines) < 2 {
+		t.Fatalf("expected the text to wrap, got %q", lines)
+	}
+	for _, l := range lines {
+		if w := textWidth(l, 9, false); w > 60 {
+			t.Errorf("line %q is %.1fpt wide, want at most 60", l, w)
+		}
+	}
+	if got := strings.Join(lines, " "); got != "one two three four five six seven eight nine ten" {
+		t.Errorf("wrapping lost words: %q", got)
+	}
+}
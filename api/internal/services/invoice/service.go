@@ -0,0 +1,261 @@
+// Package invoice issues VAT invoices for paid orders and stores them as PDF
+// files in private storage.
+package invoice
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/order"
+	"github.com/forgecommerce/api/internal/services/webhook"
+	"github.com/forgecommerce/api/internal/storage"
+)
+
+var (
+	// ErrNotFound is returned when an order has no invoice, or the order to
+	// invoice does not exist.
+	ErrNotFound = errors.New("invoice not found")
+
+	// ErrNotPaid is returned when invoicing an order that has not been paid.
+	ErrNotPaid = errors.New("order has not been paid")
+
+	// ErrNoItems is returned when invoicing an order without lines, which
+	// has no VAT breakdown to print.
+	ErrNoItems = errors.New("order has no lines to invoice")
+
+	// ErrSellerDetails is returned when the store's address or VAT number,
+	// which every invoice must show, are not set.
+	ErrSellerDetails = errors.New("store address and VAT number must be set before issuing invoices")
+
+	// ErrNoStorage is returned when no private storage is configured.
+	ErrNoStorage = errors.New("no private storage configured for invoices")
+)
+
+// DownloadExpiry is how long a download link for an invoice stays valid.
+const DownloadExpiry = 15 * time.Minute
+
+// Service issues invoices. It implements webhook.Publisher so every order is
+// invoiced as soon as its payment is received.
+type Service struct {
+	pool    *pgxpool.Pool
+	queries *db.Queries
+	store   storage.Storage
+	logger  *slog.Logger
+}
+
+// NewService creates a new invoice service. store is the private bucket the
+// PDF files are written to; with nil, invoices cannot be issued.
+func NewService(pool *pgxpool.Pool, store storage.Storage, logger *slog.Logger) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Service{
+		pool:    pool,
+		queries: db.New(pool),
+		store:   store,
+		logger:  logger,
+	}
+}
+
+// Number formats an invoice number. Numbers restart at 1 every year.
+func Number(year, sequence int32) string {
+	return fmt.Sprintf("INV-%d-%06d", year, sequence)
+}
+
+// Issue invoices a paid order and stores the PDF. An order is invoiced once;
+// issuing it again returns the existing invoice.
+//
+// The number is taken in a short transaction and the PDF is uploaded after
+// it commits, so the numbering lock is never held during an upload. If the
+// upload fails the invoice keeps its number and is not shown until a later
+// Issue call uploads it.
+func (s *Service) Issue(ctx context.Context, orderID uuid.UUID) (db.Invoice, error) {
+	inv, err := s.queries.GetInvoiceByOrder(ctx, orderID)
+	switch {
+	case err == nil && inv.StoredAt.Valid:
+		return inv, nil
+	case err != nil && !errors.Is(err, pgx.ErrNoRows):
+		return db.Invoice{}, fmt.Errorf("getting invoice: %w", err)
+	}
+	if s.store == nil {
+		return db.Invoice{}, ErrNoStorage
+	}
+
+	o, err := s.queries.GetOrder(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Invoice{}, ErrNotFound
+		}
+		return db.Invoice{}, fmt.Errorf("getting order: %w", err)
+	}
+	if o.PaymentStatus == order.PaymentUnpaid {
+		return db.Invoice{}, ErrNotPaid
+	}
+
+	items, err := s.queries.ListOrderItems(ctx, orderID)
+	if err != nil {
+		return db.Invoice{}, fmt.Errorf("listing order items: %w", err)
+	}
+	if len(items) == 0 {
+		return db.Invoice{}, ErrNoItems
+	}
+	settings, err := s.queries.GetStoreSettings(ctx)
+	if err != nil {
+		return db.Invoice{}, fmt.Errorf("getting store settings: %w", err)
+	}
+	seller, err := ParseSellerAddress(settings.StoreAddress)
+	if err != nil {
+		return db.Invoice{}, err
+	}
+	if seller.Line1 == "" || seller.City == "" || derefString(settings.VatCountryCode) == "" ||
+		(settings.VatEnabled && derefString(settings.VatNumber) == "") {
+		return db.Invoice{}, ErrSellerDetails
+	}
+	countries, err := s.countryNames(ctx)
+	if err != nil {
+		return db.Invoice{}, err
+	}
+
+	if inv.ID == uuid.Nil {
+		if inv, err = s.create(ctx, orderID); err != nil {
+			return db.Invoice{}, err
+		}
+		if inv.StoredAt.Valid {
+			return inv, nil
+		}
+	}
+
+	pdf := render(newDocument(inv.InvoiceNumber, inv.IssuedAt, o, items, settings, seller, countries))
+	if _, err := s.store.Put(ctx, inv.StorageKey, bytes.NewReader(pdf), "application/pdf"); err != nil {
+		return db.Invoice{}, fmt.Errorf("storing invoice %s: %w", inv.InvoiceNumber, err)
+	}
+	stored, err := s.queries.MarkInvoiceStored(ctx, inv.ID)
+	if err != nil {
+		return db.Invoice{}, fmt.Errorf("marking invoice %s stored: %w", inv.InvoiceNumber, err)
+	}
+
+	s.logger.Info("invoice issued",
+		slog.String("invoice_number", stored.InvoiceNumber),
+		slog.String("order_id", orderID.String()),
+	)
+	return stored, nil
+}
+
+// create takes the next invoice number for the order and records the
+// invoice. When the order was invoiced concurrently it returns that invoice.
+func (s *Service) create(ctx context.Context, orderID uuid.UUID) (db.Invoice, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.Invoice{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	now := time.Now().UTC()
+	year := int32(now.Year())
+
+	seq, err := qtx.NextInvoiceSequence(ctx, year)
+	if err != nil {
+		return db.Invoice{}, fmt.Errorf("reserving invoice number: %w", err)
+	}
+	number := Number(year, seq)
+
+	inv, err := qtx.CreateInvoice(ctx, db.CreateInvoiceParams{
+		ID:            uuid.New(),
+		OrderID:       orderID,
+		Year:          year,
+		Sequence:      seq,
+		InvoiceNumber: number,
+		StorageKey:    fmt.Sprintf("invoices/%d/%s.pdf", year, number),
+		IssuedAt:      now,
+	})
+	if err != nil {
+		if isDuplicateKeyError(err) {
+			// Issued concurrently; the rollback returns our number.
+			tx.Rollback(ctx)
+			inv, err = s.queries.GetInvoiceByOrder(ctx, orderID)
+			if err != nil {
+				return db.Invoice{}, fmt.Errorf("getting invoice: %w", err)
+			}
+			return inv, nil
+		}
+		return db.Invoice{}, fmt.Errorf("creating invoice: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.Invoice{}, fmt.Errorf("committing invoice: %w", err)
+	}
+	return inv, nil
+}
+
+// GetForOrder returns the invoice of an order, or ErrNotFound. An invoice
+// whose PDF has not been uploaded yet is not returned.
+func (s *Service) GetForOrder(ctx context.Context, orderID uuid.UUID) (db.Invoice, error) {
+	inv, err := s.queries.GetInvoiceByOrder(ctx, orderID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Invoice{}, ErrNotFound
+		}
+		return db.Invoice{}, fmt.Errorf("getting invoice: %w", err)
+	}
+	if !inv.StoredAt.Valid {
+		return db.Invoice{}, ErrNotFound
+	}
+	return inv, nil
+}
+
+// DownloadURL returns a link to the invoice PDF that is valid for
+// DownloadExpiry.
+func (s *Service) DownloadURL(ctx context.Context, inv db.Invoice) (string, error) {
+	if s.store == nil {
+		return "", ErrNoStorage
+	}
+	url, err := s.store.PresignGet(ctx, inv.StorageKey, DownloadExpiry)
+	if err != nil {
+		return "", fmt.Errorf("signing invoice download: %w", err)
+	}
+	return url, nil
+}
+
+// Publish issues the invoice when an order is paid. Rendering and uploading
+// the PDF can outlast the payment webhook's request, so the invoice is issued
+// under a context that the request's end does not cancel. Failures are
+// logged; the invoice can then be issued from the admin.
+func (s *Service) Publish(ctx context.Context, eventType string, data any) {
+	d, ok := data.(webhook.OrderData)
+	if !ok || eventType != webhook.EventOrderPaid || s.store == nil {
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	if _, err := s.Issue(ctx, d.Order.ID); err != nil {
+		s.logger.Error("failed to issue invoice", "error", err, "order_id", d.Order.ID)
+	}
+}
+
+// isDuplicateKeyError checks if a PostgreSQL error is a unique constraint
+// violation (23505).
+func isDuplicateKeyError(err error) bool {
+	var pgErr interface{ SQLState() string }
+	return errors.As(err, &pgErr) && pgErr.SQLState() == "23505"
+}
+
+func (s *Service) countryNames(ctx context.Context) (map[string]string, error) {
+	countries, err := s.queries.ListEUCountries(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("listing countries: %w", err)
+	}
+	names := make(map[string]string, len(countries))
+	for _, c := range countries {
+		names[c.CountryCode] = c.Name
+	}
+	return names, nil
+}
@@ -0,0 +1,72 @@
+package order
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+)
+
+// ErrCheckoutNotFound is returned when a checkout snapshot does not exist.
+var ErrCheckoutNotFound = errors.New("checkout snapshot not found")
+
+// Checkout is the order a Stripe Checkout Session was priced as: the cart's
+// lines with the VAT on each, as they were when the session was created. The
+// payment webhook creates the order from it, so the order matches what the
+// customer paid even if the cart changed while they were paying.
+type Checkout struct {
+	ID            uuid.UUID
+	CartID        uuid.UUID
+	Items         []CreateOrderItemInput
+	Subtotal      pgtype.Numeric // net
+	ReverseCharge bool
+	CompanyName   *string
+}
+
+// SaveCheckout stores a checkout snapshot under c.ID.
+func (s *Service) SaveCheckout(ctx context.Context, c Checkout) error {
+	items, err := json.Marshal(c.Items)
+	if err != nil {
+		return fmt.Errorf("encoding checkout lines: %w", err)
+	}
+	if err := s.queries.CreateCheckoutSnapshot(ctx, db.CreateCheckoutSnapshotParams{
+		ID:               c.ID,
+		CartID:           c.CartID,
+		Items:            items,
+		Subtotal:         c.Subtotal,
+		VatReverseCharge: c.ReverseCharge,
+		VatCompanyName:   c.CompanyName,
+	}); err != nil {
+		return fmt.Errorf("saving checkout %s: %w", c.ID, err)
+	}
+	return nil
+}
+
+// GetCheckout returns the checkout snapshot saved under id.
+func (s *Service) GetCheckout(ctx context.Context, id uuid.UUID) (Checkout, error) {
+	row, err := s.queries.GetCheckoutSnapshot(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return Checkout{}, ErrCheckoutNotFound
+		}
+		return Checkout{}, fmt.Errorf("getting checkout %s: %w", id, err)
+	}
+
+	c := Checkout{
+		ID:            row.ID,
+		CartID:        row.CartID,
+		Subtotal:      row.Subtotal,
+		ReverseCharge: row.VatReverseCharge,
+		CompanyName:   row.VatCompanyName,
+	}
+	if err := json.Unmarshal(row.Items, &c.Items); err != nil {
+		return Checkout{}, fmt.Errorf("decoding lines of checkout %s: %w", id, err)
+	}
+	return c, nil
+}
@@ -0,0 +1,69 @@
+package order
+
+import "github.com/shopspring/decimal"
+
+// SpreadDiscount splits an order-level discount over the order's lines in
+// proportion to each line's gross total, the same way the OSS report does,
+// and returns each line's share. Shares are whole cents and sum exactly to
+// the discount, capped at the lines' total.
+func SpreadDiscount(lineTotals []decimal.Decimal, discount decimal.Decimal) []decimal.Decimal {
+	shares := make([]decimal.Decimal, len(lineTotals))
+
+	sum := decimal.Zero
+	last := -1
+	for i, t := range lineTotals {
+		if t.IsPositive() {
+			sum = sum.Add(t)
+			last = i
+		}
+	}
+	if !discount.IsPositive() || last < 0 {
+		return shares
+	}
+	discount = decimal.Min(discount, sum).Round(2)
+
+	// The last line absorbs rounding so the shares sum exactly.
+	allocated := decimal.Zero
+	for i, t := range lineTotals {
+		if !t.IsPositive() {
+			continue
+		}
+		if i == last {
+			shares[i] = discount.Sub(allocated)
+			break
+		}
+		shares[i] = discount.Mul(t).Div(sum).Round(2)
+		allocated = allocated.Add(shares[i])
+	}
+	return shares
+}
+
+// VATLine is an order line's gross total and the VAT in it at Rate percent.
+type VATLine struct {
+	Total decimal.Decimal
+	Rate  decimal.Decimal
+	VAT   decimal.Decimal
+}
+
+// DiscountVAT takes each line's share of an order-level discount off its
+// total and works out the VAT left in it, so the lines add up to what was
+// charged. Lines whose share is zero are returned unchanged.
+func DiscountVAT(lines []VATLine, discount decimal.Decimal) []VATLine {
+	totals := make([]decimal.Decimal, len(lines))
+	for i, l := range lines {
+		totals[i] = l.Total
+	}
+	shares := SpreadDiscount(totals, discount)
+
+	hundred := decimal.NewFromInt(100)
+	out := make([]VATLine, len(lines))
+	for i, l := range lines {
+		out[i] = l
+		if shares[i].IsZero() {
+			continue
+		}
+		out[i].Total = l.Total.Sub(shares[i])
+		out[i].VAT = out[i].Total.Mul(l.Rate).Div(hundred.Add(l.Rate)).Round(2)
+	}
+	return out
+}
@@ -0,0 +1,40 @@
+package order_test
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/forgecommerce/api/internal/services/order"
+)
+
+func TestDiscountVAT(t *testing.T) {
+	dec := decimal.RequireFromString
+	lines := []order.VATLine{
+		{Total: dec("24.20"), Rate: dec("21"), VAT: dec("4.20")},
+		{Total: dec("0"), Rate: dec("21"), VAT: dec("0")},
+		{Total: dec("22.00"), Rate: dec("10"), VAT: dec("2.00")},
+	}
+
+	got := order.DiscountVAT(lines, dec("4.62"))
+
+	want := []order.VATLine{
+		{Total: dec("21.78"), Rate: dec("21"), VAT: dec("3.78")},
+		{Total: dec("0"), Rate: dec("21"), VAT: dec("0")},
+		{Total: dec("19.80"), Rate: dec("10"), VAT: dec("1.80")},
+	}
+	for i, w := range want {
+		if !got[i].Total.Equal(w.Total) || !got[i].VAT.Equal(w.VAT) {
+			t.Errorf("line %d: got %s with %s VAT, want %s with %s VAT",
+				i, got[i].Total, got[i].VAT, w.Total, w.VAT)
+		}
+	}
+
+	undiscounted := order.DiscountVAT(lines, decimal.Zero)
+	for i, l := range lines {
+		if !undiscounted[i].Total.Equal(l.Total) || !undiscounted[i].VAT.Equal(l.VAT) {
+			t.Errorf("line %d without discount: got %s with %s VAT, want it unchanged",
+				i, undiscounted[i].Total, undiscounted[i].VAT)
+		}
+	}
+}
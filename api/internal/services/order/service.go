@@ -14,52 +14,71 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/inventory"
+	"github.com/forgecommerce/api/internal/services/webhook"
 )
 
 var (
 	// ErrNotFound is returned when an order does not exist.
 	ErrNotFound = errors.New("order not found")
+
+	// ErrDuplicateCheckoutSession is returned when an order already exists
+	// for the Stripe checkout session being turned into an order.
+	ErrDuplicateCheckoutSession = errors.New("an order already exists for this checkout session")
 )
 
 // Service provides business logic for order operations.
 type Service struct {
-	queries *db.Queries
-	pool    *pgxpool.Pool
-	logger  *slog.Logger
+	queries   *db.Queries
+	pool      *pgxpool.Pool
+	inventory *inventory.Service
+	hooks     map[string][]TransitionHook
+	events    webhook.Publisher
+	logger    *slog.Logger
 }
 
-// NewService creates a new order service.
-func NewService(pool *pgxpool.Pool, logger *slog.Logger) *Service {
+// NewService creates a new order service. Order lifecycle events are
+// published to events; pass nil to run without webhooks.
+func NewService(pool *pgxpool.Pool, events webhook.Publisher, logger *slog.Logger) *Service {
 	if logger == nil {
 		logger = slog.Default()
 	}
-	return &Service{
-		queries: db.New(pool),
-		pool:    pool,
-		logger:  logger,
+	if events == nil {
+		events = webhook.Discard
+	}
+	s := &Service{
+		queries:   db.New(pool),
+		pool:      pool,
+		inventory: inventory.NewService(pool, events, logger),
+		hooks:     make(map[string][]TransitionHook),
+		events:    events,
+		logger:    logger,
 	}
+	s.registerDefaultHooks()
+	return s
 }
 
 // CreateOrderItemInput contains the input fields for a single order item
-// to be created as part of a new order.
+// to be created as part of a new order. It is also how checkout snapshots
+// store their lines, hence the JSON tags.
 type CreateOrderItemInput struct {
-	ProductID        pgtype.UUID
-	VariantID        pgtype.UUID
-	ProductName      string
-	VariantName      *string
-	VariantOptions   []byte // JSON snapshot
-	Sku              *string
-	Quantity         int32
-	UnitPrice        pgtype.Numeric
-	TotalPrice       pgtype.Numeric
-	VatRate          pgtype.Numeric
-	VatRateType      *string
-	VatAmount        pgtype.Numeric
-	PriceIncludesVat bool
-	NetUnitPrice     pgtype.Numeric
-	GrossUnitPrice   pgtype.Numeric
-	WeightGrams      *int32
-	Metadata         json.RawMessage
+	ProductID        pgtype.UUID     `json:"product_id"`
+	VariantID        pgtype.UUID     `json:"variant_id"`
+	ProductName      string          `json:"product_name"`
+	VariantName      *string         `json:"variant_name"`
+	VariantOptions   []byte          `json:"variant_options"` // JSON snapshot
+	Sku              *string         `json:"sku"`
+	Quantity         int32           `json:"quantity"`
+	UnitPrice        pgtype.Numeric  `json:"unit_price"`
+	TotalPrice       pgtype.Numeric  `json:"total_price"`
+	VatRate          pgtype.Numeric  `json:"vat_rate"`
+	VatRateType      *string         `json:"vat_rate_type"`
+	VatAmount        pgtype.Numeric  `json:"vat_amount"`
+	PriceIncludesVat bool            `json:"price_includes_vat"`
+	NetUnitPrice     pgtype.Numeric  `json:"net_unit_price"`
+	GrossUnitPrice   pgtype.Numeric  `json:"gross_unit_price"`
+	WeightGrams      *int32          `json:"weight_grams"`
+	Metadata         json.RawMessage `json:"metadata"`
 }
 
 // CreateOrderParams contains the input fields for creating an order,
@@ -117,6 +136,18 @@ func (s *Service) GetByNumber(ctx context.Context, orderNumber int64) (db.Order,
 	return order, nil
 }
 
+// GetByCheckoutSession returns the order paid for by a Stripe checkout session.
+func (s *Service) GetByCheckoutSession(ctx context.Context, sessionID string) (db.Order, error) {
+	order, err := s.queries.GetOrderByCheckoutSession(ctx, &sessionID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Order{}, ErrNotFound
+		}
+		return db.Order{}, fmt.Errorf("getting order by checkout session %s: %w", sessionID, err)
+	}
+	return order, nil
+}
+
 // List returns paginated orders with an optional status filter.
 // It returns the order slice, total count, and any error.
 func (s *Service) List(ctx context.Context, status *string, page, pageSize int) ([]db.Order, int64, error) {
@@ -165,6 +196,16 @@ LIMIT $2 OFFSET $3`
 	return orders, total, nil
 }
 
+// ListByCustomer returns the orders placed by a customer, newest first.
+// Guest orders are not included, even if placed with the customer's email.
+func (s *Service) ListByCustomer(ctx context.Context, customerID uuid.UUID) ([]db.Order, error) {
+	orders, err := s.queries.ListOrdersByCustomer(ctx, pgtype.UUID{Bytes: customerID, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("listing orders for customer %s: %w", customerID, err)
+	}
+	return orders, nil
+}
+
 // Create creates a new order with its items and an initial "order_created" event,
 // all within a single transaction. If any step fails, the entire operation is
 // rolled back.
@@ -217,6 +258,9 @@ func (s *Service) Create(ctx context.Context, params CreateOrderParams) (db.Orde
 		CreatedAt:               now,
 	})
 	if err != nil {
+		if params.StripeCheckoutSessionID != nil && isDuplicateKeyError(err) {
+			return db.Order{}, nil, ErrDuplicateCheckoutSession
+		}
 		return db.Order{}, nil, fmt.Errorf("creating order: %w", err)
 	}
 
@@ -273,21 +317,20 @@ func (s *Service) Create(ctx context.Context, params CreateOrderParams) (db.Orde
 		slog.Int("items", len(items)),
 	)
 
+	data := webhook.OrderData{Order: webhook.NewOrder(order, items)}
+	s.events.Publish(ctx, webhook.EventOrderCreated, data)
+	if order.PaymentStatus == PaymentPaid {
+		s.events.Publish(ctx, webhook.EventOrderPaid, data)
+	}
+
 	return order, items, nil
 }
 
-// UpdateStatus updates an order's status and records a status change event.
-// It returns the updated order or ErrNotFound if the order does not exist.
+// UpdateStatus moves an order to a new status, runs the hooks registered for
+// that status and records a status change event, all in one transaction. It
+// returns ErrNotFound if the order does not exist and a *TransitionError if
+// the move is not allowed from the order's current status.
 func (s *Service) UpdateStatus(ctx context.Context, id uuid.UUID, newStatus string) (db.Order, error) {
-	// Fetch the current order to capture the from_status for the event.
-	existing, err := s.queries.GetOrder(ctx, id)
-	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
-			return db.Order{}, ErrNotFound
-		}
-		return db.Order{}, fmt.Errorf("fetching order for status update: %w", err)
-	}
-
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
 		return db.Order{}, fmt.Errorf("beginning transaction: %w", err)
@@ -297,6 +340,19 @@ func (s *Service) UpdateStatus(ctx context.Context, id uuid.UUID, newStatus stri
 	qtx := s.queries.WithTx(tx)
 	now := time.Now().UTC()
 
+	// Lock the order so concurrent updates see each other's status.
+	existing, err := qtx.GetOrderForUpdate(ctx, id)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Order{}, ErrNotFound
+		}
+		return db.Order{}, fmt.Errorf("fetching order for status update: %w", err)
+	}
+
+	if err := CheckTransition(existing, newStatus); err != nil {
+		return db.Order{}, err
+	}
+
 	order, err := qtx.UpdateOrderStatus(ctx, db.UpdateOrderStatusParams{
 		ID:        id,
 		Status:    newStatus,
@@ -306,8 +362,14 @@ func (s *Service) UpdateStatus(ctx context.Context, id uuid.UUID, newStatus stri
 		return db.Order{}, fmt.Errorf("updating order status %s: %w", id, err)
 	}
 
-	// Record the status change event.
 	fromStatus := existing.Status
+	for _, hook := range s.hooks[newStatus] {
+		if err := hook(ctx, Transition{Order: order, From: fromStatus, To: newStatus, Tx: tx}); err != nil {
+			return db.Order{}, err
+		}
+	}
+
+	// Record the status change event.
 	if err := qtx.CreateOrderEvent(ctx, db.CreateOrderEventParams{
 		ID:         uuid.New(),
 		OrderID:    id,
@@ -319,6 +381,13 @@ func (s *Service) UpdateStatus(ctx context.Context, id uuid.UUID, newStatus stri
 		return db.Order{}, fmt.Errorf("creating status change event: %w", err)
 	}
 
+	// Hooks may have changed other columns; return the final row.
+	if len(s.hooks[newStatus]) > 0 {
+		if order, err = qtx.GetOrder(ctx, id); err != nil {
+			return db.Order{}, fmt.Errorf("reloading order after status update: %w", err)
+		}
+	}
+
 	if err := tx.Commit(ctx); err != nil {
 		return db.Order{}, fmt.Errorf("committing status update: %w", err)
 	}
@@ -329,6 +398,8 @@ func (s *Service) UpdateStatus(ctx context.Context, id uuid.UUID, newStatus stri
 		slog.String("to_status", newStatus),
 	)
 
+	s.publishStatusChanged(ctx, order, fromStatus)
+
 	return order, nil
 }
 
@@ -379,3 +450,25 @@ func (s *Service) ListEvents(ctx context.Context, orderID uuid.UUID) ([]db.Order
 	}
 	return events, nil
 }
+
+// publishStatusChanged emits order.status_changed for an order that has just
+// moved out of fromStatus.
+func (s *Service) publishStatusChanged(ctx context.Context, o db.Order, fromStatus string) {
+	items, err := s.queries.ListOrderItems(ctx, o.ID)
+	if err != nil {
+		s.logger.Warn("loading order items for status event",
+			slog.String("order_id", o.ID.String()),
+			slog.String("error", err.Error()),
+		)
+	}
+	s.events.Publish(ctx, webhook.EventOrderStatusChanged, webhook.OrderStatusChangedData{
+		Order:      webhook.NewOrder(o, items),
+		FromStatus: fromStatus,
+		ToStatus:   o.Status,
+	})
+}
+
+func isDuplicateKeyError(err error) bool {
+	var pgErr interface{ SQLState() string }
+	return errors.As(err, &pgErr) && pgErr.SQLState() == "23505"
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/jackc/pgx/v5/pgtype"
 
 	"github.com/forgecommerce/api/internal/services/order"
+	"github.com/forgecommerce/api/internal/services/webhook"
 	"github.com/forgecommerce/api/internal/testutil"
 )
 
@@ -32,7 +33,7 @@ func TestMain(m *testing.M) {
 }
 
 func newService() *order.Service {
-	return order.NewService(testDB.Pool, nil)
+	return order.NewService(testDB.Pool, nil, nil)
 }
 
 func numericFromCents(cents int64) pgtype.Numeric {
@@ -299,9 +300,10 @@ func TestList_StatusFilter(t *testing.T) {
 	svc.Create(ctx, minimalOrderParams())
 	svc.Create(ctx, minimalOrderParams())
 
-	// Create 1 and update to "shipped".
-	o3, _, _ := svc.Create(ctx, minimalOrderParams())
-	svc.UpdateStatus(ctx, o3.ID, "shipped")
+	// Create 1 already shipped.
+	shippedParams := minimalOrderParams()
+	shippedParams.Status = "shipped"
+	svc.Create(ctx, shippedParams)
 
 	shipped := "shipped"
 	orders, total, err := svc.List(ctx, &shipped, 1, 10)
@@ -370,12 +372,12 @@ func TestUpdateStatus(t *testing.T) {
 
 	o, _, _ := svc.Create(ctx, minimalOrderParams())
 
-	updated, err := svc.UpdateStatus(ctx, o.ID, "shipped")
+	updated, err := svc.UpdateStatus(ctx, o.ID, "confirmed")
 	if err != nil {
 		t.Fatalf("UpdateStatus: %v", err)
 	}
-	if updated.Status != "shipped" {
-		t.Errorf("status: got %q, want %q", updated.Status, "shipped")
+	if updated.Status != "confirmed" {
+		t.Errorf("status: got %q, want %q", updated.Status, "confirmed")
 	}
 
 	// Verify status_changed event was recorded.
@@ -392,8 +394,8 @@ func TestUpdateStatus(t *testing.T) {
 			if e.FromStatus == nil || *e.FromStatus != "pending" {
 				t.Errorf("from_status: got %v, want 'pending'", e.FromStatus)
 			}
-			if e.ToStatus == nil || *e.ToStatus != "shipped" {
-				t.Errorf("to_status: got %v, want 'shipped'", e.ToStatus)
+			if e.ToStatus == nil || *e.ToStatus != "confirmed" {
+				t.Errorf("to_status: got %v, want 'confirmed'", e.ToStatus)
 			}
 		}
 	}
@@ -486,3 +488,66 @@ func TestListEvents_Empty(t *testing.T) {
 		t.Errorf("expected 0 events, got %d", len(events))
 	}
 }
+
+// --------------------------------------------------------------------------
+// Webhook events
+// --------------------------------------------------------------------------
+
+func TestCreate_PublishesEvents(t *testing.T) {
+	testDB.Truncate(t)
+	events := &testutil.Events{}
+	svc := order.NewService(testDB.Pool, events, nil)
+	ctx := context.Background()
+
+	if _, _, err := svc.Create(ctx, minimalOrderParams()); err != nil {
+		t.Fatalf("Create unpaid: %v", err)
+	}
+	if got := events.Types(); len(got) != 1 || got[0] != webhook.EventOrderCreated {
+		t.Fatalf("unpaid order: got events %v, want [order.created]", got)
+	}
+
+	params := minimalOrderParams()
+	params.PaymentStatus = "paid"
+	o, _, err := svc.Create(ctx, params)
+	if err != nil {
+		t.Fatalf("Create paid: %v", err)
+	}
+
+	paid := events.OfType(webhook.EventOrderPaid)
+	if len(paid) != 1 {
+		t.Fatalf("paid order: got events %v, want one order.paid", events.Types())
+	}
+	data := paid[0].(webhook.OrderData)
+	if data.Order.ID != o.ID || data.Order.Total != "50.00" || len(data.Order.Items) != 1 {
+		t.Errorf("unexpected payload: %+v", data.Order)
+	}
+}
+
+func TestUpdateStatus_PublishesStatusChanged(t *testing.T) {
+	testDB.Truncate(t)
+	events := &testutil.Events{}
+	svc := order.NewService(testDB.Pool, events, nil)
+	ctx := context.Background()
+
+	o, _, _ := svc.Create(ctx, minimalOrderParams())
+	if _, err := svc.UpdateStatus(ctx, o.ID, "confirmed"); err != nil {
+		t.Fatalf("UpdateStatus: %v", err)
+	}
+
+	// A rejected transition publishes nothing.
+	if _, err := svc.UpdateStatus(ctx, o.ID, "delivered"); err == nil {
+		t.Fatal("expected confirmed -> delivered to be rejected")
+	}
+
+	changed := events.OfType(webhook.EventOrderStatusChanged)
+	if len(changed) != 1 {
+		t.Fatalf("got events %v, want one order.status_changed", events.Types())
+	}
+	data := changed[0].(webhook.OrderStatusChangedData)
+	if data.FromStatus != "pending" || data.ToStatus != "confirmed" || data.Order.Status != "confirmed" {
+		t.Errorf("unexpected payload: from=%s to=%s status=%s", data.FromStatus, data.ToStatus, data.Order.Status)
+	}
+	if len(data.Order.Items) != 1 {
+		t.Errorf("items: got %d, want 1", len(data.Order.Items))
+	}
+}
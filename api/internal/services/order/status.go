@@ -0,0 +1,189 @@
+package order
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/inventory"
+)
+
+// Order statuses, matching the CHECK constraint on orders.status.
+const (
+	StatusPending    = "pending"
+	StatusConfirmed  = "confirmed"
+	StatusProcessing = "processing"
+	StatusShipped    = "shipped"
+	StatusDelivered  = "delivered"
+	StatusCancelled  = "cancelled"
+	StatusRefunded   = "refunded"
+)
+
+// Payment statuses, matching the CHECK constraint on orders.payment_status.
+const (
+	PaymentUnpaid            = "unpaid"
+	PaymentPaid              = "paid"
+	PaymentRefunded          = "refunded"
+	PaymentPartiallyRefunded = "partially_refunded"
+)
+
+// ErrInvalidTransition is matched by every *TransitionError.
+var ErrInvalidTransition = errors.New("invalid order status transition")
+
+// TransitionError is returned when an order cannot move from one status to
+// another. Its message is suitable for showing to an operator.
+type TransitionError struct {
+	From   string
+	To     string
+	Reason string
+}
+
+func (e *TransitionError) Error() string {
+	msg := fmt.Sprintf("cannot change order status from %q to %q", e.From, e.To)
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+func (e *TransitionError) Unwrap() error {
+	return ErrInvalidTransition
+}
+
+// transitions lists the forward moves allowed from each status, in the order
+// they are offered to operators. Refunds are handled separately because they
+// depend on the payment status rather than the fulfilment status.
+var transitions = map[string][]string{
+	StatusPending:    {StatusConfirmed, StatusCancelled},
+	StatusConfirmed:  {StatusProcessing, StatusCancelled},
+	StatusProcessing: {StatusShipped, StatusCancelled},
+	StatusShipped:    {StatusDelivered},
+	StatusDelivered:  {},
+	StatusCancelled:  {},
+	StatusRefunded:   {},
+}
+
+// isPaid reports whether money has been taken for the order.
+func isPaid(paymentStatus string) bool {
+	switch paymentStatus {
+	case PaymentPaid, PaymentPartiallyRefunded, PaymentRefunded:
+		return true
+	}
+	return false
+}
+
+// CheckTransition reports whether o may move to the given status. It returns
+// a *TransitionError explaining why not, or nil.
+func CheckTransition(o db.Order, to string) error {
+	if _, known := transitions[to]; !known {
+		return &TransitionError{From: o.Status, To: to, Reason: "unknown status"}
+	}
+	if o.Status == to {
+		return &TransitionError{From: o.Status, To: to, Reason: "order already has this status"}
+	}
+
+	if to == StatusRefunded {
+		if o.Status == StatusCancelled || o.Status == StatusRefunded {
+			return &TransitionError{From: o.Status, To: to, Reason: "order is closed"}
+		}
+		if !isPaid(o.PaymentStatus) {
+			return &TransitionError{From: o.Status, To: to, Reason: "order has not been paid"}
+		}
+		return nil
+	}
+
+	for _, next := range transitions[o.Status] {
+		if next == to {
+			return nil
+		}
+	}
+
+	reason := ""
+	switch {
+	case to == StatusCancelled && (o.Status == StatusShipped || o.Status == StatusDelivered):
+		reason = "order has already shipped"
+	case len(transitions[o.Status]) == 0:
+		reason = "order is closed"
+	}
+	return &TransitionError{From: o.Status, To: to, Reason: reason}
+}
+
+// NextStatuses returns the statuses o may move to, in display order.
+func NextStatuses(o db.Order) []string {
+	next := append([]string(nil), transitions[o.Status]...)
+	if CheckTransition(o, StatusRefunded) == nil {
+		next = append(next, StatusRefunded)
+	}
+	return next
+}
+
+// Transition describes a status change in progress. Hooks receive it inside
+// the transaction that updates the order.
+type Transition struct {
+	Order db.Order // the order after the status update
+	From  string
+	To    string
+	Tx    pgx.Tx
+}
+
+// TransitionHook runs a side effect of a status change. Returning an error
+// aborts the change.
+type TransitionHook func(ctx context.Context, t Transition) error
+
+// OnTransition registers a hook that runs whenever an order moves to the
+// given status. Hooks run in registration order, inside the status update
+// transaction. OnTransition is not safe for concurrent use and should be
+// called during start-up.
+func (s *Service) OnTransition(to string, hook TransitionHook) {
+	s.hooks[to] = append(s.hooks[to], hook)
+}
+
+// registerDefaultHooks installs the side effects every store needs.
+func (s *Service) registerDefaultHooks() {
+	s.OnTransition(StatusCancelled, s.restockOnCancel)
+	s.OnTransition(StatusShipped, s.stampShipped)
+	s.OnTransition(StatusDelivered, s.stampDelivered)
+}
+
+// restockOnCancel returns stock committed at checkout to inventory.
+func (s *Service) restockOnCancel(ctx context.Context, t Transition) error {
+	n, err := s.inventory.WithTx(t.Tx).RestockOrder(ctx, t.Order.ID, inventory.Change{
+		Notes: "Order cancelled",
+	})
+	if err != nil {
+		return fmt.Errorf("restocking cancelled order: %w", err)
+	}
+	if n > 0 {
+		s.logger.Info("stock returned for cancelled order",
+			slog.String("order_id", t.Order.ID.String()),
+			slog.Int("lines", n),
+		)
+	}
+	return nil
+}
+
+func (s *Service) stampShipped(ctx context.Context, t Transition) error {
+	if err := s.queries.WithTx(t.Tx).SetOrderShippedAt(ctx, db.SetOrderShippedAtParams{
+		ID:        t.Order.ID,
+		ShippedAt: pgtype.Timestamptz{Time: time.Now().UTC(), Valid: true},
+	}); err != nil {
+		return fmt.Errorf("setting shipped_at: %w", err)
+	}
+	return nil
+}
+
+func (s *Service) stampDelivered(ctx context.Context, t Transition) error {
+	if err := s.queries.WithTx(t.Tx).SetOrderDeliveredAt(ctx, db.SetOrderDeliveredAtParams{
+		ID:          t.Order.ID,
+		DeliveredAt: pgtype.Timestamptz{Time: time.Now().UTC(), Valid: true},
+	}); err != nil {
+		return fmt.Errorf("setting delivered_at: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,167 @@
+package order_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/inventory"
+	"github.com/forgecommerce/api/internal/services/order"
+)
+
+func TestCheckTransition(t *testing.T) {
+	tests := []struct {
+		from    string
+		payment string
+		to      string
+		ok      bool
+	}{
+		{"pending", "unpaid", "confirmed", true},
+		{"confirmed", "paid", "processing", true},
+		{"processing", "paid", "shipped", true},
+		{"shipped", "paid", "delivered", true},
+		{"pending", "unpaid", "shipped", false},
+		{"confirmed", "paid", "delivered", false},
+		{"pending", "unpaid", "cancelled", true},
+		{"processing", "paid", "cancelled", true},
+		{"shipped", "paid", "cancelled", false},
+		{"delivered", "paid", "cancelled", false},
+		{"delivered", "paid", "refunded", true},
+		{"shipped", "partially_refunded", "refunded", true},
+		{"pending", "unpaid", "refunded", false},
+		{"cancelled", "paid", "refunded", false},
+		{"cancelled", "unpaid", "pending", false},
+		{"delivered", "paid", "shipped", false},
+		{"pending", "unpaid", "pending", false},
+		{"pending", "unpaid", "lost", false},
+	}
+
+	for _, tt := range tests {
+		o := db.Order{Status: tt.from, PaymentStatus: tt.payment}
+		err := order.CheckTransition(o, tt.to)
+		if tt.ok && err != nil {
+			t.Errorf("%s (%s) -> %s: unexpected error %v", tt.from, tt.payment, tt.to, err)
+		}
+		if !tt.ok {
+			var transitionErr *order.TransitionError
+			if !errors.As(err, &transitionErr) || !errors.Is(err, order.ErrInvalidTransition) {
+				t.Errorf("%s (%s) -> %s: expected TransitionError, got %v", tt.from, tt.payment, tt.to, err)
+			}
+		}
+	}
+}
+
+func TestNextStatuses(t *testing.T) {
+	got := order.NextStatuses(db.Order{Status: "processing", PaymentStatus: "paid"})
+	want := []string{"shipped", "cancelled", "refunded"}
+	if !slices.Equal(got, want) {
+		t.Errorf("processing/paid: got %v, want %v", got, want)
+	}
+
+	if got := order.NextStatuses(db.Order{Status: "cancelled", PaymentStatus: "unpaid"}); len(got) != 0 {
+		t.Errorf("cancelled: got %v, want none", got)
+	}
+}
+
+func TestUpdateStatus_InvalidTransition(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	o, _, _ := svc.Create(ctx, minimalOrderParams())
+
+	_, err := svc.UpdateStatus(ctx, o.ID, "delivered")
+	var transitionErr *order.TransitionError
+	if !errors.As(err, &transitionErr) {
+		t.Fatalf("expected TransitionError, got %v", err)
+	}
+	if transitionErr.From != "pending" || transitionErr.To != "delivered" {
+		t.Errorf("unexpected error fields: %+v", transitionErr)
+	}
+
+	// Nothing should have changed.
+	got, _ := svc.Get(ctx, o.ID)
+	if got.Status != "pending" {
+		t.Errorf("status: got %q, want pending", got.Status)
+	}
+	events, _ := svc.ListEvents(ctx, o.ID)
+	if len(events) != 1 {
+		t.Errorf("events: got %d, want 1", len(events))
+	}
+}
+
+func TestUpdateStatus_StampsShippedAndDelivered(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	params := minimalOrderParams()
+	params.Status = "processing"
+	o, _, _ := svc.Create(ctx, params)
+
+	shipped, err := svc.UpdateStatus(ctx, o.ID, "shipped")
+	if err != nil {
+		t.Fatalf("UpdateStatus shipped: %v", err)
+	}
+	if !shipped.ShippedAt.Valid {
+		t.Error("expected shipped_at to be set")
+	}
+
+	delivered, err := svc.UpdateStatus(ctx, o.ID, "delivered")
+	if err != nil {
+		t.Fatalf("UpdateStatus delivered: %v", err)
+	}
+	if !delivered.DeliveredAt.Valid {
+		t.Error("expected delivered_at to be set")
+	}
+}
+
+func TestUpdateStatus_CancelRestocks(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	inv := inventory.NewService(testDB.Pool, nil, nil)
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Tote", "tote")
+	variant := testDB.FixtureVariant(t, product.ID, "TOTE-1", 5)
+	cartID := uuid.New()
+	if _, err := testDB.Pool.Exec(ctx, `INSERT INTO carts (id) VALUES ($1)`, cartID); err != nil {
+		t.Fatalf("creating cart: %v", err)
+	}
+
+	o, _, _ := svc.Create(ctx, minimalOrderParams())
+	if err := inv.ReserveCart(ctx, cartID, []inventory.ReservationLine{{VariantID: variant.ID, Quantity: 2}}, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("ReserveCart: %v", err)
+	}
+	if err := inv.AttachCheckoutSession(ctx, cartID, "cs_cancel"); err != nil {
+		t.Fatalf("AttachCheckoutSession: %v", err)
+	}
+	if _, err := inv.CommitCheckoutSession(ctx, "cs_cancel", o.ID); err != nil {
+		t.Fatalf("CommitCheckoutSession: %v", err)
+	}
+
+	if _, err := svc.UpdateStatus(ctx, o.ID, "cancelled"); err != nil {
+		t.Fatalf("UpdateStatus cancelled: %v", err)
+	}
+
+	var stock int32
+	if err := testDB.Pool.QueryRow(ctx, `SELECT stock_quantity FROM product_variants WHERE id = $1`, variant.ID).Scan(&stock); err != nil {
+		t.Fatalf("reading stock: %v", err)
+	}
+	if stock != 5 {
+		t.Errorf("stock after cancel: got %d, want 5", stock)
+	}
+
+	movements, _, err := inv.ListMovements(ctx, inventory.EntityVariant, variant.ID, 1, 10)
+	if err != nil {
+		t.Fatalf("ListMovements: %v", err)
+	}
+	if len(movements) == 0 || movements[0].MovementType != inventory.MovementReturn {
+		t.Errorf("expected a return movement first, got %+v", movements)
+	}
+}
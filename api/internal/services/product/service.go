@@ -15,6 +15,7 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/webhook"
 )
 
 var (
@@ -29,17 +30,23 @@ var (
 type Service struct {
 	queries *db.Queries
 	pool    *pgxpool.Pool
+	events  webhook.Publisher
 	logger  *slog.Logger
 }
 
-// NewService creates a new product service.
-func NewService(pool *pgxpool.Pool, logger *slog.Logger) *Service {
+// NewService creates a new product service. Product changes are published
+// to events; pass nil to run without webhooks.
+func NewService(pool *pgxpool.Pool, events webhook.Publisher, logger *slog.Logger) *Service {
 	if logger == nil {
 		logger = slog.Default()
 	}
+	if events == nil {
+		events = webhook.Discard
+	}
 	return &Service{
 		queries: db.New(pool),
 		pool:    pool,
+		events:  events,
 		logger:  logger,
 	}
 }
@@ -59,6 +66,7 @@ type CreateProductParams struct {
 	BaseDimensionsMm        json.RawMessage
 	ShippingExtraFeePerUnit pgtype.Numeric
 	HasVariants             bool
+	AllowBackorder          bool
 	SeoTitle                *string
 	SeoDescription          *string
 	Metadata                json.RawMessage
@@ -79,6 +87,7 @@ type UpdateProductParams struct {
 	BaseDimensionsMm        json.RawMessage
 	ShippingExtraFeePerUnit pgtype.Numeric
 	HasVariants             bool
+	AllowBackorder          bool
 	SeoTitle                *string
 	SeoDescription          *string
 	Metadata                json.RawMessage
@@ -194,6 +203,7 @@ func (s *Service) Create(ctx context.Context, params CreateProductParams) (db.Pr
 		BaseDimensionsMm:        params.BaseDimensionsMm,
 		ShippingExtraFeePerUnit: params.ShippingExtraFeePerUnit,
 		HasVariants:             params.HasVariants,
+		AllowBackorder:          params.AllowBackorder,
 		SeoTitle:                params.SeoTitle,
 		SeoDescription:          params.SeoDescription,
 		Metadata:                metadata,
@@ -264,6 +274,7 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateProduct
 		BaseDimensionsMm:        params.BaseDimensionsMm,
 		ShippingExtraFeePerUnit: params.ShippingExtraFeePerUnit,
 		HasVariants:             params.HasVariants,
+		AllowBackorder:          params.AllowBackorder,
 		SeoTitle:                params.SeoTitle,
 		SeoDescription:          params.SeoDescription,
 		Metadata:                metadata,
@@ -278,6 +289,10 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateProduct
 		slog.String("name", product.Name),
 	)
 
+	s.events.Publish(ctx, webhook.EventProductUpdated, webhook.ProductData{
+		Product: webhook.NewProduct(product),
+	})
+
 	return product, nil
 }
 
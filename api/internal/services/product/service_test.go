@@ -33,7 +33,7 @@ func TestMain(m *testing.M) {
 }
 
 func newService() *product.Service {
-	return product.NewService(testDB.Pool, nil)
+	return product.NewService(testDB.Pool, nil, nil)
 }
 
 func minimalCreateParams(name string) product.CreateProductParams {
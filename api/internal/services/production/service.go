@@ -11,8 +11,12 @@ import (
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
 
 	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/bom"
+	"github.com/forgecommerce/api/internal/services/inventory"
+	"github.com/forgecommerce/api/internal/services/webhook"
 )
 
 var (
@@ -21,24 +25,44 @@ var (
 
 	// ErrInvalidStatus is returned when a status transition is not allowed.
 	ErrInvalidStatus = errors.New("invalid status transition")
+
+	// ErrInsufficientMaterial is returned when completing a batch would take
+	// a raw material's stock below zero.
+	ErrInsufficientMaterial = errors.New("insufficient raw material stock")
+
+	// ErrVariantMismatch is returned when a batch's variant does not belong
+	// to its product.
+	ErrVariantMismatch = errors.New("variant does not belong to the product")
 )
 
 // Service provides business logic for production batch operations.
 type Service struct {
-	queries *db.Queries
-	pool    *pgxpool.Pool
-	logger  *slog.Logger
+	queries   *db.Queries
+	pool      *pgxpool.Pool
+	bom       *bom.Service
+	inventory *inventory.Service
+	events    webhook.Publisher
+	logger    *slog.Logger
 }
 
-// NewService creates a new production batch service.
-func NewService(pool *pgxpool.Pool, logger *slog.Logger) *Service {
+// NewService creates a new production batch service. The BOM service is used
+// to snapshot the resolved bill of materials when a batch is created.
+// Completed batches are published to events; pass nil to run without
+// webhooks.
+func NewService(pool *pgxpool.Pool, bomSvc *bom.Service, events webhook.Publisher, logger *slog.Logger) *Service {
 	if logger == nil {
 		logger = slog.Default()
 	}
+	if events == nil {
+		events = webhook.Discard
+	}
 	return &Service{
-		queries: db.New(pool),
-		pool:    pool,
-		logger:  logger,
+		queries:   db.New(pool),
+		pool:      pool,
+		bom:       bomSvc,
+		inventory: inventory.NewService(pool, events, logger),
+		events:    events,
+		logger:    logger,
 	}
 }
 
@@ -53,8 +77,42 @@ type CreateBatchParams struct {
 }
 
 // CreateBatch creates a new production batch with an auto-generated batch number.
+// When the batch targets a variant, its resolved BOM is snapshotted into the
+// batch materials, scaled to the planned quantity, in the same transaction.
+// The variant must belong to the batch's product, or ErrVariantMismatch is
+// returned.
 func (s *Service) CreateBatch(ctx context.Context, params CreateBatchParams) (db.ProductionBatch, error) {
-	nextNum, err := s.queries.NextBatchNumber(ctx)
+	if params.VariantID.Valid {
+		v, err := s.queries.GetProductVariant(ctx, uuid.UUID(params.VariantID.Bytes))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return db.ProductionBatch{}, ErrVariantMismatch
+			}
+			return db.ProductionBatch{}, fmt.Errorf("getting batch variant: %w", err)
+		}
+		if v.ProductID != params.ProductID {
+			return db.ProductionBatch{}, ErrVariantMismatch
+		}
+	}
+
+	var resolved *bom.ResolvedBOM
+	if params.VariantID.Valid && s.bom != nil {
+		r, err := s.bom.ResolveVariant(ctx, uuid.UUID(params.VariantID.Bytes))
+		if err != nil {
+			return db.ProductionBatch{}, fmt.Errorf("resolving BOM for batch: %w", err)
+		}
+		resolved = &r
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.ProductionBatch{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	nextNum, err := qtx.NextBatchNumber(ctx)
 	if err != nil {
 		return db.ProductionBatch{}, fmt.Errorf("generating batch number: %w", err)
 	}
@@ -74,7 +132,7 @@ func (s *Service) CreateBatch(ctx context.Context, params CreateBatchParams) (db
 		notes = &params.Notes
 	}
 
-	batch, err := s.queries.CreateProductionBatch(ctx, db.CreateProductionBatchParams{
+	batch, err := qtx.CreateProductionBatch(ctx, db.CreateProductionBatchParams{
 		BatchNumber:     batchNumber,
 		ProductID:       params.ProductID,
 		VariantID:       params.VariantID,
@@ -88,10 +146,34 @@ func (s *Service) CreateBatch(ctx context.Context, params CreateBatchParams) (db
 		return db.ProductionBatch{}, fmt.Errorf("creating production batch: %w", err)
 	}
 
+	materialCount := 0
+	if resolved != nil {
+		planned := decimal.NewFromInt(int64(params.PlannedQty))
+		for _, m := range resolved.Materials {
+			if !m.Quantity.IsPositive() {
+				continue
+			}
+			if _, err := qtx.CreateBatchMaterial(ctx, db.CreateBatchMaterialParams{
+				BatchID:          batch.ID,
+				RawMaterialID:    m.RawMaterialID,
+				RequiredQuantity: decimalToNumeric(m.Quantity.Mul(planned)),
+				UnitCost:         decimalToNumeric(m.CostPerUnit),
+			}); err != nil {
+				return db.ProductionBatch{}, fmt.Errorf("adding material %s to batch: %w", m.RawMaterialID, err)
+			}
+			materialCount++
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.ProductionBatch{}, fmt.Errorf("committing transaction: %w", err)
+	}
+
 	s.logger.Info("production batch created",
 		slog.String("batch_id", batch.ID.String()),
 		slog.String("batch_number", batch.BatchNumber),
 		slog.String("product_id", batch.ProductID.String()),
+		slog.Int("materials", materialCount),
 	)
 
 	return batch, nil
@@ -154,13 +236,40 @@ func (s *Service) Start(ctx context.Context, id uuid.UUID) (db.ProductionBatch,
 	return batch, nil
 }
 
-// Complete transitions a batch from in_progress to completed.
-func (s *Service) Complete(ctx context.Context, id uuid.UUID, actualQty int, costTotal pgtype.Numeric) (db.ProductionBatch, error) {
-	qty := int32(actualQty)
-	batch, err := s.queries.CompleteProductionBatch(ctx, db.CompleteProductionBatchParams{
+// CompleteParams contains the input for completing a production batch.
+type CompleteParams struct {
+	ActualQty int
+	// CostTotal overrides the computed batch cost. When NULL, the cost is
+	// the sum of consumed quantity times unit cost across batch materials.
+	CostTotal pgtype.Numeric
+	// Consumed optionally overrides the consumed quantity per batch material
+	// line (keyed by production_batch_materials.id). Lines without an entry
+	// consume their required quantity scaled to the actual quantity.
+	Consumed    map[uuid.UUID]pgtype.Numeric
+	CompletedBy pgtype.UUID
+}
+
+// Complete transitions a batch from in_progress to completed. In a single
+// transaction it consumes the batch materials from raw material stock, adds
+// the produced units to the batch variant's stock, and records
+// production_consume / production_output stock movements.
+func (s *Service) Complete(ctx context.Context, id uuid.UUID, params CompleteParams) (db.ProductionBatch, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.ProductionBatch{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	inv := s.inventory.WithTx(tx)
+
+	// Transition first: the status guard also locks the batch row, so two
+	// concurrent completions cannot both consume materials.
+	qty := int32(params.ActualQty)
+	batch, err := qtx.CompleteProductionBatch(ctx, db.CompleteProductionBatchParams{
 		ID:             id,
 		ActualQuantity: &qty,
-		CostTotal:      costTotal,
+		CostTotal:      params.CostTotal,
 	})
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -169,11 +278,91 @@ func (s *Service) Complete(ctx context.Context, id uuid.UUID, actualQty int, cos
 		return db.ProductionBatch{}, fmt.Errorf("completing production batch %s: %w", id, err)
 	}
 
+	materials, err := qtx.ListBatchMaterials(ctx, id)
+	if err != nil {
+		return db.ProductionBatch{}, fmt.Errorf("listing materials for batch %s: %w", id, err)
+	}
+
+	ratio := decimal.Zero
+	if batch.PlannedQuantity > 0 {
+		ratio = decimal.NewFromInt(int64(params.ActualQty)).Div(decimal.NewFromInt(int64(batch.PlannedQuantity)))
+	}
+
+	batchRef := pgtype.UUID{Bytes: batch.ID, Valid: true}
+	costTotal := decimal.Zero
+	for _, m := range materials {
+		consumed := numericToDecimal(m.RequiredQuantity).Mul(ratio).Round(4)
+		if override, ok := params.Consumed[m.ID]; ok && override.Valid {
+			consumed = numericToDecimal(override)
+		}
+		if consumed.IsNegative() {
+			return db.ProductionBatch{}, fmt.Errorf("consumed quantity for %s cannot be negative", m.MaterialSku)
+		}
+		costTotal = costTotal.Add(consumed.Mul(numericToDecimal(m.UnitCost)))
+
+		if err := qtx.UpdateBatchMaterialConsumed(ctx, db.UpdateBatchMaterialConsumedParams{
+			ID:               m.ID,
+			ConsumedQuantity: decimalToNumeric(consumed),
+		}); err != nil {
+			return db.ProductionBatch{}, fmt.Errorf("recording consumed quantity for material %s: %w", m.RawMaterialID, err)
+		}
+		if consumed.IsZero() {
+			continue
+		}
+
+		if _, err := inv.AdjustRawMaterial(ctx, m.RawMaterialID, consumed.Neg(), inventory.Change{
+			MovementType:  inventory.MovementProductionConsume,
+			ReferenceType: inventory.ReferenceProductionBatch,
+			ReferenceID:   batchRef,
+			UnitCost:      m.UnitCost,
+			Notes:         batch.BatchNumber,
+			CreatedBy:     params.CompletedBy,
+		}); err != nil {
+			if errors.Is(err, inventory.ErrInsufficientStock) {
+				return db.ProductionBatch{}, fmt.Errorf("%w: %v", ErrInsufficientMaterial, err)
+			}
+			return db.ProductionBatch{}, fmt.Errorf("consuming raw material %s: %w", m.RawMaterialID, err)
+		}
+	}
+
+	if batch.VariantID.Valid && params.ActualQty > 0 {
+		variantID := uuid.UUID(batch.VariantID.Bytes)
+		unitCost := decimalToNumeric(costTotal.Div(decimal.NewFromInt(int64(params.ActualQty))).Round(4))
+		if _, err := inv.AdjustVariant(ctx, variantID, int32(params.ActualQty), inventory.Change{
+			MovementType:  inventory.MovementProductionOutput,
+			ReferenceType: inventory.ReferenceProductionBatch,
+			ReferenceID:   batchRef,
+			UnitCost:      unitCost,
+			Notes:         batch.BatchNumber,
+			CreatedBy:     params.CompletedBy,
+		}); err != nil {
+			return db.ProductionBatch{}, fmt.Errorf("adding output to variant %s: %w", variantID, err)
+		}
+	}
+
+	// Store the computed cost unless the caller supplied one.
+	if !params.CostTotal.Valid {
+		batch, err = qtx.SetProductionBatchCost(ctx, db.SetProductionBatchCostParams{
+			ID:        id,
+			CostTotal: decimalToNumeric(costTotal.Round(2)),
+		})
+		if err != nil {
+			return db.ProductionBatch{}, fmt.Errorf("storing cost for batch %s: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.ProductionBatch{}, fmt.Errorf("committing transaction: %w", err)
+	}
+
 	s.logger.Info("production batch completed",
 		slog.String("batch_id", id.String()),
-		slog.Int("actual_quantity", actualQty),
+		slog.Int("actual_quantity", params.ActualQty),
+		slog.Int("materials", len(materials)),
 	)
 
+	s.events.Publish(ctx, webhook.EventProductionBatchCompleted, webhook.NewProductionBatchCompleted(batch))
+
 	return batch, nil
 }
 
@@ -225,3 +414,21 @@ func (s *Service) CountByStatus(ctx context.Context, status db.ProductionBatchSt
 	}
 	return count, nil
 }
+
+// numericToDecimal converts a pgtype.Numeric to a shopspring Decimal.
+// Returns decimal.Zero if the Numeric is not valid.
+func numericToDecimal(n pgtype.Numeric) decimal.Decimal {
+	if !n.Valid || n.Int == nil {
+		return decimal.Zero
+	}
+	return decimal.NewFromBigInt(n.Int, n.Exp)
+}
+
+// decimalToNumeric converts a shopspring Decimal to a pgtype.Numeric.
+func decimalToNumeric(d decimal.Decimal) pgtype.Numeric {
+	return pgtype.Numeric{
+		Int:   d.Coefficient(),
+		Exp:   d.Exponent(),
+		Valid: true,
+	}
+}
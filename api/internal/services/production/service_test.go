@@ -0,0 +1,228 @@
+package production_test
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/bom"
+	"github.com/forgecommerce/api/internal/services/production"
+	"github.com/forgecommerce/api/internal/testutil"
+)
+
+var testDB *testutil.TestDB
+
+func TestMain(m *testing.M) {
+	var code int
+	defer func() { os.Exit(code) }()
+
+	db, err := testutil.SetupTestDB()
+	if err != nil {
+		log.Fatalf("setting up test database: %v", err)
+	}
+	defer db.Close()
+	testDB = db
+
+	code = m.Run()
+}
+
+func newService() *production.Service {
+	return production.NewService(testDB.Pool, bom.NewService(testDB.Pool, nil), nil, nil)
+}
+
+func numeric(n int64) pgtype.Numeric {
+	return pgtype.Numeric{Int: big.NewInt(n), Exp: 0, Valid: true}
+}
+
+// setupBatch creates a product with one variant whose BOM needs 2 units of
+// a material (100 in stock at 5.00), plus a started batch for plannedQty units.
+func setupBatch(t *testing.T, svc *production.Service, plannedQty int) (db.ProductionBatch, db.ProductVariant, db.RawMaterial) {
+	t.Helper()
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Wallet", "wallet")
+	variant := testDB.FixtureVariant(t, product.ID, "WAL-1", 0)
+	material := testDB.FixtureRawMaterial(t, "Leather", "LTH-001")
+
+	if _, err := bom.NewService(testDB.Pool, nil).CreateProductEntry(ctx, bom.CreateProductEntryParams{
+		ProductID:     product.ID,
+		RawMaterialID: material.ID,
+		Quantity:      numeric(2),
+		UnitOfMeasure: "unit",
+		IsRequired:    true,
+	}); err != nil {
+		t.Fatalf("CreateProductEntry: %v", err)
+	}
+
+	batch, err := svc.CreateBatch(ctx, production.CreateBatchParams{
+		ProductID:  product.ID,
+		VariantID:  pgtype.UUID{Bytes: variant.ID, Valid: true},
+		PlannedQty: plannedQty,
+	})
+	if err != nil {
+		t.Fatalf("CreateBatch: %v", err)
+	}
+	if _, err := svc.Start(ctx, batch.ID); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	return batch, variant, material
+}
+
+func TestCreateBatch_SnapshotsResolvedBOM(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+
+	batch, _, material := setupBatch(t, svc, 10)
+
+	materials, err := svc.ListMaterials(context.Background(), batch.ID)
+	if err != nil {
+		t.Fatalf("ListMaterials: %v", err)
+	}
+	if len(materials) != 1 {
+		t.Fatalf("expected 1 batch material, got %d", len(materials))
+	}
+	if materials[0].RawMaterialID != material.ID {
+		t.Error("material ID mismatch")
+	}
+	if v, _ := materials[0].RequiredQuantity.Float64Value(); v.Float64 != 20 {
+		t.Errorf("required quantity: got %v, want 20", v.Float64)
+	}
+}
+
+func TestCreateBatch_VariantOfAnotherProduct(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+
+	wallet := testDB.FixtureProduct(t, "Wallet", "wallet")
+	belt := testDB.FixtureProduct(t, "Belt", "belt")
+	variant := testDB.FixtureVariant(t, belt.ID, "BLT-1", 0)
+
+	_, err := svc.CreateBatch(context.Background(), production.CreateBatchParams{
+		ProductID:  wallet.ID,
+		VariantID:  pgtype.UUID{Bytes: variant.ID, Valid: true},
+		PlannedQty: 5,
+	})
+	if !errors.Is(err, production.ErrVariantMismatch) {
+		t.Errorf("got %v, want ErrVariantMismatch", err)
+	}
+}
+
+func TestComplete_ConsumesMaterialsAndOutputsStock(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+	q := db.New(testDB.Pool)
+
+	batch, variant, material := setupBatch(t, svc, 10)
+
+	completed, err := svc.Complete(ctx, batch.ID, production.CompleteParams{ActualQty: 8})
+	if err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+	if completed.Status != db.ProductionBatchStatusCompleted {
+		t.Errorf("status: got %q, want completed", completed.Status)
+	}
+	// 8 units * 2 * 5.00 = 80.00
+	if v, _ := completed.CostTotal.Float64Value(); v.Float64 != 80 {
+		t.Errorf("cost total: got %v, want 80", v.Float64)
+	}
+
+	m, _ := q.GetRawMaterial(ctx, material.ID)
+	if v, _ := m.StockQuantity.Float64Value(); v.Float64 != 84 {
+		t.Errorf("material stock: got %v, want 84", v.Float64)
+	}
+	v, _ := q.GetProductVariant(ctx, variant.ID)
+	if v.StockQuantity != 8 {
+		t.Errorf("variant stock: got %d, want 8", v.StockQuantity)
+	}
+
+	consume, _ := q.ListStockMovements(ctx, db.ListStockMovementsParams{
+		EntityType: "raw_material", EntityID: material.ID, Limit: 10,
+	})
+	if len(consume) != 1 || consume[0].MovementType != "production_consume" {
+		t.Errorf("expected one production_consume movement, got %+v", consume)
+	}
+	output, _ := q.ListStockMovements(ctx, db.ListStockMovementsParams{
+		EntityType: "product_variant", EntityID: variant.ID, Limit: 10,
+	})
+	if len(output) != 1 || output[0].MovementType != "production_output" {
+		t.Errorf("expected one production_output movement, got %+v", output)
+	}
+}
+
+func TestComplete_ConsumedOverride(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+
+	batch, _, material := setupBatch(t, svc, 10)
+	lines, _ := svc.ListMaterials(ctx, batch.ID)
+
+	if _, err := svc.Complete(ctx, batch.ID, production.CompleteParams{
+		ActualQty: 10,
+		Consumed:  map[uuid.UUID]pgtype.Numeric{lines[0].ID: numeric(25)},
+	}); err != nil {
+		t.Fatalf("Complete: %v", err)
+	}
+
+	m, _ := db.New(testDB.Pool).GetRawMaterial(ctx, material.ID)
+	if v, _ := m.StockQuantity.Float64Value(); v.Float64 != 75 {
+		t.Errorf("material stock: got %v, want 75", v.Float64)
+	}
+}
+
+func TestComplete_InsufficientMaterial(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+
+	batch, variant, material := setupBatch(t, svc, 60) // needs 120, 100 in stock
+
+	_, err := svc.Complete(ctx, batch.ID, production.CompleteParams{ActualQty: 60})
+	if !errors.Is(err, production.ErrInsufficientMaterial) {
+		t.Fatalf("expected ErrInsufficientMaterial, got %v", err)
+	}
+
+	// Nothing should have changed.
+	got, _ := svc.Get(ctx, batch.ID)
+	if got.Status != db.ProductionBatchStatusInProgress {
+		t.Errorf("status: got %q, want in_progress", got.Status)
+	}
+	q := db.New(testDB.Pool)
+	m, _ := q.GetRawMaterial(ctx, material.ID)
+	if v, _ := m.StockQuantity.Float64Value(); v.Float64 != 100 {
+		t.Errorf("material stock: got %v, want 100", v.Float64)
+	}
+	v, _ := q.GetProductVariant(ctx, variant.ID)
+	if v.StockQuantity != 0 {
+		t.Errorf("variant stock: got %d, want 0", v.StockQuantity)
+	}
+}
+
+func TestComplete_InvalidStatus(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+
+	batch, _, _ := setupBatch(t, svc, 1)
+	if _, err := svc.Complete(ctx, batch.ID, production.CompleteParams{ActualQty: 1}); err != nil {
+		t.Fatalf("first Complete: %v", err)
+	}
+	_, err := svc.Complete(ctx, batch.ID, production.CompleteParams{ActualQty: 1})
+	if !errors.Is(err, production.ErrInvalidStatus) {
+		t.Errorf("expected ErrInvalidStatus on second completion, got %v", err)
+	}
+}
@@ -10,24 +10,28 @@ import (
 	"time"
 
 	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/inventory"
 	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
 )
 
 // Service wraps sqlc-generated queries for raw material CRUD operations.
 type Service struct {
-	queries *db.Queries
-	pool    *pgxpool.Pool
-	logger  *slog.Logger
+	queries   *db.Queries
+	pool      *pgxpool.Pool
+	inventory *inventory.Service
+	logger    *slog.Logger
 }
 
 // NewService creates a new raw material service backed by the given connection pool.
 func NewService(pool *pgxpool.Pool, logger *slog.Logger) *Service {
 	return &Service{
-		queries: db.New(pool),
-		pool:    pool,
-		logger:  logger,
+		queries:   db.New(pool),
+		pool:      pool,
+		inventory: inventory.NewService(pool, nil, logger),
+		logger:    logger,
 	}
 }
 
@@ -63,6 +67,10 @@ type UpdateRawMaterialParams struct {
 	LeadTimeDays      *int32
 	Metadata          json.RawMessage
 	IsActive          bool
+
+	// ChangedBy is the admin user editing the material, recorded on the
+	// stock movement when StockQuantity differs from the current level.
+	ChangedBy pgtype.UUID
 }
 
 // CreateCategoryParams holds the input fields for creating a raw material category.
@@ -177,7 +185,7 @@ func (s *Service) Create(ctx context.Context, params CreateRawMaterialParams) (d
 		CategoryID:        categoryID,
 		UnitOfMeasure:     params.UnitOfMeasure,
 		CostPerUnit:       params.CostPerUnit,
-		StockQuantity:     params.StockQuantity,
+		StockQuantity:     decimalToNumeric(decimal.Zero),
 		LowStockThreshold: params.LowStockThreshold,
 		SupplierName:      params.SupplierName,
 		SupplierSku:       params.SupplierSku,
@@ -187,11 +195,36 @@ func (s *Service) Create(ctx context.Context, params CreateRawMaterialParams) (d
 		CreatedAt:         now,
 	}
 
-	material, err := s.queries.CreateRawMaterial(ctx, dbParams)
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.RawMaterial{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The material starts empty; any initial stock is booked through the
+	// inventory ledger so it shows up in the movement history.
+	material, err := s.queries.WithTx(tx).CreateRawMaterial(ctx, dbParams)
 	if err != nil {
 		return db.RawMaterial{}, fmt.Errorf("creating raw material: %w", err)
 	}
 
+	if stock := numericToDecimal(params.StockQuantity); !stock.IsZero() {
+		movement, err := s.inventory.WithTx(tx).SetRawMaterialStock(ctx, material.ID, stock, inventory.Change{
+			MovementType:  inventory.MovementAdjustment,
+			ReferenceType: inventory.ReferenceManual,
+			Notes:         "Initial stock",
+			AllowNegative: true,
+		})
+		if err != nil {
+			return db.RawMaterial{}, fmt.Errorf("setting initial stock for raw material: %w", err)
+		}
+		material.StockQuantity = movement.QuantityAfter
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.RawMaterial{}, fmt.Errorf("committing transaction: %w", err)
+	}
+
 	s.logger.Info("raw material created",
 		slog.String("id", material.ID.String()),
 		slog.String("name", material.Name),
@@ -215,6 +248,22 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateRawMate
 		metadata = json.RawMessage(`{}`)
 	}
 
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.RawMaterial{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	// Lock the material so the stock level read here is the one the ledger
+	// records as "before".
+	existing, err := qtx.GetRawMaterialForUpdate(ctx, id)
+	if err != nil {
+		return db.RawMaterial{}, fmt.Errorf("fetching raw material %s for update: %w", id, err)
+	}
+
+	// Stock is left untouched here and changed through the inventory ledger below.
 	dbParams := db.UpdateRawMaterialParams{
 		ID:                id,
 		Name:              params.Name,
@@ -223,7 +272,7 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateRawMate
 		CategoryID:        categoryID,
 		UnitOfMeasure:     params.UnitOfMeasure,
 		CostPerUnit:       params.CostPerUnit,
-		StockQuantity:     params.StockQuantity,
+		StockQuantity:     existing.StockQuantity,
 		LowStockThreshold: params.LowStockThreshold,
 		SupplierName:      params.SupplierName,
 		SupplierSku:       params.SupplierSku,
@@ -233,11 +282,30 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateRawMate
 		UpdatedAt:         now,
 	}
 
-	material, err := s.queries.UpdateRawMaterial(ctx, dbParams)
+	material, err := qtx.UpdateRawMaterial(ctx, dbParams)
 	if err != nil {
 		return db.RawMaterial{}, fmt.Errorf("updating raw material %s: %w", id, err)
 	}
 
+	if params.StockQuantity.Valid {
+		movement, err := s.inventory.WithTx(tx).SetRawMaterialStock(ctx, id, numericToDecimal(params.StockQuantity), inventory.Change{
+			MovementType:  inventory.MovementAdjustment,
+			ReferenceType: inventory.ReferenceManual,
+			CreatedBy:     params.ChangedBy,
+			AllowNegative: true,
+		})
+		if err != nil {
+			return db.RawMaterial{}, fmt.Errorf("updating stock for raw material %s: %w", id, err)
+		}
+		if movement.ID != uuid.Nil {
+			material.StockQuantity = movement.QuantityAfter
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.RawMaterial{}, fmt.Errorf("committing transaction: %w", err)
+	}
+
 	s.logger.Info("raw material updated",
 		slog.String("id", material.ID.String()),
 		slog.String("name", material.Name),
@@ -351,3 +419,21 @@ func slugify(name string) string {
 	s = strings.Trim(s, "-")
 	return s
 }
+
+// numericToDecimal converts a pgtype.Numeric to a shopspring Decimal.
+// Returns decimal.Zero if the Numeric is not valid.
+func numericToDecimal(n pgtype.Numeric) decimal.Decimal {
+	if !n.Valid || n.Int == nil {
+		return decimal.Zero
+	}
+	return decimal.NewFromBigInt(n.Int, n.Exp)
+}
+
+// decimalToNumeric converts a shopspring Decimal to a pgtype.Numeric.
+func decimalToNumeric(d decimal.Decimal) pgtype.Numeric {
+	return pgtype.Numeric{
+		Int:   d.Coefficient(),
+		Exp:   d.Exponent(),
+		Valid: true,
+	}
+}
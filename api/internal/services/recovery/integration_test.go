@@ -0,0 +1,239 @@
+package recovery_test
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/big"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	"github.com/forgecommerce/api/internal/auth"
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/email"
+	"github.com/forgecommerce/api/internal/services/cart"
+	"github.com/forgecommerce/api/internal/services/discount"
+	"github.com/forgecommerce/api/internal/services/recovery"
+	"github.com/forgecommerce/api/internal/testutil"
+)
+
+const testJWTSecret = "test-secret-key-for-recovery-tests"
+
+var testDB *testutil.TestDB
+
+func TestMain(m *testing.M) {
+	var code int
+	defer func() { os.Exit(code) }()
+
+	db, err := testutil.SetupTestDB()
+	if err != nil {
+		log.Fatalf("setting up test database: %v", err)
+	}
+	defer db.Close()
+	testDB = db
+
+	code = m.Run()
+}
+
+func newService(discountID uuid.UUID) *recovery.Service {
+	emailSvc := email.NewService(testDB.Pool, nil, email.Config{ShopURL: "https://shop.example.com"}, nil)
+	return recovery.NewService(testDB.Pool, discount.NewService(testDB.Pool, nil), emailSvc, auth.NewJWTManager(testJWTSecret), recovery.Config{
+		IdleAfter:  time.Hour,
+		DiscountID: discountID,
+		CouponTTL:  72 * time.Hour,
+	}, nil)
+}
+
+// abandonedCart creates a cart with an email and one item and backdates it
+// so it has been idle for idle.
+func abandonedCart(t *testing.T, email string, idle time.Duration) db.Cart {
+	t.Helper()
+	ctx := context.Background()
+	cartSvc := cart.NewService(testDB.Pool, nil)
+
+	c, err := cartSvc.Create(ctx)
+	if err != nil {
+		t.Fatalf("creating cart: %v", err)
+	}
+	if _, err := cartSvc.Update(ctx, c.ID, cart.UpdateParams{Email: &email}); err != nil {
+		t.Fatalf("updating cart: %v", err)
+	}
+	p := testDB.FixtureProduct(t, "Recovery Product "+email, "recovery-"+strings.ReplaceAll(email, "@", "-"))
+	v := testDB.FixtureVariant(t, p.ID, "REC-"+email, 10)
+	if _, err := cartSvc.AddItem(ctx, c.ID, v.ID, 2); err != nil {
+		t.Fatalf("adding item: %v", err)
+	}
+
+	at := time.Now().Add(-idle)
+	if _, err := testDB.Pool.Exec(ctx, `UPDATE carts SET updated_at = $2 WHERE id = $1`, c.ID, at); err != nil {
+		t.Fatalf("backdating cart: %v", err)
+	}
+	if _, err := testDB.Pool.Exec(ctx, `UPDATE cart_items SET updated_at = $2 WHERE cart_id = $1`, c.ID, at); err != nil {
+		t.Fatalf("backdating cart items: %v", err)
+	}
+	return c
+}
+
+func createDiscount(t *testing.T) db.Discount {
+	t.Helper()
+	d, err := discount.NewService(testDB.Pool, nil).CreateDiscount(context.Background(), discount.CreateDiscountParams{
+		Name:     "Come back",
+		Type:     "percentage",
+		Value:    pgtype.Numeric{Int: big.NewInt(1000), Exp: -2, Valid: true},
+		Scope:    "subtotal",
+		IsActive: true,
+	})
+	if err != nil {
+		t.Fatalf("creating discount: %v", err)
+	}
+	return d
+}
+
+func outboxCount(t *testing.T, template string) int {
+	t.Helper()
+	var n int
+	if err := testDB.Pool.QueryRow(context.Background(),
+		`SELECT COUNT(*) FROM email_outbox WHERE template = $1`, template,
+	).Scan(&n); err != nil {
+		t.Fatalf("counting outbox: %v", err)
+	}
+	return n
+}
+
+func TestSendReminders_OncePerCartWithCoupon(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	ctx := context.Background()
+	d := createDiscount(t)
+	svc := newService(d.ID)
+
+	c := abandonedCart(t, "idle@example.com", 2*time.Hour)
+
+	sent, err := svc.SendReminders(ctx)
+	if err != nil {
+		t.Fatalf("SendReminders: %v", err)
+	}
+	if sent != 1 {
+		t.Fatalf("sent: got %d, want 1", sent)
+	}
+	if n := outboxCount(t, email.KindCartRecovery); n != 1 {
+		t.Errorf("queued emails: got %d, want 1", n)
+	}
+
+	var couponID pgtype.UUID
+	if err := testDB.Pool.QueryRow(ctx,
+		`SELECT coupon_id FROM cart_recoveries WHERE cart_id = $1`, c.ID,
+	).Scan(&couponID); err != nil {
+		t.Fatalf("reading cart recovery: %v", err)
+	}
+	if !couponID.Valid {
+		t.Error("expected a recovery coupon")
+	}
+
+	sent, err = svc.SendReminders(ctx)
+	if err != nil {
+		t.Fatalf("SendReminders again: %v", err)
+	}
+	if sent != 0 {
+		t.Errorf("second run sent %d reminders, want 0", sent)
+	}
+}
+
+func TestSendReminders_SkipsActiveAndOrderedCarts(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	ctx := context.Background()
+	svc := newService(uuid.Nil)
+
+	abandonedCart(t, "active@example.com", 10*time.Minute)
+	ordered := abandonedCart(t, "ordered@example.com", 2*time.Hour)
+	if _, err := testDB.Pool.Exec(ctx, `
+		INSERT INTO orders (id, email, billing_address, shipping_address, subtotal, vat_total, discount_amount, total)
+		VALUES ($1, 'ordered@example.com', '{}', '{}', 0, 0, 0, 0)`, uuid.New()); err != nil {
+		t.Fatalf("inserting order: %v", err)
+	}
+	if _, err := testDB.Pool.Exec(ctx,
+		`UPDATE carts SET order_id = (SELECT id FROM orders LIMIT 1) WHERE id = $1`, ordered.ID); err != nil {
+		t.Fatalf("marking cart ordered: %v", err)
+	}
+
+	sent, err := svc.SendReminders(ctx)
+	if err != nil {
+		t.Fatalf("SendReminders: %v", err)
+	}
+	if sent != 0 {
+		t.Errorf("sent: got %d, want 0", sent)
+	}
+}
+
+func TestRestore_AppliesCouponAndTracksOrder(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	ctx := context.Background()
+	d := createDiscount(t)
+	svc := newService(d.ID)
+
+	c := abandonedCart(t, "restore@example.com", 2*time.Hour)
+	if _, err := svc.SendReminders(ctx); err != nil {
+		t.Fatalf("SendReminders: %v", err)
+	}
+
+	token, err := auth.NewJWTManager(testJWTSecret).GenerateCartRestoreToken(c.ID, c.ExpiresAt)
+	if err != nil {
+		t.Fatalf("generating token: %v", err)
+	}
+	restored, err := svc.Restore(ctx, token)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if restored.CouponCode == nil || !strings.HasPrefix(*restored.CouponCode, "BACK-") {
+		t.Errorf("coupon code: got %v, want a BACK- code", restored.CouponCode)
+	}
+
+	orderID := uuid.New()
+	if _, err := testDB.Pool.Exec(ctx, `
+		INSERT INTO orders (id, email, billing_address, shipping_address, subtotal, vat_total, discount_amount, total)
+		VALUES ($1, 'restore@example.com', '{}', '{}', 0, 0, 0, 0)`, orderID); err != nil {
+		t.Fatalf("inserting order: %v", err)
+	}
+	if err := cart.NewService(testDB.Pool, nil).MarkOrdered(ctx, c.ID, orderID); err != nil {
+		t.Fatalf("MarkOrdered: %v", err)
+	}
+
+	var restoredAt pgtype.Timestamptz
+	var recoveredOrder pgtype.UUID
+	if err := testDB.Pool.QueryRow(ctx,
+		`SELECT restored_at, order_id FROM cart_recoveries WHERE cart_id = $1`, c.ID,
+	).Scan(&restoredAt, &recoveredOrder); err != nil {
+		t.Fatalf("reading cart recovery: %v", err)
+	}
+	if !restoredAt.Valid {
+		t.Error("expected restored_at to be set")
+	}
+	if !recoveredOrder.Valid || recoveredOrder.Bytes != orderID {
+		t.Errorf("order_id: got %v, want %s", recoveredOrder, orderID)
+	}
+
+	// The link no longer works once the cart has been checked out.
+	if _, err := svc.Restore(ctx, token); !errors.Is(err, recovery.ErrCartUnavailable) {
+		t.Errorf("expected ErrCartUnavailable, got %v", err)
+	}
+}
+
+func TestRestore_InvalidToken(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService(uuid.Nil)
+
+	token, err := auth.NewJWTManager("another-secret").GenerateCartRestoreToken(uuid.New(), time.Now().Add(time.Hour))
+	if err != nil {
+		t.Fatalf("generating token: %v", err)
+	}
+	if _, err := svc.Restore(context.Background(), token); !errors.Is(err, recovery.ErrInvalidLink) {
+		t.Errorf("expected ErrInvalidLink, got %v", err)
+	}
+}
@@ -0,0 +1,319 @@
+// Package recovery sends reminders for abandoned carts and restores them
+// from the signed link in the reminder.
+package recovery
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+
+	"github.com/forgecommerce/api/internal/auth"
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/email"
+	"github.com/forgecommerce/api/internal/services/discount"
+)
+
+var (
+	// ErrInvalidLink is returned when a restore token is malformed, forged
+	// or expired.
+	ErrInvalidLink = errors.New("invalid or expired restore link")
+
+	// ErrCartUnavailable is returned when the cart behind a restore link has
+	// been checked out, has expired or no longer exists.
+	ErrCartUnavailable = errors.New("cart is no longer available")
+)
+
+const (
+	// batchSize is the number of carts reminded per run.
+	batchSize = 100
+
+	// couponPrefix starts every recovery coupon code, so they are easy to
+	// tell apart in the coupon list.
+	couponPrefix = "BACK-"
+)
+
+// Config controls when reminders are sent and what they offer.
+type Config struct {
+	// IdleAfter is how long a cart must go untouched before a reminder is
+	// sent.
+	IdleAfter time.Duration
+	// DiscountID is the discount behind the one-time coupon offered in each
+	// reminder. uuid.Nil sends reminders without a coupon.
+	DiscountID uuid.UUID
+	// CouponTTL is how long a recovery coupon stays valid.
+	CouponTTL time.Duration
+}
+
+// Service finds abandoned carts and sends one reminder per cart.
+type Service struct {
+	queries     *db.Queries
+	pool        *pgxpool.Pool
+	discountSvc *discount.Service
+	emailSvc    *email.Service
+	jwtMgr      *auth.JWTManager
+	cfg         Config
+	logger      *slog.Logger
+}
+
+// NewService creates a new cart recovery service.
+func NewService(
+	pool *pgxpool.Pool,
+	discountSvc *discount.Service,
+	emailSvc *email.Service,
+	jwtMgr *auth.JWTManager,
+	cfg Config,
+	logger *slog.Logger,
+) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &Service{
+		queries:     db.New(pool),
+		pool:        pool,
+		discountSvc: discountSvc,
+		emailSvc:    emailSvc,
+		jwtMgr:      jwtMgr,
+		cfg:         cfg,
+		logger:      logger,
+	}
+}
+
+// SendReminders queues a reminder for up to one batch of carts that have an
+// email and items and have been idle for Config.IdleAfter. Carts that were
+// checked out, have expired or were already reminded are skipped. It returns
+// the number of reminders queued; a cart that fails is logged and retried on
+// the next run.
+func (s *Service) SendReminders(ctx context.Context) (int, error) {
+	now := time.Now().UTC()
+	carts, err := s.queries.ListAbandonedCarts(ctx, db.ListAbandonedCartsParams{
+		Now:        now,
+		IdleBefore: now.Add(-s.cfg.IdleAfter),
+		MaxResults: batchSize,
+	})
+	if err != nil {
+		return 0, fmt.Errorf("listing abandoned carts: %w", err)
+	}
+
+	sent := 0
+	for _, c := range carts {
+		if ctx.Err() != nil {
+			break
+		}
+		ok, err := s.remind(ctx, c)
+		if err != nil {
+			s.logger.Error("sending cart reminder", "error", err, "cart_id", c.ID)
+			continue
+		}
+		if ok {
+			sent++
+		}
+	}
+
+	if sent > 0 {
+		s.logger.Info("cart reminders queued", slog.Int("count", sent))
+	}
+	return sent, nil
+}
+
+// remind claims a cart, creates its coupon and queues the reminder. It
+// reports false if another process claimed the cart first or it is empty.
+func (s *Service) remind(ctx context.Context, c db.Cart) (bool, error) {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	now := time.Now().UTC()
+
+	rec, err := qtx.ClaimCartRecovery(ctx, db.ClaimCartRecoveryParams{
+		ID:     uuid.New(),
+		CartID: pgtype.UUID{Bytes: c.ID, Valid: true},
+		Email:  *c.Email,
+		SentAt: now,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("claiming cart %s: %w", c.ID, err)
+	}
+
+	items, err := qtx.GetCartItems(ctx, c.ID)
+	if err != nil {
+		return false, fmt.Errorf("listing cart items: %w", err)
+	}
+	if len(items) == 0 {
+		return false, nil
+	}
+
+	content := email.CartRecovery{
+		Items: make([]email.OrderLine, 0, len(items)),
+	}
+	subtotal := decimal.Zero
+	for _, it := range items {
+		total := itemPrice(it).Mul(decimal.NewFromInt32(it.Quantity))
+		subtotal = subtotal.Add(total)
+		content.Items = append(content.Items, email.OrderLine{
+			Name:     it.ProductName,
+			Quantity: it.Quantity,
+			Total:    total.StringFixed(2),
+		})
+	}
+	content.Subtotal = subtotal.StringFixed(2)
+
+	lang := email.DefaultLanguage
+	if c.CustomerID.Valid {
+		customer, err := qtx.GetCustomer(ctx, c.CustomerID.Bytes)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return false, fmt.Errorf("getting customer: %w", err)
+		}
+		if err == nil {
+			lang = email.LanguageFromMetadata(customer.Metadata)
+			if customer.FirstName != nil {
+				content.CustomerName = *customer.FirstName
+			}
+		}
+	}
+
+	token, err := s.jwtMgr.GenerateCartRestoreToken(c.ID, c.ExpiresAt)
+	if err != nil {
+		return false, err
+	}
+
+	if s.cfg.DiscountID != uuid.Nil {
+		coupon, err := s.discountSvc.GenerateCouponTx(ctx, tx, s.cfg.DiscountID, couponPrefix, now.Add(s.cfg.CouponTTL))
+		switch {
+		case errors.Is(err, discount.ErrNotFound):
+			// A deleted discount should not hold back every reminder.
+			s.logger.Warn("cart recovery discount not found; sending reminder without coupon",
+				slog.String("discount_id", s.cfg.DiscountID.String()),
+			)
+		case err != nil:
+			return false, fmt.Errorf("generating recovery coupon: %w", err)
+		default:
+			if err := qtx.SetCartRecoveryCoupon(ctx, db.SetCartRecoveryCouponParams{
+				ID:       rec.ID,
+				CouponID: pgtype.UUID{Bytes: coupon.ID, Valid: true},
+			}); err != nil {
+				return false, fmt.Errorf("recording recovery coupon: %w", err)
+			}
+			content.CouponCode = coupon.Code
+		}
+	}
+
+	// The outbox dedupes on the cart, so a reminder queued here is not sent
+	// twice if the commit below fails and the cart is picked up again.
+	if err := s.emailSvc.SendCartRecovery(ctx, c.ID, rec.Email, lang, content, token); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("committing cart recovery: %w", err)
+	}
+
+	s.logger.Info("cart reminder queued",
+		slog.String("cart_id", c.ID.String()),
+		slog.Bool("coupon", content.CouponCode != ""),
+	)
+	return true, nil
+}
+
+// Restore returns the cart behind a restore link and records that the link
+// was used. A coupon offered in the reminder is applied to the cart unless
+// it already has one.
+func (s *Service) Restore(ctx context.Context, token string) (db.Cart, error) {
+	claims, err := s.jwtMgr.ValidateCartRestoreToken(token)
+	if err != nil {
+		return db.Cart{}, ErrInvalidLink
+	}
+
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.Cart{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+	now := time.Now().UTC()
+
+	c, err := qtx.GetCart(ctx, claims.CartID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Cart{}, ErrCartUnavailable
+		}
+		return db.Cart{}, fmt.Errorf("getting cart: %w", err)
+	}
+	if c.OrderID.Valid || !c.ExpiresAt.After(now) {
+		return db.Cart{}, ErrCartUnavailable
+	}
+
+	cartID := pgtype.UUID{Bytes: c.ID, Valid: true}
+	rec, err := qtx.GetCartRecoveryByCart(ctx, cartID)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.Cart{}, ErrInvalidLink
+		}
+		return db.Cart{}, fmt.Errorf("getting cart recovery: %w", err)
+	}
+
+	if err := qtx.MarkCartRecoveryRestored(ctx, db.MarkCartRecoveryRestoredParams{
+		CartID:     cartID,
+		RestoredAt: pgtype.Timestamptz{Time: now, Valid: true},
+	}); err != nil {
+		return db.Cart{}, fmt.Errorf("marking cart restored: %w", err)
+	}
+
+	if rec.CouponID.Valid && c.CouponCode == nil {
+		coupon, err := qtx.GetCoupon(ctx, rec.CouponID.Bytes)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return db.Cart{}, fmt.Errorf("getting recovery coupon: %w", err)
+		}
+		if err == nil {
+			c, err = qtx.UpdateCart(ctx, db.UpdateCartParams{
+				ID:          c.ID,
+				Email:       c.Email,
+				CountryCode: c.CountryCode,
+				VatNumber:   c.VatNumber,
+				CouponCode:  &coupon.Code,
+				UpdatedAt:   now,
+			})
+			if err != nil {
+				return db.Cart{}, fmt.Errorf("applying recovery coupon: %w", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.Cart{}, fmt.Errorf("committing cart restore: %w", err)
+	}
+
+	s.logger.Info("cart restored from reminder", slog.String("cart_id", c.ID.String()))
+	return c, nil
+}
+
+// itemPrice is the variant price, or the product base price if the variant
+// has none.
+func itemPrice(item db.GetCartItemsRow) decimal.Decimal {
+	price := numericToDecimal(item.VariantPrice)
+	if price.IsZero() {
+		price = numericToDecimal(item.ProductBasePrice)
+	}
+	return price
+}
+
+func numericToDecimal(n pgtype.Numeric) decimal.Decimal {
+	if !n.Valid || n.Int == nil {
+		return decimal.Zero
+	}
+	return decimal.NewFromBigInt(n.Int, n.Exp)
+}
@@ -0,0 +1,74 @@
+package recovery
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// Worker runs SendReminders on a fixed interval. Several workers, in one or
+// many processes, can run against the same database; each cart is claimed
+// by exactly one of them.
+type Worker struct {
+	svc      *Service
+	interval time.Duration
+	logger   *slog.Logger
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewWorker creates a reminder worker that runs every interval.
+func NewWorker(svc *Service, interval time.Duration, logger *slog.Logger) *Worker {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Worker{
+		svc:      svc,
+		interval: interval,
+		logger:   logger,
+		ctx:      ctx,
+		cancel:   cancel,
+	}
+}
+
+// Start runs the worker loop in a goroutine. The first run happens right
+// away.
+func (w *Worker) Start() {
+	w.logger.Info("starting cart recovery worker", "interval", w.interval.String())
+	w.wg.Add(1)
+	go w.loop()
+}
+
+// Stop signals the worker to stop and waits for the run in flight to
+// finish. It is safe to call Stop multiple times.
+func (w *Worker) Stop() {
+	if w.ctx.Err() == nil {
+		w.logger.Info("stopping cart recovery worker")
+	}
+	w.cancel()
+	w.wg.Wait()
+}
+
+func (w *Worker) loop() {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := w.svc.SendReminders(w.ctx); err != nil && w.ctx.Err() == nil {
+			w.logger.Error("sending cart reminders", "error", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-w.ctx.Done():
+			w.logger.Info("cart recovery worker stopped")
+			return
+		}
+	}
+}
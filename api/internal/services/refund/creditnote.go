@@ -0,0 +1,166 @@
+package refund
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/order"
+)
+
+var hundred = decimal.NewFromInt(100)
+
+// Line is one line of a credit note. Amount is gross; Net and VAT split it by
+// VATRate (a percentage).
+type Line struct {
+	OrderItemID pgtype.UUID
+	VariantID   pgtype.UUID
+	Description string
+	Quantity    int32 // 0 when the line carries a share of a custom amount
+	Amount      decimal.Decimal
+	Net         decimal.Decimal
+	VATRate     decimal.Decimal
+	VAT         decimal.Decimal
+}
+
+// ItemLines builds credit note lines for whole units of order lines, at the
+// gross price the customer paid for them: each line's share of the order's
+// discount is taken off before its units are priced. refunded holds the units
+// of each order line already refunded.
+func ItemLines(o db.Order, items []db.OrderItem, refunded map[uuid.UUID]int32, req []ItemLine) ([]Line, error) {
+	totals := make([]decimal.Decimal, len(items))
+	for i, item := range items {
+		totals[i] = numericToDecimal(item.TotalPrice)
+	}
+	shares := order.SpreadDiscount(totals, numericToDecimal(o.DiscountAmount))
+
+	byID := make(map[uuid.UUID]db.OrderItem, len(items))
+	paid := make(map[uuid.UUID]decimal.Decimal, len(items))
+	for i, item := range items {
+		byID[item.ID] = item
+		paid[item.ID] = totals[i].Sub(shares[i])
+	}
+
+	requested := make(map[uuid.UUID]int32, len(req))
+	var lines []Line
+	for _, r := range req {
+		if r.Quantity <= 0 {
+			continue
+		}
+		item, ok := byID[r.OrderItemID]
+		if !ok {
+			return nil, fmt.Errorf("%w: order item %s is not on this order", ErrInvalidItem, r.OrderItemID)
+		}
+		before := refunded[item.ID] + requested[item.ID]
+		requested[item.ID] += r.Quantity
+		if left := item.Quantity - refunded[item.ID]; requested[item.ID] > left {
+			return nil, fmt.Errorf("%w: only %d of %q left to refund", ErrInvalidItem, left, item.ProductName)
+		}
+
+		total := paid[item.ID]
+		amount := unitsShare(total, item.Quantity, before+r.Quantity).Sub(unitsShare(total, item.Quantity, before))
+		lines = append(lines, splitVAT(Line{
+			OrderItemID: pgtype.UUID{Bytes: item.ID, Valid: true},
+			VariantID:   item.VariantID,
+			Description: itemDescription(item),
+			Quantity:    r.Quantity,
+			Amount:      amount,
+			VATRate:     numericToDecimal(item.VatRate),
+		}))
+	}
+	if len(lines) == 0 {
+		return nil, ErrInvalidAmount
+	}
+	return lines, nil
+}
+
+// unitsShare is the part of a line's paid total that covers its first units
+// units, rounded to cents. Pricing refunds as the difference of two shares
+// means refunding every unit, in any number of refunds, returns exactly the
+// line's total.
+func unitsShare(total decimal.Decimal, quantity, units int32) decimal.Decimal {
+	if quantity <= 0 {
+		return decimal.Zero
+	}
+	return total.Mul(decimal.NewFromInt32(units)).Div(decimal.NewFromInt32(quantity)).Round(2)
+}
+
+// AmountLines spreads a custom refund amount over the order's lines in
+// proportion to what each line cost, so the VAT share follows each line's
+// rate. Orders without lines get a single line at the order's effective rate;
+// if they have no VAT total either, there is nothing to base the VAT on and
+// ErrNoVATBasis is returned.
+func AmountLines(o db.Order, items []db.OrderItem, amount decimal.Decimal) ([]Line, error) {
+	amount = amount.Round(2)
+
+	weights := make([]decimal.Decimal, len(items))
+	totalWeight := decimal.Zero
+	for i, item := range items {
+		weights[i] = numericToDecimal(item.GrossUnitPrice).Mul(decimal.NewFromInt32(item.Quantity))
+		totalWeight = totalWeight.Add(weights[i])
+	}
+
+	if !totalWeight.IsPositive() {
+		total := numericToDecimal(o.Total)
+		vatTotal := numericToDecimal(o.VatTotal)
+		net := total.Sub(vatTotal)
+		if !vatTotal.IsPositive() || !net.IsPositive() {
+			return nil, ErrNoVATBasis
+		}
+		return []Line{splitVAT(Line{
+			Description: "Refund",
+			Amount:      amount,
+			VATRate:     vatTotal.Div(net).Mul(hundred).Round(2),
+		})}, nil
+	}
+
+	// Allocate in cents; the last line absorbs rounding so lines sum exactly.
+	lines := make([]Line, 0, len(items))
+	allocated := decimal.Zero
+	for i, item := range items {
+		share := amount.Mul(weights[i]).Div(totalWeight).Round(2)
+		if i == len(items)-1 {
+			share = amount.Sub(allocated)
+		}
+		allocated = allocated.Add(share)
+		if share.IsZero() {
+			continue
+		}
+		lines = append(lines, splitVAT(Line{
+			OrderItemID: pgtype.UUID{Bytes: item.ID, Valid: true},
+			VariantID:   item.VariantID,
+			Description: itemDescription(item),
+			Amount:      share,
+			VATRate:     numericToDecimal(item.VatRate),
+		}))
+	}
+	return lines, nil
+}
+
+// splitVAT fills in the net and VAT parts of a gross line amount.
+func splitVAT(l Line) Line {
+	l.VAT = l.Amount.Mul(l.VATRate).Div(hundred.Add(l.VATRate)).Round(2)
+	l.Net = l.Amount.Sub(l.VAT)
+	return l
+}
+
+// sumLines totals a credit note's lines.
+func sumLines(lines []Line) Line {
+	var total Line
+	for _, l := range lines {
+		total.Amount = total.Amount.Add(l.Amount)
+		total.Net = total.Net.Add(l.Net)
+		total.VAT = total.VAT.Add(l.VAT)
+	}
+	return total
+}
+
+func itemDescription(item db.OrderItem) string {
+	if item.VariantName != nil && *item.VariantName != "" {
+		return item.ProductName + " (" + *item.VariantName + ")"
+	}
+	return item.ProductName
+}
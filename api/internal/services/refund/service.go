@@ -0,0 +1,626 @@
+package refund
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/shopspring/decimal"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/inventory"
+	"github.com/forgecommerce/api/internal/services/order"
+	"github.com/forgecommerce/api/internal/services/webhook"
+	forgestripe "github.com/forgecommerce/api/internal/stripe"
+)
+
+var (
+	// ErrOrderNotFound is returned when the order to refund does not exist.
+	ErrOrderNotFound = errors.New("order not found")
+
+	// ErrNotRefundable is returned when the order has not been paid or has
+	// already been refunded in full.
+	ErrNotRefundable = errors.New("order cannot be refunded")
+
+	// ErrNoPayment is returned when the order has no Stripe payment to refund.
+	ErrNoPayment = errors.New("order has no Stripe payment to refund")
+
+	// ErrInvalidAmount is returned when a custom refund amount is not positive.
+	ErrInvalidAmount = errors.New("refund amount must be positive")
+
+	// ErrExceedsRemaining is returned when a refund is larger than what is
+	// left to refund on the order.
+	ErrExceedsRemaining = errors.New("refund exceeds the amount left to refund")
+
+	// ErrInvalidItem is returned when a refund line does not belong to the
+	// order or asks for more units than are left to refund.
+	ErrInvalidItem = errors.New("invalid refund line")
+
+	// ErrNoVATBasis is returned when a custom amount is refunded on an order
+	// with neither lines nor a VAT total, so the credit note's VAT cannot be
+	// worked out.
+	ErrNoVATBasis = errors.New("order has no lines or VAT total to base the refund's VAT on")
+
+	// ErrAlreadyRestocked is returned when a refund asks to restock units
+	// that are no longer held by the order, typically because cancelling it
+	// already returned them to stock.
+	ErrAlreadyRestocked = errors.New("refunded units have already been returned to stock")
+
+	// ErrGateway is returned when Stripe rejects the refund.
+	ErrGateway = errors.New("payment provider rejected the refund")
+)
+
+// Refund sources.
+const (
+	SourceAdmin  = "admin"
+	SourceStripe = "stripe"
+)
+
+// Refund statuses.
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Gateway issues refunds with the payment provider. *stripe.Service
+// implements it.
+type Gateway interface {
+	CreateRefund(ctx context.Context, input forgestripe.RefundInput) (forgestripe.RefundResult, error)
+}
+
+// Service issues refunds and records them as credit notes.
+type Service struct {
+	pool      *pgxpool.Pool
+	queries   *db.Queries
+	gateway   Gateway
+	orders    *order.Service
+	inventory *inventory.Service
+	events    webhook.Publisher
+	logger    *slog.Logger
+}
+
+// NewService creates a new refund service. Recorded refunds are published to
+// events as order.refunded; pass nil to run without webhooks.
+func NewService(pool *pgxpool.Pool, gateway Gateway, orders *order.Service, events webhook.Publisher, logger *slog.Logger) *Service {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if events == nil {
+		events = webhook.Discard
+	}
+	return &Service{
+		pool:      pool,
+		queries:   db.New(pool),
+		gateway:   gateway,
+		orders:    orders,
+		inventory: inventory.NewService(pool, events, logger),
+		events:    events,
+		logger:    logger,
+	}
+}
+
+// ItemLine asks for whole units of an order line to be refunded.
+type ItemLine struct {
+	OrderItemID uuid.UUID
+	Quantity    int32
+}
+
+// CreateParams describes a refund issued from the admin.
+type CreateParams struct {
+	OrderID uuid.UUID
+	// Items refunds whole units at their paid price. When empty, Amount is
+	// refunded instead and its VAT share is spread over the order's lines.
+	Items     []ItemLine
+	Amount    decimal.Decimal
+	Reason    string
+	Restock   bool // return refunded units to stock
+	CreatedBy pgtype.UUID
+}
+
+// Create refunds part or all of an order through Stripe. The refund is
+// recorded as pending before Stripe is called and its ID is the idempotency
+// key, so a crash in between leaves a visible record and the money it covers
+// is never refunded twice by the webhook reconciliation. It is given its
+// credit note number only once Stripe accepts it, so a failed refund leaves
+// no gap in the numbering. On success the
+// order's payment status is updated, an order event is recorded and, if
+// requested, the refunded units are restocked. A full refund also moves the
+// order to refunded when its status allows it.
+func (s *Service) Create(ctx context.Context, params CreateParams) (db.Refund, error) {
+	if len(params.Items) == 0 && !params.Amount.IsPositive() {
+		return db.Refund{}, ErrInvalidAmount
+	}
+
+	var (
+		refund          db.Refund
+		lines           []Line
+		paymentIntentID string
+	)
+	err := s.inTx(ctx, func(tx pgx.Tx, q *db.Queries) error {
+		o, err := q.GetOrderForUpdate(ctx, params.OrderID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrOrderNotFound
+			}
+			return fmt.Errorf("locking order %s: %w", params.OrderID, err)
+		}
+		if o.PaymentStatus != order.PaymentPaid && o.PaymentStatus != order.PaymentPartiallyRefunded {
+			return ErrNotRefundable
+		}
+		if o.StripePaymentIntentID == nil || *o.StripePaymentIntentID == "" {
+			return ErrNoPayment
+		}
+		paymentIntentID = *o.StripePaymentIntentID
+
+		items, err := q.ListOrderItems(ctx, o.ID)
+		if err != nil {
+			return fmt.Errorf("listing order items: %w", err)
+		}
+
+		if len(params.Items) > 0 {
+			refunded, err := s.refundedQuantities(ctx, q, o.ID)
+			if err != nil {
+				return err
+			}
+			lines, err = ItemLines(o, items, refunded, params.Items)
+			if err != nil {
+				return err
+			}
+		} else {
+			lines, err = AmountLines(o, items, params.Amount)
+			if err != nil {
+				return err
+			}
+		}
+
+		total := sumLines(lines).Amount
+		if err := checkRemaining(ctx, q, o, total); err != nil {
+			return err
+		}
+		if params.Restock {
+			if err := s.checkRestockable(ctx, tx, o.ID, lines); err != nil {
+				return err
+			}
+		}
+
+		refund, err = insertRefund(ctx, q, o.ID, nil, StatusPending, SourceAdmin, lines, params.Reason, params.Restock, nil, params.CreatedBy)
+		return err
+	})
+	if err != nil {
+		return db.Refund{}, err
+	}
+
+	result, err := s.gateway.CreateRefund(ctx, forgestripe.RefundInput{
+		PaymentIntentID: paymentIntentID,
+		Amount:          toCents(numericToDecimal(refund.Amount)),
+		IdempotencyKey:  "refund_" + refund.ID.String(),
+		Metadata: map[string]string{
+			"order_id":  params.OrderID.String(),
+			"refund_id": refund.ID.String(),
+		},
+	})
+	if err != nil {
+		msg := err.Error()
+		if markErr := s.queries.MarkRefundFailed(ctx, db.MarkRefundFailedParams{
+			ID:             refund.ID,
+			FailureMessage: &msg,
+		}); markErr != nil {
+			s.logger.Error("failed to mark refund failed", "error", markErr, "refund_id", refund.ID)
+		}
+		return db.Refund{}, fmt.Errorf("%w: %w", ErrGateway, err)
+	}
+
+	var fullyRefunded bool
+	err = s.inTx(ctx, func(tx pgx.Tx, q *db.Queries) error {
+		number, err := q.NextCreditNoteNumber(ctx)
+		if err != nil {
+			return fmt.Errorf("reserving credit note number: %w", err)
+		}
+		refund, err = q.MarkRefundSucceeded(ctx, db.MarkRefundSucceededParams{
+			ID:               refund.ID,
+			StripeRefundID:   &result.RefundID,
+			CreditNoteNumber: &number,
+		})
+		if err != nil {
+			return fmt.Errorf("marking refund succeeded: %w", err)
+		}
+
+		if params.Restock {
+			if err := s.restock(ctx, tx, refund, lines, params.CreatedBy); err != nil {
+				return err
+			}
+		}
+
+		fullyRefunded, err = s.settleOrder(ctx, q, params.OrderID, refund, params.CreatedBy)
+		return err
+	})
+	if err != nil {
+		// Stripe has already refunded the money; the record stays pending and
+		// the charge.refunded webhook will not double count it.
+		return db.Refund{}, fmt.Errorf("recording refund %s: %w", refund.ID, err)
+	}
+
+	s.logger.Info("order refunded",
+		slog.String("order_id", params.OrderID.String()),
+		slog.String("refund_id", refund.ID.String()),
+		slog.Int64("credit_note_number", *refund.CreditNoteNumber),
+		slog.String("amount", numericToDecimal(refund.Amount).StringFixed(2)),
+	)
+
+	if fullyRefunded {
+		s.markOrderRefunded(ctx, params.OrderID)
+	}
+	s.publishRefunded(ctx, refund)
+	return refund, nil
+}
+
+// SyncCharge reconciles a Stripe charge.refunded event with the recorded
+// refunds. amountRefunded is the charge's cumulative refunded amount in cents.
+// Any difference not yet recorded, typically a refund made in the Stripe
+// dashboard, is recorded as a credit note. It returns false when there was
+// nothing new to record.
+func (s *Service) SyncCharge(ctx context.Context, paymentIntentID string, amountRefunded int64, stripeRefundID string) (db.Refund, bool, error) {
+	var (
+		refund        db.Refund
+		recorded      bool
+		fullyRefunded bool
+		orderID       uuid.UUID
+	)
+	err := s.inTx(ctx, func(tx pgx.Tx, q *db.Queries) error {
+		found, err := q.GetOrderByPaymentIntent(ctx, &paymentIntentID)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrOrderNotFound
+			}
+			return fmt.Errorf("finding order for payment intent %s: %w", paymentIntentID, err)
+		}
+		o, err := q.GetOrderForUpdate(ctx, found.ID)
+		if err != nil {
+			return fmt.Errorf("locking order %s: %w", found.ID, err)
+		}
+		orderID = o.ID
+
+		refundedSoFar, err := q.SumOrderRefunds(ctx, o.ID)
+		if err != nil {
+			return fmt.Errorf("summing refunds: %w", err)
+		}
+		missing := decimal.New(amountRefunded, -2).Sub(numericToDecimal(refundedSoFar))
+		if !missing.IsPositive() {
+			return nil
+		}
+
+		items, err := q.ListOrderItems(ctx, o.ID)
+		if err != nil {
+			return fmt.Errorf("listing order items: %w", err)
+		}
+
+		// Only claim the Stripe refund ID if no other record has it.
+		var refundID *string
+		if stripeRefundID != "" {
+			if _, err := q.GetRefundByStripeID(ctx, &stripeRefundID); errors.Is(err, pgx.ErrNoRows) {
+				refundID = &stripeRefundID
+			} else if err != nil {
+				return fmt.Errorf("looking up stripe refund %s: %w", stripeRefundID, err)
+			}
+		}
+
+		lines, err := AmountLines(o, items, missing)
+		if err != nil {
+			return err
+		}
+
+		number, err := q.NextCreditNoteNumber(ctx)
+		if err != nil {
+			return fmt.Errorf("reserving credit note number: %w", err)
+		}
+		refund, err = insertRefund(ctx, q, o.ID, &number, StatusSucceeded, SourceStripe, lines, "Refunded in Stripe", false, refundID, pgtype.UUID{})
+		if err != nil {
+			return err
+		}
+		recorded = true
+
+		fullyRefunded, err = s.settleOrder(ctx, q, o.ID, refund, pgtype.UUID{})
+		return err
+	})
+	if err != nil || !recorded {
+		return db.Refund{}, false, err
+	}
+
+	s.logger.Info("stripe refund recorded",
+		slog.String("order_id", orderID.String()),
+		slog.String("refund_id", refund.ID.String()),
+		slog.Int64("credit_note_number", *refund.CreditNoteNumber),
+		slog.String("amount", numericToDecimal(refund.Amount).StringFixed(2)),
+	)
+
+	if fullyRefunded {
+		s.markOrderRefunded(ctx, orderID)
+	}
+	s.publishRefunded(ctx, refund)
+	return refund, true, nil
+}
+
+// ListForOrder returns an order's refunds, newest first.
+func (s *Service) ListForOrder(ctx context.Context, orderID uuid.UUID) ([]db.Refund, error) {
+	refunds, err := s.queries.ListOrderRefunds(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("listing refunds for order %s: %w", orderID, err)
+	}
+	return refunds, nil
+}
+
+// ListItems returns the lines of a credit note.
+func (s *Service) ListItems(ctx context.Context, refundID uuid.UUID) ([]db.RefundItem, error) {
+	items, err := s.queries.ListRefundItems(ctx, refundID)
+	if err != nil {
+		return nil, fmt.Errorf("listing items for refund %s: %w", refundID, err)
+	}
+	return items, nil
+}
+
+// Remaining returns how much of the order's total is left to refund. Refunds
+// still pending with Stripe count as refunded.
+func (s *Service) Remaining(ctx context.Context, o db.Order) (decimal.Decimal, error) {
+	refundedSoFar, err := s.queries.SumOrderRefunds(ctx, o.ID)
+	if err != nil {
+		return decimal.Zero, fmt.Errorf("summing refunds for order %s: %w", o.ID, err)
+	}
+	return numericToDecimal(o.Total).Sub(numericToDecimal(refundedSoFar)), nil
+}
+
+// RefundedQuantities returns how many units of each order line have been
+// refunded, keyed by order item ID.
+func (s *Service) RefundedQuantities(ctx context.Context, orderID uuid.UUID) (map[uuid.UUID]int32, error) {
+	return s.refundedQuantities(ctx, s.queries, orderID)
+}
+
+func (s *Service) refundedQuantities(ctx context.Context, q *db.Queries, orderID uuid.UUID) (map[uuid.UUID]int32, error) {
+	rows, err := q.SumRefundedQuantities(ctx, orderID)
+	if err != nil {
+		return nil, fmt.Errorf("summing refunded quantities: %w", err)
+	}
+	refunded := make(map[uuid.UUID]int32, len(rows))
+	for _, r := range rows {
+		refunded[uuid.UUID(r.OrderItemID.Bytes)] = r.Quantity
+	}
+	return refunded, nil
+}
+
+// checkRemaining rejects a refund larger than what is left on the order.
+func checkRemaining(ctx context.Context, q *db.Queries, o db.Order, amount decimal.Decimal) error {
+	refundedSoFar, err := q.SumOrderRefunds(ctx, o.ID)
+	if err != nil {
+		return fmt.Errorf("summing refunds: %w", err)
+	}
+	remaining := numericToDecimal(o.Total).Sub(numericToDecimal(refundedSoFar))
+	if amount.GreaterThan(remaining) {
+		return fmt.Errorf("%w (%s left)", ErrExceedsRemaining, remaining.StringFixed(2))
+	}
+	return nil
+}
+
+// insertRefund writes a refund and its credit note lines. Pending refunds
+// have no credit note number until they succeed.
+func insertRefund(ctx context.Context, q *db.Queries, orderID uuid.UUID, creditNoteNumber *int64, status, source string, lines []Line, reason string, restock bool, stripeRefundID *string, createdBy pgtype.UUID) (db.Refund, error) {
+	total := sumLines(lines)
+	refund, err := q.CreateRefund(ctx, db.CreateRefundParams{
+		ID:               uuid.New(),
+		OrderID:          orderID,
+		CreditNoteNumber: creditNoteNumber,
+		Status:           status,
+		Source:           source,
+		Amount:           decimalToNumeric(total.Amount),
+		NetAmount:        decimalToNumeric(total.Net),
+		VatAmount:        decimalToNumeric(total.VAT),
+		Reason:           strPtr(reason),
+		Restocked:        restock,
+		StripeRefundID:   stripeRefundID,
+		CreatedBy:        createdBy,
+		CreatedAt:        time.Now().UTC(),
+	})
+	if err != nil {
+		return db.Refund{}, fmt.Errorf("creating refund: %w", err)
+	}
+
+	for _, l := range lines {
+		if _, err := q.CreateRefundItem(ctx, db.CreateRefundItemParams{
+			ID:          uuid.New(),
+			RefundID:    refund.ID,
+			OrderItemID: l.OrderItemID,
+			Description: l.Description,
+			Quantity:    l.Quantity,
+			Amount:      decimalToNumeric(l.Amount),
+			NetAmount:   decimalToNumeric(l.Net),
+			VatRate:     decimalToNumeric(l.VATRate),
+			VatAmount:   decimalToNumeric(l.VAT),
+		}); err != nil {
+			return db.Refund{}, fmt.Errorf("creating refund line %q: %w", l.Description, err)
+		}
+	}
+	return refund, nil
+}
+
+// checkRestockable rejects restocking units the order no longer holds, so a
+// cancelled and restocked order is not restocked again by its refund.
+func (s *Service) checkRestockable(ctx context.Context, tx pgx.Tx, orderID uuid.UUID, lines []Line) error {
+	committed, err := s.inventory.WithTx(tx).CommittedOrderUnits(ctx, orderID)
+	if err != nil {
+		return err
+	}
+	for variantID, qty := range restockUnits(lines) {
+		if committed[variantID] < qty {
+			return ErrAlreadyRestocked
+		}
+	}
+	return nil
+}
+
+// restock returns refunded units to stock, taking them off the units the
+// order holds so that cancelling it later does not return them again.
+func (s *Service) restock(ctx context.Context, tx pgx.Tx, refund db.Refund, lines []Line, createdBy pgtype.UUID) error {
+	units := restockUnits(lines)
+	restock := make([]inventory.ReservationLine, 0, len(units))
+	var want int32
+	for variantID, qty := range units {
+		restock = append(restock, inventory.ReservationLine{VariantID: variantID, Quantity: qty})
+		want += qty
+	}
+
+	n, err := s.inventory.WithTx(tx).RestockOrderLines(ctx, refund.OrderID, restock, inventory.Change{
+		Notes:     fmt.Sprintf("Credit note %d", *refund.CreditNoteNumber),
+		CreatedBy: createdBy,
+	})
+	if err != nil {
+		return fmt.Errorf("restocking refund: %w", err)
+	}
+	if n < want {
+		// The order was restocked between taking the refund and Stripe
+		// accepting it; the missing units are already back in stock.
+		s.logger.Warn("refund restocked fewer units than refunded",
+			slog.String("refund_id", refund.ID.String()),
+			slog.Int("restocked", int(n)),
+			slog.Int("refunded", int(want)),
+		)
+	}
+	return nil
+}
+
+// restockUnits totals the whole units of a credit note's lines per variant.
+// Lines whose variant has since been deleted are left out.
+func restockUnits(lines []Line) map[uuid.UUID]int32 {
+	units := make(map[uuid.UUID]int32)
+	for _, l := range lines {
+		if l.Quantity == 0 || !l.VariantID.Valid {
+			continue
+		}
+		units[uuid.UUID(l.VariantID.Bytes)] += l.Quantity
+	}
+	return units
+}
+
+// settleOrder updates the order's payment status after a refund and records
+// an order event. It reports whether the order is now refunded in full.
+func (s *Service) settleOrder(ctx context.Context, q *db.Queries, orderID uuid.UUID, refund db.Refund, createdBy pgtype.UUID) (bool, error) {
+	o, err := q.GetOrder(ctx, orderID)
+	if err != nil {
+		return false, fmt.Errorf("fetching order %s: %w", orderID, err)
+	}
+	refundedSoFar, err := q.SumOrderRefunds(ctx, orderID)
+	if err != nil {
+		return false, fmt.Errorf("summing refunds: %w", err)
+	}
+
+	full := !numericToDecimal(refundedSoFar).LessThan(numericToDecimal(o.Total))
+	paymentStatus := order.PaymentPartiallyRefunded
+	if full {
+		paymentStatus = order.PaymentRefunded
+	}
+	now := time.Now().UTC()
+	if _, err := q.UpdateOrderPaymentStatus(ctx, db.UpdateOrderPaymentStatusParams{
+		ID:            orderID,
+		PaymentStatus: paymentStatus,
+		UpdatedAt:     now,
+	}); err != nil {
+		return false, fmt.Errorf("updating payment status: %w", err)
+	}
+
+	data, err := json.Marshal(map[string]any{
+		"refund_id":          refund.ID,
+		"credit_note_number": refund.CreditNoteNumber,
+		"amount":             numericToDecimal(refund.Amount).StringFixed(2),
+		"vat_amount":         numericToDecimal(refund.VatAmount).StringFixed(2),
+		"source":             refund.Source,
+		"restocked":          refund.Restocked,
+		"payment_status":     paymentStatus,
+	})
+	if err != nil {
+		return false, fmt.Errorf("encoding refund event: %w", err)
+	}
+	if err := q.CreateOrderEvent(ctx, db.CreateOrderEventParams{
+		ID:        uuid.New(),
+		OrderID:   orderID,
+		EventType: "refund_created",
+		Data:      data,
+		CreatedBy: createdBy,
+		CreatedAt: now,
+	}); err != nil {
+		return false, fmt.Errorf("creating refund event: %w", err)
+	}
+	return full, nil
+}
+
+// markOrderRefunded moves a fully refunded order to the refunded status when
+// the status machine allows it. Failures are logged; the refund itself has
+// already been recorded.
+func (s *Service) markOrderRefunded(ctx context.Context, orderID uuid.UUID) {
+	_, err := s.orders.UpdateStatus(ctx, orderID, order.StatusRefunded)
+	if err != nil && !errors.Is(err, order.ErrInvalidTransition) {
+		s.logger.Error("failed to mark order refunded", "error", err, "order_id", orderID)
+	}
+}
+
+// publishRefunded emits order.refunded with the order as it stands after the
+// refund.
+func (s *Service) publishRefunded(ctx context.Context, refund db.Refund) {
+	o, err := s.queries.GetOrder(ctx, refund.OrderID)
+	if err != nil {
+		s.logger.Error("failed to load order for refund event", "error", err, "order_id", refund.OrderID)
+		return
+	}
+	items, err := s.queries.ListOrderItems(ctx, o.ID)
+	if err != nil {
+		s.logger.Error("failed to load order items for refund event", "error", err, "order_id", o.ID)
+		return
+	}
+	s.events.Publish(ctx, webhook.EventOrderRefunded, webhook.OrderRefundedData{
+		Order:  webhook.NewOrder(o, items),
+		Refund: webhook.NewRefund(refund),
+	})
+}
+
+// inTx runs fn in a new transaction.
+func (s *Service) inTx(ctx context.Context, fn func(tx pgx.Tx, q *db.Queries) error) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	if err := fn(tx, s.queries.WithTx(tx)); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+func numericToDecimal(n pgtype.Numeric) decimal.Decimal {
+	if !n.Valid || n.Int == nil {
+		return decimal.Zero
+	}
+	return decimal.NewFromBigInt(n.Int, n.Exp)
+}
+
+func decimalToNumeric(d decimal.Decimal) pgtype.Numeric {
+	return pgtype.Numeric{Int: d.Coefficient(), Exp: d.Exponent(), Valid: true}
+}
+
+func toCents(d decimal.Decimal) int64 {
+	return d.Mul(decimal.NewFromInt(100)).Round(0).IntPart()
+}
+
+func strPtr(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
@@ -0,0 +1,508 @@
+package refund_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/inventory"
+	"github.com/forgecommerce/api/internal/services/order"
+	"github.com/forgecommerce/api/internal/services/refund"
+	"github.com/forgecommerce/api/internal/services/webhook"
+	forgestripe "github.com/forgecommerce/api/internal/stripe"
+	"github.com/forgecommerce/api/internal/testutil"
+)
+
+var testDB *testutil.TestDB
+
+func TestMain(m *testing.M) {
+	var code int
+	defer func() { os.Exit(code) }()
+
+	db, err := testutil.SetupTestDB()
+	if err != nil {
+		log.Fatalf("setting up test database: %v", err)
+	}
+	defer db.Close()
+	testDB = db
+
+	code = m.Run()
+}
+
+// fakeGateway records refund requests instead of calling Stripe.
+type fakeGateway struct {
+	calls []forgestripe.RefundInput
+	err   error
+}
+
+func (g *fakeGateway) CreateRefund(_ context.Context, input forgestripe.RefundInput) (forgestripe.RefundResult, error) {
+	g.calls = append(g.calls, input)
+	if g.err != nil {
+		return forgestripe.RefundResult{}, g.err
+	}
+	return forgestripe.RefundResult{RefundID: "re_" + uuid.NewString()[:8], Status: "succeeded"}, nil
+}
+
+func newService(g refund.Gateway) (*refund.Service, *order.Service) {
+	orders := order.NewService(testDB.Pool, nil, nil)
+	return refund.NewService(testDB.Pool, g, orders, nil, nil), orders
+}
+
+func cents(c int64) pgtype.Numeric {
+	return pgtype.Numeric{Int: big.NewInt(c), Exp: -2, Valid: true}
+}
+
+func numericString(n pgtype.Numeric) string {
+	return decimal.NewFromBigInt(n.Int, n.Exp).StringFixed(2)
+}
+
+// paidOrder creates a paid order with a 21% line (2 × 60.50) and a 10% line
+// (1 × 22.00), total 143.00 of which 23.00 is VAT.
+func paidOrder(t *testing.T, orders *order.Service, variantID pgtype.UUID) (db.Order, []db.OrderItem) {
+	t.Helper()
+	pi := "pi_test_" + uuid.NewString()[:8]
+	o, items, err := orders.Create(context.Background(), order.CreateOrderParams{
+		Status:                "confirmed",
+		Email:                 "buyer@example.com",
+		PaymentStatus:         "paid",
+		StripePaymentIntentID: &pi,
+		BillingAddress:        json.RawMessage(`{}`),
+		ShippingAddress:       json.RawMessage(`{}`),
+		Subtotal:              cents(14300),
+		ShippingFee:           cents(0),
+		ShippingExtraFees:     cents(0),
+		DiscountAmount:        cents(0),
+		VatTotal:              cents(2300),
+		Total:                 cents(14300),
+		Metadata:              json.RawMessage(`{}`),
+		Items: []order.CreateOrderItemInput{
+			{
+				VariantID:      variantID,
+				ProductName:    "Wallet",
+				Quantity:       2,
+				UnitPrice:      cents(6050),
+				TotalPrice:     cents(12100),
+				VatRate:        cents(2100),
+				VatAmount:      cents(2100),
+				NetUnitPrice:   cents(5000),
+				GrossUnitPrice: cents(6050),
+				Metadata:       json.RawMessage(`{}`),
+			},
+			{
+				ProductName:    "Book",
+				Quantity:       1,
+				UnitPrice:      cents(2200),
+				TotalPrice:     cents(2200),
+				VatRate:        cents(1000),
+				VatAmount:      cents(200),
+				NetUnitPrice:   cents(2000),
+				GrossUnitPrice: cents(2200),
+				Metadata:       json.RawMessage(`{}`),
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("creating order: %v", err)
+	}
+	return o, items
+}
+
+// commitStock records the sale of qty units of a variant against an order,
+// as the payment webhook does.
+func commitStock(t *testing.T, orderID, variantID uuid.UUID, qty int32) {
+	t.Helper()
+	ctx := context.Background()
+	cartID := uuid.New()
+	if _, err := testDB.Pool.Exec(ctx, `INSERT INTO carts (id) VALUES ($1)`, cartID); err != nil {
+		t.Fatalf("creating cart: %v", err)
+	}
+	if _, err := inventory.NewService(testDB.Pool, nil, nil).CommitCartLines(ctx, cartID, "cs_test_"+uuid.NewString()[:8], orderID,
+		[]inventory.ReservationLine{{VariantID: variantID, Quantity: qty}}); err != nil {
+		t.Fatalf("committing stock: %v", err)
+	}
+}
+
+func variantStock(t *testing.T, id uuid.UUID) int32 {
+	t.Helper()
+	var stock int32
+	if err := testDB.Pool.QueryRow(context.Background(),
+		`SELECT stock_quantity FROM product_variants WHERE id = $1`, id).Scan(&stock); err != nil {
+		t.Fatalf("reading stock: %v", err)
+	}
+	return stock
+}
+
+func TestCreate_ItemRefundSplitsVATAndRestocks(t *testing.T) {
+	testDB.Truncate(t)
+	gw := &fakeGateway{}
+	svc, orders := newService(gw)
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Wallet", "wallet")
+	variant := testDB.FixtureVariant(t, product.ID, "WAL-1", 5)
+	o, items := paidOrder(t, orders, pgtype.UUID{Bytes: variant.ID, Valid: true})
+	commitStock(t, o.ID, variant.ID, 2)
+
+	rf, err := svc.Create(ctx, refund.CreateParams{
+		OrderID: o.ID,
+		Items:   []refund.ItemLine{{OrderItemID: items[0].ID, Quantity: 1}},
+		Restock: true,
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	if rf.Status != refund.StatusSucceeded {
+		t.Errorf("status: got %q, want succeeded", rf.Status)
+	}
+	if got := numericString(rf.Amount); got != "60.50" {
+		t.Errorf("amount: got %s, want 60.50", got)
+	}
+	if got := numericString(rf.VatAmount); got != "10.50" {
+		t.Errorf("vat: got %s, want 10.50", got)
+	}
+	if got := numericString(rf.NetAmount); got != "50.00" {
+		t.Errorf("net: got %s, want 50.00", got)
+	}
+
+	if len(gw.calls) != 1 || gw.calls[0].Amount != 6050 || gw.calls[0].PaymentIntentID != *o.StripePaymentIntentID {
+		t.Errorf("unexpected gateway calls: %+v", gw.calls)
+	}
+
+	updated, _ := orders.Get(ctx, o.ID)
+	if updated.PaymentStatus != "partially_refunded" {
+		t.Errorf("payment status: got %q, want partially_refunded", updated.PaymentStatus)
+	}
+
+	if stock := variantStock(t, variant.ID); stock != 4 {
+		t.Errorf("stock: got %d, want 4", stock)
+	}
+
+	events, _ := orders.ListEvents(ctx, o.ID)
+	found := false
+	for _, e := range events {
+		if e.EventType == "refund_created" {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("expected a refund_created order event")
+	}
+
+	// Only one wallet is left to refund.
+	_, err = svc.Create(ctx, refund.CreateParams{
+		OrderID: o.ID,
+		Items:   []refund.ItemLine{{OrderItemID: items[0].ID, Quantity: 2}},
+	})
+	if !errors.Is(err, refund.ErrInvalidItem) {
+		t.Errorf("expected ErrInvalidItem, got %v", err)
+	}
+}
+
+func TestCreate_RestockAndCancelReturnUnitsOnce(t *testing.T) {
+	testDB.Truncate(t)
+	svc, orders := newService(&fakeGateway{})
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Wallet", "wallet")
+	variant := testDB.FixtureVariant(t, product.ID, "WAL-1", 5)
+
+	// Refund one wallet into stock, then cancel: only the other comes back.
+	o, items := paidOrder(t, orders, pgtype.UUID{Bytes: variant.ID, Valid: true})
+	commitStock(t, o.ID, variant.ID, 2)
+	if _, err := svc.Create(ctx, refund.CreateParams{
+		OrderID: o.ID,
+		Items:   []refund.ItemLine{{OrderItemID: items[0].ID, Quantity: 1}},
+		Restock: true,
+	}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, err := orders.UpdateStatus(ctx, o.ID, order.StatusCancelled); err != nil {
+		t.Fatalf("cancelling: %v", err)
+	}
+	if stock := variantStock(t, variant.ID); stock != 5 {
+		t.Errorf("stock after refund and cancel: got %d, want 5", stock)
+	}
+
+	// Cancel first: the refund may not restock again.
+	o, items = paidOrder(t, orders, pgtype.UUID{Bytes: variant.ID, Valid: true})
+	commitStock(t, o.ID, variant.ID, 2)
+	if _, err := orders.UpdateStatus(ctx, o.ID, order.StatusCancelled); err != nil {
+		t.Fatalf("cancelling: %v", err)
+	}
+	_, err := svc.Create(ctx, refund.CreateParams{
+		OrderID: o.ID,
+		Items:   []refund.ItemLine{{OrderItemID: items[0].ID, Quantity: 2}},
+		Restock: true,
+	})
+	if !errors.Is(err, refund.ErrAlreadyRestocked) {
+		t.Errorf("expected ErrAlreadyRestocked, got %v", err)
+	}
+	if stock := variantStock(t, variant.ID); stock != 5 {
+		t.Errorf("stock after cancel and refund: got %d, want 5", stock)
+	}
+}
+
+func TestCreate_ItemRefundOnDiscountedOrder(t *testing.T) {
+	testDB.Truncate(t)
+	svc, orders := newService(&fakeGateway{})
+	ctx := context.Background()
+
+	// A 10% coupon: the customer paid 128.70 instead of 143.00.
+	o, items := paidOrder(t, orders, pgtype.UUID{})
+	if _, err := testDB.Pool.Exec(ctx, `UPDATE orders SET discount_amount = 14.30, total = 128.70 WHERE id = $1`, o.ID); err != nil {
+		t.Fatalf("discounting order: %v", err)
+	}
+
+	first, err := svc.Create(ctx, refund.CreateParams{
+		OrderID: o.ID,
+		Items:   []refund.ItemLine{{OrderItemID: items[0].ID, Quantity: 1}},
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	// The wallets carry 12.10 of the discount: 108.90 for two, 18.90 VAT.
+	if got := numericString(first.Amount); got != "54.45" {
+		t.Errorf("amount: got %s, want 54.45", got)
+	}
+	if got := numericString(first.VatAmount); got != "9.45" {
+		t.Errorf("vat: got %s, want 9.45", got)
+	}
+
+	// Refunding everything else returns exactly what was charged.
+	rest, err := svc.Create(ctx, refund.CreateParams{
+		OrderID: o.ID,
+		Items: []refund.ItemLine{
+			{OrderItemID: items[0].ID, Quantity: 1},
+			{OrderItemID: items[1].ID, Quantity: 1},
+		},
+	})
+	if err != nil {
+		t.Fatalf("refunding the rest: %v", err)
+	}
+	if got := numericString(rest.Amount); got != "74.25" {
+		t.Errorf("amount: got %s, want 74.25", got)
+	}
+	if got := numericString(rest.VatAmount); got != "11.25" {
+		t.Errorf("vat: got %s, want 11.25", got)
+	}
+
+	updated, _ := orders.Get(ctx, o.ID)
+	if updated.PaymentStatus != "refunded" {
+		t.Errorf("payment status: got %q, want refunded", updated.PaymentStatus)
+	}
+}
+
+func TestCreate_CustomAmountSpreadsOverRates(t *testing.T) {
+	testDB.Truncate(t)
+	svc, orders := newService(&fakeGateway{})
+	ctx := context.Background()
+
+	o, _ := paidOrder(t, orders, pgtype.UUID{})
+
+	rf, err := svc.Create(ctx, refund.CreateParams{
+		OrderID: o.ID,
+		Amount:  decimal.RequireFromString("14.30"),
+		Reason:  "Goodwill",
+	})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 14.30 is 10% of the order: 12.10 at 21% (2.10 VAT), 2.20 at 10% (0.20).
+	if got := numericString(rf.VatAmount); got != "2.30" {
+		t.Errorf("vat: got %s, want 2.30", got)
+	}
+	lines, err := svc.ListItems(ctx, rf.ID)
+	if err != nil {
+		t.Fatalf("ListItems: %v", err)
+	}
+	if len(lines) != 2 {
+		t.Fatalf("lines: got %d, want 2", len(lines))
+	}
+}
+
+func TestCreate_FullRefundMarksOrderRefunded(t *testing.T) {
+	testDB.Truncate(t)
+	svc, orders := newService(&fakeGateway{})
+	ctx := context.Background()
+
+	o, _ := paidOrder(t, orders, pgtype.UUID{})
+
+	if _, err := svc.Create(ctx, refund.CreateParams{OrderID: o.ID, Amount: decimal.RequireFromString("143.00")}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	updated, _ := orders.Get(ctx, o.ID)
+	if updated.PaymentStatus != "refunded" {
+		t.Errorf("payment status: got %q, want refunded", updated.PaymentStatus)
+	}
+	if updated.Status != "refunded" {
+		t.Errorf("status: got %q, want refunded", updated.Status)
+	}
+
+	_, err := svc.Create(ctx, refund.CreateParams{OrderID: o.ID, Amount: decimal.RequireFromString("1.00")})
+	if !errors.Is(err, refund.ErrNotRefundable) {
+		t.Errorf("expected ErrNotRefundable, got %v", err)
+	}
+}
+
+func TestCreate_Rejections(t *testing.T) {
+	testDB.Truncate(t)
+	svc, orders := newService(&fakeGateway{})
+	ctx := context.Background()
+
+	o, _ := paidOrder(t, orders, pgtype.UUID{})
+
+	_, err := svc.Create(ctx, refund.CreateParams{OrderID: o.ID, Amount: decimal.RequireFromString("200")})
+	if !errors.Is(err, refund.ErrExceedsRemaining) {
+		t.Errorf("over-refund: expected ErrExceedsRemaining, got %v", err)
+	}
+
+	_, err = svc.Create(ctx, refund.CreateParams{OrderID: o.ID})
+	if !errors.Is(err, refund.ErrInvalidAmount) {
+		t.Errorf("no amount: expected ErrInvalidAmount, got %v", err)
+	}
+
+	_, err = svc.Create(ctx, refund.CreateParams{OrderID: uuid.New(), Amount: decimal.NewFromInt(1)})
+	if !errors.Is(err, refund.ErrOrderNotFound) {
+		t.Errorf("missing order: expected ErrOrderNotFound, got %v", err)
+	}
+
+	// An order without lines or VAT gives a custom amount nothing to split.
+	pi := "pi_test_" + uuid.NewString()[:8]
+	bare, _, err := orders.Create(ctx, order.CreateOrderParams{
+		Status:                "confirmed",
+		Email:                 "buyer@example.com",
+		PaymentStatus:         "paid",
+		StripePaymentIntentID: &pi,
+		BillingAddress:        json.RawMessage(`{}`),
+		ShippingAddress:       json.RawMessage(`{}`),
+		Subtotal:              cents(5000),
+		ShippingFee:           cents(0),
+		ShippingExtraFees:     cents(0),
+		DiscountAmount:        cents(0),
+		VatTotal:              cents(0),
+		Total:                 cents(5000),
+		Metadata:              json.RawMessage(`{}`),
+	})
+	if err != nil {
+		t.Fatalf("creating order: %v", err)
+	}
+	_, err = svc.Create(ctx, refund.CreateParams{OrderID: bare.ID, Amount: decimal.NewFromInt(10)})
+	if !errors.Is(err, refund.ErrNoVATBasis) {
+		t.Errorf("no lines: expected ErrNoVATBasis, got %v", err)
+	}
+}
+
+func TestCreate_GatewayFailureMarksRefundFailed(t *testing.T) {
+	testDB.Truncate(t)
+	svc, orders := newService(&fakeGateway{err: errors.New("card_declined")})
+	ctx := context.Background()
+
+	o, _ := paidOrder(t, orders, pgtype.UUID{})
+
+	_, err := svc.Create(ctx, refund.CreateParams{OrderID: o.ID, Amount: decimal.NewFromInt(10)})
+	if !errors.Is(err, refund.ErrGateway) {
+		t.Fatalf("expected ErrGateway, got %v", err)
+	}
+
+	refunds, _ := svc.ListForOrder(ctx, o.ID)
+	if len(refunds) != 1 || refunds[0].Status != refund.StatusFailed {
+		t.Fatalf("expected one failed refund, got %+v", refunds)
+	}
+
+	// A failed refund does not use up the refundable amount.
+	remaining, _ := svc.Remaining(ctx, o)
+	if remaining.StringFixed(2) != "143.00" {
+		t.Errorf("remaining: got %s, want 143.00", remaining.StringFixed(2))
+	}
+	updated, _ := orders.Get(ctx, o.ID)
+	if updated.PaymentStatus != "paid" {
+		t.Errorf("payment status: got %q, want paid", updated.PaymentStatus)
+	}
+
+	// Only refunds Stripe accepts take a credit note number, so the next one
+	// is CN-1.
+	if refunds[0].CreditNoteNumber != nil {
+		t.Errorf("failed refund has credit note number %d", *refunds[0].CreditNoteNumber)
+	}
+	svc, _ = newService(&fakeGateway{})
+	rf, err := svc.Create(ctx, refund.CreateParams{OrderID: o.ID, Amount: decimal.NewFromInt(10)})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if rf.CreditNoteNumber == nil || *rf.CreditNoteNumber != 1 {
+		t.Errorf("credit note number: got %v, want 1", rf.CreditNoteNumber)
+	}
+}
+
+func TestSyncCharge_RecordsDashboardRefundsOnce(t *testing.T) {
+	testDB.Truncate(t)
+	svc, orders := newService(&fakeGateway{})
+	ctx := context.Background()
+
+	o, _ := paidOrder(t, orders, pgtype.UUID{})
+
+	// An admin refund of 10.00, then the webhook for it.
+	if _, err := svc.Create(ctx, refund.CreateParams{OrderID: o.ID, Amount: decimal.NewFromInt(10)}); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if _, recorded, err := svc.SyncCharge(ctx, *o.StripePaymentIntentID, 1000, ""); err != nil || recorded {
+		t.Fatalf("admin refund should not be recorded again: recorded=%v err=%v", recorded, err)
+	}
+
+	// A further 5.00 refunded in the Stripe dashboard.
+	rf, recorded, err := svc.SyncCharge(ctx, *o.StripePaymentIntentID, 1500, "re_dashboard")
+	if err != nil || !recorded {
+		t.Fatalf("SyncCharge: recorded=%v err=%v", recorded, err)
+	}
+	if rf.Source != refund.SourceStripe || numericString(rf.Amount) != "5.00" {
+		t.Errorf("unexpected refund: source=%s amount=%s", rf.Source, numericString(rf.Amount))
+	}
+
+	// Redelivery of the same event is a no-op.
+	if _, recorded, _ := svc.SyncCharge(ctx, *o.StripePaymentIntentID, 1500, "re_dashboard"); recorded {
+		t.Error("redelivered event should not record a refund")
+	}
+
+	if _, _, err := svc.SyncCharge(ctx, "pi_unknown", 100, ""); !errors.Is(err, refund.ErrOrderNotFound) {
+		t.Errorf("expected ErrOrderNotFound, got %v", err)
+	}
+}
+
+func TestCreate_PublishesOrderRefunded(t *testing.T) {
+	testDB.Truncate(t)
+	events := &testutil.Events{}
+	orders := order.NewService(testDB.Pool, nil, nil)
+	svc := refund.NewService(testDB.Pool, &fakeGateway{}, orders, events, nil)
+	ctx := context.Background()
+
+	o, _ := paidOrder(t, orders, pgtype.UUID{})
+
+	rf, err := svc.Create(ctx, refund.CreateParams{OrderID: o.ID, Amount: decimal.NewFromInt(20)})
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	published := events.OfType(webhook.EventOrderRefunded)
+	if len(published) != 1 {
+		t.Fatalf("got events %v, want one order.refunded", events.Types())
+	}
+	data := published[0].(webhook.OrderRefundedData)
+	if data.Refund.ID != rf.ID || data.Refund.Amount != "20.00" {
+		t.Errorf("refund payload: %+v", data.Refund)
+	}
+	if data.Order.PaymentStatus != "partially_refunded" {
+		t.Errorf("order payment status: got %q, want partially_refunded", data.Order.PaymentStatus)
+	}
+}
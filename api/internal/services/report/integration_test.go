@@ -2,6 +2,7 @@ package report_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"math"
@@ -577,6 +578,149 @@ func TestPredictSales_WithRecentData(t *testing.T) {
 	}
 }
 
+// --------------------------------------------------------------------------
+// Cart Recovery
+// --------------------------------------------------------------------------
+
+func TestGetCartRecoveryReport(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+
+	sent := time.Date(2026, 2, 10, 10, 0, 0, 0, time.UTC)
+	paid := insertPaidOrder(t, sent.Add(time.Hour), 100.00, 21.00, 0, 121.00, strPtr("ES"), false, nil, nil)
+
+	insertRecovery := func(sentAt time.Time, restored bool, orderID *uuid.UUID) {
+		t.Helper()
+		var restoredAt *time.Time
+		if restored {
+			restoredAt = &sentAt
+		}
+		_, err := testDB.Pool.Exec(ctx, `
+			INSERT INTO cart_recoveries (id, email, order_id, sent_at, restored_at)
+			VALUES ($1, 'shopper@example.com', $2, $3, $4)`,
+			uuid.New(), orderID, sentAt, restoredAt,
+		)
+		if err != nil {
+			t.Fatalf("inserting cart recovery: %v", err)
+		}
+	}
+	insertRecovery(sent, true, &paid)
+	insertRecovery(sent, true, nil)
+	insertRecovery(sent, false, nil)
+	// Outside the range.
+	insertRecovery(sent.AddDate(0, -1, 0), true, nil)
+
+	from := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	rpt, err := svc.GetCartRecoveryReport(ctx, from, to)
+	if err != nil {
+		t.Fatalf("GetCartRecoveryReport: %v", err)
+	}
+	if rpt.EmailsSent != 3 {
+		t.Errorf("emails sent: got %d, want 3", rpt.EmailsSent)
+	}
+	if rpt.CartsRestored != 2 {
+		t.Errorf("carts restored: got %d, want 2", rpt.CartsRestored)
+	}
+	if rpt.OrdersRecovered != 1 {
+		t.Errorf("orders recovered: got %d, want 1", rpt.OrdersRecovered)
+	}
+	r, _ := rpt.RecoveredRevenue.Float64Value()
+	if math.Abs(r.Float64-121.00) > 0.01 {
+		t.Errorf("recovered revenue: got %.2f, want 121.00", r.Float64)
+	}
+}
+
+// --------------------------------------------------------------------------
+// OSS Return
+// --------------------------------------------------------------------------
+
+// insertRefund records a succeeded refund of one line of an order.
+func insertRefund(t *testing.T, orderID uuid.UUID, createdAt time.Time, net, vatRate, vat float64) {
+	t.Helper()
+	ctx := context.Background()
+	refundID := uuid.New()
+
+	if _, err := testDB.Pool.Exec(ctx, `
+		INSERT INTO refunds (id, order_id, status, source, amount, net_amount, vat_amount, created_at, updated_at)
+		VALUES ($1, $2, 'succeeded', 'admin', $3, $4, $5, $6, $6)`,
+		refundID, orderID, net+vat, net, vat, createdAt,
+	); err != nil {
+		t.Fatalf("inserting refund: %v", err)
+	}
+	if _, err := testDB.Pool.Exec(ctx, `
+		INSERT INTO refund_items (refund_id, description, amount, net_amount, vat_rate, vat_amount)
+		VALUES ($1, 'Refund', $2, $3, $4, $5)`,
+		refundID, net+vat, net, vatRate, vat,
+	); err != nil {
+		t.Fatalf("inserting refund item: %v", err)
+	}
+}
+
+func TestGetOSSReturn(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	if _, err := testDB.Pool.Exec(ctx,
+		`UPDATE store_settings SET vat_country_code = 'ES', vat_number = 'ESB12345678'`); err != nil {
+		t.Fatalf("setting store country: %v", err)
+	}
+
+	q1 := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	q2 := time.Date(2026, 5, 10, 12, 0, 0, 0, time.UTC)
+
+	// Cross-border sale this quarter, partly refunded this quarter.
+	de := insertPaidOrder(t, q2, 100, 19, 0, 119, strPtr("DE"), false, nil, nil)
+	insertOrderItem(t, de, "Bag", 1, 119, 119, 19, 19, 100, 119, "standard")
+	insertRefund(t, de, q2.AddDate(0, 0, 5), 20, 19, 3.80)
+
+	// Domestic sale.
+	es := insertPaidOrder(t, q2, 50, 10.50, 0, 60.50, strPtr("ES"), false, nil, nil)
+	insertOrderItem(t, es, "Bag", 1, 60.50, 60.50, 21, 10.50, 50, 60.50, "standard")
+
+	// B2B reverse charge: not part of the return.
+	b2b := insertPaidOrder(t, q2, 200, 0, 0, 200, strPtr("FR"), true, strPtr("FR123"), strPtr("Acme"))
+	insertOrderItem(t, b2b, "Bag", 2, 100, 200, 0, 0, 100, 100, "standard")
+
+	// Cross-border sale last quarter, refunded this quarter.
+	fr := insertPaidOrder(t, q1, 10, 2, 0, 12, strPtr("FR"), false, nil, nil)
+	insertOrderItem(t, fr, "Book", 1, 12, 12, 20, 2, 10, 12, "standard")
+	insertRefund(t, fr, q2, 10, 20, 2)
+
+	ret, err := svc.GetOSSReturn(ctx, 2026, 2)
+	if err != nil {
+		t.Fatalf("GetOSSReturn: %v", err)
+	}
+
+	if ret.StoreCountry != "ES" || ret.VATNumber != "ESB12345678" {
+		t.Errorf("seller: got %s / %s, want ES / ESB12345678", ret.StoreCountry, ret.VATNumber)
+	}
+	if len(ret.CrossBorder) != 1 {
+		t.Fatalf("cross-border lines: got %d, want 1", len(ret.CrossBorder))
+	}
+	if l := ret.CrossBorder[0]; l.CountryCode != "DE" || l.Taxable.StringFixed(2) != "80.00" || l.VAT.StringFixed(2) != "15.20" {
+		t.Errorf("DE line: got %s taxable %s VAT %s, want DE 80.00 / 15.20",
+			l.CountryCode, l.Taxable.StringFixed(2), l.VAT.StringFixed(2))
+	}
+	if len(ret.Domestic) != 1 || ret.Domestic[0].VAT.StringFixed(2) != "10.50" {
+		t.Errorf("domestic: got %+v, want one line with VAT 10.50", ret.Domestic)
+	}
+	if len(ret.Corrections) != 1 || ret.Corrections[0].Quarter != 1 || ret.Corrections[0].VAT.StringFixed(2) != "-2.00" {
+		t.Errorf("corrections: got %+v, want FR 2026 Q1 -2.00", ret.Corrections)
+	}
+	if got := ret.TotalVAT.StringFixed(2); got != "13.20" {
+		t.Errorf("total VAT: got %s, want 13.20", got)
+	}
+
+	if _, err := svc.GetOSSReturn(ctx, 2026, 5); !errors.Is(err, report.ErrInvalidQuarter) {
+		t.Errorf("expected ErrInvalidQuarter, got %v", err)
+	}
+}
+
 // --------------------------------------------------------------------------
 // Helpers
 // --------------------------------------------------------------------------
@@ -0,0 +1,219 @@
+package report
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+)
+
+// ErrInvalidQuarter is returned for a quarter outside 1-4.
+var ErrInvalidQuarter = errors.New("quarter must be between 1 and 4")
+
+// OSSReturn is the quarterly One-Stop-Shop VAT return: B2C sales shipped to
+// other member states, per country and VAT rate. Domestic B2C sales are
+// listed alongside for the national return but are not part of the OSS
+// return. B2B reverse-charge sales are excluded from both.
+//
+// Refunds made in the quarter for orders of the same quarter are netted
+// into the lines. Refunds of orders from earlier quarters correct the
+// return of that quarter and are listed as Corrections.
+type OSSReturn struct {
+	Year    int
+	Quarter int
+	From    time.Time
+	To      time.Time
+
+	// The store's country and VAT number, the member state of identification.
+	StoreCountry string
+	VATNumber    string
+
+	Domestic    []OSSLine
+	CrossBorder []OSSLine
+	Corrections []OSSCorrection
+
+	// TotalVAT is the VAT due through the OSS: cross-border VAT plus
+	// corrections.
+	TotalVAT decimal.Decimal
+}
+
+// OSSLine is the taxable amount and VAT for one country and rate. Taxable
+// and VAT are net of the refunded amounts.
+type OSSLine struct {
+	CountryCode string
+	CountryName string
+	RateType    string
+	Rate        decimal.Decimal
+	SalesNet    decimal.Decimal
+	SalesVAT    decimal.Decimal
+	RefundNet   decimal.Decimal
+	RefundVAT   decimal.Decimal
+	Taxable     decimal.Decimal
+	VAT         decimal.Decimal
+}
+
+// OSSCorrection reduces the VAT declared for a country in an earlier
+// quarter, for refunds of orders placed in that quarter.
+type OSSCorrection struct {
+	CountryCode string
+	CountryName string
+	Year        int
+	Quarter     int
+	VAT         decimal.Decimal
+}
+
+// QuarterRange returns the first day of the quarter and the first day of the
+// next one, in UTC.
+func QuarterRange(year, quarter int) (time.Time, time.Time) {
+	from := time.Date(year, time.Month((quarter-1)*3+1), 1, 0, 0, 0, 0, time.UTC)
+	return from, from.AddDate(0, 3, 0)
+}
+
+// GetOSSReturn returns the OSS return for the given quarter.
+func (s *Service) GetOSSReturn(ctx context.Context, year, quarter int) (*OSSReturn, error) {
+	if quarter < 1 || quarter > 4 {
+		return nil, ErrInvalidQuarter
+	}
+	from, to := QuarterRange(year, quarter)
+
+	settings, err := s.queries.GetStoreSettings(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("getting store settings: %w", err)
+	}
+
+	sales, err := s.queries.OSSSalesByCountry(ctx, db.OSSSalesByCountryParams{
+		FromDate: from,
+		ToDate:   to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying OSS sales: %w", err)
+	}
+
+	refunds, err := s.queries.OSSRefundsByCountry(ctx, db.OSSRefundsByCountryParams{
+		FromDate: from,
+		ToDate:   to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying OSS refunds: %w", err)
+	}
+
+	ret := &OSSReturn{
+		Year:         year,
+		Quarter:      quarter,
+		From:         from,
+		To:           to,
+		StoreCountry: derefString(settings.VatCountryCode),
+		VATNumber:    derefString(settings.VatNumber),
+	}
+	ret.build(sales, refunds)
+	return ret, nil
+}
+
+// build splits sales and refunds into domestic and cross-border lines and
+// corrections, and totals the VAT due.
+func (r *OSSReturn) build(sales []db.OSSSalesByCountryRow, refunds []db.OSSRefundsByCountryRow) {
+	type key struct{ country, rate string }
+	lines := make([]*OSSLine, 0, len(sales))
+	byKey := make(map[key]*OSSLine, len(sales))
+
+	for _, row := range sales {
+		country := derefString(row.CountryCode)
+		rate := numericToDecimal(row.Rate)
+		k := key{country, rate.StringFixed(2)}
+		line, ok := byKey[k]
+		if !ok {
+			line = &OSSLine{
+				CountryCode: country,
+				CountryName: row.CountryName,
+				RateType:    derefString(row.RateType),
+				Rate:        rate,
+			}
+			byKey[k] = line
+			lines = append(lines, line)
+		}
+		line.SalesNet = line.SalesNet.Add(numericToDecimal(row.NetSales))
+		line.SalesVAT = line.SalesVAT.Add(numericToDecimal(row.VatAmount))
+	}
+
+	var corrections []*OSSCorrection
+	byPeriod := make(map[key]*OSSCorrection)
+	for _, row := range refunds {
+		country := derefString(row.CountryCode)
+		samePeriod := int(row.OrderYear) == r.Year && int(row.OrderQuarter) == r.Quarter
+		if country != r.StoreCountry && !samePeriod {
+			k := key{country, fmt.Sprintf("%d-Q%d", row.OrderYear, row.OrderQuarter)}
+			c, ok := byPeriod[k]
+			if !ok {
+				c = &OSSCorrection{
+					CountryCode: country,
+					CountryName: row.CountryName,
+					Year:        int(row.OrderYear),
+					Quarter:     int(row.OrderQuarter),
+				}
+				byPeriod[k] = c
+				corrections = append(corrections, c)
+			}
+			c.VAT = c.VAT.Sub(numericToDecimal(row.VatAmount))
+			continue
+		}
+
+		rate := numericToDecimal(row.Rate)
+		k := key{country, rate.StringFixed(2)}
+		line, ok := byKey[k]
+		if !ok {
+			line = &OSSLine{
+				CountryCode: country,
+				CountryName: row.CountryName,
+				Rate:        rate,
+			}
+			byKey[k] = line
+			lines = append(lines, line)
+		}
+		line.RefundNet = line.RefundNet.Add(numericToDecimal(row.NetAmount))
+		line.RefundVAT = line.RefundVAT.Add(numericToDecimal(row.VatAmount))
+	}
+
+	for _, c := range corrections {
+		r.Corrections = append(r.Corrections, *c)
+		r.TotalVAT = r.TotalVAT.Add(c.VAT)
+	}
+
+	// Lines that only have refunds were appended last.
+	sort.SliceStable(lines, func(i, j int) bool {
+		if lines[i].CountryCode != lines[j].CountryCode {
+			return lines[i].CountryCode < lines[j].CountryCode
+		}
+		return lines[i].Rate.GreaterThan(lines[j].Rate)
+	})
+
+	for _, line := range lines {
+		line.Taxable = line.SalesNet.Sub(line.RefundNet)
+		line.VAT = line.SalesVAT.Sub(line.RefundVAT)
+		if line.CountryCode == r.StoreCountry {
+			r.Domestic = append(r.Domestic, *line)
+			continue
+		}
+		r.CrossBorder = append(r.CrossBorder, *line)
+		r.TotalVAT = r.TotalVAT.Add(line.VAT)
+	}
+}
+
+func numericToDecimal(n pgtype.Numeric) decimal.Decimal {
+	if !n.Valid || n.Int == nil {
+		return decimal.Zero
+	}
+	return decimal.NewFromBigInt(n.Int, n.Exp)
+}
+
+func derefString(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
@@ -0,0 +1,72 @@
+package report
+
+import (
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+)
+
+func num(t *testing.T, s string) pgtype.Numeric {
+	t.Helper()
+	var n pgtype.Numeric
+	if err := n.Scan(s); err != nil {
+		t.Fatalf("scanning %q: %v", s, err)
+	}
+	return n
+}
+
+func strPtr(s string) *string { return &s }
+
+func TestOSSReturnBuild(t *testing.T) {
+	ret := &OSSReturn{Year: 2026, Quarter: 2, StoreCountry: "ES"}
+	ret.build(
+		[]db.OSSSalesByCountryRow{
+			{CountryCode: strPtr("DE"), CountryName: "Germany", RateType: strPtr("standard"), Rate: num(t, "19.00"), NetSales: num(t, "100.00"), VatAmount: num(t, "19.00")},
+			{CountryCode: strPtr("DE"), CountryName: "Germany", RateType: strPtr("reduced"), Rate: num(t, "7.00"), NetSales: num(t, "50.00"), VatAmount: num(t, "3.50")},
+			{CountryCode: strPtr("ES"), CountryName: "Spain", RateType: strPtr("standard"), Rate: num(t, "21.00"), NetSales: num(t, "200.00"), VatAmount: num(t, "42.00")},
+		},
+		[]db.OSSRefundsByCountryRow{
+			// Refund of an order from this quarter: netted.
+			{CountryCode: strPtr("DE"), CountryName: "Germany", Rate: num(t, "19.00"), OrderYear: 2026, OrderQuarter: 2, NetAmount: num(t, "20.00"), VatAmount: num(t, "3.80")},
+			// Refund of an order from last quarter: a correction.
+			{CountryCode: strPtr("FR"), CountryName: "France", Rate: num(t, "20.00"), OrderYear: 2026, OrderQuarter: 1, NetAmount: num(t, "10.00"), VatAmount: num(t, "2.00")},
+			// Domestic refunds are always netted.
+			{CountryCode: strPtr("ES"), CountryName: "Spain", Rate: num(t, "21.00"), OrderYear: 2025, OrderQuarter: 4, NetAmount: num(t, "10.00"), VatAmount: num(t, "2.10")},
+		},
+	)
+
+	if len(ret.CrossBorder) != 2 {
+		t.Fatalf("cross-border lines: got %d, want 2", len(ret.CrossBorder))
+	}
+	de := ret.CrossBorder[0]
+	if de.Rate.StringFixed(2) != "19.00" || de.Taxable.StringFixed(2) != "80.00" || de.VAT.StringFixed(2) != "15.20" {
+		t.Errorf("DE standard: got rate %s taxable %s VAT %s, want 19.00 / 80.00 / 15.20",
+			de.Rate.StringFixed(2), de.Taxable.StringFixed(2), de.VAT.StringFixed(2))
+	}
+
+	if len(ret.Domestic) != 1 || ret.Domestic[0].VAT.StringFixed(2) != "39.90" {
+		t.Errorf("domestic: got %+v, want one line with VAT 39.90", ret.Domestic)
+	}
+
+	if len(ret.Corrections) != 1 {
+		t.Fatalf("corrections: got %d, want 1", len(ret.Corrections))
+	}
+	c := ret.Corrections[0]
+	if c.CountryCode != "FR" || c.Year != 2026 || c.Quarter != 1 || c.VAT.StringFixed(2) != "-2.00" {
+		t.Errorf("correction: got %+v, want FR 2026 Q1 -2.00", c)
+	}
+
+	// 15.20 + 3.50 - 2.00
+	if got := ret.TotalVAT.StringFixed(2); got != "16.70" {
+		t.Errorf("total VAT: got %s, want 16.70", got)
+	}
+}
+
+func TestQuarterRange(t *testing.T) {
+	from, to := QuarterRange(2026, 4)
+	if from.Format("2006-01-02") != "2026-10-01" || to.Format("2006-01-02") != "2027-01-01" {
+		t.Errorf("got %s to %s, want 2026-10-01 to 2027-01-01", from.Format("2006-01-02"), to.Format("2006-01-02"))
+	}
+}
@@ -88,6 +88,16 @@ type TopProduct struct {
 	TotalRevenue  pgtype.Numeric
 }
 
+// CartRecoveryReport measures abandoned cart reminders sent in a date range:
+// how many shoppers came back through the link, and the paid orders and
+// revenue that followed.
+type CartRecoveryReport struct {
+	EmailsSent       int64
+	CartsRestored    int64
+	OrdersRecovered  int64
+	RecoveredRevenue pgtype.Numeric
+}
+
 // Service provides business logic for sales and VAT reporting.
 type Service struct {
 	queries *db.Queries
@@ -270,6 +280,27 @@ func (s *Service) GetTopProducts(ctx context.Context, from, to time.Time, limit
 	return products, nil
 }
 
+// GetCartRecoveryReport returns abandoned cart recovery metrics for reminders
+// sent in the given date range. An order counts as recovered when the cart
+// it was checked out from had been sent a reminder, whether or not the
+// shopper used the link.
+func (s *Service) GetCartRecoveryReport(ctx context.Context, from, to time.Time) (*CartRecoveryReport, error) {
+	row, err := s.queries.CartRecoverySummary(ctx, db.CartRecoverySummaryParams{
+		FromDate: from,
+		ToDate:   to,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("querying cart recovery summary: %w", err)
+	}
+
+	return &CartRecoveryReport{
+		EmailsSent:       row.EmailsSent,
+		CartsRestored:    row.CartsRestored,
+		OrdersRecovered:  row.OrdersRecovered,
+		RecoveredRevenue: toNumeric(row.RecoveredRevenue),
+	}, nil
+}
+
 // toNumeric converts an interface{} value (as returned by sqlc for COALESCE/SUM
 // expressions) into a pgtype.Numeric. It handles pgtype.Numeric directly, numeric
 // strings, and common numeric Go types.
@@ -14,6 +14,8 @@ import (
 	"github.com/jackc/pgx/v5/pgxpool"
 
 	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/inventory"
+	"github.com/forgecommerce/api/internal/services/webhook"
 )
 
 var (
@@ -55,24 +57,32 @@ type UpdateVariantParams struct {
 	Barcode           *string
 	IsActive          bool
 	Position          int32
+
+	// ChangedBy is the admin user editing the variant, recorded on the
+	// stock movement when StockQuantity differs from the current level.
+	ChangedBy pgtype.UUID
 }
 
 // Service provides business logic for product variant operations.
 type Service struct {
-	queries *db.Queries
-	pool    *pgxpool.Pool
-	logger  *slog.Logger
+	queries   *db.Queries
+	pool      *pgxpool.Pool
+	inventory *inventory.Service
+	logger    *slog.Logger
 }
 
-// NewService creates a new variant service.
-func NewService(pool *pgxpool.Pool, logger *slog.Logger) *Service {
+// NewService creates a new variant service. Stock edits that take a variant
+// to its low-stock threshold are published to events; pass nil to run
+// without webhooks.
+func NewService(pool *pgxpool.Pool, events webhook.Publisher, logger *slog.Logger) *Service {
 	if logger == nil {
 		logger = slog.Default()
 	}
 	return &Service{
-		queries: db.New(pool),
-		pool:    pool,
-		logger:  logger,
+		queries:   db.New(pool),
+		pool:      pool,
+		inventory: inventory.NewService(pool, events, logger),
+		logger:    logger,
 	}
 }
 
@@ -115,7 +125,15 @@ func (s *Service) Create(ctx context.Context, params CreateVariantParams) (db.Pr
 		return db.ProductVariant{}, ErrSKURequired
 	}
 
-	variant, err := s.queries.CreateProductVariant(ctx, db.CreateProductVariantParams{
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.ProductVariant{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	// The variant starts empty; any initial stock is booked through the
+	// inventory ledger so it shows up in the movement history.
+	variant, err := s.queries.WithTx(tx).CreateProductVariant(ctx, db.CreateProductVariantParams{
 		ID:                uuid.New(),
 		ProductID:         params.ProductID,
 		Sku:               params.Sku,
@@ -123,7 +141,7 @@ func (s *Service) Create(ctx context.Context, params CreateVariantParams) (db.Pr
 		CompareAtPrice:    params.CompareAtPrice,
 		WeightGrams:       params.WeightGrams,
 		DimensionsMm:      params.DimensionsMm,
-		StockQuantity:     params.StockQuantity,
+		StockQuantity:     0,
 		LowStockThreshold: params.LowStockThreshold,
 		Barcode:           params.Barcode,
 		IsActive:          params.IsActive,
@@ -133,6 +151,24 @@ func (s *Service) Create(ctx context.Context, params CreateVariantParams) (db.Pr
 		return db.ProductVariant{}, fmt.Errorf("creating variant: %w", err)
 	}
 
+	inv := s.inventory.WithTx(tx)
+	if params.StockQuantity != 0 {
+		if _, err := inv.SetVariantStock(ctx, variant.ID, params.StockQuantity, inventory.Change{
+			MovementType:  inventory.MovementAdjustment,
+			ReferenceType: inventory.ReferenceManual,
+			Notes:         "Initial stock",
+			AllowNegative: true,
+		}); err != nil {
+			return db.ProductVariant{}, fmt.Errorf("setting initial stock for variant: %w", err)
+		}
+		variant.StockQuantity = params.StockQuantity
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.ProductVariant{}, fmt.Errorf("committing transaction: %w", err)
+	}
+	inv.PublishPending(ctx)
+
 	s.logger.Info("variant created",
 		slog.String("variant_id", variant.ID.String()),
 		slog.String("sku", variant.Sku),
@@ -149,8 +185,17 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateVariant
 		return db.ProductVariant{}, ErrSKURequired
 	}
 
-	// Verify the variant exists before attempting the update.
-	_, err := s.queries.GetProductVariant(ctx, id)
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return db.ProductVariant{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	// Lock the variant so the stock level read here is the one the ledger
+	// records as "before".
+	existing, err := qtx.GetProductVariantForUpdate(ctx, id)
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return db.ProductVariant{}, ErrNotFound
@@ -158,14 +203,15 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateVariant
 		return db.ProductVariant{}, fmt.Errorf("fetching variant for update: %w", err)
 	}
 
-	variant, err := s.queries.UpdateProductVariant(ctx, db.UpdateProductVariantParams{
+	// Stock is left untouched here and changed through the inventory ledger below.
+	variant, err := qtx.UpdateProductVariant(ctx, db.UpdateProductVariantParams{
 		ID:                id,
 		Sku:               params.Sku,
 		Price:             params.Price,
 		CompareAtPrice:    params.CompareAtPrice,
 		WeightGrams:       params.WeightGrams,
 		DimensionsMm:      params.DimensionsMm,
-		StockQuantity:     params.StockQuantity,
+		StockQuantity:     existing.StockQuantity,
 		LowStockThreshold: params.LowStockThreshold,
 		Barcode:           params.Barcode,
 		IsActive:          params.IsActive,
@@ -175,6 +221,24 @@ func (s *Service) Update(ctx context.Context, id uuid.UUID, params UpdateVariant
 		return db.ProductVariant{}, fmt.Errorf("updating variant %s: %w", id, err)
 	}
 
+	inv := s.inventory.WithTx(tx)
+	if params.StockQuantity != existing.StockQuantity {
+		if _, err := inv.SetVariantStock(ctx, id, params.StockQuantity, inventory.Change{
+			MovementType:  inventory.MovementAdjustment,
+			ReferenceType: inventory.ReferenceManual,
+			CreatedBy:     params.ChangedBy,
+			AllowNegative: true,
+		}); err != nil {
+			return db.ProductVariant{}, fmt.Errorf("updating stock for variant %s: %w", id, err)
+		}
+		variant.StockQuantity = params.StockQuantity
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return db.ProductVariant{}, fmt.Errorf("committing transaction: %w", err)
+	}
+	inv.PublishPending(ctx)
+
 	s.logger.Info("variant updated",
 		slog.String("variant_id", variant.ID.String()),
 		slog.String("sku", variant.Sku),
@@ -202,28 +266,20 @@ func (s *Service) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-// UpdateStock updates the stock quantity for a variant.
+// UpdateStock sets the stock quantity for a variant. The change is recorded
+// in the stock ledger as a manual adjustment.
 func (s *Service) UpdateStock(ctx context.Context, id uuid.UUID, quantity int32) error {
-	// Verify existence first so callers get a clear ErrNotFound.
-	_, err := s.queries.GetProductVariant(ctx, id)
+	_, err := s.inventory.SetVariantStock(ctx, id, quantity, inventory.Change{
+		MovementType:  inventory.MovementAdjustment,
+		ReferenceType: inventory.ReferenceManual,
+		AllowNegative: true,
+	})
 	if err != nil {
-		if errors.Is(err, pgx.ErrNoRows) {
+		if errors.Is(err, inventory.ErrNotFound) {
 			return ErrNotFound
 		}
-		return fmt.Errorf("fetching variant for stock update: %w", err)
-	}
-
-	if err := s.queries.UpdateVariantStock(ctx, db.UpdateVariantStockParams{
-		ID:            id,
-		StockQuantity: quantity,
-	}); err != nil {
 		return fmt.Errorf("updating stock for variant %s: %w", id, err)
 	}
-
-	s.logger.Info("variant stock updated",
-		slog.String("variant_id", id.String()),
-		slog.Int("stock_quantity", int(quantity)),
-	)
 	return nil
 }
 
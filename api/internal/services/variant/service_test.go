@@ -30,7 +30,7 @@ func TestMain(m *testing.M) {
 }
 
 func newService() *variant.Service {
-	return variant.NewService(testDB.Pool, nil)
+	return variant.NewService(testDB.Pool, nil, nil)
 }
 
 // --------------------------------------------------------------------------
@@ -303,6 +303,42 @@ func TestUpdateStock(t *testing.T) {
 	}
 }
 
+func TestUpdateStock_RecordsMovement(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+	svc := newService()
+	ctx := context.Background()
+
+	product := testDB.FixtureProduct(t, "Test Product", "test-product")
+	created := testDB.FixtureVariant(t, product.ID, "TST-MOV", 5)
+
+	if err := svc.UpdateStock(ctx, created.ID, 8); err != nil {
+		t.Fatalf("UpdateStock: %v", err)
+	}
+	// Setting the same quantity again must not add a second movement.
+	if err := svc.UpdateStock(ctx, created.ID, 8); err != nil {
+		t.Fatalf("UpdateStock (unchanged): %v", err)
+	}
+
+	movements, err := db.New(testDB.Pool).ListStockMovements(ctx, db.ListStockMovementsParams{
+		EntityType: "product_variant",
+		EntityID:   created.ID,
+		Limit:      10,
+	})
+	if err != nil {
+		t.Fatalf("ListStockMovements: %v", err)
+	}
+	if len(movements) != 1 {
+		t.Fatalf("expected 1 movement, got %d", len(movements))
+	}
+	if movements[0].MovementType != "adjustment" {
+		t.Errorf("movement type: got %q, want adjustment", movements[0].MovementType)
+	}
+	if v, _ := movements[0].QuantityChange.Float64Value(); v.Float64 != 3 {
+		t.Errorf("quantity change: got %v, want 3", v.Float64)
+	}
+}
+
 func TestUpdateStock_NotFound(t *testing.T) {
 	testDB.Truncate(t)
 	svc := newService()
@@ -0,0 +1,363 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/shopspring/decimal"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+)
+
+// Event types in the outbound webhook catalogue. Endpoints subscribe to these
+// names, or to wildcards such as "order.*".
+const (
+	EventOrderCreated             = "order.created"
+	EventOrderPaid                = "order.paid"
+	EventOrderStatusChanged       = "order.status_changed"
+	EventOrderRefunded            = "order.refunded"
+	EventProductUpdated           = "product.updated"
+	EventVariantStockLow          = "variant.stock_low"
+	EventProductionBatchCompleted = "production_batch.completed"
+	EventVATRatesChanged          = "vat.rates_changed"
+)
+
+// EventDefinition describes an event in the catalogue. Version is the schema
+// version of the event's data object: it is bumped when a field is removed,
+// renamed or changes meaning. Adding a field does not change it, so receivers
+// must ignore fields they do not know.
+type EventDefinition struct {
+	Type        string
+	Version     int
+	Description string
+}
+
+// Catalogue lists every event the store emits, in display order.
+var Catalogue = []EventDefinition{
+	{EventOrderCreated, 1, "An order was placed."},
+	{EventOrderPaid, 1, "Payment for an order was received."},
+	{EventOrderStatusChanged, 1, "An order moved to a new fulfilment status."},
+	{EventOrderRefunded, 1, "Part or all of an order was refunded."},
+	{EventProductUpdated, 1, "A product's details were changed."},
+	{EventVariantStockLow, 1, "A variant's stock fell to or below its low-stock threshold."},
+	{EventProductionBatchCompleted, 1, "A production batch was completed and its output added to stock."},
+	{EventVATRatesChanged, 1, "The EU VAT rate sync detected changed rates."},
+}
+
+// EventTypes returns the names of all catalogue events.
+func EventTypes() []string {
+	types := make([]string, len(Catalogue))
+	for i, d := range Catalogue {
+		types[i] = d.Type
+	}
+	return types
+}
+
+// eventVersion returns the schema version of eventType, or 0 if it is not in
+// the catalogue.
+func eventVersion(eventType string) int {
+	for _, d := range Catalogue {
+		if d.Type == eventType {
+			return d.Version
+		}
+	}
+	return 0
+}
+
+// Envelope is the JSON body of every webhook delivery.
+type Envelope struct {
+	ID         uuid.UUID `json:"id"`
+	Type       string    `json:"type"`
+	Version    int       `json:"version"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Data       any       `json:"data"`
+}
+
+// Publisher emits catalogue events. Domain services depend on this interface
+// rather than on *Service so they can run without webhooks configured.
+type Publisher interface {
+	Publish(ctx context.Context, eventType string, data any)
+}
+
+// Discard is a Publisher that drops every event.
+var Discard Publisher = discard{}
+
+type discard struct{}
+
+func (discard) Publish(context.Context, string, any) {}
+
+// Publishers returns a Publisher that passes every event to each of ps in
+// order.
+func Publishers(ps ...Publisher) Publisher {
+	return multi(ps)
+}
+
+type multi []Publisher
+
+func (m multi) Publish(ctx context.Context, eventType string, data any) {
+	for _, p := range m {
+		p.Publish(ctx, eventType, data)
+	}
+}
+
+// Publish wraps data in an Envelope and dispatches it to subscribed endpoints.
+func (s *Service) Publish(ctx context.Context, eventType string, data any) {
+	s.Dispatch(ctx, eventType, Envelope{
+		ID:         uuid.New(),
+		Type:       eventType,
+		Version:    eventVersion(eventType),
+		OccurredAt: time.Now().UTC(),
+		Data:       data,
+	})
+}
+
+// --------------------------------------------------------------------------
+// Payload schemas (version 1)
+//
+// Monetary amounts are decimal strings with two places, e.g. "12.50", in the
+// store currency. Timestamps are RFC 3339 in UTC.
+// --------------------------------------------------------------------------
+
+// OrderData is the data object of order.created and order.paid.
+type OrderData struct {
+	Order Order `json:"order"`
+}
+
+// OrderStatusChangedData is the data object of order.status_changed.
+type OrderStatusChangedData struct {
+	Order      Order  `json:"order"`
+	FromStatus string `json:"from_status"`
+	ToStatus   string `json:"to_status"`
+}
+
+// OrderRefundedData is the data object of order.refunded.
+type OrderRefundedData struct {
+	Order  Order  `json:"order"`
+	Refund Refund `json:"refund"`
+}
+
+// Order is an order as it appears in event payloads.
+type Order struct {
+	ID               uuid.UUID   `json:"id"`
+	OrderNumber      int64       `json:"order_number"`
+	Status           string      `json:"status"`
+	PaymentStatus    string      `json:"payment_status"`
+	Email            string      `json:"email"`
+	CustomerID       *uuid.UUID  `json:"customer_id"`
+	Subtotal         string      `json:"subtotal"`
+	ShippingFee      string      `json:"shipping_fee"`
+	DiscountAmount   string      `json:"discount_amount"`
+	VATTotal         string      `json:"vat_total"`
+	Total            string      `json:"total"`
+	VATCountryCode   *string     `json:"vat_country_code"`
+	VATReverseCharge bool        `json:"vat_reverse_charge"`
+	ShippingMethod   *string     `json:"shipping_method"`
+	TrackingNumber   *string     `json:"tracking_number"`
+	Items            []OrderItem `json:"items"`
+	CreatedAt        time.Time   `json:"created_at"`
+	UpdatedAt        time.Time   `json:"updated_at"`
+}
+
+// OrderItem is an order line as it appears in event payloads.
+type OrderItem struct {
+	ID          uuid.UUID  `json:"id"`
+	ProductID   *uuid.UUID `json:"product_id"`
+	VariantID   *uuid.UUID `json:"variant_id"`
+	SKU         *string    `json:"sku"`
+	ProductName string     `json:"product_name"`
+	VariantName *string    `json:"variant_name"`
+	Quantity    int32      `json:"quantity"`
+	UnitPrice   string     `json:"unit_price"`
+	TotalPrice  string     `json:"total_price"`
+	VATRate     string     `json:"vat_rate"`
+	VATAmount   string     `json:"vat_amount"`
+}
+
+// Refund is a refund (credit note) as it appears in event payloads.
+type Refund struct {
+	ID               uuid.UUID `json:"id"`
+	CreditNoteNumber int64     `json:"credit_note_number"`
+	Source           string    `json:"source"`
+	Amount           string    `json:"amount"`
+	NetAmount        string    `json:"net_amount"`
+	VATAmount        string    `json:"vat_amount"`
+	Reason           *string   `json:"reason"`
+	Restocked        bool      `json:"restocked"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// ProductData is the data object of product.updated.
+type ProductData struct {
+	Product Product `json:"product"`
+}
+
+// Product is a product as it appears in event payloads.
+type Product struct {
+	ID             uuid.UUID `json:"id"`
+	Name           string    `json:"name"`
+	Slug           string    `json:"slug"`
+	Status         string    `json:"status"`
+	SKUPrefix      *string   `json:"sku_prefix"`
+	BasePrice      string    `json:"base_price"`
+	CompareAtPrice *string   `json:"compare_at_price"`
+	HasVariants    bool      `json:"has_variants"`
+	AllowBackorder bool      `json:"allow_backorder"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+// VariantStockLowData is the data object of variant.stock_low.
+type VariantStockLowData struct {
+	VariantID         uuid.UUID `json:"variant_id"`
+	ProductID         uuid.UUID `json:"product_id"`
+	SKU               string    `json:"sku"`
+	StockQuantity     int32     `json:"stock_quantity"`
+	LowStockThreshold int32     `json:"low_stock_threshold"`
+}
+
+// ProductionBatchCompletedData is the data object of production_batch.completed.
+type ProductionBatchCompletedData struct {
+	BatchID         uuid.UUID  `json:"batch_id"`
+	BatchNumber     string     `json:"batch_number"`
+	ProductID       uuid.UUID  `json:"product_id"`
+	VariantID       *uuid.UUID `json:"variant_id"`
+	PlannedQuantity int32      `json:"planned_quantity"`
+	ActualQuantity  int32      `json:"actual_quantity"`
+	CostTotal       *string    `json:"cost_total"`
+	CompletedAt     *time.Time `json:"completed_at"`
+}
+
+// VATRatesChangedData is the data object of vat.rates_changed.
+type VATRatesChangedData struct {
+	Source  string          `json:"source"`
+	Changes []VATRateChange `json:"changes"`
+}
+
+// VATRateChange is a single changed rate. OldRate is null for a rate that did
+// not exist before. Rates are percentages, e.g. "21.00".
+type VATRateChange struct {
+	CountryCode string  `json:"country_code"`
+	RateType    string  `json:"rate_type"`
+	OldRate     *string `json:"old_rate"`
+	NewRate     string  `json:"new_rate"`
+}
+
+// NewOrder converts an order and its items to the payload representation.
+func NewOrder(o db.Order, items []db.OrderItem) Order {
+	out := Order{
+		ID:               o.ID,
+		OrderNumber:      o.OrderNumber,
+		Status:           o.Status,
+		PaymentStatus:    o.PaymentStatus,
+		Email:            o.Email,
+		CustomerID:       uuidPtr(o.CustomerID),
+		Subtotal:         money(o.Subtotal),
+		ShippingFee:      money(o.ShippingFee),
+		DiscountAmount:   money(o.DiscountAmount),
+		VATTotal:         money(o.VatTotal),
+		Total:            money(o.Total),
+		VATCountryCode:   o.VatCountryCode,
+		VATReverseCharge: o.VatReverseCharge,
+		ShippingMethod:   o.ShippingMethod,
+		TrackingNumber:   o.TrackingNumber,
+		Items:            make([]OrderItem, 0, len(items)),
+		CreatedAt:        o.CreatedAt,
+		UpdatedAt:        o.UpdatedAt,
+	}
+	for _, it := range items {
+		out.Items = append(out.Items, OrderItem{
+			ID:          it.ID,
+			ProductID:   uuidPtr(it.ProductID),
+			VariantID:   uuidPtr(it.VariantID),
+			SKU:         it.Sku,
+			ProductName: it.ProductName,
+			VariantName: it.VariantName,
+			Quantity:    it.Quantity,
+			UnitPrice:   money(it.UnitPrice),
+			TotalPrice:  money(it.TotalPrice),
+			VATRate:     money(it.VatRate),
+			VATAmount:   money(it.VatAmount),
+		})
+	}
+	return out
+}
+
+// NewRefund converts a refund to the payload representation. Only succeeded
+// refunds are published, and those always have a credit note number.
+func NewRefund(r db.Refund) Refund {
+	var creditNoteNumber int64
+	if r.CreditNoteNumber != nil {
+		creditNoteNumber = *r.CreditNoteNumber
+	}
+	return Refund{
+		ID:               r.ID,
+		CreditNoteNumber: creditNoteNumber,
+		Source:           r.Source,
+		Amount:           money(r.Amount),
+		NetAmount:        money(r.NetAmount),
+		VATAmount:        money(r.VatAmount),
+		Reason:           r.Reason,
+		Restocked:        r.Restocked,
+		CreatedAt:        r.CreatedAt,
+	}
+}
+
+// NewProduct converts a product to the payload representation.
+func NewProduct(p db.Product) Product {
+	out := Product{
+		ID:             p.ID,
+		Name:           p.Name,
+		Slug:           p.Slug,
+		Status:         p.Status,
+		SKUPrefix:      p.SkuPrefix,
+		BasePrice:      money(p.BasePrice),
+		HasVariants:    p.HasVariants,
+		AllowBackorder: p.AllowBackorder,
+		UpdatedAt:      p.UpdatedAt,
+	}
+	if p.CompareAtPrice.Valid {
+		s := money(p.CompareAtPrice)
+		out.CompareAtPrice = &s
+	}
+	return out
+}
+
+// NewProductionBatchCompleted converts a completed batch to its payload.
+func NewProductionBatchCompleted(b db.ProductionBatch) ProductionBatchCompletedData {
+	out := ProductionBatchCompletedData{
+		BatchID:         b.ID,
+		BatchNumber:     b.BatchNumber,
+		ProductID:       b.ProductID,
+		VariantID:       uuidPtr(b.VariantID),
+		PlannedQuantity: b.PlannedQuantity,
+	}
+	if b.ActualQuantity != nil {
+		out.ActualQuantity = *b.ActualQuantity
+	}
+	if b.CostTotal.Valid {
+		s := money(b.CostTotal)
+		out.CostTotal = &s
+	}
+	if b.CompletedAt.Valid {
+		t := b.CompletedAt.Time.UTC()
+		out.CompletedAt = &t
+	}
+	return out
+}
+
+// money formats a numeric column as a two-place decimal string.
+func money(n pgtype.Numeric) string {
+	if !n.Valid || n.Int == nil {
+		return "0.00"
+	}
+	return decimal.NewFromBigInt(n.Int, n.Exp).StringFixed(2)
+}
+
+func uuidPtr(u pgtype.UUID) *uuid.UUID {
+	if !u.Valid {
+		return nil
+	}
+	id := uuid.UUID(u.Bytes)
+	return &id
+}
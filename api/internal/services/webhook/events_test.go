@@ -0,0 +1,90 @@
+package webhook
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgtype"
+
+	db "github.com/forgecommerce/api/internal/database/gen"
+)
+
+func TestEnvelopeJSON(t *testing.T) {
+	id := uuid.MustParse("0b7c1d52-6a55-4b54-9b0e-6f0f1a1e2d3c")
+	env := Envelope{
+		ID:         id,
+		Type:       EventVariantStockLow,
+		Version:    1,
+		OccurredAt: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+		Data:       VariantStockLowData{SKU: "WAL-1", StockQuantity: 2, LowStockThreshold: 5},
+	}
+
+	b, err := json.Marshal(env)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(b, &got); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	for _, key := range []string{"id", "type", "version", "occurred_at", "data"} {
+		if _, ok := got[key]; !ok {
+			t.Errorf("envelope missing %q: %s", key, b)
+		}
+	}
+	if got["occurred_at"] != "2026-03-01T12:00:00Z" {
+		t.Errorf("occurred_at: got %v", got["occurred_at"])
+	}
+	data := got["data"].(map[string]any)
+	if data["sku"] != "WAL-1" || data["stock_quantity"] != float64(2) {
+		t.Errorf("data: got %v", data)
+	}
+}
+
+func TestNewOrder(t *testing.T) {
+	customer := uuid.New()
+	o := db.Order{
+		ID:            uuid.New(),
+		OrderNumber:   1042,
+		Status:        "confirmed",
+		PaymentStatus: "paid",
+		Email:         "buyer@example.com",
+		CustomerID:    pgtype.UUID{Bytes: customer, Valid: true},
+		Subtotal:      pgtype.Numeric{Int: big.NewInt(12100), Exp: -2, Valid: true},
+		VatTotal:      pgtype.Numeric{Int: big.NewInt(21), Exp: 0, Valid: true},
+		Total:         pgtype.Numeric{Int: big.NewInt(1210), Exp: -1, Valid: true},
+	}
+	items := []db.OrderItem{{
+		ID:          uuid.New(),
+		ProductName: "Wallet",
+		Quantity:    2,
+		UnitPrice:   pgtype.Numeric{Int: big.NewInt(6050), Exp: -2, Valid: true},
+	}}
+
+	got := NewOrder(o, items)
+
+	if got.Subtotal != "121.00" || got.VATTotal != "21.00" || got.Total != "121.00" {
+		t.Errorf("amounts: subtotal=%s vat=%s total=%s", got.Subtotal, got.VATTotal, got.Total)
+	}
+	if got.ShippingFee != "0.00" {
+		t.Errorf("invalid numeric should format as 0.00, got %s", got.ShippingFee)
+	}
+	if got.CustomerID == nil || *got.CustomerID != customer {
+		t.Errorf("customer_id: got %v", got.CustomerID)
+	}
+	if len(got.Items) != 1 || got.Items[0].UnitPrice != "60.50" || got.Items[0].VariantID != nil {
+		t.Errorf("items: got %+v", got.Items)
+	}
+
+	// Orders without items still serialise items as an empty array.
+	b, _ := json.Marshal(NewOrder(o, nil))
+	var raw map[string]json.RawMessage
+	json.Unmarshal(b, &raw)
+	if string(raw["items"]) != "[]" {
+		t.Errorf("items: got %s, want []", raw["items"])
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"sync"
 	"sync/atomic"
 	"testing"
 	"time"
@@ -38,6 +39,14 @@ func newService() *webhook.Service {
 	return webhook.NewService(testDB.Pool, nil)
 }
 
+// startWorker runs a delivery worker for svc until the test ends.
+func startWorker(t *testing.T, svc *webhook.Service) {
+	t.Helper()
+	w := webhook.NewWorker(svc, nil)
+	w.Start()
+	t.Cleanup(w.Stop)
+}
+
 // --------------------------------------------------------------------------
 // CreateEndpoint
 // --------------------------------------------------------------------------
@@ -719,6 +728,7 @@ func TestDispatch_DeliversToMatchingEndpoint(t *testing.T) {
 	defer server.Close()
 
 	svc := webhook.NewService(testDB.Pool, nil)
+	startWorker(t, svc)
 
 	// Create an endpoint pointing to our mock server.
 	_, err := svc.CreateEndpoint(ctx,
@@ -766,6 +776,7 @@ func TestDispatch_SkipsNonMatchingEndpoints(t *testing.T) {
 	defer server.Close()
 
 	svc := webhook.NewService(testDB.Pool, nil)
+	startWorker(t, svc)
 
 	// Create an endpoint that only subscribes to "product.created".
 	_, err := svc.CreateEndpoint(ctx,
@@ -802,6 +813,7 @@ func TestDispatch_WildcardEndpoint(t *testing.T) {
 	defer server.Close()
 
 	svc := webhook.NewService(testDB.Pool, nil)
+	startWorker(t, svc)
 
 	// Create an endpoint with wildcard "*".
 	_, err := svc.CreateEndpoint(ctx, server.URL, "secret", "", []string{"*"}, true)
@@ -838,6 +850,7 @@ func TestDispatch_InactiveEndpointSkipped(t *testing.T) {
 	defer server.Close()
 
 	svc := webhook.NewService(testDB.Pool, nil)
+	startWorker(t, svc)
 
 	// Create an inactive endpoint.
 	_, err := svc.CreateEndpoint(ctx, server.URL, "secret", "", []string{"*"}, false)
@@ -936,6 +949,7 @@ func TestDispatch_HTTPErrorSchedulesRetry(t *testing.T) {
 	defer server.Close()
 
 	svc := webhook.NewService(testDB.Pool, nil)
+	startWorker(t, svc)
 
 	ep, err := svc.CreateEndpoint(ctx, server.URL, "secret-key", "Error-test endpoint", []string{"order.created"}, true)
 	if err != nil {
@@ -987,6 +1001,7 @@ func TestDispatch_UnreachableURLMarksDeliveryFailed(t *testing.T) {
 
 	// Use a URL that will refuse connections immediately.
 	svc := webhook.NewService(testDB.Pool, nil)
+	startWorker(t, svc)
 
 	ep, err := svc.CreateEndpoint(ctx, "http://127.0.0.1:1", "secret", "Unreachable", []string{"order.created"}, true)
 	if err != nil {
@@ -1011,9 +1026,12 @@ func TestDispatch_UnreachableURLMarksDeliveryFailed(t *testing.T) {
 	if d.DeliveredAt.Valid {
 		t.Error("delivery should NOT be marked as delivered for unreachable URL")
 	}
-	// markFailed stores the error message in response_body
-	if d.ResponseBody == nil || *d.ResponseBody == "" {
-		t.Error("response_body should contain the error message")
+	// markFailed stores the transport error in last_error
+	if d.LastError == nil || *d.LastError == "" {
+		t.Error("last_error should contain the error message")
+	}
+	if d.Status != webhook.DeliveryPending {
+		t.Errorf("status: got %q, want %q", d.Status, webhook.DeliveryPending)
 	}
 	if !d.NextRetryAt.Valid {
 		t.Error("next_retry_at should be set for failed delivery")
@@ -1043,6 +1061,7 @@ func TestDispatch_SetsCorrectHeaders(t *testing.T) {
 	defer server.Close()
 
 	svc := webhook.NewService(testDB.Pool, nil)
+	startWorker(t, svc)
 	_, err := svc.CreateEndpoint(ctx, server.URL, "my-secret-123", "", []string{"product.updated"}, true)
 	if err != nil {
 		t.Fatalf("CreateEndpoint: %v", err)
@@ -1110,6 +1129,7 @@ func TestCountDeliveries_WithDeliveries(t *testing.T) {
 	defer server.Close()
 
 	svc := webhook.NewService(testDB.Pool, nil)
+	startWorker(t, svc)
 	ep, err := svc.CreateEndpoint(ctx, server.URL, "sec", "", []string{"*"}, true)
 	if err != nil {
 		t.Fatalf("CreateEndpoint: %v", err)
@@ -1137,3 +1157,429 @@ func TestCountDeliveries_WithDeliveries(t *testing.T) {
 		t.Errorf("expected 2 deliveries, got %d", count)
 	}
 }
+
+// --------------------------------------------------------------------------
+// Delivery worker: leasing, backoff, dead-lettering and redelivery
+// --------------------------------------------------------------------------
+
+// insertDueDelivery queues a delivery that is due now and has already been
+// attempted the given number of times.
+func insertDueDelivery(t *testing.T, endpointID uuid.UUID, attempts int) uuid.UUID {
+	t.Helper()
+	var id uuid.UUID
+	err := testDB.Pool.QueryRow(context.Background(), `
+		INSERT INTO webhook_deliveries (endpoint_id, event_type, payload, attempt, next_retry_at)
+		VALUES ($1, 'order.created', '{"order_id":"x"}', $2, NOW() - INTERVAL '1 second')
+		RETURNING id
+	`, endpointID, attempts).Scan(&id)
+	if err != nil {
+		t.Fatalf("inserting delivery: %v", err)
+	}
+	return id
+}
+
+func getDelivery(t *testing.T, svc *webhook.Service, endpointID, id uuid.UUID) (found bool, status string, attempt int32, nextRetry bool) {
+	t.Helper()
+	deliveries, err := svc.ListDeliveries(context.Background(), endpointID, 100, 0)
+	if err != nil {
+		t.Fatalf("ListDeliveries: %v", err)
+	}
+	for _, d := range deliveries {
+		if d.ID == id {
+			return true, d.Status, d.Attempt, d.NextRetryAt.Valid
+		}
+	}
+	return false, "", 0, false
+}
+
+func failingServer(t *testing.T, received *atomic.Int32) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestDispatch_QueuesDeliveryDurably(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	ep, err := svc.CreateEndpoint(ctx, "https://example.com/queued", "secret", "", []string{"order.*"}, true)
+	if err != nil {
+		t.Fatalf("CreateEndpoint: %v", err)
+	}
+
+	// No worker is running: the delivery must still be recorded as pending.
+	svc.Dispatch(ctx, "order.paid", map[string]string{"order_id": "1"})
+
+	deliveries, err := svc.ListDeliveries(ctx, ep.ID, 10, 0)
+	if err != nil {
+		t.Fatalf("ListDeliveries: %v", err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("expected 1 queued delivery, got %d", len(deliveries))
+	}
+	d := deliveries[0]
+	if d.Status != webhook.DeliveryPending {
+		t.Errorf("status: got %q, want %q", d.Status, webhook.DeliveryPending)
+	}
+	if d.Attempt != 0 {
+		t.Errorf("attempt: got %d, want 0", d.Attempt)
+	}
+	if !d.NextRetryAt.Valid {
+		t.Error("queued delivery should be due immediately")
+	}
+}
+
+func TestProcessPendingDeliveries_FailureSchedulesBackoff(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	var received atomic.Int32
+	server := failingServer(t, &received)
+
+	ep, err := svc.CreateEndpoint(ctx, server.URL, "secret", "", []string{"*"}, true)
+	if err != nil {
+		t.Fatalf("CreateEndpoint: %v", err)
+	}
+	id := insertDueDelivery(t, ep.ID, 3)
+
+	before := time.Now()
+	if err := svc.ProcessPendingDeliveries(ctx); err != nil {
+		t.Fatalf("ProcessPendingDeliveries: %v", err)
+	}
+
+	var status string
+	var attempt int32
+	var nextRetry time.Time
+	err = testDB.Pool.QueryRow(ctx,
+		`SELECT status, attempt, next_retry_at FROM webhook_deliveries WHERE id = $1`, id,
+	).Scan(&status, &attempt, &nextRetry)
+	if err != nil {
+		t.Fatalf("reading delivery: %v", err)
+	}
+	if status != webhook.DeliveryPending {
+		t.Errorf("status: got %q, want %q", status, webhook.DeliveryPending)
+	}
+	if attempt != 4 {
+		t.Errorf("attempt: got %d, want 4", attempt)
+	}
+	// Fourth failure waits 8 minutes plus up to 10% jitter.
+	wait := nextRetry.Sub(before)
+	if wait < webhook.RetryDelay(4) || wait > webhook.RetryDelay(4)*11/10+time.Minute {
+		t.Errorf("next retry in %s, want about %s", wait, webhook.RetryDelay(4))
+	}
+
+	// Not due again yet.
+	if err := svc.ProcessPendingDeliveries(ctx); err != nil {
+		t.Fatalf("ProcessPendingDeliveries: %v", err)
+	}
+	if received.Load() != 1 {
+		t.Errorf("expected 1 request before the backoff elapses, got %d", received.Load())
+	}
+}
+
+func TestProcessPendingDeliveries_DeadLettersAfterMaxAttempts(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	var received atomic.Int32
+	server := failingServer(t, &received)
+
+	ep, err := svc.CreateEndpoint(ctx, server.URL, "secret", "", []string{"*"}, true)
+	if err != nil {
+		t.Fatalf("CreateEndpoint: %v", err)
+	}
+	id := insertDueDelivery(t, ep.ID, webhook.MaxAttempts-1)
+
+	if err := svc.ProcessPendingDeliveries(ctx); err != nil {
+		t.Fatalf("ProcessPendingDeliveries: %v", err)
+	}
+
+	found, status, attempt, nextRetry := getDelivery(t, svc, ep.ID, id)
+	if !found {
+		t.Fatal("delivery not found")
+	}
+	if status != webhook.DeliveryDead {
+		t.Errorf("status: got %q, want %q", status, webhook.DeliveryDead)
+	}
+	if attempt != webhook.MaxAttempts {
+		t.Errorf("attempt: got %d, want %d", attempt, webhook.MaxAttempts)
+	}
+	if nextRetry {
+		t.Error("dead-lettered delivery should have no next retry")
+	}
+
+	got, err := svc.GetEndpoint(ctx, ep.ID)
+	if err != nil {
+		t.Fatalf("GetEndpoint: %v", err)
+	}
+	if got.ConsecutiveDeadLetters != 1 {
+		t.Errorf("consecutive_dead_letters: got %d, want 1", got.ConsecutiveDeadLetters)
+	}
+	if !got.IsActive {
+		t.Error("one dead letter should not disable the endpoint")
+	}
+
+	// Dead letters are never retried.
+	if err := svc.ProcessPendingDeliveries(ctx); err != nil {
+		t.Fatalf("ProcessPendingDeliveries: %v", err)
+	}
+	if received.Load() != 1 {
+		t.Errorf("expected 1 request, got %d", received.Load())
+	}
+}
+
+func TestProcessPendingDeliveries_DisablesEndpointAfterRepeatedDeadLetters(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	var received atomic.Int32
+	server := failingServer(t, &received)
+
+	ep, err := svc.CreateEndpoint(ctx, server.URL, "secret", "", []string{"*"}, true)
+	if err != nil {
+		t.Fatalf("CreateEndpoint: %v", err)
+	}
+	for i := 0; i < webhook.DisableAfterDeadLetters; i++ {
+		insertDueDelivery(t, ep.ID, webhook.MaxAttempts-1)
+	}
+	if err := svc.ProcessPendingDeliveries(ctx); err != nil {
+		t.Fatalf("ProcessPendingDeliveries: %v", err)
+	}
+
+	got, err := svc.GetEndpoint(ctx, ep.ID)
+	if err != nil {
+		t.Fatalf("GetEndpoint: %v", err)
+	}
+	if got.IsActive {
+		t.Error("endpoint should be disabled")
+	}
+	if !got.DisabledAt.Valid {
+		t.Error("disabled_at should be set")
+	}
+	if got.DisabledReason == nil || *got.DisabledReason == "" {
+		t.Error("disabled_reason should be set")
+	}
+
+	// Re-activating from the admin clears the automatic disable.
+	got, err = svc.UpdateEndpoint(ctx, ep.ID, got.Url, "", got.Events, true)
+	if err != nil {
+		t.Fatalf("UpdateEndpoint: %v", err)
+	}
+	if got.DisabledAt.Valid || got.DisabledReason != nil || got.ConsecutiveDeadLetters != 0 {
+		t.Errorf("re-activation should clear disable state, got disabled_at=%v reason=%v dead_letters=%d",
+			got.DisabledAt.Valid, got.DisabledReason, got.ConsecutiveDeadLetters)
+	}
+}
+
+func TestProcessPendingDeliveries_SuccessResetsDeadLetters(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	ep, err := svc.CreateEndpoint(ctx, server.URL, "secret", "", []string{"*"}, true)
+	if err != nil {
+		t.Fatalf("CreateEndpoint: %v", err)
+	}
+	if _, err := testDB.Pool.Exec(ctx,
+		`UPDATE webhook_endpoints SET consecutive_dead_letters = 3 WHERE id = $1`, ep.ID,
+	); err != nil {
+		t.Fatalf("setting dead letters: %v", err)
+	}
+	id := insertDueDelivery(t, ep.ID, 2)
+
+	if err := svc.ProcessPendingDeliveries(ctx); err != nil {
+		t.Fatalf("ProcessPendingDeliveries: %v", err)
+	}
+
+	_, status, attempt, nextRetry := getDelivery(t, svc, ep.ID, id)
+	if status != webhook.DeliveryDelivered {
+		t.Errorf("status: got %q, want %q", status, webhook.DeliveryDelivered)
+	}
+	if attempt != 3 {
+		t.Errorf("attempt: got %d, want 3", attempt)
+	}
+	if nextRetry {
+		t.Error("delivered delivery should have no next retry")
+	}
+
+	got, err := svc.GetEndpoint(ctx, ep.ID)
+	if err != nil {
+		t.Fatalf("GetEndpoint: %v", err)
+	}
+	if got.ConsecutiveDeadLetters != 0 {
+		t.Errorf("consecutive_dead_letters: got %d, want 0", got.ConsecutiveDeadLetters)
+	}
+}
+
+func TestProcessPendingDeliveries_RespectsLease(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	ep, err := svc.CreateEndpoint(ctx, server.URL, "secret", "", []string{"*"}, true)
+	if err != nil {
+		t.Fatalf("CreateEndpoint: %v", err)
+	}
+	leased := insertDueDelivery(t, ep.ID, 0)
+	expired := insertDueDelivery(t, ep.ID, 0)
+	if _, err := testDB.Pool.Exec(ctx, `
+		UPDATE webhook_deliveries
+		SET locked_until = CASE WHEN id = $1 THEN NOW() + INTERVAL '1 minute' ELSE NOW() - INTERVAL '1 second' END
+		WHERE id IN ($1, $2)
+	`, leased, expired); err != nil {
+		t.Fatalf("setting leases: %v", err)
+	}
+
+	if err := svc.ProcessPendingDeliveries(ctx); err != nil {
+		t.Fatalf("ProcessPendingDeliveries: %v", err)
+	}
+
+	if received.Load() != 1 {
+		t.Errorf("expected only the expired lease to be sent, got %d requests", received.Load())
+	}
+	if _, status, _, _ := getDelivery(t, svc, ep.ID, leased); status != webhook.DeliveryPending {
+		t.Errorf("leased delivery status: got %q, want %q", status, webhook.DeliveryPending)
+	}
+	if _, status, _, _ := getDelivery(t, svc, ep.ID, expired); status != webhook.DeliveryDelivered {
+		t.Errorf("expired lease status: got %q, want %q", status, webhook.DeliveryDelivered)
+	}
+}
+
+func TestProcessPendingDeliveries_ConcurrentCallersSendOnce(t *testing.T) {
+	testDB.Truncate(t)
+	ctx := context.Background()
+
+	var received atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received.Add(1)
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	svc := newService()
+	ep, err := svc.CreateEndpoint(ctx, server.URL, "secret", "", []string{"*"}, true)
+	if err != nil {
+		t.Fatalf("CreateEndpoint: %v", err)
+	}
+	const total = 60
+	for i := 0; i < total; i++ {
+		insertDueDelivery(t, ep.ID, 0)
+	}
+
+	// Separate services stand in for separate processes.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := newService().ProcessPendingDeliveries(ctx); err != nil {
+				t.Errorf("ProcessPendingDeliveries: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if received.Load() != total {
+		t.Errorf("requests: got %d, want %d", received.Load(), total)
+	}
+}
+
+func TestRedeliver(t *testing.T) {
+	testDB.Truncate(t)
+	ctx := context.Background()
+
+	var received atomic.Int32
+	var deliveryIDs sync.Map
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		deliveryIDs.Store(r.Header.Get("X-Webhook-Delivery"), true)
+		received.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	svc := newService()
+	ep, err := svc.CreateEndpoint(ctx, server.URL, "secret", "", []string{"*"}, true)
+	if err != nil {
+		t.Fatalf("CreateEndpoint: %v", err)
+	}
+	original := insertDueDelivery(t, ep.ID, webhook.MaxAttempts-1)
+	if _, err := testDB.Pool.Exec(ctx,
+		`UPDATE webhook_deliveries SET status = 'dead', next_retry_at = NULL WHERE id = $1`, original,
+	); err != nil {
+		t.Fatalf("dead-lettering delivery: %v", err)
+	}
+
+	copied, err := svc.Redeliver(ctx, ep.ID, original)
+	if err != nil {
+		t.Fatalf("Redeliver: %v", err)
+	}
+	if copied.ID == original {
+		t.Fatal("redelivery should be a new delivery")
+	}
+	if copied.Status != webhook.DeliveryPending || copied.Attempt != 0 {
+		t.Errorf("redelivery: got status %q attempt %d, want pending 0", copied.Status, copied.Attempt)
+	}
+	if string(copied.Payload) != `{"order_id": "x"}` {
+		t.Errorf("payload: got %s", copied.Payload)
+	}
+
+	if err := svc.ProcessPendingDeliveries(ctx); err != nil {
+		t.Fatalf("ProcessPendingDeliveries: %v", err)
+	}
+	if received.Load() != 1 {
+		t.Fatalf("requests: got %d, want 1", received.Load())
+	}
+	if _, ok := deliveryIDs.Load(copied.ID.String()); !ok {
+		t.Error("request should carry the new delivery ID")
+	}
+	if _, status, _, _ := getDelivery(t, svc, ep.ID, original); status != webhook.DeliveryDead {
+		t.Errorf("original delivery status changed to %q", status)
+	}
+}
+
+func TestRedeliver_Errors(t *testing.T) {
+	testDB.Truncate(t)
+	svc := newService()
+	ctx := context.Background()
+
+	ep, err := svc.CreateEndpoint(ctx, "https://example.com/a", "secret", "", []string{"*"}, true)
+	if err != nil {
+		t.Fatalf("CreateEndpoint: %v", err)
+	}
+	other, err := svc.CreateEndpoint(ctx, "https://example.com/b", "secret", "", []string{"*"}, false)
+	if err != nil {
+		t.Fatalf("CreateEndpoint: %v", err)
+	}
+	delivery := insertDueDelivery(t, ep.ID, 1)
+
+	if _, err := svc.Redeliver(ctx, uuid.New(), delivery); !errors.Is(err, webhook.ErrNotFound) {
+		t.Errorf("unknown endpoint: got %v, want ErrNotFound", err)
+	}
+	if _, err := svc.Redeliver(ctx, ep.ID, uuid.New()); !errors.Is(err, webhook.ErrDeliveryNotFound) {
+		t.Errorf("unknown delivery: got %v, want ErrDeliveryNotFound", err)
+	}
+	if _, err := svc.Redeliver(ctx, other.ID, delivery); !errors.Is(err, webhook.ErrEndpointInactive) {
+		t.Errorf("inactive endpoint: got %v, want ErrEndpointInactive", err)
+	}
+}
@@ -11,8 +11,10 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"math/rand/v2"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/google/uuid"
@@ -26,17 +28,37 @@ import (
 var (
 	// ErrNotFound is returned when a webhook endpoint does not exist.
 	ErrNotFound = errors.New("webhook endpoint not found")
+
+	// ErrDeliveryNotFound is returned when a delivery does not exist for the endpoint.
+	ErrDeliveryNotFound = errors.New("webhook delivery not found")
+
+	// ErrEndpointInactive is returned when redelivering to an inactive endpoint.
+	ErrEndpointInactive = errors.New("webhook endpoint is inactive")
+)
+
+// Delivery statuses.
+const (
+	DeliveryPending   = "pending"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
 )
 
-// Event type constants for webhook dispatch.
 const (
-	EventOrderCreated   = "order.created"
-	EventOrderUpdated   = "order.updated"
-	EventOrderCompleted = "order.completed"
-	EventProductCreated = "product.created"
-	EventProductUpdated = "product.updated"
-	EventProductDeleted = "product.deleted"
-	EventStockLow       = "stock.low"
+	// MaxAttempts is the number of attempts after which a delivery is
+	// dead-lettered. With RetryDelay this spans a little over a day.
+	MaxAttempts = 12
+
+	// DisableAfterDeadLetters is the number of consecutive dead-lettered
+	// deliveries after which an endpoint is disabled.
+	DisableAfterDeadLetters = 5
+
+	retryBaseDelay = time.Minute
+	retryMaxDelay  = 12 * time.Hour
+
+	// leaseDuration must comfortably exceed the HTTP client timeout; a
+	// delivery whose lease expires is picked up again.
+	leaseDuration  = 2 * time.Minute
+	leaseBatchSize = 20
 )
 
 // Service provides business logic for webhook operations.
@@ -45,6 +67,9 @@ type Service struct {
 	pool    *pgxpool.Pool
 	logger  *slog.Logger
 	client  *http.Client
+
+	// wake nudges the Worker when new deliveries are queued.
+	wake chan struct{}
 }
 
 // NewService creates a new webhook service.
@@ -59,6 +84,7 @@ func NewService(pool *pgxpool.Pool, logger *slog.Logger) *Service {
 		client: &http.Client{
 			Timeout: 10 * time.Second,
 		},
+		wake: make(chan struct{}, 1),
 	}
 }
 
@@ -149,68 +175,98 @@ func (s *Service) DeleteEndpoint(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
-// Dispatch sends an event to all active endpoints subscribed to the event type.
-// The delivery is performed asynchronously in a background goroutine.
+// Dispatch queues an event for every active endpoint subscribed to the event
+// type. The deliveries are written to the database before Dispatch returns,
+// so they survive a restart; the delivery worker sends them.
 func (s *Service) Dispatch(ctx context.Context, eventType string, payload interface{}) {
-	go func() {
-		bgCtx := context.Background()
+	// Events are usually published right after a request commits; the queue
+	// insert must not be abandoned when that request's context ends.
+	ctx = context.WithoutCancel(ctx)
 
-		endpoints, err := s.queries.ListActiveWebhookEndpoints(bgCtx)
-		if err != nil {
-			s.logger.Error("list active webhook endpoints for dispatch", "error", err)
-			return
+	endpoints, err := s.queries.ListActiveWebhookEndpoints(ctx)
+	if err != nil {
+		s.logger.Error("list active webhook endpoints for dispatch", "error", err)
+		return
+	}
+
+	payloadBytes, err := json.Marshal(payload)
+	if err != nil {
+		s.logger.Error("marshal webhook payload", "error", err, "event", eventType)
+		return
+	}
+
+	queued := 0
+	for _, ep := range endpoints {
+		if !containsEvent(ep.Events, eventType) {
+			continue
 		}
 
-		payloadBytes, err := json.Marshal(payload)
-		if err != nil {
-			s.logger.Error("marshal webhook payload", "error", err, "event", eventType)
-			return
+		if _, err := s.queries.CreateWebhookDelivery(ctx, db.CreateWebhookDeliveryParams{
+			EndpointID: ep.ID,
+			EventType:  eventType,
+			Payload:    payloadBytes,
+		}); err != nil {
+			s.logger.Error("create webhook delivery record",
+				"error", err,
+				"endpoint_id", ep.ID,
+				"event", eventType,
+			)
+			continue
 		}
+		queued++
+	}
 
-		for _, ep := range endpoints {
-			if !containsEvent(ep.Events, eventType) {
-				continue
-			}
+	if queued > 0 {
+		s.notify()
+	}
+}
 
-			delivery, err := s.queries.CreateWebhookDelivery(bgCtx, db.CreateWebhookDeliveryParams{
-				EndpointID: ep.ID,
-				EventType:  eventType,
-				Payload:    payloadBytes,
-			})
-			if err != nil {
-				s.logger.Error("create webhook delivery record",
-					"error", err,
-					"endpoint_id", ep.ID,
-					"event", eventType,
-				)
-				continue
-			}
+// notify wakes the delivery worker without blocking. A pending wake-up
+// already covers any deliveries queued since.
+func (s *Service) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
 
-			s.deliver(bgCtx, delivery.ID, ep.Url, ep.Secret, eventType, payloadBytes)
+// RetryDelay returns the wait before the next attempt after the given number
+// of failed attempts: one minute doubling per attempt, capped at retryMaxDelay.
+func RetryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	delay := retryBaseDelay
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= retryMaxDelay {
+			return retryMaxDelay
 		}
-	}()
+	}
+	return delay
 }
 
-// deliver performs the actual HTTP POST to the webhook endpoint.
-func (s *Service) deliver(ctx context.Context, deliveryID uuid.UUID, url, secret, eventType string, payload []byte) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(payload))
+// deliver performs the HTTP POST for a leased delivery and records the
+// outcome: delivered, rescheduled with backoff, or dead-lettered.
+func (s *Service) deliver(ctx context.Context, d db.LeaseWebhookDeliveriesRow) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.Url, bytes.NewReader(d.Payload))
 	if err != nil {
-		s.markFailed(ctx, deliveryID, err.Error())
+		s.markFailed(ctx, d, nil, nil, err.Error())
 		return
 	}
 
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("X-Webhook-Event", eventType)
-	req.Header.Set("X-Webhook-Delivery", deliveryID.String())
+	req.Header.Set("X-Webhook-Event", d.EventType)
+	req.Header.Set("X-Webhook-Delivery", d.ID.String())
 
-	if secret != "" {
-		sig := signPayload(payload, secret)
+	if d.Secret != "" {
+		sig := signPayload(d.Payload, d.Secret)
 		req.Header.Set("X-Webhook-Signature", sig)
 	}
 
 	resp, err := s.client.Do(req)
 	if err != nil {
-		s.markFailed(ctx, deliveryID, err.Error())
+		s.markFailed(ctx, d, nil, nil, err.Error())
 		return
 	}
 	defer resp.Body.Close()
@@ -220,46 +276,144 @@ func (s *Service) deliver(ctx context.Context, deliveryID uuid.UUID, url, secret
 
 	statusCode := int32(resp.StatusCode)
 
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		// Success
-		if err := s.queries.UpdateWebhookDeliverySuccess(ctx, db.UpdateWebhookDeliverySuccessParams{
-			ID:             deliveryID,
-			ResponseStatus: &statusCode,
-			ResponseBody:   &bodyStr,
-		}); err != nil {
-			s.logger.Error("update webhook delivery success",
-				"error", err,
-				"delivery_id", deliveryID,
-			)
-		}
-	} else {
-		// HTTP error, schedule retry
-		if err := s.queries.UpdateWebhookDeliveryFailed(ctx, db.UpdateWebhookDeliveryFailedParams{
-			ID:             deliveryID,
-			ResponseStatus: &statusCode,
-			ResponseBody:   &bodyStr,
-			NextRetryAt:    pgtype.Timestamptz{Time: time.Now().Add(5 * time.Minute), Valid: true},
-		}); err != nil {
-			s.logger.Error("update webhook delivery failed",
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		s.markFailed(ctx, d, &statusCode, &bodyStr, fmt.Sprintf("endpoint responded with HTTP %d", resp.StatusCode))
+		return
+	}
+
+	if err := s.queries.UpdateWebhookDeliverySuccess(ctx, db.UpdateWebhookDeliverySuccessParams{
+		ID:             d.ID,
+		ResponseStatus: &statusCode,
+		ResponseBody:   &bodyStr,
+	}); err != nil {
+		s.logger.Error("update webhook delivery success",
+			"error", err,
+			"delivery_id", d.ID,
+		)
+		return
+	}
+	if err := s.queries.ResetWebhookEndpointDeadLetters(ctx, d.EndpointID); err != nil {
+		s.logger.Error("reset webhook endpoint dead letters",
+			"error", err,
+			"endpoint_id", d.EndpointID,
+		)
+	}
+}
+
+// markFailed records a failed attempt. The delivery is rescheduled with
+// backoff until MaxAttempts is reached, then dead-lettered.
+func (s *Service) markFailed(ctx context.Context, d db.LeaseWebhookDeliveriesRow, status *int32, body *string, errMsg string) {
+	attempts := int(d.Attempt) + 1
+	if attempts >= MaxAttempts {
+		if err := s.deadLetter(ctx, d, status, body, errMsg); err != nil {
+			s.logger.Error("dead-letter webhook delivery",
 				"error", err,
-				"delivery_id", deliveryID,
+				"delivery_id", d.ID,
 			)
 		}
+		return
 	}
-}
 
-// markFailed records a delivery failure when no HTTP response was received (e.g., DNS error, timeout).
-func (s *Service) markFailed(ctx context.Context, deliveryID uuid.UUID, errMsg string) {
+	delay := RetryDelay(attempts)
+	// Up to 10% jitter so deliveries that failed together do not retry in lockstep.
+	delay += rand.N(delay/10 + 1)
+
 	if err := s.queries.UpdateWebhookDeliveryFailed(ctx, db.UpdateWebhookDeliveryFailedParams{
-		ID:           deliveryID,
-		ResponseBody: &errMsg,
-		NextRetryAt:  pgtype.Timestamptz{Time: time.Now().Add(5 * time.Minute), Valid: true},
+		ID:             d.ID,
+		Status:         DeliveryPending,
+		ResponseStatus: status,
+		ResponseBody:   body,
+		LastError:      &errMsg,
+		NextRetryAt:    pgtype.Timestamptz{Time: time.Now().Add(delay), Valid: true},
 	}); err != nil {
 		s.logger.Error("mark webhook delivery failed",
 			"error", err,
-			"delivery_id", deliveryID,
+			"delivery_id", d.ID,
+		)
+	}
+}
+
+// deadLetter gives up on a delivery and disables its endpoint once
+// DisableAfterDeadLetters deliveries in a row have been dead-lettered.
+func (s *Service) deadLetter(ctx context.Context, d db.LeaseWebhookDeliveriesRow, status *int32, body *string, errMsg string) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	qtx := s.queries.WithTx(tx)
+
+	if err := qtx.UpdateWebhookDeliveryFailed(ctx, db.UpdateWebhookDeliveryFailedParams{
+		ID:             d.ID,
+		Status:         DeliveryDead,
+		ResponseStatus: status,
+		ResponseBody:   body,
+		LastError:      &errMsg,
+	}); err != nil {
+		return fmt.Errorf("mark delivery dead: %w", err)
+	}
+
+	deadLetters, err := qtx.IncrementWebhookEndpointDeadLetters(ctx, d.EndpointID)
+	if err != nil {
+		return fmt.Errorf("count endpoint dead letters: %w", err)
+	}
+
+	disabled := deadLetters >= DisableAfterDeadLetters
+	if disabled {
+		reason := fmt.Sprintf("Disabled automatically after %d consecutive failed deliveries. Last error: %s", deadLetters, errMsg)
+		if err := qtx.DisableWebhookEndpoint(ctx, db.DisableWebhookEndpointParams{
+			ID:             d.EndpointID,
+			DisabledReason: &reason,
+		}); err != nil {
+			return fmt.Errorf("disable endpoint: %w", err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit transaction: %w", err)
+	}
+
+	s.logger.Warn("webhook delivery dead-lettered",
+		"delivery_id", d.ID,
+		"endpoint_id", d.EndpointID,
+		"event", d.EventType,
+		"error", errMsg,
+	)
+	if disabled {
+		s.logger.Warn("webhook endpoint disabled after repeated failures",
+			"endpoint_id", d.EndpointID,
+			"url", d.Url,
+			"dead_letters", deadLetters,
 		)
 	}
+	return nil
+}
+
+// Redeliver queues a new delivery of an earlier delivery's payload to the
+// same endpoint. The original delivery is left untouched.
+func (s *Service) Redeliver(ctx context.Context, endpointID, deliveryID uuid.UUID) (db.WebhookDelivery, error) {
+	ep, err := s.GetEndpoint(ctx, endpointID)
+	if err != nil {
+		return db.WebhookDelivery{}, err
+	}
+	if !ep.IsActive {
+		return db.WebhookDelivery{}, ErrEndpointInactive
+	}
+
+	delivery, err := s.queries.RedeliverWebhookDelivery(ctx, db.RedeliverWebhookDeliveryParams{
+		ID:         deliveryID,
+		EndpointID: endpointID,
+	})
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return db.WebhookDelivery{}, ErrDeliveryNotFound
+		}
+		return db.WebhookDelivery{}, fmt.Errorf("redeliver webhook delivery: %w", err)
+	}
+
+	s.notify()
+	return delivery, nil
 }
 
 // ListDeliveries returns delivery history for an endpoint.
@@ -284,20 +438,42 @@ func (s *Service) CountDeliveries(ctx context.Context, endpointID uuid.UUID) (in
 	return count, nil
 }
 
-// ProcessPendingDeliveries retries failed/pending webhook deliveries.
-// This should be called periodically (e.g., every minute via scheduler).
+// ProcessPendingDeliveries leases due deliveries in batches and sends them
+// until none are left or ctx is cancelled. Each batch is sent concurrently.
+// It is run by the Worker, and is safe to call from several processes at once.
 func (s *Service) ProcessPendingDeliveries(ctx context.Context) error {
-	deliveries, err := s.queries.ListPendingWebhookDeliveries(ctx, 50)
-	if err != nil {
-		return fmt.Errorf("list pending deliveries: %w", err)
-	}
+	// A batch in flight is finished even if ctx is cancelled, so a shutdown
+	// does not count interrupted requests as failed attempts.
+	sendCtx := context.WithoutCancel(ctx)
+
+	processed := 0
+	for {
+		deliveries, err := s.queries.LeaseWebhookDeliveries(ctx, db.LeaseWebhookDeliveriesParams{
+			Limit:       leaseBatchSize,
+			LockedUntil: pgtype.Timestamptz{Time: time.Now().Add(leaseDuration), Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("lease pending deliveries: %w", err)
+		}
 
-	for _, d := range deliveries {
-		s.deliver(ctx, d.ID, d.Url, d.Secret, d.EventType, d.Payload)
+		var wg sync.WaitGroup
+		for _, d := range deliveries {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				s.deliver(sendCtx, d)
+			}()
+		}
+		wg.Wait()
+
+		processed += len(deliveries)
+		if len(deliveries) < leaseBatchSize || ctx.Err() != nil {
+			break
+		}
 	}
 
-	if len(deliveries) > 0 {
-		s.logger.Info("processed pending webhook deliveries", "count", len(deliveries))
+	if processed > 0 {
+		s.logger.Info("processed pending webhook deliveries", "count", processed)
 	}
 
 	return nil
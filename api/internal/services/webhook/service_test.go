@@ -5,6 +5,7 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"testing"
+	"time"
 )
 
 // --------------------------------------------------------------------------
@@ -85,9 +86,9 @@ func TestContainsEvent(t *testing.T) {
 			want:   true,
 		},
 		{
-			name:   "stock.low exact match",
-			events: []string{"stock.low"},
-			event:  EventStockLow,
+			name:   "variant.stock_low exact match",
+			events: []string{"variant.stock_low"},
+			event:  EventVariantStockLow,
 			want:   true,
 		},
 		{
@@ -255,42 +256,80 @@ func TestSignPayload_KnownVector(t *testing.T) {
 // --------------------------------------------------------------------------
 
 func TestEventConstants(t *testing.T) {
-	events := map[string]string{
-		"EventOrderCreated":   EventOrderCreated,
-		"EventOrderUpdated":   EventOrderUpdated,
-		"EventOrderCompleted": EventOrderCompleted,
-		"EventProductCreated": EventProductCreated,
-		"EventProductUpdated": EventProductUpdated,
-		"EventProductDeleted": EventProductDeleted,
-		"EventStockLow":       EventStockLow,
+	want := map[string]string{
+		EventOrderCreated:             "order.created",
+		EventOrderPaid:                "order.paid",
+		EventOrderStatusChanged:       "order.status_changed",
+		EventOrderRefunded:            "order.refunded",
+		EventProductUpdated:           "product.updated",
+		EventVariantStockLow:          "variant.stock_low",
+		EventProductionBatchCompleted: "production_batch.completed",
+		EventVATRatesChanged:          "vat.rates_changed",
 	}
-
-	for name, value := range events {
-		if value == "" {
-			t.Errorf("event constant %s should not be empty", name)
+	for got, expected := range want {
+		if got != expected {
+			t.Errorf("event constant = %q, want %q", got, expected)
 		}
 	}
+}
 
-	// Verify specific values match the expected format "domain.action".
-	if EventOrderCreated != "order.created" {
-		t.Errorf("EventOrderCreated = %q, want %q", EventOrderCreated, "order.created")
+func TestCatalogue(t *testing.T) {
+	seen := map[string]bool{}
+	for _, d := range Catalogue {
+		if seen[d.Type] {
+			t.Errorf("duplicate catalogue entry %q", d.Type)
+		}
+		seen[d.Type] = true
+		if d.Version < 1 {
+			t.Errorf("%s: version %d, want >= 1", d.Type, d.Version)
+		}
+		if d.Description == "" {
+			t.Errorf("%s: missing description", d.Type)
+		}
+		if eventVersion(d.Type) != d.Version {
+			t.Errorf("eventVersion(%q) = %d, want %d", d.Type, eventVersion(d.Type), d.Version)
+		}
 	}
-	if EventOrderUpdated != "order.updated" {
-		t.Errorf("EventOrderUpdated = %q, want %q", EventOrderUpdated, "order.updated")
+
+	types := EventTypes()
+	if len(types) != len(Catalogue) || types[0] != EventOrderCreated {
+		t.Errorf("EventTypes() = %v", types)
 	}
-	if EventOrderCompleted != "order.completed" {
-		t.Errorf("EventOrderCompleted = %q, want %q", EventOrderCompleted, "order.completed")
+	if eventVersion("unknown.event") != 0 {
+		t.Error("unknown events should have version 0")
 	}
-	if EventProductCreated != "product.created" {
-		t.Errorf("EventProductCreated = %q, want %q", EventProductCreated, "product.created")
+}
+
+// --------------------------------------------------------------------------
+// Tests for RetryDelay
+// --------------------------------------------------------------------------
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, time.Minute},
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{10, 512 * time.Minute},
+		{11, 12 * time.Hour},
+		{50, 12 * time.Hour},
 	}
-	if EventProductUpdated != "product.updated" {
-		t.Errorf("EventProductUpdated = %q, want %q", EventProductUpdated, "product.updated")
+	for _, tt := range tests {
+		if got := RetryDelay(tt.attempts); got != tt.want {
+			t.Errorf("RetryDelay(%d) = %s, want %s", tt.attempts, got, tt.want)
+		}
 	}
-	if EventProductDeleted != "product.deleted" {
-		t.Errorf("EventProductDeleted = %q, want %q", EventProductDeleted, "product.deleted")
+}
+
+func TestRetrySchedule_SpansAboutADay(t *testing.T) {
+	var total time.Duration
+	for attempts := 1; attempts < MaxAttempts; attempts++ {
+		total += RetryDelay(attempts)
 	}
-	if EventStockLow != "stock.low" {
-		t.Errorf("EventStockLow = %q, want %q", EventStockLow, "stock.low")
+	if total < 24*time.Hour || total > 36*time.Hour {
+		t.Errorf("retry schedule spans %s, want between 24h and 36h", total)
 	}
 }
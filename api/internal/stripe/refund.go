@@ -0,0 +1,82 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	stripe "github.com/stripe/stripe-go/v82"
+	"github.com/stripe/stripe-go/v82/refund"
+)
+
+var (
+	// ErrMissingPaymentIntent is returned when a refund has no PaymentIntent to
+	// refund against.
+	ErrMissingPaymentIntent = errors.New("payment intent ID is required")
+
+	// ErrInvalidRefundAmount is returned when a refund amount is not positive.
+	ErrInvalidRefundAmount = errors.New("refund amount must be positive")
+)
+
+// RefundInput contains the data needed to refund part or all of a payment.
+type RefundInput struct {
+	// PaymentIntentID is the PaymentIntent that captured the original payment.
+	PaymentIntentID string
+
+	// Amount is the amount to refund in the smallest currency unit (cents).
+	Amount int64
+
+	// IdempotencyKey makes retries of the same refund safe. Use the internal
+	// refund ID so a retried request never refunds twice.
+	IdempotencyKey string
+
+	// Metadata is attached to the Stripe Refund object, e.g. order_id.
+	Metadata map[string]string
+}
+
+// RefundResult contains the output of a successfully created refund.
+type RefundResult struct {
+	// RefundID is the Stripe Refund ID (e.g., "re_...").
+	RefundID string
+
+	// Status is the Stripe refund status: pending, succeeded, failed, etc.
+	Status string
+}
+
+// CreateRefund refunds an amount against a PaymentIntent.
+func (s *Service) CreateRefund(ctx context.Context, input RefundInput) (RefundResult, error) {
+	if input.PaymentIntentID == "" {
+		return RefundResult{}, ErrMissingPaymentIntent
+	}
+	if input.Amount <= 0 {
+		return RefundResult{}, ErrInvalidRefundAmount
+	}
+
+	params := &stripe.RefundParams{
+		PaymentIntent: stripe.String(input.PaymentIntentID),
+		Amount:        stripe.Int64(input.Amount),
+		Metadata:      input.Metadata,
+	}
+	params.Context = ctx
+	if input.IdempotencyKey != "" {
+		params.SetIdempotencyKey(input.IdempotencyKey)
+	}
+
+	re, err := refund.New(params)
+	if err != nil {
+		return RefundResult{}, fmt.Errorf("creating stripe refund: %w", err)
+	}
+
+	s.logger.Info("stripe refund created",
+		slog.String("refund_id", re.ID),
+		slog.String("payment_intent_id", input.PaymentIntentID),
+		slog.Int64("amount", input.Amount),
+		slog.String("status", string(re.Status)),
+	)
+
+	return RefundResult{
+		RefundID: re.ID,
+		Status:   string(re.Status),
+	}, nil
+}
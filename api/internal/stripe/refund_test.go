@@ -0,0 +1,42 @@
+package stripe
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestCreateRefund_Validation(t *testing.T) {
+	svc := NewService("sk_test_fake", nil)
+
+	tests := []struct {
+		name  string
+		input RefundInput
+		want  error
+	}{
+		{
+			name:  "missing payment intent",
+			input: RefundInput{Amount: 100},
+			want:  ErrMissingPaymentIntent,
+		},
+		{
+			name:  "zero amount",
+			input: RefundInput{PaymentIntentID: "pi_123"},
+			want:  ErrInvalidRefundAmount,
+		},
+		{
+			name:  "negative amount",
+			input: RefundInput{PaymentIntentID: "pi_123", Amount: -5},
+			want:  ErrInvalidRefundAmount,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.CreateRefund(context.Background(), tt.input)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
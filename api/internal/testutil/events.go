@@ -0,0 +1,50 @@
+package testutil
+
+import (
+	"context"
+	"sync"
+)
+
+// PublishedEvent is a webhook event captured by Events.
+type PublishedEvent struct {
+	Type string
+	Data any
+}
+
+// Events is a webhook.Publisher that records events instead of delivering
+// them, for asserting which events a service emits.
+type Events struct {
+	mu     sync.Mutex
+	events []PublishedEvent
+}
+
+// Publish records the event.
+func (e *Events) Publish(_ context.Context, eventType string, data any) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.events = append(e.events, PublishedEvent{Type: eventType, Data: data})
+}
+
+// Types returns the types of the recorded events in publication order.
+func (e *Events) Types() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	types := make([]string, len(e.events))
+	for i, ev := range e.events {
+		types[i] = ev.Type
+	}
+	return types
+}
+
+// OfType returns the data of every recorded event of the given type.
+func (e *Events) OfType(eventType string) []any {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	var out []any
+	for _, ev := range e.events {
+		if ev.Type == eventType {
+			out = append(out, ev.Data)
+		}
+	}
+	return out
+}
@@ -106,12 +106,23 @@ func (tdb *TestDB) Truncate(t *testing.T) {
 
 	// Truncate in dependency order (children first).
 	tables := []string{
+		"cart_recoveries",
+		"invoices",
+		"invoice_sequences",
+		"refund_items",
+		"refunds",
+		"credit_note_sequences",
+		"stock_reservations",
+		"checkout_snapshots",
 		"order_events",
 		"order_items",
+		"coupon_usage",
 		"orders",
 		"cart_items",
 		"carts",
 		"stock_movements",
+		"production_batch_materials",
+		"production_batches",
 		"variant_bom_overrides",
 		"attribute_option_bom_modifiers",
 		"attribute_option_bom_entries",
@@ -130,12 +141,19 @@ func (tdb *TestDB) Truncate(t *testing.T) {
 		"raw_materials",
 		"raw_material_categories",
 		"coupons",
+		"coupon_campaigns",
 		"discounts",
 		"shipping_zones",
 		"shipping_configs",
+		"email_outbox",
+		"customer_tokens",
+		"customer_addresses",
 		"webhook_deliveries",
 		"webhook_endpoints",
 		"admin_audit_log",
+		"admin_api_keys",
+		"admin_webauthn_challenges",
+		"admin_webauthn_credentials",
 		"sessions",
 		"admin_users",
 		"customers",
@@ -20,6 +20,7 @@ import (
 
 	"github.com/forgecommerce/api/internal/config"
 	db "github.com/forgecommerce/api/internal/database/gen"
+	"github.com/forgecommerce/api/internal/services/webhook"
 	"github.com/forgecommerce/api/internal/testutil"
 )
 
@@ -1276,3 +1277,60 @@ func TestFlexibleFloat_UnmarshalJSON_Invalid(t *testing.T) {
 		t.Error("expected error for invalid data, got nil")
 	}
 }
+
+func TestRateSyncer_ProcessFetchedRates_PublishesChanges(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+
+	insertVATRate(t, "DE", RateTypeStandard, 19.0, SourceSeed)
+
+	events := &testutil.Events{}
+	syncer := &RateSyncer{
+		db:     testDB.Pool,
+		logger: slog.Default(),
+		cache:  NewRateCache(),
+		events: events,
+	}
+
+	syncer.processFetchedRates(context.Background(), []VATRate{
+		{CountryCode: "DE", RateType: RateTypeStandard, Rate: decimal.NewFromFloat(20.0)},
+	}, SourceECTEDB, time.Now())
+
+	published := events.OfType(webhook.EventVATRatesChanged)
+	if len(published) != 1 {
+		t.Fatalf("expected one vat.rates_changed event, got %v", events.Types())
+	}
+	data := published[0].(webhook.VATRatesChangedData)
+	if len(data.Changes) != 1 || data.Changes[0].CountryCode != "DE" || data.Changes[0].NewRate != "20.00" {
+		t.Errorf("unexpected payload: %+v", data)
+	}
+
+	// A second sync with the same rates changes nothing.
+	syncer.processFetchedRates(context.Background(), []VATRate{
+		{CountryCode: "DE", RateType: RateTypeStandard, Rate: decimal.NewFromFloat(20.0)},
+	}, SourceECTEDB, time.Now())
+	if len(events.Types()) != 1 {
+		t.Errorf("unchanged rates should not publish, got %v", events.Types())
+	}
+}
+
+func TestRateSyncer_ProcessFetchedRates_FirstSyncIsSilent(t *testing.T) {
+	testDB.Truncate(t)
+	testDB.SeedEssentials(t)
+
+	events := &testutil.Events{}
+	syncer := &RateSyncer{
+		db:     testDB.Pool,
+		logger: slog.Default(),
+		cache:  NewRateCache(),
+		events: events,
+	}
+
+	syncer.processFetchedRates(context.Background(), []VATRate{
+		{CountryCode: "DE", RateType: RateTypeStandard, Rate: decimal.NewFromFloat(19.0)},
+	}, SourceECTEDB, time.Now())
+
+	if len(events.Types()) != 0 {
+		t.Errorf("initial load should not publish, got %v", events.Types())
+	}
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/shopspring/decimal"
 
 	"github.com/forgecommerce/api/internal/config"
+	"github.com/forgecommerce/api/internal/services/webhook"
 )
 
 // RateSyncer fetches EU VAT rates from external sources and keeps the
@@ -27,16 +28,19 @@ type RateSyncer struct {
 	cfg    config.VATConfig
 	logger *slog.Logger
 	cache  *RateCache
+	events webhook.Publisher // optional
 	client *http.Client
 }
 
-// NewRateSyncer creates a new RateSyncer with the given dependencies.
-func NewRateSyncer(db *pgxpool.Pool, cfg config.VATConfig, logger *slog.Logger, cache *RateCache) *RateSyncer {
+// NewRateSyncer creates a new RateSyncer with the given dependencies. Detected
+// rate changes are published to events, which may be nil.
+func NewRateSyncer(db *pgxpool.Pool, cfg config.VATConfig, logger *slog.Logger, cache *RateCache, events webhook.Publisher) *RateSyncer {
 	return &RateSyncer{
 		db:     db,
 		cfg:    cfg,
 		logger: logger,
 		cache:  cache,
+		events: events,
 		client: &http.Client{
 			Timeout: cfg.TEDBTimeout,
 		},
@@ -139,6 +143,12 @@ func (s *RateSyncer) processFetchedRates(ctx context.Context, rates []VATRate, s
 	// Refresh in-memory cache.
 	s.cache.Load(rates)
 
+	// Without the previous rates every rate looks new, so only announce
+	// changes measured against a known baseline.
+	if err == nil && len(existingRates) > 0 && len(changes) > 0 && s.events != nil {
+		s.events.Publish(ctx, webhook.EventVATRatesChanged, rateChangesPayload(source, changes))
+	}
+
 	return SyncResult{
 		Source:       source,
 		RatesLoaded:  len(rates),
@@ -595,6 +605,28 @@ func (s *RateSyncer) saveRates(ctx context.Context, rates []VATRate, source stri
 	return nil
 }
 
+// rateChangesPayload converts detected changes to the vat.rates_changed
+// payload. A zero old rate marks a rate that did not exist before.
+func rateChangesPayload(source string, changes []RateChange) webhook.VATRatesChangedData {
+	data := webhook.VATRatesChangedData{
+		Source:  source,
+		Changes: make([]webhook.VATRateChange, 0, len(changes)),
+	}
+	for _, ch := range changes {
+		c := webhook.VATRateChange{
+			CountryCode: ch.CountryCode,
+			RateType:    ch.RateType,
+			NewRate:     ch.NewRate.StringFixed(2),
+		}
+		if !ch.OldRate.IsZero() {
+			old := ch.OldRate.StringFixed(2)
+			c.OldRate = &old
+		}
+		data.Changes = append(data.Changes, c)
+	}
+	return data
+}
+
 // detectChanges compares old (from DB) and new (fetched) rates and returns
 // a list of changes. Only compares currently active rates.
 func (s *RateSyncer) detectChanges(old, new []VATRate) []RateChange {
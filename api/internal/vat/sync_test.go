@@ -325,3 +325,28 @@ func TestDetectChanges_EmptyNew(t *testing.T) {
 		t.Errorf("expected 0 changes for empty new, got %d", len(changes))
 	}
 }
+
+func TestRateChangesPayload(t *testing.T) {
+	data := rateChangesPayload(SourceECTEDB, []RateChange{
+		{CountryCode: "DE", RateType: RateTypeStandard, OldRate: decimal.NewFromFloat(19.0), NewRate: decimal.NewFromFloat(20.0)},
+		{CountryCode: "FR", RateType: RateTypeReduced, OldRate: decimal.Zero, NewRate: decimal.NewFromFloat(5.5)},
+	})
+
+	if data.Source != SourceECTEDB {
+		t.Errorf("source: got %q", data.Source)
+	}
+	if len(data.Changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(data.Changes))
+	}
+	de := data.Changes[0]
+	if de.OldRate == nil || *de.OldRate != "19.00" || de.NewRate != "20.00" {
+		t.Errorf("DE change: got old=%v new=%s", de.OldRate, de.NewRate)
+	}
+	fr := data.Changes[1]
+	if fr.OldRate != nil {
+		t.Errorf("new rate should have a null old_rate, got %q", *fr.OldRate)
+	}
+	if fr.NewRate != "5.50" {
+		t.Errorf("FR new rate: got %s", fr.NewRate)
+	}
+}
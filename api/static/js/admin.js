@@ -319,4 +319,118 @@
     },
   };
 
+  // ─── Security Keys (WebAuthn) ──────────────────────────────────
+  // Registers security keys and uses them at the 2FA step of login.
+  // The server sends and expects binary fields as base64url strings.
+
+  function b64urlToBuffer(s) {
+    s = s.replace(/-/g, '+').replace(/_/g, '/');
+    while (s.length % 4) s += '=';
+    var bin = atob(s);
+    var buf = new Uint8Array(bin.length);
+    for (var i = 0; i < bin.length; i++) buf[i] = bin.charCodeAt(i);
+    return buf.buffer;
+  }
+
+  function bufferToB64url(buf) {
+    var bytes = new Uint8Array(buf);
+    var bin = '';
+    for (var i = 0; i < bytes.length; i++) bin += String.fromCharCode(bytes[i]);
+    return btoa(bin).replace(/\+/g, '-').replace(/\//g, '_').replace(/=+$/, '');
+  }
+
+  function decodeDescriptors(list) {
+    return (list || []).map(function (c) {
+      return { type: c.type, id: b64urlToBuffer(c.id), transports: c.transports };
+    });
+  }
+
+  function postJSON(url, body) {
+    var csrfEl = document.querySelector('input[name="csrf_token"]');
+    var headers = { 'Content-Type': 'application/json' };
+    if (csrfEl) headers['X-CSRF-Token'] = csrfEl.value;
+
+    return fetch(url, {
+      method: 'POST',
+      headers: headers,
+      body: JSON.stringify(body || {}),
+    }).then(function (resp) {
+      return resp.json().then(function (data) {
+        if (!resp.ok) throw new Error(data.error || 'Request failed');
+        return data;
+      });
+    });
+  }
+
+  // Turn a browser cancellation into a readable message.
+  function webauthnError(err) {
+    if (err && err.name === 'NotAllowedError') {
+      return new Error('The security key request was cancelled or timed out.');
+    }
+    if (err && err.name === 'InvalidStateError') {
+      return new Error('This security key is already registered.');
+    }
+    return err;
+  }
+
+  window.ForgeWebAuthn = {
+    supported: function () {
+      return !!(window.PublicKeyCredential && navigator.credentials);
+    },
+
+    // Register a new security key under name, then reload the page.
+    register: function (name) {
+      if (!this.supported()) {
+        return Promise.reject(new Error('This browser does not support security keys.'));
+      }
+      return postJSON('/admin/security-keys/options').then(function (opts) {
+        opts.challenge = b64urlToBuffer(opts.challenge);
+        opts.user.id = b64urlToBuffer(opts.user.id);
+        opts.excludeCredentials = decodeDescriptors(opts.excludeCredentials);
+        return navigator.credentials.create({ publicKey: opts });
+      }).then(function (cred) {
+        return postJSON('/admin/security-keys', {
+          name: name,
+          credential: {
+            id: cred.id,
+            response: {
+              clientDataJSON: bufferToB64url(cred.response.clientDataJSON),
+              attestationObject: bufferToB64url(cred.response.attestationObject),
+              transports: cred.response.getTransports ? cred.response.getTransports() : [],
+            },
+          },
+        });
+      }).then(function (data) {
+        window.location.href = data.redirect;
+      }).catch(function (err) {
+        throw webauthnError(err);
+      });
+    },
+
+    // Complete the 2FA step of login with a security key.
+    login: function () {
+      if (!this.supported()) {
+        return Promise.reject(new Error('This browser does not support security keys.'));
+      }
+      return postJSON('/admin/login/2fa/webauthn/options').then(function (opts) {
+        opts.challenge = b64urlToBuffer(opts.challenge);
+        opts.allowCredentials = decodeDescriptors(opts.allowCredentials);
+        return navigator.credentials.get({ publicKey: opts });
+      }).then(function (cred) {
+        return postJSON('/admin/login/2fa/webauthn', {
+          id: cred.id,
+          response: {
+            clientDataJSON: bufferToB64url(cred.response.clientDataJSON),
+            authenticatorData: bufferToB64url(cred.response.authenticatorData),
+            signature: bufferToB64url(cred.response.signature),
+          },
+        });
+      }).then(function (data) {
+        window.location.href = data.redirect;
+      }).catch(function (err) {
+        throw webauthnError(err);
+      });
+    },
+  };
+
 })();
@@ -156,7 +156,7 @@ VALUES (
   'admin@forgecommerce.local',
   'Admin',
   '$2a$12$MJIaL5VIKmVDGD4.qiC3OumyGXw4ESB4oV8dK8NmdkbvfbuEBYcwm',
-  'super_admin',
+  'owner',
   true,
   false,
   false,
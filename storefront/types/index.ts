@@ -111,6 +111,10 @@ export interface Cart {
   expires_at: string
   created_at: string
   items: CartItem[]
+  subtotal: string
+  discount_amount: string
+  discounts: AppliedDiscount[]
+  coupon_error?: string
 }
 
 export interface CartItem {
@@ -134,11 +138,20 @@ export interface CheckoutCalculation {
   vat_total: string
   shipping_fee: string
   discount_amount: string
+  discounts: AppliedDiscount[]
+  coupon_error?: string
   total: string
   vat_breakdown: VATBreakdownItem[]
   reverse_charge: boolean
 }
 
+export interface AppliedDiscount {
+  discount_id: string
+  name: string
+  scope: string
+  amount: string
+}
+
 export interface VATBreakdownItem {
   product_name: string
   rate: string
@@ -155,22 +168,117 @@ export interface VATValidation {
   message?: string
 }
 
+// Response when a cart is reopened from an abandoned cart reminder.
+export interface RestoreCartResponse {
+  cart_id: string
+  coupon_code: string | null
+  expires_at: string
+}
+
 // Auth types
 export interface AuthResponse {
   access_token: string
   refresh_token: string
   customer: CustomerProfile
+  cart?: AuthCart
+}
+
+// The customer's cart after login or registration; fetch the cart for totals.
+export interface AuthCart {
+  id: string
+  expires_at: string
+  items: CartItem[]
+  subtotal: string
 }
 
 export interface CustomerProfile {
   id: string
   email: string
+  email_verified: boolean
   first_name: string | null
   last_name: string | null
   phone: string | null
   vat_number?: string
 }
 
+export interface CustomerAddress {
+  id: string
+  label: string | null
+  first_name: string
+  last_name: string
+  company: string | null
+  address_line1: string
+  address_line2: string | null
+  city: string
+  state_province: string | null
+  postal_code: string
+  country_code: string
+  phone: string | null
+  is_default_billing: boolean
+  is_default_shipping: boolean
+  created_at: string
+  updated_at: string
+}
+
+// Order types
+export interface OrderItem {
+  product_name: string
+  variant_name: string | null
+  sku: string | null
+  quantity: number
+  unit_price: string
+  total_price: string
+  vat_rate: string
+  vat_rate_type: string | null
+  vat_amount: string
+}
+
+export interface OrderVATBreakdown {
+  rate: string
+  net_amount: string
+  vat_amount: string
+}
+
+export interface OrderEvent {
+  event_type: string
+  from_status: string | null
+  to_status: string | null
+  created_at: string
+}
+
+export interface OrderDetail {
+  order_number: number
+  status: string
+  payment_status: string
+  email: string
+  billing_address: Record<string, string> | null
+  shipping_address: Record<string, string> | null
+  subtotal: string
+  shipping_fee: string
+  shipping_extra_fees: string
+  discount_amount: string
+  vat_total: string
+  total: string
+  vat_number: string | null
+  vat_company_name: string | null
+  vat_reverse_charge: boolean
+  shipping_method: string | null
+  tracking_number: string | null
+  shipped_at: string | null
+  delivered_at: string | null
+  customer_notes: string | null
+  created_at: string
+  items: OrderItem[]
+  vat_breakdown: OrderVATBreakdown[]
+  events: OrderEvent[]
+}
+
+export interface OrderLookupResponse {
+  order_number: number
+  access_token: string
+  expires_at: string
+}
+
 // Paginated list response
 export interface PaginatedResponse<T> {
   data: T[]